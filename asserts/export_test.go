@@ -174,3 +174,10 @@ var (
 func (gkm *GPGKeypairManager) ParametersForGenerate(passphrase string, name string) string {
 	return gkm.parametersForGenerate(passphrase, name)
 }
+
+// ifacedecls tests
+var (
+	CompileAttributeConstraints = compileAttributeConstraints
+	CompilePlugRule             = compilePlugRule
+	CompileSlotRule             = compileSlotRule
+)
@@ -225,3 +225,15 @@ func checkOptionalBool(headers map[string]interface{}, name string) (bool, error
 	}
 	return s == "true", nil
 }
+
+func checkOptionalMap(headers map[string]interface{}, name string) (map[string]interface{}, error) {
+	value, ok := headers[name]
+	if !ok {
+		return nil, nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%q header must be a map", name)
+	}
+	return m, nil
+}
@@ -0,0 +1,778 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/release"
+)
+
+// AttrMatchContext has contextual attribute values of the plug and
+// slot of a connection, used to resolve $PLUG(attr) and $SLOT(attr)
+// references in attribute constraints.
+type AttrMatchContext interface {
+	PlugAttr(attr string) (interface{}, error)
+	SlotAttr(attr string) (interface{}, error)
+}
+
+type attrMatcher interface {
+	match(context string, v interface{}, ctx AttrMatchContext) error
+}
+
+// AttributeConstraints implements a set of constraints on the attributes of a slot or plug.
+type AttributeConstraints struct {
+	matcher attrMatcher
+}
+
+// compileAttributeConstraints checks and compiles a mapping of attribute names to constraints.
+func compileAttributeConstraints(constraints interface{}) (*AttributeConstraints, error) {
+	m, ok := constraints.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("attribute constraints must be a map")
+	}
+	matcher, err := compileMapAttrMatcher(m)
+	if err != nil {
+		return nil, err
+	}
+	return &AttributeConstraints{matcher: matcher}, nil
+}
+
+type fixedAttrMatcher struct {
+	result error
+}
+
+func (matcher fixedAttrMatcher) match(context string, v interface{}, ctx AttrMatchContext) error {
+	return matcher.result
+}
+
+var (
+	// AlwaysMatchAttributes is an AttributeConstraints which matches any set of attributes.
+	AlwaysMatchAttributes = &AttributeConstraints{matcher: fixedAttrMatcher{nil}}
+	// NeverMatchAttributes is an AttributeConstraints which never matches.
+	NeverMatchAttributes = &AttributeConstraints{matcher: fixedAttrMatcher{fmt.Errorf("not allowed")}}
+)
+
+// Check checks whether attrs don't match the constraints.
+func (c *AttributeConstraints) Check(attrs map[string]interface{}, ctx AttrMatchContext) error {
+	return c.matcher.match("", attrs, ctx)
+}
+
+func compileAttrMatcher(context string, constraint interface{}) (attrMatcher, error) {
+	switch x := constraint.(type) {
+	case map[string]interface{}:
+		return compileMapAttrMatcher(x)
+	case string:
+		if strings.HasPrefix(x, "$") {
+			return compileEvalAttrMatcher(context, x)
+		}
+		return compileRegexpAttrMatcher(context, x)
+	default:
+		return nil, fmt.Errorf("constraint %q must be a key-value map or a regexp", context)
+	}
+}
+
+type mapAttrMatcher map[string]attrMatcher
+
+func compileMapAttrMatcher(m map[string]interface{}) (attrMatcher, error) {
+	matcher := make(mapAttrMatcher, len(m))
+	for k, constraint := range m {
+		cmatcher, err := compileAttrMatcher(k, constraint)
+		if err != nil {
+			return nil, err
+		}
+		matcher[k] = cmatcher
+	}
+	return matcher, nil
+}
+
+func chain(context, k string) string {
+	if context == "" {
+		return k
+	}
+	return fmt.Sprintf("%s.%s", context, k)
+}
+
+func (matcher mapAttrMatcher) match(context string, v interface{}, ctx AttrMatchContext) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("attribute %q must be a map", context)
+	}
+	// check in a stable order for predictable error messages
+	keys := make([]string, 0, len(matcher))
+	for k := range matcher {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		subContext := chain(context, k)
+		val, ok := m[k]
+		if !ok {
+			return fmt.Errorf("attribute %q has constraints but is unset", subContext)
+		}
+		if err := matcher[k].match(subContext, val, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type regexpAttrMatcher struct {
+	*regexp.Regexp
+}
+
+func compileRegexpAttrMatcher(context, s string) (attrMatcher, error) {
+	rx, err := regexp.Compile("^(" + s + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile %q constraint %q: %v", context, s, err)
+	}
+	return regexpAttrMatcher{rx}, nil
+}
+
+func (matcher regexpAttrMatcher) match(context string, v interface{}, ctx AttrMatchContext) error {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case bool, int, int64, float64:
+		s = fmt.Sprint(x)
+	case []interface{}:
+		for _, elem := range x {
+			if err := matcher.match(context, elem, ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("attribute %q must be a scalar or a list of scalars", context)
+	}
+	if !matcher.MatchString(s) {
+		return fmt.Errorf("attribute %q value %q does not match %v", context, s, matcher.Regexp)
+	}
+	return nil
+}
+
+var validEvalAttrMatcher = regexp.MustCompile(`^\$(PLUG|SLOT)\(([a-z](?:-?[a-z0-9])*)\)$`)
+
+type evalAttrMatcher struct {
+	// first iteration supports only $(PLUG|SLOT)(attr)
+	op   string
+	attr string
+}
+
+func compileEvalAttrMatcher(context, s string) (attrMatcher, error) {
+	ops := validEvalAttrMatcher.FindStringSubmatch(s)
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("cannot compile %q constraint %q: not a valid $PLUG()/$SLOT() reference", context, s)
+	}
+	return evalAttrMatcher{op: ops[1], attr: ops[2]}, nil
+}
+
+func (matcher evalAttrMatcher) match(context string, v interface{}, ctx AttrMatchContext) error {
+	if ctx == nil {
+		return fmt.Errorf("attribute %q cannot be matched without context", context)
+	}
+	var comp func(string) (interface{}, error)
+	switch matcher.op {
+	case "PLUG":
+		comp = ctx.PlugAttr
+	case "SLOT":
+		comp = ctx.SlotAttr
+	}
+	v1, err := comp(matcher.attr)
+	if err != nil {
+		return fmt.Errorf("attribute %q does not match $%s(%s): %v", context, matcher.op, matcher.attr, err)
+	}
+	if !reflect.DeepEqual(v, v1) {
+		return fmt.Errorf("attribute %q does not match $%s(%s): %v != %v", context, matcher.op, matcher.attr, v, v1)
+	}
+	return nil
+}
+
+// rules
+
+var (
+	validSnapType  = regexp.MustCompile("^(?:core|app|kernel|gadget)$")
+	validSnapID    = regexp.MustCompile("^[a-z0-9A-Z]{32}$")
+	validAccountID = regexp.MustCompile("^(?:[a-z0-9A-Z]{32}|[-a-z0-9]{2,28})$")
+)
+
+func checkMapOrShortcut(context string, v interface{}) (m map[string]interface{}, invert bool, err error) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return x, false, nil
+	case string:
+		switch x {
+		case "true":
+			return nil, false, nil
+		case "false":
+			return nil, true, nil
+		}
+	}
+	return nil, false, fmt.Errorf("%s must be a map or one of the shortcuts 'true' or 'false'", context)
+}
+
+type constraintsHolder interface {
+	setAttributeConstraints(field string, cstrs *AttributeConstraints)
+	setIDConstraints(field string, cstrs []string)
+}
+
+func baseCompileConstraints(context string, cDef map[string]interface{}, invert bool, target constraintsHolder, attrConstraints, idConstraints []string) error {
+	for field := range cDef {
+		if !containsString(attrConstraints, field) && !containsString(idConstraints, field) {
+			return fmt.Errorf("%s has unsupported constraint %q", context, field)
+		}
+	}
+	for _, field := range idConstraints {
+		lst, err := checkStringListInMap(cDef, field, fmt.Sprintf("%s in %s", field, context))
+		if err != nil {
+			return err
+		}
+		if lst == nil {
+			continue
+		}
+		for _, elem := range lst {
+			if err := checkIDConstraint(field, elem); err != nil {
+				return fmt.Errorf("%s in %s contains an invalid element: %v", field, context, err)
+			}
+		}
+		target.setIDConstraints(field, lst)
+	}
+	for _, field := range attrConstraints {
+		cstrs := AlwaysMatchAttributes
+		if invert {
+			cstrs = NeverMatchAttributes
+		}
+		v := cDef[field]
+		if v != nil {
+			var err error
+			cstrs, err = compileAttributeConstraints(v)
+			if err != nil {
+				return fmt.Errorf("cannot compile %s in %s: %v", field, context, err)
+			}
+		}
+		target.setAttributeConstraints(field, cstrs)
+	}
+	return nil
+}
+
+func containsString(lst []string, s string) bool {
+	for _, elem := range lst {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
+
+func checkStringListInMap(m map[string]interface{}, name, what string) ([]string, error) {
+	value, ok := m[name]
+	if !ok {
+		return nil, nil
+	}
+	lst, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of strings", what)
+	}
+	if len(lst) == 0 {
+		return nil, nil
+	}
+	res := make([]string, len(lst))
+	for i, v := range lst {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of strings", what)
+		}
+		res[i] = s
+	}
+	return res, nil
+}
+
+func checkIDConstraint(field, elem string) error {
+	switch {
+	case strings.HasSuffix(field, "-snap-type"):
+		if !validSnapType.MatchString(elem) {
+			return fmt.Errorf("%q is not a valid snap type", elem)
+		}
+	case strings.HasSuffix(field, "-snap-id"):
+		if !validSnapID.MatchString(elem) {
+			return fmt.Errorf("%q is not a valid snap id", elem)
+		}
+	case strings.HasSuffix(field, "-publisher-id"):
+		// the publisher of the snap on the other side of the rule
+		if field == "slot-publisher-id" && elem == "$PLUG_PUBLISHER_ID" {
+			return nil
+		}
+		if field == "plug-publisher-id" && elem == "$SLOT_PUBLISHER_ID" {
+			return nil
+		}
+		if !validAccountID.MatchString(elem) {
+			return fmt.Errorf("%q is not a valid account id", elem)
+		}
+	}
+	return nil
+}
+
+type subruleCompiler func(context string, def interface{}) (interface{}, error)
+
+var ruleSubrules = []string{
+	"allow-installation",
+	"deny-installation",
+	"allow-connection",
+	"deny-connection",
+	"allow-auto-connection",
+	"deny-auto-connection",
+}
+
+func compileRule(context string, rule interface{}, compilers map[string]subruleCompiler, set func(subrule string, constraints interface{})) error {
+	rMap, ok := rule.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be a map", context)
+	}
+	if len(rMap) == 0 {
+		return fmt.Errorf("%s must specify at least one of %s", context, strings.Join(ruleSubrules, ", "))
+	}
+	for subrule := range rMap {
+		if compilers[subrule] == nil {
+			return fmt.Errorf("%s has unsupported subrule %q", context, subrule)
+		}
+	}
+	for _, subrule := range ruleSubrules {
+		v, ok := rMap[subrule]
+		if !ok {
+			continue
+		}
+		cstrs, err := compilers[subrule](fmt.Sprintf("%s in %s", subrule, context), v)
+		if err != nil {
+			return err
+		}
+		set(subrule, cstrs)
+	}
+	return nil
+}
+
+// PlugRule holds the rule of what is allowed, wrt installation and
+// connection, for a plug of a specific interface for a snap.
+//
+// A nil subrule means that the rule does not express anything about
+// the corresponding operation.
+type PlugRule struct {
+	Interface string
+
+	AllowInstallation *PlugInstallationConstraints
+	DenyInstallation  *PlugInstallationConstraints
+
+	AllowConnection *PlugConnectionConstraints
+	DenyConnection  *PlugConnectionConstraints
+
+	AllowAutoConnection *PlugConnectionConstraints
+	DenyAutoConnection  *PlugConnectionConstraints
+}
+
+// PlugInstallationConstraints specifies a set of constraints on an
+// interface plug relevant to the installation of snap.
+type PlugInstallationConstraints struct {
+	PlugSnapTypes []string
+
+	PlugAttributes *AttributeConstraints
+}
+
+func (c *PlugInstallationConstraints) setAttributeConstraints(field string, cstrs *AttributeConstraints) {
+	c.PlugAttributes = cstrs
+}
+
+func (c *PlugInstallationConstraints) setIDConstraints(field string, cstrs []string) {
+	c.PlugSnapTypes = cstrs
+}
+
+func compilePlugInstallationConstraints(context string, def interface{}) (interface{}, error) {
+	cDef, invert, err := checkMapOrShortcut(context, def)
+	if err != nil {
+		return nil, err
+	}
+	plugInstCstrs := &PlugInstallationConstraints{}
+	err = baseCompileConstraints(context, cDef, invert, plugInstCstrs, []string{"plug-attributes"}, []string{"plug-snap-type"})
+	if err != nil {
+		return nil, err
+	}
+	return plugInstCstrs, nil
+}
+
+// PlugConnectionConstraints specfies a set of constraints on an
+// interface plug relevant to its connection or auto-connection.
+type PlugConnectionConstraints struct {
+	SlotSnapTypes    []string
+	SlotSnapIDs      []string
+	SlotPublisherIDs []string
+
+	PlugAttributes *AttributeConstraints
+	SlotAttributes *AttributeConstraints
+}
+
+func (c *PlugConnectionConstraints) setAttributeConstraints(field string, cstrs *AttributeConstraints) {
+	switch field {
+	case "plug-attributes":
+		c.PlugAttributes = cstrs
+	case "slot-attributes":
+		c.SlotAttributes = cstrs
+	}
+}
+
+func (c *PlugConnectionConstraints) setIDConstraints(field string, cstrs []string) {
+	switch field {
+	case "slot-snap-type":
+		c.SlotSnapTypes = cstrs
+	case "slot-snap-id":
+		c.SlotSnapIDs = cstrs
+	case "slot-publisher-id":
+		c.SlotPublisherIDs = cstrs
+	}
+}
+
+func compilePlugConnectionConstraints(context string, def interface{}) (interface{}, error) {
+	cDef, invert, err := checkMapOrShortcut(context, def)
+	if err != nil {
+		return nil, err
+	}
+	plugConnCstrs := &PlugConnectionConstraints{}
+	err = baseCompileConstraints(context, cDef, invert, plugConnCstrs, []string{"plug-attributes", "slot-attributes"}, []string{"slot-snap-type", "slot-snap-id", "slot-publisher-id"})
+	if err != nil {
+		return nil, err
+	}
+	return plugConnCstrs, nil
+}
+
+var plugRuleCompilers = map[string]subruleCompiler{
+	"allow-installation":    compilePlugInstallationConstraints,
+	"deny-installation":     compilePlugInstallationConstraints,
+	"allow-connection":      compilePlugConnectionConstraints,
+	"deny-connection":       compilePlugConnectionConstraints,
+	"allow-auto-connection": compilePlugConnectionConstraints,
+	"deny-auto-connection":  compilePlugConnectionConstraints,
+}
+
+func compilePlugRule(interfaceName string, rule interface{}) (*PlugRule, error) {
+	context := fmt.Sprintf("plug rule for interface %q", interfaceName)
+	plugRule := &PlugRule{
+		Interface: interfaceName,
+	}
+	err := compileRule(context, rule, plugRuleCompilers, func(subrule string, cstrs interface{}) {
+		switch subrule {
+		case "allow-installation":
+			plugRule.AllowInstallation = cstrs.(*PlugInstallationConstraints)
+		case "deny-installation":
+			plugRule.DenyInstallation = cstrs.(*PlugInstallationConstraints)
+		case "allow-connection":
+			plugRule.AllowConnection = cstrs.(*PlugConnectionConstraints)
+		case "deny-connection":
+			plugRule.DenyConnection = cstrs.(*PlugConnectionConstraints)
+		case "allow-auto-connection":
+			plugRule.AllowAutoConnection = cstrs.(*PlugConnectionConstraints)
+		case "deny-auto-connection":
+			plugRule.DenyAutoConnection = cstrs.(*PlugConnectionConstraints)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plugRule, nil
+}
+
+// SlotRule holds the rule of what is allowed, wrt installation and
+// connection, for a slot of a specific interface for a snap.
+//
+// A nil subrule means that the rule does not express anything about
+// the corresponding operation.
+type SlotRule struct {
+	Interface string
+
+	AllowInstallation *SlotInstallationConstraints
+	DenyInstallation  *SlotInstallationConstraints
+
+	AllowConnection *SlotConnectionConstraints
+	DenyConnection  *SlotConnectionConstraints
+
+	AllowAutoConnection *SlotConnectionConstraints
+	DenyAutoConnection  *SlotConnectionConstraints
+}
+
+// SlotInstallationConstraints specifies a set of constraints on an
+// interface slot relevant to the installation of snap.
+type SlotInstallationConstraints struct {
+	SlotSnapTypes []string
+
+	SlotAttributes *AttributeConstraints
+}
+
+func (c *SlotInstallationConstraints) setAttributeConstraints(field string, cstrs *AttributeConstraints) {
+	c.SlotAttributes = cstrs
+}
+
+func (c *SlotInstallationConstraints) setIDConstraints(field string, cstrs []string) {
+	c.SlotSnapTypes = cstrs
+}
+
+func compileSlotInstallationConstraints(context string, def interface{}) (interface{}, error) {
+	cDef, invert, err := checkMapOrShortcut(context, def)
+	if err != nil {
+		return nil, err
+	}
+	slotInstCstrs := &SlotInstallationConstraints{}
+	err = baseCompileConstraints(context, cDef, invert, slotInstCstrs, []string{"slot-attributes"}, []string{"slot-snap-type"})
+	if err != nil {
+		return nil, err
+	}
+	return slotInstCstrs, nil
+}
+
+// SlotConnectionConstraints specfies a set of constraints on an
+// interface slot relevant to its connection or auto-connection.
+type SlotConnectionConstraints struct {
+	PlugSnapTypes    []string
+	PlugSnapIDs      []string
+	PlugPublisherIDs []string
+
+	SlotAttributes *AttributeConstraints
+	PlugAttributes *AttributeConstraints
+}
+
+func (c *SlotConnectionConstraints) setAttributeConstraints(field string, cstrs *AttributeConstraints) {
+	switch field {
+	case "plug-attributes":
+		c.PlugAttributes = cstrs
+	case "slot-attributes":
+		c.SlotAttributes = cstrs
+	}
+}
+
+func (c *SlotConnectionConstraints) setIDConstraints(field string, cstrs []string) {
+	switch field {
+	case "plug-snap-type":
+		c.PlugSnapTypes = cstrs
+	case "plug-snap-id":
+		c.PlugSnapIDs = cstrs
+	case "plug-publisher-id":
+		c.PlugPublisherIDs = cstrs
+	}
+}
+
+func compileSlotConnectionConstraints(context string, def interface{}) (interface{}, error) {
+	cDef, invert, err := checkMapOrShortcut(context, def)
+	if err != nil {
+		return nil, err
+	}
+	slotConnCstrs := &SlotConnectionConstraints{}
+	err = baseCompileConstraints(context, cDef, invert, slotConnCstrs, []string{"plug-attributes", "slot-attributes"}, []string{"plug-snap-type", "plug-snap-id", "plug-publisher-id"})
+	if err != nil {
+		return nil, err
+	}
+	return slotConnCstrs, nil
+}
+
+var slotRuleCompilers = map[string]subruleCompiler{
+	"allow-installation":    compileSlotInstallationConstraints,
+	"deny-installation":     compileSlotInstallationConstraints,
+	"allow-connection":      compileSlotConnectionConstraints,
+	"deny-connection":       compileSlotConnectionConstraints,
+	"allow-auto-connection": compileSlotConnectionConstraints,
+	"deny-auto-connection":  compileSlotConnectionConstraints,
+}
+
+func compileSlotRule(interfaceName string, rule interface{}) (*SlotRule, error) {
+	context := fmt.Sprintf("slot rule for interface %q", interfaceName)
+	slotRule := &SlotRule{
+		Interface: interfaceName,
+	}
+	err := compileRule(context, rule, slotRuleCompilers, func(subrule string, cstrs interface{}) {
+		switch subrule {
+		case "allow-installation":
+			slotRule.AllowInstallation = cstrs.(*SlotInstallationConstraints)
+		case "deny-installation":
+			slotRule.DenyInstallation = cstrs.(*SlotInstallationConstraints)
+		case "allow-connection":
+			slotRule.AllowConnection = cstrs.(*SlotConnectionConstraints)
+		case "deny-connection":
+			slotRule.DenyConnection = cstrs.(*SlotConnectionConstraints)
+		case "allow-auto-connection":
+			slotRule.AllowAutoConnection = cstrs.(*SlotConnectionConstraints)
+		case "deny-auto-connection":
+			slotRule.DenyAutoConnection = cstrs.(*SlotConnectionConstraints)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slotRule, nil
+}
+
+// checkPlugsSlots checks and compiles the optional plugs and slots
+// headers of snap-declaration and base-declaration assertions.
+func checkPlugsSlots(headers map[string]interface{}) (plugRules map[string]*PlugRule, slotRules map[string]*SlotRule, err error) {
+	plugs, err := checkOptionalMap(headers, "plugs")
+	if err != nil {
+		return nil, nil, err
+	}
+	if plugs != nil {
+		plugRules = make(map[string]*PlugRule, len(plugs))
+		for iface, rule := range plugs {
+			plugRule, err := compilePlugRule(iface, rule)
+			if err != nil {
+				return nil, nil, err
+			}
+			plugRules[iface] = plugRule
+		}
+	}
+
+	slots, err := checkOptionalMap(headers, "slots")
+	if err != nil {
+		return nil, nil, err
+	}
+	if slots != nil {
+		slotRules = make(map[string]*SlotRule, len(slots))
+		for iface, rule := range slots {
+			slotRule, err := compileSlotRule(iface, rule)
+			if err != nil {
+				return nil, nil, err
+			}
+			slotRules[iface] = slotRule
+		}
+	}
+
+	return plugRules, slotRules, nil
+}
+
+// BaseDeclaration holds a base-declaration assertion, declaring the
+// policy about plugs and slots of interfaces that applies to all
+// snaps unless overridden by the rules in their snap-declarations.
+type BaseDeclaration struct {
+	assertionBase
+	plugRules map[string]*PlugRule
+	slotRules map[string]*SlotRule
+	timestamp time.Time
+}
+
+// Series returns the series whose snaps are governed by the declaration.
+func (basedcl *BaseDeclaration) Series() string {
+	return basedcl.HeaderString("series")
+}
+
+// Timestamp returns the time when the base-declaration was issued.
+func (basedcl *BaseDeclaration) Timestamp() time.Time {
+	return basedcl.timestamp
+}
+
+// PlugRule returns the plug-side rule about the given interface if one was included in the plugs stanza of the declaration, otherwise it returns nil.
+func (basedcl *BaseDeclaration) PlugRule(interfaceName string) *PlugRule {
+	return basedcl.plugRules[interfaceName]
+}
+
+// SlotRule returns the slot-side rule about the given interface if one was included in the slots stanza of the declaration, otherwise it returns nil.
+func (basedcl *BaseDeclaration) SlotRule(interfaceName string) *SlotRule {
+	return basedcl.slotRules[interfaceName]
+}
+
+// Implement further consistency checks.
+func (basedcl *BaseDeclaration) checkConsistency(db RODatabase, acck *AccountKey) error {
+	// XXX: not signed or stored yet in a db, but being ready for that
+	if !db.IsTrustedAccount(basedcl.AuthorityID()) {
+		return fmt.Errorf("base-declaration assertion for series %s is not signed by a directly trusted authority: %s", basedcl.Series(), basedcl.AuthorityID())
+	}
+	return nil
+}
+
+// sanity
+var _ consistencyChecker = (*BaseDeclaration)(nil)
+
+func assembleBaseDeclaration(assert assertionBase) (Assertion, error) {
+	plugRules, slotRules, err := checkPlugsSlots(assert.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := checkRFC3339Date(assert.headers, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &BaseDeclaration{
+		assertionBase: assert,
+		plugRules:     plugRules,
+		slotRules:     slotRules,
+		timestamp:     timestamp,
+	}, nil
+}
+
+var builtinBaseDeclaration *BaseDeclaration
+
+// BuiltinBaseDeclaration exposes the initialized builtin base-declaration assertion. This is used by overlord/assertstate, other code should use assertstate.BaseDeclaration.
+func BuiltinBaseDeclaration() *BaseDeclaration {
+	return builtinBaseDeclaration
+}
+
+var (
+	builtinBaseDeclarationCheckOrder      = []string{"type", "authority-id", "series"}
+	builtinBaseDeclarationExpectedHeaders = map[string]interface{}{
+		"type":         "base-declaration",
+		"authority-id": "canonical",
+		"series":       release.Series,
+	}
+)
+
+// InitBuiltinBaseDeclaration initializes the builtin base-declaration based on headers (or resets it if headers is nil).
+func InitBuiltinBaseDeclaration(headers []byte) error {
+	if headers == nil {
+		builtinBaseDeclaration = nil
+		return nil
+	}
+	trimmed := bytes.TrimSpace(headers)
+	h, err := parseHeaders(trimmed)
+	if err != nil {
+		return err
+	}
+	for _, name := range builtinBaseDeclarationCheckOrder {
+		expected := builtinBaseDeclarationExpectedHeaders[name]
+		if h[name] != expected {
+			return fmt.Errorf("the builtin base-declaration %q header is not set to expected value %q", name, expected)
+		}
+	}
+	revision, err := checkRevision(h)
+	if err != nil {
+		return fmt.Errorf("cannot assemble the builtin base-declaration: %v", err)
+	}
+	h["timestamp"] = time.Now().UTC().Format(time.RFC3339)
+	a, err := assembleBaseDeclaration(assertionBase{
+		headers:   h,
+		body:      nil,
+		revision:  revision,
+		content:   trimmed,
+		signature: []byte("$builtin"),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot assemble the builtin base-declaration: %v", err)
+	}
+	builtinBaseDeclaration = a.(*BaseDeclaration)
+	return nil
+}
@@ -0,0 +1,463 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+var (
+	_ = Suite(&attrConstraintsSuite{})
+	_ = Suite(&plugSlotRulesSuite{})
+)
+
+type attrConstraintsSuite struct{}
+
+func attrs(yml string) map[string]interface{} {
+	h, err := asserts.ParseHeaders([]byte(strings.TrimSpace(yml)))
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+func (s *attrConstraintsSuite) TestSimple(c *C) {
+	m := attrs(`
+attrs:
+  foo: FOO
+  bar: BAR`)
+
+	cstrs, err := asserts.CompileAttributeConstraints(m["attrs"])
+	c.Assert(err, IsNil)
+
+	err = cstrs.Check(map[string]interface{}{
+		"foo": "FOO",
+		"bar": "BAR",
+		"baz": "BAZ",
+	}, nil)
+	c.Check(err, IsNil)
+
+	err = cstrs.Check(map[string]interface{}{
+		"foo": "FOO",
+		"bar": "BAZ",
+	}, nil)
+	c.Check(err, ErrorMatches, `attribute "bar" value "BAZ" does not match \^\(BAR\)\$`)
+
+	err = cstrs.Check(map[string]interface{}{
+		"foo": "FOO",
+	}, nil)
+	c.Check(err, ErrorMatches, `attribute "bar" has constraints but is unset`)
+}
+
+func (s *attrConstraintsSuite) TestNested(c *C) {
+	m := attrs(`
+attrs:
+  foo: FOO
+  bar:
+    bar1: BAR1
+    bar2: BAR[22]`)
+
+	cstrs, err := asserts.CompileAttributeConstraints(m["attrs"])
+	c.Assert(err, IsNil)
+
+	err = cstrs.Check(attrs(`
+foo: FOO
+bar:
+  bar1: BAR1
+  bar2: BAR2
+  bar3: BAR3
+`), nil)
+	c.Check(err, IsNil)
+
+	err = cstrs.Check(attrs(`
+foo: FOO
+bar: BAZ
+`), nil)
+	c.Check(err, ErrorMatches, `attribute "bar" must be a map`)
+
+	err = cstrs.Check(attrs(`
+foo: FOO
+bar:
+  bar1: BAR1
+  bar2: BAR3
+`), nil)
+	c.Check(err, ErrorMatches, `attribute "bar\.bar2" value "BAR3" does not match \^\(BAR\[22\]\)\$`)
+}
+
+func (s *attrConstraintsSuite) TestAlternativeMatchingList(c *C) {
+	m := attrs(`
+attrs:
+  foo: /foo/.*|/bar`)
+
+	cstrs, err := asserts.CompileAttributeConstraints(m["attrs"])
+	c.Assert(err, IsNil)
+
+	err = cstrs.Check(map[string]interface{}{
+		"foo": []interface{}{"/foo/1", "/bar"},
+	}, nil)
+	c.Check(err, IsNil)
+
+	err = cstrs.Check(map[string]interface{}{
+		"foo": []interface{}{"/foo/1", "/baz"},
+	}, nil)
+	c.Check(err, ErrorMatches, `attribute "foo" value "/baz" does not match .*`)
+}
+
+func (s *attrConstraintsSuite) TestOtherScalars(c *C) {
+	m := attrs(`
+attrs:
+  foo: 1
+  bar: true`)
+
+	cstrs, err := asserts.CompileAttributeConstraints(m["attrs"])
+	c.Assert(err, IsNil)
+
+	err = cstrs.Check(map[string]interface{}{
+		"foo": int64(1),
+		"bar": true,
+	}, nil)
+	c.Check(err, IsNil)
+
+	err = cstrs.Check(map[string]interface{}{
+		"foo": int64(1),
+		"bar": map[string]interface{}{"x": "y"},
+	}, nil)
+	c.Check(err, ErrorMatches, `attribute "bar" must be a scalar or a list of scalars`)
+}
+
+type testAttrMatchContext struct {
+	plug, slot map[string]interface{}
+}
+
+func (ctx *testAttrMatchContext) PlugAttr(attr string) (interface{}, error) {
+	v, ok := ctx.plug[attr]
+	if !ok {
+		return nil, fmt.Errorf("plug attribute %q not found", attr)
+	}
+	return v, nil
+}
+
+func (ctx *testAttrMatchContext) SlotAttr(attr string) (interface{}, error) {
+	v, ok := ctx.slot[attr]
+	if !ok {
+		return nil, fmt.Errorf("slot attribute %q not found", attr)
+	}
+	return v, nil
+}
+
+func (s *attrConstraintsSuite) TestEvalReferences(c *C) {
+	m := attrs(`
+attrs:
+  content: $SLOT(content)`)
+
+	cstrs, err := asserts.CompileAttributeConstraints(m["attrs"])
+	c.Assert(err, IsNil)
+
+	ctx := &testAttrMatchContext{slot: map[string]interface{}{"content": "mylib"}}
+	err = cstrs.Check(map[string]interface{}{"content": "mylib"}, ctx)
+	c.Check(err, IsNil)
+
+	err = cstrs.Check(map[string]interface{}{"content": "otherlib"}, ctx)
+	c.Check(err, ErrorMatches, `attribute "content" does not match \$SLOT\(content\): otherlib != mylib`)
+
+	err = cstrs.Check(map[string]interface{}{"content": "mylib"}, &testAttrMatchContext{})
+	c.Check(err, ErrorMatches, `attribute "content" does not match \$SLOT\(content\): slot attribute "content" not found`)
+
+	err = cstrs.Check(map[string]interface{}{"content": "mylib"}, nil)
+	c.Check(err, ErrorMatches, `attribute "content" cannot be matched without context`)
+}
+
+func (s *attrConstraintsSuite) TestCompileErrors(c *C) {
+	_, err := asserts.CompileAttributeConstraints(attrs(`
+foo: $FOO()`))
+	c.Check(err, ErrorMatches, `cannot compile "foo" constraint "\$FOO\(\)": not a valid \$PLUG\(\)/\$SLOT\(\) reference`)
+
+	_, err = asserts.CompileAttributeConstraints(attrs(`
+foo: [`))
+	c.Check(err, ErrorMatches, `cannot compile "foo" constraint "\[": .*`)
+
+	_, err = asserts.CompileAttributeConstraints(attrs(`
+foo:
+  - a`))
+	c.Check(err, ErrorMatches, `constraint "foo" must be a key-value map or a regexp`)
+
+	_, err = asserts.CompileAttributeConstraints("foo")
+	c.Check(err, ErrorMatches, `attribute constraints must be a map`)
+}
+
+func (s *attrConstraintsSuite) TestAlwaysNever(c *C) {
+	c.Check(asserts.AlwaysMatchAttributes.Check(nil, nil), IsNil)
+	c.Check(asserts.NeverMatchAttributes.Check(nil, nil), NotNil)
+}
+
+type plugSlotRulesSuite struct{}
+
+func (s *plugSlotRulesSuite) TestCompilePlugRuleAllAllowDenyStanzas(c *C) {
+	m := attrs(`
+iface:
+  allow-installation:
+    plug-attributes:
+      a1: A1
+  deny-installation:
+    plug-snap-type:
+      - kernel
+  allow-connection:
+    slot-snap-type:
+      - core
+    slot-publisher-id:
+      - $PLUG_PUBLISHER_ID
+  deny-connection:
+    slot-snap-id:
+      - snapidsnapidsnapidsnapidsnapid01
+  allow-auto-connection:
+    slot-attributes:
+      s1: S1
+  deny-auto-connection: true
+`)
+
+	rule, err := asserts.CompilePlugRule("iface", m["iface"])
+	c.Assert(err, IsNil)
+
+	c.Check(rule.Interface, Equals, "iface")
+	c.Check(rule.AllowInstallation.PlugAttributes.Check(map[string]interface{}{"a1": "A1"}, nil), IsNil)
+	c.Check(rule.AllowInstallation.PlugSnapTypes, IsNil)
+	c.Check(rule.DenyInstallation.PlugSnapTypes, DeepEquals, []string{"kernel"})
+	c.Check(rule.DenyInstallation.PlugAttributes, Equals, asserts.AlwaysMatchAttributes)
+	c.Check(rule.AllowConnection.SlotSnapTypes, DeepEquals, []string{"core"})
+	c.Check(rule.AllowConnection.SlotPublisherIDs, DeepEquals, []string{"$PLUG_PUBLISHER_ID"})
+	c.Check(rule.DenyConnection.SlotSnapIDs, DeepEquals, []string{"snapidsnapidsnapidsnapidsnapid01"})
+	c.Check(rule.AllowAutoConnection.SlotAttributes.Check(map[string]interface{}{"s1": "S1"}, nil), IsNil)
+	c.Check(rule.AllowAutoConnection.PlugAttributes, Equals, asserts.AlwaysMatchAttributes)
+	c.Check(rule.DenyAutoConnection.PlugAttributes, Equals, asserts.AlwaysMatchAttributes)
+	c.Check(rule.DenyAutoConnection.SlotAttributes, Equals, asserts.AlwaysMatchAttributes)
+}
+
+func (s *plugSlotRulesSuite) TestCompilePlugRuleShortcuts(c *C) {
+	m := attrs(`
+iface:
+  allow-connection: false
+  allow-auto-connection: true
+`)
+
+	rule, err := asserts.CompilePlugRule("iface", m["iface"])
+	c.Assert(err, IsNil)
+
+	c.Check(rule.AllowInstallation, IsNil)
+	c.Check(rule.DenyInstallation, IsNil)
+	c.Check(rule.AllowConnection.PlugAttributes, Equals, asserts.NeverMatchAttributes)
+	c.Check(rule.AllowConnection.SlotAttributes, Equals, asserts.NeverMatchAttributes)
+	c.Check(rule.DenyConnection, IsNil)
+	c.Check(rule.AllowAutoConnection.PlugAttributes, Equals, asserts.AlwaysMatchAttributes)
+	c.Check(rule.DenyAutoConnection, IsNil)
+}
+
+func (s *plugSlotRulesSuite) TestCompileSlotRuleAllAllowDenyStanzas(c *C) {
+	m := attrs(`
+iface:
+  allow-installation:
+    slot-snap-type:
+      - gadget
+  deny-installation: false
+  allow-connection:
+    plug-attributes:
+      p1: P1
+  deny-connection:
+    plug-snap-type:
+      - app
+  allow-auto-connection:
+    plug-publisher-id:
+      - $SLOT_PUBLISHER_ID
+      - canonical
+    plug-snap-id:
+      - snapidsnapidsnapidsnapidsnapid01
+  deny-auto-connection: true
+`)
+
+	rule, err := asserts.CompileSlotRule("iface", m["iface"])
+	c.Assert(err, IsNil)
+
+	c.Check(rule.Interface, Equals, "iface")
+	c.Check(rule.AllowInstallation.SlotSnapTypes, DeepEquals, []string{"gadget"})
+	c.Check(rule.DenyInstallation.SlotAttributes, Equals, asserts.NeverMatchAttributes)
+	c.Check(rule.AllowConnection.PlugAttributes.Check(map[string]interface{}{"p1": "P1"}, nil), IsNil)
+	c.Check(rule.DenyConnection.PlugSnapTypes, DeepEquals, []string{"app"})
+	c.Check(rule.AllowAutoConnection.PlugPublisherIDs, DeepEquals, []string{"$SLOT_PUBLISHER_ID", "canonical"})
+	c.Check(rule.AllowAutoConnection.PlugSnapIDs, DeepEquals, []string{"snapidsnapidsnapidsnapidsnapid01"})
+	c.Check(rule.DenyAutoConnection.SlotAttributes, Equals, asserts.AlwaysMatchAttributes)
+}
+
+func (s *plugSlotRulesSuite) TestCompilePlugRuleErrors(c *C) {
+	tests := []struct {
+		stanza string
+		err    string
+	}{
+		{`iface: foo`, `plug rule for interface "iface" must be a map`},
+		{`iface:
+  allow-installation: foo`, `allow-installation in plug rule for interface "iface" must be a map or one of the shortcuts 'true' or 'false'`},
+		{`iface:
+  allow-nothing: true`, `plug rule for interface "iface" has unsupported subrule "allow-nothing"`},
+		{`iface:
+  allow-installation:
+    slot-snap-type:
+      - core`, `allow-installation in plug rule for interface "iface" has unsupported constraint "slot-snap-type"`},
+		{`iface:
+  allow-connection:
+    slot-snap-type: foo`, `slot-snap-type in allow-connection in plug rule for interface "iface" must be a list of strings`},
+		{`iface:
+  allow-connection:
+    slot-snap-type:
+      - foo`, `slot-snap-type in allow-connection in plug rule for interface "iface" contains an invalid element: "foo" is not a valid snap type`},
+		{`iface:
+  allow-connection:
+    slot-snap-id:
+      - foo`, `slot-snap-id in allow-connection in plug rule for interface "iface" contains an invalid element: "foo" is not a valid snap id`},
+		{`iface:
+  allow-connection:
+    slot-publisher-id:
+      - $SLOT_PUBLISHER_ID`, `slot-publisher-id in allow-connection in plug rule for interface "iface" contains an invalid element: "\$SLOT_PUBLISHER_ID" is not a valid account id`},
+		{`iface:
+  allow-connection:
+    plug-attributes: foo`, `cannot compile plug-attributes in allow-connection in plug rule for interface "iface": attribute constraints must be a map`},
+	}
+
+	for _, t := range tests {
+		m := attrs(t.stanza)
+		_, err := asserts.CompilePlugRule("iface", m["iface"])
+		c.Check(err, ErrorMatches, t.err, Commentf(t.stanza))
+	}
+}
+
+func (s *plugSlotRulesSuite) TestCompileSlotRuleErrors(c *C) {
+	tests := []struct {
+		stanza string
+		err    string
+	}{
+		{`iface: foo`, `slot rule for interface "iface" must be a map`},
+		{`iface:
+  deny-connection:
+    slot-snap-type:
+      - core`, `deny-connection in slot rule for interface "iface" has unsupported constraint "slot-snap-type"`},
+		{`iface:
+  allow-auto-connection:
+    plug-publisher-id:
+      - $PLUG_PUBLISHER_ID`, `plug-publisher-id in allow-auto-connection in slot rule for interface "iface" contains an invalid element: "\$PLUG_PUBLISHER_ID" is not a valid account id`},
+	}
+
+	for _, t := range tests {
+		m := attrs(t.stanza)
+		_, err := asserts.CompileSlotRule("iface", m["iface"])
+		c.Check(err, ErrorMatches, t.err, Commentf(t.stanza))
+	}
+}
+
+type baseDeclSuite struct{}
+
+var _ = Suite(&baseDeclSuite{})
+
+func (s *baseDeclSuite) TestDecodeOK(c *C) {
+	encoded := `type: base-declaration
+authority-id: canonical
+series: 16
+plugs:
+  interface1:
+    deny-installation: false
+    allow-auto-connection:
+      slot-snap-type:
+        - app
+  interface2:
+    allow-connection: true
+slots:
+  interface3:
+    deny-auto-connection: true
+timestamp: 2016-09-29T19:50:49Z
+sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij
+
+AXNpZw==`
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.BaseDeclarationType)
+	baseDecl := a.(*asserts.BaseDeclaration)
+	c.Check(baseDecl.Series(), Equals, "16")
+	c.Check(baseDecl.Timestamp().Format(time.RFC3339), Equals, "2016-09-29T19:50:49Z")
+
+	plug1 := baseDecl.PlugRule("interface1")
+	c.Assert(plug1, NotNil)
+	c.Check(plug1.AllowAutoConnection.SlotSnapTypes, DeepEquals, []string{"app"})
+	c.Check(baseDecl.PlugRule("interface2").AllowConnection, NotNil)
+	c.Check(baseDecl.SlotRule("interface3").DenyAutoConnection, NotNil)
+	c.Check(baseDecl.SlotRule("interface1"), IsNil)
+}
+
+func (s *baseDeclSuite) TestBuiltin(c *C) {
+	// nothing in asserts sets up the builtin base-declaration
+	c.Assert(asserts.BuiltinBaseDeclaration(), IsNil)
+	defer asserts.InitBuiltinBaseDeclaration(nil)
+
+	headers := []byte(`
+type: base-declaration
+authority-id: canonical
+series: 16
+revision: 0
+plugs:
+  network:
+    allow-connection: true
+slots:
+  network:
+    allow-auto-connection: true
+`)
+	err := asserts.InitBuiltinBaseDeclaration(headers)
+	c.Assert(err, IsNil)
+
+	builtin := asserts.BuiltinBaseDeclaration()
+	c.Assert(builtin, NotNil)
+	c.Check(builtin.AuthorityID(), Equals, "canonical")
+	c.Check(builtin.Series(), Equals, "16")
+	c.Check(builtin.PlugRule("network").AllowConnection, NotNil)
+	c.Check(builtin.SlotRule("network").AllowAutoConnection, NotNil)
+
+	err = asserts.InitBuiltinBaseDeclaration(nil)
+	c.Assert(err, IsNil)
+	c.Check(asserts.BuiltinBaseDeclaration(), IsNil)
+}
+
+func (s *baseDeclSuite) TestBuiltinErrors(c *C) {
+	defer asserts.InitBuiltinBaseDeclaration(nil)
+
+	tests := []struct {
+		headers string
+		err     string
+	}{
+		{"type: snap-declaration\nauthority-id: canonical\nseries: 16\n", `the builtin base-declaration "type" header is not set to expected value "base-declaration"`},
+		{"type: base-declaration\nauthority-id: acme\nseries: 16\n", `the builtin base-declaration "authority-id" header is not set to expected value "canonical"`},
+		{"type: base-declaration\nauthority-id: canonical\nseries: 12\n", `the builtin base-declaration "series" header is not set to expected value "16"`},
+		{"type: base-declaration\nauthority-id: canonical\nseries: 16\nplugs: foo\n", `cannot assemble the builtin base-declaration: "plugs" header must be a map`},
+	}
+
+	for _, t := range tests {
+		err := asserts.InitBuiltinBaseDeclaration([]byte(t.headers))
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
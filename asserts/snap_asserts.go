@@ -36,6 +36,9 @@ import (
 type SnapDeclaration struct {
 	assertionBase
 	refreshControl []string
+	allowClassic   bool
+	plugRules      map[string]*PlugRule
+	slotRules      map[string]*SlotRule
 	timestamp      time.Time
 }
 
@@ -69,6 +72,21 @@ func (snapdcl *SnapDeclaration) RefreshControl() []string {
 	return snapdcl.refreshControl
 }
 
+// AllowClassic returns whether the snap is permitted to be installed with classic confinement.
+func (snapdcl *SnapDeclaration) AllowClassic() bool {
+	return snapdcl.allowClassic
+}
+
+// PlugRule returns the plug-side rule about the given interface if one was included in the plugs stanza of the declaration, otherwise it returns nil.
+func (snapdcl *SnapDeclaration) PlugRule(interfaceName string) *PlugRule {
+	return snapdcl.plugRules[interfaceName]
+}
+
+// SlotRule returns the slot-side rule about the given interface if one was included in the slots stanza of the declaration, otherwise it returns nil.
+func (snapdcl *SnapDeclaration) SlotRule(interfaceName string) *SlotRule {
+	return snapdcl.slotRules[interfaceName]
+}
+
 // Implement further consistency checks.
 func (snapdcl *SnapDeclaration) checkConsistency(db RODatabase, acck *AccountKey) error {
 	if !db.IsTrustedAccount(snapdcl.AuthorityID()) {
@@ -118,10 +136,23 @@ func assembleSnapDeclaration(assert assertionBase) (Assertion, error) {
 		return nil, err
 	}
 
+	allowClassic, err := checkOptionalBool(assert.headers, "allow-classic")
+	if err != nil {
+		return nil, err
+	}
+
+	plugRules, slotRules, err := checkPlugsSlots(assert.headers)
+	if err != nil {
+		return nil, err
+	}
+
 	return &SnapDeclaration{
 		assertionBase:  assert,
 		timestamp:      timestamp,
 		refreshControl: refControl,
+		allowClassic:   allowClassic,
+		plugRules:      plugRules,
+		slotRules:      slotRules,
 	}, nil
 }
 
@@ -75,6 +75,70 @@ func (sds *snapDeclSuite) TestDecodeOK(c *C) {
 	c.Check(snapDecl.SnapName(), Equals, "first")
 	c.Check(snapDecl.PublisherID(), Equals, "dev-id1")
 	c.Check(snapDecl.RefreshControl(), DeepEquals, []string{"foo", "bar"})
+	c.Check(snapDecl.AllowClassic(), Equals, false)
+}
+
+func (sds *snapDeclSuite) TestDecodeOKAllowClassic(c *C) {
+	encoded := "type: snap-declaration\n" +
+		"authority-id: canonical\n" +
+		"series: 16\n" +
+		"snap-id: snap-id-1\n" +
+		"snap-name: first\n" +
+		"publisher-id: dev-id1\n" +
+		"allow-classic: true\n" +
+		sds.tsLine +
+		"body-length: 0\n" +
+		"sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij" +
+		"\n\n" +
+		"AXNpZw=="
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	snapDecl := a.(*asserts.SnapDeclaration)
+	c.Check(snapDecl.AllowClassic(), Equals, true)
+}
+
+func (sds *snapDeclSuite) TestDecodeOKWithPlugsSlots(c *C) {
+	encoded := "type: snap-declaration\n" +
+		"authority-id: canonical\n" +
+		"series: 16\n" +
+		"snap-id: snap-id-1\n" +
+		"snap-name: first\n" +
+		"publisher-id: dev-id1\n" +
+		"plugs:\n  interface1:\n    deny-installation: false\n    allow-auto-connection:\n      slot-snap-type:\n        - app\n      slot-publisher-id:\n        - acme\n      slot-attributes:\n        a1: /foo/.*\n      plug-attributes:\n        b1: B1\n  interface2:\n    allow-connection: true\n" +
+		"slots:\n  interface3:\n    deny-auto-connection: true\n" +
+		sds.tsLine +
+		"body-length: 0\n" +
+		"sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij" +
+		"\n\n" +
+		"AXNpZw=="
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	snapDecl := a.(*asserts.SnapDeclaration)
+	c.Check(snapDecl.SnapID(), Equals, "snap-id-1")
+
+	c.Check(snapDecl.PlugRule("interfaceX"), IsNil)
+	c.Check(snapDecl.SlotRule("interfaceX"), IsNil)
+
+	plug1 := snapDecl.PlugRule("interface1")
+	c.Assert(plug1, NotNil)
+	c.Check(plug1.DenyInstallation.PlugAttributes, Equals, asserts.NeverMatchAttributes)
+	c.Check(plug1.AllowAutoConnection.SlotSnapTypes, DeepEquals, []string{"app"})
+	c.Check(plug1.AllowAutoConnection.SlotPublisherIDs, DeepEquals, []string{"acme"})
+	c.Check(plug1.AllowAutoConnection.SlotAttributes.Check(map[string]interface{}{
+		"a1": "/foo/bar",
+	}, nil), IsNil)
+	c.Check(plug1.AllowAutoConnection.PlugAttributes.Check(map[string]interface{}{
+		"b1": "B2",
+	}, nil), NotNil)
+
+	plug2 := snapDecl.PlugRule("interface2")
+	c.Assert(plug2, NotNil)
+	c.Check(plug2.AllowConnection.PlugAttributes, Equals, asserts.AlwaysMatchAttributes)
+	c.Check(plug2.AllowAutoConnection, IsNil)
+
+	slot3 := snapDecl.SlotRule("interface3")
+	c.Assert(slot3, NotNil)
+	c.Check(slot3.DenyAutoConnection.PlugAttributes, Equals, asserts.AlwaysMatchAttributes)
 }
 
 func (sds *snapDeclSuite) TestEmptySnapName(c *C) {
@@ -144,6 +208,11 @@ func (sds *snapDeclSuite) TestDecodeInvalid(c *C) {
 		{sds.tsLine, "", `"timestamp" header is mandatory`},
 		{sds.tsLine, "timestamp: \n", `"timestamp" header should not be empty`},
 		{sds.tsLine, "timestamp: 12:30\n", `"timestamp" header is not a RFC3339 date: .*`},
+		{"refresh-control:\n  - foo\n  - bar\n", "allow-classic: maybe\n", `"allow-classic" header must be 'true' or 'false'`},
+		{"refresh-control:\n  - foo\n  - bar\n", "plugs: foo\n", `"plugs" header must be a map`},
+		{"refresh-control:\n  - foo\n  - bar\n", "plugs:\n  iface: foo\n", `plug rule for interface "iface" must be a map`},
+		{"refresh-control:\n  - foo\n  - bar\n", "slots: foo\n", `"slots" header must be a map`},
+		{"refresh-control:\n  - foo\n  - bar\n", "slots:\n  iface:\n    allow-installation: maybe\n", `allow-installation in slot rule for interface "iface" must be a map or one of the shortcuts 'true' or 'false'`},
 	}
 
 	for _, test := range invalidTests {
@@ -0,0 +1,71 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// AliasStatus represents the status of an alias of a snap app.
+type AliasStatus struct {
+	App    string `json:"app"`
+	Status string `json:"status"` // "enabled" or "disabled"
+}
+
+type aliasAction struct {
+	Action  string   `json:"action"`
+	Snap    string   `json:"snap"`
+	Aliases []string `json:"aliases"`
+}
+
+func (client *Client) performAliasAction(action *aliasAction) (changeID string, err error) {
+	b, err := json.Marshal(action)
+	if err != nil {
+		return "", err
+	}
+
+	return client.doAsync("POST", "/v2/aliases", nil, nil, bytes.NewReader(b))
+}
+
+// Alias enables the given aliases declared by the apps of the snap.
+func (client *Client) Alias(snapName string, aliases []string) (changeID string, err error) {
+	return client.performAliasAction(&aliasAction{
+		Action:  "alias",
+		Snap:    snapName,
+		Aliases: aliases,
+	})
+}
+
+// Unalias disables the given enabled aliases of the snap.
+func (client *Client) Unalias(snapName string, aliases []string) (changeID string, err error) {
+	return client.performAliasAction(&aliasAction{
+		Action:  "unalias",
+		Snap:    snapName,
+		Aliases: aliases,
+	})
+}
+
+// Aliases returns the status of the aliases declared by the installed
+// snaps, keyed by snap and then by alias name.
+func (client *Client) Aliases() (allStatuses map[string]map[string]AliasStatus, err error) {
+	_, err = client.doSync("GET", "/v2/aliases", nil, nil, nil, &allStatuses)
+	return allStatuses, err
+}
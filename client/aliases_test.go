@@ -0,0 +1,73 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientAliases(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": {
+		"foo": {
+			"foo0": {"app": "foo", "status": "enabled"},
+			"foo_reset": {"app": "foo.reset", "status": "disabled"}
+		}
+	}}`
+	allStatuses, err := cs.cli.Aliases()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/aliases")
+	c.Check(allStatuses, check.DeepEquals, map[string]map[string]client.AliasStatus{
+		"foo": {
+			"foo0":      {App: "foo", Status: "enabled"},
+			"foo_reset": {App: "foo.reset", Status: "disabled"},
+		},
+	})
+}
+
+func (cs *clientSuite) TestClientAliasActions(c *check.C) {
+	for _, t := range []struct {
+		do     func() (string, error)
+		action string
+	}{
+		{func() (string, error) { return cs.cli.Alias("foo", []string{"foo0", "foo_reset"}) }, "alias"},
+		{func() (string, error) { return cs.cli.Unalias("foo", []string{"foo0", "foo_reset"}) }, "unalias"},
+	} {
+		cs.status = http.StatusAccepted
+		cs.rsp = `{"type": "async", "status-code": 202, "change": "chg"}`
+		id, err := t.do()
+		c.Assert(err, check.IsNil)
+		c.Check(id, check.Equals, "chg")
+		c.Check(cs.req.Method, check.Equals, "POST")
+		c.Check(cs.req.URL.Path, check.Equals, "/v2/aliases")
+		var body map[string]interface{}
+		c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+		c.Check(body, check.DeepEquals, map[string]interface{}{
+			"action":  t.action,
+			"snap":    "foo",
+			"aliases": []interface{}{"foo0", "foo_reset"},
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AppOptions represent the options of the Apps call.
+type AppOptions struct {
+	// If Service is true, only return apps that are services
+	// (app.IsService() is true); otherwise, return all.
+	Service bool
+}
+
+// Apps returns information about the given apps, which can be given as
+// snap or snap.app names (all the apps of installed snaps, if none are
+// given).
+func (client *Client) Apps(names []string, opts AppOptions) ([]*AppInfo, error) {
+	q := make(url.Values)
+	if len(names) > 0 {
+		q.Add("names", strings.Join(names, ","))
+	}
+	if opts.Service {
+		q.Add("select", "service")
+	}
+
+	var appInfos []*AppInfo
+	_, err := client.doSync("GET", "/v2/apps", q, nil, nil, &appInfos)
+
+	return appInfos, err
+}
+
+// LogOptions represent the options of the Logs call.
+type LogOptions struct {
+	N      int  // The maximum number of log lines to retrieve initially. If <0, no limit.
+	Follow bool // Whether to continue returning new lines as they appear
+}
+
+// A Log holds the information of a single syslog entry
+type Log struct {
+	Timestamp time.Time `json:"timestamp"` // Timestamp of the event, in its original timezone.
+	Message   string    `json:"message"`   // Message is the log message itself
+	SID       string    `json:"sid"`       // SID the identifier of the log source
+	PID       string    `json:"pid"`       // PID of the process that wrote the log
+}
+
+func (l Log) String() string {
+	return fmt.Sprintf("%s %s[%s]: %s", l.Timestamp.Format(time.RFC3339), l.SID, l.PID, l.Message)
+}
+
+// Logs asks for the logs of a series of services, by name.
+func (client *Client) Logs(names []string, opts LogOptions) (<-chan Log, error) {
+	q := make(url.Values)
+	if len(names) > 0 {
+		q.Add("names", strings.Join(names, ","))
+	}
+	q.Add("n", strconv.Itoa(opts.N))
+	if opts.Follow {
+		q.Add("follow", strconv.FormatBool(opts.Follow))
+	}
+
+	rsp, err := client.raw("GET", "/v2/logs", q, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot communicate with server: %v", err)
+	}
+
+	if rsp.StatusCode != http.StatusOK {
+		defer rsp.Body.Close()
+		return nil, parseError(rsp)
+	}
+
+	ch := make(chan Log, 20)
+	go func() {
+		defer rsp.Body.Close()
+		defer close(ch)
+
+		// the logs come as a JSON text sequence (RFC 7464): each
+		// log is preceded by a record separator and ends in a newline
+		r := bufio.NewReader(rsp.Body)
+		for {
+			buf, err := r.ReadBytes('\n')
+			buf = bytes.TrimLeft(buf, "\x1e")
+			if len(bytes.TrimSpace(buf)) > 0 {
+				var l Log
+				if json.Unmarshal(buf, &l) != nil {
+					return
+				}
+				ch <- l
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+type appInstruction struct {
+	Action  string   `json:"action"`
+	Names   []string `json:"names"`
+	Enable  bool     `json:"enable,omitempty"`
+	Disable bool     `json:"disable,omitempty"`
+}
+
+func (client *Client) doApps(inst *appInstruction) (changeID string, err error) {
+	b, err := json.Marshal(inst)
+	if err != nil {
+		return "", err
+	}
+
+	return client.doAsync("POST", "/v2/apps", nil, nil, bytes.NewReader(b))
+}
+
+// StartOptions represent the different options of the Start call.
+type StartOptions struct {
+	// Enable, as well as starting, makes it so the services will
+	// start on boot.
+	Enable bool
+}
+
+// Start services.
+//
+// It takes a list of names that can be snaps, of which all their
+// services are started, or snap.service which are individual
+// services to start; it shouldn't be empty.
+func (client *Client) Start(names []string, opts StartOptions) (changeID string, err error) {
+	return client.doApps(&appInstruction{Action: "start", Names: names, Enable: opts.Enable})
+}
+
+// StopOptions represent the different options of the Stop call.
+type StopOptions struct {
+	// Disable, as well as stopping, makes it so the services will
+	// not start on boot.
+	Disable bool
+}
+
+// Stop services.
+//
+// It takes a list of names that can be snaps, of which all their
+// services are stopped, or snap.service which are individual
+// services to stop; it shouldn't be empty.
+func (client *Client) Stop(names []string, opts StopOptions) (changeID string, err error) {
+	return client.doApps(&appInstruction{Action: "stop", Names: names, Disable: opts.Disable})
+}
+
+// Restart services.
+//
+// It takes a list of names that can be snaps, of which all their
+// services are restarted, or snap.service which are individual
+// services to restart; it shouldn't be empty.
+func (client *Client) Restart(names []string) (changeID string, err error) {
+	return client.doApps(&appInstruction{Action: "restart", Names: names})
+}
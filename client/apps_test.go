@@ -0,0 +1,125 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientApps(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": [
+		{"snap": "foo", "name": "svc", "daemon": "simple", "enabled": true, "active": true},
+		{"snap": "foo", "name": "app"}
+	]}`
+	apps, err := cs.cli.Apps([]string{"foo", "bar.baz"}, client.AppOptions{})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/apps")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{"names": {"foo,bar.baz"}})
+	c.Check(apps, check.DeepEquals, []*client.AppInfo{
+		{Snap: "foo", Name: "svc", Daemon: "simple", Enabled: true, Active: true},
+		{Snap: "foo", Name: "app"},
+	})
+	c.Check(apps[0].IsService(), check.Equals, true)
+	c.Check(apps[1].IsService(), check.Equals, false)
+
+	_, err = cs.cli.Apps(nil, client.AppOptions{Service: true})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{"select": {"service"}})
+}
+
+func (cs *clientSuite) TestClientLogs(c *check.C) {
+	cs.rsp = "\x1e" + `{"timestamp": "2016-12-06T09:00:00Z", "message": "hello", "sid": "foo.svc", "pid": "42"}` + "\n" +
+		"\x1e" + `{"timestamp": "2016-12-06T09:00:01Z", "message": "bye", "sid": "foo.svc", "pid": "42"}` + "\n"
+	ch, err := cs.cli.Logs([]string{"foo.svc"}, client.LogOptions{N: -1, Follow: true})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/logs")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"names":  {"foo.svc"},
+		"n":      {"-1"},
+		"follow": {"true"},
+	})
+
+	var logs []client.Log
+	for l := range ch {
+		logs = append(logs, l)
+	}
+	c.Assert(logs, check.DeepEquals, []client.Log{
+		{Timestamp: time.Date(2016, 12, 6, 9, 0, 0, 0, time.UTC), Message: "hello", SID: "foo.svc", PID: "42"},
+		{Timestamp: time.Date(2016, 12, 6, 9, 0, 1, 0, time.UTC), Message: "bye", SID: "foo.svc", PID: "42"},
+	})
+	c.Check(logs[0].String(), check.Equals, "2016-12-06T09:00:00Z foo.svc[42]: hello")
+}
+
+func (cs *clientSuite) TestClientLogsError(c *check.C) {
+	cs.status = http.StatusNotFound
+	cs.header = http.Header{"Content-Type": {"application/json"}}
+	cs.rsp = `{"type": "error", "status-code": 404, "result": {"message": "snap \"foo\" not found"}}`
+	_, err := cs.cli.Logs([]string{"foo"}, client.LogOptions{N: 10})
+	c.Assert(err, check.ErrorMatches, `snap "foo" not found`)
+}
+
+func (cs *clientSuite) TestClientServiceActions(c *check.C) {
+	for _, t := range []struct {
+		do   func() (string, error)
+		body map[string]interface{}
+	}{
+		{
+			do: func() (string, error) { return cs.cli.Start([]string{"foo"}, client.StartOptions{Enable: true}) },
+			body: map[string]interface{}{
+				"action": "start",
+				"names":  []interface{}{"foo"},
+				"enable": true,
+			},
+		}, {
+			do: func() (string, error) { return cs.cli.Stop([]string{"foo.svc"}, client.StopOptions{Disable: true}) },
+			body: map[string]interface{}{
+				"action":  "stop",
+				"names":   []interface{}{"foo.svc"},
+				"disable": true,
+			},
+		}, {
+			do: func() (string, error) { return cs.cli.Restart([]string{"foo", "bar"}) },
+			body: map[string]interface{}{
+				"action": "restart",
+				"names":  []interface{}{"foo", "bar"},
+			},
+		},
+	} {
+		cs.status = http.StatusAccepted
+		cs.rsp = `{"type": "async", "status-code": 202, "change": "chg"}`
+		id, err := t.do()
+		c.Assert(err, check.IsNil)
+		c.Check(id, check.Equals, "chg")
+		c.Check(cs.req.Method, check.Equals, "POST")
+		c.Check(cs.req.URL.Path, check.Equals, "/v2/apps")
+		var body map[string]interface{}
+		c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+		c.Check(body, check.DeepEquals, t.body)
+	}
+}
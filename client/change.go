@@ -56,15 +56,28 @@ func (c *Change) Get(key string, value interface{}) error {
 
 // A Task is an operation done to change the system's state.
 type Task struct {
-	ID       string       `json:"id"`
-	Kind     string       `json:"kind"`
-	Summary  string       `json:"summary"`
-	Status   string       `json:"status"`
-	Log      []string     `json:"log,omitempty"`
-	Progress TaskProgress `json:"progress"`
+	ID         string         `json:"id"`
+	Kind       string         `json:"kind"`
+	Summary    string         `json:"summary"`
+	Status     string         `json:"status"`
+	Log        []string       `json:"log,omitempty"`
+	LogEntries []TaskLogEntry `json:"log-entries,omitempty"`
+	Progress   TaskProgress   `json:"progress"`
 
 	SpawnTime time.Time `json:"spawn-time,omitempty"`
 	ReadyTime time.Time `json:"ready-time,omitempty"`
+	AtTime    time.Time `json:"at-time,omitempty"`
+	Deadline  time.Time `json:"deadline,omitempty"`
+
+	// Data is only filled in by ChangeWithTaskData.
+	Data map[string]json.RawMessage `json:"data,omitempty"`
+}
+
+// A TaskLogEntry is a message logged into a task.
+type TaskLogEntry struct {
+	Time    time.Time `json:"time,omitempty"`
+	Kind    string    `json:"kind,omitempty"`
+	Message string    `json:"message"`
 }
 
 type TaskProgress struct {
@@ -80,8 +93,18 @@ type changeAndData struct {
 
 // Change fetches information about a Change given its ID
 func (client *Client) Change(id string) (*Change, error) {
+	return client.change(id, nil)
+}
+
+// ChangeWithTaskData fetches information about a Change given its ID,
+// including the data of its tasks. Only admins can see it.
+func (client *Client) ChangeWithTaskData(id string) (*Change, error) {
+	return client.change(id, url.Values{"data": []string{"true"}})
+}
+
+func (client *Client) change(id string, query url.Values) (*Change, error) {
 	var chgd changeAndData
-	_, err := client.doSync("GET", "/v2/changes/"+id, nil, nil, nil, &chgd)
+	_, err := client.doSync("GET", "/v2/changes/"+id, query, nil, nil, &chgd)
 	if err != nil {
 		return nil, err
 	}
@@ -81,6 +81,36 @@ func (cs *clientSuite) TestClientChangeData(c *check.C) {
 	c.Assert(err, check.Equals, client.ErrNoData)
 }
 
+func (cs *clientSuite) TestClientChangeWithTaskData(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": {
+  "id":   "uno",
+  "kind": "foo",
+  "summary": "...",
+  "status": "Error",
+  "ready": true,
+  "tasks": [{"kind": "bar", "summary": "...", "status": "Error", "progress": {"done": 1, "total": 1},
+             "log": ["2016-04-21T01:02:03Z ERROR boom"],
+             "log-entries": [{"time": "2016-04-21T01:02:03Z", "kind": "ERROR", "message": "boom"}],
+             "data": {"snap-setup": {"channel": "edge"}}}]
+}}`
+
+	chg, err := cs.cli.ChangeWithTaskData("uno")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/changes/uno")
+	c.Check(cs.req.URL.Query().Get("data"), check.Equals, "true")
+
+	c.Assert(chg.Tasks, check.HasLen, 1)
+	t := chg.Tasks[0]
+	c.Check(t.LogEntries, check.DeepEquals, []client.TaskLogEntry{{
+		Time:    time.Date(2016, 04, 21, 1, 2, 3, 0, time.UTC),
+		Kind:    "ERROR",
+		Message: "boom",
+	}})
+	c.Assert(t.Data, check.HasLen, 1)
+	c.Check(string(t.Data["snap-setup"]), check.Equals, `{"channel": "edge"}`)
+}
+
 func (cs *clientSuite) TestClientChangeError(c *check.C) {
 	cs.rsp = `{"type": "sync", "result": {
   "id":   "uno",
@@ -69,20 +69,25 @@ type Config struct {
 type Client struct {
 	baseURL url.URL
 	doer    doer
+	// dial connects to the daemon for websocket requests, net.Dial is
+	// used if nil
+	dial func(network, addr string) (net.Conn, error)
 }
 
 // New returns a new instance of Client
 func New(config *Config) *Client {
 	// By default talk over an UNIX socket.
 	if config == nil || config.BaseURL == "" {
+		dial := unixDialer()
 		return &Client{
 			baseURL: url.URL{
 				Scheme: "http",
 				Host:   "localhost",
 			},
 			doer: &http.Client{
-				Transport: &http.Transport{Dial: unixDialer()},
+				Transport: &http.Transport{Dial: dial},
 			},
+			dial: dial,
 		}
 	}
 	baseURL, err := url.Parse(config.BaseURL)
@@ -280,8 +285,9 @@ type response struct {
 
 // Error is the real value of response.Result when an error occurs.
 type Error struct {
-	Kind    string `json:"kind"`
-	Message string `json:"message"`
+	Kind    string      `json:"kind"`
+	Message string      `json:"message"`
+	Value   interface{} `json:"value"`
 
 	StatusCode int
 }
@@ -291,11 +297,28 @@ func (e *Error) Error() string {
 }
 
 const (
-	ErrorKindTwoFactorRequired = "two-factor-required"
-	ErrorKindTwoFactorFailed   = "two-factor-failed"
-	ErrorKindLoginRequired     = "login-required"
+	ErrorKindTwoFactorRequired  = "two-factor-required"
+	ErrorKindTwoFactorFailed    = "two-factor-failed"
+	ErrorKindLoginRequired      = "login-required"
+	ErrorKindUnsuccessful       = "unsuccessful"
+	ErrorKindSnapChangeConflict = "snap-change-conflict"
 )
 
+// ConflictingChangeID returns the ID of the change in progress that
+// caused the given snap-change-conflict error, or "" if there is none.
+func ConflictingChangeID(err error) string {
+	e, ok := err.(*Error)
+	if !ok || e == nil || e.Kind != ErrorKindSnapChangeConflict {
+		return ""
+	}
+	value, ok := e.Value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	id, _ := value["change-id"].(string)
+	return id
+}
+
 // IsTwoFactorError returns whether the given error is due to problems
 // in two-factor authentication.
 func IsTwoFactorError(err error) bool {
@@ -319,6 +342,15 @@ type SysInfo struct {
 	Version   string    `json:"version,omitempty"`
 	OSRelease OSRelease `json:"os-release"`
 	OnClassic bool      `json:"on-classic"`
+
+	Refresh RefreshInfo `json:"refresh"`
+}
+
+// RefreshInfo contains information about the automatic refresh of snaps.
+type RefreshInfo struct {
+	Schedule string `json:"schedule"`
+	Last     string `json:"last,omitempty"`
+	Next     string `json:"next,omitempty"`
 }
 
 func (rsp *response) err() error {
@@ -146,7 +146,10 @@ func (cs *clientSuite) TestClientSysInfo(c *check.C) {
                      {"series": "16",
                       "version": "2",
                       "os-release": {"id": "ubuntu", "version-id": "16.04"},
-                      "on-classic": true}}`
+                      "on-classic": true,
+                      "refresh": {"schedule": "mon,10:00-12:00",
+                                  "last": "2017-02-06T10:30:00Z",
+                                  "next": "2017-02-13T11:12:00Z"}}}`
 	sysInfo, err := cs.cli.SysInfo()
 	c.Check(err, check.IsNil)
 	c.Check(sysInfo, check.DeepEquals, &client.SysInfo{
@@ -157,6 +160,11 @@ func (cs *clientSuite) TestClientSysInfo(c *check.C) {
 			VersionID: "16.04",
 		},
 		OnClassic: true,
+		Refresh: client.RefreshInfo{
+			Schedule: "mon,10:00-12:00",
+			Last:     "2017-02-06T10:30:00Z",
+			Next:     "2017-02-13T11:12:00Z",
+		},
 	})
 }
 
@@ -311,6 +319,18 @@ func (cs *clientSuite) TestIsTwoFactor(c *check.C) {
 	c.Check(client.IsTwoFactorError((*client.Error)(nil)), check.Equals, false)
 }
 
+func (cs *clientSuite) TestConflictingChangeID(c *check.C) {
+	cs.rsp = `{"type": "error", "status-code": 409, "result": {"message": "snap \"foo\" has changes in progress", "kind": "snap-change-conflict", "value": {"snap-name": "foo", "change-kind": "refresh-snap", "change-id": "42"}}}`
+	_, err := cs.cli.Remove("foo", nil)
+	c.Assert(err, check.ErrorMatches, `snap "foo" has changes in progress`)
+	c.Check(client.ConflictingChangeID(err), check.Equals, "42")
+
+	c.Check(client.ConflictingChangeID(&client.Error{Kind: client.ErrorKindSnapChangeConflict}), check.Equals, "")
+	c.Check(client.ConflictingChangeID(&client.Error{Kind: "some other kind"}), check.Equals, "")
+	c.Check(client.ConflictingChangeID(errors.New("test")), check.Equals, "")
+	c.Check(client.ConflictingChangeID(nil), check.Equals, "")
+}
+
 func (cs *clientSuite) TestClientCreateUser(c *check.C) {
 	cs.rsp = `{
 		"type": "sync",
@@ -0,0 +1,122 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// An Event is a notification published by the daemon, about the status
+// or progress of a change or one of its tasks, or about a snap or
+// interface operation that completed.
+type Event struct {
+	Timestamp time.Time
+	// Type is one of "change-update", "task-update", "task-progress",
+	// "snap" or "interface".
+	Type     string
+	Resource string
+	Metadata map[string]interface{}
+}
+
+type eventJSON struct {
+	Timestamp int64                  `json:"timestamp"`
+	Type      string                 `json:"type"`
+	Resource  string                 `json:"resource"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+// EventsOptions selects the events to receive; all of them if empty.
+type EventsOptions struct {
+	// Types of the events to receive.
+	Types []string
+	// Resource is the suffix of the resource the events are about,
+	// e.g. a change id or a snap name.
+	Resource string
+	// ChangeID restricts the events to those about the given change.
+	ChangeID string
+}
+
+// An EventStream receives the events published by the daemon.
+type EventStream struct {
+	conn *websocket.Conn
+}
+
+// Events subscribes to the events published by the daemon.
+func (client *Client) Events(opts *EventsOptions) (*EventStream, error) {
+	if opts == nil {
+		opts = &EventsOptions{}
+	}
+	query := url.Values{}
+	if len(opts.Types) > 0 {
+		query.Set("types", strings.Join(opts.Types, ","))
+	}
+	if opts.Resource != "" {
+		query.Set("resource", opts.Resource)
+	}
+	if opts.ChangeID != "" {
+		query.Set("change", opts.ChangeID)
+	}
+
+	u := client.baseURL
+	u.Scheme = "ws"
+	u.Path = path.Join(client.baseURL.Path, "/v2/events")
+	u.RawQuery = query.Encode()
+
+	// borrow the Authorization header from a throwaway request
+	req := &http.Request{Header: make(http.Header)}
+	if err := client.setAuthorization(req); err != nil {
+		return nil, err
+	}
+
+	dialer := websocket.Dialer{NetDial: client.dial}
+	conn, _, err := dialer.Dial(u.String(), req.Header)
+	if err != nil {
+		return nil, fmt.Errorf("cannot subscribe to events: %v", err)
+	}
+
+	return &EventStream{conn: conn}, nil
+}
+
+// Next waits for the next event.
+func (s *EventStream) Next() (*Event, error) {
+	var ev eventJSON
+	if err := s.conn.ReadJSON(&ev); err != nil {
+		return nil, fmt.Errorf("cannot read event: %v", err)
+	}
+
+	return &Event{
+		Timestamp: time.Unix(0, ev.Timestamp),
+		Type:      ev.Type,
+		Resource:  ev.Resource,
+		Metadata:  ev.Metadata,
+	}, nil
+}
+
+// Close unsubscribes from the events.
+func (s *EventStream) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,95 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+)
+
+func (cs *clientSuite) TestClientEvents(c *check.C) {
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapdSocket), 0755), check.IsNil)
+	l, err := net.Listen("unix", dirs.SnapdSocket)
+	c.Assert(err, check.IsNil)
+
+	f := func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/events")
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+			"types":  {"change-update,task-update"},
+			"change": {"42"},
+		})
+
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		c.Assert(err, check.IsNil)
+		defer conn.Close()
+		err = conn.WriteMessage(websocket.TextMessage, []byte(`{"timestamp":1478001600000000000,"type":"change-update","resource":"/v2/changes/42","metadata":{"change-id":"42","status":"Done"}}`))
+		c.Check(err, check.IsNil)
+	}
+
+	srv := &httptest.Server{
+		Listener: l,
+		Config:   &http.Server{Handler: http.HandlerFunc(f)},
+	}
+	srv.Start()
+	defer srv.Close()
+
+	cli := client.New(nil)
+	events, err := cli.Events(&client.EventsOptions{
+		Types:    []string{"change-update", "task-update"},
+		ChangeID: "42",
+	})
+	c.Assert(err, check.IsNil)
+	defer events.Close()
+
+	ev, err := events.Next()
+	c.Assert(err, check.IsNil)
+	c.Check(ev, check.DeepEquals, &client.Event{
+		Timestamp: time.Date(2016, 11, 1, 12, 0, 0, 0, time.UTC).Local(),
+		Type:      "change-update",
+		Resource:  "/v2/changes/42",
+		Metadata:  map[string]interface{}{"change-id": "42", "status": "Done"},
+	})
+
+	// the server closed the connection
+	_, err = events.Next()
+	c.Check(err, check.ErrorMatches, "cannot read event: .*")
+}
+
+func (cs *clientSuite) TestClientEventsNotAWebsocket(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer srv.Close()
+
+	cli := client.New(&client.Config{BaseURL: srv.URL})
+	_, err := cli.Events(nil)
+	c.Check(err, check.ErrorMatches, "cannot subscribe to events: websocket: bad handshake")
+}
@@ -50,12 +50,33 @@ type Snap struct {
 	TryMode       bool          `json:"trymode"`
 	Apps          []AppInfo     `json:"apps"`
 	Broken        string        `json:"broken"`
+	Held          bool          `json:"held,omitempty"`
+	HoldUntil     time.Time     `json:"hold-until,omitempty"`
+	// PinnedRevision is the revision a held snap is pinned to
+	PinnedRevision snap.Revision `json:"pinned-revision,omitempty"`
+
+	TrackingChannel string `json:"tracking-channel,omitempty"`
+
+	// SnapName and InstanceKey are set for instances of a snap
+	// installed side by side, whose Name is the instance name
+	SnapName    string `json:"snap-name,omitempty"`
+	InstanceKey string `json:"instance-key,omitempty"`
 
 	Prices map[string]float64 `json:"prices"`
 }
 
+// AppInfo describes a single snap application.
 type AppInfo struct {
-	Name string `json:"name"`
+	Snap    string `json:"snap,omitempty"`
+	Name    string `json:"name"`
+	Daemon  string `json:"daemon,omitempty"`
+	Enabled bool   `json:"enabled,omitempty"`
+	Active  bool   `json:"active,omitempty"`
+}
+
+// IsService returns true if the application is a background daemon.
+func (a *AppInfo) IsService() bool {
+	return a != nil && a.Daemon != ""
 }
 
 // Statuses and types a snap may have.
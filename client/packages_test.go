@@ -161,6 +161,8 @@ func (cs *clientSuite) TestClientSnap(c *check.C) {
 			"status": "active",
 			"type": "app",
 			"version": "0.1-8",
+			"channel": "stable",
+			"tracking-channel": "candidate",
 			"confinement": "strict",
 			"private": true,
 			"devmode": true,
@@ -172,21 +174,23 @@ func (cs *clientSuite) TestClientSnap(c *check.C) {
 	c.Assert(cs.req.URL.Path, check.Equals, fmt.Sprintf("/v2/snaps/%s", pkgName))
 	c.Assert(err, check.IsNil)
 	c.Assert(pkg, check.DeepEquals, &client.Snap{
-		ID:            "funky-snap-id",
-		Summary:       "bla bla",
-		Description:   "WebRTC Video chat server for Snappy",
-		DownloadSize:  6930947,
-		Icon:          "/v2/icons/chatroom.ogra/icon",
-		InstalledSize: 18976651,
-		InstallDate:   time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC),
-		Name:          "chatroom",
-		Developer:     "ogra",
-		Status:        client.StatusActive,
-		Type:          client.TypeApp,
-		Version:       "0.1-8",
-		Confinement:   client.StrictConfinement,
-		Private:       true,
-		DevMode:       true,
-		TryMode:       true,
+		ID:              "funky-snap-id",
+		Summary:         "bla bla",
+		Description:     "WebRTC Video chat server for Snappy",
+		DownloadSize:    6930947,
+		Icon:            "/v2/icons/chatroom.ogra/icon",
+		InstalledSize:   18976651,
+		InstallDate:     time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC),
+		Name:            "chatroom",
+		Developer:       "ogra",
+		Status:          client.StatusActive,
+		Type:            client.TypeApp,
+		Version:         "0.1-8",
+		Channel:         "stable",
+		TrackingChannel: "candidate",
+		Confinement:     client.StrictConfinement,
+		Private:         true,
+		DevMode:         true,
+		TryMode:         true,
 	})
 }
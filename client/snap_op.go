@@ -28,6 +28,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 )
 
 type SnapOptions struct {
@@ -35,7 +36,16 @@ type SnapOptions struct {
 	Revision  string `json:"revision,omitempty"`
 	DevMode   bool   `json:"devmode,omitempty"`
 	JailMode  bool   `json:"jailmode,omitempty"`
+	Classic   bool   `json:"classic,omitempty"`
 	Dangerous bool   `json:"dangerous,omitempty"`
+	Purge     bool   `json:"purge,omitempty"`
+
+	HoldUntil *time.Time `json:"hold-until,omitempty"`
+	// At schedules the change to start no earlier than the given time.
+	At *time.Time `json:"at,omitempty"`
+	// Deadline is the time by which the change must be done, otherwise
+	// it is undone.
+	Deadline *time.Time `json:"deadline,omitempty"`
 }
 
 type actionData struct {
@@ -46,8 +56,10 @@ type actionData struct {
 }
 
 type multiActionData struct {
-	Action string   `json:"action"`
-	Snaps  []string `json:"snaps,omitempty"`
+	Action   string     `json:"action"`
+	Snaps    []string   `json:"snaps,omitempty"`
+	At       *time.Time `json:"at,omitempty"`
+	Deadline *time.Time `json:"deadline,omitempty"`
 }
 
 // Install adds the snap with the given name from the given channel (or
@@ -87,6 +99,27 @@ func (client *Client) Disable(name string, options *SnapOptions) (changeID strin
 	return client.doSnapAction("disable", name, options)
 }
 
+// Hold holds back refreshes of the snap with the given name until the
+// given time, or indefinitely if until is zero.
+func (client *Client) Hold(name string, until time.Time) (changeID string, err error) {
+	options := &SnapOptions{}
+	if !until.IsZero() {
+		options.HoldUntil = &until
+	}
+	return client.doSnapAction("hold", name, options)
+}
+
+// Unhold allows refreshes of the held snap with the given name again.
+func (client *Client) Unhold(name string) (changeID string, err error) {
+	return client.doSnapAction("unhold", name, nil)
+}
+
+// Switch switches the snap with the given name to track the channel
+// given in the options, without refreshing it.
+func (client *Client) Switch(name string, options *SnapOptions) (changeID string, err error) {
+	return client.doSnapAction("switch", name, options)
+}
+
 // Revert rolls the snap back to the previous on-disk state
 func (client *Client) Revert(name string, options *SnapOptions) (changeID string, err error) {
 	return client.doSnapAction("revert", name, options)
@@ -111,13 +144,17 @@ func (client *Client) doSnapAction(actionName string, snapName string, options *
 }
 
 func (client *Client) doMultiSnapAction(actionName string, snaps []string, options *SnapOptions) (changeID string, err error) {
-	if options != nil {
+	if options != nil && *options != (SnapOptions{At: options.At, Deadline: options.Deadline}) {
 		return "", fmt.Errorf("cannot use options for multi-action") // (yet)
 	}
 	action := multiActionData{
 		Action: actionName,
 		Snaps:  snaps,
 	}
+	if options != nil {
+		action.At = options.At
+		action.Deadline = options.Deadline
+	}
 	data, err := json.Marshal(&action)
 	if err != nil {
 		return "", fmt.Errorf("cannot marshal multi-snap action: %s", err)
@@ -167,6 +204,7 @@ func (client *Client) Try(path string, options *SnapOptions) (changeID string, e
 	mw.WriteField("snap-path", path)
 	mw.WriteField("devmode", strconv.FormatBool(options.DevMode))
 	mw.WriteField("jailmode", strconv.FormatBool(options.JailMode))
+	mw.WriteField("classic", strconv.FormatBool(options.Classic))
 	mw.Close()
 
 	headers := map[string]string{
@@ -189,6 +227,7 @@ func sendSnapFile(snapPath string, snapFile *os.File, pw *io.PipeWriter, mw *mul
 		mw.WriteField("channel", action.Channel),
 		mw.WriteField("devmode", strconv.FormatBool(action.DevMode)),
 		mw.WriteField("jailmode", strconv.FormatBool(action.JailMode)),
+		mw.WriteField("classic", strconv.FormatBool(action.Classic)),
 		mw.WriteField("dangerous", strconv.FormatBool(action.Dangerous)),
 	}
 	for _, err := range errs {
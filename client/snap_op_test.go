@@ -29,6 +29,7 @@ import (
 	"mime/multipart"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -47,6 +48,7 @@ var ops = []struct {
 	{(*client.Client).Revert, "revert"},
 	{(*client.Client).Enable, "enable"},
 	{(*client.Client).Disable, "disable"},
+	{(*client.Client).Switch, "switch"},
 }
 
 var multiOps = []struct {
@@ -146,6 +148,43 @@ func (cs *clientSuite) TestClientOpSnap(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientOpHold(c *check.C) {
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	id, err := cs.cli.Hold(pkgName, time.Time{})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "d728")
+	c.Check(cs.req.URL.Path, check.Equals, fmt.Sprintf("/v2/snaps/%s", pkgName))
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"hold"}`)
+
+	until := time.Date(2017, 2, 6, 10, 0, 0, 0, time.UTC)
+	_, err = cs.cli.Hold(pkgName, until)
+	c.Assert(err, check.IsNil)
+	body, err = ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"hold","hold-until":"2017-02-06T10:00:00Z"}`)
+}
+
+func (cs *clientSuite) TestClientOpUnhold(c *check.C) {
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	id, err := cs.cli.Unhold(pkgName)
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "d728")
+	c.Check(cs.req.URL.Path, check.Equals, fmt.Sprintf("/v2/snaps/%s", pkgName))
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"unhold"}`)
+}
+
 func (cs *clientSuite) TestClientMultiOpSnap(c *check.C) {
 	cs.rsp = `{
 		"change": "d728",
@@ -172,6 +211,41 @@ func (cs *clientSuite) TestClientMultiOpSnap(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientMultiOpSnapAt(c *check.C) {
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	at := time.Date(2030, 1, 2, 2, 0, 0, 0, time.UTC)
+	_, err := cs.cli.RefreshMany(nil, &client.SnapOptions{At: &at})
+	c.Assert(err, check.IsNil)
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"refresh","at":"2030-01-02T02:00:00Z"}`)
+
+	// other options are still not supported
+	_, err = cs.cli.RefreshMany(nil, &client.SnapOptions{At: &at, Channel: "beta"})
+	c.Check(err, check.ErrorMatches, "cannot use options for multi-action")
+}
+
+func (cs *clientSuite) TestClientMultiOpSnapDeadline(c *check.C) {
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	at := time.Date(2030, 1, 2, 2, 0, 0, 0, time.UTC)
+	deadline := time.Date(2030, 1, 2, 4, 0, 0, 0, time.UTC)
+	_, err := cs.cli.RefreshMany(nil, &client.SnapOptions{At: &at, Deadline: &deadline})
+	c.Assert(err, check.IsNil)
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(body), check.Equals, `{"action":"refresh","at":"2030-01-02T02:00:00Z","deadline":"2030-01-02T04:00:00Z"}`)
+}
+
 func (cs *clientSuite) TestClientOpInstallPath(c *check.C) {
 	cs.rsp = `{
 		"change": "66b3",
@@ -228,6 +302,7 @@ func (cs *clientSuite) TestClientOpTryMode(c *check.C) {
 		{DevMode: false, JailMode: true},
 		{DevMode: true, JailMode: true},
 		{DevMode: true, JailMode: false},
+		{Classic: true},
 	} {
 		id, err := cs.cli.Try(snapdir, opts)
 		c.Assert(err, check.IsNil)
@@ -242,6 +317,7 @@ func (cs *clientSuite) TestClientOpTryMode(c *check.C) {
 			"snap-path": snapdir,
 			"devmode":   strconv.FormatBool(opts.DevMode),
 			"jailmode":  strconv.FormatBool(opts.JailMode),
+			"classic":   strconv.FormatBool(opts.Classic),
 		})
 
 		c.Check(cs.req.Method, check.Equals, "POST")
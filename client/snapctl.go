@@ -28,13 +28,39 @@ import (
 // SnapCtlOptions holds the various options with which snapctl is invoked.
 type SnapCtlOptions struct {
 	// ContextID is a string used to determine the context of this call (e.g.
-	// which context and handler should be used, etc.)
+	// which context and handler should be used, etc.), either the ID of a
+	// hook context or the cookie of a snap.
 	ContextID string `json:"context-id"`
 
 	// Args contains a list of parameters to use for this invocation.
 	Args []string `json:"args"`
 }
 
+// UnsuccessfulError is returned by RunSnapctl when the command ran but
+// asked for snapctl to exit with a non-zero exit code.
+type UnsuccessfulError struct {
+	ExitCode int
+}
+
+func (e *UnsuccessfulError) Error() string {
+	return fmt.Sprintf("snapctl exited with code %d", e.ExitCode)
+}
+
+func unsuccessfulOutput(e *Error) (stdout, stderr []byte, err error) {
+	value, ok := e.Value.(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("cannot run snapctl: %s", e)
+	}
+	// JSON numbers come back as float64
+	code, ok := value["exit-code"].(float64)
+	if !ok {
+		return nil, nil, fmt.Errorf("cannot run snapctl: %s", e)
+	}
+	stdoutStr, _ := value["stdout"].(string)
+	stderrStr, _ := value["stderr"].(string)
+	return []byte(stdoutStr), []byte(stderrStr), &UnsuccessfulError{ExitCode: int(code)}
+}
+
 type snapctlOutput struct {
 	Stdout string `json:"stdout"`
 	Stderr string `json:"stderr"`
@@ -49,6 +75,9 @@ func (client *Client) RunSnapctl(options *SnapCtlOptions) (stdout, stderr []byte
 
 	var output snapctlOutput
 	_, err = client.doSync("POST", "/v2/snapctl", nil, nil, bytes.NewReader(b), &output)
+	if e, ok := err.(*Error); ok && e.Kind == ErrorKindUnsuccessful {
+		return unsuccessfulOutput(e)
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("cannot run snapctl: %s", err)
 	}
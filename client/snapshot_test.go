@@ -0,0 +1,111 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (cs *clientSuite) TestClientSnapshotSets(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [{"id": 1, "snapshots": [{"set": 1, "time": "2016-12-06T09:00:00Z", "snap": "foo", "revision": "10", "sha3-384": {"archive.tgz": "abc"}, "size": 42}]}]
+	}`
+	sets, err := cs.cli.SnapshotSets(1, []string{"foo", "bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots")
+	c.Check(cs.req.URL.Query().Get("set"), check.Equals, "1")
+	c.Check(cs.req.URL.Query().Get("snaps"), check.Equals, "foo,bar")
+
+	c.Check(sets, check.DeepEquals, []client.SnapshotSet{{
+		ID: 1,
+		Snapshots: []*client.Snapshot{{
+			SetID:    1,
+			Time:     time.Date(2016, 12, 6, 9, 0, 0, 0, time.UTC),
+			Snap:     "foo",
+			Revision: snap.R(10),
+			SHA3_384: map[string]string{"archive.tgz": "abc"},
+			Size:     42,
+		}},
+	}})
+	c.Check(sets[0].Size(), check.Equals, int64(42))
+	c.Check(sets[0].Time(), check.Equals, time.Date(2016, 12, 6, 9, 0, 0, 0, time.UTC))
+}
+
+func (cs *clientSuite) TestClientSnapshotSetsNoFilters(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": []}`
+	_, err := cs.cli.SnapshotSets(0, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.RawQuery, check.Equals, "")
+}
+
+func (cs *clientSuite) TestClientSnapshotActions(c *check.C) {
+	for _, t := range []struct {
+		do   func() (string, error)
+		body map[string]interface{}
+	}{
+		{
+			do: func() (string, error) { return cs.cli.SnapshotMany([]string{"foo"}, []string{"user1"}) },
+			body: map[string]interface{}{
+				"action": "save",
+				"set":    0.,
+				"snaps":  []interface{}{"foo"},
+				"users":  []interface{}{"user1"},
+			},
+		}, {
+			do: func() (string, error) { return cs.cli.RestoreSnapshots(42, nil, []string{"user1"}) },
+			body: map[string]interface{}{
+				"action": "restore",
+				"set":    42.,
+				"users":  []interface{}{"user1"},
+			},
+		}, {
+			do: func() (string, error) { return cs.cli.ForgetSnapshots(42, []string{"foo"}) },
+			body: map[string]interface{}{
+				"action": "forget",
+				"set":    42.,
+				"snaps":  []interface{}{"foo"},
+			},
+		},
+	} {
+		cs.rsp = `{
+			"type": "async",
+			"status-code": 202,
+			"result": { },
+			"change": "chg"
+		}`
+		id, err := t.do()
+		c.Assert(err, check.IsNil)
+		c.Check(id, check.Equals, "chg")
+		c.Check(cs.req.Method, check.Equals, "POST")
+		c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots")
+		var body map[string]interface{}
+		c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+		c.Check(body, check.DeepEquals, t.body)
+	}
+}
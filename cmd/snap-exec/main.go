@@ -36,7 +36,7 @@ var syscallExec = syscall.Exec
 
 // commandline args
 var opts struct {
-	Command string `long:"command" description:"use a different command like {stop,post-stop} from the app"`
+	Command string `long:"command" description:"use a different command like {stop,reload,post-stop} from the app"`
 	Hook    string `long:"hook" description:"hook to run" hidden:"yes"`
 }
 
@@ -94,6 +94,8 @@ func findCommand(app *snap.AppInfo, command string) (string, error) {
 		cmd = "/bin/bash"
 	case "stop":
 		cmd = app.StopCommand
+	case "reload":
+		cmd = app.ReloadCommand
 	case "post-stop":
 		cmd = app.PostStopCommand
 	case "":
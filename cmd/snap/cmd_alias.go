@@ -0,0 +1,94 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdAlias struct {
+	Positionals struct {
+		Snap    string   `positional-arg-name:"<snap>"`
+		Aliases []string `positional-arg-name:"<alias>" required:"1"`
+	} `positional-args:"true" required:"true"`
+}
+
+type cmdUnalias struct {
+	Positionals struct {
+		Snap    string   `positional-arg-name:"<snap>"`
+		Aliases []string `positional-arg-name:"<alias>" required:"1"`
+	} `positional-args:"true" required:"true"`
+}
+
+var (
+	shortAliasHelp = i18n.G("Enables the given aliases")
+	longAliasHelp  = i18n.G(`
+The alias command enables the given aliases declared by the apps of the
+snap, making them available as commands in /snap/bin.
+`)
+	shortUnaliasHelp = i18n.G("Disables the given aliases")
+	longUnaliasHelp  = i18n.G(`
+The unalias command disables the given enabled aliases of the snap.
+`)
+)
+
+func init() {
+	argdescs := []argDesc{{
+		name: "<snap>",
+		desc: i18n.G("The snap declaring the aliases"),
+	}, {
+		name: "<alias>",
+		desc: i18n.G("The name of an alias"),
+	}}
+	addCommand("alias", shortAliasHelp, longAliasHelp, func() flags.Commander { return &cmdAlias{} }, nil, argdescs)
+	addCommand("unalias", shortUnaliasHelp, longUnaliasHelp, func() flags.Commander { return &cmdUnalias{} }, nil, argdescs)
+}
+
+func (x *cmdAlias) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	cli := Client()
+	id, err := cli.Alias(x.Positionals.Snap, x.Positionals.Aliases)
+	if err != nil {
+		return err
+	}
+	_, err = wait(cli, id)
+
+	return err
+}
+
+func (x *cmdUnalias) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	cli := Client()
+	id, err := cli.Unalias(x.Positionals.Snap, x.Positionals.Aliases)
+	if err != nil {
+		return err
+	}
+	_, err = wait(cli, id)
+
+	return err
+}
@@ -0,0 +1,77 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) checkAliasAction(c *check.C, args []string, body map[string]interface{}) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/aliases")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, body)
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs(args)
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestAlias(c *check.C) {
+	s.checkAliasAction(c, []string{"alias", "alias-snap", "alias1", "alias2"}, map[string]interface{}{
+		"action":  "alias",
+		"snap":    "alias-snap",
+		"aliases": []interface{}{"alias1", "alias2"},
+	})
+}
+
+func (s *SnapSuite) TestUnalias(c *check.C) {
+	s.checkAliasAction(c, []string{"unalias", "alias-snap", "alias1"}, map[string]interface{}{
+		"action":  "unalias",
+		"snap":    "alias-snap",
+		"aliases": []interface{}{"alias1"},
+	})
+}
+
+func (s *SnapSuite) TestAliasNeedsAliases(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser().ParseArgs([]string{"alias", "alias-snap"})
+	c.Assert(err, check.ErrorMatches, `the required argument .* was not provided`)
+}
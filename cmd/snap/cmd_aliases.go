@@ -0,0 +1,113 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdAliases struct {
+	Positionals struct {
+		Snap string `positional-arg-name:"<snap>"`
+	} `positional-args:"true"`
+}
+
+var (
+	shortAliasesHelp = i18n.G("Lists aliases in the system")
+	longAliasesHelp  = i18n.G(`
+The aliases command lists the aliases declared by the apps of the installed
+snaps, or only by the apps of the given snap, and whether they are enabled.
+`)
+)
+
+func init() {
+	addCommand("aliases", shortAliasesHelp, longAliasesHelp, func() flags.Commander { return &cmdAliases{} }, nil, []argDesc{{
+		name: "<snap>",
+		desc: i18n.G("Only list the aliases of this snap"),
+	}})
+}
+
+type aliasInfo struct {
+	App    string
+	Alias  string
+	Status string
+}
+
+type aliasInfos []*aliasInfo
+
+func (infos aliasInfos) Len() int      { return len(infos) }
+func (infos aliasInfos) Swap(i, j int) { infos[i], infos[j] = infos[j], infos[i] }
+func (infos aliasInfos) Less(i, j int) bool {
+	if infos[i].App == infos[j].App {
+		return infos[i].Alias < infos[j].Alias
+	}
+	return infos[i].App < infos[j].App
+}
+
+func (x *cmdAliases) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	allStatuses, err := Client().Aliases()
+	if err != nil {
+		return err
+	}
+
+	var infos aliasInfos
+	for snapName, statuses := range allStatuses {
+		if x.Positionals.Snap != "" && snapName != x.Positionals.Snap {
+			continue
+		}
+		for alias, status := range statuses {
+			infos = append(infos, &aliasInfo{
+				App:    status.App,
+				Alias:  alias,
+				Status: status.Status,
+			})
+		}
+	}
+
+	if len(infos) == 0 {
+		if x.Positionals.Snap != "" {
+			fmt.Fprintf(Stderr, i18n.G("Snap %q declares no aliases.\n"), x.Positionals.Snap)
+		} else {
+			fmt.Fprintln(Stderr, i18n.G("No aliases are declared by installed snaps."))
+		}
+		return nil
+	}
+
+	sort.Sort(infos)
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("App\tAlias\tStatus"))
+	for _, info := range infos {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", info.App, info.Alias, info.Status)
+	}
+
+	return nil
+}
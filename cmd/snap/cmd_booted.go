@@ -62,7 +62,7 @@ func (x *cmdBooted) Execute(args []string) error {
 		return err
 	}
 
-	ovld, err := overlord.New()
+	ovld, err := overlord.New(nil)
 	if err != nil {
 		return err
 	}
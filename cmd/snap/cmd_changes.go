@@ -33,10 +33,16 @@ import (
 
 var shortChangesHelp = i18n.G("List system changes")
 var shortChangeHelp = i18n.G("List a change's tasks")
+var shortTasksHelp = i18n.G("List a change's tasks")
 var longChangesHelp = i18n.G(`
 The changes command displays a summary of the recent system changes performed.`)
 var longChangeHelp = i18n.G(`
 The change command displays a summary of tasks associated to an individual change.`)
+var longTasksHelp = i18n.G(`
+The tasks command displays a summary of tasks associated to an individual
+change, like the change command. With --verbose it displays every detail of
+each task instead, including its kind, progress and log entries, and with
+--data also the data of the tasks, which requires admin access.`)
 
 type cmdChanges struct {
 	Positional struct {
@@ -50,9 +56,23 @@ type cmdChange struct {
 	} `positional-args:"yes"`
 }
 
+type cmdTasks struct {
+	Verbose bool `long:"verbose"`
+	Data    bool `long:"data"`
+
+	Positional struct {
+		ID string `positional-arg-name:"<id>" required:"yes"`
+	} `positional-args:"yes"`
+}
+
 func init() {
 	addCommand("changes", shortChangesHelp, longChangesHelp, func() flags.Commander { return &cmdChanges{} }, nil, nil)
 	addCommand("change", shortChangeHelp, longChangeHelp, func() flags.Commander { return &cmdChange{} }, nil, nil)
+	addCommand("tasks", shortTasksHelp, longTasksHelp, func() flags.Commander { return &cmdTasks{} },
+		map[string]string{
+			"verbose": i18n.G("Show every detail of each task."),
+			"data":    i18n.G("Show the data of each task as well (implies --verbose)."),
+		}, nil)
 }
 
 type changesByTime []*client.Change
@@ -120,6 +140,46 @@ func (c *cmdChange) Execute([]string) error {
 		return err
 	}
 
+	showChangeTasks(chg)
+
+	return nil
+}
+
+func (x *cmdTasks) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	cli := Client()
+	var chg *client.Change
+	var err error
+	if x.Data {
+		chg, err = cli.ChangeWithTaskData(x.Positional.ID)
+	} else {
+		chg, err = cli.Change(x.Positional.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !x.Verbose && !x.Data {
+		showChangeTasks(chg)
+		return nil
+	}
+
+	for i, t := range chg.Tasks {
+		if i > 0 {
+			fmt.Fprintln(Stdout)
+		}
+		showTaskVerbose(t)
+	}
+
+	return nil
+}
+
+// showChangeTasks prints a table of the tasks of chg, followed by
+// the log of each task that has one.
+func showChangeTasks(chg *client.Change) {
 	w := tabWriter()
 
 	fmt.Fprintf(w, i18n.G("Status\tSpawn\tReady\tSummary\n"))
@@ -152,8 +212,62 @@ func (c *cmdChange) Execute([]string) error {
 	}
 
 	fmt.Fprintln(Stdout)
+}
 
-	return nil
+// showTaskVerbose prints every detail of t, one per line, followed by
+// its log entries and its data, if any.
+func showTaskVerbose(t *client.Task) {
+	w := tabWriter()
+
+	fmt.Fprintf(w, "id:\t%s\n", t.ID)
+	fmt.Fprintf(w, "kind:\t%s\n", t.Kind)
+	fmt.Fprintf(w, "summary:\t%s\n", t.Summary)
+	fmt.Fprintf(w, "status:\t%s\n", t.Status)
+	progress := fmt.Sprintf("%d/%d", t.Progress.Done, t.Progress.Total)
+	if t.Progress.Label != "" {
+		progress += " " + t.Progress.Label
+	}
+	fmt.Fprintf(w, "progress:\t%s\n", progress)
+	fmt.Fprintf(w, "spawn:\t%s\n", formatTaskTime(t.SpawnTime))
+	fmt.Fprintf(w, "ready:\t%s\n", formatTaskTime(t.ReadyTime))
+	if !t.AtTime.IsZero() {
+		fmt.Fprintf(w, "at:\t%s\n", formatTaskTime(t.AtTime))
+	}
+	if !t.Deadline.IsZero() {
+		fmt.Fprintf(w, "deadline:\t%s\n", formatTaskTime(t.Deadline))
+	}
+
+	if len(t.LogEntries) > 0 {
+		fmt.Fprintln(w, "log:")
+		for _, entry := range t.LogEntries {
+			if entry.Time.IsZero() {
+				fmt.Fprintf(w, "  %s\n", entry.Message)
+				continue
+			}
+			fmt.Fprintf(w, "  %s %s %s\n", formatTaskTime(entry.Time), entry.Kind, entry.Message)
+		}
+	}
+
+	if len(t.Data) > 0 {
+		fmt.Fprintln(w, "data:")
+		keys := make([]string, 0, len(t.Data))
+		for k := range t.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "  %s:\t%s\n", k, t.Data[k])
+		}
+	}
+
+	w.Flush()
+}
+
+func formatTaskTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
 }
 
 const line = "......................................................................"
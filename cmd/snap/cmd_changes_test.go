@@ -95,3 +95,83 @@ Doing +2016-04-21T01:02:03Z +2016-04-21T01:02:04Z +some summary \(50.00%\)
 `)
 	c.Check(s.Stderr(), check.Equals, "")
 }
+
+var mockChangeVerboseJSON = `{"type": "sync", "result": {
+  "id":   "uno",
+  "kind": "foo",
+  "summary": "...",
+  "status": "Error",
+  "ready": true,
+  "spawn-time": "2016-04-21T01:02:03Z",
+  "ready-time": "2016-04-21T01:02:04Z",
+  "tasks": [{"id": "1", "kind": "bar", "summary": "some summary", "status": "Error", "progress": {"label": "foo", "done": 1, "total": 1},
+             "log": ["2016-04-21T01:02:03Z ERROR boom"],
+             "log-entries": [{"time": "2016-04-21T01:02:03Z", "kind": "ERROR", "message": "boom"}],
+             "spawn-time": "2016-04-21T01:02:03Z", "ready-time": "2016-04-21T01:02:04Z", "deadline": "2016-04-21T02:00:00Z",
+             "data": {"b": {"c": "d"}, "a": 1}},
+            {"id": "2", "kind": "baz", "summary": "other summary", "status": "Hold", "progress": {"done": 0, "total": 1},
+             "spawn-time": "2016-04-21T01:02:03Z"}]
+}}`
+
+func (s *SnapSuite) TestTasks(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+		c.Check(r.URL.RawQuery, check.Equals, "")
+		fmt.Fprintln(w, mockChangeJSON)
+	})
+	rest, err := snap.Parser().ParseArgs([]string{"tasks", "42"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?ms)Status +Spawn +Ready +Summary
+Do +2016-04-21T01:02:03Z +2016-04-21T01:02:04Z +some summary
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestTasksVerbose(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+		c.Check(r.URL.RawQuery, check.Equals, "")
+		fmt.Fprintln(w, mockChangeVerboseJSON)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"tasks", "--verbose", "42"})
+	c.Assert(err, check.IsNil)
+	// the mock server sends data regardless, it is shown when present
+	c.Check(s.Stdout(), check.Equals, `id:        1
+kind:      bar
+summary:   some summary
+status:    Error
+progress:  1/1 foo
+spawn:     2016-04-21T01:02:03Z
+ready:     2016-04-21T01:02:04Z
+deadline:  2016-04-21T02:00:00Z
+log:
+  2016-04-21T01:02:03Z ERROR boom
+data:
+  a:  1
+  b:  {"c": "d"}
+
+id:        2
+kind:      baz
+summary:   other summary
+status:    Hold
+progress:  0/1
+spawn:     2016-04-21T01:02:03Z
+ready:     -
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestTasksData(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+		c.Check(r.URL.RawQuery, check.Equals, "data=true")
+		fmt.Fprintln(w, mockChangeVerboseJSON)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"tasks", "--data", "42"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `(?ms)id: +1\n.*data:\n  a:  1\n  b:  {"c": "d"}\n\nid: +2\n.*`)
+}
@@ -0,0 +1,232 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var shortDebugStateHelp = i18n.G("Inspect a snapd state file")
+var longDebugStateHelp = i18n.G(`
+The state command reads the given snapd state file, without talking to
+snapd, and displays the changes it holds. The state journal next to the
+file (e.g. state.journal for state.json), if any, is replayed over it
+without being modified. With --change it displays the tasks
+of that change like the change command, and with --task every detail of that
+task, including its log entries and data.
+`)
+
+type cmdDebugState struct {
+	ChangeID string `long:"change"`
+	TaskID   string `long:"task"`
+
+	Positional struct {
+		StateFilePath string `positional-arg-name:"<state-file>" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	addDebugCommand("state", shortDebugStateHelp, longDebugStateHelp, func() flags.Commander { return &cmdDebugState{} },
+		map[string]string{
+			"change": i18n.G("Show the tasks of the given change."),
+			"task":   i18n.G("Show every detail of the given task."),
+		}, []argDesc{{
+			name: i18n.G("<state-file>"),
+			desc: i18n.G("The state file to inspect, usually /var/lib/snapd/state.json"),
+		}})
+}
+
+// stateJournalPath returns the path of the journal of the given state
+// file, state.journal for state.json.
+func stateJournalPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".journal"
+}
+
+// replayStateJournal returns the state resulting from replaying the
+// journal at journalPath, if any, over the given state, leaving the
+// journal untouched.
+func replayStateJournal(data []byte, journalPath string) ([]byte, error) {
+	f, err := os.Open(journalPath)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return state.ReplayJournal(data, f)
+}
+
+func loadState(path string) (*state.State, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.G("cannot open state file: %v"), err)
+	}
+
+	if replayed, err := replayStateJournal(data, stateJournalPath(path)); err != nil {
+		fmt.Fprintf(Stderr, i18n.G("WARNING: skipping the state journal: %v\n"), err)
+	} else {
+		data = replayed
+	}
+
+	st, err := state.ReadState(nil, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf(i18n.G("cannot read state file: %v"), err)
+	}
+	return st, nil
+}
+
+func (x *cmdDebugState) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if x.ChangeID != "" && x.TaskID != "" {
+		return fmt.Errorf(i18n.G("cannot use --change and --task together"))
+	}
+
+	st, err := loadState(x.Positional.StateFilePath)
+	if err != nil {
+		return err
+	}
+	st.Lock()
+	defer st.Unlock()
+
+	switch {
+	case x.ChangeID != "":
+		chg := st.Change(x.ChangeID)
+		if chg == nil {
+			return fmt.Errorf(i18n.G("cannot find change with id %q"), x.ChangeID)
+		}
+		showChangeTasks(stateChange2clientChange(chg))
+	case x.TaskID != "":
+		t := st.Task(x.TaskID)
+		if t == nil {
+			return fmt.Errorf(i18n.G("cannot find task with id %q"), x.TaskID)
+		}
+		showTaskVerbose(stateTask2clientTask(t, true))
+	default:
+		x.showChanges(st)
+	}
+
+	return nil
+}
+
+func (x *cmdDebugState) showChanges(st *state.State) {
+	chgs := st.Changes()
+	changes := make([]*client.Change, len(chgs))
+	for i, chg := range chgs {
+		changes[i] = stateChange2clientChange(chg)
+	}
+	sort.Sort(changesBySpawn(changes))
+
+	w := tabWriter()
+
+	fmt.Fprintf(w, i18n.G("ID\tStatus\tSpawn\tReady\tKind\tSummary\n"))
+	for _, chg := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", chg.ID, chg.Status, formatTaskTime(chg.SpawnTime), formatTaskTime(chg.ReadyTime), chg.Kind, chg.Summary)
+	}
+
+	w.Flush()
+}
+
+// changesBySpawn sorts changes by spawn time, and by id the changes
+// spawned at the same time.
+type changesBySpawn []*client.Change
+
+func (s changesBySpawn) Len() int      { return len(s) }
+func (s changesBySpawn) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s changesBySpawn) Less(i, j int) bool {
+	if !s[i].SpawnTime.Equal(s[j].SpawnTime) {
+		return s[i].SpawnTime.Before(s[j].SpawnTime)
+	}
+	if len(s[i].ID) != len(s[j].ID) {
+		return len(s[i].ID) < len(s[j].ID)
+	}
+	return s[i].ID < s[j].ID
+}
+
+func stateChange2clientChange(chg *state.Change) *client.Change {
+	status := chg.Status()
+	clientChg := &client.Change{
+		ID:      chg.ID(),
+		Kind:    chg.Kind(),
+		Summary: chg.Summary(),
+		Status:  status.String(),
+		Ready:   status.Ready(),
+
+		SpawnTime: chg.SpawnTime(),
+		ReadyTime: chg.ReadyTime(),
+	}
+	if err := chg.Err(); err != nil {
+		clientChg.Err = err.Error()
+	}
+	for _, t := range chg.Tasks() {
+		clientChg.Tasks = append(clientChg.Tasks, stateTask2clientTask(t, false))
+	}
+	return clientChg
+}
+
+func stateTask2clientTask(t *state.Task, withData bool) *client.Task {
+	label, done, total := t.Progress()
+	clientTask := &client.Task{
+		ID:       t.ID(),
+		Kind:     t.Kind(),
+		Summary:  t.Summary(),
+		Status:   t.Status().String(),
+		Log:      t.Log(),
+		Progress: client.TaskProgress{Label: label, Done: done, Total: total},
+
+		SpawnTime: t.SpawnTime(),
+		ReadyTime: t.ReadyTime(),
+		AtTime:    t.AtTime(),
+		Deadline:  t.Deadline(),
+	}
+	for _, entry := range t.LogEntries() {
+		clientTask.LogEntries = append(clientTask.LogEntries, client.TaskLogEntry{
+			Time:    entry.Time,
+			Kind:    entry.Kind,
+			Message: entry.Message,
+		})
+	}
+	if withData {
+		data := t.Data()
+		if len(data) > 0 {
+			clientTask.Data = make(map[string]json.RawMessage, len(data))
+			for k, v := range data {
+				clientTask.Data[k] = *v
+			}
+		}
+	}
+	return clientTask
+}
@@ -0,0 +1,159 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+func (s *SnapSuite) mockStateFile(c *check.C) (path string, ids []string) {
+	restore := state.MockTime(time.Date(2016, 04, 21, 1, 2, 3, 0, time.UTC))
+	defer restore()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg1 := st.NewChange("install-snap", "Install \"foo\" snap")
+	t1 := st.NewTask("download-snap", "Download snap \"foo\"")
+	t1.Set("snap-setup", map[string]string{"channel": "edge"})
+	t1.Errorf("cannot download: %s", "boom")
+	t1.SetStatus(state.ErrorStatus)
+	t2 := st.NewTask("link-snap", "Make snap \"foo\" available")
+	t2.SetStatus(state.HoldStatus)
+	chg1.AddTask(t1)
+	chg1.AddTask(t2)
+
+	chg2 := st.NewChange("remove-snap", "Remove \"bar\" snap")
+	t3 := st.NewTask("unlink-snap", "Make snap \"bar\" unavailable")
+	chg2.AddTask(t3)
+
+	data, err := json.Marshal(st)
+	c.Assert(err, check.IsNil)
+	path = filepath.Join(c.MkDir(), "state.json")
+	c.Assert(ioutil.WriteFile(path, data, 0644), check.IsNil)
+
+	return path, []string{chg1.ID(), chg2.ID(), t1.ID(), t2.ID(), t3.ID()}
+}
+
+func (s *SnapSuite) TestDebugStateChanges(c *check.C) {
+	path, ids := s.mockStateFile(c)
+
+	rest, err := snap.Parser().ParseArgs([]string{"debug", "state", path})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `ID +Status +Spawn +Ready +Kind +Summary
+`+ids[0]+` +Error +2016-04-21T01:02:03Z +- +install-snap +Install "foo" snap
+`+ids[1]+` +Do +2016-04-21T01:02:03Z +- +remove-snap +Remove "bar" snap
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugStateReplaysJournal(c *check.C) {
+	path, ids := s.mockStateFile(c)
+	journalPath := filepath.Join(filepath.Dir(path), "state.journal")
+	journal := `{"del":["changes/` + ids[1] + `"]}` + "\n"
+	c.Assert(ioutil.WriteFile(journalPath, []byte(journal), 0644), check.IsNil)
+
+	_, err := snap.Parser().ParseArgs([]string{"debug", "state", path})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `ID +Status +Spawn +Ready +Kind +Summary
+`+ids[0]+` +Error +2016-04-21T01:02:03Z +- +install-snap +Install "foo" snap
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+
+	// the journal is left alone
+	data, err := ioutil.ReadFile(journalPath)
+	c.Assert(err, check.IsNil)
+	c.Check(string(data), check.Equals, journal)
+}
+
+func (s *SnapSuite) TestDebugStateSkipsUnreadableJournal(c *check.C) {
+	path, ids := s.mockStateFile(c)
+	c.Assert(os.Mkdir(filepath.Join(filepath.Dir(path), "state.journal"), 0755), check.IsNil)
+
+	_, err := snap.Parser().ParseArgs([]string{"debug", "state", path})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `(?s)ID +Status.*\n`+ids[0]+` .*\n`+ids[1]+` .*\n`)
+	c.Check(s.Stderr(), check.Matches, "WARNING: skipping the state journal: cannot replay the state journal: .*\n")
+}
+
+func (s *SnapSuite) TestDebugStateChange(c *check.C) {
+	path, ids := s.mockStateFile(c)
+
+	_, err := snap.Parser().ParseArgs([]string{"debug", "state", "--change=" + ids[0], path})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `(?ms)Status +Spawn +Ready +Summary
+Error +2016-04-21T01:02:03Z +2016-04-21T01:02:03Z +Download snap "foo"
+Hold +2016-04-21T01:02:03Z +2016-04-21T01:02:03Z +Make snap "foo" available
+.*
+2016-04-21T01:02:03Z ERROR cannot download: boom
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugStateTask(c *check.C) {
+	path, ids := s.mockStateFile(c)
+
+	_, err := snap.Parser().ParseArgs([]string{"debug", "state", "--task=" + ids[2], path})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `id:        `+ids[2]+`
+kind:      download-snap
+summary:   Download snap "foo"
+status:    Error
+progress:  1/1
+spawn:     2016-04-21T01:02:03Z
+ready:     2016-04-21T01:02:03Z
+log:
+  2016-04-21T01:02:03Z ERROR cannot download: boom
+data:
+  snap-setup:  {"channel":"edge"}
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugStateErrors(c *check.C) {
+	path, _ := s.mockStateFile(c)
+	garbage := filepath.Join(c.MkDir(), "garbage.json")
+	c.Assert(ioutil.WriteFile(garbage, []byte("garbage"), 0644), check.IsNil)
+
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"debug", "state", "--change=99", path}, `cannot find change with id "99"`},
+		{[]string{"debug", "state", "--task=99", path}, `cannot find task with id "99"`},
+		{[]string{"debug", "state", "--change=1", "--task=1", path}, `cannot use --change and --task together`},
+		{[]string{"debug", "state", filepath.Join(c.MkDir(), "missing")}, `cannot open state file: .*`},
+		{[]string{"debug", "state", garbage}, `cannot read state file: .*`},
+	} {
+		_, err := snap.Parser().ParseArgs(t.args)
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
@@ -76,7 +76,7 @@ func listSnaps(names []string) error {
 	w := tabWriter()
 	defer w.Flush()
 
-	fmt.Fprintln(w, i18n.G("Name\tVersion\tRev\tDeveloper\tNotes"))
+	fmt.Fprintln(w, i18n.G("Name\tVersion\tRev\tTracking\tDeveloper\tNotes"))
 
 	for _, snap := range snaps {
 		// TODO: make JailMode a flag in the snap itself
@@ -92,13 +92,34 @@ func listSnaps(names []string) error {
 			//        diabled.
 			Disabled: snap.Status == client.StatusInstalled,
 			Broken:   snap.Broken != "",
+			Held:     snap.Held,
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", snap.Name, snap.Version, snap.Revision, snap.Developer, notes)
+		if snap.Held && !snap.PinnedRevision.Unset() {
+			notes.Pinned = snap.PinnedRevision.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", snap.Name, snap.Version, snap.Revision, trackingChannel(snap), snap.Developer, notes)
 	}
 
 	return nil
 }
 
+// trackingChannel shows the channel tracked by the snap, along with the
+// channel it was actually installed from if that is different (for
+// example because the tracked channel is closed).
+func trackingChannel(snap *client.Snap) string {
+	tracking := snap.TrackingChannel
+	if tracking == "" {
+		tracking = snap.Channel
+	}
+	if tracking == "" {
+		return "-"
+	}
+	if snap.Channel != "" && snap.Channel != tracking {
+		return fmt.Sprintf("%s (%s)", tracking, snap.Channel)
+	}
+	return tracking
+}
+
 func tabWriter() *tabwriter.Writer {
 	return tabwriter.NewWriter(Stdout, 5, 3, 2, ' ', 0)
 }
@@ -0,0 +1,75 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestLogs(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/logs")
+			c.Check(r.URL.Query().Get("names"), check.Equals, "foo,bar.svc")
+			c.Check(r.URL.Query().Get("n"), check.Equals, "-1")
+			c.Check(r.URL.Query().Get("follow"), check.Equals, "true")
+			w.Header().Set("Content-Type", "application/json-seq")
+			fmt.Fprint(w, "\x1e"+`{"timestamp":"2016-12-06T09:00:00Z","message":"hello","sid":"foo.svc","pid":"42"}`+"\n")
+			fmt.Fprint(w, "\x1e"+`{"timestamp":"2016-12-06T09:00:01Z","message":"bye","sid":"bar.svc","pid":"43"}`+"\n")
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"logs", "-n", "all", "-f", "foo", "bar.svc"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `2016-12-06T09:00:00Z foo.svc[42]: hello
+2016-12-06T09:00:01Z bar.svc[43]: bye
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestLogsDefaultN(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query().Get("n"), check.Equals, "10")
+		c.Check(r.URL.Query().Get("follow"), check.Equals, "")
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"logs", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestLogsBadN(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser().ParseArgs([]string{"logs", "-n", "foo", "foo"})
+	c.Assert(err, check.ErrorMatches, `invalid number of lines "foo", must be a non-negative number or 'all'`)
+}
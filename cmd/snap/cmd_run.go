@@ -21,13 +21,16 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
+	"path/filepath"
 	"strings"
 	"syscall"
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/snap"
@@ -179,12 +182,17 @@ func runSnapConfine(info *snap.Info, securityTag, snapApp, command, hook string,
 		logger.Noticef("WARNING: cannot create user data directory: %s", err)
 	}
 
-	cmd := []string{
-		"/usr/bin/ubuntu-core-launcher",
-		securityTag,
-		securityTag,
-		"/usr/lib/snapd/snap-exec",
+	cmd := []string{"/usr/bin/ubuntu-core-launcher"}
+	if info.NeedsClassic() {
+		// classic snaps run on the host filesystem, without a
+		// private mount namespace
+		cmd = append(cmd, "--classic")
+	} else if info.Base != "" {
+		// have the launcher pivot into the root filesystem of the
+		// base instead of the one of the OS snap
+		cmd = append(cmd, "--base", info.Base)
 	}
+	cmd = append(cmd, securityTag, securityTag, "/usr/lib/snapd/snap-exec")
 
 	if command != "" {
 		cmd = append(cmd, "--command="+command)
@@ -198,5 +206,15 @@ func runSnapConfine(info *snap.Info, securityTag, snapApp, command, hook string,
 	cmd = append(cmd, snapApp)
 	cmd = append(cmd, args...)
 
-	return syscallExec(cmd[0], cmd, snapenv.ExecEnv(info))
+	env := snapenv.ExecEnv(info)
+	// the cookie file is only readable by root, so only the snap's
+	// services get to use snapctl outside of hooks
+	cookie, err := ioutil.ReadFile(filepath.Join(dirs.SnapCookieDir, "snap."+info.Name()))
+	if err == nil {
+		env = append(env, "SNAP_COOKIE="+string(cookie))
+	} else if !os.IsNotExist(err) && !os.IsPermission(err) {
+		logger.Noticef("WARNING: cannot read cookie of snap %q: %s", info.Name(), err)
+	}
+
+	return syscallExec(cmd[0], cmd, env)
 }
@@ -21,6 +21,7 @@ package main_test
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/user"
@@ -102,6 +103,107 @@ func (s *SnapSuite) TestSnapRunAppIntegration(c *check.C) {
 	c.Check(execEnv, testutil.Contains, "SNAP_REVISION=42")
 }
 
+func (s *SnapSuite) TestSnapRunAppWithCookie(c *check.C) {
+	// mock installed snap
+	dirs.SetRootDir(c.MkDir())
+	defer func() { dirs.SetRootDir("/") }()
+
+	snaptest.MockSnap(c, string(mockYaml), &snap.SideInfo{
+		Revision: snap.R(42),
+	})
+	c.Assert(os.MkdirAll(dirs.SnapCookieDir, 0700), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapCookieDir, "snap.snapname"), []byte("snapname-cookie"), 0600), check.IsNil)
+
+	// and mock the server
+	s.mockServer(c)
+
+	// redirect exec
+	execEnv := []string{}
+	restorer := snaprun.MockSyscallExec(func(arg0 string, args []string, envv []string) error {
+		execEnv = envv
+		return nil
+	})
+	defer restorer()
+
+	_, err := snaprun.Parser().ParseArgs([]string{"run", "snapname.app"})
+	c.Assert(err, check.IsNil)
+	c.Check(execEnv, testutil.Contains, "SNAP_COOKIE=snapname-cookie")
+}
+
+func (s *SnapSuite) TestSnapRunAppWithBase(c *check.C) {
+	// mock installed snap
+	dirs.SetRootDir(c.MkDir())
+	defer func() { dirs.SetRootDir("/") }()
+
+	snaptest.MockSnap(c, `name: snapname
+version: 1.0
+base: some-base
+apps:
+ app:
+  command: run-app
+`, &snap.SideInfo{
+		Revision: snap.R(42),
+	})
+
+	// and mock the server
+	s.mockServer(c)
+
+	// redirect exec
+	execArgs := []string{}
+	restorer := snaprun.MockSyscallExec(func(arg0 string, args []string, envv []string) error {
+		execArgs = args
+		return nil
+	})
+	defer restorer()
+
+	_, err := snaprun.Parser().ParseArgs([]string{"run", "snapname.app"})
+	c.Assert(err, check.IsNil)
+	c.Check(execArgs, check.DeepEquals, []string{
+		"/usr/bin/ubuntu-core-launcher",
+		"--base", "some-base",
+		"snap.snapname.app",
+		"snap.snapname.app",
+		"/usr/lib/snapd/snap-exec",
+		"snapname.app"})
+}
+
+func (s *SnapSuite) TestSnapRunClassicApp(c *check.C) {
+	// mock installed snap
+	dirs.SetRootDir(c.MkDir())
+	defer func() { dirs.SetRootDir("/") }()
+
+	snaptest.MockSnap(c, `name: snapname
+version: 1.0
+confinement: classic
+apps:
+ app:
+  command: run-app
+`, &snap.SideInfo{
+		Revision: snap.R(42),
+	})
+
+	// and mock the server
+	s.mockServer(c)
+
+	// redirect exec
+	execArgs := []string{}
+	restorer := snaprun.MockSyscallExec(func(arg0 string, args []string, envv []string) error {
+		execArgs = args
+		return nil
+	})
+	defer restorer()
+
+	_, err := snaprun.Parser().ParseArgs([]string{"run", "snapname.app"})
+	c.Assert(err, check.IsNil)
+	c.Check(execArgs, check.DeepEquals, []string{
+		"/usr/bin/ubuntu-core-launcher",
+		"--classic",
+		"snap.snapname.app",
+		"snap.snapname.app",
+		"/usr/lib/snapd/snap-exec",
+		"snapname.app"})
+}
+
 func (s *SnapSuite) TestSnapRunAppWithCommandIntegration(c *check.C) {
 	// mock installed snap
 	dirs.SetRootDir(c.MkDir())
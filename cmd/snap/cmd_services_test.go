@@ -0,0 +1,125 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestServices(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/apps")
+			c.Check(r.URL.Query().Get("names"), check.Equals, "foo")
+			c.Check(r.URL.Query().Get("select"), check.Equals, "service")
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+  {"snap": "foo", "name": "svc1", "daemon": "simple", "enabled": true, "active": true},
+  {"snap": "foo", "name": "svc2", "daemon": "forking"}
+]}`)
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"services", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?ms)Service +Startup +Current
+foo.svc1 +enabled +active
+foo.svc2 +disabled +inactive
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestServicesNone(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"services"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "There are no services provided by installed snaps.\n")
+}
+
+func (s *SnapSuite) checkServiceAction(c *check.C, args []string, body map[string]interface{}) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/apps")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, body)
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs(args)
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestStartServices(c *check.C) {
+	s.checkServiceAction(c, []string{"start", "--enable", "foo", "bar.svc"}, map[string]interface{}{
+		"action": "start",
+		"names":  []interface{}{"foo", "bar.svc"},
+		"enable": true,
+	})
+}
+
+func (s *SnapSuite) TestStopServices(c *check.C) {
+	s.checkServiceAction(c, []string{"stop", "--disable", "foo"}, map[string]interface{}{
+		"action":  "stop",
+		"names":   []interface{}{"foo"},
+		"disable": true,
+	})
+}
+
+func (s *SnapSuite) TestRestartServices(c *check.C) {
+	s.checkServiceAction(c, []string{"restart", "foo.svc"}, map[string]interface{}{
+		"action": "restart",
+		"names":  []interface{}{"foo.svc"},
+	})
+}
+
+func (s *SnapSuite) TestStartServicesNeedsNames(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser().ParseArgs([]string{"start"})
+	c.Assert(err, check.ErrorMatches, `the required argument .* was not provided`)
+}
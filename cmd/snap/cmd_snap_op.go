@@ -132,12 +132,26 @@ The install command installs the named snap in the system.
 var longRemoveHelp = i18n.G(`
 The remove command removes the named snap from the system.
 
-The snap's data is currently not removed; use purge for that. This behaviour
-will change before 16.04 is final.
+By default a snapshot of the snap's data is saved before it is removed; the
+snapshot can be restored with 'snap restore' if the snap is installed again.
+Use --purge to remove the data without saving a snapshot of it.
 `)
 
 var longRefreshHelp = i18n.G(`
 The refresh command refreshes (updates) the named snap.
+
+With --hold, refreshes of the named snap are held back for the given
+duration (e.g. 72h), or indefinitely if none is given, keeping it on its
+current revision. --unhold allows refreshes of the snap again.
+
+With --at, the refresh is scheduled to start no earlier than the given time,
+either a time of day (e.g. 02:00) for its next occurrence or an RFC3339
+time, and the command returns without waiting for it. Until then other
+changes to the snap are not held back by the scheduled refresh.
+
+With --deadline, given in the same way, the refresh is undone if it is
+not done by then; a time of day is its next occurrence after --at, if
+given.
 `)
 
 var longTryHelp = i18n.G(`
@@ -159,6 +173,7 @@ and the snap can easily be enabled again.
 
 type cmdRemove struct {
 	Revision   string `long:"revision"`
+	Purge      bool   `long:"purge"`
 	Positional struct {
 		Snaps []string `positional-arg-name:"<snap>"`
 	} `positional-args:"yes" required:"yes"`
@@ -209,7 +224,7 @@ func (x *cmdRemove) removeMany(opts *client.SnapOptions) error {
 }
 
 func (x *cmdRemove) Execute([]string) error {
-	opts := &client.SnapOptions{Revision: x.Revision}
+	opts := &client.SnapOptions{Revision: x.Revision, Purge: x.Purge}
 	if len(x.Positional.Snaps) == 1 {
 		return x.removeOne(opts)
 	}
@@ -217,6 +232,9 @@ func (x *cmdRemove) Execute([]string) error {
 	if x.Revision != "" {
 		return errors.New(i18n.G("a single snap name is needed to specify the revision"))
 	}
+	if x.Purge {
+		return errors.New(i18n.G("a single snap name is needed to use --purge"))
+	}
 	return x.removeMany(nil)
 }
 
@@ -313,24 +331,30 @@ func (mx *channelMixin) asksForChannel() bool {
 type modeMixin struct {
 	DevMode  bool `long:"devmode"`
 	JailMode bool `long:"jailmode"`
+	Classic  bool `long:"classic"`
 }
 
 var modeDescs = mixinDescs{
 	"devmode":  i18n.G("Request non-enforcing security"),
 	"jailmode": i18n.G("Override a snap's request for non-enforcing security"),
+	"classic":  i18n.G("Put snap in classic mode and disable security confinement"),
 }
 
 var errModeConflict = errors.New(i18n.G("cannot use devmode and jailmode flags together"))
+var errClassicJailMode = errors.New(i18n.G("cannot use classic and jailmode flags together"))
 
 func (mx modeMixin) validateMode() error {
 	if mx.DevMode && mx.JailMode {
 		return errModeConflict
 	}
+	if mx.Classic && mx.JailMode {
+		return errClassicJailMode
+	}
 	return nil
 }
 
 func (mx modeMixin) asksForMode() bool {
-	return mx.DevMode || mx.JailMode
+	return mx.DevMode || mx.JailMode || mx.Classic
 }
 
 type cmdInstall struct {
@@ -426,6 +450,7 @@ func (x *cmdInstall) Execute([]string) error {
 		Channel:   x.Channel,
 		DevMode:   x.DevMode,
 		JailMode:  x.JailMode,
+		Classic:   x.Classic,
 		Revision:  x.Revision,
 		Dangerous: dangerous,
 	}
@@ -447,6 +472,10 @@ type cmdRefresh struct {
 
 	Revision   string `long:"revision"`
 	List       bool   `long:"list"`
+	Hold       string `long:"hold" optional:"yes" optional-value:"forever"`
+	Unhold     bool   `long:"unhold"`
+	At         string `long:"at"`
+	Deadline   string `long:"deadline"`
 	Positional struct {
 		Snaps []string `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -459,6 +488,11 @@ func refreshMany(snaps []string, opts *client.SnapOptions) error {
 		return err
 	}
 
+	if opts != nil && opts.At != nil {
+		showScheduled(changeID, *opts.At)
+		return nil
+	}
+
 	chg, err := wait(cli, changeID)
 	if err != nil {
 		return err
@@ -485,6 +519,11 @@ func refreshOne(name string, opts *client.SnapOptions) error {
 		return err
 	}
 
+	if opts.At != nil {
+		showScheduled(changeID, *opts.At)
+		return nil
+	}
+
 	if _, err := wait(cli, changeID); err != nil {
 		return err
 	}
@@ -492,6 +531,68 @@ func refreshOne(name string, opts *client.SnapOptions) error {
 	return showDone([]string{name}, "upgrade")
 }
 
+func showScheduled(changeID string, at time.Time) {
+	fmt.Fprintf(Stdout, i18n.G("Refresh scheduled for %s, see \"snap change %s\".\n"), at.Format(time.RFC3339), changeID)
+}
+
+// parseAt parses the value of the given time option, either a time of
+// day as HH:MM for its next occurrence after now, or an RFC3339 time.
+func parseAt(option, value string, now time.Time) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(i18n.G("invalid time for %s: %q, expected HH:MM or an RFC3339 time"), option, value)
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at, nil
+}
+
+func holdRefreshes(name, duration string) error {
+	var until time.Time
+	if duration != "forever" {
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			return fmt.Errorf(i18n.G("invalid duration for --hold: %q"), duration)
+		}
+		until = time.Now().Add(d)
+	}
+
+	cli := Client()
+	changeID, err := cli.Hold(name, until)
+	if err != nil {
+		return err
+	}
+	if _, err := wait(cli, changeID); err != nil {
+		return err
+	}
+
+	if until.IsZero() {
+		fmt.Fprintf(Stdout, i18n.G("Refreshes of %q are held indefinitely.\n"), name)
+	} else {
+		fmt.Fprintf(Stdout, i18n.G("Refreshes of %q are held until %s.\n"), name, until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func unholdRefreshes(name string) error {
+	cli := Client()
+	changeID, err := cli.Unhold(name)
+	if err != nil {
+		return err
+	}
+	if _, err := wait(cli, changeID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Refreshes of %q are allowed again.\n"), name)
+	return nil
+}
+
 func listRefresh() error {
 	cli := Client()
 	snaps, _, err := cli.Find(&client.FindOptions{
@@ -530,6 +631,27 @@ func (x *cmdRefresh) Execute([]string) error {
 		return err
 	}
 
+	if (x.At != "" || x.Deadline != "") && (x.List || x.Hold != "" || x.Unhold) {
+		return errors.New(i18n.G("cannot use --at or --deadline with --list, --hold or --unhold"))
+	}
+	now := time.Now()
+	var at, deadline *time.Time
+	if x.At != "" {
+		when, err := parseAt("--at", x.At, now)
+		if err != nil {
+			return err
+		}
+		at = &when
+		now = when
+	}
+	if x.Deadline != "" {
+		when, err := parseAt("--deadline", x.Deadline, now)
+		if err != nil {
+			return err
+		}
+		deadline = &when
+	}
+
 	if x.List {
 		if x.asksForMode() || x.asksForChannel() {
 			return errors.New(i18n.G("--list does not take mode nor channel flags"))
@@ -537,12 +659,30 @@ func (x *cmdRefresh) Execute([]string) error {
 
 		return listRefresh()
 	}
+	if x.Hold != "" || x.Unhold {
+		if x.Hold != "" && x.Unhold {
+			return errors.New(i18n.G("cannot use --hold and --unhold together"))
+		}
+		if len(x.Positional.Snaps) != 1 {
+			return errors.New(i18n.G("a single snap name is needed to hold or unhold refreshes"))
+		}
+		if x.asksForMode() || x.asksForChannel() || x.Revision != "" {
+			return errors.New(i18n.G("--hold and --unhold do not take mode, channel nor revision flags"))
+		}
+		if x.Unhold {
+			return unholdRefreshes(x.Positional.Snaps[0])
+		}
+		return holdRefreshes(x.Positional.Snaps[0], x.Hold)
+	}
 	if len(x.Positional.Snaps) == 1 {
 		opts := &client.SnapOptions{
 			Channel:  x.Channel,
 			DevMode:  x.DevMode,
 			JailMode: x.JailMode,
+			Classic:  x.Classic,
 			Revision: x.Revision,
+			At:       at,
+			Deadline: deadline,
 		}
 		return refreshOne(x.Positional.Snaps[0], opts)
 	}
@@ -551,7 +691,11 @@ func (x *cmdRefresh) Execute([]string) error {
 		return errors.New(i18n.G("a single snap name is needed to specify mode or channel flags"))
 	}
 
-	return refreshMany(x.Positional.Snaps, nil)
+	var opts *client.SnapOptions
+	if at != nil || deadline != nil {
+		opts = &client.SnapOptions{At: at, Deadline: deadline}
+	}
+	return refreshMany(x.Positional.Snaps, opts)
 }
 
 type cmdTry struct {
@@ -570,6 +714,7 @@ func (x *cmdTry) Execute([]string) error {
 	opts := &client.SnapOptions{
 		DevMode:  x.DevMode,
 		JailMode: x.JailMode,
+		Classic:  x.Classic,
 	}
 
 	path, err := filepath.Abs(name)
@@ -688,7 +833,7 @@ func (x *cmdRevert) Execute(args []string) error {
 
 	cli := Client()
 	name := x.Positional.Snap
-	opts := &client.SnapOptions{DevMode: x.DevMode, JailMode: x.JailMode, Revision: x.Revision}
+	opts := &client.SnapOptions{DevMode: x.DevMode, JailMode: x.JailMode, Classic: x.Classic, Revision: x.Revision}
 	changeID, err := cli.Revert(name, opts)
 	if err != nil {
 		return err
@@ -712,9 +857,54 @@ func (x *cmdRevert) Execute(args []string) error {
 	return nil
 }
 
+type cmdSwitch struct {
+	channelMixin
+	Positional struct {
+		Snap string `positional-arg-name:"<snap>" required:"yes"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+var shortSwitchHelp = i18n.G("Switches snap to a different channel")
+var longSwitchHelp = i18n.G(`
+The switch command changes the channel tracked by the given snap,
+without refreshing it. The snap is refreshed from the new channel
+the next time it is refreshed.
+`)
+
+func (x *cmdSwitch) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	if err := x.setChannelFromCommandline(); err != nil {
+		return err
+	}
+	if x.Channel == "" {
+		return fmt.Errorf(i18n.G("missing --channel=<channel-name> parameter"))
+	}
+
+	cli := Client()
+	name := x.Positional.Snap
+	opts := &client.SnapOptions{Channel: x.Channel}
+	changeID, err := cli.Switch(name, opts)
+	if err != nil {
+		return err
+	}
+
+	if _, err := wait(cli, changeID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("%q switched to the %q channel\n"), name, x.Channel)
+	return nil
+}
+
 func init() {
 	addCommand("remove", shortRemoveHelp, longRemoveHelp, func() flags.Commander { return &cmdRemove{} },
-		map[string]string{"revision": i18n.G("Remove only the given revision")}, nil)
+		map[string]string{
+			"revision": i18n.G("Remove only the given revision"),
+			"purge":    i18n.G("Remove the snap's data without saving a snapshot of it first"),
+		}, nil)
 	addCommand("install", shortInstallHelp, longInstallHelp, func() flags.Commander { return &cmdInstall{} },
 		channelDescs.also(modeDescs).also(map[string]string{
 			"revision":        i18n.G("Install the given revision of a snap, to which you must have developer access"),
@@ -725,6 +915,10 @@ func init() {
 		channelDescs.also(modeDescs).also(map[string]string{
 			"revision": i18n.G("Refresh to the given revision"),
 			"list":     i18n.G("Show available snaps for refresh"),
+			"hold":     i18n.G("Hold back refreshes of the snap for the given duration, or indefinitely"),
+			"unhold":   i18n.G("Allow refreshes of a held snap again"),
+			"at":       i18n.G("Schedule the refresh to start no earlier than the given time"),
+			"deadline": i18n.G("Undo the refresh if it is not done by the given time"),
 		}), nil)
 	addCommand("try", shortTryHelp, longTryHelp, func() flags.Commander { return &cmdTry{} }, modeDescs, nil)
 	addCommand("enable", shortEnableHelp, longEnableHelp, func() flags.Commander { return &cmdEnable{} }, nil, nil)
@@ -732,4 +926,5 @@ func init() {
 	addCommand("revert", shortRevertHelp, longRevertHelp, func() flags.Commander { return &cmdRevert{} }, modeDescs.also(map[string]string{
 		"revision": "Revert to the given revision",
 	}), nil)
+	addCommand("switch", shortSwitchHelp, longSwitchHelp, func() flags.Commander { return &cmdSwitch{} }, channelDescs, nil)
 }
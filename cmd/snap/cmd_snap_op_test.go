@@ -344,6 +344,171 @@ func (s *SnapOpSuite) TestRefreshOne(c *check.C) {
 	c.Assert(err, check.IsNil)
 }
 
+func (s *SnapOpSuite) TestRefreshHold(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/one")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "hold",
+		})
+	}
+	_, err := snap.Parser().ParseArgs([]string{"refresh", "--hold", "one"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Refreshes of \"one\" are held indefinitely.\n")
+}
+
+func (s *SnapOpSuite) TestRefreshHoldDuration(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/one")
+		body := DecodedRequestBody(c, r)
+		c.Check(body["action"], check.Equals, "hold")
+		until, err := time.Parse(time.RFC3339, body["hold-until"].(string))
+		c.Assert(err, check.IsNil)
+		c.Check(until.Sub(time.Now()) > 71*time.Hour, check.Equals, true)
+		c.Check(until.Sub(time.Now()) <= 72*time.Hour, check.Equals, true)
+	}
+	_, err := snap.Parser().ParseArgs([]string{"refresh", "--hold=72h", "one"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `Refreshes of "one" are held until .*\.\n`)
+}
+
+func (s *SnapOpSuite) TestRefreshUnhold(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/one")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "unhold",
+		})
+	}
+	_, err := snap.Parser().ParseArgs([]string{"refresh", "--unhold", "one"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Refreshes of \"one\" are allowed again.\n")
+}
+
+func (s *SnapOpSuite) TestRefreshHoldErrors(c *check.C) {
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"refresh", "--hold=bogus", "one"}, `invalid duration for --hold: "bogus"`},
+		{[]string{"refresh", "--hold=-1h", "one"}, `invalid duration for --hold: "-1h"`},
+		{[]string{"refresh", "--hold", "--unhold", "one"}, `cannot use --hold and --unhold together`},
+		{[]string{"refresh", "--hold"}, `a single snap name is needed to hold or unhold refreshes`},
+		{[]string{"refresh", "--unhold", "one", "two"}, `a single snap name is needed to hold or unhold refreshes`},
+		{[]string{"refresh", "--hold", "--beta", "one"}, `--hold and --unhold do not take mode, channel nor revision flags`},
+	} {
+		_, err := snap.Parser().ParseArgs(t.args)
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
+
+func (s *SnapOpSuite) TestRefreshAt(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/one")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "refresh",
+			"at":     "2030-01-02T02:00:00Z",
+		})
+	}
+	_, err := snap.Parser().ParseArgs([]string{"refresh", "--at=2030-01-02T02:00:00Z", "one"})
+	c.Assert(err, check.IsNil)
+	// the scheduled change is not waited for
+	c.Check(s.srv.n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "Refresh scheduled for 2030-01-02T02:00:00Z, see \"snap change 42\".\n")
+}
+
+func (s *SnapOpSuite) TestRefreshAllAt(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		body := DecodedRequestBody(c, r)
+		c.Check(body["action"], check.Equals, "refresh")
+		at, err := time.Parse(time.RFC3339, body["at"].(string))
+		c.Assert(err, check.IsNil)
+		c.Check(at.After(time.Now()), check.Equals, true)
+		c.Check(at.Sub(time.Now()) <= 24*time.Hour, check.Equals, true)
+		c.Check(at.Local().Format("15:04"), check.Equals, "02:00")
+	}
+	_, err := snap.Parser().ParseArgs([]string{"refresh", "--at=02:00"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.srv.n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Matches, `Refresh scheduled for .*, see "snap change 42".\n`)
+}
+
+func (s *SnapOpSuite) TestRefreshAtDeadline(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":   "refresh",
+			"at":       "2030-01-02T02:00:00Z",
+			"deadline": "2030-01-02T04:00:00Z",
+		})
+	}
+	_, err := snap.Parser().ParseArgs([]string{"refresh", "--at=2030-01-02T02:00:00Z", "--deadline=2030-01-02T04:00:00Z"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.srv.n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "Refresh scheduled for 2030-01-02T02:00:00Z, see \"snap change 42\".\n")
+}
+
+func (s *SnapOpSuite) TestRefreshDeadline(c *check.C) {
+	s.RedirectClientToTestServer(s.srv.handle)
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/one")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":   "refresh",
+			"deadline": "2030-01-02T04:00:00Z",
+		})
+	}
+	_, err := snap.Parser().ParseArgs([]string{"refresh", "--deadline=2030-01-02T04:00:00Z", "one"})
+	c.Assert(err, check.IsNil)
+	// without --at the change is waited for
+	c.Check(s.srv.n, check.Equals, 4)
+}
+
+func (s *SnapOpSuite) TestRefreshAtErrors(c *check.C) {
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"refresh", "--at=bogus", "one"}, `invalid time for --at: "bogus", expected HH:MM or an RFC3339 time`},
+		{[]string{"refresh", "--at=25:00", "one"}, `invalid time for --at: "25:00", expected HH:MM or an RFC3339 time`},
+		{[]string{"refresh", "--deadline=bogus", "one"}, `invalid time for --deadline: "bogus", expected HH:MM or an RFC3339 time`},
+		{[]string{"refresh", "--at=02:00", "--list"}, `cannot use --at or --deadline with --list, --hold or --unhold`},
+		{[]string{"refresh", "--at=02:00", "--hold", "one"}, `cannot use --at or --deadline with --list, --hold or --unhold`},
+		{[]string{"refresh", "--deadline=02:00", "--unhold", "one"}, `cannot use --at or --deadline with --list, --hold or --unhold`},
+	} {
+		_, err := snap.Parser().ParseArgs(t.args)
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
+
+func (s *SnapOpSuite) TestParseAt(c *check.C) {
+	now := time.Date(2017, 1, 2, 10, 30, 0, 0, time.UTC)
+	for _, t := range []struct {
+		value string
+		at    time.Time
+	}{
+		{"11:00", time.Date(2017, 1, 2, 11, 0, 0, 0, time.UTC)},
+		{"02:00", time.Date(2017, 1, 3, 2, 0, 0, 0, time.UTC)},
+		{"10:30", time.Date(2017, 1, 3, 10, 30, 0, 0, time.UTC)},
+		{"2017-01-05T02:00:00Z", time.Date(2017, 1, 5, 2, 0, 0, 0, time.UTC)},
+	} {
+		at, err := snap.ParseAt("--at", t.value, now)
+		c.Assert(err, check.IsNil)
+		c.Check(at.Equal(t.at), check.Equals, true, check.Commentf("%s: %s", t.value, at))
+	}
+}
+
 func (s *SnapOpSuite) TestRefreshOneSwitchChannel(c *check.C) {
 	s.RedirectClientToTestServer(s.srv.handle)
 	s.srv.checker = func(r *http.Request) {
@@ -392,6 +557,12 @@ func (s *SnapOpSuite) TestRefreshOneModeErr(c *check.C) {
 	c.Assert(err, check.ErrorMatches, `cannot use devmode and jailmode flags together`)
 }
 
+func (s *SnapOpSuite) TestInstallClassicJailModeErr(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser().ParseArgs([]string{"install", "--classic", "--jailmode", "one"})
+	c.Assert(err, check.ErrorMatches, `cannot use classic and jailmode flags together`)
+}
+
 func (s *SnapOpSuite) TestRefreshOneChanErr(c *check.C) {
 	s.RedirectClientToTestServer(nil)
 	_, err := snap.Parser().ParseArgs([]string{"refresh", "--beta", "--channel=foo", "one"})
@@ -524,6 +695,32 @@ func (s *SnapOpSuite) TestDisable(c *check.C) {
 	c.Check(s.srv.n, check.Equals, s.srv.total)
 }
 
+func (s *SnapOpSuite) TestSwitch(c *check.C) {
+	s.srv.total = 3
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":  "switch",
+			"channel": "beta",
+		})
+	}
+
+	s.RedirectClientToTestServer(s.srv.handle)
+	rest, err := snap.Parser().ParseArgs([]string{"switch", "--beta", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `"foo" switched to the "beta" channel`+"\n")
+	c.Check(s.Stderr(), check.Equals, "")
+	// ensure that the fake server api was actually hit
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestSwitchNoChannel(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser().ParseArgs([]string{"switch", "foo"})
+	c.Assert(err, check.ErrorMatches, `missing --channel=<channel-name> parameter`)
+}
+
 func (s *SnapOpSuite) TestRemove(c *check.C) {
 	s.srv.total = 3
 	s.srv.checker = func(r *http.Request) {
@@ -543,6 +740,31 @@ func (s *SnapOpSuite) TestRemove(c *check.C) {
 	c.Check(s.srv.n, check.Equals, s.srv.total)
 }
 
+func (s *SnapOpSuite) TestRemovePurge(c *check.C) {
+	s.srv.total = 3
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "remove",
+			"purge":  true,
+		})
+	}
+
+	s.RedirectClientToTestServer(s.srv.handle)
+	rest, err := snap.Parser().ParseArgs([]string{"remove", "--purge", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?sm).*foo removed`)
+	// ensure that the fake server api was actually hit
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestRemoveManyPurge(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser().ParseArgs([]string{"remove", "--purge", "one", "two"})
+	c.Assert(err, check.ErrorMatches, `a single snap name is needed to use --purge`)
+}
+
 func (s *SnapOpSuite) TestRemoveManyRevision(c *check.C) {
 	s.RedirectClientToTestServer(nil)
 	_, err := snap.Parser().ParseArgs([]string{"remove", "--revision=17", "one", "two"})
@@ -0,0 +1,222 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var (
+	shortSaveHelp    = i18n.G("Saves a snapshot of the data of snaps")
+	shortSavedHelp   = i18n.G("Lists the saved snapshots")
+	shortRestoreHelp = i18n.G("Restores the data of snaps from a snapshot")
+	shortForgetHelp  = i18n.G("Deletes a snapshot")
+)
+
+var longSaveHelp = i18n.G(`
+The save command saves a snapshot of the system and user data of the
+given snaps (or of all installed snaps, if none are given) as a new
+snapshot set. The data of all users is saved, unless --users is given.
+`)
+
+var longSavedHelp = i18n.G(`
+The saved command lists the snapshots that have been saved, optionally
+limited to a given snapshot set and to the given snaps.
+`)
+
+var longRestoreHelp = i18n.G(`
+The restore command replaces the current data of the given snaps (or of
+all the snaps in the snapshot set, if none are given) with the data from
+the given snapshot set. The data is restored for all users, unless --users
+is given.
+`)
+
+var longForgetHelp = i18n.G(`
+The forget command deletes the snapshots of the given snaps (or of all
+the snaps in the snapshot set, if none are given) from the given
+snapshot set.
+`)
+
+type cmdSaveSnapshot struct {
+	Users      string `long:"users"`
+	Positional struct {
+		Snaps []string `positional-arg-name:"<snap>"`
+	} `positional-args:"yes"`
+}
+
+type cmdSavedSnapshots struct {
+	ID         uint64 `long:"id"`
+	Positional struct {
+		Snaps []string `positional-arg-name:"<snap>"`
+	} `positional-args:"yes"`
+}
+
+type cmdRestoreSnapshot struct {
+	Users      string `long:"users"`
+	Positional struct {
+		ID    string   `positional-arg-name:"<set-id>" required:"yes"`
+		Snaps []string `positional-arg-name:"<snap>"`
+	} `positional-args:"yes"`
+}
+
+type cmdForgetSnapshot struct {
+	Positional struct {
+		ID    string   `positional-arg-name:"<set-id>" required:"yes"`
+		Snaps []string `positional-arg-name:"<snap>"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	addCommand("save", shortSaveHelp, longSaveHelp, func() flags.Commander { return &cmdSaveSnapshot{} },
+		map[string]string{"users": i18n.G("Save the data of only the given comma-separated users")}, nil)
+	addCommand("saved", shortSavedHelp, longSavedHelp, func() flags.Commander { return &cmdSavedSnapshots{} },
+		map[string]string{"id": i18n.G("Show only the given snapshot set")}, nil)
+	addCommand("restore", shortRestoreHelp, longRestoreHelp, func() flags.Commander { return &cmdRestoreSnapshot{} },
+		map[string]string{"users": i18n.G("Restore the data of only the given comma-separated users")}, nil)
+	addCommand("forget", shortForgetHelp, longForgetHelp, func() flags.Commander { return &cmdForgetSnapshot{} }, nil, nil)
+}
+
+func splitUsers(users string) []string {
+	if users == "" {
+		return nil
+	}
+	return strings.Split(users, ",")
+}
+
+func parseSetID(id string) (uint64, error) {
+	setID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || setID == 0 {
+		return 0, fmt.Errorf(i18n.G("invalid snapshot set id %q"), id)
+	}
+	return setID, nil
+}
+
+// humanSize returns the given size in bytes in a short human-readable form.
+func humanSize(size int64) string {
+	const units = "kMGTPE"
+	if size < 1000 {
+		return fmt.Sprintf("%dB", size)
+	}
+	f := float64(size)
+	i := -1
+	for f >= 1000 && i < len(units)-1 {
+		f /= 1000
+		i++
+	}
+	return fmt.Sprintf("%.1f%cB", f, units[i])
+}
+
+func showSnapshotSets(sets []client.SnapshotSet) {
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("Set\tSnap\tTime\tVersion\tRev\tSize"))
+	for _, set := range sets {
+		for _, sh := range set.Snapshots {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", set.ID, sh.Snap, sh.Time.UTC().Format(time.RFC3339), sh.Version, sh.Revision, humanSize(sh.Size))
+		}
+	}
+	w.Flush()
+}
+
+func (x *cmdSaveSnapshot) Execute(args []string) error {
+	cli := Client()
+	changeID, err := cli.SnapshotMany(x.Positional.Snaps, splitUsers(x.Users))
+	if err != nil {
+		return err
+	}
+
+	chg, err := wait(cli, changeID)
+	if err != nil {
+		return err
+	}
+
+	var setID uint64
+	if err := chg.Get("set-id", &setID); err != nil {
+		return err
+	}
+
+	sets, err := cli.SnapshotSets(setID, nil)
+	if err != nil {
+		return err
+	}
+	showSnapshotSets(sets)
+
+	return nil
+}
+
+func (x *cmdSavedSnapshots) Execute(args []string) error {
+	sets, err := Client().SnapshotSets(x.ID, x.Positional.Snaps)
+	if err != nil {
+		return err
+	}
+	if len(sets) == 0 {
+		return fmt.Errorf(i18n.G("no snapshots found"))
+	}
+	showSnapshotSets(sets)
+
+	return nil
+}
+
+func (x *cmdRestoreSnapshot) Execute(args []string) error {
+	setID, err := parseSetID(x.Positional.ID)
+	if err != nil {
+		return err
+	}
+
+	cli := Client()
+	changeID, err := cli.RestoreSnapshots(setID, x.Positional.Snaps, splitUsers(x.Users))
+	if err != nil {
+		return err
+	}
+
+	if _, err := wait(cli, changeID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Restored snapshot #%d.\n"), setID)
+	return nil
+}
+
+func (x *cmdForgetSnapshot) Execute(args []string) error {
+	setID, err := parseSetID(x.Positional.ID)
+	if err != nil {
+		return err
+	}
+
+	cli := Client()
+	changeID, err := cli.ForgetSnapshots(setID, x.Positional.Snaps)
+	if err != nil {
+		return err
+	}
+
+	if _, err := wait(cli, changeID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Snapshot #%d forgotten.\n"), setID)
+	return nil
+}
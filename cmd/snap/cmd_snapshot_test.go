@@ -0,0 +1,162 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const mockSnapshotSetsJSON = `{"type": "sync", "status-code": 200, "result": [
+  {"id": 1, "snapshots": [
+    {"set": 1, "time": "2016-12-06T09:00:00Z", "snap": "bar", "revision": "3", "version": "2.0", "sha3-384": {}, "size": 123},
+    {"set": 1, "time": "2016-12-06T09:00:01Z", "snap": "foo", "revision": "x1", "version": "1.0", "sha3-384": {}, "size": 1234567}
+  ]}
+]}`
+
+func (s *SnapSuite) TestSaveSnapshot(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snapshots")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+				"action": "save",
+				"set":    0.,
+				"snaps":  []interface{}{"foo", "bar"},
+				"users":  []interface{}{"user1", "user2"},
+			})
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {"set-id": 1}}}`)
+		case 2:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snapshots")
+			c.Check(r.URL.Query().Get("set"), check.Equals, "1")
+			fmt.Fprintln(w, mockSnapshotSetsJSON)
+		default:
+			c.Fatalf("expected to get 3 requests, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"save", "--users=user1,user2", "foo", "bar"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?ms)Set +Snap +Time +Version +Rev +Size
+1 +bar +2016-12-06T09:00:00Z +2.0 +3 +123B
+1 +foo +2016-12-06T09:00:01Z +1.0 +x1 +1.2MB
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 3)
+}
+
+func (s *SnapSuite) TestSavedSnapshots(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snapshots")
+			c.Check(r.URL.Query().Get("set"), check.Equals, "1")
+			c.Check(r.URL.Query().Get("snaps"), check.Equals, "foo,bar")
+			fmt.Fprintln(w, mockSnapshotSetsJSON)
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"saved", "--id=1", "foo", "bar"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?ms)Set +Snap +Time +Version +Rev +Size
+1 +bar .*
+1 +foo .*
+`)
+}
+
+func (s *SnapSuite) TestSavedSnapshotsNone(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"saved"})
+	c.Assert(err, check.ErrorMatches, "no snapshots found")
+}
+
+func (s *SnapSuite) checkSnapshotAction(c *check.C, args []string, body map[string]interface{}, stdout string) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snapshots")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, body)
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs(args)
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, stdout)
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestRestoreSnapshot(c *check.C) {
+	s.checkSnapshotAction(c, []string{"restore", "--users=user1", "42", "foo"}, map[string]interface{}{
+		"action": "restore",
+		"set":    42.,
+		"snaps":  []interface{}{"foo"},
+		"users":  []interface{}{"user1"},
+	}, "Restored snapshot #42.\n")
+}
+
+func (s *SnapSuite) TestForgetSnapshot(c *check.C) {
+	s.checkSnapshotAction(c, []string{"forget", "42"}, map[string]interface{}{
+		"action": "forget",
+		"set":    42.,
+	}, "Snapshot #42 forgotten.\n")
+}
+
+func (s *SnapSuite) TestRestoreSnapshotBadSetID(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser().ParseArgs([]string{"restore", "foo"})
+	c.Assert(err, check.ErrorMatches, `invalid snapshot set id "foo"`)
+	_, err = snap.Parser().ParseArgs([]string{"forget", "0"})
+	c.Assert(err, check.ErrorMatches, `invalid snapshot set id "0"`)
+}
@@ -0,0 +1,109 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var (
+	shortWatchHelp = i18n.G("Watch changes, tasks and snaps as they progress")
+	longWatchHelp  = i18n.G(`
+The watch command prints the events published by snapd as they happen: the
+status and progress of the given change and its tasks until the change is
+ready or, with --all, the events of all changes, snaps and interfaces until
+interrupted.
+`)
+)
+
+type cmdWatch struct {
+	Positional struct {
+		ID string `positional-arg-name:"<change-id>"`
+	} `positional-args:"yes"`
+
+	All bool `long:"all"`
+}
+
+func init() {
+	addCommand("watch", shortWatchHelp, longWatchHelp, func() flags.Commander { return &cmdWatch{} },
+		map[string]string{
+			"all": i18n.G("Watch the events of all changes, snaps and interfaces."),
+		}, nil)
+}
+
+func (x *cmdWatch) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if x.All == (x.Positional.ID != "") {
+		return fmt.Errorf(i18n.G("need exactly one of a change id or --all"))
+	}
+
+	cli := Client()
+	events, err := cli.Events(&client.EventsOptions{ChangeID: x.Positional.ID})
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+
+	if !x.All {
+		// the change might have become ready before we subscribed
+		chg, err := cli.Change(x.Positional.ID)
+		if err != nil {
+			return err
+		}
+		if chg.Ready {
+			fmt.Fprintf(Stdout, i18n.G("change %s is already ready (%s)\n"), chg.ID, chg.Status)
+			return nil
+		}
+	}
+
+	for {
+		ev, err := events.Next()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(Stdout, "%s %s %s: %s\n", ev.Timestamp.UTC().Format(time.RFC3339), ev.Type, ev.Resource, describeEvent(ev))
+		if !x.All && ev.Type == "change-update" && ev.Metadata["ready"] == true {
+			return nil
+		}
+	}
+}
+
+func describeEvent(ev *client.Event) string {
+	m := ev.Metadata
+	switch ev.Type {
+	case "change-update", "task-update":
+		return fmt.Sprintf("%v %v -> %v", m["summary"], m["old-status"], m["status"])
+	case "task-progress":
+		if progress, ok := m["progress"].(map[string]interface{}); ok {
+			return fmt.Sprintf("%v %v/%v", m["summary"], progress["done"], progress["total"])
+		}
+	case "snap", "interface":
+		return fmt.Sprintf("%v %v", m["action"], m["snap-name"])
+	}
+	return fmt.Sprintf("%v", m)
+}
@@ -0,0 +1,108 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) serveEvents(c *check.C, w http.ResponseWriter, r *http.Request, events ...string) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	c.Assert(err, check.IsNil)
+	defer conn.Close()
+	for _, ev := range events {
+		c.Check(conn.WriteMessage(websocket.TextMessage, []byte(ev)), check.IsNil)
+	}
+}
+
+func (s *SnapSuite) TestWatchChange(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/events":
+			c.Check(r.URL.Query().Get("change"), check.Equals, "42")
+			s.serveEvents(c, w, r,
+				`{"timestamp":1481014800000000000,"type":"task-progress","resource":"/v2/changes/42","metadata":{"change-id":"42","summary":"Download foo","progress":{"done":5,"total":10}}}`,
+				`{"timestamp":1481014801000000000,"type":"change-update","resource":"/v2/changes/42","metadata":{"change-id":"42","summary":"Install foo","old-status":"Doing","status":"Done","ready":true}}`,
+				`{"timestamp":1481014802000000000,"type":"snap","resource":"/v2/snaps/foo","metadata":{"change-id":"42","action":"install","snap-name":"foo"}}`,
+			)
+		case "/v2/changes/42":
+			fmt.Fprintln(w, `{"type":"sync","result":{"id":"42","status":"Doing","ready":false}}`)
+		default:
+			c.Fatalf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"watch", "42"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `2016-12-06T09:00:00Z task-progress /v2/changes/42: Download foo 5/10
+2016-12-06T09:00:01Z change-update /v2/changes/42: Install foo Doing -> Done
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestWatchChangeAlreadyReady(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/events":
+			s.serveEvents(c, w, r)
+		case "/v2/changes/42":
+			fmt.Fprintln(w, `{"type":"sync","result":{"id":"42","status":"Error","ready":true}}`)
+		default:
+			c.Fatalf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"watch", "42"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "change 42 is already ready (Error)\n")
+}
+
+func (s *SnapSuite) TestWatchAll(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/events")
+		c.Check(r.URL.RawQuery, check.Equals, "")
+		s.serveEvents(c, w, r,
+			`{"timestamp":1481014801000000000,"type":"change-update","resource":"/v2/changes/42","metadata":{"change-id":"42","summary":"Connect foo:bar to baz:bar","old-status":"Doing","status":"Done","ready":true}}`,
+			`{"timestamp":1481014802000000000,"type":"interface","resource":"/v2/snaps/foo","metadata":{"change-id":"42","action":"connect","snap-name":"foo"}}`,
+		)
+	})
+
+	// runs until the connection goes away
+	_, err := snap.Parser().ParseArgs([]string{"watch", "--all"})
+	c.Assert(err, check.ErrorMatches, "cannot read event: .*")
+	c.Check(s.Stdout(), check.Equals, `2016-12-06T09:00:01Z change-update /v2/changes/42: Connect foo:bar to baz:bar Doing -> Done
+2016-12-06T09:00:02Z interface /v2/snaps/foo: connect foo
+`)
+}
+
+func (s *SnapSuite) TestWatchNeedsChangeOrAll(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser().ParseArgs([]string{"watch"})
+	c.Check(err, check.ErrorMatches, "need exactly one of a change id or --all")
+	_, err = snap.Parser().ParseArgs([]string{"watch", "--all", "42"})
+	c.Check(err, check.ErrorMatches, "need exactly one of a change id or --all")
+}
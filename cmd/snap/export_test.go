@@ -34,6 +34,7 @@ var (
 	SnapRunApp         = snapRunApp
 	SnapRunHook        = snapRunHook
 	Wait               = wait
+	ParseAt            = parseAt
 )
 
 func MockPollTime(d time.Duration) (restore func()) {
@@ -74,6 +74,9 @@ var commands []*cmdInfo
 // experimentalCommands holds information about all experimental commands.
 var experimentalCommands []*cmdInfo
 
+// debugCommands holds information about all debug commands.
+var debugCommands []*cmdInfo
+
 // addCommand replaces parser.addCommand() in a way that is compatible with
 // re-constructing a pristine parser.
 func addCommand(name, shortHelp, longHelp string, builder func() flags.Commander, optDescs map[string]string, argDescs []argDesc) *cmdInfo {
@@ -103,6 +106,22 @@ func addExperimentalCommand(name, shortHelp, longHelp string, builder func() fla
 	return info
 }
 
+// addDebugCommand replaces parser.addCommand() in a way that is
+// compatible with re-constructing a pristine parser. It is meant for
+// adding debug commands.
+func addDebugCommand(name, shortHelp, longHelp string, builder func() flags.Commander, optDescs map[string]string, argDescs []argDesc) *cmdInfo {
+	info := &cmdInfo{
+		name:      name,
+		shortHelp: shortHelp,
+		longHelp:  longHelp,
+		builder:   builder,
+		optDescs:  optDescs,
+		argDescs:  argDescs,
+	}
+	debugCommands = append(debugCommands, info)
+	return info
+}
+
 type parserSetter interface {
 	setParser(*flags.Parser)
 }
@@ -128,6 +147,62 @@ func lintArg(cmdName, optName, desc, origDesc string) {
 	}
 }
 
+// commandAdder is the part of flags.Parser and flags.Command that
+// commands are added with.
+type commandAdder interface {
+	AddCommand(command, shortDescription, longDescription string, data interface{}) (*flags.Command, error)
+}
+
+// addCommandInfo adds the command described by c to adder, setting
+// the descriptions of its options and arguments.
+func addCommandInfo(parser *flags.Parser, adder commandAdder, c *cmdInfo) {
+	obj := c.builder()
+	if x, ok := obj.(parserSetter); ok {
+		x.setParser(parser)
+	}
+
+	cmd, err := adder.AddCommand(c.name, c.shortHelp, strings.TrimSpace(c.longHelp), obj)
+	if err != nil {
+
+		logger.Panicf("cannot add command %q: %v", c.name, err)
+	}
+	cmd.Hidden = c.hidden
+
+	opts := cmd.Options()
+	if c.optDescs != nil && len(opts) != len(c.optDescs) {
+		logger.Panicf("wrong number of option descriptions for %s: expected %d, got %d", c.name, len(opts), len(c.optDescs))
+	}
+	for _, opt := range opts {
+		name := opt.LongName
+		if name == "" {
+			name = string(opt.ShortName)
+		}
+		desc, ok := c.optDescs[name]
+		if !(c.optDescs == nil || ok) {
+			logger.Panicf("%s missing description for %s", c.name, name)
+		}
+		lintDesc(c.name, name, desc, opt.Description)
+		if desc != "" {
+			opt.Description = desc
+		}
+	}
+
+	args := cmd.Args()
+	if c.argDescs != nil && len(args) != len(c.argDescs) {
+		logger.Panicf("wrong number of argument descriptions for %s: expected %d, got %d", c.name, len(args), len(c.argDescs))
+	}
+	for i, arg := range args {
+		name, desc := arg.Name, ""
+		if c.argDescs != nil {
+			name = c.argDescs[i].name
+			desc = c.argDescs[i].desc
+		}
+		lintArg(c.name, name, desc, arg.Description)
+		arg.Name = name
+		arg.Description = desc
+	}
+}
+
 // Parser creates and populates a fresh parser.
 // Since commands have local state a fresh parser is required to isolate tests
 // from each other.
@@ -164,51 +239,17 @@ The snap tool interacts with the snapd daemon to control the snappy software pla
 
 	// Add all regular commands
 	for _, c := range commands {
-		obj := c.builder()
-		if x, ok := obj.(parserSetter); ok {
-			x.setParser(parser)
-		}
-
-		cmd, err := parser.AddCommand(c.name, c.shortHelp, strings.TrimSpace(c.longHelp), obj)
-		if err != nil {
-
-			logger.Panicf("cannot add command %q: %v", c.name, err)
-		}
-		cmd.Hidden = c.hidden
-
-		opts := cmd.Options()
-		if c.optDescs != nil && len(opts) != len(c.optDescs) {
-			logger.Panicf("wrong number of option descriptions for %s: expected %d, got %d", c.name, len(opts), len(c.optDescs))
-		}
-		for _, opt := range opts {
-			name := opt.LongName
-			if name == "" {
-				name = string(opt.ShortName)
-			}
-			desc, ok := c.optDescs[name]
-			if !(c.optDescs == nil || ok) {
-				logger.Panicf("%s missing description for %s", c.name, name)
-			}
-			lintDesc(c.name, name, desc, opt.Description)
-			if desc != "" {
-				opt.Description = desc
-			}
-		}
-
-		args := cmd.Args()
-		if c.argDescs != nil && len(args) != len(c.argDescs) {
-			logger.Panicf("wrong number of argument descriptions for %s: expected %d, got %d", c.name, len(args), len(c.argDescs))
-		}
-		for i, arg := range args {
-			name, desc := arg.Name, ""
-			if c.argDescs != nil {
-				name = c.argDescs[i].name
-				desc = c.argDescs[i].desc
-			}
-			lintArg(c.name, name, desc, arg.Description)
-			arg.Name = name
-			arg.Description = desc
-		}
+		addCommandInfo(parser, parser, c)
+	}
+	// Add the debug command
+	debugCommand, err := parser.AddCommand("debug", shortDebugHelp, longDebugHelp, &cmdDebug{})
+	if err != nil {
+		logger.Panicf("cannot add command %q: %v", "debug", err)
+	}
+	debugCommand.Hidden = true
+	// Add all the sub-commands of the debug command
+	for _, c := range debugCommands {
+		addCommandInfo(parser, debugCommand, c)
 	}
 	// Add the experimental command
 	experimentalCommand, err := parser.AddCommand("experimental", shortExperimentalHelp, longExperimentalHelp, &cmdExperimental{})
@@ -304,6 +345,10 @@ func run() error {
 			// TRANSLATORS: %s will be a message along the lines of "login required"
 			return fmt.Errorf(i18n.G(`%s (try with sudo)`), e.Message)
 		}
+		if id := client.ConflictingChangeID(err); id != "" {
+			// TRANSLATORS: %s will be a message along the lines of "snap "foo" has changes in progress"
+			return fmt.Errorf(i18n.G(`%s (see "snap change %s")`), err, id)
+		}
 	}
 
 	return err
@@ -160,6 +160,20 @@ func (s *SnapSuite) TestAccessDeniedHint(c *C) {
 	c.Check(err.Error(), Equals, `access denied (try with sudo)`)
 }
 
+func (s *SnapSuite) TestChangeConflictHint(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(409)
+		fmt.Fprintln(w, `{"type": "error", "result": {"message": "cannot remove \"foo\": snap \"foo\" has changes in progress", "kind": "snap-change-conflict", "value": {"snap-name": "foo", "change-kind": "refresh-snap", "change-id": "42"}}, "status-code": 409}`)
+	})
+
+	restore := mockArgs("snap", "remove", "foo")
+	defer restore()
+
+	err := snap.RunMain()
+	c.Assert(err, NotNil)
+	c.Check(err.Error(), Equals, `cannot remove "foo": snap "foo" has changes in progress (see "snap change 42")`)
+}
+
 func (s *SnapSuite) TestExtraArgs(c *C) {
 	restore := mockArgs("snap", "abort", "1", "xxx", "zzz")
 	defer restore()
@@ -35,6 +35,9 @@ type Notes struct {
 	TryMode  bool
 	Disabled bool
 	Broken   bool
+	Held     bool
+	// Pinned is the revision a held snap is pinned to, if any
+	Pinned string
 }
 
 func (n *Notes) String() string {
@@ -71,6 +74,15 @@ func (n *Notes) String() string {
 		ns = append(ns, i18n.G("broken"))
 	}
 
+	if n.Held {
+		// TRANSLATORS: if possible, a single short word
+		held := i18n.G("held")
+		if n.Pinned != "" {
+			held += "@" + n.Pinned
+		}
+		ns = append(ns, held)
+	}
+
 	if len(ns) == 0 {
 		return "-"
 	}
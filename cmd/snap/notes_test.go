@@ -75,6 +75,19 @@ func (notesSuite) TestNotesBroken(c *check.C) {
 	}).String(), check.Equals, "broken")
 }
 
+func (notesSuite) TestNotesHeld(c *check.C) {
+	c.Check((&snap.Notes{
+		Held: true,
+	}).String(), check.Equals, "held")
+}
+
+func (notesSuite) TestNotesHeldPinned(c *check.C) {
+	c.Check((&snap.Notes{
+		Held:   true,
+		Pinned: "17",
+	}).String(), check.Equals, "held@17")
+}
+
 func (notesSuite) TestNotesNothing(c *check.C) {
 	c.Check((&snap.Notes{}).String(), check.Equals, "-")
 }
@@ -30,11 +30,19 @@ var clientConfig client.Config
 
 func main() {
 	stdout, stderr, err := run()
+	if e, ok := err.(*client.UnsuccessfulError); ok {
+		write(stdout, stderr)
+		os.Exit(e.ExitCode)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %s\n", err)
 		os.Exit(1)
 	}
 
+	write(stdout, stderr)
+}
+
+func write(stdout, stderr []byte) {
 	if stdout != nil {
 		os.Stdout.Write(stdout)
 	}
@@ -47,8 +55,14 @@ func main() {
 func run() (stdout, stderr []byte, err error) {
 	cli := client.New(&clientConfig)
 
+	// hooks get a context, the snap's apps use its cookie instead
+	contextID := os.Getenv("SNAP_CONTEXT")
+	if contextID == "" {
+		contextID = os.Getenv("SNAP_COOKIE")
+	}
+
 	return cli.RunSnapctl(&client.SnapCtlOptions{
-		ContextID: os.Getenv("SNAP_CONTEXT"),
+		ContextID: contextID,
 		Args:      os.Args[1:],
 	})
 }
@@ -106,3 +106,15 @@ func (s *snapctlSuite) TestSnapctlHelp(c *C) {
 	_, _, err := run()
 	c.Check(err, IsNil)
 }
+
+func (s *snapctlSuite) TestSnapctlWithCookie(c *C) {
+	os.Unsetenv("SNAP_CONTEXT")
+	os.Setenv("SNAP_COOKIE", "snap-cookie-test")
+	defer os.Unsetenv("SNAP_COOKIE")
+
+	s.expectedContextID = "snap-cookie-test"
+	stdout, stderr, err := run()
+	c.Check(err, IsNil)
+	c.Check(string(stdout), Equals, "test stdout")
+	c.Check(string(stderr), Equals, "test stderr")
+}
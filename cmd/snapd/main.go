@@ -28,6 +28,7 @@ import (
 	"github.com/snapcore/snapd/cmd"
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/store"
 )
 
@@ -49,7 +50,11 @@ func main() {
 func run() error {
 	store.SetUserAgentFromVersion(cmd.Version)
 
-	d, err := daemon.New()
+	d, err := daemon.New(&overlord.Options{
+		// journal the state instead of rewriting it on every
+		// checkpoint, see state.Journal
+		StateJournal: os.Getenv("SNAPD_STATE_JOURNAL") == "1",
+	})
 	if err != nil {
 		return err
 	}
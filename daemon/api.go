@@ -51,12 +51,15 @@ import (
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
 	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/systemd"
 )
 
 var api = []*Command{
@@ -80,6 +83,10 @@ var api = []*Command{
 	readyToBuyCmd,
 	paymentMethodsCmd,
 	snapctlCmd,
+	snapshotsCmd,
+	appsCmd,
+	logsCmd,
+	aliasesCmd,
 }
 
 var (
@@ -205,6 +212,32 @@ var (
 		SnapOK: true,
 		POST:   runSnapctl,
 	}
+
+	appsCmd = &Command{
+		Path:   "/v2/apps",
+		UserOK: true,
+		GET:    getAppsInfo,
+		POST:   postApps,
+	}
+
+	logsCmd = &Command{
+		Path: "/v2/logs",
+		GET:  getLogs,
+	}
+
+	aliasesCmd = &Command{
+		Path:   "/v2/aliases",
+		UserOK: true,
+		GET:    getAliases,
+		POST:   changeAliases,
+	}
+
+	snapshotsCmd = &Command{
+		Path:   "/v2/snapshots",
+		UserOK: true,
+		GET:    listSnapshots,
+		POST:   changeSnapshots,
+	}
 )
 
 func tbd(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -212,11 +245,20 @@ func tbd(c *Command, r *http.Request, user *auth.UserState) Response {
 }
 
 func sysInfo(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	refresh, err := refreshInfo(st)
+	st.Unlock()
+	if err != nil {
+		return InternalError("cannot get refresh information: %v", err)
+	}
+
 	m := map[string]interface{}{
 		"series":     release.Series,
 		"version":    c.d.Version,
 		"os-release": release.ReleaseInfo,
 		"on-classic": release.OnClassic,
+		"refresh":    refresh,
 	}
 
 	// TODO: set the store-id here from the model information
@@ -227,6 +269,33 @@ func sysInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(m, nil)
 }
 
+func refreshInfo(st *state.State) (map[string]interface{}, error) {
+	schedule, err := snapstate.RefreshSchedule(st)
+	if err != nil {
+		return nil, err
+	}
+	last, err := snapstate.LastRefresh(st)
+	if err != nil {
+		return nil, err
+	}
+	next, err := snapstate.NextRefresh(st)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh := map[string]interface{}{
+		"schedule": schedule,
+	}
+	if !last.IsZero() {
+		refresh["last"] = last.Format(time.RFC3339)
+	}
+	if !next.IsZero() {
+		refresh["next"] = next.Format(time.RFC3339)
+	}
+
+	return refresh, nil
+}
+
 type loginResponseData struct {
 	Macaroon   string   `json:"macaroon,omitempty"`
 	Discharges []string `json:"discharges,omitempty"`
@@ -640,11 +709,20 @@ type snapInstruction struct {
 	Revision snap.Revision `json:"revision"`
 	DevMode  bool          `json:"devmode"`
 	JailMode bool          `json:"jailmode"`
+	Classic  bool          `json:"classic"`
 	// dropping support temporarely until flag confusion is sorted,
 	// this isn't supported by client atm anyway
 	LeaveOld bool         `json:"temp-dropped-leave-old"`
 	License  *licenseData `json:"license"`
 	Snaps    []string     `json:"snaps"`
+	Purge    bool         `json:"purge"`
+	// HoldUntil is only used by hold, zero means indefinitely
+	HoldUntil time.Time `json:"hold-until"`
+	// At schedules the change to start no earlier than the given time
+	At time.Time `json:"at"`
+	// Deadline is the time by which the change must be done, otherwise
+	// it is undone
+	Deadline time.Time `json:"deadline"`
 
 	// The fields below should not be unmarshalled into. Do not export them.
 	userID int
@@ -711,8 +789,9 @@ func withEnsureUbuntuCore(st *state.State, targetSnap string, userID int, instal
 
 var errModeConflict = errors.New("cannot use devmode and jailmode flags together")
 var errNoJailMode = errors.New("this system cannot honour the jailmode flag")
+var errClassicJailMode = errors.New("cannot use classic and jailmode flags together")
 
-func modeFlags(devMode, jailMode bool) (snapstate.Flags, error) {
+func modeFlags(devMode, jailMode, classic bool) (snapstate.Flags, error) {
 	devModeOS := release.ReleaseInfo.ForceDevMode()
 	flags := snapstate.Flags(0)
 	if jailMode {
@@ -722,11 +801,17 @@ func modeFlags(devMode, jailMode bool) (snapstate.Flags, error) {
 		if devMode {
 			return 0, errModeConflict
 		}
+		if classic {
+			return 0, errClassicJailMode
+		}
 		flags |= snapstate.JailMode
 	}
 	if devMode || devModeOS {
 		flags |= snapstate.DevMode
 	}
+	if classic {
+		flags |= snapstate.Classic
+	}
 
 	return flags, nil
 
@@ -785,7 +870,7 @@ func snapInstallMany(inst *snapInstruction, st *state.State) (msg string, instal
 }
 
 func snapInstall(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
-	flags, err := modeFlags(inst.DevMode, inst.JailMode)
+	flags, err := modeFlags(inst.DevMode, inst.JailMode, inst.Classic)
 	if err != nil {
 		return "", nil, err
 	}
@@ -810,7 +895,7 @@ func snapInstall(inst *snapInstruction, st *state.State) (string, []*state.TaskS
 
 func snapUpdate(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
 	// TODO: bail if revision is given (and != current?), *or* behave as with install --revision?
-	flags, err := modeFlags(inst.DevMode, inst.JailMode)
+	flags, err := modeFlags(inst.DevMode, inst.JailMode, inst.Classic)
 	if err != nil {
 		return "", nil, err
 	}
@@ -857,7 +942,12 @@ func snapRemoveMany(inst *snapInstruction, st *state.State) (msg string, removed
 }
 
 func snapRemove(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
-	ts, err := snapstate.Remove(st, inst.Snaps[0], inst.Revision)
+	var flags snapstate.Flags
+	if inst.Purge {
+		flags |= snapstate.Purge
+	}
+
+	ts, err := snapstate.Remove(st, inst.Snaps[0], inst.Revision, flags)
 	if err != nil {
 		return "", nil, err
 	}
@@ -869,7 +959,7 @@ func snapRemove(inst *snapInstruction, st *state.State) (string, []*state.TaskSe
 func snapRevert(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
 	var ts *state.TaskSet
 
-	flags, err := modeFlags(inst.DevMode, inst.JailMode)
+	flags, err := modeFlags(inst.DevMode, inst.JailMode, inst.Classic)
 	if err != nil {
 		return "", nil, err
 	}
@@ -915,6 +1005,48 @@ func snapDisable(inst *snapInstruction, st *state.State) (string, []*state.TaskS
 
 type snapActionFunc func(*snapInstruction, *state.State) (string, []*state.TaskSet, error)
 
+func snapHold(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
+	if !inst.Revision.Unset() {
+		return "", nil, errors.New("hold takes no revision")
+	}
+	ts, err := snapstate.Hold(st, inst.Snaps[0], inst.HoldUntil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	msg := fmt.Sprintf(i18n.G("Hold refreshes of %q snap"), inst.Snaps[0])
+	return msg, []*state.TaskSet{ts}, nil
+}
+
+func snapUnhold(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
+	if !inst.Revision.Unset() {
+		return "", nil, errors.New("unhold takes no revision")
+	}
+	ts, err := snapstate.Unhold(st, inst.Snaps[0])
+	if err != nil {
+		return "", nil, err
+	}
+
+	msg := fmt.Sprintf(i18n.G("Allow refreshes of %q snap"), inst.Snaps[0])
+	return msg, []*state.TaskSet{ts}, nil
+}
+
+func snapSwitch(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
+	if !inst.Revision.Unset() {
+		return "", nil, errors.New("switch takes no revision")
+	}
+	if inst.Channel == "" {
+		return "", nil, errors.New("switch requires a channel")
+	}
+	ts, err := snapstate.Switch(st, inst.Snaps[0], inst.Channel)
+	if err != nil {
+		return "", nil, err
+	}
+
+	msg := fmt.Sprintf(i18n.G("Switch %q snap to %s"), inst.Snaps[0], inst.Channel)
+	return msg, []*state.TaskSet{ts}, nil
+}
+
 var snapInstructionDispTable = map[string]snapActionFunc{
 	"install": snapInstall,
 	"refresh": snapUpdate,
@@ -922,6 +1054,9 @@ var snapInstructionDispTable = map[string]snapActionFunc{
 	"revert":  snapRevert,
 	"enable":  snapEnable,
 	"disable": snapDisable,
+	"hold":    snapHold,
+	"unhold":  snapUnhold,
+	"switch":  snapSwitch,
 }
 
 func (inst *snapInstruction) dispatch() snapActionFunc {
@@ -942,6 +1077,9 @@ func postSnap(c *Command, r *http.Request, user *auth.UserState) Response {
 	if err := decoder.Decode(&inst); err != nil {
 		return BadRequest("cannot decode request body into snap instruction: %v", err)
 	}
+	if err := inst.validateSchedule(); err != nil {
+		return BadRequest("%v", err)
+	}
 
 	state := c.d.overlord.State()
 	state.Lock()
@@ -960,17 +1098,58 @@ func postSnap(c *Command, r *http.Request, user *auth.UserState) Response {
 	}
 
 	msg, tsets, err := impl(&inst, state)
+	if conflErr, ok := err.(*snapstate.ChangeConflictError); ok {
+		return SnapChangeConflict(conflErr, "cannot %s %q: %v", inst.Action, inst.Snaps[0], err)
+	}
 	if err != nil {
 		return BadRequest("cannot %s %q: %v", inst.Action, inst.Snaps[0], err)
 	}
 
 	chg := newChange(state, inst.Action+"-snap", msg, tsets, inst.Snaps)
+	if err := scheduleChange(chg, inst.Snaps, inst.At, inst.Deadline); err != nil {
+		chg.Abort()
+		return InternalError("cannot schedule %s of %q: %v", inst.Action, inst.Snaps[0], err)
+	}
 
 	ensureStateSoon(state)
 
 	return AsyncResponse(nil, &Meta{Change: chg.ID()})
 }
 
+// validateSchedule checks that the change can be done by its deadline,
+// if any.
+func (inst *snapInstruction) validateSchedule() error {
+	if inst.Deadline.IsZero() {
+		return nil
+	}
+	start := inst.At
+	if start.IsZero() {
+		start = time.Now()
+	}
+	if !inst.Deadline.After(start) {
+		return fmt.Errorf("cannot use a deadline that is not after the time the change starts")
+	}
+	return nil
+}
+
+// scheduleChange has chg, operating on the given snaps, start no
+// earlier than at and be undone if it is not done by deadline; the
+// zero time means no such constraint. Until it starts the change does
+// not stop other changes from operating on the snaps.
+func scheduleChange(chg *state.Change, snapNames []string, at, deadline time.Time) error {
+	if !at.IsZero() {
+		if err := snapstate.ScheduleChange(chg, snapNames, at); err != nil {
+			return err
+		}
+	}
+	if !deadline.IsZero() {
+		for _, t := range chg.Tasks() {
+			t.SetDeadline(deadline)
+		}
+	}
+	return nil
+}
+
 func newChange(st *state.State, kind, summary string, tsets []*state.TaskSet, snapNames []string) *state.Change {
 	chg := st.NewChange(kind, summary)
 	for _, ts := range tsets {
@@ -1041,9 +1220,12 @@ func snapsOp(c *Command, r *http.Request, user *auth.UserState) Response {
 		return BadRequest("cannot decode request body into snap instruction: %v", err)
 	}
 
-	if inst.Channel != "" || !inst.Revision.Unset() || inst.DevMode || inst.JailMode {
+	if inst.Channel != "" || !inst.Revision.Unset() || inst.DevMode || inst.JailMode || inst.Classic {
 		return BadRequest("unsupported option provided for multi-snap operation")
 	}
+	if err := inst.validateSchedule(); err != nil {
+		return BadRequest("%v", err)
+	}
 
 	st := c.d.overlord.State()
 	st.Lock()
@@ -1067,6 +1249,9 @@ func snapsOp(c *Command, r *http.Request, user *auth.UserState) Response {
 	default:
 		return BadRequest("unsupported multi-snap operation %q", inst.Action)
 	}
+	if conflErr, ok := err.(*snapstate.ChangeConflictError); ok {
+		return SnapChangeConflict(conflErr, "cannot %s %q: %v", inst.Action, inst.Snaps, err)
+	}
 	if err != nil {
 		return InternalError("cannot %s %q: %v", inst.Action, inst.Snaps, err)
 	}
@@ -1077,6 +1262,10 @@ func snapsOp(c *Command, r *http.Request, user *auth.UserState) Response {
 		chg.SetStatus(state.DoneStatus)
 	} else {
 		chg = newChange(st, inst.Action+"-snap", msg, tsets, affected)
+		if err := scheduleChange(chg, affected, inst.At, inst.Deadline); err != nil {
+			chg.Abort()
+			return InternalError("cannot schedule %s of %q: %v", inst.Action, affected, err)
+		}
 		ensureStateSoon(st)
 	}
 	chg.Set("api-data", map[string]interface{}{"snap-names": affected})
@@ -1113,7 +1302,7 @@ func postSnaps(c *Command, r *http.Request, user *auth.UserState) Response {
 
 	dangerousOK := isTrue(form, "dangerous")
 	devmode := isTrue(form, "devmode")
-	flags, err := modeFlags(devmode, isTrue(form, "jailmode"))
+	flags, err := modeFlags(devmode, isTrue(form, "jailmode"), isTrue(form, "classic"))
 	if err != nil {
 		return BadRequest(err.Error())
 	}
@@ -1310,7 +1499,13 @@ func setSnapConf(c *Command, r *http.Request, user *auth.UserState) Response {
 	s.Lock()
 	defer s.Unlock()
 
-	taskset := configstate.Change(s, snapName, patchValues)
+	taskset, err := configstate.Change(s, snapName, patchValues)
+	if conflErr, ok := err.(*snapstate.ChangeConflictError); ok {
+		return SnapChangeConflict(conflErr, "cannot set config for %q: %v", snapName, err)
+	}
+	if err != nil {
+		return InternalError("cannot set config for %q: %v", snapName, err)
+	}
 	change := s.NewChange("configure-snap", fmt.Sprintf("Setting config for %s", snapName))
 	change.AddAll(taskset)
 
@@ -1396,6 +1591,9 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 		summary = fmt.Sprintf("Disconnect %s:%s from %s:%s", a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name)
 		taskset, err = ifacestate.Disconnect(state, a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name)
 	}
+	if conflErr, ok := err.(*snapstate.ChangeConflictError); ok {
+		return SnapChangeConflict(conflErr, "cannot %s: %v", a.Action, err)
+	}
 	if err != nil {
 		return BadRequest("%v", err)
 	}
@@ -1476,15 +1674,26 @@ type changeInfo struct {
 }
 
 type taskInfo struct {
-	ID       string           `json:"id"`
-	Kind     string           `json:"kind"`
-	Summary  string           `json:"summary"`
-	Status   string           `json:"status"`
-	Log      []string         `json:"log,omitempty"`
-	Progress taskInfoProgress `json:"progress"`
+	ID         string             `json:"id"`
+	Kind       string             `json:"kind"`
+	Summary    string             `json:"summary"`
+	Status     string             `json:"status"`
+	Log        []string           `json:"log,omitempty"`
+	LogEntries []taskInfoLogEntry `json:"log-entries,omitempty"`
+	Progress   taskInfoProgress   `json:"progress"`
 
 	SpawnTime time.Time  `json:"spawn-time,omitempty"`
 	ReadyTime *time.Time `json:"ready-time,omitempty"`
+	AtTime    *time.Time `json:"at-time,omitempty"`
+	Deadline  *time.Time `json:"deadline,omitempty"`
+
+	Data map[string]*json.RawMessage `json:"data,omitempty"`
+}
+
+type taskInfoLogEntry struct {
+	Time    time.Time `json:"time,omitempty"`
+	Kind    string    `json:"kind,omitempty"`
+	Message string    `json:"message"`
 }
 
 type taskInfoProgress struct {
@@ -1530,10 +1739,23 @@ func change2changeInfo(chg *state.Change) *changeInfo {
 			},
 			SpawnTime: t.SpawnTime(),
 		}
+		for _, entry := range t.LogEntries() {
+			taskInfo.LogEntries = append(taskInfo.LogEntries, taskInfoLogEntry{
+				Time:    entry.Time,
+				Kind:    entry.Kind,
+				Message: entry.Message,
+			})
+		}
 		readyTime := t.ReadyTime()
 		if !readyTime.IsZero() {
 			taskInfo.ReadyTime = &readyTime
 		}
+		if atTime := t.AtTime(); !atTime.IsZero() {
+			taskInfo.AtTime = &atTime
+		}
+		if deadline := t.Deadline(); !deadline.IsZero() {
+			taskInfo.Deadline = &deadline
+		}
 		taskInfos[j] = taskInfo
 	}
 	chgInfo.Tasks = taskInfos
@@ -1556,7 +1778,27 @@ func getChange(c *Command, r *http.Request, user *auth.UserState) Response {
 		return NotFound("cannot find change with id %q", chID)
 	}
 
-	return SyncResponse(change2changeInfo(chg), nil)
+	chgInfo := change2changeInfo(chg)
+
+	switch r.URL.Query().Get("data") {
+	case "", "false":
+		// nothing to add
+	case "true":
+		// task data can hold anything from configuration values
+		// to store details, only let admins see it
+		if user == nil {
+			if uid, err := ucrednetGetUID(r.RemoteAddr); err != nil || uid != 0 {
+				return Unauthorized("access denied")
+			}
+		}
+		for i, t := range chg.Tasks() {
+			chgInfo.Tasks[i].Data = t.Data()
+		}
+	default:
+		return BadRequest("data should be one of: true,false")
+	}
+
+	return SyncResponse(chgInfo, nil)
 }
 
 func getChanges(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -1801,13 +2043,40 @@ func runSnapctl(c *Command, r *http.Request, user *auth.UserState) Response {
 		return BadRequest("snapctl cannot run without args")
 	}
 
-	// Right now snapctl is only used for hooks. If at some point it grows
-	// beyond that, this probably shouldn't go straight to the HookManager.
-	context, _ := c.d.overlord.HookManager().Context(snapctlOptions.ContextID)
+	// The context ID is either the one of a running hook or the cookie
+	// of a snap, for snapctl used by its apps.
+	hookMgr := c.d.overlord.HookManager()
+	context, err := hookMgr.Context(snapctlOptions.ContextID)
+	if err != nil && snapctlOptions.ContextID != "" {
+		context, _ = hookMgr.EphemeralContext(snapctlOptions.ContextID)
+	}
 	stdout, stderr, err := ctlcmd.Run(context, snapctlOptions.Args)
+	if err == nil && context != nil && context.IsEphemeral() {
+		// there is no hook to complete, so apply the changes
+		// (e.g. configuration) right away
+		context.Lock()
+		err = context.Done()
+		context.Unlock()
+	}
 	if err != nil {
 		if e, ok := err.(*flags.Error); ok && e.Type == flags.ErrHelp {
 			stdout = []byte(e.Error())
+		} else if e, ok := err.(*ctlcmd.UnsuccessfulError); ok {
+			// not an error as such, the command just wants snapctl
+			// to exit with the given code
+			return &resp{
+				Type: ResponseTypeError,
+				Result: &errorResult{
+					Message: e.Error(),
+					Kind:    errorKindUnsuccessful,
+					Value: map[string]interface{}{
+						"stdout":    string(stdout),
+						"stderr":    string(stderr),
+						"exit-code": e.ExitCode,
+					},
+				},
+				Status: 200,
+			}
 		} else {
 			return BadRequest("error running snapctl: %s", err)
 		}
@@ -1820,3 +2089,306 @@ func runSnapctl(c *Command, r *http.Request, user *auth.UserState) Response {
 
 	return SyncResponse(result, nil)
 }
+
+var (
+	snapshotList    = snapshotstate.List
+	snapshotSave    = snapshotstate.Save
+	snapshotRestore = snapshotstate.Restore
+	snapshotForget  = snapshotstate.Forget
+)
+
+func listSnapshots(c *Command, r *http.Request, user *auth.UserState) Response {
+	query := r.URL.Query()
+	var setID uint64
+	if sid := query.Get("set"); sid != "" {
+		var err error
+		setID, err = strconv.ParseUint(sid, 10, 64)
+		if err != nil {
+			return BadRequest("cannot parse set id %q: %v", sid, err)
+		}
+	}
+	var snapNames []string
+	if snaps := query.Get("snaps"); snaps != "" {
+		snapNames = strings.Split(snaps, ",")
+	}
+
+	sets, err := snapshotList(setID, snapNames)
+	if err != nil {
+		return InternalError("cannot list snapshots: %v", err)
+	}
+
+	return SyncResponse(sets, nil)
+}
+
+type snapshotAction struct {
+	SetID  uint64   `json:"set"`
+	Action string   `json:"action"`
+	Snaps  []string `json:"snaps"`
+	Users  []string `json:"users"`
+}
+
+func changeSnapshots(c *Command, r *http.Request, user *auth.UserState) Response {
+	var action snapshotAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&action); err != nil {
+		return BadRequest("cannot decode request body into snapshot action: %v", err)
+	}
+
+	if action.Action == "save" {
+		if action.SetID != 0 {
+			return BadRequest(`snapshot action "save" does not take a set id`)
+		}
+	} else if action.SetID == 0 {
+		return BadRequest("snapshot action %q requires a set id", action.Action)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var setID uint64
+	var affected []string
+	var ts *state.TaskSet
+	var msg string
+	var err error
+	switch action.Action {
+	case "save":
+		setID, affected, ts, err = snapshotSave(st, action.Snaps, action.Users)
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = i18n.G("Save data of snaps %s")
+	case "restore":
+		setID = action.SetID
+		affected, ts, err = snapshotRestore(st, action.SetID, action.Snaps, action.Users)
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = i18n.G("Restore data of snaps %s")
+	case "forget":
+		if len(action.Users) != 0 {
+			return BadRequest(`snapshot action "forget" does not take users`)
+		}
+		setID = action.SetID
+		affected, ts, err = snapshotForget(st, action.SetID, action.Snaps)
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = i18n.G("Drop data of snaps %s")
+	default:
+		return BadRequest("unknown snapshot action %q", action.Action)
+	}
+	if err != nil {
+		return BadRequest("cannot %s snapshot: %v", action.Action, err)
+	}
+
+	quoted := make([]string, len(affected))
+	for i, name := range affected {
+		quoted[i] = strconv.Quote(name)
+	}
+	msg = fmt.Sprintf(msg, strings.Join(quoted, ", "))
+
+	chg := newChange(st, action.Action+"-snapshot", msg, []*state.TaskSet{ts}, affected)
+	chg.Set("api-data", map[string]interface{}{"snap-names": affected, "set-id": setID})
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+}
+
+func splitQS(qs string) []string {
+	if qs == "" {
+		return nil
+	}
+
+	return strings.Split(qs, ",")
+}
+
+func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
+	query := r.URL.Query()
+
+	opts := appInfoOptions{}
+	switch sel := query.Get("select"); sel {
+	case "":
+		// nothing to do
+	case "service":
+		opts.service = true
+	default:
+		return BadRequest("invalid select parameter: %q", sel)
+	}
+
+	appInfos, rsp := appInfosFor(c.d.overlord.State(), splitQS(query.Get("names")), opts)
+	if rsp != nil {
+		return rsp
+	}
+
+	clientAppInfos, err := clientAppInfosFromSnapAppInfos(appInfos)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+
+	return SyncResponse(clientAppInfos, nil)
+}
+
+func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
+	query := r.URL.Query()
+	n := 10
+	if s := query.Get("n"); s != "" {
+		m, err := strconv.ParseInt(s, 0, 32)
+		if err != nil {
+			return BadRequest(`invalid value for n: %q: %v`, s, err)
+		}
+		n = int(m)
+	}
+	follow := false
+	if s := query.Get("follow"); s != "" {
+		f, err := strconv.ParseBool(s)
+		if err != nil {
+			return BadRequest(`invalid value for follow: %q: %v`, s, err)
+		}
+		follow = f
+	}
+
+	// only services have logs for now
+	opts := appInfoOptions{service: true}
+	appInfos, rsp := appInfosFor(c.d.overlord.State(), splitQS(query.Get("names")), opts)
+	if rsp != nil {
+		return rsp
+	}
+	if len(appInfos) == 0 {
+		return NotFound("no matching services")
+	}
+
+	serviceNames := make([]string, len(appInfos))
+	for i, appInfo := range appInfos {
+		serviceNames[i] = filepath.Base(appInfo.ServiceFile())
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, &progress.NullProgress{})
+	reader, err := sysd.LogReader(serviceNames, n, follow)
+	if err != nil {
+		return InternalError("cannot get logs: %v", err)
+	}
+
+	return &journalLineReaderSeqResponse{ReadCloser: reader, follow: follow}
+}
+
+func postApps(c *Command, r *http.Request, user *auth.UserState) Response {
+	var inst servicestate.Instruction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&inst); err != nil {
+		return BadRequest("cannot decode request body into service operation: %v", err)
+	}
+	if len(inst.Names) == 0 {
+		// on POST, don't allow empty to mean all
+		return BadRequest("cannot perform operation on services without a list of services to operate on")
+	}
+
+	st := c.d.overlord.State()
+	appInfos, rsp := appInfosFor(st, inst.Names, appInfoOptions{service: true})
+	if rsp != nil {
+		return rsp
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	ts, err := servicestate.Control(st, appInfos, &inst)
+	if err != nil {
+		return BadRequest("%v", err)
+	}
+
+	var snapNames []string
+	for _, app := range appInfos {
+		if len(snapNames) == 0 || snapNames[len(snapNames)-1] != app.Snap.Name() {
+			snapNames = append(snapNames, app.Snap.Name())
+		}
+	}
+
+	chg := newChange(st, "service-control", ts.Tasks()[0].Summary(), []*state.TaskSet{ts}, snapNames)
+	chg.Set("api-data", map[string]interface{}{"snap-names": snapNames})
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+}
+
+type aliasAction struct {
+	Action  string   `json:"action"`
+	Snap    string   `json:"snap"`
+	Aliases []string `json:"aliases"`
+}
+
+func changeAliases(c *Command, r *http.Request, user *auth.UserState) Response {
+	var a aliasAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&a); err != nil {
+		return BadRequest("cannot decode request body into an alias action: %v", err)
+	}
+	if len(a.Aliases) == 0 {
+		return BadRequest("at least one alias name is required")
+	}
+
+	var doAlias func(*state.State, string, []string) (*state.TaskSet, error)
+	switch a.Action {
+	case "alias":
+		doAlias = snapstate.Alias
+	case "unalias":
+		doAlias = snapstate.Unalias
+	default:
+		return BadRequest("unsupported alias action: %q", a.Action)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ts, err := doAlias(st, a.Snap, a.Aliases)
+	if conflErr, ok := err.(*snapstate.ChangeConflictError); ok {
+		return SnapChangeConflict(conflErr, "cannot %s for %q: %v", a.Action, a.Snap, err)
+	}
+	if err != nil {
+		return BadRequest("%v", err)
+	}
+
+	chg := newChange(st, a.Action, ts.Tasks()[0].Summary(), []*state.TaskSet{ts}, []string{a.Snap})
+	chg.Set("api-data", map[string]string{"snap-name": a.Snap})
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+}
+
+// getAliases produces a response with a map snap -> alias -> client.AliasStatus
+func getAliases(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapStates, err := snapstate.All(st)
+	if err != nil {
+		return InternalError("cannot list local snaps: %v", err)
+	}
+
+	res := make(map[string]map[string]client.AliasStatus)
+	for snapName, snapst := range snapStates {
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return InternalError("cannot read snap %q information: %v", snapName, err)
+		}
+		if len(info.Aliases) == 0 {
+			continue
+		}
+
+		enabled := make(map[string]bool, len(snapst.Aliases))
+		for _, alias := range snapst.Aliases {
+			enabled[alias] = true
+		}
+
+		statuses := make(map[string]client.AliasStatus, len(info.Aliases))
+		for alias, app := range info.Aliases {
+			status := "disabled"
+			if enabled[alias] {
+				status = "enabled"
+			}
+			statuses[alias] = client.AliasStatus{
+				App:    filepath.Base(app.WrapperPath()),
+				Status: status,
+			}
+		}
+		res[snapName] = statuses
+	}
+
+	return SyncResponse(res, nil)
+}
@@ -44,6 +44,7 @@ import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/asserts/sysdb"
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/osutil"
@@ -51,6 +52,7 @@ import (
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/progress"
@@ -58,6 +60,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -106,7 +109,7 @@ func (s *apiSuite) SuggestedCurrency() string {
 	return s.suggestedCurrency
 }
 
-func (s *apiSuite) Download(string, *snap.DownloadInfo, progress.Meter, *auth.UserState) (string, error) {
+func (s *apiSuite) Download(string, string, *snap.DownloadInfo, progress.Meter, *auth.UserState) error {
 	panic("Download not expected to be called")
 }
 
@@ -181,6 +184,10 @@ func (s *apiSuite) TearDownTest(c *check.C) {
 	assertstateRefreshSnapDeclarations = assertstate.RefreshSnapDeclarations
 	unsafeReadSnapInfo = unsafeReadSnapInfoImpl
 	ensureStateSoon = ensureStateSoonImpl
+	snapshotList = snapshotstate.List
+	snapshotSave = snapshotstate.Save
+	snapshotRestore = snapshotstate.Restore
+	snapshotForget = snapshotstate.Forget
 	dirs.SetRootDir("")
 }
 
@@ -188,7 +195,7 @@ func (s *apiSuite) daemon(c *check.C) *Daemon {
 	if s.d != nil {
 		panic("called daemon() twice")
 	}
-	d, err := New()
+	d, err := New(nil)
 	c.Assert(err, check.IsNil)
 	d.addRoutes()
 
@@ -394,6 +401,7 @@ func (s *apiSuite) TestListIncludesAll(c *check.C) {
 		"errNothingToInstall",
 		"errModeConflict",
 		"errNoJailMode",
+		"errClassicJailMode",
 		// snapInstruction vars:
 		"snapInstructionDispTable",
 		"snapstateInstall",
@@ -408,6 +416,11 @@ func (s *apiSuite) TestListIncludesAll(c *check.C) {
 		"assertstateRefreshSnapDeclarations",
 		"unsafeReadSnapInfo",
 		"osutilAddUser",
+		// snapshot vars:
+		"snapshotList",
+		"snapshotSave",
+		"snapshotRestore",
+		"snapshotForget",
 		"storeUserInfo",
 		"postCreateUserUcrednetGetUID",
 		"ensureStateSoon",
@@ -465,6 +478,9 @@ func (s *apiSuite) TestSysInfo(c *check.C) {
 			"version-id": "1.2",
 		},
 		"on-classic": true,
+		"refresh": map[string]interface{}{
+			"schedule": "00:00-04:59/05:00-10:59/11:00-16:59/17:00-23:59",
+		},
 	}
 	var rsp resp
 	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), check.IsNil)
@@ -473,6 +489,30 @@ func (s *apiSuite) TestSysInfo(c *check.C) {
 	c.Check(rsp.Result, check.DeepEquals, expected)
 }
 
+func (s *apiSuite) TestSysInfoRefreshTimes(c *check.C) {
+	d := s.daemon(c)
+
+	last := time.Date(2017, 2, 6, 10, 30, 0, 0, time.UTC)
+	next := time.Date(2017, 2, 6, 17, 12, 0, 0, time.UTC)
+	st := d.overlord.State()
+	st.Lock()
+	st.Set("last-refresh", last)
+	st.Set("next-refresh", next)
+	st.Unlock()
+
+	rec := httptest.NewRecorder()
+	sysInfoCmd.GET(sysInfoCmd, nil, nil).ServeHTTP(rec, nil)
+	c.Check(rec.Code, check.Equals, 200)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), check.IsNil)
+	c.Check(rsp.Result.(map[string]interface{})["refresh"], check.DeepEquals, map[string]interface{}{
+		"schedule": "00:00-04:59/05:00-10:59/11:00-16:59/17:00-23:59",
+		"last":     "2017-02-06T10:30:00Z",
+		"next":     "2017-02-06T17:12:00Z",
+	})
+}
+
 func (s *apiSuite) makeMyAppsServer(statusCode int, data string) *httptest.Server {
 	mockMyAppsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(statusCode)
@@ -1315,6 +1355,133 @@ func (s *apiSuite) TestPostSnap(c *check.C) {
 	c.Check(soon, check.Equals, 1)
 }
 
+func (s *apiSuite) TestPostSnapConflict(c *check.C) {
+	s.daemon(c)
+
+	s.vars = map[string]string{"name": "foo"}
+
+	snapInstructionDispTable["remove"] = func(*snapInstruction, *state.State) (string, []*state.TaskSet, error) {
+		return "", nil, &snapstate.ChangeConflictError{Snap: "foo", ChangeKind: "refresh-snap", ChangeID: "42"}
+	}
+	defer func() {
+		snapInstructionDispTable["remove"] = snapRemove
+	}()
+
+	buf := bytes.NewBufferString(`{"action": "remove"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 409)
+	c.Check(rsp.Result, check.DeepEquals, &errorResult{
+		Message: `cannot remove "foo": snap "foo" has changes in progress`,
+		Kind:    errorKindSnapChangeConflict,
+		Value: map[string]interface{}{
+			"snap-name":   "foo",
+			"change-kind": "refresh-snap",
+			"change-id":   "42",
+		},
+	})
+}
+
+func (s *apiSuite) TestPostSnapAt(c *check.C) {
+	d := s.daemon(c)
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+	ensureStateSoon = func(st *state.State) {}
+
+	s.vars = map[string]string{"name": "foo"}
+
+	snapInstructionDispTable["refresh"] = func(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
+		t1 := st.NewTask("fake-download", "...")
+		t2 := st.NewTask("fake-link", "...")
+		t2.WaitFor(t1)
+		return "refresh later", []*state.TaskSet{state.NewTaskSet(t1, t2)}, nil
+	}
+	defer func() {
+		snapInstructionDispTable["refresh"] = snapUpdate
+	}()
+
+	buf := bytes.NewBufferString(`{"action": "refresh", "at": "2030-01-02T02:00:00Z"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg.Tasks(), check.HasLen, 3)
+	wait := chg.Tasks()[2]
+	c.Check(wait.Kind(), check.Equals, "wait-scheduled")
+	c.Check(wait.AtTime().Equal(time.Date(2030, 1, 2, 2, 0, 0, 0, time.UTC)), check.Equals, true)
+	var snapNames []string
+	c.Assert(wait.Get("snap-names", &snapNames), check.IsNil)
+	c.Check(snapNames, check.DeepEquals, []string{"foo"})
+	for _, t := range chg.Tasks()[:2] {
+		c.Check(t.WaitTasks(), testutil.Contains, wait)
+		c.Check(t.AtTime().IsZero(), check.Equals, true)
+		c.Check(t.Deadline().IsZero(), check.Equals, true)
+	}
+}
+
+func (s *apiSuite) TestPostSnapDeadline(c *check.C) {
+	d := s.daemon(c)
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+	ensureStateSoon = func(st *state.State) {}
+
+	s.vars = map[string]string{"name": "foo"}
+
+	snapInstructionDispTable["refresh"] = func(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
+		t1 := st.NewTask("fake-download", "...")
+		t2 := st.NewTask("fake-link", "...")
+		t2.WaitFor(t1)
+		return "refresh later", []*state.TaskSet{state.NewTaskSet(t1, t2)}, nil
+	}
+	defer func() {
+		snapInstructionDispTable["refresh"] = snapUpdate
+	}()
+
+	buf := bytes.NewBufferString(`{"action": "refresh", "at": "2030-01-02T02:00:00Z", "deadline": "2030-01-02T04:00:00Z"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg.Tasks(), check.HasLen, 3)
+	for _, t := range chg.Tasks() {
+		c.Check(t.Deadline().Equal(time.Date(2030, 1, 2, 4, 0, 0, 0, time.UTC)), check.Equals, true)
+	}
+}
+
+func (s *apiSuite) TestPostSnapDeadlineNotAfterAt(c *check.C) {
+	s.daemon(c)
+	s.vars = map[string]string{"name": "foo"}
+
+	for _, body := range []string{
+		`{"action": "refresh", "at": "2030-01-02T02:00:00Z", "deadline": "2030-01-02T01:00:00Z"}`,
+		`{"action": "refresh", "deadline": "2000-01-02T01:00:00Z"}`,
+	} {
+		req, err := http.NewRequest("POST", "/v2/snaps/foo", bytes.NewBufferString(body))
+		c.Assert(err, check.IsNil)
+
+		rsp := postSnap(snapCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError)
+		c.Check(rsp.Status, check.Equals, http.StatusBadRequest)
+		c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot use a deadline that is not after the time the change starts")
+	}
+}
+
 func (s *apiSuite) TestPostSnapSetsUser(c *check.C) {
 	d := s.daemon(c)
 	ensureStateSoon = func(st *state.State) {}
@@ -1364,6 +1531,9 @@ func (s *apiSuite) TestPostSnapDispatch(c *check.C) {
 		{"revert", snapRevert},
 		{"enable", snapEnable},
 		{"disable", snapDisable},
+		{"hold", snapHold},
+		{"unhold", snapUnhold},
+		{"switch", snapSwitch},
 		{"xyzzy", nil},
 	}
 
@@ -1388,6 +1558,169 @@ func (s *apiSuite) TestPostSnapEnableDisableRevision(c *check.C) {
 	}
 }
 
+func (s *apiSuite) TestPostSnapHold(c *check.C) {
+	d := s.daemon(c)
+	ensureStateSoon = func(st *state.State) {}
+	s.vars = map[string]string{"name": "foo"}
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	buf := bytes.NewBufferString(`{"action": "hold", "hold-until": "2037-02-06T10:00:00Z"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "hold-snap")
+	c.Check(chg.Summary(), check.Equals, `Hold refreshes of "foo" snap`)
+	tasks := chg.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	var until time.Time
+	c.Assert(tasks[0].Get("hold-until", &until), check.IsNil)
+	c.Check(until.Equal(time.Date(2037, 2, 6, 10, 0, 0, 0, time.UTC)), check.Equals, true)
+}
+
+func (s *apiSuite) TestPostSnapHoldUnholdRevision(c *check.C) {
+	for _, action := range []string{"hold", "unhold"} {
+		buf := bytes.NewBufferString(`{"action": "` + action + `", "revision": "42"}`)
+		req, err := http.NewRequest("POST", "/v2/snaps/hello-world", buf)
+		c.Assert(err, check.IsNil)
+
+		rsp := postSnap(snapCmd, req, nil).(*resp)
+
+		c.Check(rsp.Type, check.Equals, ResponseTypeError)
+		c.Check(rsp.Status, check.Equals, http.StatusBadRequest)
+		c.Check(rsp.Result.(*errorResult).Message, testutil.Contains, "takes no revision")
+	}
+}
+
+func (s *apiSuite) TestHeldSnapInfo(c *check.C) {
+	d := s.daemon(c)
+	s.vars = map[string]string{"name": "held-snap"}
+	s.mkInstalledInState(c, d, "held-snap", "bar", "v1", snap.R(10), true, "")
+
+	until := time.Now().Add(time.Hour).UTC()
+	st := d.overlord.State()
+	st.Lock()
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(st, "held-snap", &snapst), check.IsNil)
+	snapst.Hold = &snapstate.RefreshHold{Until: until, Revision: snap.R(10)}
+	snapstate.Set(st, "held-snap", &snapst)
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/held-snap", nil)
+	c.Assert(err, check.IsNil)
+	rsp, ok := getSnapInfo(snapCmd, req, nil).(*resp)
+	c.Assert(ok, check.Equals, true)
+
+	m := rsp.Result.(map[string]interface{})
+	c.Check(m["held"], check.Equals, true)
+	c.Check(m["hold-until"], check.DeepEquals, until)
+	c.Check(m["pinned-revision"], check.Equals, snap.R(10))
+}
+
+func (s *apiSuite) TestInstanceSnapInfo(c *check.C) {
+	d := s.daemon(c)
+	s.vars = map[string]string{"name": "instance-snap_key"}
+
+	metaDir := filepath.Join(dirs.SnapMountDir, "instance-snap_key", "10", "meta")
+	c.Assert(os.MkdirAll(metaDir, 0755), check.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(metaDir, "snap.yaml"), []byte("name: instance-snap\nversion: v1\n"), 0644), check.IsNil)
+
+	st := d.overlord.State()
+	st.Lock()
+	snapstate.Set(st, "instance-snap_key", &snapstate.SnapState{
+		Active:      true,
+		Sequence:    []*snap.SideInfo{{RealName: "instance-snap", Revision: snap.R(10)}},
+		Current:     snap.R(10),
+		InstanceKey: "key",
+	})
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/instance-snap_key", nil)
+	c.Assert(err, check.IsNil)
+	rsp, ok := getSnapInfo(snapCmd, req, nil).(*resp)
+	c.Assert(ok, check.Equals, true)
+
+	m := rsp.Result.(map[string]interface{})
+	c.Check(m["name"], check.Equals, "instance-snap_key")
+	c.Check(m["snap-name"], check.Equals, "instance-snap")
+	c.Check(m["instance-key"], check.Equals, "key")
+}
+
+func (s *apiSuite) TestPostSnapSwitch(c *check.C) {
+	d := s.daemon(c)
+	ensureStateSoon = func(st *state.State) {}
+	s.vars = map[string]string{"name": "foo"}
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	buf := bytes.NewBufferString(`{"action": "switch", "channel": "beta"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "switch-snap")
+	c.Check(chg.Summary(), check.Equals, `Switch "foo" snap to beta`)
+	tasks := chg.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(tasks[0].Kind(), check.Equals, "switch-snap-channel")
+}
+
+func (s *apiSuite) TestPostSnapSwitchErrors(c *check.C) {
+	for _, t := range []struct {
+		body string
+		err  string
+	}{
+		{`{"action": "switch", "channel": "beta", "revision": "42"}`, "switch takes no revision"},
+		{`{"action": "switch"}`, "switch requires a channel"},
+	} {
+		buf := bytes.NewBufferString(t.body)
+		req, err := http.NewRequest("POST", "/v2/snaps/hello-world", buf)
+		c.Assert(err, check.IsNil)
+
+		rsp := postSnap(snapCmd, req, nil).(*resp)
+
+		c.Check(rsp.Type, check.Equals, ResponseTypeError)
+		c.Check(rsp.Status, check.Equals, http.StatusBadRequest)
+		c.Check(rsp.Result.(*errorResult).Message, testutil.Contains, t.err)
+	}
+}
+
+func (s *apiSuite) TestTrackingChannelSnapInfo(c *check.C) {
+	d := s.daemon(c)
+	s.vars = map[string]string{"name": "tracking-snap"}
+	s.mkInstalledInState(c, d, "tracking-snap", "bar", "v1", snap.R(10), true, "")
+
+	st := d.overlord.State()
+	st.Lock()
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(st, "tracking-snap", &snapst), check.IsNil)
+	snapst.Channel = "candidate"
+	snapstate.Set(st, "tracking-snap", &snapst)
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/snaps/tracking-snap", nil)
+	c.Assert(err, check.IsNil)
+	rsp, ok := getSnapInfo(snapCmd, req, nil).(*resp)
+	c.Assert(ok, check.Equals, true)
+
+	m := rsp.Result.(map[string]interface{})
+	c.Check(m["tracking-channel"], check.Equals, "candidate")
+	c.Check(m["channel"], check.Equals, "stable")
+}
+
 var sideLoadBodyWithoutDevMode = "" +
 	"----hello--\r\n" +
 	"Content-Disposition: form-data; name=\"snap\"; filename=\"x\"\r\n" +
@@ -1440,6 +1773,27 @@ func (s *apiSuite) TestSideloadSnapDevMode(c *check.C) {
 	c.Check(chgSummary, check.Equals, `Install "local" snap from file "x"`)
 }
 
+func (s *apiSuite) TestSideloadSnapClassic(c *check.C) {
+	body := "" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap\"; filename=\"x\"\r\n" +
+		"\r\n" +
+		"xyzzy\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"classic\"\r\n" +
+		"\r\n" +
+		"true\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"dangerous\"\r\n" +
+		"\r\n" +
+		"true\r\n" +
+		"----hello--\r\n"
+	head := map[string]string{"Content-Type": "multipart/thing; boundary=--hello--"}
+	// try a multipart/form-data upload
+	chgSummary := s.sideloadCheck(c, body, head, snapstate.Classic, true)
+	c.Check(chgSummary, check.Equals, `Install "local" snap from file "x"`)
+}
+
 func (s *apiSuite) TestSideloadSnapJailMode(c *check.C) {
 	body := "" +
 		"----hello--\r\n" +
@@ -1893,6 +2247,47 @@ func (s *apiSuite) TestSetConf(c *check.C) {
 	}})
 }
 
+func (s *apiSuite) TestSetConfConflict(c *check.C) {
+	d := s.daemon(c)
+	s.mockSnap(c, configYaml)
+
+	st := d.overlord.State()
+	st.Lock()
+	chg := st.NewChange("refresh-snap", "...")
+	t := st.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "config-snap"},
+	})
+	chg.AddTask(t)
+	st.Unlock()
+
+	text, err := json.Marshal(map[string]interface{}{"key": "value"})
+	c.Assert(err, check.IsNil)
+
+	buffer := bytes.NewBuffer(text)
+	req, err := http.NewRequest("PUT", "/v2/snaps/config-snap/conf", buffer)
+	c.Assert(err, check.IsNil)
+
+	s.vars = map[string]string{"name": "config-snap"}
+
+	rec := httptest.NewRecorder()
+	snapConfCmd.PUT(snapConfCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 409)
+
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	c.Check(body["result"], check.DeepEquals, map[string]interface{}{
+		"message": `cannot set config for "config-snap": snap "config-snap" has changes in progress`,
+		"kind":    "snap-change-conflict",
+		"value": map[string]interface{}{
+			"snap-name":   "config-snap",
+			"change-kind": "refresh-snap",
+			"change-id":   chg.ID(),
+		},
+	})
+}
+
 func (s *apiSuite) TestAppIconGet(c *check.C) {
 	d := s.daemon(c)
 
@@ -2160,6 +2555,75 @@ func (s *apiSuite) TestPostSnapsOp(c *check.C) {
 	c.Check(apiData["snap-names"], check.DeepEquals, []interface{}{"fake1", "fake2"})
 }
 
+func (s *apiSuite) TestPostSnapsOpAt(c *check.C) {
+	snapstateUpdateMany = func(s *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+		t := s.NewTask("fake-refresh-all", "Refreshing everything")
+		return []string{"fake1"}, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	}
+
+	d := s.daemon(c)
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+	ensureStateSoon = func(st *state.State) {}
+
+	buf := bytes.NewBufferString(`{"action": "refresh", "at": "2030-01-02T02:00:00Z"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp := postSnaps(snapsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg.Tasks(), check.HasLen, 2)
+	at := time.Date(2030, 1, 2, 2, 0, 0, 0, time.UTC)
+	wait := chg.Tasks()[1]
+	c.Check(wait.Kind(), check.Equals, "wait-scheduled")
+	c.Check(wait.AtTime().Equal(at), check.Equals, true)
+	var snapNames []string
+	c.Assert(wait.Get("snap-names", &snapNames), check.IsNil)
+	c.Check(snapNames, check.DeepEquals, []string{"fake1"})
+	c.Check(chg.Tasks()[0].WaitTasks(), check.DeepEquals, []*state.Task{wait})
+
+	// the schedule is shown with the change
+	info := change2changeInfo(chg)
+	c.Assert(info.Tasks, check.HasLen, 2)
+	c.Check(info.Tasks[0].AtTime, check.IsNil)
+	c.Assert(info.Tasks[1].AtTime, check.NotNil)
+	c.Check(info.Tasks[1].AtTime.Equal(at), check.Equals, true)
+}
+
+func (s *apiSuite) TestPostSnapsOpDeadline(c *check.C) {
+	snapstateUpdateMany = func(s *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+		t := s.NewTask("fake-refresh-all", "Refreshing everything")
+		return []string{"fake1"}, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	}
+
+	d := s.daemon(c)
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+	ensureStateSoon = func(st *state.State) {}
+
+	buf := bytes.NewBufferString(`{"action": "refresh", "deadline": "2030-01-02T02:00:00Z"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp := postSnaps(snapsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg.Tasks(), check.HasLen, 1)
+	c.Check(chg.Tasks()[0].AtTime().IsZero(), check.Equals, true)
+	c.Check(chg.Tasks()[0].Deadline().Equal(time.Date(2030, 1, 2, 2, 0, 0, 0, time.UTC)), check.Equals, true)
+}
+
 func (s *apiSuite) TestRefreshAll(c *check.C) {
 	refreshSnapDecls := false
 	assertstateRefreshSnapDeclarations = func(s *state.State, userID int) error {
@@ -2270,6 +2734,35 @@ func (s *apiSuite) TestRemoveMany(c *check.C) {
 	c.Check(removes, check.DeepEquals, inst.Snaps)
 }
 
+func (s *apiSuite) TestRemovePurge(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	kinds := func(ts *state.TaskSet) []string {
+		var kinds []string
+		for _, t := range ts.Tasks() {
+			kinds = append(kinds, t.Kind())
+		}
+		return kinds
+	}
+
+	inst := &snapInstruction{Action: "remove", Snaps: []string{"foo"}}
+	_, tsets, err := snapRemove(inst, st)
+	c.Assert(err, check.IsNil)
+	c.Assert(tsets, check.HasLen, 1)
+	c.Check(kinds(tsets[0]), testutil.Contains, "save-snapshot")
+
+	inst.Purge = true
+	_, tsets, err = snapRemove(inst, st)
+	c.Assert(err, check.IsNil)
+	c.Assert(tsets, check.HasLen, 1)
+	c.Check(kinds(tsets[0]), check.Not(testutil.Contains), "save-snapshot")
+}
+
 func (s *apiSuite) TestInstallMissingUbuntuCore(c *check.C) {
 	installQueue := []*state.Task{}
 
@@ -2506,6 +2999,22 @@ func (s *apiSuite) TestInstallJailModeDevMode(c *check.C) {
 	c.Check(err, check.ErrorMatches, "cannot use devmode and jailmode flags together")
 }
 
+func (s *apiSuite) TestInstallJailModeClassic(c *check.C) {
+	d := s.daemon(c)
+	inst := &snapInstruction{
+		Action:   "install",
+		Classic:  true,
+		JailMode: true,
+		Snaps:    []string{"foo"},
+	}
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	_, _, err := inst.dispatch()(inst, st)
+	c.Check(err, check.ErrorMatches, "cannot use classic and jailmode flags together")
+}
+
 func snapList(rawSnaps interface{}) []map[string]interface{} {
 	snaps := make([]map[string]interface{}, len(rawSnaps.([]*json.RawMessage)))
 	for i, raw := range rawSnaps.([]*json.RawMessage) {
@@ -3305,7 +3814,7 @@ func (s *apiSuite) TestStateChangesDefaultToInProgress(c *check.C) {
 	res, err := rsp.MarshalJSON()
 	c.Assert(err, check.IsNil)
 
-	c.Check(string(res), check.Matches, `.*{"id":"\w+","kind":"install","summary":"install...","status":"Do","tasks":\[{"id":"\w+","kind":"download","summary":"1...","status":"Do","log":\["2016-04-21T01:02:03Z INFO l11","2016-04-21T01:02:03Z INFO l12"],"progress":{"label":"","done":0,"total":1},"spawn-time":"2016-04-21T01:02:03Z"}.*`)
+	c.Check(string(res), check.Matches, `.*{"id":"\w+","kind":"install","summary":"install...","status":"Do","tasks":\[{"id":"\w+","kind":"download","summary":"1...","status":"Do","log":\["2016-04-21T01:02:03Z INFO l11","2016-04-21T01:02:03Z INFO l12"],"log-entries":\[{"time":"2016-04-21T01:02:03Z","kind":"INFO","message":"l11"},{"time":"2016-04-21T01:02:03Z","kind":"INFO","message":"l12"}],"progress":{"label":"","done":0,"total":1},"spawn-time":"2016-04-21T01:02:03Z"}.*`)
 }
 
 func (s *apiSuite) TestStateChangesInProgress(c *check.C) {
@@ -3332,7 +3841,7 @@ func (s *apiSuite) TestStateChangesInProgress(c *check.C) {
 	res, err := rsp.MarshalJSON()
 	c.Assert(err, check.IsNil)
 
-	c.Check(string(res), check.Matches, `.*{"id":"\w+","kind":"install","summary":"install...","status":"Do","tasks":\[{"id":"\w+","kind":"download","summary":"1...","status":"Do","log":\["2016-04-21T01:02:03Z INFO l11","2016-04-21T01:02:03Z INFO l12"],"progress":{"label":"","done":0,"total":1},"spawn-time":"2016-04-21T01:02:03Z"}.*],"ready":false,"spawn-time":"2016-04-21T01:02:03Z"}.*`)
+	c.Check(string(res), check.Matches, `.*{"id":"\w+","kind":"install","summary":"install...","status":"Do","tasks":\[{"id":"\w+","kind":"download","summary":"1...","status":"Do","log":\["2016-04-21T01:02:03Z INFO l11","2016-04-21T01:02:03Z INFO l12"],"log-entries":\[{"time":"2016-04-21T01:02:03Z","kind":"INFO","message":"l11"},{"time":"2016-04-21T01:02:03Z","kind":"INFO","message":"l12"}],"progress":{"label":"","done":0,"total":1},"spawn-time":"2016-04-21T01:02:03Z"}.*],"ready":false,"spawn-time":"2016-04-21T01:02:03Z"}.*`)
 }
 
 func (s *apiSuite) TestStateChangesAll(c *check.C) {
@@ -3358,8 +3867,8 @@ func (s *apiSuite) TestStateChangesAll(c *check.C) {
 	res, err := rsp.MarshalJSON()
 	c.Assert(err, check.IsNil)
 
-	c.Check(string(res), check.Matches, `.*{"id":"\w+","kind":"install","summary":"install...","status":"Do","tasks":\[{"id":"\w+","kind":"download","summary":"1...","status":"Do","log":\["2016-04-21T01:02:03Z INFO l11","2016-04-21T01:02:03Z INFO l12"],"progress":{"label":"","done":0,"total":1},"spawn-time":"2016-04-21T01:02:03Z"}.*],"ready":false,"spawn-time":"2016-04-21T01:02:03Z"}.*`)
-	c.Check(string(res), check.Matches, `.*{"id":"\w+","kind":"remove","summary":"remove..","status":"Error","tasks":\[{"id":"\w+","kind":"unlink","summary":"1...","status":"Error","log":\["2016-04-21T01:02:03Z ERROR rm failed"],"progress":{"label":"","done":1,"total":1},"spawn-time":"2016-04-21T01:02:03Z","ready-time":"2016-04-21T01:02:03Z"}.*],"ready":true,"err":"[^"]+".*`)
+	c.Check(string(res), check.Matches, `.*{"id":"\w+","kind":"install","summary":"install...","status":"Do","tasks":\[{"id":"\w+","kind":"download","summary":"1...","status":"Do","log":\["2016-04-21T01:02:03Z INFO l11","2016-04-21T01:02:03Z INFO l12"],"log-entries":\[{"time":"2016-04-21T01:02:03Z","kind":"INFO","message":"l11"},{"time":"2016-04-21T01:02:03Z","kind":"INFO","message":"l12"}],"progress":{"label":"","done":0,"total":1},"spawn-time":"2016-04-21T01:02:03Z"}.*],"ready":false,"spawn-time":"2016-04-21T01:02:03Z"}.*`)
+	c.Check(string(res), check.Matches, `.*{"id":"\w+","kind":"remove","summary":"remove..","status":"Error","tasks":\[{"id":"\w+","kind":"unlink","summary":"1...","status":"Error","log":\["2016-04-21T01:02:03Z ERROR rm failed"],"log-entries":\[{"time":"2016-04-21T01:02:03Z","kind":"ERROR","message":"rm failed"}],"progress":{"label":"","done":1,"total":1},"spawn-time":"2016-04-21T01:02:03Z","ready-time":"2016-04-21T01:02:03Z"}.*],"ready":true,"err":"[^"]+".*`)
 }
 
 func (s *apiSuite) TestStateChangesReady(c *check.C) {
@@ -3385,7 +3894,7 @@ func (s *apiSuite) TestStateChangesReady(c *check.C) {
 	res, err := rsp.MarshalJSON()
 	c.Assert(err, check.IsNil)
 
-	c.Check(string(res), check.Matches, `.*{"id":"\w+","kind":"remove","summary":"remove..","status":"Error","tasks":\[{"id":"\w+","kind":"unlink","summary":"1...","status":"Error","log":\["2016-04-21T01:02:03Z ERROR rm failed"],"progress":{"label":"","done":1,"total":1},"spawn-time":"2016-04-21T01:02:03Z","ready-time":"2016-04-21T01:02:03Z"}.*],"ready":true,"err":"[^"]+".*`)
+	c.Check(string(res), check.Matches, `.*{"id":"\w+","kind":"remove","summary":"remove..","status":"Error","tasks":\[{"id":"\w+","kind":"unlink","summary":"1...","status":"Error","log":\["2016-04-21T01:02:03Z ERROR rm failed"],"log-entries":\[{"time":"2016-04-21T01:02:03Z","kind":"ERROR","message":"rm failed"}],"progress":{"label":"","done":1,"total":1},"spawn-time":"2016-04-21T01:02:03Z","ready-time":"2016-04-21T01:02:03Z"}.*],"ready":true,"err":"[^"]+".*`)
 }
 
 func (s *apiSuite) TestStateChangesForSnapName(c *check.C) {
@@ -3456,11 +3965,15 @@ func (s *apiSuite) TestStateChange(c *check.C) {
 		"spawn-time": "2016-04-21T01:02:03Z",
 		"tasks": []interface{}{
 			map[string]interface{}{
-				"id":         ids[2],
-				"kind":       "download",
-				"summary":    "1...",
-				"status":     "Do",
-				"log":        []interface{}{"2016-04-21T01:02:03Z INFO l11", "2016-04-21T01:02:03Z INFO l12"},
+				"id":      ids[2],
+				"kind":    "download",
+				"summary": "1...",
+				"status":  "Do",
+				"log":     []interface{}{"2016-04-21T01:02:03Z INFO l11", "2016-04-21T01:02:03Z INFO l12"},
+				"log-entries": []interface{}{
+					map[string]interface{}{"time": "2016-04-21T01:02:03Z", "kind": "INFO", "message": "l11"},
+					map[string]interface{}{"time": "2016-04-21T01:02:03Z", "kind": "INFO", "message": "l12"},
+				},
 				"progress":   map[string]interface{}{"label": "", "done": 0., "total": 1.},
 				"spawn-time": "2016-04-21T01:02:03Z",
 			},
@@ -3479,6 +3992,58 @@ func (s *apiSuite) TestStateChange(c *check.C) {
 	})
 }
 
+func (s *apiSuite) TestStateChangeTaskData(c *check.C) {
+	// Setup
+	d := newTestDaemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	ids := setupChanges(st)
+	st.Task(ids[2]).Set("snap-setup", map[string]string{"channel": "edge"})
+	st.Unlock()
+	s.vars = map[string]string{"id": ids[0]}
+
+	// Execute
+	req, err := http.NewRequest("GET", "/v2/changes/"+ids[0]+"?data=true", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "uid=0;"
+	rsp := getChange(stateChangeCmd, req, nil).(*resp)
+
+	// Verify
+	c.Assert(rsp.Status, check.Equals, http.StatusOK)
+	chgInfo := rsp.Result.(*changeInfo)
+	c.Assert(chgInfo.Tasks, check.HasLen, 2)
+	c.Assert(chgInfo.Tasks[0].Data, check.HasLen, 1)
+	c.Check(string(*chgInfo.Tasks[0].Data["snap-setup"]), check.Equals, `{"channel":"edge"}`)
+	c.Check(chgInfo.Tasks[1].Data, check.HasLen, 0)
+}
+
+func (s *apiSuite) TestStateChangeTaskDataErrors(c *check.C) {
+	// Setup
+	d := newTestDaemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	ids := setupChanges(st)
+	st.Unlock()
+	s.vars = map[string]string{"id": ids[0]}
+
+	for _, t := range []struct {
+		query, remoteAddr string
+		status            int
+		message           string
+	}{
+		{"data=true", "uid=1000;", http.StatusUnauthorized, "access denied"},
+		{"data=true", "", http.StatusUnauthorized, "access denied"},
+		{"data=maybe", "uid=0;", http.StatusBadRequest, "data should be one of: true,false"},
+	} {
+		req, err := http.NewRequest("GET", "/v2/changes/"+ids[0]+"?"+t.query, nil)
+		c.Assert(err, check.IsNil)
+		req.RemoteAddr = t.remoteAddr
+		rsp := getChange(stateChangeCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, t.status, check.Commentf("%v", t))
+		c.Check(rsp.Result.(*errorResult).Message, check.Equals, t.message)
+	}
+}
+
 func (s *apiSuite) TestStateChangeAbort(c *check.C) {
 	restore := state.MockTime(time.Date(2016, 04, 21, 1, 2, 3, 0, time.UTC))
 	defer restore()
@@ -3527,11 +4092,15 @@ func (s *apiSuite) TestStateChangeAbort(c *check.C) {
 		"ready-time": "2016-04-21T01:02:03Z",
 		"tasks": []interface{}{
 			map[string]interface{}{
-				"id":         ids[2],
-				"kind":       "download",
-				"summary":    "1...",
-				"status":     "Hold",
-				"log":        []interface{}{"2016-04-21T01:02:03Z INFO l11", "2016-04-21T01:02:03Z INFO l12"},
+				"id":      ids[2],
+				"kind":    "download",
+				"summary": "1...",
+				"status":  "Hold",
+				"log":     []interface{}{"2016-04-21T01:02:03Z INFO l11", "2016-04-21T01:02:03Z INFO l12"},
+				"log-entries": []interface{}{
+					map[string]interface{}{"time": "2016-04-21T01:02:03Z", "kind": "INFO", "message": "l11"},
+					map[string]interface{}{"time": "2016-04-21T01:02:03Z", "kind": "INFO", "message": "l12"},
+				},
 				"progress":   map[string]interface{}{"label": "", "done": 1., "total": 1.},
 				"spawn-time": "2016-04-21T01:02:03Z",
 				"ready-time": "2016-04-21T01:02:03Z",
@@ -3824,3 +4393,407 @@ func (s *apiSuite) TestPaymentMethods(c *check.C) {
 	c.Assert(rsp.Result, check.FitsTypeOf, s.paymentMethods)
 	c.Check(rsp.Result, check.DeepEquals, s.paymentMethods)
 }
+
+func (s *apiSuite) TestListSnapshots(c *check.C) {
+	sets := []client.SnapshotSet{{ID: 1}, {ID: 2}}
+	snapshotList = func(setID uint64, snapNames []string) ([]client.SnapshotSet, error) {
+		c.Check(setID, check.Equals, uint64(2))
+		c.Check(snapNames, check.DeepEquals, []string{"foo", "bar"})
+		return sets[1:], nil
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snapshots?set=2&snaps=foo,bar", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := listSnapshots(snapshotsCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Status, check.Equals, http.StatusOK)
+	c.Check(rsp.Result, check.DeepEquals, sets[1:])
+}
+
+func (s *apiSuite) TestListSnapshotsBadSetID(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/snapshots?set=foo", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := listSnapshots(snapshotsCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, http.StatusBadRequest)
+}
+
+func (s *apiSuite) TestSaveSnapshots(c *check.C) {
+	ensureStateSoon = func(st *state.State) {}
+	snapshotSave = func(st *state.State, snapNames []string, users []string) (uint64, []string, *state.TaskSet, error) {
+		c.Check(snapNames, check.DeepEquals, []string{"foo", "bar"})
+		c.Check(users, check.DeepEquals, []string{"user1"})
+		t := st.NewTask("fake-save-snapshot", "...")
+		return 42, []string{"bar", "foo"}, state.NewTaskSet(t), nil
+	}
+
+	d := s.daemon(c)
+
+	buf := bytes.NewBufferString(`{"action": "save", "snaps": ["foo", "bar"], "users": ["user1"]}`)
+	req, err := http.NewRequest("POST", "/v2/snapshots", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := changeSnapshots(snapshotsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "save-snapshot")
+	c.Check(chg.Summary(), check.Equals, `Save data of snaps "bar", "foo"`)
+	c.Check(chg.Tasks(), check.HasLen, 1)
+
+	var apiData map[string]interface{}
+	c.Assert(chg.Get("api-data", &apiData), check.IsNil)
+	c.Check(apiData, check.DeepEquals, map[string]interface{}{
+		"snap-names": []interface{}{"bar", "foo"},
+		"set-id":     42.,
+	})
+}
+
+func (s *apiSuite) TestRestoreAndForgetSnapshots(c *check.C) {
+	ensureStateSoon = func(st *state.State) {}
+	snapshotRestore = func(st *state.State, setID uint64, snapNames []string, users []string) ([]string, *state.TaskSet, error) {
+		c.Check(setID, check.Equals, uint64(42))
+		c.Check(snapNames, check.HasLen, 0)
+		c.Check(users, check.DeepEquals, []string{"user1"})
+		t := st.NewTask("fake-restore-snapshot", "...")
+		return []string{"foo"}, state.NewTaskSet(t), nil
+	}
+	snapshotForget = func(st *state.State, setID uint64, snapNames []string) ([]string, *state.TaskSet, error) {
+		c.Check(setID, check.Equals, uint64(42))
+		c.Check(snapNames, check.DeepEquals, []string{"foo"})
+		t := st.NewTask("fake-forget-snapshot", "...")
+		return []string{"foo"}, state.NewTaskSet(t), nil
+	}
+
+	d := s.daemon(c)
+	st := d.overlord.State()
+
+	for _, t := range []struct {
+		body    string
+		kind    string
+		summary string
+	}{
+		{`{"action": "restore", "set": 42, "users": ["user1"]}`, "restore-snapshot", `Restore data of snaps "foo"`},
+		{`{"action": "forget", "set": 42, "snaps": ["foo"]}`, "forget-snapshot", `Drop data of snaps "foo"`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/snapshots", bytes.NewBufferString(t.body))
+		c.Assert(err, check.IsNil)
+
+		rsp := changeSnapshots(snapshotsCmd, req, nil).(*resp)
+		c.Assert(rsp.Type, check.Equals, ResponseTypeAsync, check.Commentf(t.body))
+
+		st.Lock()
+		chg := st.Change(rsp.Change)
+		c.Assert(chg, check.NotNil)
+		c.Check(chg.Kind(), check.Equals, t.kind)
+		c.Check(chg.Summary(), check.Equals, t.summary)
+		st.Unlock()
+	}
+}
+
+func (s *apiSuite) TestChangeSnapshotsBadRequests(c *check.C) {
+	s.daemon(c)
+
+	for _, t := range []struct {
+		body  string
+		error string
+	}{
+		{`garbage`, `cannot decode request body into snapshot action: .*`},
+		{`{"action": "save", "set": 1}`, `snapshot action "save" does not take a set id`},
+		{`{"action": "restore"}`, `snapshot action "restore" requires a set id`},
+		{`{"action": "forget", "set": 1, "users": ["user1"]}`, `snapshot action "forget" does not take users`},
+		{`{"action": "frobble", "set": 1}`, `unknown snapshot action "frobble"`},
+		{`{"action": "restore", "set": 1}`, `cannot restore snapshot: cannot find snapshot set #1`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/snapshots", bytes.NewBufferString(t.body))
+		c.Assert(err, check.IsNil)
+
+		rsp := changeSnapshots(snapshotsCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError, check.Commentf(t.body))
+		c.Check(rsp.Status, check.Equals, http.StatusBadRequest, check.Commentf(t.body))
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.error, check.Commentf(t.body))
+	}
+}
+
+const appsYaml = `
+apps:
+  svc1:
+    daemon: simple
+  svc2:
+    daemon: forking
+  app:
+`
+
+func (s *apiSuite) mockSystemctl(c *check.C) (restore func()) {
+	old := systemd.SystemctlCmd
+	systemd.SystemctlCmd = func(args ...string) ([]byte, error) {
+		c.Assert(args[0], check.Equals, "show")
+		if args[2] == "snap.foo.svc1.service" {
+			return []byte("ActiveState=active\nUnitFileState=enabled\n"), nil
+		}
+		return []byte("ActiveState=inactive\nUnitFileState=disabled\n"), nil
+	}
+	return func() {
+		systemd.SystemctlCmd = old
+	}
+}
+
+func (s *apiSuite) TestAppsInfo(c *check.C) {
+	defer s.mockSystemctl(c)()
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, appsYaml)
+	s.mkInstalledInState(c, d, "baz", "bar", "v1", snap.R(1), true, "apps: {baz: }")
+
+	for _, t := range []struct {
+		query string
+		apps  []*client.AppInfo
+	}{
+		{"", []*client.AppInfo{
+			{Snap: "baz", Name: "baz"},
+			{Snap: "foo", Name: "app"},
+			{Snap: "foo", Name: "svc1", Daemon: "simple", Enabled: true, Active: true},
+			{Snap: "foo", Name: "svc2", Daemon: "forking"},
+		}},
+		{"select=service", []*client.AppInfo{
+			{Snap: "foo", Name: "svc1", Daemon: "simple", Enabled: true, Active: true},
+			{Snap: "foo", Name: "svc2", Daemon: "forking"},
+		}},
+		{"names=foo.svc2,baz", []*client.AppInfo{
+			{Snap: "baz", Name: "baz"},
+			{Snap: "foo", Name: "svc2", Daemon: "forking"},
+		}},
+	} {
+		req, err := http.NewRequest("GET", "/v2/apps?"+t.query, nil)
+		c.Assert(err, check.IsNil)
+
+		rsp := getAppsInfo(appsCmd, req, nil).(*resp)
+		c.Assert(rsp.Type, check.Equals, ResponseTypeSync, check.Commentf(t.query))
+		c.Check(rsp.Result, check.DeepEquals, t.apps, check.Commentf(t.query))
+	}
+}
+
+func (s *apiSuite) TestAppsInfoErrors(c *check.C) {
+	defer s.mockSystemctl(c)()
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, appsYaml)
+	s.mkInstalledInState(c, d, "baz", "bar", "v1", snap.R(1), true, "apps: {baz: }")
+
+	for _, t := range []struct {
+		query  string
+		status int
+		error  string
+	}{
+		{"select=frobble", http.StatusBadRequest, `invalid select parameter: "frobble"`},
+		{"names=quux", http.StatusNotFound, `snap "quux" not found`},
+		{"names=quux.svc", http.StatusNotFound, `snap "quux" not found`},
+		{"names=foo.nope", http.StatusNotFound, `snap "foo" has no app "nope"`},
+		{"names=foo.app&select=service", http.StatusNotFound, `snap "foo" has no service "app"`},
+		{"names=baz&select=service", http.StatusNotFound, `snap "baz" has no services`},
+	} {
+		req, err := http.NewRequest("GET", "/v2/apps?"+t.query, nil)
+		c.Assert(err, check.IsNil)
+
+		rsp := getAppsInfo(appsCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError, check.Commentf(t.query))
+		c.Check(rsp.Status, check.Equals, t.status, check.Commentf(t.query))
+		c.Check(rsp.Result.(*errorResult).Message, check.Equals, t.error, check.Commentf(t.query))
+	}
+}
+
+func (s *apiSuite) TestLogs(c *check.C) {
+	var args []interface{}
+	old := systemd.JournalctlReaderCmd
+	defer func() { systemd.JournalctlReaderCmd = old }()
+	systemd.JournalctlReaderCmd = func(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+		args = []interface{}{svcs, n, follow}
+		return ioutil.NopCloser(strings.NewReader(`
+{"MESSAGE": "hello", "SYSLOG_IDENTIFIER": "foo.svc1", "_PID": "42", "__REALTIME_TIMESTAMP": "1481014800000000"}
+{"MESSAGE": "bye", "SYSLOG_IDENTIFIER": "foo.svc2", "_PID": "43", "__REALTIME_TIMESTAMP": "1481014801000000"}
+`)), nil
+	}
+
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, appsYaml)
+
+	req, err := http.NewRequest("GET", "/v2/logs?names=foo&n=-1&follow=false", nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	getLogs(logsCmd, req, nil).ServeHTTP(rec, req)
+
+	c.Check(args, check.DeepEquals, []interface{}{[]string{"snap.foo.svc1.service", "snap.foo.svc2.service"}, -1, false})
+	c.Check(rec.Code, check.Equals, http.StatusOK)
+	c.Check(rec.HeaderMap.Get("Content-Type"), check.Equals, "application/json-seq")
+	c.Check(rec.Body.String(), check.Equals, "\x1e"+`{"timestamp":"2016-12-06T09:00:00Z","message":"hello","sid":"foo.svc1","pid":"42"}`+"\n"+
+		"\x1e"+`{"timestamp":"2016-12-06T09:00:01Z","message":"bye","sid":"foo.svc2","pid":"43"}`+"\n")
+}
+
+func (s *apiSuite) TestLogsBadRequests(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, appsYaml)
+
+	for _, t := range []struct {
+		query  string
+		status int
+		error  string
+	}{
+		{"n=foo", http.StatusBadRequest, `invalid value for n: "foo": .*`},
+		{"follow=foo", http.StatusBadRequest, `invalid value for follow: "foo": .*`},
+		{"names=foo.app", http.StatusNotFound, `snap "foo" has no service "app"`},
+	} {
+		req, err := http.NewRequest("GET", "/v2/logs?"+t.query, nil)
+		c.Assert(err, check.IsNil)
+
+		rsp := getLogs(logsCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError, check.Commentf(t.query))
+		c.Check(rsp.Status, check.Equals, t.status, check.Commentf(t.query))
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.error, check.Commentf(t.query))
+	}
+}
+
+func (s *apiSuite) TestPostApps(c *check.C) {
+	ensureStateSoon = func(st *state.State) {}
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, appsYaml)
+
+	buf := bytes.NewBufferString(`{"action": "start", "names": ["foo"], "enable": true}`)
+	req, err := http.NewRequest("POST", "/v2/apps", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postApps(appsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "service-control")
+	c.Check(chg.Summary(), check.Equals, "Start services foo.svc1, foo.svc2")
+	c.Assert(chg.Tasks(), check.HasLen, 1)
+	c.Check(chg.Tasks()[0].Kind(), check.Equals, "service-control")
+
+	var apiData map[string]interface{}
+	c.Assert(chg.Get("api-data", &apiData), check.IsNil)
+	c.Check(apiData, check.DeepEquals, map[string]interface{}{
+		"snap-names": []interface{}{"foo"},
+	})
+}
+
+func (s *apiSuite) TestPostAppsBadRequests(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, appsYaml)
+
+	for _, t := range []struct {
+		body   string
+		status int
+		error  string
+	}{
+		{`garbage`, http.StatusBadRequest, `cannot decode request body into service operation: .*`},
+		{`{"action": "start"}`, http.StatusBadRequest, `cannot perform operation on services without a list of services to operate on`},
+		{`{"action": "frobble", "names": ["foo"]}`, http.StatusBadRequest, `unknown service action "frobble"`},
+		{`{"action": "stop", "names": ["foo"], "enable": true}`, http.StatusBadRequest, `cannot enable services when stopping them`},
+		{`{"action": "start", "names": ["foo.app"]}`, http.StatusNotFound, `snap "foo" has no service "app"`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/apps", bytes.NewBufferString(t.body))
+		c.Assert(err, check.IsNil)
+
+		rsp := postApps(appsCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError, check.Commentf(t.body))
+		c.Check(rsp.Status, check.Equals, t.status, check.Commentf(t.body))
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.error, check.Commentf(t.body))
+	}
+}
+
+const aliasYaml = `
+apps:
+  app:
+    aliases: [alias1, alias2]
+  reset:
+    aliases: [alias3]
+`
+
+func (s *apiSuite) TestAliases(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "alias-snap", "bar", "v1", snap.R(1), true, aliasYaml)
+	s.mkInstalledInState(c, d, "other-snap", "bar", "v1", snap.R(1), true, "")
+
+	st := d.overlord.State()
+	st.Lock()
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(st, "alias-snap", &snapst), check.IsNil)
+	snapst.Aliases = []string{"alias1"}
+	snapstate.Set(st, "alias-snap", &snapst)
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/aliases", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getAliases(aliasesCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Assert(rsp.Status, check.Equals, http.StatusOK)
+	c.Check(rsp.Result, check.DeepEquals, map[string]map[string]client.AliasStatus{
+		"alias-snap": {
+			"alias1": {App: "alias-snap.app", Status: "enabled"},
+			"alias2": {App: "alias-snap.app", Status: "disabled"},
+			"alias3": {App: "alias-snap.reset", Status: "disabled"},
+		},
+	})
+}
+
+func (s *apiSuite) TestAliasChange(c *check.C) {
+	ensureStateSoon = func(st *state.State) {}
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "alias-snap", "bar", "v1", snap.R(1), true, aliasYaml)
+
+	buf := bytes.NewBufferString(`{"action": "alias", "snap": "alias-snap", "aliases": ["alias1", "alias3"]}`)
+	req, err := http.NewRequest("POST", "/v2/aliases", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := changeAliases(aliasesCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "alias")
+	c.Check(chg.Summary(), check.Equals, `Enable aliases alias1, alias3 for snap "alias-snap"`)
+	c.Assert(chg.Tasks(), check.HasLen, 1)
+	c.Check(chg.Tasks()[0].Kind(), check.Equals, "alias")
+
+	var snapNames []string
+	c.Assert(chg.Get("snap-names", &snapNames), check.IsNil)
+	c.Check(snapNames, check.DeepEquals, []string{"alias-snap"})
+}
+
+func (s *apiSuite) TestAliasChangeBadRequests(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "alias-snap", "bar", "v1", snap.R(1), true, aliasYaml)
+
+	for _, t := range []struct {
+		body  string
+		error string
+	}{
+		{`garbage`, `cannot decode request body into an alias action: .*`},
+		{`{"action": "alias", "snap": "alias-snap"}`, `at least one alias name is required`},
+		{`{"action": "frobble", "snap": "alias-snap", "aliases": ["alias1"]}`, `unsupported alias action: "frobble"`},
+		{`{"action": "alias", "snap": "alias-snap", "aliases": ["alias4"]}`, `cannot enable alias "alias4" for "alias-snap", no such alias`},
+		{`{"action": "unalias", "snap": "alias-snap", "aliases": ["alias1"]}`, `cannot disable alias "alias1" for "alias-snap", alias is not enabled`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/aliases", bytes.NewBufferString(t.body))
+		c.Assert(err, check.IsNil)
+
+		rsp := changeAliases(aliasesCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError, check.Commentf(t.body))
+		c.Check(rsp.Status, check.Equals, http.StatusBadRequest, check.Commentf(t.body))
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.error, check.Commentf(t.body))
+	}
+}
@@ -50,6 +50,7 @@ type Daemon struct {
 	tomb          tomb.Tomb
 	router        *mux.Router
 	hub           *notifications.Hub
+	events        *eventPublisher
 	// enableInternalInterfaceActions controls if adding and removing slots and plugs is allowed.
 	enableInternalInterfaceActions bool
 }
@@ -248,6 +249,10 @@ func (d *Daemon) Start() {
 	// the loop runs in its own goroutine
 	d.overlord.Loop()
 
+	d.tomb.Go(func() error {
+		return d.events.run(d.tomb.Dying())
+	})
+
 	d.tomb.Go(func() error {
 		if d.snapListener != nil {
 			d.tomb.Go(func() error {
@@ -284,15 +289,24 @@ func (d *Daemon) Dying() <-chan struct{} {
 	return d.tomb.Dying()
 }
 
-// New Daemon
-func New() (*Daemon, error) {
-	ovld, err := overlord.New()
+// New Daemon, with its overlord created using the given options, if any
+func New(opts *overlord.Options) (*Daemon, error) {
+	ovld, err := overlord.New(opts)
 	if err != nil {
 		return nil, err
 	}
+	hub := notifications.NewHub()
+	events := newEventPublisher(hub)
+
+	st := ovld.State()
+	st.Lock()
+	st.AddObserver(events)
+	st.Unlock()
+
 	return &Daemon{
 		overlord: ovld,
-		hub:      notifications.NewHub(),
+		hub:      hub,
+		events:   events,
 		// TODO: Decide when this should be disabled by default.
 		enableInternalInterfaceActions: true,
 	}, nil
@@ -55,7 +55,7 @@ func (s *daemonSuite) TearDownTest(c *check.C) {
 
 // build a new daemon, with only a little of Init(), suitable for the tests
 func newTestDaemon(c *check.C) *Daemon {
-	d, err := New()
+	d, err := New(nil)
 	c.Assert(err, check.IsNil)
 	d.addRoutes()
 
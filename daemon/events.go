@@ -0,0 +1,207 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/notifications"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// progressInterval is the minimum time between two task-progress
+// notifications for the same task, other than the final one.
+var progressInterval = 250 * time.Millisecond
+
+var timeNow = time.Now
+
+// eventPublisher observes the state and publishes the status transitions
+// of changes and tasks, the progress of tasks and the snap and interface
+// operations that completed on the notifications hub.
+//
+// Observer methods are called with the state lock held, so notifications
+// are only queued there and published to the (possibly slow) websockets
+// by run.
+type eventPublisher struct {
+	hub *notifications.Hub
+
+	mu     sync.Mutex
+	queue  []*notifications.Notification
+	wakeup chan struct{}
+
+	// lastProgress is only accessed with the state lock held
+	lastProgress map[string]time.Time
+}
+
+func newEventPublisher(hub *notifications.Hub) *eventPublisher {
+	return &eventPublisher{
+		hub:          hub,
+		wakeup:       make(chan struct{}, 1),
+		lastProgress: make(map[string]time.Time),
+	}
+}
+
+func (p *eventPublisher) enqueue(n *notifications.Notification) {
+	n.Timestamp = timeNow().UnixNano()
+
+	p.mu.Lock()
+	p.queue = append(p.queue, n)
+	p.mu.Unlock()
+
+	select {
+	case p.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// flush publishes the queued notifications, in order.
+func (p *eventPublisher) flush() {
+	p.mu.Lock()
+	queue := p.queue
+	p.queue = nil
+	p.mu.Unlock()
+
+	for _, n := range queue {
+		p.hub.Publish(n)
+	}
+}
+
+// run publishes the queued notifications until dying is closed.
+func (p *eventPublisher) run(dying <-chan struct{}) error {
+	for {
+		select {
+		case <-p.wakeup:
+			p.flush()
+		case <-dying:
+			return nil
+		}
+	}
+}
+
+func changeResource(chg *state.Change) string {
+	return "/v2/changes/" + chg.ID()
+}
+
+func (p *eventPublisher) ChangeStatusChanged(chg *state.Change, old, new state.Status) {
+	p.enqueue(&notifications.Notification{
+		Type:     "change-update",
+		Resource: changeResource(chg),
+		Metadata: map[string]interface{}{
+			"change-id":  chg.ID(),
+			"kind":       chg.Kind(),
+			"summary":    chg.Summary(),
+			"status":     new.String(),
+			"old-status": old.String(),
+			"ready":      new.Ready(),
+		},
+	})
+
+	if new == state.DoneStatus {
+		p.changeDone(chg)
+	}
+}
+
+// changeDone publishes the snap or interface operation performed by the
+// change, once per affected snap.
+func (p *eventPublisher) changeDone(chg *state.Change) {
+	var typ string
+	kind := chg.Kind()
+	switch kind {
+	case "connect-snap", "disconnect-snap":
+		typ = "interface"
+	case "install-snap", "try-snap", "refresh-snap", "remove-snap", "revert-snap", "enable-snap", "disable-snap":
+		typ = "snap"
+	default:
+		return
+	}
+
+	var snapNames []string
+	if err := chg.Get("snap-names", &snapNames); err != nil {
+		return
+	}
+	for _, name := range snapNames {
+		p.enqueue(&notifications.Notification{
+			Type:     typ,
+			Resource: "/v2/snaps/" + name,
+			Metadata: map[string]interface{}{
+				"change-id": chg.ID(),
+				"action":    strings.TrimSuffix(kind, "-snap"),
+				"snap-name": name,
+			},
+		})
+	}
+}
+
+func taskMetadata(t *state.Task) map[string]interface{} {
+	label, done, total := t.Progress()
+	metadata := map[string]interface{}{
+		"task-id": t.ID(),
+		"kind":    t.Kind(),
+		"summary": t.Summary(),
+		"status":  t.Status().String(),
+		"progress": map[string]interface{}{
+			"label": label,
+			"done":  done,
+			"total": total,
+		},
+	}
+	if chg := t.Change(); chg != nil {
+		metadata["change-id"] = chg.ID()
+	}
+	return metadata
+}
+
+func taskResource(t *state.Task) string {
+	if chg := t.Change(); chg != nil {
+		return changeResource(chg)
+	}
+	return ""
+}
+
+func (p *eventPublisher) TaskStatusChanged(t *state.Task, old, new state.Status) {
+	if new.Ready() {
+		delete(p.lastProgress, t.ID())
+	}
+
+	metadata := taskMetadata(t)
+	metadata["old-status"] = old.String()
+	p.enqueue(&notifications.Notification{
+		Type:     "task-update",
+		Resource: taskResource(t),
+		Metadata: metadata,
+	})
+}
+
+func (p *eventPublisher) TaskProgressChanged(t *state.Task) {
+	_, done, total := t.Progress()
+	now := timeNow()
+	if done != total && now.Sub(p.lastProgress[t.ID()]) < progressInterval {
+		return
+	}
+	p.lastProgress[t.ID()] = now
+
+	p.enqueue(&notifications.Notification{
+		Type:     "task-progress",
+		Resource: taskResource(t),
+		Metadata: taskMetadata(t),
+	})
+}
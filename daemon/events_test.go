@@ -0,0 +1,261 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/notifications"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type eventsSuite struct {
+	st   *state.State
+	hub  *notifications.Hub
+	pub  *eventPublisher
+	conn *recordingConn
+
+	restore func()
+}
+
+var _ = check.Suite(&eventsSuite{})
+
+type recordingConn struct {
+	messages []*notifications.Notification
+}
+
+func (c *recordingConn) WriteMessage(messageType int, data []byte) error {
+	var n notifications.Notification
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	c.messages = append(c.messages, &n)
+	return nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (s *eventsSuite) SetUpTest(c *check.C) {
+	now := time.Date(2016, 11, 1, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	s.restore = func() { timeNow = time.Now }
+
+	s.st = state.New(nil)
+	s.hub = notifications.NewHub()
+	s.pub = newEventPublisher(s.hub)
+	s.st.Lock()
+	s.st.AddObserver(s.pub)
+	s.st.Unlock()
+
+	s.conn = &recordingConn{}
+	s.subscribe(c, s.conn, "/v2/events")
+}
+
+func (s *eventsSuite) TearDownTest(c *check.C) {
+	s.restore()
+}
+
+func (s *eventsSuite) subscribe(c *check.C, conn *recordingConn, path string) {
+	req, err := http.NewRequest("GET", path, nil)
+	c.Assert(err, check.IsNil)
+	s.hub.Subscribe(notifications.NewSubscriber(conn, req))
+}
+
+func summarize(ns []*notifications.Notification) []string {
+	var out []string
+	for _, n := range ns {
+		line := n.Type + " " + n.Resource
+		if status, ok := n.Metadata["status"].(string); ok {
+			line += " " + status
+		}
+		if action, ok := n.Metadata["action"].(string); ok {
+			line += " " + action
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func (s *eventsSuite) TestPublishesChangeAndTaskUpdates(c *check.C) {
+	st := s.st
+	st.Lock()
+	chg := st.NewChange("install-snap", "Install foo")
+	chg.Set("snap-names", []string{"foo"})
+	t := st.NewTask("download-snap", "Download foo")
+	chg.AddTask(t)
+	t.SetStatus(state.DoingStatus)
+	t.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	// nothing reaches the websockets until flushed
+	c.Check(s.conn.messages, check.HasLen, 0)
+	s.pub.flush()
+
+	c.Check(summarize(s.conn.messages), check.DeepEquals, []string{
+		"task-update /v2/changes/1 Doing",
+		"change-update /v2/changes/1 Doing",
+		"task-update /v2/changes/1 Done",
+		"change-update /v2/changes/1 Done",
+		"snap /v2/snaps/foo install",
+	})
+
+	n := s.conn.messages[3]
+	c.Check(n.Timestamp, check.Equals, timeNow().UnixNano())
+	c.Check(n.Metadata, check.DeepEquals, map[string]interface{}{
+		"change-id":  "1",
+		"kind":       "install-snap",
+		"summary":    "Install foo",
+		"status":     "Done",
+		"old-status": "Doing",
+		"ready":      true,
+	})
+	c.Check(s.conn.messages[2].Metadata["task-id"], check.Equals, "1")
+	c.Check(s.conn.messages[2].Metadata["change-id"], check.Equals, "1")
+	c.Check(s.conn.messages[4].Metadata["change-id"], check.Equals, "1")
+}
+
+func (s *eventsSuite) TestPublishesInterfaceEvents(c *check.C) {
+	st := s.st
+	st.Lock()
+	chg := st.NewChange("connect-snap", "Connect foo:bar to baz:bar")
+	chg.Set("snap-names", []string{"foo", "baz"})
+	t := st.NewTask("connect", "...")
+	chg.AddTask(t)
+	t.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	s.pub.flush()
+
+	c.Check(summarize(s.conn.messages), check.DeepEquals, []string{
+		"task-update /v2/changes/1 Done",
+		"change-update /v2/changes/1 Done",
+		"interface /v2/snaps/foo connect",
+		"interface /v2/snaps/baz connect",
+	})
+}
+
+func (s *eventsSuite) TestNoSnapEventsOnError(c *check.C) {
+	st := s.st
+	st.Lock()
+	chg := st.NewChange("remove-snap", "Remove foo")
+	chg.Set("snap-names", []string{"foo"})
+	t := st.NewTask("unlink-snap", "...")
+	chg.AddTask(t)
+	t.SetStatus(state.ErrorStatus)
+	st.Unlock()
+
+	s.pub.flush()
+
+	c.Check(summarize(s.conn.messages), check.DeepEquals, []string{
+		"task-update /v2/changes/1 Error",
+		"change-update /v2/changes/1 Error",
+	})
+}
+
+func (s *eventsSuite) TestProgressIsThrottled(c *check.C) {
+	now := timeNow()
+	timeNow = func() time.Time { return now }
+
+	st := s.st
+	st.Lock()
+	chg := st.NewChange("install-snap", "...")
+	t := st.NewTask("download-snap", "...")
+	chg.AddTask(t)
+	t.SetProgress("foo", 1, 10)
+	t.SetProgress("foo", 2, 10)
+	now = now.Add(progressInterval)
+	t.SetProgress("foo", 3, 10)
+	// the final progress is always published
+	t.SetProgress("foo", 10, 10)
+	st.Unlock()
+
+	s.pub.flush()
+
+	var done []float64
+	for _, n := range s.conn.messages {
+		c.Assert(n.Type, check.Equals, "task-progress")
+		c.Assert(n.Resource, check.Equals, "/v2/changes/1")
+		progress := n.Metadata["progress"].(map[string]interface{})
+		c.Check(progress["label"], check.Equals, "foo")
+		c.Check(progress["total"], check.Equals, float64(10))
+		done = append(done, progress["done"].(float64))
+	}
+	c.Check(done, check.DeepEquals, []float64{1, 3, 10})
+}
+
+func (s *eventsSuite) TestFilterByChange(c *check.C) {
+	conn := &recordingConn{}
+	s.subscribe(c, conn, "/v2/events?change=2&types=change-update")
+
+	st := s.st
+	st.Lock()
+	for i := 0; i < 2; i++ {
+		chg := st.NewChange("refresh-snap", "...")
+		t := st.NewTask("download-snap", "...")
+		chg.AddTask(t)
+		t.SetStatus(state.DoingStatus)
+	}
+	st.Unlock()
+
+	s.pub.flush()
+
+	c.Check(s.conn.messages, check.HasLen, 4)
+	c.Check(summarize(conn.messages), check.DeepEquals, []string{
+		"change-update /v2/changes/2 Doing",
+	})
+}
+
+func (s *eventsSuite) TestRun(c *check.C) {
+	dying := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- s.pub.run(dying)
+	}()
+
+	s.st.Lock()
+	chg := s.st.NewChange("install-snap", "...")
+	chg.SetStatus(state.ErrorStatus)
+	s.st.Unlock()
+
+	// published by run without explicit flushing
+	for i := 0; i < 100; i++ {
+		s.hub.Lock()
+		n := len(s.conn.messages)
+		s.hub.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.hub.Lock()
+	c.Check(summarize(s.conn.messages), check.DeepEquals, []string{
+		"change-update /v2/changes/1 Error",
+	})
+	s.hub.Unlock()
+
+	close(dying)
+	c.Check(<-done, check.IsNil)
+}
@@ -20,18 +20,24 @@
 package daemon
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"mime"
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"sync"
 
 	"github.com/gorilla/websocket"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/notifications"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/systemd"
 )
 
 // ResponseType is the response type
@@ -120,12 +126,14 @@ func (r *resp) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
 type errorKind string
 
 const (
-	errorKindTwoFactorRequired = errorKind("two-factor-required")
-	errorKindTwoFactorFailed   = errorKind("two-factor-failed")
-	errorKindLoginRequired     = errorKind("login-required")
-	errorKindInvalidAuthData   = errorKind("invalid-auth-data")
-	errorKindTermsNotAccepted  = errorKind("terms-not-accepted")
-	errorKindNoPaymentMethods  = errorKind("no-payment-methods")
+	errorKindTwoFactorRequired  = errorKind("two-factor-required")
+	errorKindTwoFactorFailed    = errorKind("two-factor-failed")
+	errorKindLoginRequired      = errorKind("login-required")
+	errorKindInvalidAuthData    = errorKind("invalid-auth-data")
+	errorKindTermsNotAccepted   = errorKind("terms-not-accepted")
+	errorKindNoPaymentMethods   = errorKind("no-payment-methods")
+	errorKindUnsuccessful       = errorKind("unsuccessful")
+	errorKindSnapChangeConflict = errorKind("snap-change-conflict")
 )
 
 type errorValue interface{}
@@ -181,6 +189,28 @@ func makeErrorResponder(status int) errorResponder {
 	}
 }
 
+// SnapChangeConflict builds the error response for an operation
+// refused because a change in progress is already operating on the
+// snap; the value carries the conflicting change.
+func SnapChangeConflict(err *snapstate.ChangeConflictError, format string, v ...interface{}) Response {
+	value := map[string]interface{}{
+		"snap-name": err.Snap,
+	}
+	if err.ChangeID != "" {
+		value["change-kind"] = err.ChangeKind
+		value["change-id"] = err.ChangeID
+	}
+	return &resp{
+		Type: ResponseTypeError,
+		Result: &errorResult{
+			Message: fmt.Sprintf(format, v...),
+			Kind:    errorKindSnapChangeConflict,
+			Value:   value,
+		},
+		Status: http.StatusConflict,
+	}
+}
+
 // A FileResponse 's ServeHTTP method serves the file
 type FileResponse string
 
@@ -246,6 +276,75 @@ func (e eventResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	e.h.Subscribe(s)
 }
 
+// A journalLineReaderSeqResponse's ServeHTTP method reads the journal
+// entries that journalctl -o json writes to the io.ReadCloser, and writes
+// each of them out as a client.Log in a JSON text sequence (RFC 7464).
+type journalLineReaderSeqResponse struct {
+	io.ReadCloser
+	follow bool
+
+	closeOnce sync.Once
+}
+
+func (rr *journalLineReaderSeqResponse) close() {
+	rr.closeOnce.Do(func() { rr.Close() })
+}
+
+func (rr *journalLineReaderSeqResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json-seq")
+	defer rr.close()
+
+	flusher, hasFlusher := w.(http.Flusher)
+	if cn, ok := w.(http.CloseNotifier); ok && rr.follow {
+		// stop following the journal when the client goes away
+		done := make(chan struct{})
+		defer close(done)
+		closed := cn.CloseNotify()
+		go func() {
+			select {
+			case <-closed:
+				rr.close()
+			case <-done:
+			}
+		}()
+	}
+
+	dec := json.NewDecoder(rr)
+	writer := bufio.NewWriter(w)
+	enc := json.NewEncoder(writer)
+	for {
+		var log systemd.Log
+		if err := dec.Decode(&log); err != nil {
+			if err != io.EOF {
+				logger.Debugf("cannot decode journal entry: %v", err)
+			}
+			break
+		}
+
+		t, _ := log.Time()
+		writer.WriteByte(0x1E) // RS -- see ascii(7), and RFC7464
+		if err := enc.Encode(client.Log{
+			Timestamp: t,
+			Message:   log.Message(),
+			SID:       log.SID(),
+			PID:       log.PID(),
+		}); err != nil {
+			logger.Noticef("cannot encode log entry: %v", err)
+			break
+		}
+
+		if rr.follow {
+			if err := writer.Flush(); err != nil {
+				break
+			}
+			if hasFlusher {
+				flusher.Flush()
+			}
+		}
+	}
+	writer.Flush()
+}
+
 // errorResponder is a callable that produces an error Response.
 // e.g., InternalError("something broke: %v", err), etc.
 type errorResponder func(string, ...interface{}) Response
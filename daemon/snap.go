@@ -24,11 +24,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
 )
 
 var errNoSnap = errors.New("no snap installed")
@@ -108,6 +113,118 @@ func allLocalSnapInfos(st *state.State) ([]aboutSnap, error) {
 	return about, firstErr
 }
 
+type appInfoOptions struct {
+	service bool
+}
+
+func (opts appInfoOptions) String() string {
+	if opts.service {
+		return "service"
+	}
+
+	return "app"
+}
+
+type bySnapApp []*snap.AppInfo
+
+func (a bySnapApp) Len() int      { return len(a) }
+func (a bySnapApp) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a bySnapApp) Less(i, j int) bool {
+	iName := a[i].Snap.Name()
+	jName := a[j].Snap.Name()
+	if iName == jName {
+		return a[i].Name < a[j].Name
+	}
+	return iName < jName
+}
+
+// appInfosFor returns the snap.AppInfos for the given names, which can
+// be snap or snap.app names (all the apps of installed snaps, if none
+// are given), sorted by snap and app name. On error it returns a
+// Response to be sent back to the client.
+func appInfosFor(st *state.State, names []string, opts appInfoOptions) ([]*snap.AppInfo, Response) {
+	requested := make(map[string]bool, len(names))
+	for _, name := range names {
+		requested[name] = true
+	}
+
+	snaps, err := allLocalSnapInfos(st)
+	if err != nil {
+		return nil, InternalError("cannot list local snaps! %v", err)
+	}
+
+	installed := make(map[string]bool, len(snaps))
+	found := make(map[string]bool, len(names))
+	appInfos := make([]*snap.AppInfo, 0, len(names))
+	for _, snp := range snaps {
+		snapName := snp.info.Name()
+		installed[snapName] = true
+		for _, app := range snp.info.Apps {
+			if opts.service && !app.IsService() {
+				continue
+			}
+			appName := snapName + "." + app.Name
+			switch {
+			case len(requested) == 0:
+			case requested[snapName]:
+				found[snapName] = true
+			case requested[appName]:
+				found[appName] = true
+			default:
+				continue
+			}
+			appInfos = append(appInfos, app)
+		}
+	}
+
+	for _, name := range names {
+		if found[name] {
+			continue
+		}
+		snapName, appName := snap.SplitSnapApp(name)
+		if !installed[snapName] {
+			return nil, NotFound("snap %q not found", snapName)
+		}
+		if name == snapName {
+			return nil, NotFound("snap %q has no %ss", snapName, opts)
+		}
+		return nil, NotFound("snap %q has no %s %q", snapName, opts, appName)
+	}
+
+	sort.Sort(bySnapApp(appInfos))
+
+	return appInfos, nil
+}
+
+// clientAppInfosFromSnapAppInfos converts the given apps into the form
+// used by the client, querying systemd for the state of services.
+func clientAppInfosFromSnapAppInfos(apps []*snap.AppInfo) ([]*client.AppInfo, error) {
+	// TODO: pass in an actual notifier here instead of null
+	//       (Status doesn't _need_ it, but benefits from it)
+	sysd := systemd.New(dirs.GlobalRootDir, &progress.NullProgress{})
+
+	out := make([]*client.AppInfo, len(apps))
+	for i, app := range apps {
+		out[i] = &client.AppInfo{
+			Snap:   app.Snap.Name(),
+			Name:   app.Name,
+			Daemon: app.Daemon,
+		}
+		if !app.IsService() {
+			continue
+		}
+
+		st, err := sysd.ServiceStatus(filepath.Base(app.ServiceFile()))
+		if err != nil {
+			return nil, err
+		}
+		out[i].Enabled = st.UnitFileState == "enabled"
+		out[i].Active = st.ActiveState == "active"
+	}
+
+	return out, nil
+}
+
 // appJSON contains the json for snap.AppInfo
 type appJSON struct {
 	Name string `json:"name"`
@@ -133,7 +250,7 @@ func mapLocal(localSnap *snap.Info, snapst *snapstate.SnapState) map[string]inte
 		})
 	}
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"description":    localSnap.Description(),
 		"developer":      localSnap.Developer,
 		"icon":           snapIcon(localSnap),
@@ -154,6 +271,27 @@ func mapLocal(localSnap *snap.Info, snapst *snapstate.SnapState) map[string]inte
 		"apps":           apps,
 		"broken":         localSnap.Broken,
 	}
+
+	if snapst.Channel != "" {
+		result["tracking-channel"] = snapst.Channel
+	}
+
+	if localSnap.InstanceKey != "" {
+		result["snap-name"] = localSnap.SnapName()
+		result["instance-key"] = localSnap.InstanceKey
+	}
+
+	if snapst.Held() {
+		result["held"] = true
+		if !snapst.Hold.Until.IsZero() {
+			result["hold-until"] = snapst.Hold.Until
+		}
+		if !snapst.Hold.Revision.Unset() {
+			result["pinned-revision"] = snapst.Hold.Revision
+		}
+	}
+
+	return result
 }
 
 func mapRemote(remoteSnap *snap.Info) map[string]interface{} {
@@ -49,12 +49,16 @@ var (
 
 	SnapSeedDir   string
 	SnapDeviceDir string
+	SnapCookieDir string
 
 	SnapAssertsDBDir      string
 	SnapTrustedAccountKey string
 
-	SnapStateFile      string
-	SnapFirstBootStamp string
+	SnapStateFile        string
+	SnapStateJournalFile string
+	SnapFirstBootStamp   string
+
+	SnapshotsDir string
 
 	SnapBinariesDir     string
 	SnapServicesDir     string
@@ -123,9 +127,13 @@ func SetRootDir(rootdir string) {
 	SnapAssertsDBDir = filepath.Join(rootdir, snappyDir, "assertions")
 
 	SnapStateFile = filepath.Join(rootdir, snappyDir, "state.json")
+	SnapStateJournalFile = filepath.Join(rootdir, snappyDir, "state.journal")
+
+	SnapshotsDir = filepath.Join(rootdir, snappyDir, "snapshots")
 
 	SnapSeedDir = filepath.Join(rootdir, snappyDir, "seed")
 	SnapDeviceDir = filepath.Join(rootdir, snappyDir, "device")
+	SnapCookieDir = filepath.Join(rootdir, snappyDir, "cookie")
 
 	// NOTE: if you change stampFile, update the condition in
 	// snapd.firstboot.service to match
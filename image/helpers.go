@@ -28,7 +28,6 @@ import (
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
-	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
@@ -45,7 +44,7 @@ type DownloadOptions struct {
 // A Store can find metadata on snaps, download snaps and fetch assertions.
 type Store interface {
 	Snap(name, channel string, devmode bool, revision snap.Revision, user *auth.UserState) (*snap.Info, error)
-	Download(name string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState) (path string, err error)
+	Download(name, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState) error
 
 	Assertion(assertType *asserts.AssertionType, primaryKey []string, user *auth.UserState) (asserts.Assertion, error)
 }
@@ -69,16 +68,11 @@ func DownloadSnap(sto Store, name string, revision snap.Revision, opts *Download
 	if err != nil {
 		return "", nil, fmt.Errorf("cannot find snap %q: %v", name, err)
 	}
-	pb := progress.NewTextProgress()
-	tmpName, err := sto.Download(name, &snap.DownloadInfo, pb, opts.User)
-	if err != nil {
-		return "", nil, err
-	}
-	defer os.Remove(tmpName)
-
 	baseName := filepath.Base(snap.MountFile())
 	targetPath = filepath.Join(targetDir, baseName)
-	if err := osutil.CopyFile(tmpName, targetPath, 0); err != nil {
+
+	pb := progress.NewTextProgress()
+	if err := sto.Download(name, targetPath, &snap.DownloadInfo, pb, opts.User); err != nil {
 		return "", nil, err
 	}
 
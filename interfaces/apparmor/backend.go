@@ -145,6 +145,9 @@ func (b *Backend) combineSnippets(snapInfo *snap.Info, devMode bool, snippets ma
 
 func addContent(securityTag string, snapInfo *snap.Info, devMode bool, snippets map[string][][]byte, content map[string]*osutil.FileState) {
 	policy := defaultTemplate
+	if snapInfo.NeedsClassic() {
+		policy = classicTemplate
+	}
 	if devMode {
 		policy = attachPattern.ReplaceAll(policy, attachComplain)
 	}
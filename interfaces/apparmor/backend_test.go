@@ -302,6 +302,49 @@ func (s *backendSuite) TestRealDefaultTemplateIsNormallyUsed(c *C) {
 	}
 }
 
+func (s *backendSuite) TestClassicTemplateForClassicSnaps(c *C) {
+	restore := apparmor.MockClassicTemplate([]byte("\n" +
+		"###PROFILEATTACH### (attach_disconnected) {\n" +
+		"  classic,\n" +
+		"###SNIPPETS###\n" +
+		"}\n"))
+	defer restore()
+	snapInfo := s.InstallSnap(c, false, `
+name: samba
+version: 1
+confinement: classic
+apps:
+    smbd:
+`, 1)
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	data, err := ioutil.ReadFile(profile)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `
+profile "snap.samba.smbd" (attach_disconnected) {
+  classic,
+
+}
+`)
+	s.RemoveSnap(c, snapInfo)
+}
+
+func (s *backendSuite) TestRealClassicTemplateIsUnrestricted(c *C) {
+	snapInfo := s.InstallSnap(c, false, `
+name: samba
+version: 1
+confinement: classic
+apps:
+    smbd:
+`, 1)
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	data, err := ioutil.ReadFile(profile)
+	c.Assert(err, IsNil)
+	c.Check(string(data), testutil.Contains, "  capability,\n")
+	c.Check(string(data), testutil.Contains, "  file,\n")
+	c.Check(string(data), Not(testutil.Contains), "/sys/class/ r,\n")
+	s.RemoveSnap(c, snapInfo)
+}
+
 type combineSnippetsScenario struct {
 	devMode bool
 	snippet string
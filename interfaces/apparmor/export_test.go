@@ -40,3 +40,10 @@ func MockTemplate(fakeTemplate []byte) (restore func()) {
 	defaultTemplate = fakeTemplate
 	return func() { defaultTemplate = orig }
 }
+
+// MockClassicTemplate replaces the apparmor template used for classic snaps.
+func MockClassicTemplate(fakeTemplate []byte) (restore func()) {
+	orig := classicTemplate
+	classicTemplate = fakeTemplate
+	return func() { classicTemplate = orig }
+}
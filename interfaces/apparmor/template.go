@@ -353,3 +353,39 @@ var defaultTemplate = []byte(`
 ###SNIPPETS###
 }
 `)
+
+// classicTemplate contains the apparmor template used for snaps with
+// classic confinement.
+//
+// Those snaps run on the host filesystem and are not meant to be
+// sandboxed, the profile grants everything and exists so that the
+// launcher can attach it like for any other snap.
+var classicTemplate = []byte(`
+# Description: Allows unrestricted access to the system
+# Usage: reserved
+
+# vim:syntax=apparmor
+
+#include <tunables/global>
+
+###VAR###
+
+###PROFILEATTACH### (attach_disconnected) {
+  # set unrestricted access for classic snaps
+  capability,
+  network,
+  dbus,
+  signal,
+  ptrace,
+  unix,
+  mount,
+  remount,
+  umount,
+  pivot_root,
+  change_profile,
+  file,
+  /{,**} mrwlkix,
+
+###SNIPPETS###
+}
+`)
@@ -0,0 +1,89 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/release"
+)
+
+// The builtin base-declaration provides the defaults for the policy
+// about interfaces, snap-declarations can override them per snap.
+//
+// Interfaces that auto-connect do so only to the slots of the OS
+// snap, all the others are connected manually unless a
+// snap-declaration allows it.
+//
+// content is special: it auto-connects plugs and slots of snaps of the
+// same publisher that share the same content attribute.
+const contentBaseDeclarationSlots = `
+  content:
+    allow-auto-connection:
+      plug-publisher-id:
+        - $SLOT_PUBLISHER_ID
+      plug-attributes:
+        content: $SLOT(content)
+`
+
+func baseDeclarationHeaders(ifaces []interfaces.Interface) []byte {
+	sorted := make([]interfaces.Interface, len(ifaces))
+	copy(sorted, ifaces)
+	sort.Sort(byInterfaceName(sorted))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "type: base-declaration\n")
+	fmt.Fprintf(&buf, "authority-id: canonical\n")
+	fmt.Fprintf(&buf, "series: %s\n", release.Series)
+	fmt.Fprintf(&buf, "revision: 0\n")
+	fmt.Fprintf(&buf, "plugs:\n")
+	for _, iface := range sorted {
+		name := iface.Name()
+		if name == "content" {
+			continue
+		}
+		fmt.Fprintf(&buf, "  %s:\n", name)
+		if iface.AutoConnect() {
+			fmt.Fprintf(&buf, "    allow-auto-connection:\n      slot-snap-type:\n        - core\n")
+		} else {
+			fmt.Fprintf(&buf, "    deny-auto-connection: true\n")
+		}
+	}
+	fmt.Fprintf(&buf, "slots:")
+	buf.WriteString(contentBaseDeclarationSlots)
+	return buf.Bytes()
+}
+
+type byInterfaceName []interfaces.Interface
+
+func (c byInterfaceName) Len() int           { return len(c) }
+func (c byInterfaceName) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byInterfaceName) Less(i, j int) bool { return c[i].Name() < c[j].Name() }
+
+func init() {
+	err := asserts.InitBuiltinBaseDeclaration(baseDeclarationHeaders(allInterfaces))
+	if err != nil {
+		panic(fmt.Sprintf("cannot initialize the builtin base-declaration: %v", err))
+	}
+}
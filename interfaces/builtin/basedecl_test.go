@@ -0,0 +1,114 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type baseDeclSuite struct {
+	baseDecl *asserts.BaseDeclaration
+}
+
+var _ = Suite(&baseDeclSuite{})
+
+func (s *baseDeclSuite) SetUpSuite(c *C) {
+	s.baseDecl = asserts.BuiltinBaseDeclaration()
+}
+
+func (s *baseDeclSuite) TestBuiltinInitialized(c *C) {
+	c.Assert(s.baseDecl, NotNil)
+	c.Check(s.baseDecl.AuthorityID(), Equals, "canonical")
+	c.Check(s.baseDecl.Series(), Equals, "16")
+}
+
+func (s *baseDeclSuite) TestEveryInterfaceHasARule(c *C) {
+	for _, iface := range builtin.Interfaces() {
+		name := iface.Name()
+		plugRule := s.baseDecl.PlugRule(name)
+		slotRule := s.baseDecl.SlotRule(name)
+		c.Check(plugRule != nil || slotRule != nil, Equals, true, Commentf(name))
+	}
+}
+
+func (s *baseDeclSuite) TestAutoConnectionDefaults(c *C) {
+	for _, iface := range builtin.Interfaces() {
+		name := iface.Name()
+		if name == "content" {
+			continue
+		}
+		plugRule := s.baseDecl.PlugRule(name)
+		c.Assert(plugRule, NotNil, Commentf(name))
+		if iface.AutoConnect() {
+			c.Check(plugRule.AllowAutoConnection.SlotSnapTypes, DeepEquals, []string{"core"}, Commentf(name))
+			c.Check(plugRule.DenyAutoConnection, IsNil, Commentf(name))
+		} else {
+			c.Check(plugRule.AllowAutoConnection, IsNil, Commentf(name))
+			c.Check(plugRule.DenyAutoConnection, NotNil, Commentf(name))
+		}
+	}
+}
+
+func (s *baseDeclSuite) connectCand(c *C, iface string, plugYaml, slotYaml string) *policy.ConnectCandidate {
+	plugSnap := snaptest.MockInfo(c, plugYaml, nil)
+	slotSnap := snaptest.MockInfo(c, slotYaml, nil)
+	return &policy.ConnectCandidate{
+		Plug:            plugSnap.Plugs[iface],
+		Slot:            slotSnap.Slots[iface],
+		BaseDeclaration: s.baseDecl,
+	}
+}
+
+func (s *baseDeclSuite) TestAutoConnectToCore(c *C) {
+	cand := s.connectCand(c, "network", "name: consumer\nplugs:\n  network:\n", "name: core\ntype: os\nslots:\n  network:\n")
+	c.Check(cand.CheckAutoConnect(), IsNil)
+
+	cand = s.connectCand(c, "network", "name: consumer\nplugs:\n  network:\n", "name: producer\nslots:\n  network:\n")
+	c.Check(cand.CheckAutoConnect(), NotNil)
+
+	cand = s.connectCand(c, "docker-support", "name: consumer\nplugs:\n  docker-support:\n", "name: core\ntype: os\nslots:\n  docker-support:\n")
+	c.Check(cand.CheckAutoConnect(), NotNil)
+	c.Check(cand.Check(), IsNil)
+}
+
+func (s *baseDeclSuite) TestContentAutoConnect(c *C) {
+	plugYaml := `name: consumer
+plugs:
+  content:
+    content: mylib
+    target: import
+`
+	cand := s.connectCand(c, "content", plugYaml, "name: producer\nslots:\n  content:\n    content: mylib\n    read:\n      - export\n")
+	c.Check(cand.CheckAutoConnect(), IsNil)
+
+	cand = s.connectCand(c, "content", plugYaml, "name: producer\nslots:\n  content:\n    content: otherlib\n    read:\n      - export\n")
+	c.Check(cand.CheckAutoConnect(), NotNil)
+
+	// the OS snap doesn't take part in content sharing
+	cand = s.connectCand(c, "content", plugYaml, "name: core\ntype: os\nslots:\n  content:\n    content: otherlib\n    read:\n      - export\n")
+	c.Check(cand.CheckAutoConnect(), NotNil)
+	c.Check(cand.Slot.Snap.Type, Equals, snap.TypeOS)
+}
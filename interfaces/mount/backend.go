@@ -26,13 +26,16 @@
 // Each fstab like file looks like a regular fstab entry:
 //   /src/dir /dst/dir none bind 0 0
 //   /src/dir /dst/dir none bind,rw 0 0
-// but only bind mounts are supported
+// but only bind mounts are supported, with the exception of the
+// entries implementing the layout of the snap (see layoutEntries).
 package mount
 
 import (
 	"bytes"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
@@ -88,9 +91,10 @@ func (b *Backend) Remove(snapName string) error {
 // combineSnippets combines security snippets collected from all the interfaces
 // affecting a given snap into a content map applicable to EnsureDirState.
 func (b *Backend) combineSnippets(snapInfo *snap.Info, snippets map[string][][]byte) (content map[string]*osutil.FileState, err error) {
+	layout := layoutEntries(snapInfo)
 	for _, appInfo := range snapInfo.Apps {
 		securityTag := appInfo.SecurityTag()
-		appSnippets := snippets[securityTag]
+		appSnippets := withLayout(layout, snippets[securityTag])
 		if len(appSnippets) == 0 {
 			continue
 		}
@@ -103,7 +107,7 @@ func (b *Backend) combineSnippets(snapInfo *snap.Info, snippets map[string][][]b
 
 	for _, hookInfo := range snapInfo.Hooks {
 		securityTag := hookInfo.SecurityTag()
-		hookSnippets := snippets[securityTag]
+		hookSnippets := withLayout(layout, snippets[securityTag])
 		if len(hookSnippets) == 0 {
 			continue
 		}
@@ -128,3 +132,64 @@ func addContent(securityTag string, executableSnippets [][]byte, content map[str
 		Mode:    0644,
 	}
 }
+
+// withLayout prepends the layout entries to the given snippets.
+func withLayout(layout, snippets [][]byte) [][]byte {
+	if len(layout) == 0 {
+		return snippets
+	}
+	all := make([][]byte, 0, len(layout)+len(snippets))
+	all = append(all, layout...)
+	return append(all, snippets...)
+}
+
+// escapeFstab escapes the whitespace in a path as it is done in fstab.
+func escapeFstab(path string) string {
+	return strings.Replace(strings.Replace(path, " ", `\040`, -1), "\t", `\011`, -1)
+}
+
+// layoutEntries returns the mount entries implementing the layout of
+// the given snap, sorted by path. They apply to the whole mount
+// namespace of the snap and are thus shared by all its apps and hooks.
+//
+// Besides bind mounts, layouts use tmpfs mounts and symlinks. The
+// x-snapd.* options tell snap-confine what to do with the entries that
+// are not plain mounts, and mark the ones coming from the layout, whose
+// missing mount points snap-confine creates: directories, or empty
+// files for x-snapd.kind=file, through a writable mimic of their parent
+// when it is read-only.
+func layoutEntries(snapInfo *snap.Info) [][]byte {
+	if len(snapInfo.Layout) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(snapInfo.Layout))
+	for path := range snapInfo.Layout {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	entries := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		l := snapInfo.Layout[path]
+		dst := escapeFstab(path)
+		var entry string
+		switch {
+		case l.Bind != "":
+			src := escapeFstab(snapInfo.ExpandSnapVariables(l.Bind))
+			entry = fmt.Sprintf("%s %s none rbind,rw,x-snapd.origin=layout 0 0", src, dst)
+		case l.BindFile != "":
+			src := escapeFstab(snapInfo.ExpandSnapVariables(l.BindFile))
+			entry = fmt.Sprintf("%s %s none bind,rw,x-snapd.kind=file,x-snapd.origin=layout 0 0", src, dst)
+		case l.Symlink != "":
+			target := escapeFstab(snapInfo.ExpandSnapVariables(l.Symlink))
+			entry = fmt.Sprintf("none %s none x-snapd.kind=symlink,x-snapd.symlink=%s,x-snapd.origin=layout 0 0", dst, target)
+		case l.Type == "tmpfs":
+			entry = fmt.Sprintf("tmpfs %s tmpfs x-snapd.origin=layout 0 0", dst)
+		default:
+			// rejected by snap.Validate
+			continue
+		}
+		entries = append(entries, []byte(entry))
+	}
+	return entries
+}
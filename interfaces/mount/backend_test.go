@@ -164,3 +164,47 @@ func (s *backendSuite) TestSetupSetsupWithoutDir(c *C) {
 		c.Assert(osutil.FileExists(fn), Equals, true, Commentf("Expected mount file for %q", binary))
 	}
 }
+
+const mockSnapWithLayoutYaml = `name: snap-name
+version: 1
+apps:
+    app1:
+hooks:
+    configure:
+layout:
+    /usr/share/foo:
+        bind: $SNAP/usr/share/foo
+    /etc/foo.conf:
+        bind-file: $SNAP_DATA/foo.conf
+    /var/lib/foo:
+        symlink: $SNAP_COMMON/lib
+    /var/cache/foo:
+        type: tmpfs
+`
+
+func (s *backendSuite) TestSetupWithLayout(c *C) {
+	fsEntry := "/src-1 /dst-1 none bind,ro 0 0"
+	s.Iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte(fsEntry), nil
+	}
+	s.InstallSnap(c, false, strings.Replace(mockSnapWithLayoutYaml, "hooks:", "slots:\n    iface-slot:\n        interface: iface\nhooks:", 1), 11)
+
+	snapDir := filepath.Join(dirs.SnapMountDir, "snap-name", "11")
+	dataDir := filepath.Join(dirs.SnapDataDir, "snap-name", "11")
+	commonDir := filepath.Join(dirs.SnapDataDir, "snap-name", "common")
+	layout := fmt.Sprintf(`%s/foo.conf /etc/foo.conf none bind,rw,x-snapd.kind=file,x-snapd.origin=layout 0 0
+%s/usr/share/foo /usr/share/foo none rbind,rw,x-snapd.origin=layout 0 0
+tmpfs /var/cache/foo tmpfs x-snapd.origin=layout 0 0
+none /var/lib/foo none x-snapd.kind=symlink,x-snapd.symlink=%s/lib,x-snapd.origin=layout 0 0
+`, dataDir, snapDir, commonDir)
+
+	// the layout goes to both the app and the hook, the snippets of
+	// the interfaces after it
+	content, err := ioutil.ReadFile(filepath.Join(dirs.SnapMountPolicyDir, "snap.snap-name.app1.fstab"))
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, layout+fsEntry+"\n")
+
+	content, err = ioutil.ReadFile(filepath.Join(dirs.SnapMountPolicyDir, "snap.snap-name.hook.configure.fstab"))
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, layout)
+}
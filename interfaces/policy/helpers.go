@@ -0,0 +1,153 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package policy
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/snap"
+)
+
+// check helpers
+
+func sortedSlotNames(info *snap.Info) []string {
+	names := make([]string, 0, len(info.Slots))
+	for name := range info.Slots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedPlugNames(info *snap.Info) []string {
+	names := make([]string, 0, len(info.Plugs))
+	for name := range info.Plugs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// typeName maps a snap type to the name used for it in declarations.
+func typeName(typ snap.Type) string {
+	if typ == snap.TypeOS {
+		return "core"
+	}
+	return string(typ)
+}
+
+func checkSnapType(typ snap.Type, types []string) error {
+	if len(types) == 0 {
+		return nil
+	}
+	s := typeName(typ)
+	for _, t := range types {
+		if t == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("snap type does not match")
+}
+
+func checkID(kind, id string, ids []string, special map[string]string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	for _, cand := range ids {
+		if v, ok := special[cand]; ok {
+			// this also matches two unasserted snaps, both
+			// installed with --dangerous
+			if v == id {
+				return nil
+			}
+			continue
+		}
+		if id != "" && id == cand {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s does not match", kind)
+}
+
+func checkPlugConnectionConstraints(connc *ConnectCandidate, cstrs *asserts.PlugConnectionConstraints) error {
+	if err := cstrs.PlugAttributes.Check(connc.Plug.Attrs, connc); err != nil {
+		return err
+	}
+	if err := cstrs.SlotAttributes.Check(connc.Slot.Attrs, connc); err != nil {
+		return err
+	}
+	if err := checkSnapType(connc.slotSnapType(), cstrs.SlotSnapTypes); err != nil {
+		return err
+	}
+	if err := checkID("snap id", connc.slotSnapID(), cstrs.SlotSnapIDs, nil); err != nil {
+		return err
+	}
+	err := checkID("publisher id", connc.slotPublisherID(), cstrs.SlotPublisherIDs, map[string]string{
+		"$PLUG_PUBLISHER_ID": connc.plugPublisherID(),
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func checkSlotConnectionConstraints(connc *ConnectCandidate, cstrs *asserts.SlotConnectionConstraints) error {
+	if err := cstrs.PlugAttributes.Check(connc.Plug.Attrs, connc); err != nil {
+		return err
+	}
+	if err := cstrs.SlotAttributes.Check(connc.Slot.Attrs, connc); err != nil {
+		return err
+	}
+	if err := checkSnapType(connc.plugSnapType(), cstrs.PlugSnapTypes); err != nil {
+		return err
+	}
+	if err := checkID("snap id", connc.plugSnapID(), cstrs.PlugSnapIDs, nil); err != nil {
+		return err
+	}
+	err := checkID("publisher id", connc.plugPublisherID(), cstrs.PlugPublisherIDs, map[string]string{
+		"$SLOT_PUBLISHER_ID": connc.slotPublisherID(),
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func checkSlotInstallationConstraints(slot *snap.SlotInfo, cstrs *asserts.SlotInstallationConstraints) error {
+	if err := cstrs.SlotAttributes.Check(slot.Attrs, nil); err != nil {
+		return err
+	}
+	if err := checkSnapType(slot.Snap.Type, cstrs.SlotSnapTypes); err != nil {
+		return err
+	}
+	return nil
+}
+
+func checkPlugInstallationConstraints(plug *snap.PlugInfo, cstrs *asserts.PlugInstallationConstraints) error {
+	if err := cstrs.PlugAttributes.Check(plug.Attrs, nil); err != nil {
+		return err
+	}
+	if err := checkSnapType(plug.Snap.Type, cstrs.PlugSnapTypes); err != nil {
+		return err
+	}
+	return nil
+}
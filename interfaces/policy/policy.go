@@ -0,0 +1,310 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package policy implements the declaration based policy checks for
+// connecting or permitting installation of snaps based on their slots
+// and plugs.
+//
+// Rules from the snap-declaration of the snaps involved take
+// precedence over the ones from the base-declaration: the first rule,
+// in the order plug snap-declaration, slot snap-declaration, plug
+// base-declaration, slot base-declaration, that says anything about
+// the operation being checked decides it.
+package policy
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/snap"
+)
+
+// InstallCandidate represents a candidate snap for installation.
+type InstallCandidate struct {
+	Snap            *snap.Info
+	SnapDeclaration *asserts.SnapDeclaration
+	BaseDeclaration *asserts.BaseDeclaration
+}
+
+func (ic *InstallCandidate) checkSlotRule(slot *snap.SlotInfo, rule *asserts.SlotRule, snapRule bool) error {
+	context := ""
+	if snapRule {
+		context = fmt.Sprintf(" for %q snap", ic.Snap.Name())
+	}
+	if rule.DenyInstallation != nil && checkSlotInstallationConstraints(slot, rule.DenyInstallation) == nil {
+		return fmt.Errorf("installation denied by %q slot rule of interface %q%s", slot.Name, slot.Interface, context)
+	}
+	if rule.AllowInstallation != nil && checkSlotInstallationConstraints(slot, rule.AllowInstallation) != nil {
+		return fmt.Errorf("installation not allowed by %q slot rule of interface %q%s", slot.Name, slot.Interface, context)
+	}
+	return nil
+}
+
+func (ic *InstallCandidate) checkPlugRule(plug *snap.PlugInfo, rule *asserts.PlugRule, snapRule bool) error {
+	context := ""
+	if snapRule {
+		context = fmt.Sprintf(" for %q snap", ic.Snap.Name())
+	}
+	if rule.DenyInstallation != nil && checkPlugInstallationConstraints(plug, rule.DenyInstallation) == nil {
+		return fmt.Errorf("installation denied by %q plug rule of interface %q%s", plug.Name, plug.Interface, context)
+	}
+	if rule.AllowInstallation != nil && checkPlugInstallationConstraints(plug, rule.AllowInstallation) != nil {
+		return fmt.Errorf("installation not allowed by %q plug rule of interface %q%s", plug.Name, plug.Interface, context)
+	}
+	return nil
+}
+
+func slotRuleAboutInstallation(rule *asserts.SlotRule) bool {
+	return rule != nil && (rule.AllowInstallation != nil || rule.DenyInstallation != nil)
+}
+
+func plugRuleAboutInstallation(rule *asserts.PlugRule) bool {
+	return rule != nil && (rule.AllowInstallation != nil || rule.DenyInstallation != nil)
+}
+
+func (ic *InstallCandidate) checkSlot(slot *snap.SlotInfo) error {
+	iface := slot.Interface
+	if ic.SnapDeclaration != nil {
+		if rule := ic.SnapDeclaration.SlotRule(iface); slotRuleAboutInstallation(rule) {
+			return ic.checkSlotRule(slot, rule, true)
+		}
+	}
+	if ic.BaseDeclaration != nil {
+		if rule := ic.BaseDeclaration.SlotRule(iface); slotRuleAboutInstallation(rule) {
+			return ic.checkSlotRule(slot, rule, false)
+		}
+	}
+	return nil
+}
+
+func (ic *InstallCandidate) checkPlug(plug *snap.PlugInfo) error {
+	iface := plug.Interface
+	if ic.SnapDeclaration != nil {
+		if rule := ic.SnapDeclaration.PlugRule(iface); plugRuleAboutInstallation(rule) {
+			return ic.checkPlugRule(plug, rule, true)
+		}
+	}
+	if ic.BaseDeclaration != nil {
+		if rule := ic.BaseDeclaration.PlugRule(iface); plugRuleAboutInstallation(rule) {
+			return ic.checkPlugRule(plug, rule, false)
+		}
+	}
+	return nil
+}
+
+// Check checks whether the installation is allowed.
+func (ic *InstallCandidate) Check() error {
+	// classic confinement needs to be granted explicitly to snaps
+	// with a declaration
+	if ic.Snap.NeedsClassic() && ic.SnapDeclaration != nil && !ic.SnapDeclaration.AllowClassic() {
+		return fmt.Errorf("installation not allowed for %q snap: classic confinement not allowed by its snap-declaration", ic.Snap.Name())
+	}
+	for _, name := range sortedSlotNames(ic.Snap) {
+		if err := ic.checkSlot(ic.Snap.Slots[name]); err != nil {
+			return err
+		}
+	}
+	for _, name := range sortedPlugNames(ic.Snap) {
+		if err := ic.checkPlug(ic.Snap.Plugs[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConnectCandidate represents a candidate connection.
+type ConnectCandidate struct {
+	Plug                *snap.PlugInfo
+	PlugSnapDeclaration *asserts.SnapDeclaration
+
+	Slot                *snap.SlotInfo
+	SlotSnapDeclaration *asserts.SnapDeclaration
+
+	BaseDeclaration *asserts.BaseDeclaration
+}
+
+func nestedGet(which string, attrs map[string]interface{}, attr string) (interface{}, error) {
+	v, ok := attrs[attr]
+	if !ok {
+		return nil, fmt.Errorf("%s attribute %q not found", which, attr)
+	}
+	return v, nil
+}
+
+// PlugAttr returns the value of the given attribute of the plug, implementing asserts.AttrMatchContext.
+func (connc *ConnectCandidate) PlugAttr(attr string) (interface{}, error) {
+	return nestedGet("plug", connc.Plug.Attrs, attr)
+}
+
+// SlotAttr returns the value of the given attribute of the slot, implementing asserts.AttrMatchContext.
+func (connc *ConnectCandidate) SlotAttr(attr string) (interface{}, error) {
+	return nestedGet("slot", connc.Slot.Attrs, attr)
+}
+
+func (connc *ConnectCandidate) plugSnapType() snap.Type {
+	return connc.Plug.Snap.Type
+}
+
+func (connc *ConnectCandidate) slotSnapType() snap.Type {
+	return connc.Slot.Snap.Type
+}
+
+func (connc *ConnectCandidate) plugSnapID() string {
+	if connc.PlugSnapDeclaration != nil {
+		return connc.PlugSnapDeclaration.SnapID()
+	}
+	return "" // never a valid snap-id
+}
+
+func (connc *ConnectCandidate) slotSnapID() string {
+	if connc.SlotSnapDeclaration != nil {
+		return connc.SlotSnapDeclaration.SnapID()
+	}
+	return "" // never a valid snap-id
+}
+
+func (connc *ConnectCandidate) plugPublisherID() string {
+	if connc.PlugSnapDeclaration != nil {
+		return connc.PlugSnapDeclaration.PublisherID()
+	}
+	return "" // never a valid publisher-id
+}
+
+func (connc *ConnectCandidate) slotPublisherID() string {
+	if connc.SlotSnapDeclaration != nil {
+		return connc.SlotSnapDeclaration.PublisherID()
+	}
+	return "" // never a valid publisher-id
+}
+
+// connectionKind selects which pair of subrules of a rule applies to a check.
+type connectionKind int
+
+const (
+	manualConnection connectionKind = iota
+	autoConnection
+)
+
+func (kind connectionKind) String() string {
+	if kind == autoConnection {
+		return "auto-connection"
+	}
+	return "connection"
+}
+
+func (kind connectionKind) plugSubrules(rule *asserts.PlugRule) (allow, deny *asserts.PlugConnectionConstraints) {
+	if kind == autoConnection {
+		return rule.AllowAutoConnection, rule.DenyAutoConnection
+	}
+	return rule.AllowConnection, rule.DenyConnection
+}
+
+func (kind connectionKind) slotSubrules(rule *asserts.SlotRule) (allow, deny *asserts.SlotConnectionConstraints) {
+	if kind == autoConnection {
+		return rule.AllowAutoConnection, rule.DenyAutoConnection
+	}
+	return rule.AllowConnection, rule.DenyConnection
+}
+
+func (connc *ConnectCandidate) checkPlugRule(kind connectionKind, rule *asserts.PlugRule, snapRule bool) error {
+	context := ""
+	if snapRule {
+		context = fmt.Sprintf(" for %q snap", connc.Plug.Snap.Name())
+	}
+	allow, deny := kind.plugSubrules(rule)
+	if deny != nil && checkPlugConnectionConstraints(connc, deny) == nil {
+		return fmt.Errorf("%s denied by plug rule of interface %q%s", kind, connc.Plug.Interface, context)
+	}
+	if allow != nil && checkPlugConnectionConstraints(connc, allow) != nil {
+		return fmt.Errorf("%s not allowed by plug rule of interface %q%s", kind, connc.Plug.Interface, context)
+	}
+	return nil
+}
+
+func (connc *ConnectCandidate) checkSlotRule(kind connectionKind, rule *asserts.SlotRule, snapRule bool) error {
+	context := ""
+	if snapRule {
+		context = fmt.Sprintf(" for %q snap", connc.Slot.Snap.Name())
+	}
+	allow, deny := kind.slotSubrules(rule)
+	if deny != nil && checkSlotConnectionConstraints(connc, deny) == nil {
+		return fmt.Errorf("%s denied by slot rule of interface %q%s", kind, connc.Slot.Interface, context)
+	}
+	if allow != nil && checkSlotConnectionConstraints(connc, allow) != nil {
+		return fmt.Errorf("%s not allowed by slot rule of interface %q%s", kind, connc.Slot.Interface, context)
+	}
+	return nil
+}
+
+func (connc *ConnectCandidate) check(kind connectionKind) error {
+	iface := connc.Plug.Interface
+	if connc.Slot.Interface != iface {
+		return fmt.Errorf("cannot connect mismatched plug interface %q to slot interface %q", iface, connc.Slot.Interface)
+	}
+
+	plugRuleApplies := func(rule *asserts.PlugRule) bool {
+		if rule == nil {
+			return false
+		}
+		allow, deny := kind.plugSubrules(rule)
+		return allow != nil || deny != nil
+	}
+	slotRuleApplies := func(rule *asserts.SlotRule) bool {
+		if rule == nil {
+			return false
+		}
+		allow, deny := kind.slotSubrules(rule)
+		return allow != nil || deny != nil
+	}
+
+	if connc.PlugSnapDeclaration != nil {
+		if rule := connc.PlugSnapDeclaration.PlugRule(iface); plugRuleApplies(rule) {
+			return connc.checkPlugRule(kind, rule, true)
+		}
+	}
+	if connc.SlotSnapDeclaration != nil {
+		if rule := connc.SlotSnapDeclaration.SlotRule(iface); slotRuleApplies(rule) {
+			return connc.checkSlotRule(kind, rule, true)
+		}
+	}
+	if connc.BaseDeclaration != nil {
+		if rule := connc.BaseDeclaration.PlugRule(iface); plugRuleApplies(rule) {
+			return connc.checkPlugRule(kind, rule, false)
+		}
+		if rule := connc.BaseDeclaration.SlotRule(iface); slotRuleApplies(rule) {
+			return connc.checkSlotRule(kind, rule, false)
+		}
+	}
+
+	if kind == autoConnection {
+		// auto-connection needs to be explicitly allowed
+		return fmt.Errorf("auto-connection not allowed for interface %q without a rule allowing it", iface)
+	}
+	return nil
+}
+
+// Check checks whether the connection is allowed.
+func (connc *ConnectCandidate) Check() error {
+	return connc.check(manualConnection)
+}
+
+// CheckAutoConnect checks whether the connection is allowed to auto-connect.
+func (connc *ConnectCandidate) CheckAutoConnect() error {
+	return connc.check(autoConnection)
+}
@@ -0,0 +1,310 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package policy_test
+
+import (
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+func TestPolicy(t *testing.T) { TestingT(t) }
+
+type policySuite struct {
+	baseDecl *asserts.BaseDeclaration
+
+	plugSnap *snap.Info
+	slotSnap *snap.Info
+	coreSnap *snap.Info
+}
+
+var _ = Suite(&policySuite{})
+
+const signPart = "timestamp: 2016-09-30T12:00:00Z\n" +
+	"sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij\n\nAXNpZw=="
+
+func decodeBaseDecl(c *C, headers string) *asserts.BaseDeclaration {
+	a, err := asserts.Decode([]byte(strings.TrimSpace(headers) + "\n" + signPart))
+	c.Assert(err, IsNil)
+	return a.(*asserts.BaseDeclaration)
+}
+
+func decodeSnapDecl(c *C, snapID, publisherID, rules string) *asserts.SnapDeclaration {
+	encoded := "type: snap-declaration\n" +
+		"authority-id: canonical\n" +
+		"series: 16\n" +
+		"snap-id: " + snapID + "\n" +
+		"snap-name: name-for-" + snapID + "\n" +
+		"publisher-id: " + publisherID + "\n" +
+		strings.TrimLeft(rules, "\n") +
+		signPart
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	return a.(*asserts.SnapDeclaration)
+}
+
+func (s *policySuite) SetUpSuite(c *C) {
+	s.baseDecl = decodeBaseDecl(c, `
+type: base-declaration
+authority-id: canonical
+series: 16
+plugs:
+  auto:
+    allow-auto-connection:
+      slot-snap-type:
+        - core
+  manual:
+    deny-auto-connection: true
+  plug-denied:
+    deny-installation: true
+  connect-denied:
+    deny-connection:
+      plug-attributes:
+        dangerous: true
+slots:
+  slot-restricted:
+    allow-installation:
+      slot-snap-type:
+        - core
+        - gadget
+  content:
+    allow-auto-connection:
+      plug-publisher-id:
+        - $SLOT_PUBLISHER_ID
+      plug-attributes:
+        content: $SLOT(content)
+`)
+
+	s.plugSnap = snaptest.MockInfo(c, `
+name: plug-snap
+plugs:
+  auto:
+  manual:
+  connect-denied:
+  dangerous:
+    interface: connect-denied
+    dangerous: true
+  content:
+    content: mylib
+  other-content:
+    interface: content
+    content: otherlib
+`, nil)
+	s.slotSnap = snaptest.MockInfo(c, `
+name: slot-snap
+slots:
+  auto:
+  manual:
+  content:
+    content: mylib
+`, nil)
+	s.coreSnap = snaptest.MockInfo(c, `
+name: core
+type: os
+slots:
+  auto:
+  manual:
+  connect-denied:
+`, nil)
+}
+
+func (s *policySuite) connectCand(c *C, plugSnap *snap.Info, plug string, slotSnap *snap.Info, slot string) *policy.ConnectCandidate {
+	p := plugSnap.Plugs[plug]
+	c.Assert(p, NotNil)
+	sl := slotSnap.Slots[slot]
+	c.Assert(sl, NotNil)
+	return &policy.ConnectCandidate{
+		Plug:            p,
+		Slot:            sl,
+		BaseDeclaration: s.baseDecl,
+	}
+}
+
+func (s *policySuite) TestBaseDeclAutoConnection(c *C) {
+	cand := s.connectCand(c, s.plugSnap, "auto", s.coreSnap, "auto")
+	c.Check(cand.CheckAutoConnect(), IsNil)
+	c.Check(cand.Check(), IsNil)
+
+	// only the slots of core are auto-connected
+	cand = s.connectCand(c, s.plugSnap, "auto", s.slotSnap, "auto")
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection not allowed by plug rule of interface "auto"`)
+	c.Check(cand.Check(), IsNil)
+
+	cand = s.connectCand(c, s.plugSnap, "manual", s.coreSnap, "manual")
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection denied by plug rule of interface "manual"`)
+	c.Check(cand.Check(), IsNil)
+}
+
+func (s *policySuite) TestNoRuleDoesNotAutoConnect(c *C) {
+	plugSnap := snaptest.MockInfo(c, "name: plug-snap\nplugs:\n  unknown:\n", nil)
+	slotSnap := snaptest.MockInfo(c, "name: core\ntype: os\nslots:\n  unknown:\n", nil)
+	cand := s.connectCand(c, plugSnap, "unknown", slotSnap, "unknown")
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection not allowed for interface "unknown" without a rule allowing it`)
+	c.Check(cand.Check(), IsNil)
+}
+
+func (s *policySuite) TestBaseDeclConnectionDenied(c *C) {
+	cand := s.connectCand(c, s.plugSnap, "dangerous", s.coreSnap, "connect-denied")
+	c.Check(cand.Check(), ErrorMatches, `connection denied by plug rule of interface "connect-denied"`)
+
+	cand = s.connectCand(c, s.plugSnap, "connect-denied", s.coreSnap, "connect-denied")
+	c.Check(cand.Check(), IsNil)
+}
+
+func (s *policySuite) TestSnapDeclGrantsAutoConnection(c *C) {
+	plugDecl := decodeSnapDecl(c, "plugsnapidsnapidsnapidsnapidsnap", "partner", `
+plugs:
+  manual:
+    allow-auto-connection: true
+`)
+	cand := s.connectCand(c, s.plugSnap, "manual", s.coreSnap, "manual")
+	cand.PlugSnapDeclaration = plugDecl
+	c.Check(cand.CheckAutoConnect(), IsNil)
+}
+
+func (s *policySuite) TestSnapDeclGrantOnlyForMatchingSlotSnap(c *C) {
+	slotDecl := decodeSnapDecl(c, "slotsnapidsnapidsnapidsnapidsnap", "brand", `
+slots:
+  manual:
+    allow-auto-connection:
+      plug-snap-id:
+        - plugsnapidsnapidsnapidsnapidsnap
+`)
+	partnerDecl := decodeSnapDecl(c, "plugsnapidsnapidsnapidsnapidsnap", "partner", "")
+	otherDecl := decodeSnapDecl(c, "othersnapidsnapidsnapidsnapidsna", "someone", "")
+
+	cand := s.connectCand(c, s.plugSnap, "manual", s.slotSnap, "manual")
+	cand.SlotSnapDeclaration = slotDecl
+	cand.PlugSnapDeclaration = partnerDecl
+	c.Check(cand.CheckAutoConnect(), IsNil)
+
+	cand.PlugSnapDeclaration = otherDecl
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection not allowed by slot rule of interface "manual" for "slot-snap" snap`)
+
+	// unasserted snaps never match snap ids
+	cand.PlugSnapDeclaration = nil
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection not allowed by slot rule of interface "manual" for "slot-snap" snap`)
+}
+
+func (s *policySuite) TestSnapDeclPlugRuleTakesPrecedence(c *C) {
+	plugDecl := decodeSnapDecl(c, "plugsnapidsnapidsnapidsnapidsnap", "partner", `
+plugs:
+  auto:
+    deny-auto-connection: true
+`)
+	cand := s.connectCand(c, s.plugSnap, "auto", s.coreSnap, "auto")
+	cand.PlugSnapDeclaration = plugDecl
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection denied by plug rule of interface "auto" for "plug-snap" snap`)
+	// the rule says nothing about manual connections
+	c.Check(cand.Check(), IsNil)
+}
+
+func (s *policySuite) TestContentAutoConnection(c *C) {
+	cand := s.connectCand(c, s.plugSnap, "content", s.slotSnap, "content")
+	// both unasserted
+	c.Check(cand.CheckAutoConnect(), IsNil)
+
+	cand.PlugSnapDeclaration = decodeSnapDecl(c, "plugsnapidsnapidsnapidsnapidsnap", "acme", "")
+	cand.SlotSnapDeclaration = decodeSnapDecl(c, "slotsnapidsnapidsnapidsnapidsnap", "acme", "")
+	c.Check(cand.CheckAutoConnect(), IsNil)
+
+	cand.SlotSnapDeclaration = decodeSnapDecl(c, "slotsnapidsnapidsnapidsnapidsnap", "other", "")
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection not allowed by slot rule of interface "content"`)
+
+	cand = s.connectCand(c, s.plugSnap, "other-content", s.slotSnap, "content")
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection not allowed by slot rule of interface "content"`)
+}
+
+func (s *policySuite) TestInterfaceMismatch(c *C) {
+	cand := s.connectCand(c, s.plugSnap, "auto", s.coreSnap, "manual")
+	c.Check(cand.Check(), ErrorMatches, `cannot connect mismatched plug interface "auto" to slot interface "manual"`)
+}
+
+func (s *policySuite) TestInstallation(c *C) {
+	ic := policy.InstallCandidate{
+		Snap:            s.plugSnap,
+		BaseDeclaration: s.baseDecl,
+	}
+	c.Check(ic.Check(), IsNil)
+
+	snap := snaptest.MockInfo(c, "name: denied\nplugs:\n  plug-denied:\n", nil)
+	ic = policy.InstallCandidate{
+		Snap:            snap,
+		BaseDeclaration: s.baseDecl,
+	}
+	c.Check(ic.Check(), ErrorMatches, `installation denied by "plug-denied" plug rule of interface "plug-denied"`)
+
+	// a snap-declaration can grant the installation
+	ic.SnapDeclaration = decodeSnapDecl(c, "snapidsnapidsnapidsnapidsnapid01", "partner", `
+plugs:
+  plug-denied:
+    allow-installation: true
+`)
+	c.Check(ic.Check(), IsNil)
+
+	// or deny it
+	ic = policy.InstallCandidate{
+		Snap: s.slotSnap,
+		SnapDeclaration: decodeSnapDecl(c, "snapidsnapidsnapidsnapidsnapid02", "someone", `
+slots:
+  auto:
+    deny-installation: true
+`),
+		BaseDeclaration: s.baseDecl,
+	}
+	c.Check(ic.Check(), ErrorMatches, `installation denied by "auto" slot rule of interface "auto" for "slot-snap" snap`)
+}
+
+func (s *policySuite) TestInstallationClassic(c *C) {
+	classicSnap := snaptest.MockInfo(c, "name: classic\nconfinement: classic\n", nil)
+	ic := policy.InstallCandidate{
+		Snap:            classicSnap,
+		BaseDeclaration: s.baseDecl,
+	}
+	// without a snap-declaration there is nothing to check against
+	c.Check(ic.Check(), IsNil)
+
+	ic.SnapDeclaration = decodeSnapDecl(c, "snapidsnapidsnapidsnapidsnapid03", "someone", "")
+	c.Check(ic.Check(), ErrorMatches, `installation not allowed for "classic" snap: classic confinement not allowed by its snap-declaration`)
+
+	ic.SnapDeclaration = decodeSnapDecl(c, "snapidsnapidsnapidsnapidsnapid03", "someone", `
+allow-classic: true
+`)
+	c.Check(ic.Check(), IsNil)
+}
+
+func (s *policySuite) TestInstallationSlotSnapType(c *C) {
+	appSnap := snaptest.MockInfo(c, "name: app\nslots:\n  slot-restricted:\n", nil)
+	ic := policy.InstallCandidate{
+		Snap:            appSnap,
+		BaseDeclaration: s.baseDecl,
+	}
+	c.Check(ic.Check(), ErrorMatches, `installation not allowed by "slot-restricted" slot rule of interface "slot-restricted"`)
+
+	gadgetSnap := snaptest.MockInfo(c, "name: gadget\ntype: gadget\nslots:\n  slot-restricted:\n", nil)
+	ic.Snap = gadgetSnap
+	c.Check(ic.Check(), IsNil)
+}
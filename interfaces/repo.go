@@ -39,6 +39,20 @@ type Repository struct {
 	slots     map[string]map[string]*Slot
 	slotPlugs map[*Slot]map[*Plug]bool
 	plugSlots map[*Plug]map[*Slot]bool
+	// Dynamic attributes of the connections, as set by interface hooks
+	connAttrs map[connKey]*connAttrs
+}
+
+// connKey identifies a connection between a plug and a slot.
+type connKey struct {
+	plug *Plug
+	slot *Slot
+}
+
+// connAttrs holds the dynamic attributes of both sides of a connection.
+type connAttrs struct {
+	plug map[string]interface{}
+	slot map[string]interface{}
 }
 
 // NewRepository creates an empty plug repository.
@@ -49,6 +63,7 @@ func NewRepository() *Repository {
 		slots:     make(map[string]map[string]*Slot),
 		slotPlugs: make(map[*Slot]map[*Plug]bool),
 		plugSlots: make(map[*Plug]map[*Slot]bool),
+		connAttrs: make(map[connKey]*connAttrs),
 	}
 }
 
@@ -123,7 +138,7 @@ func (r *Repository) AddPlug(plug *Plug) error {
 	defer r.m.Unlock()
 
 	// Reject snaps with invalid names
-	if err := snap.ValidateName(plug.Snap.Name()); err != nil {
+	if err := snap.ValidateInstanceName(plug.Snap.Name()); err != nil {
 		return err
 	}
 	// Reject plug with invalid names
@@ -217,7 +232,7 @@ func (r *Repository) AddSlot(slot *Slot) error {
 	defer r.m.Unlock()
 
 	// Reject snaps with invalid names
-	if err := snap.ValidateName(slot.Snap.Name()); err != nil {
+	if err := snap.ValidateInstanceName(slot.Snap.Name()); err != nil {
 		return err
 	}
 	// Reject plug with invalid names
@@ -305,6 +320,64 @@ func (r *Repository) Connect(plugSnapName, plugName, slotSnapName, slotName stri
 	return nil
 }
 
+// SetDynamicAttrs sets the dynamic attributes of the two sides of an
+// existing connection, as set by the interface hooks when the
+// connection was made. They are seen by the interfaces next to the
+// attributes declared in snap.yaml, which take precedence, when
+// computing the security snippets of the connection.
+func (r *Repository) SetDynamicAttrs(plugSnapName, plugName, slotSnapName, slotName string, plugAttrs, slotAttrs map[string]interface{}) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	plug := r.plugs[plugSnapName][plugName]
+	slot := r.slots[slotSnapName][slotName]
+	if plug == nil || slot == nil || !r.slotPlugs[slot][plug] {
+		return fmt.Errorf("cannot set dynamic attributes of plug %q from snap %q and slot %q from snap %q, they are not connected",
+			plugName, plugSnapName, slotName, slotSnapName)
+	}
+	key := connKey{plug: plug, slot: slot}
+	if len(plugAttrs) == 0 && len(slotAttrs) == 0 {
+		delete(r.connAttrs, key)
+		return nil
+	}
+	r.connAttrs[key] = &connAttrs{plug: plugAttrs, slot: slotAttrs}
+	return nil
+}
+
+// connectedPlugAndSlot returns the plug and the slot of a connection as
+// seen by its interface, with the dynamic attributes of the connection
+// if it has any.
+func (r *Repository) connectedPlugAndSlot(plug *Plug, slot *Slot) (*Plug, *Slot) {
+	attrs := r.connAttrs[connKey{plug: plug, slot: slot}]
+	if attrs == nil {
+		return plug, slot
+	}
+	if len(attrs.plug) > 0 {
+		plugInfo := *plug.PlugInfo
+		plugInfo.Attrs = mergeAttrs(plug.Attrs, attrs.plug)
+		plug = &Plug{PlugInfo: &plugInfo, Connections: plug.Connections}
+	}
+	if len(attrs.slot) > 0 {
+		slotInfo := *slot.SlotInfo
+		slotInfo.Attrs = mergeAttrs(slot.Attrs, attrs.slot)
+		slot = &Slot{SlotInfo: &slotInfo, Connections: slot.Connections}
+	}
+	return plug, slot
+}
+
+// mergeAttrs returns the static attributes together with the dynamic
+// ones that don't clash with them.
+func mergeAttrs(static, dynamic map[string]interface{}) map[string]interface{} {
+	attrs := make(map[string]interface{}, len(static)+len(dynamic))
+	for k, v := range dynamic {
+		attrs[k] = v
+	}
+	for k, v := range static {
+		attrs[k] = v
+	}
+	return attrs
+}
+
 // Disconnect disconnects the named plug from the slot of the given snap.
 //
 // Disconnect has three modes of operation that depend on the passed arguments:
@@ -388,6 +461,7 @@ func (r *Repository) disconnectPlugFromSlot(plugSnapName, plugName, slotSnapName
 
 // disconnect disconnects a plug from a slot.
 func (r *Repository) disconnect(plug *Plug, slot *Slot) {
+	delete(r.connAttrs, connKey{plug: plug, slot: slot})
 	delete(r.slotPlugs[slot], plug)
 	if len(r.slotPlugs[slot]) == 0 {
 		delete(r.slotPlugs, slot)
@@ -492,7 +566,8 @@ func (r *Repository) securitySnippetsForSnap(snapName string, securitySystem Sec
 
 		// Add connection-specific snippet specific to each plug
 		for plug := range r.slotPlugs[slot] {
-			snippet, err := iface.ConnectedSlotSnippet(plug, slot, securitySystem)
+			connPlug, connSlot := r.connectedPlugAndSlot(plug, slot)
+			snippet, err := iface.ConnectedSlotSnippet(connPlug, connSlot, securitySystem)
 			if err != nil {
 				return nil, err
 			}
@@ -511,7 +586,8 @@ func (r *Repository) securitySnippetsForSnap(snapName string, securitySystem Sec
 
 		// Add connection-specific snippet specific to each slot
 		for slot := range r.plugSlots[plug] {
-			snippet, err := iface.ConnectedPlugSnippet(plug, slot, securitySystem)
+			connPlug, connSlot := r.connectedPlugAndSlot(plug, slot)
+			snippet, err := iface.ConnectedPlugSnippet(connPlug, connSlot, securitySystem)
 			if err != nil {
 				return nil, err
 			}
@@ -719,7 +795,7 @@ func (r *Repository) DisconnectSnap(snapName string) ([]string, error) {
 // isLivePatchSnap checks special Name/Developer combinations to see
 // if this particular snap's connections should be automatically connected even
 // if the interfaces are not autoconnect and the snap is not an OS snap.
-// FIXME: remove once we have assertions that provide this feature
+// FIXME: remove once the snap-declaration of the snap provides this
 func isLivePatchSnap(snap *snap.Info) bool {
 	if snap.Name() == "canonical-livepatch" && snap.DeveloperID == "canonical" {
 		return true
@@ -729,7 +805,10 @@ func isLivePatchSnap(snap *snap.Info) bool {
 
 // AutoConnectCandidates finds and returns viable auto-connection candidates
 // for a given plug.
-func (r *Repository) AutoConnectCandidates(plugSnapName, plugName string) []*Slot {
+//
+// The policyCheck function decides whether the plug is allowed to
+// auto-connect to a slot of the same interface.
+func (r *Repository) AutoConnectCandidates(plugSnapName, plugName string, policyCheck func(*Plug, *Slot) bool) []*Slot {
 	r.m.Lock()
 	defer r.m.Unlock()
 
@@ -741,44 +820,15 @@ func (r *Repository) AutoConnectCandidates(plugSnapName, plugName string) []*Slo
 	var candidates []*Slot
 	for _, slotsForSnap := range r.slots {
 		for _, slot := range slotsForSnap {
-			if r.isAutoConnectCandidate(plug, slot) {
+			if slot.Interface != plug.Interface {
+				continue
+			}
+			// FIXME: remove once the snap-declaration of the
+			// snap provides this
+			if isLivePatchSnap(plug.Snap) || policyCheck(plug, slot) {
 				candidates = append(candidates, slot)
 			}
 		}
 	}
 	return candidates
 }
-
-// isAutoConnectCandidate returns true if the plug is a candidate to
-// automatically connect to the given slot.
-func (r *Repository) isAutoConnectCandidate(plug *Plug, slot *Slot) bool {
-	if slot.Interface != plug.Interface {
-		return false
-	}
-
-	// FIXME: remove once we have assertions that provide this feature
-	if isLivePatchSnap(plug.Snap) {
-		return true
-	}
-
-	if !r.ifaces[plug.Interface].AutoConnect() {
-		return false
-	}
-
-	// content sharing auto connect candidates
-	if slot.Interface == "content" {
-		if slot.Attrs["content"] == plug.Attrs["content"] && slot.Snap.Developer == plug.Snap.Developer {
-			return true
-		}
-		// we need to stop here to avoid the OS snap autoconnecting
-		// any content later
-		return false
-	}
-
-	// OS snap auto connect candidates
-	if slot.Snap.Type == snap.TypeOS {
-		return true
-	}
-
-	return false
-}
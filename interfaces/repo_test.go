@@ -185,6 +185,22 @@ func (s *RepositorySuite) TestAddPlugFailsWithInvalidSnapName(c *C) {
 	c.Assert(s.testRepo.AllPlugs(""), HasLen, 0)
 }
 
+func (s *RepositorySuite) TestAddPlugInstance(c *C) {
+	plug := &Plug{
+		PlugInfo: &snap.PlugInfo{
+			Snap:      &snap.Info{SuggestedName: "consumer", InstanceKey: "test"},
+			Name:      "plug",
+			Interface: "interface",
+		},
+	}
+	err := s.testRepo.AddPlug(s.plug)
+	c.Assert(err, IsNil)
+	err = s.testRepo.AddPlug(plug)
+	c.Assert(err, IsNil)
+	c.Assert(s.testRepo.AllPlugs(""), HasLen, 2)
+	c.Assert(s.testRepo.Plug("consumer_test", "plug"), DeepEquals, plug)
+}
+
 func (s *RepositorySuite) TestAddPlugFailsWithInvalidPlugName(c *C) {
 	plug := &Plug{
 		PlugInfo: &snap.PlugInfo{
@@ -810,6 +826,42 @@ func (s *RepositorySuite) TestSlotSnippetsForSnapSuccess(c *C) {
 	})
 }
 
+func (s *RepositorySuite) TestSecuritySnippetsForSnapWithDynamicAttrs(c *C) {
+	iface := &TestInterface{
+		InterfaceName: "interface",
+		PlugSnippetCallback: func(plug *Plug, slot *Slot, securitySystem SecuritySystem) ([]byte, error) {
+			return []byte(fmt.Sprintf("plug %v %v, slot %v %v", plug.Attrs["attr"], plug.Attrs["dyn"], slot.Attrs["attr"], slot.Attrs["dyn"])), nil
+		},
+	}
+	repo := s.emptyRepo
+	c.Assert(repo.AddInterface(iface), IsNil)
+	c.Assert(repo.AddPlug(s.plug), IsNil)
+	c.Assert(repo.AddSlot(s.slot), IsNil)
+
+	err := repo.SetDynamicAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name, nil, nil)
+	c.Assert(err, ErrorMatches, `cannot set dynamic attributes of plug "plug" from snap "consumer" and slot "slot" from snap "producer", they are not connected`)
+
+	c.Assert(repo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name), IsNil)
+	// the attributes declared in snap.yaml take precedence
+	err = repo.SetDynamicAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name,
+		map[string]interface{}{"attr": "other", "dyn": "plug-dyn"},
+		map[string]interface{}{"dyn": "slot-dyn"})
+	c.Assert(err, IsNil)
+	snippets, err := repo.SecuritySnippetsForSnap(s.plug.Snap.Name(), testSecurity)
+	c.Assert(err, IsNil)
+	c.Check(snippets["snap.consumer.app"], DeepEquals, [][]byte{[]byte("plug value plug-dyn, slot value slot-dyn")})
+	// the plug and slot themselves are left alone
+	c.Check(s.plug.Attrs, DeepEquals, map[string]interface{}{"attr": "value"})
+	c.Check(s.slot.Attrs, DeepEquals, map[string]interface{}{"attr": "value"})
+
+	// disconnecting forgets about the dynamic attributes
+	c.Assert(repo.Disconnect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name), IsNil)
+	c.Assert(repo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name), IsNil)
+	snippets, err = repo.SecuritySnippetsForSnap(s.plug.Snap.Name(), testSecurity)
+	c.Assert(err, IsNil)
+	c.Check(snippets["snap.consumer.app"], DeepEquals, [][]byte{[]byte("plug value <nil>, slot value <nil>")})
+}
+
 func (s *RepositorySuite) TestOrphanInterfaces(c *C) {
 	repo := s.emptyRepo
 	snaps := addPlugsSlots(c, s.testRepo, `
@@ -946,7 +998,7 @@ slots:
 
 	// Sanity check, our test is valid because plug "auto" is a candidate
 	// for auto-connection
-	c.Assert(repo.AutoConnectCandidates("consumer", "auto"), HasLen, 1)
+	c.Assert(repo.AutoConnectCandidates("consumer", "auto", func(*Plug, *Slot) bool { return true }), HasLen, 1)
 
 	// Without any connections in place, the plug "auto" is blacklisted
 	// because in normal circumstances it would be auto-connected.
@@ -1171,64 +1223,43 @@ func (s *DisconnectSnapSuite) TestCrossConnection(c *C) {
 	}
 }
 
-// internal helper that creates a new repository with two snaps, one
-// is a content plug and one a content slot
-func makeContentConnectionTestSnaps(c *C, plugContentToken, slotContentToken string) (*Repository, *snap.Info, *snap.Info) {
-	repo := NewRepository()
-	err := repo.AddInterface(&TestInterface{InterfaceName: "content", AutoConnectFlag: true})
+func (s *RepositorySuite) TestAutoConnectCandidatesPolicyCheck(c *C) {
+	repo := s.emptyRepo
+	c.Assert(repo.AddInterface(&TestInterface{InterfaceName: "iface"}), IsNil)
+	c.Assert(repo.AddInterface(&TestInterface{InterfaceName: "other"}), IsNil)
 
-	plugSnap := snaptest.MockInfo(c, fmt.Sprintf(`
-name: content-plug-snap
+	consumer := snaptest.MockInfo(c, `
+name: consumer
 plugs:
-  import-content:
-    interface: content
-    content: %s
-`, plugContentToken), nil)
-	slotSnap := snaptest.MockInfo(c, fmt.Sprintf(`
-name: content-slot-snap
+    iface:
+`, nil)
+	producer1 := snaptest.MockInfo(c, `
+name: producer1
 slots:
-  exported-content:
-    interface: content
-    content: %s
-`, slotContentToken), nil)
-
-	err = repo.AddSnap(plugSnap)
-	c.Assert(err, IsNil)
-	err = repo.AddSnap(slotSnap)
-	c.Assert(err, IsNil)
-
-	return repo, plugSnap, slotSnap
-}
-
-func (s *RepositorySuite) TestAutoConnectContentInterfaceSimple(c *C) {
-	repo, _, _ := makeContentConnectionTestSnaps(c, "mylib", "mylib")
-	candidateSlots := repo.AutoConnectCandidates("content-plug-snap", "import-content")
-	c.Check(candidateSlots, HasLen, 1)
-	c.Check(candidateSlots[0].Name, Equals, "exported-content")
-}
-
-func (s *RepositorySuite) TestAutoConnectContentInterfaceOSWorksCorrectly(c *C) {
-	repo, _, slotSnap := makeContentConnectionTestSnaps(c, "mylib", "otherlib")
-	slotSnap.Type = snap.TypeOS
-
-	candidateSlots := repo.AutoConnectCandidates("content-plug-snap", "import-content")
-	c.Check(candidateSlots, HasLen, 0)
-}
-
-func (s *RepositorySuite) TestAutoConnectContentInterfaceNoMatchingContent(c *C) {
-	repo, _, _ := makeContentConnectionTestSnaps(c, "mylib", "otherlib")
-	candidateSlots := repo.AutoConnectCandidates("content-plug-snap", "import-content")
-	c.Check(candidateSlots, HasLen, 0)
-}
+    iface:
+    other:
+`, nil)
+	producer2 := snaptest.MockInfo(c, `
+name: producer2
+slots:
+    iface:
+`, nil)
+	for _, info := range []*snap.Info{consumer, producer1, producer2} {
+		c.Assert(repo.AddSnap(info), IsNil)
+	}
 
-func (s *RepositorySuite) TestAutoConnectContentInterfaceNoMatchingDeveloper(c *C) {
-	repo, plugSnap, slotSnap := makeContentConnectionTestSnaps(c, "mylib", "mylib")
-	// this comes via SideInfo
-	plugSnap.Developer = "foo"
-	slotSnap.Developer = "bar"
+	var checked []string
+	candidateSlots := repo.AutoConnectCandidates("consumer", "iface", func(plug *Plug, slot *Slot) bool {
+		c.Check(plug.Snap.Name(), Equals, "consumer")
+		c.Check(slot.Interface, Equals, "iface")
+		checked = append(checked, slot.Snap.Name())
+		return slot.Snap.Name() == "producer2"
+	})
+	c.Check(checked, HasLen, 2)
+	c.Assert(candidateSlots, HasLen, 1)
+	c.Check(candidateSlots[0].Snap.Name(), Equals, "producer2")
 
-	candidateSlots := repo.AutoConnectCandidates("content-plug-snap", "import-content")
-	c.Check(candidateSlots, HasLen, 0)
+	c.Check(repo.AutoConnectCandidates("consumer", "missing", func(*Plug, *Slot) bool { return true }), HasLen, 0)
 }
 
 func makeLivepatchConnectionTestSnaps(c *C, name, developer string) (*Repository, *snap.Info, *snap.Info) {
@@ -1269,10 +1300,16 @@ slots:
 	return repo, plugSnap, slotSnap
 }
 
+// livepatchTestPolicy stands in for the declarations' rules, which
+// only allow auto-connecting the non-restricted interface.
+func livepatchTestPolicy(plug *Plug, slot *Slot) bool {
+	return plug.Interface == "non-restricted"
+}
+
 // test auto-connecting livepatch interfaces for special snaps
 func (s *RepositorySuite) TestAutoConnectLivepatchInterfaces(c *C) {
 	repo, _, _ := makeLivepatchConnectionTestSnaps(c, "canonical-livepatch", "canonical")
-	candidateSlots := repo.AutoConnectCandidates("canonical-livepatch", "restricted")
+	candidateSlots := repo.AutoConnectCandidates("canonical-livepatch", "restricted", livepatchTestPolicy)
 	c.Check(candidateSlots, HasLen, 1)
 	c.Check(candidateSlots[0].Snap.Name(), Equals, "ubuntu-core")
 	c.Check(candidateSlots[0].Snap.DeveloperID, Equals, "canonical")
@@ -1282,7 +1319,7 @@ func (s *RepositorySuite) TestAutoConnectLivepatchInterfaces(c *C) {
 // test auto-connecting unrestricted (auto-connect) interfaces for special snaps
 func (s *RepositorySuite) TestAutoConnectNonRestrictedInterfaces(c *C) {
 	repo, _, _ := makeLivepatchConnectionTestSnaps(c, "canonical-livepatch", "canonical")
-	candidateSlots := repo.AutoConnectCandidates("canonical-livepatch", "non-restricted")
+	candidateSlots := repo.AutoConnectCandidates("canonical-livepatch", "non-restricted", livepatchTestPolicy)
 	c.Check(candidateSlots, HasLen, 1)
 	c.Check(candidateSlots[0].Snap.Name(), Equals, "ubuntu-core")
 	c.Check(candidateSlots[0].Snap.DeveloperID, Equals, "canonical")
@@ -1292,7 +1329,7 @@ func (s *RepositorySuite) TestAutoConnectNonRestrictedInterfaces(c *C) {
 // test auto-connecting unrestricted (auto-connect) interfaces for non-special snaps
 func (s *RepositorySuite) TestAutoConnectNonRestrictedInterfacesNonSpecialSnap2(c *C) {
 	repo, _, _ := makeLivepatchConnectionTestSnaps(c, "canonical-livepatch", "someone-else")
-	candidateSlots := repo.AutoConnectCandidates("canonical-livepatch", "non-restricted")
+	candidateSlots := repo.AutoConnectCandidates("canonical-livepatch", "non-restricted", livepatchTestPolicy)
 	c.Check(candidateSlots, HasLen, 1)
 	c.Check(candidateSlots[0].Snap.Name(), Equals, "ubuntu-core")
 	c.Check(candidateSlots[0].Snap.DeveloperID, Equals, "canonical")
@@ -1301,12 +1338,12 @@ func (s *RepositorySuite) TestAutoConnectNonRestrictedInterfacesNonSpecialSnap2(
 
 func (s *RepositorySuite) TestAutoConnectLivepatchWrongDeveloper(c *C) {
 	repo, _, _ := makeLivepatchConnectionTestSnaps(c, "canonical-livepatch", "somebody")
-	candidateSlots := repo.AutoConnectCandidates("canonical-livepatch", "restricted")
+	candidateSlots := repo.AutoConnectCandidates("canonical-livepatch", "restricted", livepatchTestPolicy)
 	c.Check(candidateSlots, HasLen, 0)
 }
 
 func (s *RepositorySuite) TestAutoConnectLivepatchWrongName(c *C) {
 	repo, _, _ := makeLivepatchConnectionTestSnaps(c, "something", "canonical")
-	candidateSlots := repo.AutoConnectCandidates("canonical-livepatch", "restricted")
+	candidateSlots := repo.AutoConnectCandidates("canonical-livepatch", "restricted", livepatchTestPolicy)
 	c.Check(candidateSlots, HasLen, 0)
 }
@@ -98,21 +98,30 @@ func (b *Backend) combineSnippets(snapInfo *snap.Info, devMode bool, snippets ma
 		if content == nil {
 			content = make(map[string]*osutil.FileState)
 		}
-		addContent(appInfo.SecurityTag(), devMode, snippets, content)
+		addContent(appInfo.SecurityTag(), snapInfo, devMode, snippets, content)
 	}
 
 	for _, hookInfo := range snapInfo.Hooks {
 		if content == nil {
 			content = make(map[string]*osutil.FileState)
 		}
-		addContent(hookInfo.SecurityTag(), devMode, snippets, content)
+		addContent(hookInfo.SecurityTag(), snapInfo, devMode, snippets, content)
 	}
 
 	return content, nil
 }
 
-func addContent(securityTag string, devMode bool, snippets map[string][][]byte, content map[string]*osutil.FileState) {
+func addContent(securityTag string, snapInfo *snap.Info, devMode bool, snippets map[string][][]byte, content map[string]*osutil.FileState) {
 	var buffer bytes.Buffer
+	if snapInfo.NeedsClassic() {
+		// NOTE: This is understood by ubuntu-core-launcher, classic
+		// snaps are not sandboxed so there is nothing to combine
+		content[securityTag] = &osutil.FileState{
+			Content: []byte("@unrestricted\n"),
+			Mode:    0644,
+		}
+		return
+	}
 	if devMode {
 		// NOTE: This is understood by ubuntu-core-launcher
 		buffer.WriteString("@complain\n")
@@ -170,6 +170,27 @@ func (s *backendSuite) TestRealDefaultTemplateIsNormallyUsed(c *C) {
 	}
 }
 
+func (s *backendSuite) TestClassicSnapsAreUnrestricted(c *C) {
+	s.Iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte("snippet"), nil
+	}
+	snapInfo := s.InstallSnap(c, false, `
+name: samba
+version: 1
+confinement: classic
+apps:
+    smbd:
+slots:
+    slot:
+        interface: iface
+`, 0)
+	profile := filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd")
+	data, err := ioutil.ReadFile(profile)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, "@unrestricted\n")
+	s.RemoveSnap(c, snapInfo)
+}
+
 type combineSnippetsScenario struct {
 	devMode bool
 	snippet string
@@ -35,6 +35,7 @@ type Subscriber struct {
 	conn     websocketConnection
 	types    []string
 	resource string
+	change   string
 }
 
 // Subscribers is a collection of subscribers
@@ -46,8 +47,8 @@ type websocketConnection interface {
 }
 
 // NewSubscriber returns a new subscriber containing the given websocket
-// connection and type/resource filters set from the query string params in the
-// supplied http request
+// connection and type/resource/change filters set from the query string params
+// in the supplied http request
 func NewSubscriber(c websocketConnection, r *http.Request) *Subscriber {
 	s := &Subscriber{
 		uuid: strutil.MakeRandomString(16),
@@ -61,6 +62,9 @@ func NewSubscriber(c websocketConnection, r *http.Request) *Subscriber {
 	if len(q["resource"]) > 0 {
 		s.resource = q["resource"][0]
 	}
+	if len(q["change"]) > 0 {
+		s.change = q["change"][0]
+	}
 
 	return s
 }
@@ -81,9 +85,13 @@ func (s *Subscriber) Notify(n *Notification) error {
 }
 
 func (s *Subscriber) canAccept(n *Notification) bool {
-	if s.resource != "" {
-		// notification has full resource path while we have the uuid portion
-		return strings.HasSuffix(n.Resource, s.resource)
+	// notification has full resource path while we have the uuid portion
+	if s.resource != "" && !strings.HasSuffix(n.Resource, s.resource) {
+		return false
+	}
+
+	if s.change != "" && n.Metadata["change-id"] != s.change {
+		return false
 	}
 
 	if len(s.types) > 0 {
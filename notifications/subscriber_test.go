@@ -34,12 +34,14 @@ func (s *SubscriberSuite) TestNewSubscriber(c *C) {
 		path     string
 		types    []string
 		resource string
+		change   string
 	}{
-		{"/events", []string(nil), ""},
-		{"/events?types=logging", []string{"logging"}, ""},
-		{"/events?types=logging,operations", []string{"logging", "operations"}, ""},
-		{"/events?resource=123", []string(nil), "123"},
-		{"/events?types=logging&resource=123", []string{"logging"}, "123"},
+		{"/events", []string(nil), "", ""},
+		{"/events?types=logging", []string{"logging"}, "", ""},
+		{"/events?types=logging,operations", []string{"logging", "operations"}, "", ""},
+		{"/events?resource=123", []string(nil), "123", ""},
+		{"/events?types=logging&resource=123", []string{"logging"}, "123", ""},
+		{"/events?change=42", []string(nil), "", "42"},
 	}
 
 	for _, tt := range tests {
@@ -52,5 +54,34 @@ func (s *SubscriberSuite) TestNewSubscriber(c *C) {
 		c.Assert(sub.conn, DeepEquals, conn)
 		c.Assert(sub.types, DeepEquals, tt.types)
 		c.Assert(sub.resource, DeepEquals, tt.resource)
+		c.Assert(sub.change, Equals, tt.change)
+	}
+}
+
+func (s *SubscriberSuite) TestCanAccept(c *C) {
+	n := &Notification{
+		Type:     "task-update",
+		Resource: "/v2/changes/42",
+		Metadata: map[string]interface{}{"change-id": "42"},
+	}
+
+	tests := []struct {
+		sub    *Subscriber
+		accept bool
+	}{
+		{&Subscriber{}, true},
+		{&Subscriber{types: []string{"change-update", "task-update"}}, true},
+		{&Subscriber{types: []string{"change-update"}}, false},
+		{&Subscriber{resource: "42"}, true},
+		{&Subscriber{resource: "43"}, false},
+		{&Subscriber{change: "42"}, true},
+		{&Subscriber{change: "4"}, false},
+		{&Subscriber{change: "42", types: []string{"task-update"}}, true},
+		{&Subscriber{change: "42", types: []string{"snap"}}, false},
+		{&Subscriber{resource: "42", types: []string{"snap"}}, false},
+	}
+
+	for _, t := range tests {
+		c.Check(t.sub.canAccept(n), Equals, t.accept, Commentf("%#v", t.sub))
 	}
 }
@@ -94,7 +94,7 @@ func (sto *fakeStore) ListRefresh([]*store.RefreshCandidate, *auth.UserState) ([
 	panic("fakeStore.ListRefresh not expected")
 }
 
-func (sto *fakeStore) Download(string, *snap.DownloadInfo, progress.Meter, *auth.UserState) (string, error) {
+func (sto *fakeStore) Download(string, string, *snap.DownloadInfo, progress.Meter, *auth.UserState) error {
 	panic("fakeStore.Download not expected")
 }
 
@@ -772,3 +772,50 @@ func (s *assertMgrSuite) TestValidateRefreshesRevokedValidation(c *C) {
 	c.Assert(err, ErrorMatches, `(?s).*cannot refresh "foo" to revision 9: validation by "baz" \(id "baz-id"\) revoked.*`)
 	c.Check(validated, HasLen, 0)
 }
+
+func (s *assertMgrSuite) TestSnapDeclaration(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// not in the db yet
+	_, err := assertstate.SnapDeclaration(s.state, "foo-id")
+	c.Check(err, Equals, asserts.ErrNotFound)
+
+	snapDeclFoo := s.snapDecl(c, "foo", nil)
+
+	err = assertstate.Add(s.state, s.storeSigning.StoreAccountKey(""))
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, s.dev1Acct)
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, snapDeclFoo)
+	c.Assert(err, IsNil)
+
+	snapDecl, err := assertstate.SnapDeclaration(s.state, "foo-id")
+	c.Assert(err, IsNil)
+	c.Check(snapDecl.SnapName(), Equals, "foo")
+}
+
+func (s *assertMgrSuite) TestBaseDeclaration(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// not initialized without the builtin interfaces
+	_, err := assertstate.BaseDeclaration(s.state)
+	c.Check(err, Equals, asserts.ErrNotFound)
+
+	err = asserts.InitBuiltinBaseDeclaration([]byte(`
+type: base-declaration
+authority-id: canonical
+series: 16
+plugs:
+  iface:
+    deny-auto-connection: true
+`))
+	c.Assert(err, IsNil)
+	defer asserts.InitBuiltinBaseDeclaration(nil)
+
+	baseDecl, err := assertstate.BaseDeclaration(s.state)
+	c.Assert(err, IsNil)
+	c.Check(baseDecl, NotNil)
+	c.Check(baseDecl.PlugRule("iface"), NotNil)
+}
@@ -20,6 +20,7 @@
 package overlord
 
 import (
+	"os"
 	"time"
 
 	"github.com/snapcore/snapd/osutil"
@@ -27,15 +28,48 @@ import (
 )
 
 type overlordStateBackend struct {
-	path           string
+	path        string
+	journalPath string
+	// journal, if set, has checkpoints journaled instead of rewriting
+	// the whole state file every time
+	journal        *state.Journal
 	ensureBefore   func(d time.Duration)
 	requestRestart func(t state.RestartType)
 }
 
 func (osb *overlordStateBackend) Checkpoint(data []byte) error {
+	if osb.journal != nil {
+		return osb.journal.Checkpoint(data)
+	}
 	return osutil.AtomicWriteFile(osb.path, data, 0600, 0)
 }
 
+// Recover recovers the state from the base state and the journal. When
+// not journaling (anymore) any journal left behind is folded into the
+// state file right away.
+func (osb *overlordStateBackend) Recover(base []byte) ([]byte, error) {
+	if osb.journal != nil {
+		return osb.journal.Recover(base)
+	}
+	if !osutil.FileExists(osb.journalPath) {
+		return base, nil
+	}
+
+	j := state.NewJournal(osb.path, osb.journalPath)
+	data, err := j.Recover(base)
+	j.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := osutil.AtomicWriteFile(osb.path, data, 0600, 0); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(osb.journalPath); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
 func (osb *overlordStateBackend) EnsureBefore(d time.Duration) {
 	osb.ensureBefore(d)
 }
@@ -62,7 +62,7 @@ func (bs *bootedSuite) SetUpTest(c *C) {
 	bs.bootloader.BootVars["snap_kernel"] = "canonical-pc-linux_2.snap"
 	partition.ForceBootloader(bs.bootloader)
 
-	ovld, err := overlord.New()
+	ovld, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 	bs.overlord = ovld
 }
@@ -52,7 +52,7 @@ func populateStateFromSeed() error {
 		return fmt.Errorf("cannot create state: state %q already exists", dirs.SnapStateFile)
 	}
 
-	ovld, err := overlord.New()
+	ovld, err := overlord.New(nil)
 	if err != nil {
 		return err
 	}
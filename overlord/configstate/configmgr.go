@@ -25,6 +25,7 @@ import (
 	"regexp"
 
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
@@ -42,5 +43,11 @@ func Manager(s *state.State, hookManager *hookstate.HookManager) (*ConfigManager
 
 	hookManager.Register(regexp.MustCompile("^configure$"), newApplyConfigHandler)
 
+	snapstate.CoreConfig = coreConfig
+
 	return manager, nil
 }
+
+func coreConfig(st *state.State, key string, result interface{}) error {
+	return NewTransaction(st).GetMaybe("core", key, result)
+}
@@ -0,0 +1,67 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configstate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type configManagerSuite struct {
+	state *state.State
+}
+
+var _ = Suite(&configManagerSuite{})
+
+func (s *configManagerSuite) SetUpTest(c *C) {
+	s.state = state.New(nil)
+
+	hookMgr, err := hookstate.Manager(s.state)
+	c.Assert(err, IsNil)
+	_, err = configstate.Manager(s.state, hookMgr)
+	c.Assert(err, IsNil)
+}
+
+func (s *configManagerSuite) TearDownTest(c *C) {
+	snapstate.CoreConfig = nil
+}
+
+func (s *configManagerSuite) TestCoreConfig(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// unset options leave the result alone
+	schedule := "unset"
+	err := snapstate.CoreConfig(s.state, "refresh.schedule", &schedule)
+	c.Assert(err, IsNil)
+	c.Check(schedule, Equals, "unset")
+
+	tr := configstate.NewTransaction(s.state)
+	tr.Set("core", "refresh.schedule", "mon,10:00-12:00")
+	tr.Commit()
+
+	err = snapstate.CoreConfig(s.state, "refresh.schedule", &schedule)
+	c.Assert(err, IsNil)
+	c.Check(schedule, Equals, "mon,10:00-12:00")
+}
@@ -24,17 +24,25 @@ import (
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
-	"github.com/snapcore/snapd/snap"
 )
 
 // Change returns a taskset required to apply the given configuration
-// patch.
-func Change(s *state.State, snapName string, patchValues map[string]interface{}) *state.TaskSet {
+// patch. It fails if a change in progress is operating on the snap.
+func Change(s *state.State, snapName string, patchValues map[string]interface{}) (*state.TaskSet, error) {
+	if err := snapstate.CheckChangeConflict(s, snapName, nil); err != nil {
+		return nil, err
+	}
+
 	initialContext := map[string]interface{}{
 		"patch": patchValues,
 	}
 	hookTaskSummary := fmt.Sprintf(i18n.G("Run configure hook for %s"), snapName)
-	task := hookstate.HookTask(s, hookTaskSummary, snapName, snap.Revision{}, "configure", initialContext)
-	return state.NewTaskSet(task)
+	setup := &hookstate.HookSetup{
+		Snap: snapName,
+		Hook: "configure",
+	}
+	task := hookstate.HookTask(s, hookTaskSummary, setup, initialContext)
+	return state.NewTaskSet(task), nil
 }
@@ -24,6 +24,7 @@ import (
 
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
@@ -40,10 +41,11 @@ func (s *tasksetsSuite) SetUpTest(c *C) {
 
 func (s *tasksetsSuite) TestChange(c *C) {
 	s.state.Lock()
-	taskset := configstate.Change(s.state, "test-snap", map[string]interface{}{
+	taskset, err := configstate.Change(s.state, "test-snap", map[string]interface{}{
 		"foo": "bar",
 	})
 	s.state.Unlock()
+	c.Assert(err, IsNil)
 
 	tasks := taskset.Tasks()
 	c.Assert(tasks, HasLen, 1)
@@ -54,7 +56,7 @@ func (s *tasksetsSuite) TestChange(c *C) {
 	// Check that the Context is initialized as we expect
 	var setup hookstate.HookSetup
 	s.state.Lock()
-	err := task.Get("hook-setup", &setup)
+	err = task.Get("hook-setup", &setup)
 	s.state.Unlock()
 	c.Check(err, IsNil)
 
@@ -74,3 +76,30 @@ func (s *tasksetsSuite) TestChange(c *C) {
 		"foo": "bar",
 	})
 }
+
+func (s *tasksetsSuite) TestChangeConflict(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("install", "...")
+	t := s.state.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "test-snap"},
+	})
+	chg.AddTask(t)
+
+	_, err := configstate.Change(s.state, "test-snap", map[string]interface{}{
+		"foo": "bar",
+	})
+	c.Check(err, DeepEquals, &snapstate.ChangeConflictError{
+		Snap:       "test-snap",
+		ChangeKind: "install",
+		ChangeID:   chg.ID(),
+	})
+
+	chg.SetStatus(state.DoneStatus)
+	_, err = configstate.Change(s.state, "test-snap", map[string]interface{}{
+		"foo": "bar",
+	})
+	c.Check(err, IsNil)
+}
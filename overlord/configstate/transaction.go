@@ -88,6 +88,24 @@ func (t *Transaction) Set(snapName, key string, value interface{}) error {
 	return nil
 }
 
+// Unset removes the provided snap's configuration key.
+//
+// Changes are not persisted until Commit is called.
+func (t *Transaction) Unset(snapName, key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	config, ok := t.changes[snapName]
+	if !ok {
+		config = make(snapConfig)
+	}
+
+	// a nil value marks the key as removed
+	config[key] = nil
+
+	t.changes[snapName] = config
+}
+
 // Get unmarshals into result the cached value of the provided snap's configuration key.
 // If the key does not exist, an error of type *NoOptionError is returned.
 //
@@ -95,6 +113,10 @@ func (t *Transaction) Set(snapName, key string, value interface{}) error {
 func (t *Transaction) Get(snapName, key string, result interface{}) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if raw, ok := t.changes[snapName][key]; ok && raw == nil {
+		// unset in this transaction
+		return &NoOptionError{SnapName: snapName, Key: key}
+	}
 	err := t.get(t.changes, snapName, key, result)
 	if IsNoOption(err) {
 		err = t.get(t.pristine, snapName, key, result)
@@ -141,6 +163,10 @@ func (t *Transaction) Commit() {
 		}
 
 		for key, value := range snapChanges {
+			if value == nil {
+				delete(newConfig, key)
+				continue
+			}
 			newConfig[key] = value
 		}
 
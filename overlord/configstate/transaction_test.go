@@ -71,6 +71,32 @@ func (s *transactionSuite) TestCommit(c *C) {
 	c.Check(value, Equals, "bar")
 }
 
+func (s *transactionSuite) TestUnset(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.transaction.Set("test-snap", "foo", "bar"), IsNil)
+	c.Check(s.transaction.Set("test-snap", "baz", "qux"), IsNil)
+	s.transaction.Commit()
+
+	transaction := configstate.NewTransaction(s.state)
+	transaction.Unset("test-snap", "foo")
+
+	// the key is gone for the transaction only
+	var value string
+	err := transaction.Get("test-snap", "foo", &value)
+	c.Check(configstate.IsNoOption(err), Equals, true)
+	err = configstate.NewTransaction(s.state).Get("test-snap", "foo", &value)
+	c.Check(err, IsNil)
+
+	// and for everybody after the commit
+	transaction.Commit()
+	transaction = configstate.NewTransaction(s.state)
+	err = transaction.Get("test-snap", "foo", &value)
+	c.Check(configstate.IsNoOption(err), Equals, true)
+	c.Check(transaction.Get("test-snap", "baz", &value), IsNil)
+	c.Check(value, Equals, "qux")
+}
+
 func (s *transactionSuite) TestCommitOnlyCommitsChanges(c *C) {
 	// Set the initial config
 	s.state.Lock()
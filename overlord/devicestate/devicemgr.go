@@ -67,9 +67,25 @@ func Manager(s *state.State) (*DeviceManager, error) {
 	runner.AddHandler("generate-device-key", m.doGenerateDeviceKey, nil)
 	runner.AddHandler("request-serial", m.doRequestSerial, nil)
 
+	snapstate.CanAutoRefresh = canAutoRefresh
+
 	return m, nil
 }
 
+// canAutoRefresh returns whether the device is ready to auto-refresh
+// snaps, that is it is either classic or already has a serial.
+func canAutoRefresh(st *state.State) (bool, error) {
+	if release.OnClassic {
+		return true, nil
+	}
+
+	device, err := auth.Device(st)
+	if err != nil {
+		return false, err
+	}
+	return device.Serial != "", nil
+}
+
 func (m *DeviceManager) ensureOperational() error {
 	m.state.Lock()
 	defer m.state.Unlock()
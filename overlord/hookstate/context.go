@@ -41,6 +41,11 @@ type Context struct {
 	cache  map[interface{}]interface{}
 	onDone []func() error
 
+	// ephemeral contexts have no task, they keep the state and the
+	// values set in them here
+	ephemeralState *state.State
+	ephemeralData  map[string]*json.RawMessage
+
 	mutex        sync.Mutex
 	mutexChecker int32
 }
@@ -63,6 +68,24 @@ func NewContext(task *state.Task, setup *HookSetup, handler Handler) (*Context,
 	}, nil
 }
 
+// newEphemeralContext returns a Context acting on behalf of the given
+// snap outside of a hook, it is not backed by a task and lasts only as
+// long as a single snapctl invocation.
+func newEphemeralContext(st *state.State, snapName string) *Context {
+	return &Context{
+		setup:          &HookSetup{Snap: snapName},
+		cache:          make(map[interface{}]interface{}),
+		ephemeralState: st,
+		ephemeralData:  make(map[string]*json.RawMessage),
+	}
+}
+
+// IsEphemeral returns whether the context is not tied to a hook (and
+// its task), as it is the case when snapctl is used by a snap's apps.
+func (c *Context) IsEphemeral() bool {
+	return c.task == nil
+}
+
 // SnapName returns the name of the snap containing the hook.
 func (c *Context) SnapName() string {
 	return c.setup.Snap
@@ -83,6 +106,11 @@ func (c *Context) ID() string {
 	return c.id
 }
 
+// Task returns the task the hook is run by, nil for ephemeral contexts.
+func (c *Context) Task() *state.Task {
+	return c.task
+}
+
 // Handler returns the handler for this context
 func (c *Context) Handler() Handler {
 	return c.handler
@@ -92,14 +120,14 @@ func (c *Context) Handler() Handler {
 // and OnDone/Done).
 func (c *Context) Lock() {
 	c.mutex.Lock()
-	c.task.State().Lock()
+	c.State().Lock()
 	atomic.AddInt32(&c.mutexChecker, 1)
 }
 
 // Unlock releases the lock for this context.
 func (c *Context) Unlock() {
 	atomic.AddInt32(&c.mutexChecker, -1)
-	c.task.State().Unlock()
+	c.State().Unlock()
 	c.mutex.Unlock()
 }
 
@@ -121,16 +149,21 @@ func (c *Context) writing() {
 func (c *Context) Set(key string, value interface{}) {
 	c.writing()
 
-	var data map[string]*json.RawMessage
-	if err := c.task.Get("hook-context", &data); err != nil {
-		data = make(map[string]*json.RawMessage)
-	}
-
 	marshalledValue, err := json.Marshal(value)
 	if err != nil {
 		panic(fmt.Sprintf("internal error: cannot marshal context value for %q: %s", key, err))
 	}
 	raw := json.RawMessage(marshalledValue)
+
+	if c.IsEphemeral() {
+		c.ephemeralData[key] = &raw
+		return
+	}
+
+	var data map[string]*json.RawMessage
+	if err := c.task.Get("hook-context", &data); err != nil {
+		data = make(map[string]*json.RawMessage)
+	}
 	data[key] = &raw
 
 	c.task.Set("hook-context", data)
@@ -142,9 +175,11 @@ func (c *Context) Set(key string, value interface{}) {
 func (c *Context) Get(key string, value interface{}) error {
 	c.reading()
 
-	var data map[string]*json.RawMessage
-	if err := c.task.Get("hook-context", &data); err != nil {
-		return err
+	data := c.ephemeralData
+	if !c.IsEphemeral() {
+		if err := c.task.Get("hook-context", &data); err != nil {
+			return err
+		}
 	}
 
 	raw, ok := data[key]
@@ -162,6 +197,9 @@ func (c *Context) Get(key string, value interface{}) error {
 
 // State returns the state contained within the context
 func (c *Context) State() *state.State {
+	if c.IsEphemeral() {
+		return c.ephemeralState
+	}
 	return c.task.State()
 }
 
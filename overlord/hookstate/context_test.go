@@ -133,3 +133,24 @@ func (s *contextSuite) TestDone(c *C) {
 	s.context.Done()
 	c.Check(called, Equals, true, Commentf("Expected finalizer to be called"))
 }
+
+func (s *contextSuite) TestEphemeralContext(c *C) {
+	c.Check(s.context.IsEphemeral(), Equals, false)
+
+	st := s.task.State()
+	context := newEphemeralContext(st, "test-snap")
+	c.Check(context.IsEphemeral(), Equals, true)
+	c.Check(context.SnapName(), Equals, "test-snap")
+	c.Check(context.HookName(), Equals, "")
+	c.Check(context.Task(), IsNil)
+	c.Check(context.State(), Equals, st)
+
+	context.Lock()
+	defer context.Unlock()
+
+	var output string
+	c.Check(context.Get("foo", &output), Equals, state.ErrNoState)
+	context.Set("foo", "bar")
+	c.Check(context.Get("foo", &output), IsNil)
+	c.Check(output, Equals, "bar")
+}
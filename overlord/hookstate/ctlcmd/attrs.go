@@ -0,0 +1,196 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var interfaceHookRegexp = regexp.MustCompile("^(prepare|connect)-(plug|slot)-([-a-z0-9]+)$")
+
+// interfaceHook describes the plug or slot an interface hook is run for.
+type interfaceHook struct {
+	prepare bool
+	// side is either "plug" or "slot"
+	side string
+	name string
+}
+
+func (h *interfaceHook) otherSide() string {
+	if h.side == "plug" {
+		return "slot"
+	}
+	return "plug"
+}
+
+// parseInterfaceHook returns the plug or slot the hook is for, or nil if
+// it isn't an interface hook.
+func parseInterfaceHook(hookName string) *interfaceHook {
+	m := interfaceHookRegexp.FindStringSubmatch(hookName)
+	if m == nil {
+		return nil
+	}
+	return &interfaceHook{
+		prepare: m[1] == "prepare",
+		side:    m[2],
+		name:    m[3],
+	}
+}
+
+// checkInterfaceHook checks that the context is the one of an interface
+// hook run for the given plug or slot name, as passed to snapctl
+// with a leading colon.
+func checkInterfaceHook(context *hookstate.Context, plugOrSlot string) (*interfaceHook, error) {
+	hook := parseInterfaceHook(context.HookName())
+	if hook == nil {
+		return nil, fmt.Errorf(i18n.G("interface attributes can only be read or set during the execution of interface hooks"))
+	}
+	if plugOrSlot != ":"+hook.name {
+		return nil, fmt.Errorf(i18n.G("unknown plug or slot %q, the hook is for %s %q"), plugOrSlot[1:], hook.side, hook.name)
+	}
+	return hook, nil
+}
+
+// attributesTask returns the connect task that holds the attributes
+// of the connection the hook is run for.
+func attributesTask(context *hookstate.Context) (*state.Task, error) {
+	var id string
+	if err := context.Get("attrs-task", &id); err != nil {
+		return nil, fmt.Errorf("internal error: cannot find the attributes of the connection: %v", err)
+	}
+	task := context.State().Task(id)
+	if task == nil {
+		return nil, fmt.Errorf("internal error: cannot find the attributes of the connection in task %s", id)
+	}
+	return task, nil
+}
+
+// staticAttrs returns the attributes of the given side of the
+// connection as declared in snap.yaml.
+func staticAttrs(st *state.State, attrsTask *state.Task, side string) (map[string]interface{}, error) {
+	var snapName, name string
+	switch side {
+	case "plug":
+		var plugRef interfaces.PlugRef
+		if err := attrsTask.Get("plug", &plugRef); err != nil {
+			return nil, err
+		}
+		snapName, name = plugRef.Snap, plugRef.Name
+	case "slot":
+		var slotRef interfaces.SlotRef
+		if err := attrsTask.Get("slot", &slotRef); err != nil {
+			return nil, err
+		}
+		snapName, name = slotRef.Snap, slotRef.Name
+	}
+
+	info, err := snapstate.CurrentInfo(st, snapName)
+	if err != nil {
+		return nil, err
+	}
+	switch side {
+	case "plug":
+		if plug, ok := info.Plugs[name]; ok {
+			return plug.Attrs, nil
+		}
+	case "slot":
+		if slot, ok := info.Slots[name]; ok {
+			return slot.Attrs, nil
+		}
+	}
+	return nil, fmt.Errorf("snap %q has no %s named %q", snapName, side, name)
+}
+
+// dynamicAttrs returns the attributes of the given side of the
+// connection set so far by the prepare hooks.
+func dynamicAttrs(attrsTask *state.Task, side string) (map[string]interface{}, error) {
+	var attrs map[string]interface{}
+	err := attrsTask.Get(side+"-dynamic", &attrs)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if attrs == nil {
+		attrs = make(map[string]interface{})
+	}
+	return attrs, nil
+}
+
+// cachedDynamicAttrs is the index into the context cache where the
+// dynamic attributes set by a prepare hook are kept until it exits.
+type cachedDynamicAttrs struct {
+	side string
+}
+
+// contextDynamicAttrs returns the dynamic attributes of the given side
+// of the connection as seen by the hook, including those it set itself
+// so far.
+func contextDynamicAttrs(context *hookstate.Context, attrsTask *state.Task, side string) (map[string]interface{}, error) {
+	if attrs, ok := context.Cached(cachedDynamicAttrs{side}).(map[string]interface{}); ok {
+		return attrs, nil
+	}
+	return dynamicAttrs(attrsTask, side)
+}
+
+// setContextDynamicAttrs buffers the dynamic attributes of the given
+// side of the connection in the context; they are stored in the
+// connect task only once the hook exits successfully.
+func setContextDynamicAttrs(context *hookstate.Context, attrsTask *state.Task, side string, attrs map[string]interface{}) {
+	key := cachedDynamicAttrs{side}
+	if context.Cached(key) == nil {
+		context.OnDone(func() error {
+			attrsTask.Set(side+"-dynamic", context.Cached(key))
+			return nil
+		})
+	}
+	context.Cache(key, attrs)
+}
+
+// normalizeAttr turns the maps decoded from snap.yaml into maps keyed by
+// strings, so that they can be printed as JSON.
+func normalizeAttr(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			m[fmt.Sprint(k)] = normalizeAttr(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			m[k] = normalizeAttr(item)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(x))
+		for i, item := range x {
+			l[i] = normalizeAttr(item)
+		}
+		return l
+	}
+	return v
+}
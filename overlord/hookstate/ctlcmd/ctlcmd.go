@@ -75,6 +75,15 @@ type command interface {
 	Execute(args []string) error
 }
 
+// UnsuccessfulError carries a specific exit code to be returned to the client.
+type UnsuccessfulError struct {
+	ExitCode int
+}
+
+func (e *UnsuccessfulError) Error() string {
+	return fmt.Sprintf("unsuccessful with exit code: %d", e.ExitCode)
+}
+
 type commandInfo struct {
 	shortHelp string
 	longHelp  string
@@ -22,9 +22,11 @@ package ctlcmd
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
 )
 
 type getCommand struct {
@@ -35,9 +37,12 @@ type getCommand struct {
 	} `positional-args:"yes" required:"yes"`
 
 	Document bool `short:"d" description:"always return document, even with single key"`
+
+	ForcePlugSide bool `long:"plug" description:"return the attribute values of the plug side of the connection"`
+	ForceSlotSide bool `long:"slot" description:"return the attribute values of the slot side of the connection"`
 }
 
-var shortGetHelp = i18n.G("Get snap configuration")
+var shortGetHelp = i18n.G("Get snap configuration or interface attributes")
 var longGetHelp = i18n.G(`
 The get command retrieves the configuration parameters requested, for example:
 
@@ -50,7 +55,19 @@ Or:
     {
         "baz": "qux",
         "foo": "bar"
-    }`)
+    }
+
+During the execution of the interface hooks, the attributes of the plug or
+slot the hook is run for can be retrieved by prefixing its name with a colon:
+
+    $ snapctl get :myplug foo
+    bar
+
+The --slot and --plug options retrieve the attributes of the other side of the
+connection instead, for example from a connect-plug-myplug hook:
+
+    $ snapctl get --slot :myplug path
+    /run/producer/socket`)
 
 func init() {
 	addCommand("get", shortGetHelp, longGetHelp, func() command { return &getCommand{} })
@@ -62,6 +79,18 @@ func (c *getCommand) Execute(args []string) error {
 		return fmt.Errorf("cannot get without a context")
 	}
 
+	if c.ForcePlugSide && c.ForceSlotSide {
+		return fmt.Errorf(i18n.G("cannot use --plug and --slot together"))
+	}
+
+	if strings.HasPrefix(c.Positional.Keys[0], ":") {
+		return c.getInterfaceAttrs(context, c.Positional.Keys[0], c.Positional.Keys[1:])
+	}
+
+	if c.ForcePlugSide || c.ForceSlotSide {
+		return fmt.Errorf(i18n.G("cannot use --plug or --slot without a :<plug|slot> argument"))
+	}
+
 	patch := make(map[string]interface{})
 	context.Lock()
 	transaction := configstate.ContextTransaction(context)
@@ -76,15 +105,68 @@ func (c *getCommand) Execute(args []string) error {
 		patch[key] = value
 	}
 
-	var confToPrint interface{} = patch
-	if !c.Document && len(c.Positional.Keys) == 1 {
-		confToPrint = patch[c.Positional.Keys[0]]
+	return c.printValues(c.Positional.Keys, patch)
+}
+
+func (c *getCommand) getInterfaceAttrs(context *hookstate.Context, plugOrSlot string, keys []string) error {
+	if len(keys) == 0 {
+		return fmt.Errorf(i18n.G("get which attribute of %s?"), plugOrSlot)
+	}
+
+	context.Lock()
+	defer context.Unlock()
+
+	hook, err := checkInterfaceHook(context, plugOrSlot)
+	if err != nil {
+		return err
+	}
+
+	side := hook.side
+	if c.ForcePlugSide {
+		side = "plug"
+	}
+	if c.ForceSlotSide {
+		side = "slot"
+	}
+
+	attrsTask, err := attributesTask(context)
+	if err != nil {
+		return err
+	}
+	static, err := staticAttrs(context.State(), attrsTask, side)
+	if err != nil {
+		return err
+	}
+	dynamic, err := contextDynamicAttrs(context, attrsTask, side)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+	for _, key := range keys {
+		value, ok := dynamic[key]
+		if !ok {
+			value, ok = static[key]
+		}
+		if !ok {
+			return fmt.Errorf(i18n.G("unknown attribute %q of %s"), key, side)
+		}
+		values[key] = normalizeAttr(value)
+	}
+
+	return c.printValues(keys, values)
+}
+
+func (c *getCommand) printValues(keys []string, values map[string]interface{}) error {
+	var toPrint interface{} = values
+	if !c.Document && len(keys) == 1 {
+		toPrint = values[keys[0]]
 	}
 
 	var bytes []byte
-	if confToPrint != nil {
+	if toPrint != nil {
 		var err error
-		bytes, err = json.MarshalIndent(confToPrint, "", "\t")
+		bytes, err = json.MarshalIndent(toPrint, "", "\t")
 		if err != nil {
 			return err
 		}
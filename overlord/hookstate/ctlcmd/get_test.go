@@ -20,12 +20,16 @@
 package ctlcmd_test
 
 import (
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/overlord/configstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
 	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 
 	. "gopkg.in/check.v1"
 )
@@ -100,3 +104,141 @@ func (s *getSuite) TestCommandWithoutContext(c *C) {
 	_, _, err := ctlcmd.Run(nil, []string{"get", "foo"})
 	c.Check(err, ErrorMatches, ".*cannot get without a context.*")
 }
+
+type attrsBaseSuite struct {
+	state       *state.State
+	attrsTask   *state.Task
+	mockHandler *hooktest.MockHandler
+}
+
+const attrsConsumerYaml = `name: consumer
+version: 1
+plugs:
+  plug:
+    interface: content
+    content: mylib
+    target: import
+hooks:
+  prepare-plug-plug:
+  connect-plug-plug:
+`
+
+const attrsProducerYaml = `name: producer
+version: 1
+slots:
+  slot:
+    interface: content
+    content: mylib
+    read:
+      - export
+hooks:
+  prepare-slot-slot:
+  connect-slot-slot:
+`
+
+func (s *attrsBaseSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	s.mockHandler = hooktest.NewMockHandler()
+
+	s.state = state.New(nil)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, yaml := range []string{attrsConsumerYaml, attrsProducerYaml} {
+		si := &snap.SideInfo{Revision: snap.R(1)}
+		info := snaptest.MockSnap(c, yaml, si)
+		si.RealName = info.Name()
+		snapstate.Set(s.state, info.Name(), &snapstate.SnapState{
+			Active:   true,
+			Sequence: []*snap.SideInfo{si},
+			Current:  si.Revision,
+		})
+	}
+
+	s.attrsTask = s.state.NewTask("connect", "")
+	s.attrsTask.Set("plug", interfaces.PlugRef{Snap: "consumer", Name: "plug"})
+	s.attrsTask.Set("slot", interfaces.SlotRef{Snap: "producer", Name: "slot"})
+	s.attrsTask.Set("slot-dynamic", map[string]interface{}{"path": "/run/producer/socket"})
+	chg := s.state.NewChange("connect", "")
+	chg.AddTask(s.attrsTask)
+}
+
+func (s *attrsBaseSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
+
+func (s *attrsBaseSuite) hookContext(c *C, snapName, hookName string) *hookstate.Context {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	task := s.state.NewTask("run-hook", "")
+	task.Set("hook-context", map[string]interface{}{"attrs-task": s.attrsTask.ID()})
+	setup := &hookstate.HookSetup{Snap: snapName, Revision: snap.R(1), Hook: hookName}
+	context, err := hookstate.NewContext(task, setup, s.mockHandler)
+	c.Assert(err, IsNil)
+	return context
+}
+
+type getAttrSuite struct {
+	attrsBaseSuite
+}
+
+var _ = Suite(&getAttrSuite{})
+
+func (s *getAttrSuite) TestGetOwnAttrs(c *C) {
+	context := s.hookContext(c, "consumer", "connect-plug-plug")
+
+	stdout, stderr, err := ctlcmd.Run(context, []string{"get", ":plug", "content"})
+	c.Check(err, IsNil)
+	c.Check(string(stderr), Equals, "")
+	c.Check(string(stdout), Equals, `"mylib"`)
+
+	stdout, _, err = ctlcmd.Run(context, []string{"get", ":plug", "content", "target"})
+	c.Check(err, IsNil)
+	c.Check(string(stdout), Equals, `{
+	"content": "mylib",
+	"target": "import"
+}`)
+}
+
+func (s *getAttrSuite) TestGetOtherSideAttrs(c *C) {
+	context := s.hookContext(c, "consumer", "connect-plug-plug")
+
+	// both the static and the dynamic attributes of the slot
+	stdout, _, err := ctlcmd.Run(context, []string{"get", "--slot", ":plug", "path", "read"})
+	c.Check(err, IsNil)
+	c.Check(string(stdout), Equals, `{
+	"path": "/run/producer/socket",
+	"read": [
+		"export"
+	]
+}`)
+
+	context = s.hookContext(c, "producer", "connect-slot-slot")
+	stdout, _, err = ctlcmd.Run(context, []string{"get", "--plug", ":slot", "target"})
+	c.Check(err, IsNil)
+	c.Check(string(stdout), Equals, `"import"`)
+}
+
+func (s *getAttrSuite) TestGetAttrErrors(c *C) {
+	context := s.hookContext(c, "consumer", "connect-plug-plug")
+
+	_, _, err := ctlcmd.Run(context, []string{"get", ":plug", "unknown"})
+	c.Check(err, ErrorMatches, `unknown attribute "unknown" of plug`)
+
+	_, _, err = ctlcmd.Run(context, []string{"get", ":other", "content"})
+	c.Check(err, ErrorMatches, `unknown plug or slot "other", the hook is for plug "plug"`)
+
+	_, _, err = ctlcmd.Run(context, []string{"get", ":plug"})
+	c.Check(err, ErrorMatches, `get which attribute of :plug\?`)
+
+	_, _, err = ctlcmd.Run(context, []string{"get", "--plug", "--slot", ":plug", "content"})
+	c.Check(err, ErrorMatches, `cannot use --plug and --slot together`)
+
+	_, _, err = ctlcmd.Run(context, []string{"get", "--slot", "content"})
+	c.Check(err, ErrorMatches, `cannot use --plug or --slot without a :<plug\|slot> argument`)
+
+	context = s.hookContext(c, "consumer", "configure")
+	_, _, err = ctlcmd.Run(context, []string{"get", ":plug", "content"})
+	c.Check(err, ErrorMatches, `interface attributes can only be read or set during the execution of interface hooks`)
+}
@@ -0,0 +1,77 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+)
+
+type isConnectedCommand struct {
+	baseCommand
+
+	Positional struct {
+		PlugOrSlotName string `positional-arg-name:"<plug|slot>" required:"1"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+var shortIsConnectedHelp = i18n.G("Return success if the given plug or slot is connected")
+var longIsConnectedHelp = i18n.G(`
+The is-connected command exits with a status of 0 if the given plug or slot of
+the snap is connected, and with a status of 1 otherwise.
+
+    $ snapctl is-connected network && echo "online"`)
+
+func init() {
+	addCommand("is-connected", shortIsConnectedHelp, longIsConnectedHelp, func() command { return &isConnectedCommand{} })
+}
+
+func (c *isConnectedCommand) Execute(args []string) error {
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf("cannot check connection status without a context")
+	}
+
+	snapName := context.SnapName()
+	plugOrSlot := c.Positional.PlugOrSlotName
+
+	context.Lock()
+	defer context.Unlock()
+
+	info, err := snapstate.CurrentInfo(context.State(), snapName)
+	if err != nil {
+		return err
+	}
+	if info.Plugs[plugOrSlot] == nil && info.Slots[plugOrSlot] == nil {
+		return fmt.Errorf(i18n.G("snap %q has no plug or slot named %q"), snapName, plugOrSlot)
+	}
+
+	connected, err := ifacestate.IsConnected(context.State(), snapName, plugOrSlot)
+	if err != nil {
+		return err
+	}
+	if !connected {
+		return &UnsuccessfulError{ExitCode: 1}
+	}
+	return nil
+}
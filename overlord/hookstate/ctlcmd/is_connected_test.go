@@ -0,0 +1,68 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+
+	. "gopkg.in/check.v1"
+)
+
+type isConnectedSuite struct {
+	attrsBaseSuite
+}
+
+var _ = Suite(&isConnectedSuite{})
+
+func (s *isConnectedSuite) TestIsConnected(c *C) {
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "content"},
+	})
+	s.state.Unlock()
+
+	for _, t := range []struct{ snap, plugOrSlot string }{
+		{"consumer", "plug"},
+		{"producer", "slot"},
+	} {
+		context := s.hookContext(c, t.snap, "configure")
+		stdout, stderr, err := ctlcmd.Run(context, []string{"is-connected", t.plugOrSlot})
+		c.Check(err, IsNil)
+		c.Check(string(stdout), Equals, "")
+		c.Check(string(stderr), Equals, "")
+	}
+}
+
+func (s *isConnectedSuite) TestIsNotConnected(c *C) {
+	context := s.hookContext(c, "consumer", "configure")
+	_, _, err := ctlcmd.Run(context, []string{"is-connected", "plug"})
+	c.Check(err, DeepEquals, &ctlcmd.UnsuccessfulError{ExitCode: 1})
+}
+
+func (s *isConnectedSuite) TestUnknownPlugOrSlot(c *C) {
+	context := s.hookContext(c, "consumer", "configure")
+	_, _, err := ctlcmd.Run(context, []string{"is-connected", "slot"})
+	c.Check(err, ErrorMatches, `snap "consumer" has no plug or slot named "slot"`)
+}
+
+func (s *isConnectedSuite) TestWithoutContext(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"is-connected", "plug"})
+	c.Check(err, ErrorMatches, "cannot check connection status without a context")
+}
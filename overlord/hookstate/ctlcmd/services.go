@@ -0,0 +1,260 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
+)
+
+type servicesCommand struct {
+	baseCommand
+	Positional struct {
+		ServiceNames []string `positional-arg-name:"<service>"`
+	} `positional-args:"yes"`
+}
+
+type startCommand struct {
+	baseCommand
+	Positional struct {
+		ServiceNames []string `positional-arg-name:"<service>" required:"yes"`
+	} `positional-args:"yes" required:"yes"`
+	Enable bool `long:"enable"`
+}
+
+type stopCommand struct {
+	baseCommand
+	Positional struct {
+		ServiceNames []string `positional-arg-name:"<service>" required:"yes"`
+	} `positional-args:"yes" required:"yes"`
+	Disable bool `long:"disable"`
+}
+
+type restartCommand struct {
+	baseCommand
+	Positional struct {
+		ServiceNames []string `positional-arg-name:"<service>" required:"yes"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+var (
+	shortServicesHelp = i18n.G("Query the status of services")
+	longServicesHelp  = i18n.G(`
+The services command lists information about the services of the snap,
+or only of the given ones.`)
+
+	shortStartHelp = i18n.G("Start services")
+	longStartHelp  = i18n.G(`
+The start command starts the given services of the snap. If --enable is
+given, the services are also enabled to start on boot.
+
+When run from a hook, the services are started once it has completed.`)
+
+	shortStopHelp = i18n.G("Stop services")
+	longStopHelp  = i18n.G(`
+The stop command stops the given services of the snap. If --disable is
+given, the services are also disabled from starting on boot.
+
+When run from a hook, the services are stopped once it has completed.`)
+
+	shortRestartHelp = i18n.G("Restart services")
+	longRestartHelp  = i18n.G(`
+The restart command restarts the given services of the snap.
+
+When run from a hook, the services are restarted once it has completed.`)
+)
+
+func init() {
+	addCommand("services", shortServicesHelp, longServicesHelp, func() command { return &servicesCommand{} })
+	addCommand("start", shortStartHelp, longStartHelp, func() command { return &startCommand{} })
+	addCommand("stop", shortStopHelp, longStopHelp, func() command { return &stopCommand{} })
+	addCommand("restart", shortRestartHelp, longRestartHelp, func() command { return &restartCommand{} })
+}
+
+type byAppName []*snap.AppInfo
+
+func (a byAppName) Len() int           { return len(a) }
+func (a byAppName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byAppName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+// serviceInfosFor returns the services of the context's snap matching
+// the given names, which can be the snap name (for all of its services)
+// or snap.app names. No names means all the services of the snap.
+func serviceInfosFor(context *hookstate.Context, names []string) ([]*snap.AppInfo, error) {
+	snapName := context.SnapName()
+
+	context.Lock()
+	info, err := snapstate.CurrentInfo(context.State(), snapName)
+	context.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	requested := make(map[string]bool, len(names))
+	for _, name := range names {
+		nameSnap, appName := snap.SplitSnapApp(name)
+		if nameSnap != snapName {
+			return nil, fmt.Errorf(i18n.G("cannot operate on services of snap %q from a hook of snap %q"), nameSnap, snapName)
+		}
+		if name == snapName {
+			// all of them
+			requested = nil
+			break
+		}
+		app := info.Apps[appName]
+		if app == nil || !app.IsService() {
+			return nil, fmt.Errorf(i18n.G("snap %q has no service %q"), snapName, appName)
+		}
+		requested[appName] = true
+	}
+
+	var svcs []*snap.AppInfo
+	for _, app := range info.Apps {
+		if !app.IsService() {
+			continue
+		}
+		if len(requested) > 0 && !requested[app.Name] {
+			continue
+		}
+		svcs = append(svcs, app)
+	}
+	if len(svcs) == 0 {
+		return nil, fmt.Errorf(i18n.G("snap %q has no services"), snapName)
+	}
+	sort.Sort(byAppName(svcs))
+
+	return svcs, nil
+}
+
+func (c *servicesCommand) Execute(args []string) error {
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf("cannot query services without a context")
+	}
+
+	svcs, err := serviceInfosFor(context, c.Positional.ServiceNames)
+	if err != nil {
+		return err
+	}
+
+	sysd := systemd.New(dirs.GlobalRootDir, &progress.NullProgress{})
+
+	w := tabwriter.NewWriter(c.stdout, 5, 3, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Service\tStartup\tCurrent"))
+	for _, svc := range svcs {
+		st, err := sysd.ServiceStatus(filepath.Base(svc.ServiceFile()))
+		if err != nil {
+			return err
+		}
+		startup := i18n.G("disabled")
+		if st.UnitFileState == "enabled" {
+			startup = i18n.G("enabled")
+		}
+		current := i18n.G("inactive")
+		if st.ActiveState == "active" {
+			current = i18n.G("active")
+		}
+		fmt.Fprintf(w, "%s.%s\t%s\t%s\n", svc.Snap.Name(), svc.Name, startup, current)
+	}
+
+	return nil
+}
+
+// queueServiceControl arranges for the services to be acted upon as
+// instructed once the hook and the rest of its change are done, or
+// right away outside of hooks.
+func queueServiceControl(context *hookstate.Context, names []string, inst *servicestate.Instruction) error {
+	if context == nil {
+		return fmt.Errorf("cannot %s services without a context", inst.Action)
+	}
+
+	svcs, err := serviceInfosFor(context, names)
+	if err != nil {
+		return err
+	}
+
+	context.Lock()
+	defer context.Unlock()
+
+	context.OnDone(func() error {
+		st := context.State()
+		if context.IsEphemeral() {
+			// outside of a hook nothing orders this after the
+			// changes in progress on the snap
+			if err := snapstate.CheckChangeConflict(st, context.SnapName(), nil); err != nil {
+				return err
+			}
+		}
+		ts, err := servicestate.Control(st, svcs, inst)
+		if err != nil {
+			return err
+		}
+		if context.IsEphemeral() {
+			// not in a hook, act right away
+			chg := st.NewChange("service-control", ts.Tasks()[0].Summary())
+			chg.AddAll(ts)
+			st.EnsureBefore(0)
+			return nil
+		}
+		chg := context.Task().Change()
+		if chg == nil {
+			return fmt.Errorf("cannot %s services: hook task is not part of a change", inst.Action)
+		}
+		ts.WaitAll(state.NewTaskSet(chg.Tasks()...))
+		chg.AddAll(ts)
+		return nil
+	})
+
+	return nil
+}
+
+func (c *startCommand) Execute(args []string) error {
+	return queueServiceControl(c.context(), c.Positional.ServiceNames, &servicestate.Instruction{
+		Action: "start",
+		Enable: c.Enable,
+	})
+}
+
+func (c *stopCommand) Execute(args []string) error {
+	return queueServiceControl(c.context(), c.Positional.ServiceNames, &servicestate.Instruction{
+		Action:  "stop",
+		Disable: c.Disable,
+	})
+}
+
+func (c *restartCommand) Execute(args []string) error {
+	return queueServiceControl(c.context(), c.Positional.ServiceNames, &servicestate.Instruction{
+		Action: "restart",
+	})
+}
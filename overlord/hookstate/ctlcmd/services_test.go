@@ -0,0 +1,245 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/systemd"
+
+	. "gopkg.in/check.v1"
+)
+
+type servicesSuite struct {
+	state       *state.State
+	change      *state.Change
+	mockContext *hookstate.Context
+	mockHandler *hooktest.MockHandler
+
+	restoreSystemctl func()
+	systemctlArgs    [][]string
+}
+
+var _ = Suite(&servicesSuite{})
+
+const servicesSnapYaml = `name: test-snap
+version: 1
+apps:
+  cmd:
+    command: bin/cmd
+  svc1:
+    command: bin/svc1
+    daemon: simple
+  svc2:
+    command: bin/svc2
+    daemon: forking
+`
+
+func (s *servicesSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	s.mockHandler = hooktest.NewMockHandler()
+
+	s.systemctlArgs = nil
+	oldSystemctl := systemd.SystemctlCmd
+	systemd.SystemctlCmd = func(args ...string) ([]byte, error) {
+		s.systemctlArgs = append(s.systemctlArgs, args)
+		unitFileState := "disabled"
+		activeState := "inactive"
+		if args[len(args)-1] == "snap.test-snap.svc1.service" {
+			unitFileState = "enabled"
+			activeState = "active"
+		}
+		return []byte(fmt.Sprintf("Id=%s\nLoadState=loaded\nActiveState=%s\nSubState=running\nUnitFileState=%s\n", args[len(args)-1], activeState, unitFileState)), nil
+	}
+	s.restoreSystemctl = func() { systemd.SystemctlCmd = oldSystemctl }
+
+	s.state = state.New(nil)
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	si := &snap.SideInfo{RealName: "test-snap", Revision: snap.R(1)}
+	snaptest.MockSnap(c, servicesSnapYaml, si)
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	})
+
+	task := s.state.NewTask("run-hook", "")
+	s.change = s.state.NewChange("install", "")
+	s.change.AddTask(task)
+	setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "configure"}
+
+	var err error
+	s.mockContext, err = hookstate.NewContext(task, setup, s.mockHandler)
+	c.Assert(err, IsNil)
+}
+
+func (s *servicesSuite) TearDownTest(c *C) {
+	s.restoreSystemctl()
+	dirs.SetRootDir("")
+}
+
+func (s *servicesSuite) TestServices(c *C) {
+	stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"services"})
+	c.Assert(err, IsNil)
+	c.Check(string(stderr), Equals, "")
+	c.Check(string(stdout), Equals, `Service         Startup   Current
+test-snap.svc1  enabled   active
+test-snap.svc2  disabled  inactive
+`)
+
+	stdout, _, err = ctlcmd.Run(s.mockContext, []string{"services", "test-snap.svc2"})
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, `Service         Startup   Current
+test-snap.svc2  disabled  inactive
+`)
+}
+
+func (s *servicesSuite) TestServicesOfOtherSnaps(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"services", "other-snap.svc"})
+	c.Check(err, ErrorMatches, `cannot operate on services of snap "other-snap" from a hook of snap "test-snap"`)
+
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"stop", "other-snap"})
+	c.Check(err, ErrorMatches, `cannot operate on services of snap "other-snap" from a hook of snap "test-snap"`)
+}
+
+func (s *servicesSuite) TestNotAService(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"restart", "test-snap.cmd"})
+	c.Check(err, ErrorMatches, `snap "test-snap" has no service "cmd"`)
+}
+
+func (s *servicesSuite) TestStartQueuedAfterHook(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"start", "--enable", "test-snap"})
+	c.Assert(err, IsNil)
+	c.Check(s.systemctlArgs, HasLen, 0)
+
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+
+	c.Check(s.change.Tasks(), HasLen, 1)
+	c.Assert(s.mockContext.Done(), IsNil)
+
+	tasks := s.change.Tasks()
+	c.Assert(tasks, HasLen, 2)
+	t := tasks[1]
+	c.Check(t.Kind(), Equals, "service-control")
+	c.Check(t.Summary(), Equals, "Start services test-snap.svc1, test-snap.svc2")
+	c.Check(t.WaitTasks(), DeepEquals, []*state.Task{s.mockContext.Task()})
+
+	var action map[string]interface{}
+	c.Assert(t.Get("service-action", &action), IsNil)
+	c.Check(action, DeepEquals, map[string]interface{}{
+		"action":   "start",
+		"enable":   true,
+		"services": []interface{}{"test-snap.svc1", "test-snap.svc2"},
+	})
+}
+
+func (s *servicesSuite) TestStopAndRestart(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"stop", "--disable", "test-snap.svc1"})
+	c.Assert(err, IsNil)
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"restart", "test-snap.svc2"})
+	c.Assert(err, IsNil)
+
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+	c.Assert(s.mockContext.Done(), IsNil)
+
+	tasks := s.change.Tasks()
+	c.Assert(tasks, HasLen, 3)
+	c.Check(tasks[1].Summary(), Equals, "Stop services test-snap.svc1")
+	c.Check(tasks[2].Summary(), Equals, "Restart services test-snap.svc2")
+}
+
+func (s *servicesSuite) TestRestartOutsideHook(c *C) {
+	s.state.Lock()
+	s.state.Set("snap-cookies", map[string]string{"test-cookie": "test-snap"})
+	s.state.Unlock()
+	hookMgr, err := hookstate.Manager(s.state)
+	c.Assert(err, IsNil)
+	context, err := hookMgr.EphemeralContext("test-cookie")
+	c.Assert(err, IsNil)
+
+	_, _, err = ctlcmd.Run(context, []string{"restart", "test-snap.svc1"})
+	c.Assert(err, IsNil)
+
+	context.Lock()
+	defer context.Unlock()
+	c.Assert(context.Done(), IsNil)
+
+	var chg *state.Change
+	for _, c := range s.state.Changes() {
+		if c.Kind() == "service-control" {
+			chg = c
+		}
+	}
+	c.Assert(chg, NotNil)
+	c.Check(chg.Summary(), Equals, "Restart services test-snap.svc1")
+	c.Assert(chg.Tasks(), HasLen, 1)
+	c.Check(chg.Tasks()[0].Kind(), Equals, "service-control")
+}
+
+func (s *servicesSuite) TestRestartOutsideHookConflict(c *C) {
+	s.state.Lock()
+	s.state.Set("snap-cookies", map[string]string{"test-cookie": "test-snap"})
+	// the snap is being refreshed
+	t := s.state.NewTask("link-snap", "")
+	t.Set("snap-setup", &snapstate.SnapSetup{SideInfo: &snap.SideInfo{RealName: "test-snap"}})
+	chg := s.state.NewChange("refresh-snap", "")
+	chg.AddTask(t)
+	s.state.Unlock()
+	hookMgr, err := hookstate.Manager(s.state)
+	c.Assert(err, IsNil)
+	context, err := hookMgr.EphemeralContext("test-cookie")
+	c.Assert(err, IsNil)
+
+	_, _, err = ctlcmd.Run(context, []string{"restart", "test-snap.svc1"})
+	c.Assert(err, IsNil)
+
+	context.Lock()
+	defer context.Unlock()
+	err = context.Done()
+	c.Assert(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err, ErrorMatches, `snap "test-snap" has changes in progress`)
+
+	for _, chg := range s.state.Changes() {
+		c.Check(chg.Kind(), Not(Equals), "service-control")
+	}
+	for _, t := range s.state.Tasks() {
+		c.Check(t.Kind(), Not(Equals), "service-control")
+	}
+}
+
+func (s *servicesSuite) TestWithoutContext(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"services"})
+	c.Check(err, ErrorMatches, "cannot query services without a context")
+
+	_, _, err = ctlcmd.Run(nil, []string{"start", "foo"})
+	c.Check(err, ErrorMatches, "cannot start services without a context")
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
 )
 
 type setCommand struct {
@@ -36,7 +37,7 @@ type setCommand struct {
 	} `positional-args:"yes" required:"yes"`
 }
 
-var shortSetHelp = i18n.G("Set snap configuration")
+var shortSetHelp = i18n.G("Set snap configuration or interface attributes")
 var longSetHelp = i18n.G(`
 The set command changes the provided configuration options as requested. For
 example:
@@ -44,7 +45,16 @@ example:
     $ snapctl set username=joe password=$PASSWORD
 
 All configuration changes are persisted at once, and only after the hook returns
-successfully.`)
+successfully.
+
+During the execution of the prepare-plug-<plug> and prepare-slot-<slot> hooks,
+the dynamic attributes of the plug or slot the hook is run for can be set by
+prefixing its name with a colon:
+
+    $ snapctl set :myslot path=/run/producer/socket
+
+Attributes declared in snap.yaml cannot be changed. As with configuration, the
+attributes are stored only after the hook returns successfully.`)
 
 func init() {
 	addCommand("set", shortSetHelp, longSetHelp, func() command { return &setCommand{} })
@@ -56,21 +66,18 @@ func (s *setCommand) Execute(args []string) error {
 		return fmt.Errorf("cannot set without a context")
 	}
 
+	if strings.HasPrefix(s.Positional.ConfValues[0], ":") {
+		return s.setInterfaceAttrs(context, s.Positional.ConfValues[0], s.Positional.ConfValues[1:])
+	}
+
 	context.Lock()
 	transaction := configstate.ContextTransaction(context)
 	context.Unlock()
 
 	for _, patchValue := range s.Positional.ConfValues {
-		parts := strings.SplitN(patchValue, "=", 2)
-		if len(parts) != 2 {
-			return fmt.Errorf(i18n.G("invalid parameter: %q (want key=value)"), patchValue)
-		}
-		key := parts[0]
-		var value interface{}
-		err := json.Unmarshal([]byte(parts[1]), &value)
+		key, value, err := parseKeyValue(patchValue)
 		if err != nil {
-			// Not valid JSON-- just save the string as-is.
-			value = parts[1]
+			return err
 		}
 
 		transaction.Set(s.context().SnapName(), key, value)
@@ -78,3 +85,60 @@ func (s *setCommand) Execute(args []string) error {
 
 	return nil
 }
+
+func (s *setCommand) setInterfaceAttrs(context *hookstate.Context, plugOrSlot string, attrValues []string) error {
+	if len(attrValues) == 0 {
+		return fmt.Errorf(i18n.G("set which attribute of %s?"), plugOrSlot)
+	}
+
+	context.Lock()
+	defer context.Unlock()
+
+	hook, err := checkInterfaceHook(context, plugOrSlot)
+	if err != nil {
+		return err
+	}
+	if !hook.prepare {
+		return fmt.Errorf(i18n.G("interface attributes can only be set during the execution of prepare hooks"))
+	}
+
+	attrsTask, err := attributesTask(context)
+	if err != nil {
+		return err
+	}
+	static, err := staticAttrs(context.State(), attrsTask, hook.side)
+	if err != nil {
+		return err
+	}
+	dynamic, err := contextDynamicAttrs(context, attrsTask, hook.side)
+	if err != nil {
+		return err
+	}
+
+	for _, attrValue := range attrValues {
+		key, value, err := parseKeyValue(attrValue)
+		if err != nil {
+			return err
+		}
+		if _, ok := static[key]; ok {
+			return fmt.Errorf(i18n.G("cannot change attribute %q of %s %q declared in snap.yaml"), key, hook.side, hook.name)
+		}
+		dynamic[key] = value
+	}
+
+	setContextDynamicAttrs(context, attrsTask, hook.side, dynamic)
+	return nil
+}
+
+func parseKeyValue(keyValue string) (key string, value interface{}, err error) {
+	parts := strings.SplitN(keyValue, "=", 2)
+	if len(parts) != 2 {
+		return "", nil, fmt.Errorf(i18n.G("invalid parameter: %q (want key=value)"), keyValue)
+	}
+	key = parts[0]
+	if err := json.Unmarshal([]byte(parts[1]), &value); err != nil {
+		// Not valid JSON-- just save the string as-is.
+		value = parts[1]
+	}
+	return key, value, nil
+}
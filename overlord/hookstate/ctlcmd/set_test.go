@@ -116,3 +116,62 @@ func (s *setSuite) TestCommandWithoutContext(c *C) {
 	_, _, err := ctlcmd.Run(nil, []string{"set", "foo=bar"})
 	c.Check(err, ErrorMatches, ".*cannot set without a context.*")
 }
+
+type setAttrSuite struct {
+	attrsBaseSuite
+}
+
+var _ = Suite(&setAttrSuite{})
+
+func (s *setAttrSuite) TestSetAttrs(c *C) {
+	context := s.hookContext(c, "consumer", "prepare-plug-plug")
+
+	stdout, stderr, err := ctlcmd.Run(context, []string{"set", ":plug", "foo=bar", "num=42"})
+	c.Check(err, IsNil)
+	c.Check(string(stdout), Equals, "")
+	c.Check(string(stderr), Equals, "")
+	_, _, err = ctlcmd.Run(context, []string{"set", ":plug", "other=1"})
+	c.Check(err, IsNil)
+
+	// the hook itself sees them right away
+	stdout, _, err = ctlcmd.Run(context, []string{"get", ":plug", "foo"})
+	c.Check(err, IsNil)
+	c.Check(string(stdout), Equals, `"bar"`)
+
+	// but they are only stored once the hook is done
+	context.Lock()
+	var dynamic map[string]interface{}
+	err = s.attrsTask.Get("plug-dynamic", &dynamic)
+	c.Check(err, Equals, state.ErrNoState)
+	c.Check(context.Done(), IsNil)
+	err = s.attrsTask.Get("plug-dynamic", &dynamic)
+	context.Unlock()
+	c.Assert(err, IsNil)
+	c.Check(dynamic, DeepEquals, map[string]interface{}{"foo": "bar", "num": 42.0, "other": 1.0})
+
+	// the connect hook of the slot sees them
+	context = s.hookContext(c, "producer", "connect-slot-slot")
+	stdout, _, err = ctlcmd.Run(context, []string{"get", "--plug", ":slot", "foo"})
+	c.Check(err, IsNil)
+	c.Check(string(stdout), Equals, `"bar"`)
+}
+
+func (s *setAttrSuite) TestSetAttrErrors(c *C) {
+	context := s.hookContext(c, "consumer", "prepare-plug-plug")
+
+	_, _, err := ctlcmd.Run(context, []string{"set", ":plug", "content=otherlib"})
+	c.Check(err, ErrorMatches, `cannot change attribute "content" of plug "plug" declared in snap.yaml`)
+
+	_, _, err = ctlcmd.Run(context, []string{"set", ":plug", "foo"})
+	c.Check(err, ErrorMatches, `invalid parameter: "foo" \(want key=value\)`)
+
+	_, _, err = ctlcmd.Run(context, []string{"set", ":plug"})
+	c.Check(err, ErrorMatches, `set which attribute of :plug\?`)
+
+	_, _, err = ctlcmd.Run(context, []string{"set", ":slot", "foo=bar"})
+	c.Check(err, ErrorMatches, `unknown plug or slot "slot", the hook is for plug "plug"`)
+
+	context = s.hookContext(c, "consumer", "connect-plug-plug")
+	_, _, err = ctlcmd.Run(context, []string{"set", ":plug", "foo=bar"})
+	c.Check(err, ErrorMatches, `interface attributes can only be set during the execution of prepare hooks`)
+}
@@ -0,0 +1,94 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+
+	. "gopkg.in/check.v1"
+)
+
+type unsetSuite struct {
+	mockContext *hookstate.Context
+	mockHandler *hooktest.MockHandler
+}
+
+var _ = Suite(&unsetSuite{})
+
+func (s *unsetSuite) SetUpTest(c *C) {
+	s.mockHandler = hooktest.NewMockHandler()
+
+	state := state.New(nil)
+	state.Lock()
+	defer state.Unlock()
+
+	task := state.NewTask("test-task", "my test task")
+	setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: "test-hook"}
+
+	var err error
+	s.mockContext, err = hookstate.NewContext(task, setup, s.mockHandler)
+	c.Assert(err, IsNil)
+
+	transaction := configstate.NewTransaction(state)
+	transaction.Set("test-snap", "foo", "a")
+	transaction.Set("test-snap", "bar", "b")
+	transaction.Commit()
+}
+
+func (s *unsetSuite) TestCommand(c *C) {
+	stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"unset", "foo"})
+	c.Check(err, IsNil)
+	c.Check(string(stdout), Equals, "")
+	c.Check(string(stderr), Equals, "")
+
+	// the hook sees the option gone right away
+	_, _, err = ctlcmd.Run(s.mockContext, []string{"get", "foo"})
+	c.Check(err, ErrorMatches, `.*snap "test-snap" has no "foo" configuration option`)
+
+	// but the global state is unchanged until the hook is done
+	s.mockContext.Lock()
+	defer s.mockContext.Unlock()
+	var value string
+	transaction := configstate.NewTransaction(s.mockContext.State())
+	c.Check(transaction.Get("test-snap", "foo", &value), IsNil)
+	c.Check(value, Equals, "a")
+
+	c.Check(s.mockContext.Done(), IsNil)
+
+	transaction = configstate.NewTransaction(s.mockContext.State())
+	c.Check(transaction.Get("test-snap", "foo", &value), ErrorMatches, `snap "test-snap" has no "foo" configuration option`)
+	c.Check(transaction.Get("test-snap", "bar", &value), IsNil)
+	c.Check(value, Equals, "b")
+}
+
+func (s *unsetSuite) TestCommandWithoutKeys(c *C) {
+	_, _, err := ctlcmd.Run(s.mockContext, []string{"unset"})
+	c.Check(err, ErrorMatches, ".*the required argument `<key>.*` was not provided.*")
+}
+
+func (s *unsetSuite) TestCommandWithoutContext(c *C) {
+	_, _, err := ctlcmd.Run(nil, []string{"unset", "foo"})
+	c.Check(err, ErrorMatches, ".*cannot unset without a context.*")
+}
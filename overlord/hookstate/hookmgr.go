@@ -32,6 +32,7 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 )
@@ -68,6 +69,11 @@ type HookSetup struct {
 	Snap     string        `json:"snap"`
 	Revision snap.Revision `json:"revision"`
 	Hook     string        `json:"hook"`
+
+	// Optional hooks are skipped if the snap doesn't have them or is
+	// not installed; their revision, if unset, is the current one of
+	// the snap when they run.
+	Optional bool `json:"optional,omitempty"`
 }
 
 // Manager returns a new HookManager.
@@ -82,18 +88,26 @@ func Manager(s *state.State) (*HookManager, error) {
 
 	runner.AddHandler("run-hook", manager.doRunHook, nil)
 
+	snapstate.AddAffectedSnapsByAttr("hook-setup", hookAffectedSnaps)
+
+	setupHooks(manager)
+
 	return manager, nil
 }
 
+func hookAffectedSnaps(t *state.Task) ([]string, error) {
+	var hooksup HookSetup
+	if err := t.Get("hook-setup", &hooksup); err != nil {
+		return nil, fmt.Errorf("internal error: cannot obtain hook data from task: %s", t.Summary())
+	}
+	return []string{hooksup.Snap}, nil
+}
+
 // HookTask returns a task that will run the specified hook. Note that the
 // initial context must properly marshal and unmarshal with encoding/json.
-func HookTask(s *state.State, taskSummary, snapName string, revision snap.Revision, hookName string, initialContext map[string]interface{}) *state.Task {
+func HookTask(s *state.State, taskSummary string, setup *HookSetup, initialContext map[string]interface{}) *state.Task {
 	task := s.NewTask("run-hook", taskSummary)
-	task.Set("hook-setup", HookSetup{
-		Snap:     snapName,
-		Revision: revision,
-		Hook:     hookName,
-	})
+	task.Set("hook-setup", setup)
 
 	// Set the initial context in the task, which will be loaded when Context
 	// is used.
@@ -141,6 +155,20 @@ func (m *HookManager) Context(contextID string) (*Context, error) {
 	return context, nil
 }
 
+// EphemeralContext returns a new ephemeral context acting on behalf of
+// the snap the given cookie belongs to, for use by snapctl outside of
+// hooks.
+func (m *HookManager) EphemeralContext(cookieID string) (*Context, error) {
+	m.state.Lock()
+	snapName, err := snapstate.SnapNameForCookie(m.state, cookieID)
+	m.state.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("cannot find context for cookie: %v", err)
+	}
+
+	return newEphemeralContext(m.state, snapName), nil
+}
+
 // doRunHook actually runs the hook that was requested.
 //
 // Note that this method is synchronous, as the task is already running in a
@@ -155,6 +183,33 @@ func (m *HookManager) doRunHook(task *state.Task, tomb *tomb.Tomb) error {
 		return fmt.Errorf("cannot extract hook setup from task: %s", err)
 	}
 
+	if setup.Optional {
+		task.State().Lock()
+		var snapst snapstate.SnapState
+		err := snapstate.Get(task.State(), setup.Snap, &snapst)
+		if err == state.ErrNoState {
+			// the snap is not installed, so it has no hooks; the
+			// tasks around this one will report about it if needed
+			task.State().Unlock()
+			return nil
+		}
+		var info *snap.Info
+		if err == nil {
+			info, err = snapst.CurrentInfo()
+		}
+		task.State().Unlock()
+		if err != nil {
+			return fmt.Errorf("cannot run hook %q: %v", setup.Hook, err)
+		}
+		if info.Hooks[setup.Hook] == nil {
+			// the snap doesn't have the hook, nothing to do
+			return nil
+		}
+		if setup.Revision.Unset() {
+			setup.Revision = info.Revision
+		}
+	}
+
 	context, err := NewContext(task, setup, nil)
 	if err != nil {
 		return err
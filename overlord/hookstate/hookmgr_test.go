@@ -58,7 +58,11 @@ func (s *hookManagerSuite) SetUpTest(c *C) {
 	}
 
 	s.state.Lock()
-	s.task = hookstate.HookTask(s.state, "test summary", "test-snap", snap.R(1), "test-hook", initialContext)
+	s.task = hookstate.HookTask(s.state, "test summary", &hookstate.HookSetup{
+		Snap:     "test-snap",
+		Revision: snap.R(1),
+		Hook:     "test-hook",
+	}, initialContext)
 	c.Assert(s.task, NotNil, Commentf("Expected HookTask to return a task"))
 
 	s.change = s.state.NewChange("kind", "summary")
@@ -362,3 +366,17 @@ func checkTaskLogContains(c *C, task *state.Task, pattern *regexp.Regexp) {
 
 	c.Check(found, Equals, true, Commentf("Expected to find regex %q in task log: %v", pattern, task.Log()))
 }
+
+func (s *hookManagerSuite) TestEphemeralContext(c *C) {
+	s.state.Lock()
+	s.state.Set("snap-cookies", map[string]string{"test-cookie": "test-snap"})
+	s.state.Unlock()
+
+	context, err := s.manager.EphemeralContext("test-cookie")
+	c.Assert(err, IsNil)
+	c.Check(context.IsEphemeral(), Equals, true)
+	c.Check(context.SnapName(), Equals, "test-snap")
+
+	_, err = s.manager.EphemeralContext("other-cookie")
+	c.Check(err, ErrorMatches, "cannot find context for cookie: unknown snap cookie")
+}
@@ -0,0 +1,92 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hookstate
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// SetupInstallHook returns a task that runs the install hook of the
+// snap, if it has one.
+func SetupInstallHook(st *state.State, snapName string) *state.Task {
+	return snapHookTask(st, i18n.G("Run install hook of %q snap if present"), snapName, "install")
+}
+
+// SetupPreRefreshHook returns a task that runs the pre-refresh hook of
+// the current revision of the snap, if it has one.
+func SetupPreRefreshHook(st *state.State, snapName string) *state.Task {
+	return snapHookTask(st, i18n.G("Run pre-refresh hook of %q snap if present"), snapName, "pre-refresh")
+}
+
+// SetupPostRefreshHook returns a task that runs the post-refresh hook
+// of the new revision of the snap, if it has one.
+func SetupPostRefreshHook(st *state.State, snapName string) *state.Task {
+	return snapHookTask(st, i18n.G("Run post-refresh hook of %q snap if present"), snapName, "post-refresh")
+}
+
+// SetupRemoveHook returns a task that runs the remove hook of the
+// snap, if it has one.
+func SetupRemoveHook(st *state.State, snapName string) *state.Task {
+	return snapHookTask(st, i18n.G("Run remove hook of %q snap if present"), snapName, "remove")
+}
+
+func snapHookTask(st *state.State, summary, snapName, hookName string) *state.Task {
+	setup := &HookSetup{
+		Snap:     snapName,
+		Hook:     hookName,
+		Optional: true,
+	}
+	return HookTask(st, fmt.Sprintf(summary, snapName), setup, nil)
+}
+
+// snapHookHandler is the handler of the hooks that are run as part of
+// the install, refresh and remove of snaps; the hooks are on their own,
+// so there is nothing to do around them.
+type snapHookHandler struct{}
+
+func (h *snapHookHandler) Before() error {
+	return nil
+}
+
+func (h *snapHookHandler) Done() error {
+	return nil
+}
+
+func (h *snapHookHandler) Error(err error) error {
+	return nil
+}
+
+func newSnapHookHandler(context *Context) Handler {
+	return &snapHookHandler{}
+}
+
+func setupHooks(hookMgr *HookManager) {
+	hookMgr.Register(regexp.MustCompile("^(install|remove|pre-refresh|post-refresh)$"), newSnapHookHandler)
+
+	snapstate.SetupInstallHook = SetupInstallHook
+	snapstate.SetupPreRefreshHook = SetupPreRefreshHook
+	snapstate.SetupPostRefreshHook = SetupPostRefreshHook
+	snapstate.SetupRemoveHook = SetupRemoveHook
+}
@@ -0,0 +1,132 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hookstate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *hookManagerSuite) mockSnap(c *C, yaml string) {
+	si := &snap.SideInfo{RealName: "test-snap", Revision: snap.R(5)}
+	snaptest.MockSnap(c, yaml, si)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	})
+}
+
+func (s *hookManagerSuite) runSnapHook(c *C, setupHook func(*state.State, string) *state.Task) *state.Change {
+	s.state.Lock()
+	// drop the change with the test-hook of the suite
+	s.change.SetStatus(state.DoneStatus)
+	chg := s.state.NewChange("kind", "summary")
+	chg.AddTask(setupHook(s.state, "test-snap"))
+	s.state.Unlock()
+
+	s.manager.Ensure()
+	s.manager.Wait()
+
+	return chg
+}
+
+func (s *hookManagerSuite) TestSetupSnapHooks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, t := range []struct {
+		setupHook func(*state.State, string) *state.Task
+		hook      string
+	}{
+		{hookstate.SetupInstallHook, "install"},
+		{hookstate.SetupPreRefreshHook, "pre-refresh"},
+		{hookstate.SetupPostRefreshHook, "post-refresh"},
+		{hookstate.SetupRemoveHook, "remove"},
+	} {
+		task := t.setupHook(s.state, "test-snap")
+		c.Check(task.Kind(), Equals, "run-hook")
+		c.Check(task.Summary(), Equals, `Run `+t.hook+` hook of "test-snap" snap if present`)
+
+		var setup hookstate.HookSetup
+		c.Assert(task.Get("hook-setup", &setup), IsNil)
+		c.Check(setup, Equals, hookstate.HookSetup{Snap: "test-snap", Hook: t.hook, Optional: true})
+	}
+
+	// the hook manager hooks them into snapstate
+	c.Check(snapstate.SetupInstallHook, NotNil)
+	c.Check(snapstate.SetupPreRefreshHook, NotNil)
+	c.Check(snapstate.SetupPostRefreshHook, NotNil)
+	c.Check(snapstate.SetupRemoveHook, NotNil)
+}
+
+func (s *hookManagerSuite) TestSnapHookRuns(c *C) {
+	s.mockSnap(c, "name: test-snap\nversion: 1\nhooks:\n  install:\n")
+
+	chg := s.runSnapHook(c, hookstate.SetupInstallHook)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.command.Calls(), DeepEquals, [][]string{{
+		"snap", "run", "--hook", "install", "-r", "5", "test-snap",
+	}})
+}
+
+func (s *hookManagerSuite) TestSnapHookSkippedIfMissing(c *C) {
+	s.mockSnap(c, "name: test-snap\nversion: 1\n")
+
+	chg := s.runSnapHook(c, hookstate.SetupPreRefreshHook)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.command.Calls(), HasLen, 0)
+}
+
+func (s *hookManagerSuite) TestSnapHookSkippedIfSnapNotInstalled(c *C) {
+	chg := s.runSnapHook(c, hookstate.SetupRemoveHook)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.command.Calls(), HasLen, 0)
+}
+
+func (s *hookManagerSuite) TestSnapHookFailureIsError(c *C) {
+	s.mockSnap(c, "name: test-snap\nversion: 1\nhooks:\n  pre-refresh:\n")
+	s.command = testutil.MockCommand(c, "snap", "echo 'migration failed'; exit 1")
+
+	chg := s.runSnapHook(c, hookstate.SetupPreRefreshHook)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*migration failed.*`)
+}
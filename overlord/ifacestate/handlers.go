@@ -232,10 +232,27 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	if err := m.checkConnectPolicy(st, plugRef, slotRef); err != nil {
+		return err
+	}
+
+	// the dynamic attributes set by the prepare hooks, if any
+	var plugDynamic, slotDynamic map[string]interface{}
+	if err := task.Get("plug-dynamic", &plugDynamic); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if err := task.Get("slot-dynamic", &slotDynamic); err != nil && err != state.ErrNoState {
+		return err
+	}
+
 	err = m.repo.Connect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name)
 	if err != nil {
 		return err
 	}
+	err = m.repo.SetDynamicAttrs(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name, plugDynamic, slotDynamic)
+	if err != nil {
+		return err
+	}
 
 	plug := m.repo.Plug(plugRef.Snap, plugRef.Name)
 	var plugSnapst snapstate.SnapState
@@ -255,7 +272,11 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
-	conns[connID(plugRef, slotRef)] = connState{Interface: plug.Interface}
+	conns[connID(plugRef, slotRef)] = connState{
+		Interface:   plug.Interface,
+		PlugDynamic: plugDynamic,
+		SlotDynamic: slotDynamic,
+	}
 	setConns(st, conns)
 
 	return nil
@@ -23,10 +23,13 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/backends"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/policy"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -84,7 +87,7 @@ func (m *InterfaceManager) reloadConnections(snapName string) error {
 	if err != nil {
 		return err
 	}
-	for id := range conns {
+	for id, conn := range conns {
 		plugRef, slotRef, err := parseConnID(id)
 		if err != nil {
 			return err
@@ -93,6 +96,9 @@ func (m *InterfaceManager) reloadConnections(snapName string) error {
 			continue
 		}
 		err = m.repo.Connect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name)
+		if err == nil {
+			err = m.repo.SetDynamicAttrs(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name, conn.PlugDynamic, conn.SlotDynamic)
+		}
 		if err != nil {
 			logger.Noticef("%s", err)
 		}
@@ -133,6 +139,11 @@ func removeSnapSecurity(task *state.Task, snapName string) error {
 type connState struct {
 	Auto      bool   `json:"auto,omitempty"`
 	Interface string `json:"interface,omitempty"`
+
+	// PlugDynamic and SlotDynamic are the attributes set by the
+	// interface hooks when the connection was made.
+	PlugDynamic map[string]interface{} `json:"plug-dynamic,omitempty"`
+	SlotDynamic map[string]interface{} `json:"slot-dynamic,omitempty"`
 }
 
 func connID(plug *interfaces.PlugRef, slot *interfaces.SlotRef) string {
@@ -163,12 +174,15 @@ func (m *InterfaceManager) autoConnect(task *state.Task, snapName string, blackl
 	if conns == nil {
 		conns = make(map[string]connState)
 	}
-	// XXX: quick hack, auto-connect everything
+	autochecker, err := newAutoConnectChecker(task.State())
+	if err != nil {
+		return err
+	}
 	for _, plug := range m.repo.Plugs(snapName) {
 		if blacklist[plug.Name] {
 			continue
 		}
-		candidates := m.repo.AutoConnectCandidates(snapName, plug.Name)
+		candidates := m.repo.AutoConnectCandidates(snapName, plug.Name, autochecker.check)
 		if len(candidates) != 1 {
 			continue
 		}
@@ -212,3 +226,132 @@ func getConns(st *state.State) (map[string]connState, error) {
 func setConns(st *state.State, conns map[string]connState) {
 	st.Set("conns", conns)
 }
+
+// snapDeclaration returns the snap-declaration of the given snap, or
+// nil for snaps that don't come from the store.
+func snapDeclaration(st *state.State, snapInfo *snap.Info) (*asserts.SnapDeclaration, error) {
+	if snapInfo.SnapID == "" {
+		return nil, nil
+	}
+	snapDecl, err := assertstate.SnapDeclaration(st, snapInfo.SnapID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find snap declaration for %q: %v", snapInfo.Name(), err)
+	}
+	return snapDecl, nil
+}
+
+func baseDeclaration(st *state.State) (*asserts.BaseDeclaration, error) {
+	baseDecl, err := assertstate.BaseDeclaration(st)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot find base declaration: %v", err)
+	}
+	return baseDecl, nil
+}
+
+type autoConnectChecker struct {
+	st       *state.State
+	baseDecl *asserts.BaseDeclaration
+	cache    map[string]*asserts.SnapDeclaration
+}
+
+func newAutoConnectChecker(st *state.State) (*autoConnectChecker, error) {
+	baseDecl, err := baseDeclaration(st)
+	if err != nil {
+		return nil, err
+	}
+	return &autoConnectChecker{
+		st:       st,
+		baseDecl: baseDecl,
+		cache:    make(map[string]*asserts.SnapDeclaration),
+	}, nil
+}
+
+func (c *autoConnectChecker) snapDeclaration(snapInfo *snap.Info) (*asserts.SnapDeclaration, error) {
+	if snapDecl, ok := c.cache[snapInfo.SnapID]; ok {
+		return snapDecl, nil
+	}
+	snapDecl, err := snapDeclaration(c.st, snapInfo)
+	if err != nil {
+		return nil, err
+	}
+	c.cache[snapInfo.SnapID] = snapDecl
+	return snapDecl, nil
+}
+
+func (c *autoConnectChecker) check(plug *interfaces.Plug, slot *interfaces.Slot) bool {
+	plugDecl, err := c.snapDeclaration(plug.Snap)
+	if err != nil {
+		logger.Noticef("cannot auto connect %s:%s to %s:%s: %v", plug.Snap.Name(), plug.Name, slot.Snap.Name(), slot.Name, err)
+		return false
+	}
+	slotDecl, err := c.snapDeclaration(slot.Snap)
+	if err != nil {
+		logger.Noticef("cannot auto connect %s:%s to %s:%s: %v", plug.Snap.Name(), plug.Name, slot.Snap.Name(), slot.Name, err)
+		return false
+	}
+
+	// check the connection against the declarations' rules
+	ic := policy.ConnectCandidate{
+		Plug:                plug.PlugInfo,
+		PlugSnapDeclaration: plugDecl,
+		Slot:                slot.SlotInfo,
+		SlotSnapDeclaration: slotDecl,
+		BaseDeclaration:     c.baseDecl,
+	}
+	return ic.CheckAutoConnect() == nil
+}
+
+// checkConnectPolicy checks whether the connection of the given plug and
+// slot is allowed by the declarations' rules. Missing plugs or slots,
+// and mismatched interfaces are left for the repository to report.
+func (m *InterfaceManager) checkConnectPolicy(st *state.State, plugRef *interfaces.PlugRef, slotRef *interfaces.SlotRef) error {
+	plug := m.repo.Plug(plugRef.Snap, plugRef.Name)
+	slot := m.repo.Slot(slotRef.Snap, slotRef.Name)
+	if plug == nil || slot == nil || plug.Interface != slot.Interface {
+		return nil
+	}
+	baseDecl, err := baseDeclaration(st)
+	if err != nil {
+		return err
+	}
+	plugDecl, err := snapDeclaration(st, plug.Snap)
+	if err != nil {
+		return err
+	}
+	slotDecl, err := snapDeclaration(st, slot.Snap)
+	if err != nil {
+		return err
+	}
+	ic := policy.ConnectCandidate{
+		Plug:                plug.PlugInfo,
+		PlugSnapDeclaration: plugDecl,
+		Slot:                slot.SlotInfo,
+		SlotSnapDeclaration: slotDecl,
+		BaseDeclaration:     baseDecl,
+	}
+	return ic.Check()
+}
+
+// CheckInterfaces checks whether the plugs and slots of the snap are
+// allowed for installation by the declarations' rules.
+func CheckInterfaces(st *state.State, snapInfo *snap.Info) error {
+	baseDecl, err := baseDeclaration(st)
+	if err != nil {
+		return err
+	}
+	snapDecl, err := snapDeclaration(st, snapInfo)
+	if err != nil {
+		return err
+	}
+	ic := policy.InstallCandidate{
+		Snap:            snapInfo,
+		SnapDeclaration: snapDecl,
+		BaseDeclaration: baseDecl,
+	}
+	return ic.Check()
+}
+
+func init() {
+	// hook the interfaces policy checks into snapstate's installation checks
+	snapstate.CheckInterfaces = CheckInterfaces
+}
@@ -0,0 +1,53 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"regexp"
+
+	"github.com/snapcore/snapd/overlord/hookstate"
+)
+
+// interfaceHookHandler is the handler of the prepare-plug-*,
+// prepare-slot-*, connect-plug-* and connect-slot-* hooks, which the
+// HookManager requires to run them. The attributes they set are
+// buffered in the hook context by snapctl and stored in the connect
+// task once they are done, so there is nothing to do around them.
+type interfaceHookHandler struct{}
+
+func (h *interfaceHookHandler) Before() error {
+	return nil
+}
+
+func (h *interfaceHookHandler) Done() error {
+	return nil
+}
+
+func (h *interfaceHookHandler) Error(err error) error {
+	return nil
+}
+
+func newInterfaceHookHandler(context *hookstate.Context) hookstate.Handler {
+	return &interfaceHookHandler{}
+}
+
+func setupHooks(hookMgr *hookstate.HookManager) {
+	hookMgr.Register(regexp.MustCompile("^(prepare|connect)-(plug|slot)-[-a-z0-9]+$"), newInterfaceHookHandler)
+}
@@ -27,6 +27,8 @@ import (
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/backends"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
@@ -41,7 +43,9 @@ type InterfaceManager struct {
 
 // Manager returns a new InterfaceManager.
 // Extra interfaces can be provided for testing.
-func Manager(s *state.State, extra []interfaces.Interface) (*InterfaceManager, error) {
+func Manager(s *state.State, hookManager *hookstate.HookManager, extra []interfaces.Interface) (*InterfaceManager, error) {
+	setupHooks(hookManager)
+
 	runner := state.NewTaskRunner(s)
 	m := &InterfaceManager{
 		state:  s,
@@ -59,6 +63,8 @@ func Manager(s *state.State, extra []interfaces.Interface) (*InterfaceManager, e
 
 	runner.AddHandler("connect", m.doConnect, nil)
 	runner.AddHandler("disconnect", m.doDisconnect, nil)
+	snapstate.AddAffectedSnapsByKind("connect", connectDisconnectAffectedSnaps)
+	snapstate.AddAffectedSnapsByKind("disconnect", connectDisconnectAffectedSnaps)
 	runner.AddHandler("setup-profiles", m.doSetupProfiles, m.doRemoveProfiles)
 	runner.AddHandler("remove-profiles", m.doRemoveProfiles, m.doSetupProfiles)
 	runner.AddHandler("discard-conns", m.doDiscardConns, m.undoDiscardConns)
@@ -67,20 +73,100 @@ func Manager(s *state.State, extra []interfaces.Interface) (*InterfaceManager, e
 
 // Connect returns a set of tasks for connecting an interface.
 //
+// The connection is surrounded by the interface hooks of the two snaps,
+// if they have them: the prepare-plug-<plug> and prepare-slot-<slot>
+// hooks run before it and can set dynamic attributes, the
+// connect-slot-<slot> and connect-plug-<plug> hooks run after it and
+// can read the attributes of both sides.
 func Connect(s *state.State, plugSnap, plugName, slotSnap, slotName string) (*state.TaskSet, error) {
+	if err := checkConnectConflicts(s, plugSnap, slotSnap); err != nil {
+		return nil, err
+	}
+
 	// TODO: Store the intent-to-connect in the state so that we automatically
 	// try to reconnect on reboot (reconnection can fail or can connect with
 	// different parameters so we cannot store the actual connection details).
 	summary := fmt.Sprintf(i18n.G("Connect %s:%s to %s:%s"),
 		plugSnap, plugName, slotSnap, slotName)
-	task := s.NewTask("connect", summary)
-	task.Set("slot", interfaces.SlotRef{Snap: slotSnap, Name: slotName})
-	task.Set("plug", interfaces.PlugRef{Snap: plugSnap, Name: plugName})
-	return state.NewTaskSet(task), nil
+	connectInterface := s.NewTask("connect", summary)
+	connectInterface.Set("slot", interfaces.SlotRef{Snap: slotSnap, Name: slotName})
+	connectInterface.Set("plug", interfaces.PlugRef{Snap: plugSnap, Name: plugName})
+
+	// the hooks find the attributes of the connection in the connect task
+	initialContext := map[string]interface{}{
+		"attrs-task": connectInterface.ID(),
+	}
+	preparePlugConnection := interfaceHookTask(s, plugSnap, "prepare-plug-"+plugName, initialContext)
+	prepareSlotConnection := interfaceHookTask(s, slotSnap, "prepare-slot-"+slotName, initialContext)
+	prepareSlotConnection.WaitFor(preparePlugConnection)
+	connectInterface.WaitFor(prepareSlotConnection)
+	connectSlotConnection := interfaceHookTask(s, slotSnap, "connect-slot-"+slotName, initialContext)
+	connectSlotConnection.WaitFor(connectInterface)
+	connectPlugConnection := interfaceHookTask(s, plugSnap, "connect-plug-"+plugName, initialContext)
+	connectPlugConnection.WaitFor(connectSlotConnection)
+
+	return state.NewTaskSet(preparePlugConnection, prepareSlotConnection, connectInterface, connectSlotConnection, connectPlugConnection), nil
+}
+
+// checkConnectConflicts makes sure neither side of a connection is
+// being operated on by a change in progress.
+func checkConnectConflicts(s *state.State, plugSnap, slotSnap string) error {
+	if err := snapstate.CheckChangeConflict(s, plugSnap, nil); err != nil {
+		return err
+	}
+	if slotSnap != plugSnap {
+		return snapstate.CheckChangeConflict(s, slotSnap, nil)
+	}
+	return nil
+}
+
+func connectDisconnectAffectedSnaps(t *state.Task) ([]string, error) {
+	var plugRef interfaces.PlugRef
+	var slotRef interfaces.SlotRef
+	if err := t.Get("plug", &plugRef); err != nil {
+		return nil, fmt.Errorf("internal error: cannot obtain plug from task: %s", t.Summary())
+	}
+	if err := t.Get("slot", &slotRef); err != nil {
+		return nil, fmt.Errorf("internal error: cannot obtain slot from task: %s", t.Summary())
+	}
+	return []string{plugRef.Snap, slotRef.Snap}, nil
+}
+
+func interfaceHookTask(s *state.State, snapName, hookName string, initialContext map[string]interface{}) *state.Task {
+	setup := &hookstate.HookSetup{
+		Snap:     snapName,
+		Hook:     hookName,
+		Optional: true,
+	}
+	summary := fmt.Sprintf(i18n.G("Run hook %s of snap %q"), hookName, snapName)
+	return hookstate.HookTask(s, summary, setup, initialContext)
+}
+
+// IsConnected returns whether the plug or slot with the given name of
+// the given snap has any connection.
+func IsConnected(st *state.State, snapName, plugOrSlotName string) (bool, error) {
+	conns, err := getConns(st)
+	if err != nil {
+		return false, err
+	}
+	for id := range conns {
+		plugRef, slotRef, err := parseConnID(id)
+		if err != nil {
+			return false, err
+		}
+		if (plugRef.Snap == snapName && plugRef.Name == plugOrSlotName) || (slotRef.Snap == snapName && slotRef.Name == plugOrSlotName) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // Disconnect returns a set of tasks for  disconnecting an interface.
 func Disconnect(s *state.State, plugSnap, plugName, slotSnap, slotName string) (*state.TaskSet, error) {
+	if err := checkConnectConflicts(s, plugSnap, slotSnap); err != nil {
+		return nil, err
+	}
+
 	// TODO: Remove the intent-to-connect from the state so that we no longer
 	// automatically try to reconnect on reboot.
 	summary := fmt.Sprintf(i18n.G("Disconnect %s:%s from %s:%s"),
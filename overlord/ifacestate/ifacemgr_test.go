@@ -20,24 +20,36 @@
 package ifacestate_test
 
 import (
+	"bytes"
+	"fmt"
 	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
 )
 
 func TestInterfaceManager(t *testing.T) { TestingT(t) }
 
 type interfaceManagerSuite struct {
 	state           *state.State
+	db              *asserts.Database
+	storeSigning    *assertstest.StoreStack
 	privateMgr      *ifacestate.InterfaceManager
+	privateHookMgr  *hookstate.HookManager
 	extraIfaces     []interfaces.Interface
 	secBackend      *interfaces.TestSecurityBackend
 	restoreBackends func()
@@ -45,11 +57,31 @@ type interfaceManagerSuite struct {
 
 var _ = Suite(&interfaceManagerSuite{})
 
+func (s *interfaceManagerSuite) SetUpSuite(c *C) {
+	rootPrivKey, _ := assertstest.GenerateKey(1024)
+	storePrivKey, _ := assertstest.GenerateKey(752)
+	s.storeSigning = assertstest.NewStoreStack("canonical", rootPrivKey, storePrivKey)
+}
+
 func (s *interfaceManagerSuite) SetUpTest(c *C) {
 	dirs.SetRootDir(c.MkDir())
 	state := state.New(nil)
 	s.state = state
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+	s.db = db
+	err = db.Add(s.storeSigning.StoreAccountKey(""))
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	assertstate.ReplaceDB(state, s.db)
+	s.state.Unlock()
+
 	s.privateMgr = nil
+	s.privateHookMgr = nil
 	s.extraIfaces = nil
 	s.secBackend = &interfaces.TestSecurityBackend{}
 	s.restoreBackends = ifacestate.MockSecurityBackends([]interfaces.SecurityBackend{s.secBackend})
@@ -59,19 +91,53 @@ func (s *interfaceManagerSuite) TearDownTest(c *C) {
 	if s.privateMgr != nil {
 		s.privateMgr.Stop()
 	}
+	if s.privateHookMgr != nil {
+		s.privateHookMgr.Stop()
+	}
 	dirs.SetRootDir("")
 	s.restoreBackends()
 }
 
 func (s *interfaceManagerSuite) manager(c *C) *ifacestate.InterfaceManager {
 	if s.privateMgr == nil {
-		mgr, err := ifacestate.Manager(s.state, s.extraIfaces)
+		mgr, err := ifacestate.Manager(s.state, s.hookManager(c), s.extraIfaces)
 		c.Assert(err, IsNil)
 		s.privateMgr = mgr
 	}
 	return s.privateMgr
 }
 
+func (s *interfaceManagerSuite) hookManager(c *C) *hookstate.HookManager {
+	if s.privateHookMgr == nil {
+		mgr, err := hookstate.Manager(s.state)
+		c.Assert(err, IsNil)
+		s.privateHookMgr = mgr
+	}
+	return s.privateHookMgr
+}
+
+// settle runs the interface and the hook managers until the tasks they
+// share, like the ones of a connection, are done.
+func (s *interfaceManagerSuite) settle(c *C) {
+	mgr := s.manager(c)
+	hookMgr := s.hookManager(c)
+	for i := 0; i < 10; i++ {
+		mgr.Ensure()
+		mgr.Wait()
+		hookMgr.Ensure()
+		hookMgr.Wait()
+	}
+}
+
+func findTask(tasks []*state.Task, kind string) *state.Task {
+	for _, t := range tasks {
+		if t.Kind() == kind {
+			return t
+		}
+	}
+	return nil
+}
+
 func (s *interfaceManagerSuite) TestSmoke(c *C) {
 	mgr := s.manager(c)
 	mgr.Ensure()
@@ -85,7 +151,32 @@ func (s *interfaceManagerSuite) TestConnectTask(c *C) {
 	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
 	c.Assert(err, IsNil)
 
-	task := ts.Tasks()[0]
+	tasks := ts.Tasks()
+	c.Assert(tasks, HasLen, 5)
+	expectedHooks := []struct{ snap, hook string }{
+		{"consumer", "prepare-plug-plug"},
+		{"producer", "prepare-slot-slot"},
+		{},
+		{"producer", "connect-slot-slot"},
+		{"consumer", "connect-plug-plug"},
+	}
+	for i, t := range tasks {
+		if i > 0 {
+			c.Check(t.WaitTasks(), DeepEquals, []*state.Task{tasks[i-1]})
+		}
+		if i == 2 {
+			continue
+		}
+		c.Check(t.Kind(), Equals, "run-hook")
+		var setup hookstate.HookSetup
+		c.Assert(t.Get("hook-setup", &setup), IsNil)
+		c.Check(setup, Equals, hookstate.HookSetup{Snap: expectedHooks[i].snap, Hook: expectedHooks[i].hook, Optional: true})
+		var hookContext map[string]interface{}
+		c.Assert(t.Get("hook-context", &hookContext), IsNil)
+		c.Check(hookContext, DeepEquals, map[string]interface{}{"attrs-task": tasks[2].ID()})
+	}
+
+	task := tasks[2]
 	c.Assert(task.Kind(), Equals, "connect")
 	var plug interfaces.PlugRef
 	err = task.Get("plug", &plug)
@@ -108,7 +199,7 @@ func (s *interfaceManagerSuite) TestEnsureProcessesConnectTask(c *C) {
 	change := s.state.NewChange("kind", "summary")
 	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
 	c.Assert(err, IsNil)
-	ts.Tasks()[0].Set("snap-setup", &snapstate.SnapSetup{
+	findTask(ts.Tasks(), "connect").Set("snap-setup", &snapstate.SnapSetup{
 		SideInfo: &snap.SideInfo{
 			RealName: "consumer",
 		},
@@ -118,15 +209,14 @@ func (s *interfaceManagerSuite) TestEnsureProcessesConnectTask(c *C) {
 	s.state.Unlock()
 
 	mgr := s.manager(c)
-	mgr.Ensure()
-	mgr.Wait()
+	s.settle(c)
 
 	s.state.Lock()
 	defer s.state.Unlock()
 
 	c.Assert(change.Err(), IsNil)
-	task := change.Tasks()[0]
-	c.Check(task.Kind(), Equals, "connect")
+	task := findTask(change.Tasks(), "connect")
+	c.Assert(task, NotNil)
 	c.Check(task.Status(), Equals, state.DoneStatus)
 	c.Check(change.Status(), Equals, state.DoneStatus)
 
@@ -160,6 +250,57 @@ func (s *interfaceManagerSuite) TestDisconnectTask(c *C) {
 	c.Assert(slot.Name, Equals, "slot")
 }
 
+func (s *interfaceManagerSuite) TestConnectDisconnectConflicts(c *C) {
+	s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("refresh-snap", "...")
+	t := s.state.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "producer"},
+	})
+	chg.AddTask(t)
+
+	expected := &snapstate.ChangeConflictError{
+		Snap:       "producer",
+		ChangeKind: "refresh-snap",
+		ChangeID:   chg.ID(),
+	}
+	_, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Check(err, DeepEquals, expected)
+	_, err = ifacestate.Disconnect(s.state, "consumer", "plug", "producer", "slot")
+	c.Check(err, DeepEquals, expected)
+
+	chg.SetStatus(state.DoneStatus)
+	_, err = ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Check(err, IsNil)
+}
+
+func (s *interfaceManagerSuite) TestConnectInProgressConflicts(c *C) {
+	s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("connect-snap", "...")
+	chg.AddAll(ts)
+
+	// both sides of the connection, through the connect task and the
+	// interface hooks, are operated on by the change
+	for _, name := range []string{"consumer", "producer"} {
+		c.Check(snapstate.CheckChangeConflict(s.state, name, nil), DeepEquals, &snapstate.ChangeConflictError{
+			Snap:       name,
+			ChangeKind: "connect-snap",
+			ChangeID:   chg.ID(),
+		})
+	}
+	c.Check(snapstate.CheckChangeConflict(s.state, "other", nil), IsNil)
+}
+
 func (s *interfaceManagerSuite) TestEnsureProcessesDisconnectTask(c *C) {
 	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
 	s.mockSnap(c, consumerYaml)
@@ -209,7 +350,12 @@ func (s *interfaceManagerSuite) mockIface(c *C, iface interfaces.Interface) {
 }
 
 func (s *interfaceManagerSuite) mockSnap(c *C, yamlText string) *snap.Info {
+	return s.mockSnapWithID(c, yamlText, "")
+}
+
+func (s *interfaceManagerSuite) mockSnapWithID(c *C, yamlText, snapID string) *snap.Info {
 	sideInfo := &snap.SideInfo{
+		SnapID:   snapID,
 		Revision: snap.R(1),
 	}
 	snapInfo := snaptest.MockSnap(c, yamlText, sideInfo)
@@ -227,6 +373,36 @@ func (s *interfaceManagerSuite) mockSnap(c *C, yamlText string) *snap.Info {
 	return snapInfo
 }
 
+// mockSnapDecl adds a snap-declaration for the snap with the given
+// plugs and slots rules, together with the account of its publisher.
+func (s *interfaceManagerSuite) mockSnapDecl(c *C, name, snapID, publisher string, rules map[string]interface{}) {
+	_, err := s.db.Find(asserts.AccountType, map[string]string{
+		"account-id": publisher,
+	})
+	if err == asserts.ErrNotFound {
+		acct := assertstest.NewAccount(s.storeSigning, publisher, map[string]interface{}{
+			"account-id": publisher,
+		}, "")
+		err = s.db.Add(acct)
+	}
+	c.Assert(err, IsNil)
+
+	headers := map[string]interface{}{
+		"series":       "16",
+		"snap-id":      snapID,
+		"snap-name":    name,
+		"publisher-id": publisher,
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}
+	for k, v := range rules {
+		headers[k] = v
+	}
+	snapDecl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, headers, nil, "")
+	c.Assert(err, IsNil)
+	err = s.db.Add(snapDecl)
+	c.Assert(err, IsNil)
+}
+
 func (s *interfaceManagerSuite) mockUpdatedSnap(c *C, yamlText string, revision int) *snap.Info {
 	sideInfo := &snap.SideInfo{Revision: snap.R(revision)}
 	snapInfo := snaptest.MockSnap(c, yamlText, sideInfo)
@@ -801,7 +977,7 @@ func (s *interfaceManagerSuite) TestConnectTracksConnectionsInState(c *C) {
 	s.state.Lock()
 	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
 	c.Assert(err, IsNil)
-	ts.Tasks()[0].Set("snap-setup", &snapstate.SnapSetup{
+	findTask(ts.Tasks(), "connect").Set("snap-setup", &snapstate.SnapSetup{
 		SideInfo: &snap.SideInfo{
 			RealName: "consumer",
 		},
@@ -811,8 +987,7 @@ func (s *interfaceManagerSuite) TestConnectTracksConnectionsInState(c *C) {
 	change.AddAll(ts)
 	s.state.Unlock()
 
-	mgr.Ensure()
-	mgr.Wait()
+	s.settle(c)
 	mgr.Stop()
 
 	s.state.Lock()
@@ -840,7 +1015,7 @@ func (s *interfaceManagerSuite) TestConnectSetsUpSecurity(c *C) {
 	s.state.Lock()
 	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
 	c.Assert(err, IsNil)
-	ts.Tasks()[0].Set("snap-setup", &snapstate.SnapSetup{
+	findTask(ts.Tasks(), "connect").Set("snap-setup", &snapstate.SnapSetup{
 		SideInfo: &snap.SideInfo{
 			RealName: "consumer",
 		},
@@ -850,8 +1025,7 @@ func (s *interfaceManagerSuite) TestConnectSetsUpSecurity(c *C) {
 	change.AddAll(ts)
 	s.state.Unlock()
 
-	mgr.Ensure()
-	mgr.Wait()
+	s.settle(c)
 	mgr.Stop()
 
 	s.state.Lock()
@@ -1032,3 +1206,292 @@ func (s *interfaceManagerSuite) TestSetupProfilesDevModeMultiple(c *C) {
 	c.Check(s.secBackend.SetupCalls[1].SnapInfo.Name(), Equals, siP.Name())
 	c.Check(s.secBackend.SetupCalls[1].DevMode, Equals, false)
 }
+
+var timeControlSnapYaml = `
+name: snap
+version: 1
+plugs:
+ time-control:
+`
+
+const timeControlSnapID = "snapidsnapidsnapidsnapidsnapid01"
+
+// The setup-profiles task will not auto-connect plugs of interfaces
+// that the base-declaration denies auto-connection for.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityDoesNotAutoConnectManualInterfaces(c *C) {
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	snapInfo := s.mockSnap(c, timeControlSnapYaml)
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: snapInfo.Name(),
+			Revision: snapInfo.Revision,
+		},
+	})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	var conns map[string]interface{}
+	err := s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, HasLen, 0)
+}
+
+// The setup-profiles task will auto-connect plugs when their snap-declaration
+// allows it.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityAutoConnectsAllowedBySnapDecl(c *C) {
+	s.mockSnapDecl(c, "snap", timeControlSnapID, "publisher", map[string]interface{}{
+		"plugs": map[string]interface{}{
+			"time-control": map[string]interface{}{
+				"allow-auto-connection": "true",
+			},
+		},
+	})
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	snapInfo := s.mockSnapWithID(c, timeControlSnapYaml, timeControlSnapID)
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: snapInfo.Name(),
+			SnapID:   snapInfo.SnapID,
+			Revision: snapInfo.Revision,
+		},
+	})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	var conns map[string]interface{}
+	err := s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"snap:time-control ubuntu-core:time-control": map[string]interface{}{
+			"interface": "time-control", "auto": true,
+		},
+	})
+}
+
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityAutoConnectLogsMissingSnapDecl(c *C) {
+	logbuf := bytes.NewBuffer(nil)
+	l, err := logger.NewConsoleLog(logbuf, logger.DefaultFlags)
+	c.Assert(err, IsNil)
+	logger.SetLogger(l)
+	defer logger.SetLogger(logger.NullLogger)
+
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	snapInfo := s.mockSnapWithID(c, timeControlSnapYaml, "missingsnapidsnapidsnapidsnapids")
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: snapInfo.Name(),
+			SnapID:   snapInfo.SnapID,
+			Revision: snapInfo.Revision,
+		},
+	})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+	c.Check(logbuf.String(), Matches, `(?s).*cannot auto connect snap:time-control to ubuntu-core:time-control: cannot find snap declaration for "snap": .*`)
+}
+
+func (s *interfaceManagerSuite) TestConnectDeniedBySnapDecl(c *C) {
+	s.mockSnapDecl(c, "snap", timeControlSnapID, "publisher", map[string]interface{}{
+		"plugs": map[string]interface{}{
+			"time-control": map[string]interface{}{
+				"deny-connection": "true",
+			},
+		},
+	})
+	s.mockSnap(c, osSnapYaml)
+	s.mockSnapWithID(c, timeControlSnapYaml, timeControlSnapID)
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	ts, err := ifacestate.Connect(s.state, "snap", "time-control", "ubuntu-core", "time-control")
+	c.Assert(err, IsNil)
+	change := s.state.NewChange("connect", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Err(), ErrorMatches, `(?s).*connection denied by plug rule of interface "time-control" for "snap" snap.*`)
+	c.Check(change.Status(), Equals, state.ErrorStatus)
+
+	repo := mgr.Repository()
+	plug := repo.Plug("snap", "time-control")
+	c.Assert(plug, NotNil)
+	c.Check(plug.Connections, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestCheckInterfaces(c *C) {
+	s.mockSnapDecl(c, "snap", timeControlSnapID, "publisher", map[string]interface{}{
+		"plugs": map[string]interface{}{
+			"time-control": map[string]interface{}{
+				"deny-installation": "true",
+			},
+		},
+	})
+	snapInfo := snaptest.MockInfo(c, timeControlSnapYaml, nil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// unasserted snaps are checked against the base-declaration only
+	c.Check(ifacestate.CheckInterfaces(s.state, snapInfo), IsNil)
+
+	snapInfo.SnapID = timeControlSnapID
+	c.Check(ifacestate.CheckInterfaces(s.state, snapInfo), ErrorMatches, `installation denied by "time-control" plug rule of interface "time-control" for "snap" snap`)
+
+	snapInfo.SnapID = "missingsnapidsnapidsnapidsnapids"
+	c.Check(ifacestate.CheckInterfaces(s.state, snapInfo), ErrorMatches, `cannot find snap declaration for "snap": .*`)
+}
+
+var consumerWithHooksYaml = `
+name: consumer
+version: 1
+plugs:
+ plug:
+  interface: test
+hooks:
+ prepare-plug-plug:
+ connect-plug-plug:
+`
+
+func (s *interfaceManagerSuite) TestConnectRunsInterfaceHooks(c *C) {
+	cmd := testutil.MockCommand(c, "snap", "")
+	defer cmd.Restore()
+
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerWithHooksYaml)
+	s.mockSnap(c, producerYaml)
+
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	change := s.state.NewChange("connect", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Err(), IsNil)
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	// the producer has no hooks, so only the ones of the consumer run
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{"snap", "run", "--hook", "prepare-plug-plug", "-r", "1", "consumer"},
+		{"snap", "run", "--hook", "connect-plug-plug", "-r", "1", "consumer"},
+	})
+}
+
+// dynamicAttrsTestInterface is an interface whose plug snippets show
+// the dynamic attributes of the connection.
+var dynamicAttrsTestInterface = &interfaces.TestInterface{
+	InterfaceName: "test",
+	PlugSnippetCallback: func(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte(fmt.Sprintf("plug %v, slot %v", plug.Attrs["foo"], slot.Attrs["path"])), nil
+	},
+}
+
+func (s *interfaceManagerSuite) TestConnectTracksDynamicAttrsInState(c *C) {
+	s.mockIface(c, dynamicAttrsTestInterface)
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	mgr := s.manager(c)
+
+	// the security backends see the dynamic attributes
+	var snippets []string
+	s.secBackend.SetupCallback = func(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) error {
+		if snapInfo.Name() != "consumer" {
+			return nil
+		}
+		all, err := repo.SecuritySnippetsForSnap(snapInfo.Name(), "test")
+		for _, snippet := range all["snap.consumer.none.plug"] {
+			snippets = append(snippets, string(snippet))
+		}
+		return err
+	}
+
+	s.state.Lock()
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	// as set by the prepare hooks via snapctl
+	connectTask := findTask(ts.Tasks(), "connect")
+	connectTask.Set("plug-dynamic", map[string]interface{}{"foo": "bar"})
+	connectTask.Set("slot-dynamic", map[string]interface{}{"path": "/run/producer/socket"})
+	change := s.state.NewChange("connect", "")
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	s.settle(c)
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Err(), IsNil)
+	var conns map[string]interface{}
+	err = s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"plug-dynamic": map[string]interface{}{"foo": "bar"},
+			"slot-dynamic": map[string]interface{}{"path": "/run/producer/socket"},
+		},
+	})
+	c.Check(snippets, DeepEquals, []string{"plug bar, slot /run/producer/socket"})
+}
+
+func (s *interfaceManagerSuite) TestManagerReloadsDynamicAttrs(c *C) {
+	s.mockIface(c, dynamicAttrsTestInterface)
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"plug-dynamic": map[string]interface{}{"foo": "bar"},
+			"slot-dynamic": map[string]interface{}{"path": "/run/producer/socket"},
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	repo := mgr.Repository()
+
+	snippets, err := repo.SecuritySnippetsForSnap("consumer", "test")
+	c.Assert(err, IsNil)
+	c.Check(snippets["snap.consumer.none.plug"], DeepEquals, [][]byte{[]byte("plug bar, slot /run/producer/socket")})
+}
@@ -40,6 +40,8 @@ import (
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/patch"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/store"
@@ -65,27 +67,44 @@ type Overlord struct {
 	// restarts
 	restartHandler func(t state.RestartType)
 	// managers
-	snapMgr   *snapstate.SnapManager
-	assertMgr *assertstate.AssertManager
-	ifaceMgr  *ifacestate.InterfaceManager
-	hookMgr   *hookstate.HookManager
-	configMgr *configstate.ConfigManager
-	deviceMgr *devicestate.DeviceManager
+	snapMgr     *snapstate.SnapManager
+	assertMgr   *assertstate.AssertManager
+	ifaceMgr    *ifacestate.InterfaceManager
+	hookMgr     *hookstate.HookManager
+	configMgr   *configstate.ConfigManager
+	deviceMgr   *devicestate.DeviceManager
+	snapshotMgr *snapshotstate.SnapshotManager
+	serviceMgr  *servicestate.ServiceManager
 }
 
 var storeNew = store.New
 
-// New creates a new Overlord with all its state managers.
-func New() (*Overlord, error) {
+// Options are the options for creating an Overlord.
+type Options struct {
+	// StateJournal has checkpoints of the state journaled instead of
+	// rewriting the whole state file every time, see state.Journal.
+	StateJournal bool
+}
+
+// New creates a new Overlord with all its state managers, using the
+// given options, if any.
+func New(opts *Options) (*Overlord, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
 	o := &Overlord{
 		loopTomb: new(tomb.Tomb),
 	}
 
 	backend := &overlordStateBackend{
 		path:           dirs.SnapStateFile,
+		journalPath:    dirs.SnapStateJournalFile,
 		ensureBefore:   o.ensureBefore,
 		requestRestart: o.requestRestart,
 	}
+	if opts.StateJournal {
+		backend.journal = state.NewJournal(dirs.SnapStateFile, dirs.SnapStateJournalFile)
+	}
 	s, err := loadState(backend)
 	if err != nil {
 		return nil, err
@@ -107,18 +126,18 @@ func New() (*Overlord, error) {
 	o.assertMgr = assertMgr
 	o.stateEng.AddManager(o.assertMgr)
 
-	ifaceMgr, err := ifacestate.Manager(s, nil)
+	hookMgr, err := hookstate.Manager(s)
 	if err != nil {
 		return nil, err
 	}
-	o.ifaceMgr = ifaceMgr
-	o.stateEng.AddManager(o.ifaceMgr)
+	o.hookMgr = hookMgr
 
-	hookMgr, err := hookstate.Manager(s)
+	ifaceMgr, err := ifacestate.Manager(s, hookMgr, nil)
 	if err != nil {
 		return nil, err
 	}
-	o.hookMgr = hookMgr
+	o.ifaceMgr = ifaceMgr
+	o.stateEng.AddManager(o.ifaceMgr)
 	o.stateEng.AddManager(o.hookMgr)
 
 	configMgr, err := configstate.Manager(s, hookMgr)
@@ -134,6 +153,20 @@ func New() (*Overlord, error) {
 	o.deviceMgr = deviceMgr
 	o.stateEng.AddManager(o.deviceMgr)
 
+	snapshotMgr, err := snapshotstate.Manager(s)
+	if err != nil {
+		return nil, err
+	}
+	o.snapshotMgr = snapshotMgr
+	o.stateEng.AddManager(o.snapshotMgr)
+
+	serviceMgr, err := servicestate.Manager(s)
+	if err != nil {
+		return nil, err
+	}
+	o.serviceMgr = serviceMgr
+	o.stateEng.AddManager(o.serviceMgr)
+
 	// setting up the store
 	authContext := auth.NewAuthContext(s, o.deviceMgr)
 	sto := storeNew(nil, authContext)
@@ -328,3 +361,15 @@ func (o *Overlord) HookManager() *hookstate.HookManager {
 func (o *Overlord) DeviceManager() *devicestate.DeviceManager {
 	return o.deviceMgr
 }
+
+// SnapshotManager returns the snapshot manager responsible for snapshots
+// of snap data under the overlord.
+func (o *Overlord) SnapshotManager() *snapshotstate.SnapshotManager {
+	return o.snapshotMgr
+}
+
+// ServiceManager returns the service manager responsible for controlling
+// the services of snaps under the overlord.
+func (o *Overlord) ServiceManager() *servicestate.ServiceManager {
+	return o.serviceMgr
+}
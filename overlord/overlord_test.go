@@ -33,6 +33,7 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/patch"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -51,6 +52,7 @@ func (ovs *overlordSuite) SetUpTest(c *C) {
 	tmpdir := c.MkDir()
 	dirs.SetRootDir(tmpdir)
 	dirs.SnapStateFile = filepath.Join(tmpdir, "test.json")
+	dirs.SnapStateJournalFile = filepath.Join(tmpdir, "test.journal")
 }
 
 func (ovs *overlordSuite) TearDownTest(c *C) {
@@ -61,7 +63,7 @@ func (ovs *overlordSuite) TestNew(c *C) {
 	restore := patch.Mock(42, nil)
 	defer restore()
 
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 	c.Check(o, NotNil)
 
@@ -69,6 +71,8 @@ func (ovs *overlordSuite) TestNew(c *C) {
 	c.Check(o.AssertManager(), NotNil)
 	c.Check(o.InterfaceManager(), NotNil)
 	c.Check(o.DeviceManager(), NotNil)
+	c.Check(o.SnapshotManager(), NotNil)
+	c.Check(o.ServiceManager(), NotNil)
 
 	s := o.State()
 	c.Check(s, NotNil)
@@ -90,7 +94,7 @@ func (ovs *overlordSuite) TestNewWithGoodState(c *C) {
 	err := ioutil.WriteFile(dirs.SnapStateFile, fakeState, 0600)
 	c.Assert(err, IsNil)
 
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	state := o.State()
@@ -115,7 +119,7 @@ func (ovs *overlordSuite) TestNewWithInvalidState(c *C) {
 	err := ioutil.WriteFile(dirs.SnapStateFile, fakeState, 0600)
 	c.Assert(err, IsNil)
 
-	_, err = overlord.New()
+	_, err = overlord.New(nil)
 	c.Assert(err, ErrorMatches, "EOF")
 }
 
@@ -130,7 +134,7 @@ func (ovs *overlordSuite) TestNewWithPatches(c *C) {
 	err := ioutil.WriteFile(dirs.SnapStateFile, fakeState, 0600)
 	c.Assert(err, IsNil)
 
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	state := o.State()
@@ -149,6 +153,56 @@ func (ovs *overlordSuite) TestNewWithPatches(c *C) {
 	c.Check(b, Equals, true)
 }
 
+func (ovs *overlordSuite) TestNewWithStateJournal(c *C) {
+	fakeState := []byte(fmt.Sprintf(`{"data":{"patch-level":%d,"some":"data"},"changes":null,"tasks":null,"last-change-id":0,"last-task-id":0}`, patch.Level))
+	err := ioutil.WriteFile(dirs.SnapStateFile, fakeState, 0600)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(dirs.SnapStateJournalFile, []byte(`{"set":{"data/some":"journaled"}}`+"\n"), 0600)
+	c.Assert(err, IsNil)
+
+	o, err := overlord.New(&overlord.Options{StateJournal: true})
+	c.Assert(err, IsNil)
+
+	st := o.State()
+	st.Lock()
+	var some string
+	c.Check(st.Get("some", &some), IsNil)
+	c.Check(some, Equals, "journaled")
+	st.Set("some", "more")
+	st.Unlock()
+
+	// the base state file is left alone
+	data, err := ioutil.ReadFile(dirs.SnapStateFile)
+	c.Assert(err, IsNil)
+	c.Check(data, DeepEquals, fakeState)
+	data, err = ioutil.ReadFile(dirs.SnapStateJournalFile)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Matches, `(?s).*{"set":{"data/some":"more"}}\n$`)
+}
+
+func (ovs *overlordSuite) TestNewFoldsLeftoverStateJournal(c *C) {
+	fakeState := []byte(fmt.Sprintf(`{"data":{"patch-level":%d,"some":"data"},"changes":null,"tasks":null,"last-change-id":0,"last-task-id":0}`, patch.Level))
+	err := ioutil.WriteFile(dirs.SnapStateFile, fakeState, 0600)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(dirs.SnapStateJournalFile, []byte(`{"set":{"data/some":"journaled"}}`+"\n"), 0600)
+	c.Assert(err, IsNil)
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+
+	st := o.State()
+	st.Lock()
+	defer st.Unlock()
+	var some string
+	c.Check(st.Get("some", &some), IsNil)
+	c.Check(some, Equals, "journaled")
+
+	c.Check(osutil.FileExists(dirs.SnapStateJournalFile), Equals, false)
+	data, err := ioutil.ReadFile(dirs.SnapStateFile)
+	c.Assert(err, IsNil)
+	c.Check(string(data), testutil.Contains, `"some":"journaled"`)
+}
+
 type witnessManager struct {
 	state          *state.State
 	expectedEnsure int
@@ -174,7 +228,7 @@ func (wm *witnessManager) Wait() {
 }
 
 func (ovs *overlordSuite) TestTrivialRunAndStop(c *C) {
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	o.Loop()
@@ -186,7 +240,7 @@ func (ovs *overlordSuite) TestTrivialRunAndStop(c *C) {
 func (ovs *overlordSuite) TestEnsureLoopRunAndStop(c *C) {
 	restoreIntv := overlord.MockEnsureInterval(10 * time.Millisecond)
 	defer restoreIntv()
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	witness := &witnessManager{
@@ -214,7 +268,7 @@ func (ovs *overlordSuite) TestEnsureLoopRunAndStop(c *C) {
 func (ovs *overlordSuite) TestEnsureLoopMediatedEnsureBeforeImmediate(c *C) {
 	restoreIntv := overlord.MockEnsureInterval(10 * time.Minute)
 	defer restoreIntv()
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	ensure := func(s *state.State) error {
@@ -244,7 +298,7 @@ func (ovs *overlordSuite) TestEnsureLoopMediatedEnsureBeforeImmediate(c *C) {
 func (ovs *overlordSuite) TestEnsureLoopMediatedEnsureBefore(c *C) {
 	restoreIntv := overlord.MockEnsureInterval(10 * time.Minute)
 	defer restoreIntv()
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	ensure := func(s *state.State) error {
@@ -275,7 +329,7 @@ func (ovs *overlordSuite) TestEnsureBeforeSleepy(c *C) {
 	restoreIntv := overlord.MockEnsureInterval(10 * time.Minute)
 	defer restoreIntv()
 
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	ensure := func(s *state.State) error {
@@ -306,7 +360,7 @@ func (ovs *overlordSuite) TestEnsureBeforeSleepy(c *C) {
 func (ovs *overlordSuite) TestEnsureLoopMediatedEnsureBeforeOutsideEnsure(c *C) {
 	restoreIntv := overlord.MockEnsureInterval(10 * time.Minute)
 	defer restoreIntv()
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	ch := make(chan struct{})
@@ -345,7 +399,7 @@ func (ovs *overlordSuite) TestEnsureLoopMediatedEnsureBeforeOutsideEnsure(c *C)
 func (ovs *overlordSuite) TestEnsureLoopPrune(c *C) {
 	restoreIntv := overlord.MockPruneInterval(10*time.Millisecond, 5*time.Millisecond, 5*time.Millisecond)
 	defer restoreIntv()
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	st := o.State()
@@ -375,7 +429,7 @@ func (ovs *overlordSuite) TestCheckpoint(c *C) {
 	oldUmask := syscall.Umask(0)
 	defer syscall.Umask(oldUmask)
 
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	s := o.State()
@@ -446,7 +500,7 @@ func (rm *runnerManager) Wait() {
 func (ovs *overlordSuite) TestTrivialSettle(c *C) {
 	restoreIntv := overlord.MockEnsureInterval(1 * time.Minute)
 	defer restoreIntv()
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	se := o.Engine()
@@ -478,7 +532,7 @@ func (ovs *overlordSuite) TestTrivialSettle(c *C) {
 func (ovs *overlordSuite) TestSettleChain(c *C) {
 	restoreIntv := overlord.MockEnsureInterval(1 * time.Minute)
 	defer restoreIntv()
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	se := o.Engine()
@@ -515,7 +569,7 @@ func (ovs *overlordSuite) TestSettleChain(c *C) {
 func (ovs *overlordSuite) TestSettleExplicitEnsureBefore(c *C) {
 	restoreIntv := overlord.MockEnsureInterval(1 * time.Minute)
 	defer restoreIntv()
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	se := o.Engine()
@@ -553,14 +607,14 @@ func (ovs *overlordSuite) TestSettleExplicitEnsureBefore(c *C) {
 }
 
 func (ovs *overlordSuite) TestRequestRestartNoHandler(c *C) {
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	o.State().RequestRestart(state.RestartDaemon)
 }
 
 func (ovs *overlordSuite) TestRequestRestartHandler(c *C) {
-	o, err := overlord.New()
+	o, err := overlord.New(nil)
 	c.Assert(err, IsNil)
 
 	restartRequested := false
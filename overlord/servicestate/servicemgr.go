@@ -0,0 +1,146 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package servicestate implements the manager and state aspects
+// responsible for starting, stopping and restarting the services of snaps.
+package servicestate
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/timeout"
+)
+
+// ServiceManager is responsible for the tasks that start, stop and
+// restart the services of snaps.
+type ServiceManager struct {
+	runner *state.TaskRunner
+}
+
+// Manager returns a new service manager.
+func Manager(s *state.State) (*ServiceManager, error) {
+	runner := state.NewTaskRunner(s)
+
+	runner.AddHandler("service-control", doServiceControl, nil)
+
+	return &ServiceManager{runner: runner}, nil
+}
+
+// Ensure implements StateManager.Ensure.
+func (m *ServiceManager) Ensure() error {
+	m.runner.Ensure()
+	return nil
+}
+
+// Wait implements StateManager.Wait.
+func (m *ServiceManager) Wait() {
+	m.runner.Wait()
+}
+
+// Stop implements StateManager.Stop.
+func (m *ServiceManager) Stop() {
+	m.runner.Stop()
+}
+
+// taskReporter logs what systemd has to say into the task
+type taskReporter struct {
+	task *state.Task
+}
+
+func (r *taskReporter) Notify(msg string) {
+	st := r.task.State()
+	st.Lock()
+	defer st.Unlock()
+	r.task.Logf("%s", msg)
+}
+
+func serviceStopTimeout(app *snap.AppInfo) time.Duration {
+	tout := app.StopTimeout
+	if tout == 0 {
+		tout = timeout.DefaultTimeout
+	}
+	return time.Duration(tout)
+}
+
+func doServiceControl(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	var sa serviceAction
+	if err := t.Get("service-action", &sa); err != nil {
+		st.Unlock()
+		return err
+	}
+
+	apps := make([]*snap.AppInfo, len(sa.Services))
+	for i, name := range sa.Services {
+		snapName, appName := snap.SplitSnapApp(name)
+		info, err := snapstate.CurrentInfo(st, snapName)
+		if err != nil {
+			st.Unlock()
+			return err
+		}
+		app, ok := info.Apps[appName]
+		if !ok || !app.IsService() {
+			st.Unlock()
+			return fmt.Errorf("snap %q has no service %q", snapName, appName)
+		}
+		apps[i] = app
+	}
+	st.Unlock()
+
+	sysd := systemd.New(dirs.GlobalRootDir, &taskReporter{task: t})
+	for _, app := range apps {
+		serviceName := filepath.Base(app.ServiceFile())
+		var err error
+		switch sa.Action {
+		case "start":
+			if sa.Enable {
+				if err := sysd.Enable(serviceName); err != nil {
+					return err
+				}
+			}
+			err = sysd.Start(serviceName)
+		case "stop":
+			if sa.Disable {
+				if err := sysd.Disable(serviceName); err != nil {
+					return err
+				}
+			}
+			err = sysd.Stop(serviceName, serviceStopTimeout(app))
+		case "restart":
+			err = sysd.Restart(serviceName, serviceStopTimeout(app))
+		default:
+			err = fmt.Errorf("unknown service action %q", sa.Action)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/systemd"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type serviceMgrSuite struct {
+	state *state.State
+	mgr   *servicestate.ServiceManager
+	info  *snap.Info
+
+	sysctlArgs    [][]string
+	restoreSysctl func(...string) ([]byte, error)
+}
+
+var _ = Suite(&serviceMgrSuite{})
+
+const snapYaml = `name: foo
+version: 1.0
+apps:
+  svc1:
+    daemon: simple
+  svc2:
+    daemon: forking
+  app:
+`
+
+func (s *serviceMgrSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+
+	s.sysctlArgs = nil
+	s.restoreSysctl = systemd.SystemctlCmd
+	systemd.SystemctlCmd = func(args ...string) ([]byte, error) {
+		s.sysctlArgs = append(s.sysctlArgs, args)
+		if args[0] == "show" {
+			return []byte("ActiveState=inactive\n"), nil
+		}
+		return nil, nil
+	}
+
+	s.state = state.New(nil)
+	mgr, err := servicestate.Manager(s.state)
+	c.Assert(err, IsNil)
+	s.mgr = mgr
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	si := &snap.SideInfo{RealName: "foo", Revision: snap.R(1)}
+	s.info = snaptest.MockSnap(c, snapYaml, si)
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	})
+}
+
+func (s *serviceMgrSuite) TearDownTest(c *C) {
+	s.mgr.Stop()
+	systemd.SystemctlCmd = s.restoreSysctl
+	dirs.SetRootDir("")
+}
+
+func (s *serviceMgrSuite) settle() {
+	for i := 0; i < 10; i++ {
+		s.mgr.Ensure()
+		s.mgr.Wait()
+	}
+}
+
+// control runs the service control for the instruction on foo's
+// services, returning the summary of its task.
+func (s *serviceMgrSuite) control(c *C, inst *servicestate.Instruction) string {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	apps := []*snap.AppInfo{s.info.Apps["svc1"], s.info.Apps["svc2"]}
+	ts, err := servicestate.Control(s.state, apps, inst)
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("service-control", "...")
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("service control failed with: %v", chg.Err()))
+	return ts.Tasks()[0].Summary()
+}
+
+func (s *serviceMgrSuite) TestStart(c *C) {
+	summary := s.control(c, &servicestate.Instruction{Action: "start"})
+	c.Check(summary, Equals, "Start services foo.svc1, foo.svc2")
+	c.Check(s.sysctlArgs, DeepEquals, [][]string{
+		{"start", "snap.foo.svc1.service"},
+		{"start", "snap.foo.svc2.service"},
+	})
+}
+
+func (s *serviceMgrSuite) TestStartEnable(c *C) {
+	s.control(c, &servicestate.Instruction{Action: "start", Enable: true})
+	c.Check(s.sysctlArgs, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "enable", "snap.foo.svc1.service"},
+		{"start", "snap.foo.svc1.service"},
+		{"--root", dirs.GlobalRootDir, "enable", "snap.foo.svc2.service"},
+		{"start", "snap.foo.svc2.service"},
+	})
+}
+
+func (s *serviceMgrSuite) TestStopDisable(c *C) {
+	summary := s.control(c, &servicestate.Instruction{Action: "stop", Disable: true})
+	c.Check(summary, Equals, "Stop services foo.svc1, foo.svc2")
+	c.Check(s.sysctlArgs, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "disable", "snap.foo.svc1.service"},
+		{"stop", "snap.foo.svc1.service"},
+		{"show", "--property=ActiveState", "snap.foo.svc1.service"},
+		{"--root", dirs.GlobalRootDir, "disable", "snap.foo.svc2.service"},
+		{"stop", "snap.foo.svc2.service"},
+		{"show", "--property=ActiveState", "snap.foo.svc2.service"},
+	})
+}
+
+func (s *serviceMgrSuite) TestRestart(c *C) {
+	s.control(c, &servicestate.Instruction{Action: "restart"})
+	c.Check(s.sysctlArgs, DeepEquals, [][]string{
+		{"stop", "snap.foo.svc1.service"},
+		{"show", "--property=ActiveState", "snap.foo.svc1.service"},
+		{"start", "snap.foo.svc1.service"},
+		{"stop", "snap.foo.svc2.service"},
+		{"show", "--property=ActiveState", "snap.foo.svc2.service"},
+		{"start", "snap.foo.svc2.service"},
+	})
+}
+
+func (s *serviceMgrSuite) TestControlErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	svcs := []*snap.AppInfo{s.info.Apps["svc1"]}
+	for _, t := range []struct {
+		apps []*snap.AppInfo
+		inst *servicestate.Instruction
+		err  string
+	}{
+		{svcs, &servicestate.Instruction{Action: "frobble"}, `unknown service action "frobble"`},
+		{svcs, &servicestate.Instruction{Action: "start", Disable: true}, `cannot disable services when starting them`},
+		{svcs, &servicestate.Instruction{Action: "stop", Enable: true}, `cannot enable services when stopping them`},
+		{svcs, &servicestate.Instruction{Action: "restart", Enable: true}, `cannot enable or disable services when restarting them`},
+		{nil, &servicestate.Instruction{Action: "start"}, `no services given`},
+		{[]*snap.AppInfo{s.info.Apps["app"]}, &servicestate.Instruction{Action: "start"}, `foo.app is not a service`},
+	} {
+		_, err := servicestate.Control(s.state, t.apps, t.inst)
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
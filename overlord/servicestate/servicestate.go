@@ -0,0 +1,109 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Instruction holds what to do to a set of services.
+type Instruction struct {
+	Action  string   `json:"action"`
+	Names   []string `json:"names"`
+	Enable  bool     `json:"enable"`
+	Disable bool     `json:"disable"`
+}
+
+func (inst *Instruction) validate() error {
+	switch inst.Action {
+	case "start":
+		if inst.Disable {
+			return fmt.Errorf("cannot disable services when starting them")
+		}
+	case "stop":
+		if inst.Enable {
+			return fmt.Errorf("cannot enable services when stopping them")
+		}
+	case "restart":
+		if inst.Enable || inst.Disable {
+			return fmt.Errorf("cannot enable or disable services when restarting them")
+		}
+	default:
+		return fmt.Errorf("unknown service action %q", inst.Action)
+	}
+
+	return nil
+}
+
+// serviceAction is what the service-control task is asked to do.
+type serviceAction struct {
+	Action  string `json:"action"`
+	Enable  bool   `json:"enable,omitempty"`
+	Disable bool   `json:"disable,omitempty"`
+	// Services are the services to act on, as snap.app
+	Services []string `json:"services"`
+}
+
+// Control returns a task set that starts, stops or restarts the given
+// services as the instruction asks.
+func Control(st *state.State, appInfos []*snap.AppInfo, inst *Instruction) (*state.TaskSet, error) {
+	if err := inst.validate(); err != nil {
+		return nil, err
+	}
+	if len(appInfos) == 0 {
+		return nil, fmt.Errorf("no services given")
+	}
+
+	services := make([]string, len(appInfos))
+	for i, app := range appInfos {
+		if !app.IsService() {
+			return nil, fmt.Errorf("%s.%s is not a service", app.Snap.Name(), app.Name)
+		}
+		services[i] = app.Snap.Name() + "." + app.Name
+	}
+
+	var summary string
+	switch inst.Action {
+	case "start":
+		// TRANSLATORS: the %s is a comma-separated list of services
+		summary = i18n.G("Start services %s")
+	case "stop":
+		// TRANSLATORS: the %s is a comma-separated list of services
+		summary = i18n.G("Stop services %s")
+	case "restart":
+		// TRANSLATORS: the %s is a comma-separated list of services
+		summary = i18n.G("Restart services %s")
+	}
+
+	t := st.NewTask("service-control", fmt.Sprintf(summary, strings.Join(services, ", ")))
+	t.Set("service-action", serviceAction{
+		Action:   inst.Action,
+		Enable:   inst.Enable,
+		Disable:  inst.Disable,
+		Services: services,
+	})
+
+	return state.NewTaskSet(t), nil
+}
@@ -0,0 +1,288 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package backend implements the low-level primitives to save, list,
+// restore and forget snapshots of snap data.
+package backend
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "golang.org/x/crypto/sha3" // expected for digests
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+const (
+	archiveName  = "archive.tgz"
+	metadataName = "meta.json"
+
+	userArchivePrefix = "user/"
+	userArchiveSuffix = ".tgz"
+)
+
+var timeNow = time.Now
+
+// Filename returns the path of the file the given snapshot is (or
+// would be) kept in.
+func Filename(snapshot *client.Snapshot) string {
+	return filepath.Join(dirs.SnapshotsDir, fmt.Sprintf("%d_%s_%s_%s.zip", snapshot.SetID, snapshot.Snap, snapshot.Version, snapshot.Revision))
+}
+
+// Iter calls f for every snapshot found in the snapshots directory,
+// closing the snapshot after f returns. Snapshots that cannot be
+// opened are logged and skipped. Iteration stops at the first error
+// returned by f, which is then returned.
+func Iter(f func(*Reader) error) error {
+	filenames, err := filepath.Glob(filepath.Join(dirs.SnapshotsDir, "*.zip"))
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range filenames {
+		rsh, err := Open(filename)
+		if err != nil {
+			logger.Noticef("cannot open snapshot %q: %v", filename, err)
+			continue
+		}
+		err = f(rsh)
+		rsh.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type byID []client.SnapshotSet
+
+func (ss byID) Len() int           { return len(ss) }
+func (ss byID) Less(i, j int) bool { return ss[i].ID < ss[j].ID }
+func (ss byID) Swap(i, j int)      { ss[i], ss[j] = ss[j], ss[i] }
+
+type bySnap []*client.Snapshot
+
+func (ss bySnap) Len() int           { return len(ss) }
+func (ss bySnap) Less(i, j int) bool { return ss[i].Snap < ss[j].Snap }
+func (ss bySnap) Swap(i, j int)      { ss[i], ss[j] = ss[j], ss[i] }
+
+// List returns the snapshot sets found in the snapshots directory,
+// limited to the given set (if non-zero) and the given snaps (if
+// non-empty), sorted by set id and then by snap name.
+func List(setID uint64, snapNames []string) ([]client.SnapshotSet, error) {
+	setshots := make(map[uint64][]*client.Snapshot)
+	err := Iter(func(rsh *Reader) error {
+		if setID != 0 && rsh.SetID != setID {
+			return nil
+		}
+		if len(snapNames) > 0 && !contains(snapNames, rsh.Snap) {
+			return nil
+		}
+		snapshot := rsh.Snapshot
+		setshots[rsh.SetID] = append(setshots[rsh.SetID], &snapshot)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make([]client.SnapshotSet, 0, len(setshots))
+	for id, shots := range setshots {
+		sort.Sort(bySnap(shots))
+		sets = append(sets, client.SnapshotSet{ID: id, Snapshots: shots})
+	}
+	sort.Sort(byID(sets))
+
+	return sets, nil
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// snapDataHome returns the directory holding all of the given user's
+// data for the given snap.
+func snapDataHome(username, snapName string) string {
+	return filepath.Join(strings.Replace(dirs.SnapDataHomeGlob, "*", username, 1), snapName)
+}
+
+// snapDataHomes returns the usernames with data for the given snap,
+// limited to the given usernames (if non-empty).
+func snapDataHomes(snapName string, usernames []string) ([]string, error) {
+	found, err := filepath.Glob(filepath.Join(dirs.SnapDataHomeGlob, snapName))
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]string, 0, len(found))
+	for _, dir := range found {
+		// dir is /home/<username>/snap/<snapName>
+		username := filepath.Base(filepath.Dir(filepath.Dir(dir)))
+		if len(usernames) > 0 && !contains(usernames, username) {
+			continue
+		}
+		users = append(users, username)
+	}
+
+	return users, nil
+}
+
+// Save creates a snapshot of the data of the given snap, for the given
+// users (or for all of them, if none are given), as part of the given
+// snapshot set.
+func Save(setID uint64, si *snap.Info, usernames []string) (*client.Snapshot, error) {
+	snapshot := &client.Snapshot{
+		SetID:    setID,
+		Snap:     si.Name(),
+		SnapID:   si.SnapID,
+		Revision: si.Revision,
+		Version:  si.Version,
+		Summary:  si.Summary(),
+		Time:     timeNow().UTC(),
+		SHA3_384: make(map[string]string),
+	}
+
+	if err := os.MkdirAll(dirs.SnapshotsDir, 0700); err != nil {
+		return nil, err
+	}
+
+	filename := Filename(snapshot)
+	f, err := ioutil.TempFile(dirs.SnapshotsDir, "."+filepath.Base(filename)+"~")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if f != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	w := zip.NewWriter(f)
+
+	parent := filepath.Join(dirs.SnapDataDir, si.Name())
+	if err := addDirToZip(snapshot, w, archiveName, parent, si.Revision.String()); err != nil {
+		return nil, err
+	}
+
+	users, err := snapDataHomes(si.Name(), usernames)
+	if err != nil {
+		return nil, err
+	}
+	for _, username := range users {
+		entry := userArchivePrefix + username + userArchiveSuffix
+		if err := addDirToZip(snapshot, w, entry, snapDataHome(username, si.Name()), si.Revision.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	metaWriter, err := w.Create(metadataName)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(metaWriter).Encode(snapshot); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(f.Name(), filename); err != nil {
+		return nil, err
+	}
+	f = nil
+
+	return snapshot, nil
+}
+
+// addDirToZip archives the given revision's and the common data
+// directories under parent (those that exist) into a compressed
+// tarball stored as entry in the zip file, recording its size and hash.
+func addDirToZip(snapshot *client.Snapshot, w *zip.Writer, entry, parent, revision string) error {
+	var dirnames []string
+	for _, dirname := range []string{revision, "common"} {
+		if osutil.IsDirectory(filepath.Join(parent, dirname)) {
+			dirnames = append(dirnames, dirname)
+		}
+	}
+	if len(dirnames) == 0 {
+		// nothing to do
+		return nil
+	}
+
+	// the tarball is already compressed
+	archiveWriter, err := w.CreateHeader(&zip.FileHeader{Name: entry, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+
+	hasher := crypto.SHA3_384.New()
+	counter := &countingWriter{}
+	var errBuf bytes.Buffer
+
+	args := append([]string{"--create", "--sparse", "--gzip", "--directory", parent}, dirnames...)
+	cmd := exec.Command("tar", args...)
+	cmd.Env = []string{}
+	cmd.Stdout = io.MultiWriter(archiveWriter, hasher, counter)
+	cmd.Stderr = &errBuf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("cannot create archive of %q: %v", parent, osutil.OutputErr(errBuf.Bytes(), err))
+	}
+
+	snapshot.SHA3_384[entry] = fmt.Sprintf("%x", hasher.Sum(nil))
+	snapshot.Size += counter.n
+
+	return nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,360 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend_test
+
+import (
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type snapshotSuite struct {
+	root string
+}
+
+var _ = Suite(&snapshotSuite{})
+
+func (s *snapshotSuite) SetUpTest(c *C) {
+	s.root = c.MkDir()
+	dirs.SetRootDir(s.root)
+}
+
+func (s *snapshotSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
+
+const helloYaml = `name: hello
+version: 1.0
+summary: hello snap
+`
+
+func writeFile(c *C, path, content string) {
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte(content), 0644), IsNil)
+}
+
+func readFile(c *C, path string) string {
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	return string(content)
+}
+
+func (s *snapshotSuite) mockData(c *C, rev int) *snap.Info {
+	info := snaptest.MockSnap(c, helloYaml, &snap.SideInfo{Revision: snap.R(rev)})
+	writeFile(c, filepath.Join(info.DataDir(), "canary.txt"), "system data")
+	writeFile(c, filepath.Join(info.CommonDataDir(), "canary.txt"), "system common")
+	for _, user := range []string{"user1", "user2"} {
+		home := filepath.Join(s.root, "home", user)
+		writeFile(c, filepath.Join(info.UserDataDir(home), "canary.txt"), user+" data")
+		writeFile(c, filepath.Join(info.UserCommonDataDir(home), "canary.txt"), user+" common")
+	}
+	return info
+}
+
+func entries(c *C, filename string) []string {
+	zr, err := zip.OpenReader(filename)
+	c.Assert(err, IsNil)
+	defer zr.Close()
+	names := make([]string, len(zr.File))
+	for i, f := range zr.File {
+		names[i] = f.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *snapshotSuite) TestSave(c *C) {
+	info := s.mockData(c, 10)
+
+	snapshot, err := backend.Save(42, info, nil)
+	c.Assert(err, IsNil)
+	c.Check(snapshot.SetID, Equals, uint64(42))
+	c.Check(snapshot.Snap, Equals, "hello")
+	c.Check(snapshot.Revision, Equals, snap.R(10))
+	c.Check(snapshot.Version, Equals, "1.0")
+	c.Check(snapshot.Summary, Equals, "hello snap")
+	c.Check(snapshot.IsValid(), Equals, true)
+	c.Check(snapshot.SHA3_384, HasLen, 3)
+	c.Check(snapshot.Size > 0, Equals, true)
+
+	filename := backend.Filename(snapshot)
+	c.Check(filename, Equals, filepath.Join(dirs.SnapshotsDir, "42_hello_1.0_10.zip"))
+	c.Check(entries(c, filename), DeepEquals, []string{"archive.tgz", "meta.json", "user/user1.tgz", "user/user2.tgz"})
+
+	// nothing else is left around
+	files, err := ioutil.ReadDir(dirs.SnapshotsDir)
+	c.Assert(err, IsNil)
+	c.Check(files, HasLen, 1)
+
+	rsh, err := backend.Open(filename)
+	c.Assert(err, IsNil)
+	defer rsh.Close()
+	c.Check(&rsh.Snapshot, DeepEquals, snapshot)
+	c.Check(rsh.Check(nil), IsNil)
+}
+
+func (s *snapshotSuite) TestSaveSomeUsers(c *C) {
+	info := s.mockData(c, 10)
+
+	snapshot, err := backend.Save(1, info, []string{"user2"})
+	c.Assert(err, IsNil)
+	c.Check(entries(c, backend.Filename(snapshot)), DeepEquals, []string{"archive.tgz", "meta.json", "user/user2.tgz"})
+}
+
+func (s *snapshotSuite) TestSaveNoData(c *C) {
+	info := snaptest.MockSnap(c, helloYaml, &snap.SideInfo{Revision: snap.R(10)})
+
+	snapshot, err := backend.Save(1, info, nil)
+	c.Assert(err, IsNil)
+	c.Check(snapshot.SHA3_384, HasLen, 0)
+	c.Check(entries(c, backend.Filename(snapshot)), DeepEquals, []string{"meta.json"})
+}
+
+func (s *snapshotSuite) TestList(c *C) {
+	info := s.mockData(c, 10)
+	other := snaptest.MockSnap(c, "name: other\nversion: 2\n", &snap.SideInfo{Revision: snap.R(3)})
+	writeFile(c, filepath.Join(other.DataDir(), "canary.txt"), "other data")
+
+	for _, id := range []uint64{2, 1} {
+		_, err := backend.Save(id, info, nil)
+		c.Assert(err, IsNil)
+		_, err = backend.Save(id, other, nil)
+		c.Assert(err, IsNil)
+	}
+	// broken files are ignored
+	writeFile(c, filepath.Join(dirs.SnapshotsDir, "3_broken_1_1.zip"), "not a zip")
+
+	sets, err := backend.List(0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(sets, HasLen, 2)
+	for i, set := range sets {
+		c.Check(set.ID, Equals, uint64(i+1))
+		c.Assert(set.Snapshots, HasLen, 2)
+		c.Check(set.Snapshots[0].Snap, Equals, "hello")
+		c.Check(set.Snapshots[1].Snap, Equals, "other")
+	}
+
+	sets, err = backend.List(2, []string{"other"})
+	c.Assert(err, IsNil)
+	c.Assert(sets, HasLen, 1)
+	c.Check(sets[0].ID, Equals, uint64(2))
+	c.Assert(sets[0].Snapshots, HasLen, 1)
+	c.Check(sets[0].Snapshots[0].Snap, Equals, "other")
+}
+
+func (s *snapshotSuite) TestCheckDetectsCorruption(c *C) {
+	info := s.mockData(c, 10)
+	snapshot, err := backend.Save(1, info, nil)
+	c.Assert(err, IsNil)
+
+	// rewrite the zip with a tampered user archive
+	filename := backend.Filename(snapshot)
+	zr, err := zip.OpenReader(filename)
+	c.Assert(err, IsNil)
+	tampered := filename + ".new"
+	f, err := os.Create(tampered)
+	c.Assert(err, IsNil)
+	zw := zip.NewWriter(f)
+	for _, zf := range zr.File {
+		w, err := zw.Create(zf.Name)
+		c.Assert(err, IsNil)
+		if zf.Name == "user/user1.tgz" {
+			_, err = w.Write([]byte("garbage"))
+			c.Assert(err, IsNil)
+			continue
+		}
+		rc, err := zf.Open()
+		c.Assert(err, IsNil)
+		_, err = io.Copy(w, rc)
+		c.Assert(err, IsNil)
+		rc.Close()
+	}
+	c.Assert(zw.Close(), IsNil)
+	c.Assert(f.Close(), IsNil)
+	zr.Close()
+	c.Assert(os.Rename(tampered, filename), IsNil)
+
+	rsh, err := backend.Open(filename)
+	c.Assert(err, IsNil)
+	defer rsh.Close()
+	c.Check(rsh.Check(nil), ErrorMatches, `snapshot entry "user/user1.tgz" expected hash \(.*\) does not match actual \(.*\)`)
+	c.Check(rsh.Check([]string{"user2"}), IsNil)
+
+	// nothing is unpacked from an entry that doesn't match its hash
+	home1 := filepath.Join(s.root, "home", "user1")
+	c.Assert(os.RemoveAll(info.UserDataDir(home1)), IsNil)
+	_, err = rsh.Restore(snap.R(10), []string{"user1"})
+	c.Check(err, ErrorMatches, `snapshot entry "user/user1.tgz" expected hash \(.*\) does not match actual \(.*\)`)
+	c.Check(osutil.FileExists(info.UserDataDir(home1)), Equals, false)
+	tempdirs, err := filepath.Glob(filepath.Join(home1, "snap", "hello", ".snapshot*"))
+	c.Assert(err, IsNil)
+	c.Check(tempdirs, HasLen, 0)
+}
+
+func (s *snapshotSuite) TestRestoreCreatesMissingUserDirs(c *C) {
+	info := s.mockData(c, 10)
+	snapshot, err := backend.Save(1, info, nil)
+	c.Assert(err, IsNil)
+
+	home1 := filepath.Join(s.root, "home", "user1")
+	c.Assert(os.RemoveAll(filepath.Join(home1, "snap")), IsNil)
+
+	rsh, err := backend.Open(backend.Filename(snapshot))
+	c.Assert(err, IsNil)
+	defer rsh.Close()
+
+	_, err = rsh.Restore(info.Revision, []string{"user1"})
+	c.Assert(err, IsNil)
+	c.Check(readFile(c, filepath.Join(info.UserDataDir(home1), "canary.txt")), Equals, "user1 data")
+	// the directories are owned by the owner of the home directory
+	for _, dir := range []string{filepath.Join(home1, "snap"), filepath.Join(home1, "snap", "hello")} {
+		fi, err := os.Stat(dir)
+		c.Assert(err, IsNil)
+		c.Check(fi.Sys().(*syscall.Stat_t).Uid, Equals, uint32(os.Getuid()), Commentf(dir))
+	}
+}
+
+func (s *snapshotSuite) TestRestoreRefusesSymlinks(c *C) {
+	info := s.mockData(c, 10)
+	snapshot, err := backend.Save(1, info, nil)
+	c.Assert(err, IsNil)
+
+	rsh, err := backend.Open(backend.Filename(snapshot))
+	c.Assert(err, IsNil)
+	defer rsh.Close()
+
+	home1 := filepath.Join(s.root, "home", "user1")
+	elsewhere := filepath.Join(s.root, "elsewhere")
+	c.Assert(os.MkdirAll(elsewhere, 0755), IsNil)
+
+	// the snap directory of the user is a symlink
+	c.Assert(os.RemoveAll(filepath.Join(home1, "snap", "hello")), IsNil)
+	c.Assert(os.Symlink(elsewhere, filepath.Join(home1, "snap", "hello")), IsNil)
+	_, err = rsh.Restore(info.Revision, []string{"user1"})
+	c.Check(err, ErrorMatches, `cannot restore into ".*/home/user1/snap/hello": not a directory`)
+
+	// the revision directory of the user is a symlink
+	c.Assert(os.Remove(filepath.Join(home1, "snap", "hello")), IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(home1, "snap", "hello"), 0755), IsNil)
+	c.Assert(os.Symlink(elsewhere, info.UserDataDir(home1)), IsNil)
+	_, err = rsh.Restore(info.Revision, []string{"user1"})
+	c.Check(err, ErrorMatches, `cannot restore into ".*/home/user1/snap/hello/10": not a directory`)
+
+	entries, err := ioutil.ReadDir(elsewhere)
+	c.Assert(err, IsNil)
+	c.Check(entries, HasLen, 0)
+}
+
+func (s *snapshotSuite) TestOpenInvalid(c *C) {
+	filename := filepath.Join(dirs.SnapshotsDir, "1_hello_1_1.zip")
+	c.Assert(os.MkdirAll(dirs.SnapshotsDir, 0755), IsNil)
+	f, err := os.Create(filename)
+	c.Assert(err, IsNil)
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("meta.json")
+	c.Assert(err, IsNil)
+	_, err = w.Write([]byte(`{"snap": "hello"}`))
+	c.Assert(err, IsNil)
+	c.Assert(zw.Close(), IsNil)
+	c.Assert(f.Close(), IsNil)
+
+	_, err = backend.Open(filename)
+	c.Check(err, ErrorMatches, `cannot read snapshot metadata from ".*": metadata is incomplete`)
+}
+
+func (s *snapshotSuite) TestRestoreRevertCleanup(c *C) {
+	info := s.mockData(c, 10)
+	snapshot, err := backend.Save(1, info, nil)
+	c.Assert(err, IsNil)
+
+	// the data changes, and the snap is refreshed
+	home1 := filepath.Join(s.root, "home", "user1")
+	writeFile(c, filepath.Join(info.CommonDataDir(), "canary.txt"), "new common")
+	writeFile(c, filepath.Join(info.UserCommonDataDir(home1), "canary.txt"), "new user1 common")
+	current := snaptest.MockSnap(c, helloYaml, &snap.SideInfo{Revision: snap.R(11)})
+	writeFile(c, filepath.Join(current.DataDir(), "canary.txt"), "new data")
+
+	rsh, err := backend.Open(backend.Filename(snapshot))
+	c.Assert(err, IsNil)
+	defer rsh.Close()
+
+	rs, err := rsh.Restore(current.Revision, nil)
+	c.Assert(err, IsNil)
+	c.Check(readFile(c, filepath.Join(current.DataDir(), "canary.txt")), Equals, "system data")
+	c.Check(readFile(c, filepath.Join(current.CommonDataDir(), "canary.txt")), Equals, "system common")
+	c.Check(readFile(c, filepath.Join(current.UserDataDir(home1), "canary.txt")), Equals, "user1 data")
+	c.Check(readFile(c, filepath.Join(current.UserCommonDataDir(home1), "canary.txt")), Equals, "user1 common")
+	c.Check(rs.Created, HasLen, 6)
+	c.Check(rs.Moved, HasLen, 4)
+
+	rs.Revert()
+	c.Check(readFile(c, filepath.Join(current.DataDir(), "canary.txt")), Equals, "new data")
+	c.Check(readFile(c, filepath.Join(current.CommonDataDir(), "canary.txt")), Equals, "new common")
+	c.Check(readFile(c, filepath.Join(current.UserCommonDataDir(home1), "canary.txt")), Equals, "new user1 common")
+	c.Check(osutil.FileExists(current.UserDataDir(home1)), Equals, false)
+
+	rs, err = rsh.Restore(current.Revision, []string{"user2"})
+	c.Assert(err, IsNil)
+	c.Check(rs.Created, HasLen, 4)
+	c.Check(readFile(c, filepath.Join(current.CommonDataDir(), "canary.txt")), Equals, "system common")
+	c.Check(readFile(c, filepath.Join(current.UserCommonDataDir(home1), "canary.txt")), Equals, "new user1 common")
+
+	rs.Cleanup()
+	leftovers, err := filepath.Glob(filepath.Join(s.root, "*", "*", "*", "*", "*.~restore~"))
+	c.Assert(err, IsNil)
+	c.Check(leftovers, HasLen, 0)
+	leftovers, err = filepath.Glob(filepath.Join(dirs.SnapDataDir, "hello", "*.~restore~"))
+	c.Assert(err, IsNil)
+	c.Check(leftovers, HasLen, 0)
+}
+
+func (s *snapshotSuite) TestEntryUsername(c *C) {
+	for entry, username := range map[string]string{
+		"user/user1.tgz":     "user1",
+		"user/foo.bar.tgz":   "foo.bar",
+		"archive.tgz":        "",
+		"meta.json":          "",
+		"user/.tgz":          "",
+		"user/../etc.tgz":    "",
+		"user/..tgz":         "",
+		"user/foo/bar.tgz":   "",
+		"user/../../foo.tgz": "",
+	} {
+		u, ok := backend.EntryUsername(entry)
+		c.Check(u, Equals, username, Commentf(entry))
+		c.Check(ok, Equals, username != "", Commentf(entry))
+	}
+}
@@ -0,0 +1,364 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+// A Reader is a snapshot that's been opened for reading.
+type Reader struct {
+	client.Snapshot
+	*zip.ReadCloser
+}
+
+// Open a snapshot, given its full filename.
+func Open(filename string) (*Reader, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rsh := &Reader{ReadCloser: zr}
+	if err := rsh.readMetadata(); err != nil {
+		zr.Close()
+		return nil, fmt.Errorf("cannot read snapshot metadata from %q: %v", filename, err)
+	}
+
+	return rsh, nil
+}
+
+func (r *Reader) readMetadata() error {
+	for _, f := range r.File {
+		if f.Name != metadataName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err := json.NewDecoder(rc).Decode(&r.Snapshot); err != nil {
+			return err
+		}
+		if !r.Snapshot.IsValid() {
+			return fmt.Errorf("metadata is incomplete")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s not found", metadataName)
+}
+
+// entryUsername returns the username a user archive entry is for, and
+// whether the entry is a user archive at all. Entries whose username
+// could escape the home directories when restoring are not considered
+// user archives.
+func entryUsername(entry string) (string, bool) {
+	if !strings.HasPrefix(entry, userArchivePrefix) || !strings.HasSuffix(entry, userArchiveSuffix) {
+		return "", false
+	}
+	username := entry[len(userArchivePrefix) : len(entry)-len(userArchiveSuffix)]
+	if username == "" || username == "." || strings.Contains(username, "/") || strings.Contains(username, "..") {
+		return "", false
+	}
+	return username, true
+}
+
+// skipEntry returns whether the given entry should be ignored when
+// working only with the data of the given users (or all of them, if
+// none are given).
+func skipEntry(entry string, usernames []string) bool {
+	if entry == metadataName {
+		return true
+	}
+	if username, ok := entryUsername(entry); ok {
+		return len(usernames) > 0 && !contains(usernames, username)
+	}
+	return false
+}
+
+func (r *Reader) checkHash(entry string, actual []byte) error {
+	expected := r.SHA3_384[entry]
+	if expected == "" {
+		return fmt.Errorf("snapshot entry %q has no recorded hash", entry)
+	}
+	if sum := fmt.Sprintf("%x", actual); sum != expected {
+		return fmt.Errorf("snapshot entry %q expected hash (%.7s…) does not match actual (%.7s…)", entry, expected, sum)
+	}
+	return nil
+}
+
+// Check verifies the hashes of the archives in the snapshot that are
+// for the given users (or for all of them, if none are given).
+func (r *Reader) Check(usernames []string) error {
+	seen := make(map[string]bool, len(r.SHA3_384))
+	for _, f := range r.File {
+		if skipEntry(f.Name, usernames) {
+			continue
+		}
+		seen[f.Name] = true
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		hasher := crypto.SHA3_384.New()
+		_, err = io.Copy(hasher, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("cannot read snapshot entry %q: %v", f.Name, err)
+		}
+		if err := r.checkHash(f.Name, hasher.Sum(nil)); err != nil {
+			return err
+		}
+	}
+
+	for entry := range r.SHA3_384 {
+		if !skipEntry(entry, usernames) && !seen[entry] {
+			return fmt.Errorf("snapshot entry %q is missing", entry)
+		}
+	}
+
+	return nil
+}
+
+// RestoreState records what a restore did, so it can be reverted, or
+// cleaned up once it is no longer needed.
+type RestoreState struct {
+	// Created holds the data directories that were restored.
+	Created []string `json:"created,omitempty"`
+	// Moved holds the data directories that were moved aside to make
+	// room for the restored ones.
+	Moved []string `json:"moved,omitempty"`
+}
+
+// trashPath returns the path the given data directory is moved aside
+// to while restoring.
+func trashPath(path string) string {
+	return path + ".~restore~"
+}
+
+// Cleanup removes the data that was moved aside by the restore.
+func (rs *RestoreState) Cleanup() {
+	for _, dir := range rs.Moved {
+		if err := os.RemoveAll(trashPath(dir)); err != nil {
+			logger.Noticef("cannot remove directory tree rooted at %q: %v", trashPath(dir), err)
+		}
+	}
+}
+
+// Revert undoes the restore, putting back the data that was moved aside.
+func (rs *RestoreState) Revert() {
+	for i := len(rs.Created) - 1; i >= 0; i-- {
+		if err := os.RemoveAll(rs.Created[i]); err != nil {
+			logger.Noticef("cannot remove directory tree rooted at %q: %v", rs.Created[i], err)
+		}
+	}
+	for _, dir := range rs.Moved {
+		if err := os.Rename(trashPath(dir), dir); err != nil {
+			logger.Noticef("cannot restore %q: %v", dir, err)
+		}
+	}
+}
+
+// Restore the data from the snapshot, for the given users (or for all of
+// them, if none are given), into the data directories of the given
+// (current) revision of the snap. Existing data directories are moved
+// aside; the returned RestoreState can be used to either revert the
+// restore or to clean up after it.
+func (r *Reader) Restore(current snap.Revision, usernames []string) (*RestoreState, error) {
+	rs := &RestoreState{}
+
+	for _, f := range r.File {
+		if skipEntry(f.Name, usernames) {
+			continue
+		}
+
+		// the data is restored into parent, created under base if
+		// missing
+		var base, parent string
+		if f.Name == archiveName {
+			base = dirs.SnapDataDir
+			parent = filepath.Join(base, r.Snap)
+		} else if username, ok := entryUsername(f.Name); ok {
+			parent = snapDataHome(username, r.Snap)
+			// parent is /home/<username>/snap/<snap>
+			base = filepath.Dir(filepath.Dir(parent))
+			if !osutil.IsDirectory(base) {
+				logger.Noticef("skipping restore of %q data for user %q: %q does not exist", r.Snap, username, base)
+				continue
+			}
+		} else {
+			logger.Noticef("skipping unknown entry %q in snapshot of %q", f.Name, r.Snap)
+			continue
+		}
+
+		if err := r.restoreEntry(rs, f, base, parent, current); err != nil {
+			rs.Revert()
+			return nil, err
+		}
+	}
+
+	return rs, nil
+}
+
+// checkDirNoFollow makes sure dir is a directory, and not a symlink to
+// one.
+func checkDirNoFollow(dir string) error {
+	fd, err := syscall.Open(dir, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+	if err == syscall.ELOOP || err == syscall.ENOTDIR {
+		return fmt.Errorf("cannot restore into %q: not a directory", dir)
+	}
+	if err != nil {
+		return &os.PathError{Op: "open", Path: dir, Err: err}
+	}
+	return syscall.Close(fd)
+}
+
+// mkdirAllNoFollow creates the missing directories of path under base,
+// owned by the owner of base, refusing to go through symlinks: under
+// the home directory of a user they could otherwise redirect the
+// restore anywhere.
+func mkdirAllNoFollow(base, path string) error {
+	fi, err := os.Stat(base)
+	if err != nil {
+		return err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("cannot find the owner of %q", base)
+	}
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return err
+	}
+
+	dir := base
+	for _, name := range strings.Split(rel, "/") {
+		dir = filepath.Join(dir, name)
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			if err := os.Lchown(dir, int(st.Uid), int(st.Gid)); err != nil {
+				return err
+			}
+		} else if !os.IsExist(err) {
+			return err
+		}
+		if err := checkDirNoFollow(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkEntryHash verifies the hash of the given entry, before anything
+// is unpacked from it.
+func (r *Reader) checkEntryHash(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	hasher := crypto.SHA3_384.New()
+	if _, err := io.Copy(hasher, rc); err != nil {
+		return fmt.Errorf("cannot read snapshot entry %q: %v", f.Name, err)
+	}
+	return r.checkHash(f.Name, hasher.Sum(nil))
+}
+
+func (r *Reader) restoreEntry(rs *RestoreState, f *zip.File, base, parent string, current snap.Revision) error {
+	if err := r.checkEntryHash(f); err != nil {
+		return err
+	}
+
+	if err := mkdirAllNoFollow(base, parent); err != nil {
+		return err
+	}
+	tempdir, err := ioutil.TempDir(parent, ".snapshot")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempdir)
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	var errBuf bytes.Buffer
+	cmd := exec.Command("tar", "--extract", "--preserve-permissions", "--preserve-order", "--gunzip", "--directory", tempdir)
+	cmd.Env = []string{}
+	cmd.Stdin = rc
+	cmd.Stderr = &errBuf
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("cannot unpack snapshot entry %q: %v", f.Name, osutil.OutputErr(errBuf.Bytes(), err))
+	}
+
+	for _, dirname := range []string{r.Revision.String(), "common"} {
+		source := filepath.Join(tempdir, dirname)
+		if !osutil.IsDirectory(source) {
+			continue
+		}
+		target := filepath.Join(parent, dirname)
+		if dirname != "common" {
+			// data is restored into the current revision
+			target = filepath.Join(parent, current.String())
+		}
+
+		if fi, err := os.Lstat(target); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("cannot restore into %q: not a directory", target)
+		}
+		if osutil.FileExists(target) {
+			if err := os.RemoveAll(trashPath(target)); err != nil {
+				return err
+			}
+			if err := os.Rename(target, trashPath(target)); err != nil {
+				return err
+			}
+			rs.Moved = append(rs.Moved, target)
+		}
+		if err := os.Rename(source, target); err != nil {
+			return err
+		}
+		rs.Created = append(rs.Created, target)
+	}
+
+	return nil
+}
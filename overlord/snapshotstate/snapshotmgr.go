@@ -0,0 +1,210 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package snapshotstate implements the manager and state aspects
+// responsible for saving, restoring and forgetting snapshots of snap data.
+package snapshotstate
+
+import (
+	"os"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func init() {
+	snapstate.AutomaticSnapshot = AutomaticSnapshot
+}
+
+// SnapshotManager is responsible for the tasks that save, restore and
+// forget snapshots of snap data.
+type SnapshotManager struct {
+	runner *state.TaskRunner
+}
+
+// Manager returns a new snapshot manager.
+func Manager(s *state.State) (*SnapshotManager, error) {
+	runner := state.NewTaskRunner(s)
+
+	runner.AddHandler("save-snapshot", doSave, undoSave)
+	runner.AddHandler("restore-snapshot", doRestore, undoRestore)
+	runner.AddHandler("cleanup-after-restore", doCleanupAfterRestore, nil)
+	runner.AddHandler("forget-snapshot", doForget, nil)
+
+	return &SnapshotManager{runner: runner}, nil
+}
+
+// Ensure implements StateManager.Ensure.
+func (m *SnapshotManager) Ensure() error {
+	m.runner.Ensure()
+	return nil
+}
+
+// Wait implements StateManager.Wait.
+func (m *SnapshotManager) Wait() {
+	m.runner.Wait()
+}
+
+// Stop implements StateManager.Stop.
+func (m *SnapshotManager) Stop() {
+	m.runner.Stop()
+}
+
+type snapshotSetup struct {
+	SetID    uint64   `json:"set-id"`
+	Snap     string   `json:"snap"`
+	Users    []string `json:"users,omitempty"`
+	Filename string   `json:"filename,omitempty"`
+	// Current is the revision the data is restored into
+	Current snap.Revision `json:"current,omitempty"`
+}
+
+func taskGetSetup(t *state.Task) (*snapshotSetup, error) {
+	var setup snapshotSetup
+	if err := t.Get("snapshot-setup", &setup); err != nil {
+		return nil, err
+	}
+	return &setup, nil
+}
+
+func doSave(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	setup, err := taskGetSetup(t)
+	if err != nil {
+		st.Unlock()
+		return err
+	}
+	info, err := snapstate.CurrentInfo(st, setup.Snap)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := backend.Save(setup.SetID, info, setup.Users)
+	if err != nil {
+		return err
+	}
+
+	st.Lock()
+	defer st.Unlock()
+	setup.Filename = backend.Filename(snapshot)
+	t.Set("snapshot-setup", setup)
+
+	return nil
+}
+
+func undoSave(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	setup, err := taskGetSetup(t)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+	if setup.Filename == "" {
+		return nil
+	}
+
+	return removeSnapshot(setup.Filename)
+}
+
+func removeSnapshot(filename string) error {
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func doRestore(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	setup, err := taskGetSetup(t)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	rsh, err := backend.Open(setup.Filename)
+	if err != nil {
+		return err
+	}
+	defer rsh.Close()
+
+	rs, err := rsh.Restore(setup.Current, setup.Users)
+	if err != nil {
+		return err
+	}
+
+	st.Lock()
+	defer st.Unlock()
+	t.Set("restore-state", rs)
+
+	return nil
+}
+
+func undoRestore(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	var rs backend.RestoreState
+	err := t.Get("restore-state", &rs)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	rs.Revert()
+
+	return nil
+}
+
+func doCleanupAfterRestore(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	for _, rt := range t.WaitTasks() {
+		if rt.Kind() != "restore-snapshot" {
+			continue
+		}
+		var rs backend.RestoreState
+		if err := rt.Get("restore-state", &rs); err != nil {
+			return err
+		}
+		rs.Cleanup()
+	}
+
+	return nil
+}
+
+func doForget(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	setup, err := taskGetSetup(t)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return removeSnapshot(setup.Filename)
+}
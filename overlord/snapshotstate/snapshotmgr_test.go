@@ -0,0 +1,229 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type snapshotMgrSuite struct {
+	state *state.State
+	mgr   *snapshotstate.SnapshotManager
+	infos map[string]*snap.Info
+}
+
+var _ = Suite(&snapshotMgrSuite{})
+
+func (s *snapshotMgrSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+
+	s.state = state.New(nil)
+	mgr, err := snapshotstate.Manager(s.state)
+	c.Assert(err, IsNil)
+	s.mgr = mgr
+
+	s.infos = make(map[string]*snap.Info)
+	s.state.Lock()
+	defer s.state.Unlock()
+	for _, name := range []string{"foo", "bar"} {
+		s.installSnap(c, name, 1)
+	}
+}
+
+func (s *snapshotMgrSuite) TearDownTest(c *C) {
+	s.mgr.Stop()
+	dirs.SetRootDir("")
+}
+
+func (s *snapshotMgrSuite) installSnap(c *C, name string, rev int) {
+	si := &snap.SideInfo{RealName: name, Revision: snap.R(rev)}
+	info := snaptest.MockSnap(c, "name: "+name+"\nversion: 1.0\n", si)
+	snapstate.Set(s.state, name, &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	})
+	writeFile(c, filepath.Join(info.DataDir(), "canary.txt"), name+" data")
+	s.infos[name] = info
+}
+
+func writeFile(c *C, path, content string) {
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(path, []byte(content), 0644), IsNil)
+}
+
+func readFile(c *C, path string) string {
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	return string(content)
+}
+
+func (s *snapshotMgrSuite) settle() {
+	for i := 0; i < 50; i++ {
+		s.mgr.Ensure()
+		s.mgr.Wait()
+	}
+}
+
+func (s *snapshotMgrSuite) run(c *C, ts *state.TaskSet) *state.Change {
+	chg := s.state.NewChange("snapshot", "...")
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	s.settle()
+	s.state.Lock()
+
+	return chg
+}
+
+func (s *snapshotMgrSuite) TestSaveRestoreForget(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	setID, saved, ts, err := snapshotstate.Save(s.state, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(setID, Equals, uint64(1))
+	c.Check(saved, DeepEquals, []string{"bar", "foo"})
+	chg := s.run(c, ts)
+	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("save failed with: %v", chg.Err()))
+
+	sets, err := snapshotstate.List(0, nil)
+	c.Assert(err, IsNil)
+	c.Assert(sets, HasLen, 1)
+	c.Check(sets[0].ID, Equals, uint64(1))
+	c.Assert(sets[0].Snapshots, HasLen, 2)
+	c.Check(sets[0].Snapshots[0].Snap, Equals, "bar")
+	c.Check(sets[0].Snapshots[1].Snap, Equals, "foo")
+
+	// foo's data changes, and it gets refreshed
+	writeFile(c, filepath.Join(s.infos["foo"].DataDir(), "canary.txt"), "new data")
+	s.installSnap(c, "foo", 2)
+	writeFile(c, filepath.Join(s.infos["foo"].DataDir(), "canary.txt"), "newer data")
+
+	restored, ts, err := snapshotstate.Restore(s.state, setID, []string{"foo"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(restored, DeepEquals, []string{"foo"})
+	chg = s.run(c, ts)
+	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("restore failed with: %v", chg.Err()))
+	c.Check(readFile(c, filepath.Join(s.infos["foo"].DataDir(), "canary.txt")), Equals, "foo data")
+	leftovers, err := filepath.Glob(filepath.Join(dirs.SnapDataDir, "foo", "*~*"))
+	c.Assert(err, IsNil)
+	c.Check(leftovers, HasLen, 0)
+
+	forgotten, ts, err := snapshotstate.Forget(s.state, setID, nil)
+	c.Assert(err, IsNil)
+	c.Check(forgotten, DeepEquals, []string{"bar", "foo"})
+	chg = s.run(c, ts)
+	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("forget failed with: %v", chg.Err()))
+
+	sets, err = snapshotstate.List(0, nil)
+	c.Assert(err, IsNil)
+	c.Check(sets, HasLen, 0)
+}
+
+func (s *snapshotMgrSuite) TestSaveNotInstalled(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, _, _, err := snapshotstate.Save(s.state, []string{"foo", "baz"}, nil)
+	c.Check(err, ErrorMatches, `cannot find snap "baz"`)
+}
+
+func (s *snapshotMgrSuite) TestSetIDsAccountForSnapshotsOnDisk(c *C) {
+	_, err := backend.Save(41, s.infos["foo"], nil)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	setID, _, _, err := snapshotstate.Save(s.state, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(setID, Equals, uint64(42))
+
+	ts, err := snapshotstate.AutomaticSnapshot(s.state, "bar")
+	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 1)
+	c.Check(ts.Tasks()[0].Kind(), Equals, "save-snapshot")
+	c.Check(ts.Tasks()[0].Summary(), Equals, `Save data of snap "bar" in automatic snapshot set #43`)
+}
+
+func (s *snapshotMgrSuite) TestRestoreUnknown(c *C) {
+	_, err := backend.Save(1, s.infos["foo"], nil)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, _, err = snapshotstate.Restore(s.state, 2, nil, nil)
+	c.Check(err, ErrorMatches, `cannot find snapshot set #2`)
+	_, _, err = snapshotstate.Restore(s.state, 1, []string{"bar"}, nil)
+	c.Check(err, ErrorMatches, `snapshot set #1 has no snapshot of snap "bar"`)
+	_, _, err = snapshotstate.Forget(s.state, 1, []string{"bar"})
+	c.Check(err, ErrorMatches, `snapshot set #1 has no snapshot of snap "bar"`)
+}
+
+func (s *snapshotMgrSuite) TestRestoreUndo(c *C) {
+	for _, name := range []string{"foo", "bar"} {
+		_, err := backend.Save(1, s.infos[name], nil)
+		c.Assert(err, IsNil)
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	writeFile(c, filepath.Join(s.infos["foo"].DataDir(), "canary.txt"), "new foo data")
+	writeFile(c, filepath.Join(s.infos["bar"].DataDir(), "canary.txt"), "new bar data")
+
+	_, ts, err := snapshotstate.Restore(s.state, 1, nil, nil)
+	c.Assert(err, IsNil)
+
+	// bar's snapshot goes away before it can be restored
+	sets, err := snapshotstate.List(1, []string{"bar"})
+	c.Assert(err, IsNil)
+	c.Assert(sets, HasLen, 1)
+	c.Assert(os.Remove(backend.Filename(sets[0].Snapshots[0])), IsNil)
+
+	chg := s.run(c, ts)
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+
+	// foo's restore was undone
+	c.Check(readFile(c, filepath.Join(s.infos["foo"].DataDir(), "canary.txt")), Equals, "new foo data")
+	c.Check(readFile(c, filepath.Join(s.infos["bar"].DataDir(), "canary.txt")), Equals, "new bar data")
+	for _, t := range chg.Tasks() {
+		if t.Kind() == "cleanup-after-restore" {
+			c.Check(t.Status(), Equals, state.HoldStatus)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// newSnapshotSetID allocates the id of a new snapshot set. It takes into
+// account the snapshots already on disk, in case the state was lost.
+func newSnapshotSetID(st *state.State) (uint64, error) {
+	var lastSetID uint64
+	err := st.Get("last-snapshot-set-id", &lastSetID)
+	if err != nil && err != state.ErrNoState {
+		return 0, err
+	}
+
+	err = backend.Iter(func(rsh *backend.Reader) error {
+		if rsh.SetID > lastSetID {
+			lastSetID = rsh.SetID
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	lastSetID++
+	st.Set("last-snapshot-set-id", lastSetID)
+
+	return lastSetID, nil
+}
+
+func saveTask(st *state.State, summary string, setID uint64, snapName string, users []string) *state.Task {
+	task := st.NewTask("save-snapshot", summary)
+	task.Set("snapshot-setup", &snapshotSetup{
+		SetID: setID,
+		Snap:  snapName,
+		Users: users,
+	})
+	return task
+}
+
+// Save creates a taskset for taking snapshots of the data of the given
+// snaps (or of all installed snaps, if none are given), for the given
+// users (or for all of them, if none are given), returning the id of the
+// new snapshot set and the names of the snaps that will be saved.
+// Note that the state must be locked by the caller.
+func Save(st *state.State, snapNames []string, users []string) (setID uint64, snapsSaved []string, ts *state.TaskSet, err error) {
+	if len(snapNames) == 0 {
+		snapStates, err := snapstate.All(st)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		for name := range snapStates {
+			snapNames = append(snapNames, name)
+		}
+	} else {
+		for _, name := range snapNames {
+			if _, err := snapstate.CurrentInfo(st, name); err != nil {
+				return 0, nil, nil, err
+			}
+		}
+	}
+	if len(snapNames) == 0 {
+		return 0, nil, nil, fmt.Errorf("cannot find any installed snaps to save")
+	}
+	snapsSaved = make([]string, len(snapNames))
+	copy(snapsSaved, snapNames)
+	sort.Strings(snapsSaved)
+
+	setID, err = newSnapshotSetID(st)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	ts = state.NewTaskSet()
+	for _, name := range snapsSaved {
+		summary := fmt.Sprintf(i18n.G("Save data of snap %q in snapshot set #%d"), name, setID)
+		ts.AddTask(saveTask(st, summary, setID, name, users))
+	}
+
+	return setID, snapsSaved, ts, nil
+}
+
+// AutomaticSnapshot creates a taskset for taking a snapshot of the data
+// of the given snap, as done when removing it.
+// Note that the state must be locked by the caller.
+func AutomaticSnapshot(st *state.State, snapName string) (*state.TaskSet, error) {
+	setID, err := newSnapshotSetID(st)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := fmt.Sprintf(i18n.G("Save data of snap %q in automatic snapshot set #%d"), snapName, setID)
+	return state.NewTaskSet(saveTask(st, summary, setID, snapName, nil)), nil
+}
+
+// snapshotsInSet returns the filenames of the snapshots in the given set
+// that are for the given snaps (or for all of them, if none are given),
+// keyed by snap name.
+func snapshotsInSet(setID uint64, snapNames []string) (map[string]string, error) {
+	inSet := make(map[string]string)
+	err := backend.Iter(func(rsh *backend.Reader) error {
+		if rsh.SetID == setID {
+			inSet[rsh.Snap] = backend.Filename(&rsh.Snapshot)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inSet) == 0 {
+		return nil, fmt.Errorf("cannot find snapshot set #%d", setID)
+	}
+	if len(snapNames) == 0 {
+		return inSet, nil
+	}
+
+	filenames := make(map[string]string, len(snapNames))
+	for _, name := range snapNames {
+		filename, ok := inSet[name]
+		if !ok {
+			return nil, fmt.Errorf("snapshot set #%d has no snapshot of snap %q", setID, name)
+		}
+		filenames[name] = filename
+	}
+
+	return filenames, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Restore creates a taskset for restoring the data of the given snaps
+// (or of all of them, if none are given) from the given snapshot set,
+// for the given users (or for all of them, if none are given). The data
+// is restored into the current revision of each snap. It returns the
+// names of the snaps that will be restored.
+// Note that the state must be locked by the caller.
+func Restore(st *state.State, setID uint64, snapNames []string, users []string) ([]string, *state.TaskSet, error) {
+	filenames, err := snapshotsInSet(setID, snapNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapsRestored := sortedKeys(filenames)
+
+	ts := state.NewTaskSet()
+	var restoreTasks []*state.Task
+	for _, name := range snapsRestored {
+		info, err := snapstate.CurrentInfo(st, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot restore snapshot of snap %q: %v", name, err)
+		}
+
+		summary := fmt.Sprintf(i18n.G("Restore data of snap %q from snapshot set #%d"), name, setID)
+		task := st.NewTask("restore-snapshot", summary)
+		task.Set("snapshot-setup", &snapshotSetup{
+			SetID:    setID,
+			Snap:     name,
+			Users:    users,
+			Filename: filenames[name],
+			Current:  info.Revision,
+		})
+		ts.AddTask(task)
+		restoreTasks = append(restoreTasks, task)
+	}
+
+	cleanup := st.NewTask("cleanup-after-restore", fmt.Sprintf(i18n.G("Clean up after restoring snapshot set #%d"), setID))
+	for _, task := range restoreTasks {
+		cleanup.WaitFor(task)
+	}
+	ts.AddTask(cleanup)
+
+	return snapsRestored, ts, nil
+}
+
+// Forget creates a taskset for permanently removing the snapshots of the
+// given snaps (or of all of them, if none are given) in the given
+// snapshot set. It returns the names of the snaps whose snapshots will be
+// removed.
+// Note that the state must be locked by the caller.
+func Forget(st *state.State, setID uint64, snapNames []string) ([]string, *state.TaskSet, error) {
+	filenames, err := snapshotsInSet(setID, snapNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapsForgotten := sortedKeys(filenames)
+
+	ts := state.NewTaskSet()
+	for _, name := range snapsForgotten {
+		summary := fmt.Sprintf(i18n.G("Drop data of snap %q from snapshot set #%d"), name, setID)
+		task := st.NewTask("forget-snapshot", summary)
+		task.Set("snapshot-setup", &snapshotSetup{
+			SetID:    setID,
+			Snap:     name,
+			Filename: filenames[name],
+		})
+		ts.AddTask(task)
+	}
+
+	return snapsForgotten, ts, nil
+}
+
+// List returns the snapshot sets on the system, limited to the given set
+// (if non-zero) and to the given snaps (if non-empty).
+func List(setID uint64, snapNames []string) ([]client.SnapshotSet, error) {
+	return backend.List(setID, snapNames)
+}
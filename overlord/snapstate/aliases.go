@@ -0,0 +1,355 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/snapstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Alias enables the given aliases declared by the apps of the snap.
+func Alias(st *state.State, snapName string, aliases []string) (*state.TaskSet, error) {
+	return aliasTaskSet(st, snapName, aliases, "alias", i18n.G("Enable aliases %s for snap %q"))
+}
+
+// Unalias disables the given aliases of the snap.
+func Unalias(st *state.State, snapName string, aliases []string) (*state.TaskSet, error) {
+	return aliasTaskSet(st, snapName, aliases, "unalias", i18n.G("Disable aliases %s for snap %q"))
+}
+
+func aliasTaskSet(st *state.State, snapName string, aliases []string, kind, summary string) (*state.TaskSet, error) {
+	if len(aliases) == 0 {
+		return nil, fmt.Errorf("no aliases given")
+	}
+
+	var snapst SnapState
+	err := Get(st, snapName, &snapst)
+	if err == state.ErrNoState {
+		return nil, fmt.Errorf("cannot find snap %q", snapName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckChangeConflict(st, snapName, nil); err != nil {
+		return nil, err
+	}
+
+	// check upfront so that errors are reported right away, the
+	// task checks again once it runs
+	if kind == "alias" {
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return nil, err
+		}
+		for _, alias := range aliases {
+			if err := checkAlias(st, info, alias); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		for _, alias := range aliases {
+			if !hasAlias(snapst.Aliases, alias) {
+				return nil, fmt.Errorf("cannot disable alias %q for %q, alias is not enabled", alias, snapName)
+			}
+		}
+	}
+
+	ss := minimalSnapSetup(snapName, snapst.Current)
+
+	t := st.NewTask(kind, fmt.Sprintf(summary, strings.Join(aliases, ", "), snapName))
+	t.Set("snap-setup", ss)
+	t.Set("aliases", aliases)
+
+	return state.NewTaskSet(t), nil
+}
+
+// checkAlias checks that the alias is declared by the snap and that
+// it doesn't conflict with other installed snaps.
+func checkAlias(st *state.State, info *snap.Info, alias string) error {
+	snapName := info.Name()
+	if info.Aliases[alias] == nil {
+		return fmt.Errorf("cannot enable alias %q for %q, no such alias", alias, snapName)
+	}
+
+	snapStates, err := All(st)
+	if err != nil {
+		return err
+	}
+	for name, snapst := range snapStates {
+		if alias == name || strings.HasPrefix(alias, name+".") {
+			return fmt.Errorf("cannot enable alias %q for %q, it conflicts with the command namespace of installed snap %q", alias, snapName, name)
+		}
+		if name != snapName && hasAlias(snapst.Aliases, alias) {
+			return fmt.Errorf("cannot enable alias %q for %q, already enabled for %q", alias, snapName, name)
+		}
+	}
+
+	return nil
+}
+
+// checkNamespaceConflict checks that the command namespace of a snap
+// about to be installed doesn't conflict with any enabled alias.
+func checkNamespaceConflict(st *state.State, snapName string) error {
+	snapStates, err := All(st)
+	if err != nil {
+		return err
+	}
+	for name, snapst := range snapStates {
+		if name == snapName {
+			continue
+		}
+		for _, alias := range snapst.Aliases {
+			if alias == snapName || strings.HasPrefix(alias, snapName+".") {
+				return fmt.Errorf("snap %q command namespace conflicts with enabled alias %q for %q", snapName, alias, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// aliasesFor returns the backend aliases for the given alias names,
+// with the wrappers of the apps of the snap they resolve to as targets.
+func aliasesFor(info *snap.Info, names []string) []*backend.Alias {
+	aliases := make([]*backend.Alias, len(names))
+	for i, name := range names {
+		aliases[i] = &backend.Alias{Name: name}
+		if app := info.Aliases[name]; app != nil {
+			aliases[i].Target = filepath.Base(app.WrapperPath())
+		}
+	}
+	return aliases
+}
+
+// declaredAliases returns the subset of the alias names that are
+// still declared by the snap.
+func declaredAliases(info *snap.Info, names []string) []string {
+	var declared []string
+	for _, name := range names {
+		if info.Aliases[name] != nil {
+			declared = append(declared, name)
+		}
+	}
+	return declared
+}
+
+func hasAlias(aliases []string, alias string) bool {
+	for _, a := range aliases {
+		if a == alias {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutAliases(names, remove []string) []string {
+	var left []string
+	for _, name := range names {
+		if !hasAlias(remove, name) {
+			left = append(left, name)
+		}
+	}
+	return left
+}
+
+// addAliases adds the symlinks of the enabled aliases of a snap
+// being linked; it does nothing if there are none.
+func (m *SnapManager) addAliases(info *snap.Info, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return m.backend.UpdateAliases(aliasesFor(info, names), nil)
+}
+
+// removeAliases removes the symlinks of the enabled aliases of a
+// snap being unlinked; it does nothing if there are none.
+func (m *SnapManager) removeAliases(info *snap.Info, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return m.backend.UpdateAliases(nil, aliasesFor(info, names))
+}
+
+func (m *SnapManager) doAlias(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ss, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	var aliases []string
+	if err := t.Get("aliases", &aliases); err != nil {
+		return err
+	}
+
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+
+	var added []string
+	for _, alias := range aliases {
+		if err := checkAlias(st, info, alias); err != nil {
+			return err
+		}
+		if !hasAlias(snapst.Aliases, alias) && !hasAlias(added, alias) {
+			added = append(added, alias)
+		}
+	}
+
+	// the symlinks of a disabled snap are added when it is enabled again
+	if snapst.Active {
+		st.Unlock()
+		err = m.backend.UpdateAliases(aliasesFor(info, added), nil)
+		st.Lock()
+		if err != nil {
+			return err
+		}
+	}
+
+	snapst.Aliases = append(snapst.Aliases, added...)
+	sort.Strings(snapst.Aliases)
+
+	// save for undoAlias
+	t.Set("added-aliases", added)
+	Set(st, ss.Name(), snapst)
+	return nil
+}
+
+func (m *SnapManager) undoAlias(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ss, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	var added []string
+	if err := t.Get("added-aliases", &added); err != nil {
+		return err
+	}
+
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+
+	if snapst.Active {
+		st.Unlock()
+		err = m.backend.UpdateAliases(nil, aliasesFor(info, added))
+		st.Lock()
+		if err != nil {
+			return err
+		}
+	}
+
+	snapst.Aliases = withoutAliases(snapst.Aliases, added)
+	Set(st, ss.Name(), snapst)
+	return nil
+}
+
+func (m *SnapManager) doUnalias(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ss, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	var aliases []string
+	if err := t.Get("aliases", &aliases); err != nil {
+		return err
+	}
+
+	for _, alias := range aliases {
+		if !hasAlias(snapst.Aliases, alias) {
+			return fmt.Errorf("cannot disable alias %q for %q, alias is not enabled", alias, ss.Name())
+		}
+	}
+
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+
+	if snapst.Active {
+		st.Unlock()
+		err = m.backend.UpdateAliases(nil, aliasesFor(info, aliases))
+		st.Lock()
+		if err != nil {
+			return err
+		}
+	}
+
+	snapst.Aliases = withoutAliases(snapst.Aliases, aliases)
+
+	// save for undoUnalias
+	t.Set("removed-aliases", aliases)
+	Set(st, ss.Name(), snapst)
+	return nil
+}
+
+func (m *SnapManager) undoUnalias(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ss, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	var removed []string
+	if err := t.Get("removed-aliases", &removed); err != nil {
+		return err
+	}
+
+	info, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+
+	if snapst.Active {
+		st.Unlock()
+		err = m.backend.UpdateAliases(aliasesFor(info, removed), nil)
+		st.Lock()
+		if err != nil {
+			return err
+		}
+	}
+
+	snapst.Aliases = append(snapst.Aliases, removed...)
+	sort.Strings(snapst.Aliases)
+	Set(st, ss.Name(), snapst)
+	return nil
+}
@@ -0,0 +1,323 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) setAliasSnap(active bool, aliases []string) {
+	snapstate.Set(s.state, "alias-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "alias-snap", Revision: snap.R(11)},
+		},
+		Current: snap.R(11),
+		Active:  active,
+		Aliases: aliases,
+	})
+}
+
+func (s *snapmgrTestSuite) TestAliasTasks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAliasSnap(true, nil)
+
+	ts, err := snapstate.Alias(s.state, "alias-snap", []string{"alias1", "alias2"})
+	c.Assert(err, IsNil)
+
+	c.Assert(ts.Tasks(), HasLen, 1)
+	t := ts.Tasks()[0]
+	c.Check(t.Kind(), Equals, "alias")
+	c.Check(t.Summary(), Equals, `Enable aliases alias1, alias2 for snap "alias-snap"`)
+	var aliases []string
+	c.Assert(t.Get("aliases", &aliases), IsNil)
+	c.Check(aliases, DeepEquals, []string{"alias1", "alias2"})
+}
+
+func (s *snapmgrTestSuite) TestAliasRunThrough(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAliasSnap(true, []string{"alias2"})
+
+	chg := s.state.NewChange("alias", "enable aliases")
+	ts, err := snapstate.Alias(s.state, "alias-snap", []string{"alias1", "alias2"})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.fakeBackend.ops, DeepEquals, fakeOps{
+		{
+			op:      "update-aliases",
+			aliases: []*backend.Alias{{Name: "alias1", Target: "alias-snap.cmd1"}},
+		},
+	})
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "alias-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Aliases, DeepEquals, []string{"alias1", "alias2"})
+}
+
+func (s *snapmgrTestSuite) TestAliasInactiveSnap(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAliasSnap(false, nil)
+
+	chg := s.state.NewChange("alias", "enable aliases")
+	ts, err := snapstate.Alias(s.state, "alias-snap", []string{"alias1"})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.fakeBackend.ops, HasLen, 0)
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "alias-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Aliases, DeepEquals, []string{"alias1"})
+}
+
+func (s *snapmgrTestSuite) TestAliasUndo(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAliasSnap(true, nil)
+
+	chg := s.state.NewChange("alias", "enable aliases")
+	ts, err := snapstate.Alias(s.state, "alias-snap", []string{"alias1"})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	terr := s.state.NewTask("fake-install-snap-error", "fail")
+	terr.WaitAll(ts)
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	aliases := []*backend.Alias{{Name: "alias1", Target: "alias-snap.cmd1"}}
+	c.Check(s.fakeBackend.ops, DeepEquals, fakeOps{
+		{
+			op:      "update-aliases",
+			aliases: aliases,
+		},
+		{
+			op:        "update-aliases",
+			rmAliases: aliases,
+		},
+	})
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "alias-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Aliases, HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestAliasErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAliasSnap(true, nil)
+	snapstate.Set(s.state, "other-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "other-snap", Revision: snap.R(2)},
+		},
+		Current: snap.R(2),
+		Active:  true,
+		Aliases: []string{"alias2"},
+	})
+	snapstate.Set(s.state, "alias1", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "alias1", Revision: snap.R(3)},
+		},
+		Current: snap.R(3),
+		Active:  true,
+	})
+
+	for _, t := range []struct {
+		snap    string
+		aliases []string
+		err     string
+	}{
+		{"alias-snap", nil, `no aliases given`},
+		{"missing-snap", []string{"alias1"}, `cannot find snap "missing-snap"`},
+		{"alias-snap", []string{"alias3"}, `cannot enable alias "alias3" for "alias-snap", no such alias`},
+		{"alias-snap", []string{"alias2"}, `cannot enable alias "alias2" for "alias-snap", already enabled for "other-snap"`},
+		{"alias-snap", []string{"alias1"}, `cannot enable alias "alias1" for "alias-snap", it conflicts with the command namespace of installed snap "alias1"`},
+	} {
+		_, err := snapstate.Alias(s.state, t.snap, t.aliases)
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+func (s *snapmgrTestSuite) TestUnaliasRunThrough(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAliasSnap(true, []string{"alias1", "alias2"})
+
+	chg := s.state.NewChange("unalias", "disable aliases")
+	ts, err := snapstate.Unalias(s.state, "alias-snap", []string{"alias1"})
+	c.Assert(err, IsNil)
+	c.Check(ts.Tasks()[0].Summary(), Equals, `Disable aliases alias1 for snap "alias-snap"`)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.fakeBackend.ops, DeepEquals, fakeOps{
+		{
+			op:        "update-aliases",
+			rmAliases: []*backend.Alias{{Name: "alias1", Target: "alias-snap.cmd1"}},
+		},
+	})
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "alias-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Aliases, DeepEquals, []string{"alias2"})
+}
+
+func (s *snapmgrTestSuite) TestUnaliasUndo(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAliasSnap(true, []string{"alias1"})
+
+	chg := s.state.NewChange("unalias", "disable aliases")
+	ts, err := snapstate.Unalias(s.state, "alias-snap", []string{"alias1"})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	terr := s.state.NewTask("fake-install-snap-error", "fail")
+	terr.WaitAll(ts)
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(s.fakeBackend.ops.Ops(), DeepEquals, []string{"update-aliases", "update-aliases"})
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "alias-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Aliases, DeepEquals, []string{"alias1"})
+}
+
+func (s *snapmgrTestSuite) TestUnaliasNotEnabled(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAliasSnap(true, []string{"alias2"})
+
+	_, err := snapstate.Unalias(s.state, "alias-snap", []string{"alias1"})
+	c.Check(err, ErrorMatches, `cannot disable alias "alias1" for "alias-snap", alias is not enabled`)
+}
+
+func (s *snapmgrTestSuite) TestDisableEnableWithAliases(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAliasSnap(true, []string{"alias1"})
+	aliases := []*backend.Alias{{Name: "alias1", Target: "alias-snap.cmd1"}}
+
+	chg := s.state.NewChange("disable", "disable a snap")
+	ts, err := snapstate.Disable(s.state, "alias-snap")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.fakeBackend.ops, DeepEquals, fakeOps{
+		{
+			op:         "stop-snap-services",
+			name:       "/snap/alias-snap/11",
+			stopReason: snap.StopReasonDisable,
+		},
+		{
+			op:        "update-aliases",
+			rmAliases: aliases,
+		},
+		{
+			op:   "unlink-snap",
+			name: "/snap/alias-snap/11",
+		},
+	})
+	s.fakeBackend.ops = nil
+
+	chg = s.state.NewChange("enable", "enable a snap")
+	ts, err = snapstate.Enable(s.state, "alias-snap")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.fakeBackend.ops.Ops(), DeepEquals, []string{"candidate", "link-snap", "update-aliases", "start-snap-services"})
+	c.Check(s.fakeBackend.ops.First("update-aliases").aliases, DeepEquals, aliases)
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "alias-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Aliases, DeepEquals, []string{"alias1"})
+}
+
+func (s *snapmgrTestSuite) TestInstallNamespaceConflictsWithAlias(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setAliasSnap(true, []string{"alias1"})
+
+	_, err := snapstate.InstallPath(s.state, &snap.SideInfo{RealName: "alias1"}, "some-path", "", 0)
+	c.Check(err, ErrorMatches, `snap "alias1" command namespace conflicts with enabled alias "alias1" for "alias-snap"`)
+}
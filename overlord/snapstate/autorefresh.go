@@ -0,0 +1,311 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timeutil"
+)
+
+// the default refresh pattern
+const defaultRefreshSchedule = "00:00-04:59/05:00-10:59/11:00-16:59/17:00-23:59"
+
+var timeNow = time.Now
+
+// CoreConfig allows to hook reading the configuration of the core snap
+// (e.g. "refresh.schedule") into snapstate. Unset options leave result
+// untouched.
+var CoreConfig func(st *state.State, key string, result interface{}) error
+
+// CanAutoRefresh allows to hook into snapstate whether the system is in
+// a position to auto-refresh snaps (e.g. it is seeded and has a serial).
+// If unset auto-refresh never happens.
+var CanAutoRefresh func(st *state.State) (bool, error)
+
+// autoRefresh will ensure that snaps are refreshed automatically
+// according to the refresh schedule.
+type autoRefresh struct {
+	state *state.State
+
+	lastRefreshSchedule string
+	nextRefresh         time.Time
+}
+
+func newAutoRefresh(st *state.State) *autoRefresh {
+	return &autoRefresh{
+		state: st,
+	}
+}
+
+// RefreshSchedule returns the refresh schedule currently in effect.
+// Note that the state must be locked by the caller.
+func RefreshSchedule(st *state.State) (string, error) {
+	_, scheduleStr, err := refreshScheduleWithDefaultsFallback(st)
+	return scheduleStr, err
+}
+
+// LastRefresh returns the time of the last auto-refresh attempt, or
+// the zero time if there was none.
+// Note that the state must be locked by the caller.
+func LastRefresh(st *state.State) (time.Time, error) {
+	var lastRefresh time.Time
+	err := st.Get("last-refresh", &lastRefresh)
+	if err != nil && err != state.ErrNoState {
+		return time.Time{}, err
+	}
+	return lastRefresh, nil
+}
+
+// NextRefresh returns the time of the next scheduled auto-refresh, or
+// the zero time if none is scheduled.
+// Note that the state must be locked by the caller.
+func NextRefresh(st *state.State) (time.Time, error) {
+	var nextRefresh time.Time
+	err := st.Get("next-refresh", &nextRefresh)
+	if err != nil && err != state.ErrNoState {
+		return time.Time{}, err
+	}
+	return nextRefresh, nil
+}
+
+func refreshScheduleWithDefaultsFallback(st *state.State) ([]*timeutil.Schedule, string, error) {
+	var scheduleStr string
+	if CoreConfig != nil {
+		if err := CoreConfig(st, "refresh.schedule", &scheduleStr); err != nil {
+			return nil, "", err
+		}
+	}
+	if scheduleStr == "" {
+		scheduleStr = defaultRefreshSchedule
+	}
+
+	refreshSchedule, err := timeutil.ParseSchedule(scheduleStr)
+	if err != nil {
+		logger.Noticef("cannot use refresh.schedule configuration: %s", err)
+		scheduleStr = defaultRefreshSchedule
+		refreshSchedule, err = timeutil.ParseSchedule(scheduleStr)
+		if err != nil {
+			panic(fmt.Sprintf("defaultRefreshSchedule cannot be parsed: %s", err))
+		}
+	}
+
+	return refreshSchedule, scheduleStr, nil
+}
+
+// Ensure ensures that we refresh all installed snaps periodically
+func (m *autoRefresh) Ensure() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	if CanAutoRefresh == nil {
+		return nil
+	}
+	if ok, err := CanAutoRefresh(m.state); err != nil || !ok {
+		return err
+	}
+
+	// don't auto-refresh while another auto-refresh is in motion
+	for _, chg := range m.state.Changes() {
+		if chg.Kind() == "auto-refresh" && !chg.Status().Ready() {
+			return nil
+		}
+	}
+
+	refreshSchedule, scheduleStr, err := refreshScheduleWithDefaultsFallback(m.state)
+	if err != nil {
+		return err
+	}
+
+	// compute the next refresh if there is none yet or the
+	// schedule was changed
+	if m.nextRefresh.IsZero() || m.lastRefreshSchedule != scheduleStr {
+		lastRefresh, err := LastRefresh(m.state)
+		if err != nil {
+			return err
+		}
+		m.nextRefresh = timeNow().Add(timeutil.Next(refreshSchedule, lastRefresh))
+		m.lastRefreshSchedule = scheduleStr
+		m.state.Set("next-refresh", m.nextRefresh)
+		logger.Debugf("Next refresh scheduled for %s.", m.nextRefresh)
+	}
+
+	if timeNow().Before(m.nextRefresh) {
+		// snaps that reached an intermediate epoch continue their
+		// multi-step refresh in the current window of the schedule
+		// instead of waiting for the next refresh of all snaps
+		if !inRefreshWindow(refreshSchedule, timeNow()) {
+			return nil
+		}
+		stepping, err := epochStepsPending(m.state)
+		if err != nil || len(stepping) == 0 {
+			return err
+		}
+		return m.launchAutoRefresh(stepping)
+	}
+
+	// the next refresh gets computed relative to this attempt on
+	// the next Ensure, even if it fails
+	m.nextRefresh = time.Time{}
+	m.state.Set("last-refresh", timeNow())
+
+	return m.launchAutoRefresh(nil)
+}
+
+// inRefreshWindow returns whether the given time falls into one of the
+// windows of the refresh schedule.
+func inRefreshWindow(refreshSchedule []*timeutil.Schedule, t time.Time) bool {
+	for _, sched := range refreshSchedule {
+		if start, _ := sched.Next(t); !start.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// epochStepsPending returns the sorted names of the snaps at a revision
+// with an intermediate ("N*") epoch that was not yet followed by a
+// refresh. Snaps whose refreshes are held are left out.
+func epochStepsPending(st *state.State) ([]string, error) {
+	var stepped map[string]snap.Revision
+	err := st.Get("epoch-steps", &stepped)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+
+	snapStates, err := All(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for name, snapst := range snapStates {
+		if !snapst.Active || snapst.Held() {
+			continue
+		}
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			continue
+		}
+		if !strings.HasSuffix(info.Epoch, "*") {
+			continue
+		}
+		if stepped[name] != info.Revision {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+
+	return pending, nil
+}
+
+// markEpochSteps records that the given snaps got refreshed from their
+// current revision, so that each intermediate epoch revision is
+// followed by a single refresh attempt.
+func markEpochSteps(st *state.State, names []string) error {
+	var stepped map[string]snap.Revision
+	err := st.Get("epoch-steps", &stepped)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	snapStates, err := All(st)
+	if err != nil {
+		return err
+	}
+
+	newStepped := make(map[string]snap.Revision, len(names))
+	for name, rev := range stepped {
+		// forget about snaps that are gone
+		if _, ok := snapStates[name]; ok {
+			newStepped[name] = rev
+		}
+	}
+	for _, name := range names {
+		newStepped[name] = snapStates[name].Current
+	}
+	st.Set("epoch-steps", newStepped)
+
+	return nil
+}
+
+// launchAutoRefresh creates the auto-refresh change for the given
+// snaps, or for all of them if names is empty
+func (m *autoRefresh) launchAutoRefresh(names []string) error {
+	snapStates, err := All(m.state)
+	if err != nil {
+		return err
+	}
+	if len(snapStates) == 0 {
+		// nothing to refresh
+		return nil
+	}
+
+	// the pending epoch steps are taken care of by this refresh,
+	// whether it finds an update for them or not
+	stepping := names
+	if len(names) == 0 {
+		stepping, err = epochStepsPending(m.state)
+		if err != nil {
+			return err
+		}
+	}
+	if len(stepping) > 0 {
+		if err := markEpochSteps(m.state, stepping); err != nil {
+			return err
+		}
+	}
+
+	updated, tasksets, err := UpdateMany(m.state, names, 0)
+	if err != nil {
+		return fmt.Errorf("cannot prepare auto-refresh change: %s", err)
+	}
+	if len(updated) == 0 {
+		return nil
+	}
+
+	var msg string
+	switch len(updated) {
+	case 1:
+		msg = fmt.Sprintf(i18n.G("Auto-refresh snap %q"), updated[0])
+	default:
+		quoted := make([]string, len(updated))
+		for i, name := range updated {
+			quoted[i] = strconv.Quote(name)
+		}
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = fmt.Sprintf(i18n.G("Auto-refresh snaps %s"), strings.Join(quoted, ", "))
+	}
+
+	chg := m.state.NewChange("auto-refresh", msg)
+	for _, ts := range tasksets {
+		chg.AddAll(ts)
+	}
+	chg.Set("snap-names", updated)
+
+	return nil
+}
@@ -0,0 +1,342 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"errors"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+type autoRefreshTestSuite struct {
+	state *state.State
+
+	fakeBackend *fakeSnappyBackend
+	fakeStore   *fakeStore
+
+	now time.Time
+
+	reset func()
+}
+
+var _ = Suite(&autoRefreshTestSuite{})
+
+func (s *autoRefreshTestSuite) SetUpTest(c *C) {
+	s.fakeBackend = &fakeSnappyBackend{}
+	s.state = state.New(nil)
+	s.fakeStore = &fakeStore{
+		fakeBackend: s.fakeBackend,
+		state:       s.state,
+	}
+
+	s.now = time.Date(2017, 2, 6, 12, 0, 0, 0, time.Local)
+	restore1 := snapstate.MockTimeNow(func() time.Time { return s.now })
+	restore2 := snapstate.MockReadInfo(s.fakeBackend.ReadInfo)
+	s.reset = func() {
+		restore2()
+		restore1()
+	}
+
+	snapstate.CanAutoRefresh = func(*state.State) (bool, error) {
+		return true, nil
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	snapstate.ReplaceStore(s.state, s.fakeStore)
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(5)},
+		},
+		Current: snap.R(5),
+	})
+}
+
+func (s *autoRefreshTestSuite) TearDownTest(c *C) {
+	snapstate.CanAutoRefresh = nil
+	snapstate.CoreConfig = nil
+	s.reset()
+}
+
+func (s *autoRefreshTestSuite) TestCannotAutoRefresh(c *C) {
+	for _, canAutoRefresh := range []func(*state.State) (bool, error){
+		nil,
+		func(*state.State) (bool, error) { return false, nil },
+	} {
+		snapstate.CanAutoRefresh = canAutoRefresh
+
+		af := snapstate.NewAutoRefresh(s.state)
+		err := af.Ensure()
+		c.Assert(err, IsNil)
+
+		s.state.Lock()
+		next, err := snapstate.NextRefresh(s.state)
+		c.Assert(err, IsNil)
+		c.Check(next.IsZero(), Equals, true)
+		c.Check(s.state.Changes(), HasLen, 0)
+		s.state.Unlock()
+	}
+}
+
+func (s *autoRefreshTestSuite) TestCanAutoRefreshError(c *C) {
+	snapstate.CanAutoRefresh = func(*state.State) (bool, error) {
+		return false, errors.New("boom")
+	}
+
+	af := snapstate.NewAutoRefresh(s.state)
+	err := af.Ensure()
+	c.Assert(err, ErrorMatches, "boom")
+}
+
+func (s *autoRefreshTestSuite) TestLastRefreshRefreshesWhenDue(c *C) {
+	af := snapstate.NewAutoRefresh(s.state)
+	err := af.Ensure()
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// never refreshed before, refresh right away
+	lastRefresh, err := snapstate.LastRefresh(s.state)
+	c.Assert(err, IsNil)
+	c.Check(lastRefresh.Equal(s.now), Equals, true)
+
+	chgs := s.state.Changes()
+	c.Assert(chgs, HasLen, 1)
+	chg := chgs[0]
+	c.Check(chg.Kind(), Equals, "auto-refresh")
+	c.Check(chg.Summary(), Equals, `Auto-refresh snap "some-snap"`)
+	var snapNames []string
+	err = chg.Get("snap-names", &snapNames)
+	c.Assert(err, IsNil)
+	c.Check(snapNames, DeepEquals, []string{"some-snap"})
+
+	// no new auto-refresh while one is in progress
+	s.state.Unlock()
+	err = af.Ensure()
+	s.state.Lock()
+	c.Assert(err, IsNil)
+	c.Check(s.state.Changes(), HasLen, 1)
+}
+
+func (s *autoRefreshTestSuite) TestNextRefreshScheduled(c *C) {
+	s.state.Lock()
+	lastRefresh := s.now.Add(-time.Hour)
+	s.state.Set("last-refresh", lastRefresh)
+	s.state.Unlock()
+
+	af := snapstate.NewAutoRefresh(s.state)
+	err := af.Ensure()
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the default schedule windows are 11:00-16:59 and 17:00-23:59
+	// around now, the last refresh happened in the current one
+	next, err := snapstate.NextRefresh(s.state)
+	c.Assert(err, IsNil)
+	c.Check(next.Before(time.Date(2017, 2, 6, 17, 0, 0, 0, time.Local)), Equals, false)
+	c.Check(next.Before(time.Date(2017, 2, 7, 0, 0, 0, 0, time.Local)), Equals, true)
+
+	last, err := snapstate.LastRefresh(s.state)
+	c.Assert(err, IsNil)
+	c.Check(last.Equal(lastRefresh), Equals, true)
+	c.Check(s.state.Changes(), HasLen, 0)
+
+	// once due the refresh happens
+	s.now = next
+	s.state.Unlock()
+	err = af.Ensure()
+	s.state.Lock()
+	c.Assert(err, IsNil)
+	c.Check(s.state.Changes(), HasLen, 1)
+	last, err = snapstate.LastRefresh(s.state)
+	c.Assert(err, IsNil)
+	c.Check(last.Equal(next), Equals, true)
+}
+
+func (s *autoRefreshTestSuite) mockEpochSnap(c *C, hold *snapstate.RefreshHold) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("last-refresh", s.now.Add(-time.Hour))
+	snapstate.Set(s.state, "some-epoch-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-epoch-snap", SnapID: "some-epoch-snap-id", Revision: snap.R(3)},
+		},
+		Current: snap.R(3),
+		Hold:    hold,
+	})
+}
+
+func (s *autoRefreshTestSuite) TestEpochStepRefreshesOnlyThatSnap(c *C) {
+	s.mockEpochSnap(c, nil)
+
+	af := snapstate.NewAutoRefresh(s.state)
+	err := af.Ensure()
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	// the snap is at an intermediate epoch, it gets refreshed in the
+	// current window of the schedule without refreshing all snaps
+	last, err := snapstate.LastRefresh(s.state)
+	c.Assert(err, IsNil)
+	c.Check(last.Equal(s.now.Add(-time.Hour)), Equals, true)
+	c.Assert(s.state.Changes(), HasLen, 1)
+	chg := s.state.Changes()[0]
+	c.Check(chg.Kind(), Equals, "auto-refresh")
+	var snapNames []string
+	err = chg.Get("snap-names", &snapNames)
+	c.Assert(err, IsNil)
+	c.Check(snapNames, DeepEquals, []string{"some-epoch-snap"})
+	chg.SetStatus(state.DoneStatus)
+	s.state.Unlock()
+
+	// but only once for the same revision
+	s.now = s.now.Add(5 * time.Minute)
+	err = af.Ensure()
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 1)
+}
+
+func (s *autoRefreshTestSuite) TestEpochStepWaitsForTheSchedule(c *C) {
+	snapstate.CoreConfig = func(st *state.State, key string, result interface{}) error {
+		*result.(*string) = "13:00-14:00"
+		return nil
+	}
+	s.mockEpochSnap(c, nil)
+
+	af := snapstate.NewAutoRefresh(s.state)
+	err := af.Ensure()
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 0)
+	s.state.Unlock()
+
+	// once in a window of the schedule the step happens
+	s.now = time.Date(2017, 2, 6, 13, 0, 0, 0, time.Local)
+	err = af.Ensure()
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 1)
+}
+
+func (s *autoRefreshTestSuite) TestEpochStepRespectsHolds(c *C) {
+	s.mockEpochSnap(c, &snapstate.RefreshHold{})
+
+	af := snapstate.NewAutoRefresh(s.state)
+	err := af.Ensure()
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+}
+
+func (s *autoRefreshTestSuite) TestNoUpdates(c *C) {
+	s.state.Lock()
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(11)},
+		},
+		Current: snap.R(11),
+	})
+	s.state.Unlock()
+
+	af := snapstate.NewAutoRefresh(s.state)
+	err := af.Ensure()
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+	lastRefresh, err := snapstate.LastRefresh(s.state)
+	c.Assert(err, IsNil)
+	c.Check(lastRefresh.Equal(s.now), Equals, true)
+}
+
+func (s *autoRefreshTestSuite) TestRefreshSchedule(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	schedule, err := snapstate.RefreshSchedule(s.state)
+	c.Assert(err, IsNil)
+	c.Check(schedule, Equals, "00:00-04:59/05:00-10:59/11:00-16:59/17:00-23:59")
+
+	var configured string
+	snapstate.CoreConfig = func(st *state.State, key string, result interface{}) error {
+		c.Check(key, Equals, "refresh.schedule")
+		*result.(*string) = configured
+		return nil
+	}
+
+	configured = "mon,10:00-12:00"
+	schedule, err = snapstate.RefreshSchedule(s.state)
+	c.Assert(err, IsNil)
+	c.Check(schedule, Equals, "mon,10:00-12:00")
+
+	// invalid schedules fall back to the default
+	configured = "invalid"
+	schedule, err = snapstate.RefreshSchedule(s.state)
+	c.Assert(err, IsNil)
+	c.Check(schedule, Equals, "00:00-04:59/05:00-10:59/11:00-16:59/17:00-23:59")
+}
+
+func (s *autoRefreshTestSuite) TestScheduleChangeReschedules(c *C) {
+	s.state.Lock()
+	s.state.Set("last-refresh", s.now.Add(-time.Hour))
+	s.state.Unlock()
+
+	configured := ""
+	snapstate.CoreConfig = func(st *state.State, key string, result interface{}) error {
+		*result.(*string) = configured
+		return nil
+	}
+
+	af := snapstate.NewAutoRefresh(s.state)
+	err := af.Ensure()
+	c.Assert(err, IsNil)
+
+	// 2017-02-06 is a monday, the next window is on the following one
+	configured = "mon,10:00-12:00"
+	err = af.Ensure()
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	next, err := snapstate.NextRefresh(s.state)
+	c.Assert(err, IsNil)
+	c.Check(next.Before(time.Date(2017, 2, 13, 10, 0, 0, 0, time.Local)), Equals, false)
+	c.Check(next.Before(time.Date(2017, 2, 13, 12, 0, 0, 0, time.Local)), Equals, true)
+}
@@ -22,6 +22,7 @@ package snapstate
 import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
@@ -33,7 +34,7 @@ type StoreService interface {
 	Find(search *store.Search, user *auth.UserState) ([]*snap.Info, error)
 	ListRefresh([]*store.RefreshCandidate, *auth.UserState) ([]*snap.Info, error)
 
-	Download(string, *snap.DownloadInfo, progress.Meter, *auth.UserState) (string, error)
+	Download(name, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState) error
 
 	Assertion(assertType *asserts.AssertionType, primaryKey []string, user *auth.UserState) (asserts.Assertion, error)
 
@@ -46,11 +47,12 @@ type StoreService interface {
 
 type managerBackend interface {
 	// install releated
-	SetupSnap(snapFilePath string, si *snap.SideInfo, meter progress.Meter) error
+	SetupSnap(snapFilePath string, si *snap.SideInfo, instanceKey string, meter progress.Meter) error
 	CopySnapData(newSnap, oldSnap *snap.Info, meter progress.Meter) error
 	LinkSnap(info *snap.Info) error
-	StartSnapServices(info *snap.Info, meter progress.Meter) error
-	StopSnapServices(info *snap.Info, meter progress.Meter) error
+	StartSnapServices(info *snap.Info, disabled []string, meter progress.Meter) error
+	StopSnapServices(info *snap.Info, reason snap.ServiceStopReason, meter progress.Meter) error
+	DisabledSnapServices(info *snap.Info, meter progress.Meter) ([]string, error)
 
 	// the undoers for install
 	UndoSetupSnap(s snap.PlaceInfo, typ snap.Type, meter progress.Meter) error
@@ -65,6 +67,9 @@ type managerBackend interface {
 	RemoveSnapCommonData(info *snap.Info) error
 	DiscardSnapNamespace(snapName string) error
 
+	// alias related
+	UpdateAliases(add []*backend.Alias, remove []*backend.Alias) error
+
 	// testing helpers
 	CurrentInfo(cur *snap.Info)
 	Candidate(sideInfo *snap.SideInfo)
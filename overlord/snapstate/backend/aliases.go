@@ -0,0 +1,75 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+)
+
+// Alias represents a command alias with a name in /snap/bin and the
+// name of the target wrapper it resolves to.
+type Alias struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+}
+
+// UpdateAliases adds the symlinks in /snap/bin for the add aliases
+// and removes the ones for the remove aliases. If adding fails the
+// symlinks added so far are removed again.
+func (b Backend) UpdateAliases(add []*Alias, remove []*Alias) error {
+	for _, alias := range remove {
+		p := filepath.Join(dirs.SnapBinariesDir, alias.Name)
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove alias symlink: %v", err)
+		}
+	}
+
+	if len(add) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dirs.SnapBinariesDir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory for alias symlinks: %v", err)
+	}
+	for i, alias := range add {
+		p := filepath.Join(dirs.SnapBinariesDir, alias.Name)
+		err := os.Symlink(alias.Target, p)
+		if os.IsExist(err) {
+			if target, rerr := os.Readlink(p); rerr == nil && target == alias.Target {
+				// already in place
+				continue
+			}
+		}
+		if err != nil {
+			for _, added := range add[:i] {
+				p := filepath.Join(dirs.SnapBinariesDir, added.Name)
+				if err := os.Remove(p); err != nil {
+					logger.Noticef("Cannot remove alias symlink %q: %v", p, err)
+				}
+			}
+			return fmt.Errorf("cannot create alias symlink: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/snapstate/backend"
+)
+
+type aliasesSuite struct {
+	be backend.Backend
+}
+
+var _ = Suite(&aliasesSuite{})
+
+func (s *aliasesSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+}
+
+func (s *aliasesSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
+
+func (s *aliasesSuite) TestUpdateAliasesAdd(c *C) {
+	err := s.be.UpdateAliases([]*backend.Alias{
+		{Name: "foo", Target: "foo.foo"},
+		{Name: "bar", Target: "foo.bar"},
+	}, nil)
+	c.Assert(err, IsNil)
+
+	for name, target := range map[string]string{"foo": "foo.foo", "bar": "foo.bar"} {
+		got, err := os.Readlink(filepath.Join(dirs.SnapBinariesDir, name))
+		c.Assert(err, IsNil)
+		c.Check(got, Equals, target)
+	}
+
+	// adding again is fine
+	err = s.be.UpdateAliases([]*backend.Alias{{Name: "foo", Target: "foo.foo"}}, nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *aliasesSuite) TestUpdateAliasesRemove(c *C) {
+	err := s.be.UpdateAliases([]*backend.Alias{{Name: "foo", Target: "foo.foo"}}, nil)
+	c.Assert(err, IsNil)
+
+	err = s.be.UpdateAliases(nil, []*backend.Alias{
+		{Name: "foo", Target: "foo.foo"},
+		{Name: "missing", Target: "foo.missing"},
+	})
+	c.Assert(err, IsNil)
+	_, err = os.Lstat(filepath.Join(dirs.SnapBinariesDir, "foo"))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *aliasesSuite) TestUpdateAliasesAddFailureCleansUp(c *C) {
+	c.Assert(os.MkdirAll(dirs.SnapBinariesDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapBinariesDir, "bar"), nil, 0644), IsNil)
+
+	err := s.be.UpdateAliases([]*backend.Alias{
+		{Name: "foo", Target: "foo.foo"},
+		{Name: "bar", Target: "foo.bar"},
+	}, nil)
+	c.Assert(err, ErrorMatches, "cannot create alias symlink: .*")
+	_, err = os.Lstat(filepath.Join(dirs.SnapBinariesDir, "foo"))
+	c.Check(os.IsNotExist(err), Equals, true)
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapBinariesDir, "bar")), Equals, true)
+}
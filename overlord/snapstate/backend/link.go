@@ -70,12 +70,16 @@ func (b Backend) LinkSnap(info *snap.Info) error {
 	return updateCurrentSymlinks(info)
 }
 
-func (b Backend) StartSnapServices(info *snap.Info, meter progress.Meter) error {
-	return wrappers.StartSnapServices(info, meter)
+func (b Backend) StartSnapServices(info *snap.Info, disabled []string, meter progress.Meter) error {
+	return wrappers.StartSnapServices(info, disabled, meter)
 }
 
-func (b Backend) StopSnapServices(info *snap.Info, meter progress.Meter) error {
-	return wrappers.StopSnapServices(info, meter)
+func (b Backend) StopSnapServices(info *snap.Info, reason snap.ServiceStopReason, meter progress.Meter) error {
+	return wrappers.StopSnapServices(info, reason, meter)
+}
+
+func (b Backend) DisabledSnapServices(info *snap.Info, meter progress.Meter) ([]string, error) {
+	return wrappers.DisabledSnapServices(info, meter)
 }
 
 func generateWrappers(s *snap.Info) error {
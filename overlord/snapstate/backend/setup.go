@@ -29,14 +29,16 @@ import (
 	"github.com/snapcore/snapd/snap"
 )
 
-// SetupSnap does prepare and mount the snap for further processing.
-func (b Backend) SetupSnap(snapFilePath string, sideInfo *snap.SideInfo, meter progress.Meter) error {
+// SetupSnap does prepare and mount the snap for further processing,
+// as the instance with the given instance key if it is not empty.
+func (b Backend) SetupSnap(snapFilePath string, sideInfo *snap.SideInfo, instanceKey string, meter progress.Meter) error {
 	// This assumes that the snap was already verified or --dangerous was used.
 
 	s, snapf, err := OpenSnapFile(snapFilePath, sideInfo)
 	if err != nil {
 		return err
 	}
+	s.InstanceKey = instanceKey
 	instdir := s.MountDir()
 
 	if err := os.MkdirAll(instdir, 0755); err != nil {
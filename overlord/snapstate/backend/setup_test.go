@@ -75,7 +75,7 @@ func (s *setupSuite) TestSetupDoUndoSimple(c *C) {
 		Revision: snap.R(14),
 	}
 
-	err := s.be.SetupSnap(snapPath, &si, &s.nullProgress)
+	err := s.be.SetupSnap(snapPath, &si, "", &s.nullProgress)
 	c.Assert(err, IsNil)
 
 	// after setup the snap file is in the right dir
@@ -128,7 +128,7 @@ type: kernel
 		Revision: snap.R(140),
 	}
 
-	err := s.be.SetupSnap(snapPath, &si, &s.nullProgress)
+	err := s.be.SetupSnap(snapPath, &si, "", &s.nullProgress)
 	c.Assert(err, IsNil)
 	l, _ := filepath.Glob(filepath.Join(bootloader.Dir(), "*"))
 	c.Assert(l, HasLen, 1)
@@ -172,11 +172,11 @@ type: kernel
 		Revision: snap.R(140),
 	}
 
-	err := s.be.SetupSnap(snapPath, &si, &s.nullProgress)
+	err := s.be.SetupSnap(snapPath, &si, "", &s.nullProgress)
 	c.Assert(err, IsNil)
 
 	// retry run
-	err = s.be.SetupSnap(snapPath, &si, &s.nullProgress)
+	err = s.be.SetupSnap(snapPath, &si, "", &s.nullProgress)
 	c.Assert(err, IsNil)
 
 	minInfo := snap.MinimalPlaceInfo("kernel", snap.R(140))
@@ -221,7 +221,7 @@ type: kernel
 		Revision: snap.R(140),
 	}
 
-	err := s.be.SetupSnap(snapPath, &si, &s.nullProgress)
+	err := s.be.SetupSnap(snapPath, &si, "", &s.nullProgress)
 	c.Assert(err, IsNil)
 
 	minInfo := snap.MinimalPlaceInfo("kernel", snap.R(140))
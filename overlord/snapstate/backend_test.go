@@ -27,6 +27,7 @@ import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
@@ -43,6 +44,12 @@ type fakeOp struct {
 	cand  store.RefreshCandidate
 
 	old string
+
+	stopReason       snap.ServiceStopReason
+	disabledServices []string
+
+	aliases   []*backend.Alias
+	rmAliases []*backend.Alias
 }
 
 type fakeOps []fakeOp
@@ -79,6 +86,7 @@ func (ops fakeOps) First(op string) *fakeOp {
 type fakeDownload struct {
 	name     string
 	macaroon string
+	target   string
 }
 
 type fakeStore struct {
@@ -87,6 +95,8 @@ type fakeStore struct {
 	fakeCurrentProgress int
 	fakeTotalProgress   int
 	state               *state.State
+	// listRefreshErrs maps channels to errors ListRefresh fails with
+	listRefreshErrs map[string]error
 }
 
 func (f *fakeStore) pokeStateLock() {
@@ -99,6 +109,10 @@ func (f *fakeStore) pokeStateLock() {
 func (f *fakeStore) Snap(name, channel string, devmode bool, revision snap.Revision, user *auth.UserState) (*snap.Info, error) {
 	f.pokeStateLock()
 
+	if channel == "closed/candidate" {
+		return nil, store.ErrSnapNotFound
+	}
+
 	if revision.Unset() {
 		revision = snap.R(11)
 		if channel == "channel-for-7" {
@@ -106,6 +120,14 @@ func (f *fakeStore) Snap(name, channel string, devmode bool, revision snap.Revis
 		}
 	}
 
+	typ := snap.TypeApp
+	if name == "some-gadget" {
+		typ = snap.TypeGadget
+	}
+	if name == "some-base" {
+		typ = snap.TypeBase
+	}
+
 	info := &snap.Info{
 		SideInfo: snap.SideInfo{
 			RealName: strings.Split(name, ".")[0],
@@ -114,6 +136,7 @@ func (f *fakeStore) Snap(name, channel string, devmode bool, revision snap.Revis
 			Revision: revision,
 		},
 		Version: name,
+		Type:    typ,
 		DownloadInfo: snap.DownloadInfo{
 			DownloadURL: "https://some-server.com/some/path.snap",
 		},
@@ -137,6 +160,9 @@ func (f *fakeStore) ListRefresh(cands []*store.RefreshCandidate, _ *auth.UserSta
 		panic("ListRefresh unexpectedly called with more than one candidate")
 	}
 	cand := cands[0]
+	if err := f.listRefreshErrs[cand.Channel]; err != nil {
+		return nil, err
+	}
 
 	snapID := cand.SnapID
 
@@ -145,9 +171,14 @@ func (f *fakeStore) ListRefresh(cands []*store.RefreshCandidate, _ *auth.UserSta
 	}
 
 	var name string
-	if snapID == "some-snap-id" {
+	switch snapID {
+	case "some-snap-id":
 		name = "some-snap"
-	} else {
+	case "some-epoch-snap-id":
+		name = "some-epoch-snap"
+	case "services-snap-id":
+		name = "services-snap"
+	default:
 		panic(fmt.Sprintf("ListRefresh: unknown snap-id: %s", snapID))
 	}
 
@@ -155,6 +186,18 @@ func (f *fakeStore) ListRefresh(cands []*store.RefreshCandidate, _ *auth.UserSta
 	if cand.Channel == "channel-for-7" {
 		revno = snap.R(7)
 	}
+	if cand.Channel == "closed/candidate" {
+		// closed channel, nothing to refresh to
+		revno = cand.Revision
+	}
+	epoch := ""
+	if cand.Channel == "channel-for-epoch-2" {
+		epoch = "2"
+	}
+	if name == "some-epoch-snap" {
+		// the next step after the intermediate epoch 1*
+		epoch = "1"
+	}
 
 	info := &snap.Info{
 		SideInfo: snap.SideInfo{
@@ -164,6 +207,7 @@ func (f *fakeStore) ListRefresh(cands []*store.RefreshCandidate, _ *auth.UserSta
 			Revision: revno,
 		},
 		Version: name,
+		Epoch:   epoch,
 		DownloadInfo: snap.DownloadInfo{
 			DownloadURL: "https://some-server.com/some/path.snap",
 		},
@@ -195,7 +239,7 @@ func (f *fakeStore) SuggestedCurrency() string {
 	return "XTS"
 }
 
-func (f *fakeStore) Download(name string, snapInfo *snap.DownloadInfo, pb progress.Meter, user *auth.UserState) (string, error) {
+func (f *fakeStore) Download(name, targetFn string, snapInfo *snap.DownloadInfo, pb progress.Meter, user *auth.UserState) error {
 	f.pokeStateLock()
 
 	var macaroon string
@@ -205,13 +249,14 @@ func (f *fakeStore) Download(name string, snapInfo *snap.DownloadInfo, pb progre
 	f.downloads = append(f.downloads, fakeDownload{
 		macaroon: macaroon,
 		name:     name,
+		target:   targetFn,
 	})
 	f.fakeBackend.ops = append(f.fakeBackend.ops, fakeOp{op: "storesvc-download", name: name})
 
 	pb.SetTotal(float64(f.fakeTotalProgress))
 	pb.Set(float64(f.fakeCurrentProgress))
 
-	return "downloaded-snap-path", nil
+	return nil
 }
 
 func (f *fakeStore) Buy(options *store.BuyOptions, user *auth.UserState) (*store.BuyResult, error) {
@@ -235,6 +280,8 @@ type fakeSnappyBackend struct {
 
 	linkSnapFailTrigger     string
 	copySnapDataFailTrigger string
+
+	disabledServices []string
 }
 
 func (f *fakeSnappyBackend) OpenSnapFile(snapFilePath string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
@@ -251,7 +298,7 @@ func (f *fakeSnappyBackend) OpenSnapFile(snapFilePath string, si *snap.SideInfo)
 	return &snap.Info{Architectures: []string{"all"}}, nil, nil
 }
 
-func (f *fakeSnappyBackend) SetupSnap(snapFilePath string, si *snap.SideInfo, p progress.Meter) error {
+func (f *fakeSnappyBackend) SetupSnap(snapFilePath string, si *snap.SideInfo, instanceKey string, p progress.Meter) error {
 	p.Notify("setup-snap")
 	revno := snap.R(0)
 	if si != nil {
@@ -270,7 +317,8 @@ func (f *fakeSnappyBackend) ReadInfo(name string, si *snap.SideInfo) (*snap.Info
 		return nil, errors.New(`cannot read info for "borken" snap`)
 	}
 	// naive emulation for now, always works
-	info := &snap.Info{SuggestedName: name, SideInfo: *si}
+	snapName, instanceKey := snap.SplitInstanceName(name)
+	info := &snap.Info{SuggestedName: snapName, SideInfo: *si, InstanceKey: instanceKey}
 	info.Type = snap.TypeApp
 	if name == "gadget" {
 		info.Type = snap.TypeGadget
@@ -278,6 +326,34 @@ func (f *fakeSnappyBackend) ReadInfo(name string, si *snap.SideInfo) (*snap.Info
 	if name == "core" {
 		info.Type = snap.TypeOS
 	}
+	if name == "some-base" {
+		info.Type = snap.TypeBase
+	}
+	if name == "some-snap-with-base" {
+		info.Base = "some-base"
+	}
+	if name == "some-snap-with-app-base" {
+		info.Base = "some-other-snap"
+	}
+	if name == "some-epoch-snap" {
+		info.Epoch = "1*"
+	}
+	if name == "services-snap" {
+		info.Apps = map[string]*snap.AppInfo{
+			"svc":    {Snap: info, Name: "svc", Daemon: "simple"},
+			"manual": {Snap: info, Name: "manual", Daemon: "simple", InstallMode: "disable"},
+		}
+	}
+	if name == "alias-snap" {
+		info.Apps = map[string]*snap.AppInfo{
+			"cmd1": {Snap: info, Name: "cmd1"},
+			"cmd2": {Snap: info, Name: "cmd2"},
+		}
+		info.Aliases = map[string]*snap.AppInfo{
+			"alias1": info.Apps["cmd1"],
+			"alias2": info.Apps["cmd2"],
+		}
+	}
 	return info, nil
 }
 
@@ -335,22 +411,28 @@ func (f *fakeSnappyBackend) LinkSnap(info *snap.Info) error {
 	return nil
 }
 
-func (f *fakeSnappyBackend) StartSnapServices(info *snap.Info, meter progress.Meter) error {
+func (f *fakeSnappyBackend) StartSnapServices(info *snap.Info, disabled []string, meter progress.Meter) error {
 	f.ops = append(f.ops, fakeOp{
-		op:   "start-snap-services",
-		name: info.MountDir(),
+		op:               "start-snap-services",
+		name:             info.MountDir(),
+		disabledServices: disabled,
 	})
 	return nil
 }
 
-func (f *fakeSnappyBackend) StopSnapServices(info *snap.Info, meter progress.Meter) error {
+func (f *fakeSnappyBackend) StopSnapServices(info *snap.Info, reason snap.ServiceStopReason, meter progress.Meter) error {
 	f.ops = append(f.ops, fakeOp{
-		op:   "stop-snap-services",
-		name: info.MountDir(),
+		op:         "stop-snap-services",
+		name:       info.MountDir(),
+		stopReason: reason,
 	})
 	return nil
 }
 
+func (f *fakeSnappyBackend) DisabledSnapServices(info *snap.Info, meter progress.Meter) ([]string, error) {
+	return f.disabledServices, nil
+}
+
 func (f *fakeSnappyBackend) UndoSetupSnap(s snap.PlaceInfo, typ snap.Type, p progress.Meter) error {
 	p.Notify("setup-snap")
 	f.ops = append(f.ops, fakeOp{
@@ -418,6 +500,15 @@ func (f *fakeSnappyBackend) DiscardSnapNamespace(snapName string) error {
 	return nil
 }
 
+func (f *fakeSnappyBackend) UpdateAliases(add []*backend.Alias, remove []*backend.Alias) error {
+	f.ops = append(f.ops, fakeOp{
+		op:        "update-aliases",
+		aliases:   add,
+		rmAliases: remove,
+	})
+	return nil
+}
+
 func (f *fakeSnappyBackend) Candidate(sideInfo *snap.SideInfo) {
 	var sinfo snap.SideInfo
 	if sideInfo != nil {
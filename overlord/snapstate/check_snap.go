@@ -55,11 +55,14 @@ func checkAssumes(s *snap.Info) error {
 
 var openSnapFile = backend.OpenSnapFile
 
+// CheckInterfaces allows to hook checking the plugs and slots of a snap against the interfaces policy before it is installed.
+var CheckInterfaces func(st *state.State, snapInfo *snap.Info) error
+
 // checkSnap ensures that the snap can be installed.
-func checkSnap(st *state.State, snapFilePath string, curInfo *snap.Info, flags Flags) error {
+func checkSnap(st *state.State, snapFilePath string, si *snap.SideInfo, curInfo *snap.Info, flags Flags) error {
 	// This assumes that the snap was already verified or --dangerous was used.
 
-	s, _, err := openSnapFile(snapFilePath, nil)
+	s, _, err := openSnapFile(snapFilePath, si)
 	if err != nil {
 		return err
 	}
@@ -68,6 +71,18 @@ func checkSnap(st *state.State, snapFilePath string, curInfo *snap.Info, flags F
 		return fmt.Errorf("snap %q requires devmode or confinement override", s.Name())
 	}
 
+	if s.NeedsClassic() {
+		if !release.OnClassic {
+			return fmt.Errorf("snap %q requires classic confinement which is only available on classic systems", s.Name())
+		}
+		if !flags.Classic() {
+			return fmt.Errorf("snap %q requires classic confinement", s.Name())
+		}
+		if flags.JailMode() {
+			return fmt.Errorf("snap %q requires classic confinement which cannot be combined with jailmode", s.Name())
+		}
+	}
+
 	// verify we have a valid architecture
 	if !arch.IsSupportedArchitecture(s.Architectures) {
 		return fmt.Errorf("snap %q supported architectures (%s) are incompatible with this system (%s)", s.Name(), strings.Join(s.Architectures, ", "), arch.UbuntuArchitecture())
@@ -79,6 +94,15 @@ func checkSnap(st *state.State, snapFilePath string, curInfo *snap.Info, flags F
 		return err
 	}
 
+	if CheckInterfaces != nil {
+		st.Lock()
+		err := CheckInterfaces(st, s)
+		st.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
 	if s.Type != snap.TypeGadget {
 		return nil
 	}
@@ -67,7 +67,7 @@ architectures:
 	restore := snapstate.MockOpenSnapFile(openSnapFile)
 	defer restore()
 
-	err = snapstate.CheckSnap(nil, "snap-path", nil, 0)
+	err = snapstate.CheckSnap(nil, "snap-path", nil, nil, 0)
 
 	errorMsg := fmt.Sprintf(`snap "hello" supported architectures (yadayada, blahblah) are incompatible with this system (%s)`, arch.UbuntuArchitecture())
 	c.Assert(err.Error(), Equals, errorMsg)
@@ -87,7 +87,7 @@ assumes: [f1, f2]`
 	restore := snapstate.MockOpenSnapFile(openSnapFile)
 	defer restore()
 
-	err = snapstate.CheckSnap(nil, "snap-path", nil, 0)
+	err = snapstate.CheckSnap(nil, "snap-path", nil, nil, 0)
 	c.Check(err, ErrorMatches, `snap "foo" assumes unsupported features: f1, f2.*`)
 }
 
@@ -105,7 +105,7 @@ assumes: [common-data-dir]`
 	restore := snapstate.MockOpenSnapFile(openSnapFile)
 	defer restore()
 
-	err = snapstate.CheckSnap(nil, "snap-path", nil, 0)
+	err = snapstate.CheckSnap(nil, "snap-path", nil, nil, 0)
 	c.Check(err, IsNil)
 }
 
@@ -145,7 +145,7 @@ version: 2
 	defer restore()
 
 	st.Unlock()
-	err = snapstate.CheckSnap(st, "snap-path", nil, 0)
+	err = snapstate.CheckSnap(st, "snap-path", nil, nil, 0)
 	st.Lock()
 	c.Check(err, IsNil)
 }
@@ -186,7 +186,7 @@ version: 2
 	defer restore()
 
 	st.Unlock()
-	err = snapstate.CheckSnap(st, "snap-path", nil, 0)
+	err = snapstate.CheckSnap(st, "snap-path", nil, nil, 0)
 	st.Lock()
 	c.Check(err, ErrorMatches, "cannot replace gadget snap with a different one")
 }
@@ -219,7 +219,7 @@ version: 1
 	defer restore()
 
 	st.Unlock()
-	err = snapstate.CheckSnap(st, "snap-path", nil, 0)
+	err = snapstate.CheckSnap(st, "snap-path", nil, nil, 0)
 	st.Lock()
 	c.Check(err, ErrorMatches, "cannot find original gadget snap")
 }
@@ -247,7 +247,7 @@ version: 1
 	defer restore()
 
 	st.Unlock()
-	err = snapstate.CheckSnap(st, "snap-path", nil, 0)
+	err = snapstate.CheckSnap(st, "snap-path", nil, nil, 0)
 	st.Lock()
 	c.Check(err, ErrorMatches, "cannot install a gadget snap on classic")
 }
@@ -268,7 +268,71 @@ confinement: devmode
 	restore := snapstate.MockOpenSnapFile(openSnapFile)
 	defer restore()
 
-	err = snapstate.CheckSnap(nil, "snap-path", nil, 0)
+	err = snapstate.CheckSnap(nil, "snap-path", nil, nil, 0)
 
 	c.Assert(err, ErrorMatches, ".* requires devmode or confinement override")
 }
+
+func (s *checkSnapSuite) TestCheckSnapClassic(c *C) {
+	const yaml = `name: hello
+version: 1.10
+confinement: classic
+`
+	info, err := snap.InfoFromSnapYaml([]byte(yaml))
+	c.Assert(err, IsNil)
+
+	var openSnapFile = func(path string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
+		return info, nil, nil
+	}
+	restore := snapstate.MockOpenSnapFile(openSnapFile)
+	defer restore()
+
+	reset := release.MockOnClassic(true)
+	defer reset()
+
+	err = snapstate.CheckSnap(nil, "snap-path", nil, nil, 0)
+	c.Check(err, ErrorMatches, `snap "hello" requires classic confinement`)
+
+	err = snapstate.CheckSnap(nil, "snap-path", nil, nil, snapstate.DevMode)
+	c.Check(err, ErrorMatches, `snap "hello" requires classic confinement`)
+
+	err = snapstate.CheckSnap(nil, "snap-path", nil, nil, snapstate.Classic)
+	c.Check(err, IsNil)
+
+	err = snapstate.CheckSnap(nil, "snap-path", nil, nil, snapstate.Classic|snapstate.JailMode)
+	c.Check(err, ErrorMatches, `snap "hello" requires classic confinement which cannot be combined with jailmode`)
+
+	release.MockOnClassic(false)
+	err = snapstate.CheckSnap(nil, "snap-path", nil, nil, snapstate.Classic)
+	c.Check(err, ErrorMatches, `snap "hello" requires classic confinement which is only available on classic systems`)
+}
+
+func (s *checkSnapSuite) TestCheckSnapCheckInterfaces(c *C) {
+	st := state.New(nil)
+
+	const yaml = `name: foo
+version: 1.0
+plugs:
+  network:
+`
+	info, err := snap.InfoFromSnapYaml([]byte(yaml))
+	c.Assert(err, IsNil)
+
+	var openSnapFile = func(path string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
+		return info, nil, nil
+	}
+	restore := snapstate.MockOpenSnapFile(openSnapFile)
+	defer restore()
+
+	called := false
+	snapstate.CheckInterfaces = func(st *state.State, snapInfo *snap.Info) error {
+		called = true
+		c.Check(snapInfo, Equals, info)
+		return fmt.Errorf("interfaces policy says no")
+	}
+	defer func() { snapstate.CheckInterfaces = nil }()
+
+	err = snapstate.CheckSnap(st, "snap-path", nil, nil, 0)
+	c.Check(err, ErrorMatches, "interfaces policy says no")
+	c.Check(called, Equals, true)
+}
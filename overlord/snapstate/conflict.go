@@ -0,0 +1,176 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// ChangeConflictError is returned when a snap cannot be operated on
+// because a change still in progress is already operating on it.
+type ChangeConflictError struct {
+	Snap       string
+	ChangeKind string
+	ChangeID   string
+}
+
+func (e *ChangeConflictError) Error() string {
+	return fmt.Sprintf("snap %q has changes in progress", e.Snap)
+}
+
+// AffectedSnapsFunc returns the names of the snaps the given task
+// operates on.
+type AffectedSnapsFunc func(t *state.Task) ([]string, error)
+
+var (
+	affectedSnapsByAttr = make(map[string]AffectedSnapsFunc)
+	affectedSnapsByKind = make(map[string]AffectedSnapsFunc)
+)
+
+// AddAffectedSnapsByAttr registers a function to find the snaps
+// affected by tasks carrying the given attribute, so that they are
+// taken into account by CheckChangeConflict.
+func AddAffectedSnapsByAttr(attr string, f AffectedSnapsFunc) {
+	affectedSnapsByAttr[attr] = f
+}
+
+// AddAffectedSnapsByKind registers a function to find the snaps
+// affected by tasks of the given kind, so that they are taken into
+// account by CheckChangeConflict.
+func AddAffectedSnapsByKind(kind string, f AffectedSnapsFunc) {
+	affectedSnapsByKind[kind] = f
+}
+
+// conflictingKinds are the kinds of snapstate tasks that lock a snap
+// for the whole lifetime of their change.
+var conflictingKinds = map[string]bool{
+	"link-snap":           true,
+	"unlink-snap":         true,
+	"alias":               true,
+	"unalias":             true,
+	"hold-snap":           true,
+	"unhold-snap":         true,
+	"switch-snap-channel": true,
+}
+
+func snapSetupAffectedSnaps(t *state.Task) ([]string, error) {
+	ss, err := TaskSnapSetup(t)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: cannot obtain snap setup from task: %s", t.Summary())
+	}
+	return []string{ss.Name()}, nil
+}
+
+// affectedSnapsFunc returns the function finding the snaps the given
+// task operates on, or nil if the task does not lock any snap.
+func affectedSnapsFunc(t *state.Task) (AffectedSnapsFunc, error) {
+	if conflictingKinds[t.Kind()] {
+		return snapSetupAffectedSnaps, nil
+	}
+
+	if f := affectedSnapsByKind[t.Kind()]; f != nil {
+		return f, nil
+	}
+
+	for attr, f := range affectedSnapsByAttr {
+		var raw json.RawMessage
+		err := t.Get(attr, &raw)
+		if err == state.ErrNoState {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+
+	return nil, nil
+}
+
+// CheckChangeConflict ensures that no change in progress is operating
+// on the given snap, returning a *ChangeConflictError otherwise.
+// Changes scheduled for later are not in progress until they start.
+//
+// If snapst is not nil it also makes sure the SnapState in state still
+// matches it.
+func CheckChangeConflict(s *state.State, snapName string, snapst *SnapState) error {
+	// whether each change met is in progress, as working it out
+	// goes through all of its tasks
+	inProgress := make(map[string]bool)
+	for _, task := range s.Tasks() {
+		f, err := affectedSnapsFunc(task)
+		if err != nil {
+			return err
+		}
+		if f == nil {
+			continue
+		}
+		chg := task.Change()
+		if chg != nil {
+			running, ok := inProgress[chg.ID()]
+			if !ok {
+				running = !chg.Status().Ready() && !waitingForSchedule(chg)
+				inProgress[chg.ID()] = running
+			}
+			if !running {
+				continue
+			}
+		}
+		snaps, err := f(task)
+		if err != nil {
+			return err
+		}
+		for _, name := range snaps {
+			if name != snapName {
+				continue
+			}
+			conflErr := &ChangeConflictError{Snap: snapName}
+			if chg != nil {
+				conflErr.ChangeKind = chg.Kind()
+				conflErr.ChangeID = chg.ID()
+			}
+			return conflErr
+		}
+	}
+
+	if snapst != nil {
+		// caller wants us to also make sure the SnapState in state
+		// matches the one they provided. Necessary because we need to
+		// unlock while talking to the store, during which a change can
+		// sneak in (if it's before the taskset is created) (e.g. for
+		// install, while getting the snap info; for refresh, when
+		// getting what needs refreshing).
+		var cursnapst SnapState
+		if err := Get(s, snapName, &cursnapst); err != nil && err != state.ErrNoState {
+			return err
+		}
+
+		// TODO: implement the rather-boring-but-more-performant SnapState.Equals
+		if !reflect.DeepEqual(snapst, &cursnapst) {
+			return fmt.Errorf("snap %q state changed during install preparations", snapName)
+		}
+	}
+
+	return nil
+}
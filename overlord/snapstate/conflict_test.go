@@ -0,0 +1,162 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+type conflictSuite struct {
+	state *state.State
+}
+
+var _ = Suite(&conflictSuite{})
+
+func (s *conflictSuite) SetUpTest(c *C) {
+	s.state = state.New(nil)
+}
+
+func (s *conflictSuite) addLinkSnapChange(kind, snapName string) *state.Change {
+	chg := s.state.NewChange(kind, "...")
+	t := s.state.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: snapName},
+	})
+	chg.AddTask(t)
+	return chg
+}
+
+func (s *conflictSuite) TestCheckChangeConflict(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.addLinkSnapChange("refresh-snap", "some-snap")
+
+	err := snapstate.CheckChangeConflict(s.state, "some-snap", nil)
+	c.Assert(err, ErrorMatches, `snap "some-snap" has changes in progress`)
+	c.Check(err, DeepEquals, &snapstate.ChangeConflictError{
+		Snap:       "some-snap",
+		ChangeKind: "refresh-snap",
+		ChangeID:   chg.ID(),
+	})
+
+	c.Check(snapstate.CheckChangeConflict(s.state, "other-snap", nil), IsNil)
+
+	chg.SetStatus(state.DoneStatus)
+	c.Check(snapstate.CheckChangeConflict(s.state, "some-snap", nil), IsNil)
+}
+
+func (s *conflictSuite) TestRemoveWhileRefreshing(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", Revision: snap.R(11)},
+		},
+		Current: snap.R(11),
+		Active:  true,
+	})
+	chg := s.addLinkSnapChange("refresh-snap", "some-snap")
+
+	_, err := snapstate.Remove(s.state, "some-snap", snap.R(0), 0)
+	c.Check(err, DeepEquals, &snapstate.ChangeConflictError{
+		Snap:       "some-snap",
+		ChangeKind: "refresh-snap",
+		ChangeID:   chg.ID(),
+	})
+}
+
+func (s *conflictSuite) TestAffectedSnapsByKind(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	restore := snapstate.MockAffectedSnapsFuncs()
+	defer restore()
+
+	snapstate.AddAffectedSnapsByKind("frobble", func(t *state.Task) ([]string, error) {
+		var names []string
+		err := t.Get("frobbled", &names)
+		return names, err
+	})
+
+	chg := s.state.NewChange("frobble-snaps", "...")
+	t := s.state.NewTask("frobble", "...")
+	t.Set("frobbled", []string{"some-snap", "other-snap"})
+	chg.AddTask(t)
+
+	for _, name := range []string{"some-snap", "other-snap"} {
+		c.Check(snapstate.CheckChangeConflict(s.state, name, nil), DeepEquals, &snapstate.ChangeConflictError{
+			Snap:       name,
+			ChangeKind: "frobble-snaps",
+			ChangeID:   chg.ID(),
+		})
+	}
+	c.Check(snapstate.CheckChangeConflict(s.state, "third-snap", nil), IsNil)
+
+	t.Set("frobbled", "garbage")
+	c.Check(snapstate.CheckChangeConflict(s.state, "third-snap", nil), NotNil)
+
+	// the tasks of changes that are done are not looked into
+	chg.SetStatus(state.DoneStatus)
+	c.Check(snapstate.CheckChangeConflict(s.state, "third-snap", nil), IsNil)
+}
+
+func (s *conflictSuite) TestAffectedSnapsByAttr(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	restore := snapstate.MockAffectedSnapsFuncs()
+	defer restore()
+
+	snapstate.AddAffectedSnapsByAttr("twiddle-setup", func(t *state.Task) ([]string, error) {
+		var name string
+		if err := t.Get("twiddle-setup", &name); err != nil {
+			return nil, err
+		}
+		if name == "" {
+			return nil, errors.New("no snap to twiddle")
+		}
+		return []string{name}, nil
+	})
+
+	chg := s.state.NewChange("twiddle", "...")
+	t := s.state.NewTask("twiddle-a-snap", "...")
+	chg.AddTask(t)
+
+	// tasks without the attribute are not considered
+	c.Check(snapstate.CheckChangeConflict(s.state, "some-snap", nil), IsNil)
+
+	t.Set("twiddle-setup", "some-snap")
+	c.Check(snapstate.CheckChangeConflict(s.state, "some-snap", nil), DeepEquals, &snapstate.ChangeConflictError{
+		Snap:       "some-snap",
+		ChangeKind: "twiddle",
+		ChangeID:   chg.ID(),
+	})
+
+	t.Set("twiddle-setup", "")
+	c.Check(snapstate.CheckChangeConflict(s.state, "some-snap", nil), ErrorMatches, "no snap to twiddle")
+}
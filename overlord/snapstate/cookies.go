@@ -0,0 +1,159 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// Every installed snap gets a secret cookie, kept in the state under
+// "snap-cookies" (mapping cookies to snap names) and in a root-only
+// file the snap's apps get it from. snapctl can present the cookie
+// instead of a hook context to act on behalf of the snap.
+
+func cookieFile(snapName string) string {
+	return filepath.Join(dirs.SnapCookieDir, "snap."+snapName)
+}
+
+func getCookies(st *state.State) (map[string]string, error) {
+	var cookies map[string]string
+	err := st.Get("snap-cookies", &cookies)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("cannot get snap cookies: %v", err)
+	}
+	if cookies == nil {
+		cookies = make(map[string]string)
+	}
+	return cookies, nil
+}
+
+func makeCookie() (string, error) {
+	cookieBytes := make([]byte, 32)
+	if _, err := rand.Read(cookieBytes); err != nil {
+		return "", fmt.Errorf("cannot generate snap cookie: %v", err)
+	}
+	return base64.URLEncoding.EncodeToString(cookieBytes), nil
+}
+
+func writeCookieFile(snapName, cookie string) error {
+	if err := os.MkdirAll(dirs.SnapCookieDir, 0700); err != nil {
+		return fmt.Errorf("cannot create snap cookie directory: %v", err)
+	}
+	if err := osutil.AtomicWriteFile(cookieFile(snapName), []byte(cookie), 0600, 0); err != nil {
+		return fmt.Errorf("cannot write cookie of snap %q: %v", snapName, err)
+	}
+	return nil
+}
+
+// createSnapCookie makes sure the given snap has a cookie, generating
+// one if needed, and that its cookie file is in place.
+func createSnapCookie(st *state.State, snapName string) error {
+	cookies, err := getCookies(st)
+	if err != nil {
+		return err
+	}
+
+	for cookie, name := range cookies {
+		if name == snapName {
+			return writeCookieFile(snapName, cookie)
+		}
+	}
+
+	cookie, err := makeCookie()
+	if err != nil {
+		return err
+	}
+	if err := writeCookieFile(snapName, cookie); err != nil {
+		return err
+	}
+	cookies[cookie] = snapName
+	st.Set("snap-cookies", cookies)
+	return nil
+}
+
+// removeSnapCookie forgets the cookie of the given snap and removes
+// its cookie file.
+func removeSnapCookie(st *state.State, snapName string) error {
+	cookies, err := getCookies(st)
+	if err != nil {
+		return err
+	}
+
+	for cookie, name := range cookies {
+		if name == snapName {
+			delete(cookies, cookie)
+		}
+	}
+	st.Set("snap-cookies", cookies)
+
+	if err := os.Remove(cookieFile(snapName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove cookie of snap %q: %v", snapName, err)
+	}
+	return nil
+}
+
+// syncCookies makes sure all the installed snaps, and only them, have
+// cookies; snaps installed before cookies were introduced get one here.
+func syncCookies(st *state.State) error {
+	snapStates, err := All(st)
+	if err != nil {
+		return err
+	}
+	for name := range snapStates {
+		if err := createSnapCookie(st, name); err != nil {
+			return err
+		}
+	}
+
+	cookies, err := getCookies(st)
+	if err != nil {
+		return err
+	}
+	for _, name := range cookies {
+		if snapStates[name] == nil {
+			if err := removeSnapCookie(st, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SnapNameForCookie returns the name of the snap the given cookie
+// belongs to.
+func SnapNameForCookie(st *state.State, cookie string) (string, error) {
+	cookies, err := getCookies(st)
+	if err != nil {
+		return "", err
+	}
+	snapName, ok := cookies[cookie]
+	if !ok {
+		return "", fmt.Errorf("unknown snap cookie")
+	}
+	return snapName, nil
+}
@@ -0,0 +1,90 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+type cookiesSuite struct {
+	state *state.State
+}
+
+var _ = Suite(&cookiesSuite{})
+
+func (s *cookiesSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	s.state = state.New(nil)
+}
+
+func (s *cookiesSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
+
+func (s *cookiesSuite) TestEnsureSyncsCookies(c *C) {
+	s.state.Lock()
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "foo", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+	})
+	// a leftover from a snap that is gone
+	s.state.Set("snap-cookies", map[string]string{"stale-cookie": "gone"})
+	c.Assert(os.MkdirAll(dirs.SnapCookieDir, 0700), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapCookieDir, "snap.gone"), []byte("stale-cookie"), 0600), IsNil)
+	s.state.Unlock()
+
+	snapmgr, err := snapstate.Manager(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(snapmgr.Ensure(), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	var cookies map[string]string
+	c.Assert(s.state.Get("snap-cookies", &cookies), IsNil)
+	c.Assert(cookies, HasLen, 1)
+	for cookie, snapName := range cookies {
+		c.Check(snapName, Equals, "foo")
+		c.Check(cookie, HasLen, 44)
+
+		fi, err := os.Stat(filepath.Join(dirs.SnapCookieDir, "snap.foo"))
+		c.Assert(err, IsNil)
+		c.Check(fi.Mode().Perm(), Equals, os.FileMode(0600))
+
+		name, err := snapstate.SnapNameForCookie(s.state, cookie)
+		c.Check(err, IsNil)
+		c.Check(name, Equals, "foo")
+	}
+	_, err = os.Stat(filepath.Join(dirs.SnapCookieDir, "snap.gone"))
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	_, err = snapstate.SnapNameForCookie(s.state, "stale-cookie")
+	c.Check(err, ErrorMatches, "unknown snap cookie")
+}
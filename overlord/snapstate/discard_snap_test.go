@@ -22,6 +22,7 @@ package snapstate_test
 import (
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -39,6 +40,8 @@ type discardSnapSuite struct {
 var _ = Suite(&discardSnapSuite{})
 
 func (s *discardSnapSuite) SetUpTest(c *C) {
+	oldCookieDir := dirs.SnapCookieDir
+	dirs.SnapCookieDir = c.MkDir()
 	s.fakeBackend = &fakeSnappyBackend{}
 	s.state = state.New(nil)
 
@@ -49,7 +52,11 @@ func (s *discardSnapSuite) SetUpTest(c *C) {
 
 	snapstate.SetSnapManagerBackend(s.snapmgr, s.fakeBackend)
 
-	s.reset = snapstate.MockReadInfo(s.fakeBackend.ReadInfo)
+	restore := snapstate.MockReadInfo(s.fakeBackend.ReadInfo)
+	s.reset = func() {
+		restore()
+		dirs.SnapCookieDir = oldCookieDir
+	}
 }
 
 func (s *discardSnapSuite) TearDownTest(c *C) {
@@ -93,6 +100,7 @@ func (s *discardSnapSuite) TestDoDiscardSnapSuccess(c *C) {
 
 func (s *discardSnapSuite) TestDoDiscardSnapToEmpty(c *C) {
 	s.state.Lock()
+	s.state.Set("snap-cookies", map[string]string{"foo-cookie": "foo"})
 	snapstate.Set(s.state, "foo", &snapstate.SnapState{
 		Sequence: []*snap.SideInfo{
 			{RealName: "foo", Revision: snap.R(3)},
@@ -119,6 +127,10 @@ func (s *discardSnapSuite) TestDoDiscardSnapToEmpty(c *C) {
 	var snapst snapstate.SnapState
 	err := snapstate.Get(s.state, "foo", &snapst)
 	c.Assert(err, Equals, state.ErrNoState)
+
+	var cookies map[string]string
+	c.Assert(s.state.Get("snap-cookies", &cookies), IsNil)
+	c.Check(cookies, HasLen, 0)
 }
 
 func (s *discardSnapSuite) TestDoDiscardSnapErrorsForActive(c *C) {
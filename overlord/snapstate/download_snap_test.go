@@ -20,8 +20,14 @@
 package snapstate_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -111,6 +117,7 @@ func (s *downloadSnapSuite) TestDoDownloadSnapCompatbility(c *C) {
 		Revision: snap.R(11),
 		Channel:  "some-channel",
 	})
+	c.Check(ss.SnapPath, Equals, filepath.Join(dirs.SnapBlobDir, "foo_11.snap"))
 	c.Check(t.Status(), Equals, state.DoneStatus)
 }
 
@@ -154,6 +161,11 @@ func (s *downloadSnapSuite) TestDoDownloadSnapNormal(c *C) {
 	var ss snapstate.SnapSetup
 	t.Get("snap-setup", &ss)
 	c.Check(ss.SideInfo, DeepEquals, si)
+	c.Check(ss.SnapPath, Equals, filepath.Join(dirs.SnapBlobDir, "foo_11.snap"))
+	c.Check(s.fakeStore.downloads, DeepEquals, []fakeDownload{{
+		name:   "foo",
+		target: filepath.Join(dirs.SnapBlobDir, "foo_11.snap"),
+	}})
 	c.Check(t.Status(), Equals, state.DoneStatus)
 }
 
@@ -179,6 +191,12 @@ func (s *downloadSnapSuite) TestDoUndoDownloadSnap(c *C) {
 
 	s.state.Unlock()
 
+	// the fake store doesn't write anything
+	blob := filepath.Join(dirs.SnapBlobDir, "foo_33.snap")
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(blob, nil, 0644), IsNil)
+	c.Assert(ioutil.WriteFile(blob+".partial", nil, 0644), IsNil)
+
 	for i := 0; i < 3; i++ {
 		s.snapmgr.Ensure()
 		s.snapmgr.Wait()
@@ -195,4 +213,7 @@ func (s *downloadSnapSuite) TestDoUndoDownloadSnap(c *C) {
 	err := snapstate.Get(s.state, "foo", &snapst)
 	c.Assert(err, Equals, state.ErrNoState)
 
+	// and the downloaded files are gone
+	c.Check(osutil.FileExists(blob), Equals, false)
+	c.Check(osutil.FileExists(blob+".partial"), Equals, false)
 }
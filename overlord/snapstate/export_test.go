@@ -21,11 +21,13 @@ package snapstate
 
 import (
 	"errors"
+	"time"
 
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timeutil"
 )
 
 type ManagerBackend managerBackend
@@ -73,6 +75,19 @@ func MockReadInfo(mock func(name string, si *snap.SideInfo) (*snap.Info, error))
 	return func() { readInfo = old }
 }
 
+// MockAffectedSnapsFuncs gives the test fresh registries of the
+// functions added with AddAffectedSnapsByAttr and AddAffectedSnapsByKind.
+func MockAffectedSnapsFuncs() (restore func()) {
+	oldByAttr := affectedSnapsByAttr
+	oldByKind := affectedSnapsByKind
+	affectedSnapsByAttr = make(map[string]AffectedSnapsFunc)
+	affectedSnapsByKind = make(map[string]AffectedSnapsFunc)
+	return func() {
+		affectedSnapsByAttr = oldByAttr
+		affectedSnapsByKind = oldByKind
+	}
+}
+
 func MockOpenSnapFile(mock func(path string, si *snap.SideInfo) (*snap.Info, snap.Container, error)) (restore func()) {
 	prevOpenSnapFile := openSnapFile
 	openSnapFile = mock
@@ -83,8 +98,24 @@ var (
 	CheckSnap   = checkSnap
 	CanRemove   = canRemove
 	CachedStore = cachedStore
+
+	DoWaitScheduled = (*SnapManager).doWaitScheduled
 )
 
 func PreviousSideInfo(snapst *SnapState) *snap.SideInfo {
 	return snapst.previousSideInfo()
 }
+
+func NewAutoRefresh(st *state.State) *autoRefresh {
+	return newAutoRefresh(st)
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	restoreTimeutil := timeutil.MockTimeNow(f)
+	return func() {
+		restoreTimeutil()
+		timeNow = old
+	}
+}
@@ -0,0 +1,162 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// Hold holds back refreshes of the snap until the given time, or
+// indefinitely if until is zero, pinning it to its current revision.
+func Hold(st *state.State, snapName string, until time.Time) (*state.TaskSet, error) {
+	snapst, err := snapStateForHold(st, snapName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !until.IsZero() && !until.After(timeNow()) {
+		return nil, fmt.Errorf("cannot hold snap %q until %s, time is in the past", snapName, until.Format(time.RFC3339))
+	}
+
+	summary := fmt.Sprintf(i18n.G("Hold refreshes of snap %q"), snapName)
+	if !until.IsZero() {
+		summary = fmt.Sprintf(i18n.G("Hold refreshes of snap %q until %s"), snapName, until.Format(time.RFC3339))
+	}
+
+	t := st.NewTask("hold-snap", summary)
+	t.Set("snap-setup", holdSnapSetup(snapName, snapst))
+	t.Set("hold-until", until)
+
+	return state.NewTaskSet(t), nil
+}
+
+// Unhold allows refreshes of a held snap again.
+func Unhold(st *state.State, snapName string) (*state.TaskSet, error) {
+	snapst, err := snapStateForHold(st, snapName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !snapst.Held() {
+		return nil, fmt.Errorf("snap %q is not held", snapName)
+	}
+
+	t := st.NewTask("unhold-snap", fmt.Sprintf(i18n.G("Allow refreshes of snap %q"), snapName))
+	t.Set("snap-setup", holdSnapSetup(snapName, snapst))
+
+	return state.NewTaskSet(t), nil
+}
+
+func snapStateForHold(st *state.State, snapName string) (*SnapState, error) {
+	var snapst SnapState
+	err := Get(st, snapName, &snapst)
+	if err == state.ErrNoState {
+		return nil, fmt.Errorf("cannot find snap %q", snapName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckChangeConflict(st, snapName, nil); err != nil {
+		return nil, err
+	}
+
+	return &snapst, nil
+}
+
+func holdSnapSetup(snapName string, snapst *SnapState) *SnapSetup {
+	return minimalSnapSetup(snapName, snapst.Current)
+}
+
+// holdDescription describes how long refreshes are held for.
+func holdDescription(hold *RefreshHold) string {
+	if hold.Until.IsZero() {
+		return "indefinitely"
+	}
+	return fmt.Sprintf("until %s", hold.Until.Format(time.RFC3339))
+}
+
+func (m *SnapManager) doHoldSnap(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ss, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	var until time.Time
+	if err := t.Get("hold-until", &until); err != nil {
+		return err
+	}
+
+	// save for undoHoldSnap
+	t.Set("old-hold", snapst.Hold)
+
+	snapst.Hold = &RefreshHold{
+		Until:    until,
+		Revision: snapst.Current,
+	}
+	Set(st, ss.Name(), snapst)
+	return nil
+}
+
+func (m *SnapManager) doUnholdSnap(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ss, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+
+	// save for undoHoldSnap
+	t.Set("old-hold", snapst.Hold)
+
+	snapst.Hold = nil
+	Set(st, ss.Name(), snapst)
+	return nil
+}
+
+func (m *SnapManager) undoHoldSnap(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ss, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	var oldHold *RefreshHold
+	if err := t.Get("old-hold", &oldHold); err != nil {
+		return err
+	}
+
+	snapst.Hold = oldHold
+	Set(st, ss.Name(), snapst)
+	return nil
+}
@@ -0,0 +1,283 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) setSomeSnap(hold *snapstate.RefreshHold) {
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(5)},
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current: snap.R(7),
+		Hold:    hold,
+	})
+}
+
+func (s *snapmgrTestSuite) TestHeld(c *C) {
+	snapst := &snapstate.SnapState{}
+	c.Check(snapst.Held(), Equals, false)
+
+	snapst.Hold = &snapstate.RefreshHold{}
+	c.Check(snapst.Held(), Equals, true)
+
+	snapst.Hold.Until = time.Now().Add(time.Hour)
+	c.Check(snapst.Held(), Equals, true)
+
+	snapst.Hold.Until = time.Now().Add(-time.Hour)
+	c.Check(snapst.Held(), Equals, false)
+}
+
+func (s *snapmgrTestSuite) TestHoldTasks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setSomeSnap(nil)
+
+	ts, err := snapstate.Hold(s.state, "some-snap", time.Time{})
+	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 1)
+	t := ts.Tasks()[0]
+	c.Check(t.Kind(), Equals, "hold-snap")
+	c.Check(t.Summary(), Equals, `Hold refreshes of snap "some-snap"`)
+
+	until := time.Date(2037, 2, 6, 10, 0, 0, 0, time.UTC)
+	ts, err = snapstate.Hold(s.state, "some-snap", until)
+	c.Assert(err, IsNil)
+	t = ts.Tasks()[0]
+	c.Check(t.Summary(), Equals, `Hold refreshes of snap "some-snap" until 2037-02-06T10:00:00Z`)
+	var holdUntil time.Time
+	c.Assert(t.Get("hold-until", &holdUntil), IsNil)
+	c.Check(holdUntil.Equal(until), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestHoldErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := snapstate.Hold(s.state, "some-snap", time.Time{})
+	c.Check(err, ErrorMatches, `cannot find snap "some-snap"`)
+
+	s.setSomeSnap(nil)
+	_, err = snapstate.Hold(s.state, "some-snap", time.Date(2016, 2, 6, 10, 0, 0, 0, time.UTC))
+	c.Check(err, ErrorMatches, `cannot hold snap "some-snap" until 2016-02-06T10:00:00Z, time is in the past`)
+
+	_, err = snapstate.Unhold(s.state, "some-snap")
+	c.Check(err, ErrorMatches, `snap "some-snap" is not held`)
+}
+
+func (s *snapmgrTestSuite) TestHoldRunThrough(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setSomeSnap(nil)
+
+	until := time.Now().Add(72 * time.Hour).UTC()
+	chg := s.state.NewChange("hold-snap", "hold snap")
+	ts, err := snapstate.Hold(s.state, "some-snap", until)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus)
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Assert(snapst.Hold, NotNil)
+	c.Check(snapst.Hold.Until.Equal(until), Equals, true)
+	c.Check(snapst.Hold.Revision, Equals, snap.R(7))
+	c.Check(snapst.Held(), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestHoldUndo(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setSomeSnap(nil)
+
+	chg := s.state.NewChange("hold-snap", "hold snap")
+	ts, err := snapstate.Hold(s.state, "some-snap", time.Time{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	terr := s.state.NewTask("fake-install-snap-error", "fail")
+	terr.WaitAll(ts)
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Hold, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUnholdRunThroughAndUndo(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	hold := &snapstate.RefreshHold{Revision: snap.R(7)}
+	s.setSomeSnap(hold)
+
+	chg := s.state.NewChange("unhold-snap", "unhold snap")
+	ts, err := snapstate.Unhold(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 1)
+	c.Check(ts.Tasks()[0].Summary(), Equals, `Allow refreshes of snap "some-snap"`)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus)
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Hold, IsNil)
+
+	// undo puts the hold back
+	s.setSomeSnap(hold)
+	chg = s.state.NewChange("unhold-snap", "unhold snap")
+	ts, err = snapstate.Unhold(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	terr := s.state.NewTask("fake-install-snap-error", "fail")
+	terr.WaitAll(ts)
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Hold, DeepEquals, hold)
+}
+
+func (s *snapmgrTestSuite) TestHeldSnapsAreNotRefreshed(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setSomeSnap(&snapstate.RefreshHold{Revision: snap.R(7)})
+
+	candidates, err := snapstate.RefreshCandidates(s.state, nil)
+	c.Assert(err, IsNil)
+	c.Check(candidates, HasLen, 0)
+
+	updates, tts, err := snapstate.UpdateMany(s.state, nil, 0)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 0)
+	c.Check(tts, HasLen, 0)
+
+	updates, tts, err = snapstate.UpdateMany(s.state, []string{"some-snap"}, 0)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 0)
+	c.Check(tts, HasLen, 0)
+
+	_, err = snapstate.Update(s.state, "some-snap", "", snap.R(0), 0, 0)
+	c.Check(err, ErrorMatches, `cannot refresh snap "some-snap", refreshes are held indefinitely`)
+
+	_, err = snapstate.Revert(s.state, "some-snap", snapstate.Flags(0))
+	c.Check(err, ErrorMatches, `cannot revert snap "some-snap", it is pinned to revision 7`)
+}
+
+func (s *snapmgrTestSuite) TestHoldWithoutRevisionAllowsRevert(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// as recorded before holds pinned the revision
+	s.setSomeSnap(&snapstate.RefreshHold{})
+
+	ts, err := snapstate.Revert(s.state, "some-snap", snapstate.Flags(0))
+	c.Assert(err, IsNil)
+	c.Check(ts.Tasks(), Not(HasLen), 0)
+}
+
+func (s *snapmgrTestSuite) TestExpiredHoldIsIgnored(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setSomeSnap(&snapstate.RefreshHold{
+		Until:    time.Now().Add(-time.Hour),
+		Revision: snap.R(7),
+	})
+
+	updates, tts, err := snapstate.UpdateMany(s.state, nil, 0)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+	c.Check(tts, HasLen, 1)
+}
+
+func (s *snapmgrTestSuite) TestExpiredHoldIsPrunedOnWrite(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setSomeSnap(nil)
+	var snaps map[string]map[string]interface{}
+	c.Assert(s.state.Get("snaps", &snaps), IsNil)
+	snaps["some-snap"]["hold"] = map[string]interface{}{
+		"until":    time.Now().Add(-time.Hour),
+		"revision": 7,
+	}
+	s.state.Set("snaps", snaps)
+
+	// reading leaves the expired hold alone
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Assert(snapst.Hold, NotNil)
+	c.Check(snapst.Held(), Equals, false)
+	all, err := snapstate.All(s.state)
+	c.Assert(err, IsNil)
+	c.Check(all["some-snap"].Hold, NotNil)
+
+	// writing drops it, without touching the written SnapState
+	snapstate.Set(s.state, "some-snap", &snapst)
+	c.Check(snapst.Hold, NotNil)
+	var written snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &written), IsNil)
+	c.Check(written.Hold, IsNil)
+
+	// and the snap can be held again right away
+	_, err = snapstate.Hold(s.state, "some-snap", time.Time{})
+	c.Check(err, IsNil)
+}
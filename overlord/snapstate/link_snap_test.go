@@ -20,10 +20,14 @@
 package snapstate_test
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"time"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
@@ -58,6 +62,8 @@ func (b *witnessRestartReqStateBackend) RequestRestart(t state.RestartType) {
 func (b *witnessRestartReqStateBackend) EnsureBefore(time.Duration) {}
 
 func (s *linkSnapSuite) SetUpTest(c *C) {
+	oldCookieDir := dirs.SnapCookieDir
+	dirs.SnapCookieDir = c.MkDir()
 	s.stateBackend = &witnessRestartReqStateBackend{}
 	s.fakeBackend = &fakeSnappyBackend{}
 	s.state = state.New(s.stateBackend)
@@ -69,7 +75,11 @@ func (s *linkSnapSuite) SetUpTest(c *C) {
 
 	snapstate.SetSnapManagerBackend(s.snapmgr, s.fakeBackend)
 
-	s.reset = snapstate.MockReadInfo(s.fakeBackend.ReadInfo)
+	restore := snapstate.MockReadInfo(s.fakeBackend.ReadInfo)
+	s.reset = func() {
+		restore()
+		dirs.SnapCookieDir = oldCookieDir
+	}
 }
 
 func (s *linkSnapSuite) TearDownTest(c *C) {
@@ -109,6 +119,43 @@ func (s *linkSnapSuite) TestDoLinkSnapSuccess(c *C) {
 	c.Check(snapst.Channel, Equals, "beta")
 	c.Check(t.Status(), Equals, state.DoneStatus)
 	c.Check(s.stateBackend.restartRequested, Equals, false)
+
+	// the snap got a cookie
+	var cookies map[string]string
+	c.Assert(s.state.Get("snap-cookies", &cookies), IsNil)
+	c.Assert(cookies, HasLen, 1)
+	for cookie, snapName := range cookies {
+		c.Check(snapName, Equals, "foo")
+		content, err := ioutil.ReadFile(filepath.Join(dirs.SnapCookieDir, "snap.foo"))
+		c.Assert(err, IsNil)
+		c.Check(string(content), Equals, cookie)
+	}
+}
+
+func (s *linkSnapSuite) TestDoLinkSnapClassic(c *C) {
+	s.state.Lock()
+	t := s.state.NewTask("link-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "foo",
+			Revision: snap.R(33),
+		},
+		Flags: snapstate.SnapSetupFlags(snapstate.Classic),
+	})
+	s.state.NewChange("dummy", "...").AddTask(t)
+
+	s.state.Unlock()
+
+	s.snapmgr.Ensure()
+	s.snapmgr.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var snapst snapstate.SnapState
+	err := snapstate.Get(s.state, "foo", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Classic(), Equals, true)
+	c.Check(snapst.DevMode(), Equals, false)
 }
 
 func (s *linkSnapSuite) TestDoUndoLinkSnap(c *C) {
@@ -142,6 +189,12 @@ func (s *linkSnapSuite) TestDoUndoLinkSnap(c *C) {
 	err := snapstate.Get(s.state, "foo", &snapst)
 	c.Assert(err, Equals, state.ErrNoState)
 	c.Check(t.Status(), Equals, state.UndoneStatus)
+
+	// the cookie is gone again
+	var cookies map[string]string
+	c.Assert(s.state.Get("snap-cookies", &cookies), IsNil)
+	c.Check(cookies, HasLen, 0)
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapCookieDir, "snap.foo")), Equals, false)
 }
 
 func (s *linkSnapSuite) TestDoLinkSnapTryToCleanupOnError(c *C) {
@@ -42,6 +42,7 @@ func (t *TaskProgressAdapter) Start(label string, total float64) {
 func (t *TaskProgressAdapter) Set(current float64) {
 	t.task.State().Lock()
 	defer t.task.State().Unlock()
+	t.current = current
 	t.task.SetProgress(t.label, int(current), int(t.total))
 }
 
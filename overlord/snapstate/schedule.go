@@ -0,0 +1,133 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// how long a scheduled change that is due waits before checking again
+// for the changes in progress on its snaps
+var scheduledRetryInterval = time.Minute
+
+// scheduledSnap records the revisions of a snap that the tasks of a
+// scheduled change were built against.
+type scheduledSnap struct {
+	Current  snap.Revision    `json:"current"`
+	Sequence []*snap.SideInfo `json:"sequence,omitempty"`
+}
+
+func scheduledSnapFor(st *state.State, snapName string) (*scheduledSnap, error) {
+	var snapst SnapState
+	if err := Get(st, snapName, &snapst); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	return &scheduledSnap{
+		Current:  snapst.Current,
+		Sequence: snapst.Sequence,
+	}, nil
+}
+
+// ScheduleChange has the given change, operating on the given snaps,
+// start no earlier than at. Until then the change is not taken into
+// account by CheckChangeConflict, so other changes can operate on the
+// same snaps; once due it waits for the ones in progress to be done
+// before starting, and fails if the revisions of the snaps changed
+// meanwhile, as its tasks were built against the current ones.
+func ScheduleChange(chg *state.Change, snapNames []string, at time.Time) error {
+	st := chg.State()
+	snaps := make(map[string]*scheduledSnap, len(snapNames))
+	for _, snapName := range snapNames {
+		sched, err := scheduledSnapFor(st, snapName)
+		if err != nil {
+			return err
+		}
+		snaps[snapName] = sched
+	}
+
+	wait := st.NewTask("wait-scheduled", fmt.Sprintf(i18n.G("Wait until %s"), at.Format(time.RFC3339)))
+	wait.Set("snap-names", snapNames)
+	wait.Set("scheduled-snaps", snaps)
+	wait.At(at)
+	for _, t := range chg.Tasks() {
+		t.WaitFor(wait)
+	}
+	chg.AddTask(wait)
+	return nil
+}
+
+// waitingForSchedule returns whether the change was scheduled for
+// later and did not start yet.
+func waitingForSchedule(chg *state.Change) bool {
+	for _, t := range chg.Tasks() {
+		if t.Kind() == "wait-scheduled" && t.Status() != state.DoneStatus {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *SnapManager) doWaitScheduled(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var snapNames []string
+	if err := t.Get("snap-names", &snapNames); err != nil && err != state.ErrNoState {
+		return err
+	}
+	var snaps map[string]*scheduledSnap
+	if err := t.Get("scheduled-snaps", &snaps); err != nil && err != state.ErrNoState {
+		return err
+	}
+	for _, snapName := range snapNames {
+		err := CheckChangeConflict(st, snapName, nil)
+		if _, ok := err.(*ChangeConflictError); ok {
+			t.Logf("Waiting for the changes in progress on snap %q.", snapName)
+			return &state.Retry{After: scheduledRetryInterval}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, snapName := range snapNames {
+		sched, err := scheduledSnapFor(st, snapName)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(sched, snaps[snapName]) {
+			return fmt.Errorf("cannot start change scheduled for snap %q, its revisions changed since", snapName)
+		}
+	}
+
+	// from now on the change conflicts with other changes operating
+	// on its snaps, including other scheduled changes that are due
+	t.SetStatus(state.DoneStatus)
+
+	return nil
+}
@@ -0,0 +1,144 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) addScheduledRefresh(c *C, snapName string, at time.Time) *state.Change {
+	chg := s.state.NewChange("refresh-snap", "...")
+	t := s.state.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: snapName},
+	})
+	chg.AddTask(t)
+	c.Assert(snapstate.ScheduleChange(chg, []string{snapName}, at), IsNil)
+	return chg
+}
+
+func (s *snapmgrTestSuite) TestScheduleChange(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	at := time.Now().Add(time.Hour)
+	chg := s.addScheduledRefresh(c, "some-snap", at)
+
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 2)
+	wait := tasks[1]
+	c.Check(wait.Kind(), Equals, "wait-scheduled")
+	c.Check(wait.Summary(), Equals, "Wait until "+at.Format(time.RFC3339))
+	c.Check(wait.AtTime().Equal(at), Equals, true)
+	var snapNames []string
+	c.Assert(wait.Get("snap-names", &snapNames), IsNil)
+	c.Check(snapNames, DeepEquals, []string{"some-snap"})
+	c.Check(tasks[0].WaitTasks(), DeepEquals, []*state.Task{wait})
+	c.Check(tasks[0].AtTime().IsZero(), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestScheduledChangeDoesNotConflictUntilItStarts(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.addScheduledRefresh(c, "some-snap", time.Now().Add(time.Hour))
+	c.Check(snapstate.CheckChangeConflict(s.state, "some-snap", nil), IsNil)
+
+	chg.Tasks()[1].SetStatus(state.DoneStatus)
+	c.Check(snapstate.CheckChangeConflict(s.state, "some-snap", nil), ErrorMatches, `snap "some-snap" has changes in progress`)
+}
+
+func (s *snapmgrTestSuite) TestWaitScheduledWaitsForChangesInProgress(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	other := s.state.NewChange("remove-snap", "...")
+	t := s.state.NewTask("unlink-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "some-snap"},
+	})
+	other.AddTask(t)
+
+	chg := s.addScheduledRefresh(c, "some-snap", time.Now())
+	wait := chg.Tasks()[1]
+
+	s.state.Unlock()
+	err := snapstate.DoWaitScheduled(s.snapmgr, wait, nil)
+	s.state.Lock()
+	c.Check(err, DeepEquals, &state.Retry{After: time.Minute})
+	c.Check(wait.Status(), Equals, state.DoStatus)
+	c.Check(wait.Log(), HasLen, 1)
+	c.Check(wait.Log()[0], Matches, `.* Waiting for the changes in progress on snap "some-snap".`)
+
+	other.SetStatus(state.DoneStatus)
+
+	s.state.Unlock()
+	err = snapstate.DoWaitScheduled(s.snapmgr, wait, nil)
+	s.state.Lock()
+	c.Check(err, IsNil)
+	c.Check(wait.Status(), Equals, state.DoneStatus)
+
+	// the started change now holds the snap
+	c.Check(snapstate.CheckChangeConflict(s.state, "some-snap", nil), ErrorMatches, `snap "some-snap" has changes in progress`)
+}
+
+func (s *snapmgrTestSuite) TestWaitScheduledFailsIfTheSnapChanged(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setSomeSnap(nil)
+	chg := s.addScheduledRefresh(c, "some-snap", time.Now())
+	wait := chg.Tasks()[1]
+
+	// the snap is reverted before the change starts
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	snapst.Current = snap.R(5)
+	snapstate.Set(s.state, "some-snap", &snapst)
+
+	s.state.Unlock()
+	err := snapstate.DoWaitScheduled(s.snapmgr, wait, nil)
+	s.state.Lock()
+	c.Check(err, ErrorMatches, `cannot start change scheduled for snap "some-snap", its revisions changed since`)
+}
+
+func (s *snapmgrTestSuite) TestWaitScheduledIgnoresOtherSnapStateChanges(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setSomeSnap(nil)
+	chg := s.addScheduledRefresh(c, "some-snap", time.Now())
+	wait := chg.Tasks()[1]
+
+	// holding the snap does not change its revisions
+	s.setSomeSnap(&snapstate.RefreshHold{Revision: snap.R(7)})
+
+	s.state.Unlock()
+	err := snapstate.DoWaitScheduled(s.snapmgr, wait, nil)
+	s.state.Lock()
+	c.Check(err, IsNil)
+	c.Check(wait.Status(), Equals, state.DoneStatus)
+}
@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"time"
 
 	"gopkg.in/tomb.v2"
@@ -43,6 +44,10 @@ type SnapManager struct {
 	backend managerBackend
 
 	runner *state.TaskRunner
+
+	autoRefresh *autoRefresh
+
+	cookiesSynced bool
 }
 
 // SnapSetupFlags are flags stored in SnapSetup to control snap manager tasks.
@@ -71,9 +76,35 @@ type SnapSetup struct {
 
 	DownloadInfo *snap.DownloadInfo `json:"download-info,omitempty"`
 	SideInfo     *snap.SideInfo     `json:"side-info,omitempty"`
+
+	// InstanceKey is set when installing the snap as an instance
+	// side by side with other instances of the same snap.
+	InstanceKey string `json:"instance-key,omitempty"`
 }
 
+// minimalSnapSetup returns a SnapSetup for the given revision of the
+// snap with the given instance name.
+func minimalSnapSetup(name string, revision snap.Revision) *SnapSetup {
+	snapName, instanceKey := snap.SplitInstanceName(name)
+	return &SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: snapName,
+			Revision: revision,
+		},
+		InstanceKey: instanceKey,
+	}
+}
+
+// Name returns the instance name of the snap.
 func (ss *SnapSetup) Name() string {
+	if ss.SideInfo.RealName == "" {
+		panic("SnapSetup.SideInfo.RealName not set")
+	}
+	return snap.InstanceName(ss.SideInfo.RealName, ss.InstanceKey)
+}
+
+// SnapName returns the name of the snap as known to the store.
+func (ss *SnapSetup) SnapName() string {
 	if ss.SideInfo.RealName == "" {
 		panic("SnapSetup.SideInfo.RealName not set")
 	}
@@ -101,6 +132,11 @@ func (ss *SnapSetup) JailMode() bool {
 	return Flags(ss.Flags).JailMode()
 }
 
+// Classic returns true if the snap is being installed with classic confinement.
+func (ss *SnapSetup) Classic() bool {
+	return Flags(ss.Flags).Classic()
+}
+
 func (ss *SnapSetup) DevModeAllowed() bool {
 	return Flags(ss.Flags).DevModeAllowed()
 }
@@ -124,6 +160,33 @@ type SnapState struct {
 	Current snap.Revision  `json:"current"`
 	Channel string         `json:"channel,omitempty"`
 	Flags   SnapStateFlags `json:"flags,omitempty"`
+	// Aliases holds the sorted names of the enabled aliases
+	Aliases []string `json:"aliases,omitempty"`
+	// Hold is set if refreshes of the snap are held back
+	Hold *RefreshHold `json:"hold,omitempty"`
+	// InstanceKey is set for instances of a snap installed side by
+	// side with other instances of the same snap
+	InstanceKey string `json:"instance-key,omitempty"`
+	// DisabledServices holds the services of the snap that were
+	// disabled when they were last stopped, they are left disabled
+	// when the services are started again
+	DisabledServices []string `json:"disabled-services,omitempty"`
+}
+
+// RefreshHold describes a hold on the refreshes of a snap.
+type RefreshHold struct {
+	// Until is when the hold expires, zero if it never does
+	Until time.Time `json:"until"`
+	// Revision is the revision the snap is pinned to while held
+	Revision snap.Revision `json:"revision"`
+}
+
+// Held returns whether refreshes of the snap are currently held back.
+func (snapst *SnapState) Held() bool {
+	if snapst.Hold == nil {
+		return false
+	}
+	return snapst.Hold.Until.IsZero() || timeNow().Before(snapst.Hold.Until)
 }
 
 // Type returns the type of the snap or an error.
@@ -225,8 +288,10 @@ func readInfoAnyway(name string, si *snap.SideInfo) (*snap.Info, error) {
 	info, err := snap.ReadInfo(name, si)
 	if _, ok := err.(*snap.NotFoundError); ok {
 		reason := fmt.Sprintf("cannot read snap %q: %s", name, err)
+		snapName, instanceKey := snap.SplitInstanceName(name)
 		info := &snap.Info{
-			SuggestedName: name,
+			SuggestedName: snapName,
+			InstanceKey:   instanceKey,
 			Broken:        reason,
 		}
 		info.Apps = snap.GuessAppsForBroken(info)
@@ -244,7 +309,7 @@ func (snapst *SnapState) CurrentInfo() (*snap.Info, error) {
 	if cur == nil {
 		return nil, ErrNoCurrent
 	}
-	return readInfo(cur.RealName, cur)
+	return readInfo(snap.InstanceName(cur.RealName, snapst.InstanceKey), cur)
 }
 
 // DevMode returns true if the snap is installed in developer mode.
@@ -278,6 +343,20 @@ func (snapst *SnapState) DevModeAllowed() bool {
 	return Flags(snapst.Flags).DevModeAllowed()
 }
 
+// Classic returns true if the snap is installed with classic confinement.
+func (snapst *SnapState) Classic() bool {
+	return Flags(snapst.Flags).Classic()
+}
+
+// SetClassic sets/clears the Classic flag in the SnapState.
+func (snapst *SnapState) SetClassic(active bool) {
+	if active {
+		snapst.Flags |= Classic
+	} else {
+		snapst.Flags &= ^Classic
+	}
+}
+
 // TryMode returns true if the snap is installed in `try` mode as an
 // unpacked directory.
 func (snapst *SnapState) TryMode() bool {
@@ -328,7 +407,19 @@ func updateInfo(st *state.State, snapst *SnapState, channel string, userID int,
 	theStore := Store(st)
 	st.Unlock() // calls to the store should be done without holding the state lock
 	res, err := theStore.ListRefresh([]*store.RefreshCandidate{refreshCand}, user)
+	if err == nil && len(res) == 0 && hasChannelFallbacks(channel) {
+		// the channel might be closed, in which case refresh from
+		// the channel it falls back to
+		info, ierr := snapInChannel(theStore, curInfo.SnapName(), channel, flags.DevModeAllowed(), user)
+		if ierr == nil && info.Channel != channel {
+			refreshCand.Channel = info.Channel
+			res, err = theStore.ListRefresh([]*store.RefreshCandidate{refreshCand}, user)
+		}
+	}
 	st.Lock()
+	if err != nil {
+		return nil, err
+	}
 	if len(res) == 0 {
 		return nil, fmt.Errorf("snap %q has no updates available", curInfo.Name())
 	}
@@ -342,9 +433,38 @@ func snapInfo(st *state.State, name, channel string, revision snap.Revision, use
 	}
 	theStore := Store(st)
 	st.Unlock() // calls to the store should be done without holding the state lock
-	snap, err := theStore.Snap(name, channel, flags.DevModeAllowed(), revision, user)
+	var info *snap.Info
+	if revision.Unset() {
+		info, err = snapInChannel(theStore, name, channel, flags.DevModeAllowed(), user)
+	} else {
+		info, err = theStore.Snap(name, channel, flags.DevModeAllowed(), revision, user)
+	}
 	st.Lock()
-	return snap, err
+	return info, err
+}
+
+func hasChannelFallbacks(channel string) bool {
+	ch, err := snap.ParseChannel(channel)
+	return err == nil && len(ch.Fallbacks()) > 0
+}
+
+// snapInChannel gets the details of the snap in the given channel from
+// the store, falling back to the less risky channels if it is closed.
+// The state must not be locked by the caller.
+func snapInChannel(theStore StoreService, name, channel string, devmode bool, user *auth.UserState) (*snap.Info, error) {
+	info, err := theStore.Snap(name, channel, devmode, snap.Revision{}, user)
+	if err != store.ErrSnapNotFound || !hasChannelFallbacks(channel) {
+		return info, err
+	}
+
+	ch, _ := snap.ParseChannel(channel)
+	for _, fallback := range ch.Fallbacks() {
+		info, err = theStore.Snap(name, fallback.String(), devmode, snap.Revision{}, user)
+		if err != store.ErrSnapNotFound {
+			break
+		}
+	}
+	return info, err
 }
 
 // Manager returns a new snap manager.
@@ -352,9 +472,10 @@ func Manager(s *state.State) (*SnapManager, error) {
 	runner := state.NewTaskRunner(s)
 
 	m := &SnapManager{
-		state:   s,
-		backend: backend.Backend{},
-		runner:  runner,
+		state:       s,
+		backend:     backend.Backend{},
+		runner:      runner,
+		autoRefresh: newAutoRefresh(s),
 	}
 
 	// this handler does nothing
@@ -364,8 +485,13 @@ func Manager(s *state.State) (*SnapManager, error) {
 
 	// install/update related
 	runner.AddHandler("prepare-snap", m.doPrepareSnap, m.undoPrepareSnap)
-	runner.AddHandler("download-snap", m.doDownloadSnap, m.undoPrepareSnap)
+	runner.AddHandler("download-snap", m.doDownloadSnap, m.undoDownloadSnap)
 	runner.AddHandler("mount-snap", m.doMountSnap, m.undoMountSnap)
+	// prerequisites has nothing to undo but must keep its place in the
+	// undo chain, otherwise mount-snap would be undone too early
+	runner.AddHandler("prerequisites", m.doPrerequisites, func(t *state.Task, _ *tomb.Tomb) error {
+		return nil
+	})
 	runner.AddHandler("unlink-current-snap", m.doUnlinkCurrentSnap, m.undoUnlinkCurrentSnap)
 	runner.AddHandler("copy-snap-data", m.doCopySnapData, m.undoCopySnapData)
 	runner.AddHandler("link-snap", m.doLinkSnap, m.undoLinkSnap)
@@ -384,6 +510,20 @@ func Manager(s *state.State) (*SnapManager, error) {
 	runner.AddHandler("clear-snap", m.doClearSnapData, nil)
 	runner.AddHandler("discard-snap", m.doDiscardSnap, nil)
 
+	// alias related
+	runner.AddHandler("alias", m.doAlias, m.undoAlias)
+	runner.AddHandler("unalias", m.doUnalias, m.undoUnalias)
+
+	// hold related
+	runner.AddHandler("hold-snap", m.doHoldSnap, m.undoHoldSnap)
+	runner.AddHandler("unhold-snap", m.doUnholdSnap, m.undoHoldSnap)
+
+	// channel related
+	runner.AddHandler("switch-snap-channel", m.doSwitchSnapChannel, m.undoSwitchSnapChannel)
+
+	// scheduling related
+	runner.AddHandler("wait-scheduled", m.doWaitScheduled, nil)
+
 	// test handlers
 	runner.AddHandler("fake-install-snap", func(t *state.Task, _ *tomb.Tomb) error {
 		return nil
@@ -428,6 +568,19 @@ func checkRevisionIsNew(name string, snapst *SnapState, revision snap.Revision)
 	return nil
 }
 
+// checkEpochCanRead checks that the revision refreshed to can read the
+// data written by the current revision of the snap.
+func checkEpochCanRead(info *snap.Info, snapst *SnapState) error {
+	curInfo, err := snapst.CurrentInfo()
+	if err != nil {
+		return err
+	}
+	if !snap.EpochCanRead(info.Epoch, curInfo.Epoch) {
+		return fmt.Errorf("cannot refresh snap %q to revision %s: its epoch %q cannot read data of epoch %q", info.Name(), info.Revision, info.Epoch, curInfo.Epoch)
+	}
+	return nil
+}
+
 func revisionInSequence(snapst *SnapState, needle snap.Revision) bool {
 	for _, si := range snapst.Sequence {
 		if si.Revision == needle {
@@ -490,25 +643,29 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
-	var downloadedSnapFile string
+	// download straight into the final location of the snap file,
+	// any interrupted download gets resumed from there
+	var targetFn string
 	if ss.DownloadInfo == nil {
 		// COMPATIBILITY - this task was created from an older version
 		// of snapd that did not store the DownloadInfo in the state
 		// yet.
-		storeInfo, err := theStore.Snap(ss.Name(), ss.Channel, ss.DevModeAllowed(), ss.Revision(), user)
+		storeInfo, err := theStore.Snap(ss.SnapName(), ss.Channel, ss.DevModeAllowed(), ss.Revision(), user)
 		if err != nil {
 			return err
 		}
-		downloadedSnapFile, err = theStore.Download(ss.Name(), &storeInfo.DownloadInfo, meter, user)
+		targetFn = storeInfo.MountFile()
+		err = theStore.Download(ss.Name(), targetFn, &storeInfo.DownloadInfo, meter, user)
 		ss.SideInfo = &storeInfo.SideInfo
 	} else {
-		downloadedSnapFile, err = theStore.Download(ss.Name(), ss.DownloadInfo, meter, user)
+		targetFn = snap.MinimalPlaceInfo(ss.Name(), ss.Revision()).MountFile()
+		err = theStore.Download(ss.Name(), targetFn, ss.DownloadInfo, meter, user)
 	}
 	if err != nil {
 		return err
 	}
 
-	ss.SnapPath = downloadedSnapFile
+	ss.SnapPath = targetFn
 	// update the snap setup for the follow up tasks
 	st.Lock()
 	t.Set("snap-setup", ss)
@@ -517,6 +674,31 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, _ *tomb.Tomb) error {
 	return nil
 }
 
+func (m *SnapManager) undoDownloadSnap(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	ss, snapst, err := snapSetupAndState(t)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// the downloaded file might still be in use by another revision
+	// in the sequence
+	if snapst.LastIndex(ss.Revision()) >= 0 {
+		return nil
+	}
+
+	targetFn := snap.MinimalPlaceInfo(ss.Name(), ss.Revision()).MountFile()
+	for _, fn := range []string{targetFn, targetFn + ".partial"} {
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (m *SnapManager) doUnlinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	// invoked only if snap has a current active revision
 
@@ -537,7 +719,10 @@ func (m *SnapManager) doUnlinkSnap(t *state.Task, _ *tomb.Tomb) error {
 
 	pb := &TaskProgressAdapter{task: t}
 	st.Unlock() // pb itself will ask for locking
-	err = m.backend.UnlinkSnap(info, pb)
+	err = m.removeAliases(info, snapst.Aliases)
+	if err == nil {
+		err = m.backend.UnlinkSnap(info, pb)
+	}
 	st.Lock()
 	if err != nil {
 		return err
@@ -632,15 +817,43 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 		}
 	}
 	st.Lock()
+	defer st.Unlock()
+	if len(snapst.Sequence) == 0 {
+		if err := removeSnapCookie(st, ss.Name()); err != nil {
+			return err
+		}
+	}
 	Set(st, ss.Name(), snapst)
-	st.Unlock()
 	return nil
 }
 
+// ensureCookies gives cookies to the snaps installed before there were
+// any, once per run.
+func (m *SnapManager) ensureCookies() {
+	if m.cookiesSynced {
+		return
+	}
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	if err := syncCookies(m.state); err != nil {
+		// snapctl won't work outside of hooks for the affected snaps
+		logger.Noticef("cannot sync snap cookies: %v", err)
+		return
+	}
+	m.cookiesSynced = true
+}
+
 // Ensure implements StateManager.Ensure.
 func (m *SnapManager) Ensure() error {
+	m.ensureCookies()
+
+	// do not exit right away on error
+	err := m.autoRefresh.Ensure()
+
 	m.runner.Ensure()
-	return nil
+
+	return err
 }
 
 // Wait implements StateManager.Wait.
@@ -728,14 +941,14 @@ func (m *SnapManager) doMountSnap(t *state.Task, _ *tomb.Tomb) error {
 
 	m.backend.CurrentInfo(curInfo)
 
-	if err := checkSnap(t.State(), ss.SnapPath, curInfo, Flags(ss.Flags)); err != nil {
+	if err := checkSnap(t.State(), ss.SnapPath, ss.SideInfo, curInfo, Flags(ss.Flags)); err != nil {
 		return err
 	}
 
 	pb := &TaskProgressAdapter{task: t}
 	// TODO Use ss.Revision() to obtain the right info to mount
 	//      instead of assuming the candidate is the right one.
-	if err := m.backend.SetupSnap(ss.SnapPath, ss.SideInfo, pb); err != nil {
+	if err := m.backend.SetupSnap(ss.SnapPath, ss.SideInfo, ss.InstanceKey, pb); err != nil {
 		return err
 	}
 
@@ -748,19 +961,109 @@ func (m *SnapManager) doMountSnap(t *state.Task, _ *tomb.Tomb) error {
 	t.Set("snap-type", newInfo.Type)
 	t.State().Unlock()
 
-	// cleanup the downloaded snap after it got installed
+	// cleanup the sideloaded snap after it got installed
 	// in backend.SetupSnap.
 	//
 	// Note that we always remove the file because the
 	// way sideloading works currently is to always create
-	// a temporary file (see daemon/api.go:sideloadSnap()
-	if err := os.Remove(ss.SnapPath); err != nil {
-		logger.Noticef("Failed to cleanup %q: %s", err)
+	// a temporary file (see daemon/api.go:sideloadSnap(),
+	// while downloaded snaps are already in their final
+	// location.
+	if ss.SnapPath != newInfo.MountFile() {
+		if err := os.Remove(ss.SnapPath); err != nil {
+			logger.Noticef("Failed to cleanup %q: %s", ss.SnapPath, err)
+		}
 	}
 
 	return nil
 }
 
+// doPrerequisites makes sure the base the snap runs on is installed,
+// queueing its installation into the change when it is missing. The
+// tasks of the change that follow wait for the base to be available.
+func (m *SnapManager) doPrerequisites(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	ss, err := TaskSnapSetup(t)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	info, err := readInfo(ss.Name(), ss.SideInfo)
+	if err != nil {
+		return err
+	}
+	if info.Base == "" {
+		return nil
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	var basest SnapState
+	if err := Get(st, info.Base, &basest); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if basest.HasCurrent() {
+		baseInfo, err := basest.CurrentInfo()
+		if err != nil {
+			return err
+		}
+		return checkBaseType(info, baseInfo)
+	}
+
+	// the base may be on its way already, from this or another change
+	if err := CheckChangeConflict(st, info.Base, nil); err != nil {
+		return &state.Retry{}
+	}
+
+	baseInfo, err := snapInfo(st, info.Base, "stable", snap.R(0), ss.UserID, 0)
+	if err != nil {
+		return fmt.Errorf("cannot install base %q of snap %q: %v", info.Base, ss.Name(), err)
+	}
+	if err := checkBaseType(info, baseInfo); err != nil {
+		return err
+	}
+
+	// the state was unlocked while talking to the store
+	if err := Get(st, info.Base, &basest); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if basest.HasCurrent() {
+		return nil
+	}
+	if err := CheckChangeConflict(st, info.Base, nil); err != nil {
+		return &state.Retry{}
+	}
+
+	ts, err := doInstall(st, &basest, &SnapSetup{
+		Channel:      "stable",
+		UserID:       ss.UserID,
+		DownloadInfo: &baseInfo.DownloadInfo,
+		SideInfo:     &baseInfo.SideInfo,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot install base %q of snap %q: %v", info.Base, ss.Name(), err)
+	}
+	for _, halted := range t.HaltTasks() {
+		halted.WaitAll(ts)
+	}
+	t.Change().AddAll(ts)
+	st.EnsureBefore(0)
+
+	return nil
+}
+
+// checkBaseType verifies that the snap named as the base of the given
+// snap is indeed a base snap.
+func checkBaseType(info, baseInfo *snap.Info) error {
+	if baseInfo.Type != snap.TypeBase {
+		return fmt.Errorf("cannot use snap %q as the base of snap %q: it is of type %q, not %q", baseInfo.Name(), info.Name(), baseInfo.Type, snap.TypeBase)
+	}
+	return nil
+}
+
 func (m *SnapManager) undoUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	st := t.State()
 
@@ -780,6 +1083,9 @@ func (m *SnapManager) undoUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	snapst.Active = true
 	st.Unlock()
 	err = m.backend.LinkSnap(oldInfo)
+	if err == nil {
+		err = m.addAliases(oldInfo, snapst.Aliases)
+	}
 	st.Lock()
 	if err != nil {
 		return err
@@ -812,7 +1118,10 @@ func (m *SnapManager) doUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 
 	pb := &TaskProgressAdapter{task: t}
 	st.Unlock() // pb itself will ask for locking
-	err = m.backend.UnlinkSnap(oldInfo, pb)
+	err = m.removeAliases(oldInfo, snapst.Aliases)
+	if err == nil {
+		err = m.backend.UnlinkSnap(oldInfo, pb)
+	}
 	st.Lock()
 	if err != nil {
 		return err
@@ -894,6 +1203,7 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	oldCurrent := snapst.Current
 	snapst.Current = cand.Revision
 	snapst.Active = true
+	snapst.InstanceKey = ss.InstanceKey
 	oldChannel := snapst.Channel
 	if ss.Channel != "" {
 		snapst.Channel = ss.Channel
@@ -904,6 +1214,8 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	snapst.SetDevMode(ss.DevMode())
 	oldJailMode := snapst.JailMode()
 	snapst.SetJailMode(ss.JailMode())
+	oldClassic := snapst.Classic()
+	snapst.SetClassic(ss.Classic())
 
 	newInfo, err := readInfo(ss.Name(), cand)
 	if err != nil {
@@ -913,9 +1225,16 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	// record type
 	snapst.SetType(newInfo.Type)
 
+	// keep only the aliases still declared by the new revision
+	oldAliases := snapst.Aliases
+	snapst.Aliases = declaredAliases(newInfo, oldAliases)
+
 	st.Unlock()
 	// XXX: this block is slightly ugly, find a pattern when we have more examples
 	err = m.backend.LinkSnap(newInfo)
+	if err == nil {
+		err = m.addAliases(newInfo, snapst.Aliases)
+	}
 	if err != nil {
 		pb := &TaskProgressAdapter{task: t}
 		err := m.backend.UnlinkSnap(newInfo, pb)
@@ -934,9 +1253,14 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	t.Set("old-trymode", oldTryMode)
 	t.Set("old-devmode", oldDevMode)
 	t.Set("old-jailmode", oldJailMode)
+	t.Set("old-classic", oldClassic)
 	t.Set("old-channel", oldChannel)
 	t.Set("old-current", oldCurrent)
 	t.Set("old-candidate-index", oldCandidateIndex)
+	t.Set("old-aliases", oldAliases)
+	if err := createSnapCookie(st, ss.Name()); err != nil {
+		return err
+	}
 	// Do at the end so we only preserve the new state if it worked.
 	Set(st, ss.Name(), snapst)
 	// Make sure if state commits and snapst is mutated we won't be rerun
@@ -991,6 +1315,12 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	// tasks from before classic confinement existed lack this
+	var oldClassic bool
+	err = t.Get("old-classic", &oldClassic)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
 	var oldCurrent snap.Revision
 	err = t.Get("old-current", &oldCurrent)
 	if err != nil {
@@ -1000,6 +1330,10 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err := t.Get("old-candidate-index", &oldCandidateIndex); err != nil {
 		return err
 	}
+	var oldAliases []string
+	if err := t.Get("old-aliases", &oldAliases); err != nil && err != state.ErrNoState {
+		return err
+	}
 
 	isRevert := ss.Flags.Revert()
 
@@ -1022,6 +1356,9 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	snapst.SetTryMode(oldTryMode)
 	snapst.SetDevMode(oldDevMode)
 	snapst.SetJailMode(oldJailMode)
+	snapst.SetClassic(oldClassic)
+	newAliases := snapst.Aliases
+	snapst.Aliases = oldAliases
 
 	newInfo, err := readInfo(ss.Name(), ss.SideInfo)
 	if err != nil {
@@ -1030,12 +1367,22 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 
 	pb := &TaskProgressAdapter{task: t}
 	st.Unlock() // pb itself will ask for locking
-	err = m.backend.UnlinkSnap(newInfo, pb)
+	err = m.removeAliases(newInfo, newAliases)
+	if err == nil {
+		err = m.backend.UnlinkSnap(newInfo, pb)
+	}
 	st.Lock()
 	if err != nil {
 		return err
 	}
 
+	if len(snapst.Sequence) == 0 {
+		// undoing the installation
+		if err := removeSnapCookie(st, ss.Name()); err != nil {
+			return err
+		}
+	}
+
 	// mark as inactive
 	Set(st, ss.Name(), snapst)
 	// Make sure if state commits and snapst is mutated we won't be rerun
@@ -1059,9 +1406,26 @@ func (m *SnapManager) startSnapServices(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	// the install-mode of the services only applies when the snap is
+	// installed, afterwards their enabled state is kept
+	disabled := snapst.DisabledServices
+	var firstInstall bool
+	if err := t.Get("first-install", &firstInstall); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if firstInstall {
+		disabled = nil
+		for _, app := range currentInfo.Apps {
+			if app.IsService() && app.InstallMode == "disable" {
+				disabled = append(disabled, app.Name)
+			}
+		}
+		sort.Strings(disabled)
+	}
+
 	pb := &TaskProgressAdapter{task: t}
 	st.Unlock()
-	err = m.backend.StartSnapServices(currentInfo, pb)
+	err = m.backend.StartSnapServices(currentInfo, disabled, pb)
 	st.Lock()
 	return err
 }
@@ -1082,12 +1446,32 @@ func (m *SnapManager) stopSnapServices(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	var reason snap.ServiceStopReason
+	if err := t.Get("stop-reason", &reason); err != nil && err != state.ErrNoState {
+		return err
+	}
+
 	pb := &TaskProgressAdapter{task: t}
 	st.Unlock()
-	err = m.backend.StopSnapServices(currentInfo, pb)
+	disabled, err := m.backend.DisabledSnapServices(currentInfo, pb)
+	if err == nil {
+		err = m.backend.StopSnapServices(currentInfo, reason, pb)
+	}
 	st.Lock()
+	if err != nil {
+		return err
+	}
 
-	return err
+	// remember which services were disabled, to keep them so when
+	// starting the services again
+	ss, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	snapst.DisabledServices = disabled
+	Set(st, ss.Name(), snapst)
+
+	return nil
 }
 
 func (m *SnapManager) cleanup(t *state.Task, _ *tomb.Tomb) error {
@@ -1112,3 +1496,40 @@ func (m *SnapManager) cleanup(t *state.Task, _ *tomb.Tomb) error {
 
 	return nil
 }
+
+func (m *SnapManager) doSwitchSnapChannel(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ss, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+
+	// save for undoSwitchSnapChannel
+	t.Set("old-channel", snapst.Channel)
+
+	snapst.Channel = ss.Channel
+	Set(st, ss.Name(), snapst)
+	return nil
+}
+
+func (m *SnapManager) undoSwitchSnapChannel(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ss, snapst, err := snapSetupAndState(t)
+	if err != nil {
+		return err
+	}
+	var oldChannel string
+	if err := t.Get("old-channel", &oldChannel); err != nil {
+		return err
+	}
+
+	snapst.Channel = oldChannel
+	Set(st, ss.Name(), snapst)
+	return nil
+}
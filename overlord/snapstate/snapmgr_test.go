@@ -37,6 +37,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/testutil"
 )
 
 func TestSnapManager(t *testing.T) { TestingT(t) }
@@ -64,6 +65,9 @@ func (s *snapmgrTestSuite) settle() {
 var _ = Suite(&snapmgrTestSuite{})
 
 func (s *snapmgrTestSuite) SetUpTest(c *C) {
+	// keep snap cookies away from the real system
+	oldCookieDir := dirs.SnapCookieDir
+	dirs.SnapCookieDir = c.MkDir()
 	s.fakeBackend = &fakeSnappyBackend{}
 	s.state = state.New(nil)
 	s.fakeStore = &fakeStore{
@@ -86,6 +90,7 @@ func (s *snapmgrTestSuite) SetUpTest(c *C) {
 	s.reset = func() {
 		restore2()
 		restore1()
+		dirs.SnapCookieDir = oldCookieDir
 	}
 
 	s.state.Lock()
@@ -97,6 +102,11 @@ func (s *snapmgrTestSuite) SetUpTest(c *C) {
 
 func (s *snapmgrTestSuite) TearDownTest(c *C) {
 	snapstate.ValidateRefreshes = nil
+	snapstate.AutomaticSnapshot = nil
+	snapstate.SetupInstallHook = nil
+	snapstate.SetupPreRefreshHook = nil
+	snapstate.SetupPostRefreshHook = nil
+	snapstate.SetupRemoveHook = nil
 	s.reset()
 }
 
@@ -116,7 +126,7 @@ func (s *snapmgrTestSuite) TestStore(c *C) {
 
 func verifyInstallUpdateTasks(c *C, curActive bool, ts *state.TaskSet, st *state.State) int {
 	i := 0
-	n := 7
+	n := 8
 	if curActive {
 		n += 2
 	}
@@ -126,6 +136,8 @@ func verifyInstallUpdateTasks(c *C, curActive bool, ts *state.TaskSet, st *state
 	i++
 	c.Assert(ts.Tasks()[i].Kind(), Equals, "mount-snap")
 	i++
+	c.Assert(ts.Tasks()[i].Kind(), Equals, "prerequisites")
+	i++
 	if curActive {
 		c.Assert(ts.Tasks()[i].Kind(), Equals, "stop-snap-services")
 		i++
@@ -180,10 +192,12 @@ func (s *snapmgrTestSuite) TestRevertTasks(c *C) {
 	c.Assert(err, IsNil)
 
 	i := 0
-	c.Assert(ts.Tasks(), HasLen, 6)
-	c.Assert(s.state.NumTask(), Equals, 6)
+	c.Assert(ts.Tasks(), HasLen, 7)
+	c.Assert(s.state.NumTask(), Equals, 7)
 	c.Assert(ts.Tasks()[i].Kind(), Equals, "prepare-snap")
 	i++
+	c.Assert(ts.Tasks()[i].Kind(), Equals, "prerequisites")
+	i++
 	c.Assert(ts.Tasks()[i].Kind(), Equals, "stop-snap-services")
 	i++
 	c.Assert(ts.Tasks()[i].Kind(), Equals, "unlink-current-snap")
@@ -378,10 +392,12 @@ func (s *snapmgrTestSuite) TestRevertCreatesNoGCTasks(c *C) {
 
 	// ensure that we do not run any form of garbage-collection
 	i := 0
-	c.Assert(ts.Tasks(), HasLen, 6)
-	c.Assert(s.state.NumTask(), Equals, 6)
+	c.Assert(ts.Tasks(), HasLen, 7)
+	c.Assert(s.state.NumTask(), Equals, 7)
 	c.Assert(ts.Tasks()[i].Kind(), Equals, "prepare-snap")
 	i++
+	c.Assert(ts.Tasks()[i].Kind(), Equals, "prerequisites")
+	i++
 	c.Assert(ts.Tasks()[i].Kind(), Equals, "stop-snap-services")
 	i++
 	c.Assert(ts.Tasks()[i].Kind(), Equals, "unlink-current-snap")
@@ -642,6 +658,184 @@ func (s *snapmgrTestSuite) TestUpdateChannelFallback(c *C) {
 	c.Check(ss.Channel, Equals, "edge")
 }
 
+func (s *snapmgrTestSuite) TestUpdateClosedChannelFallsBackToLessRisky(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Channel:  "closed/candidate",
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+	})
+
+	ts, err := snapstate.Update(s.state, "some-snap", "", snap.R(0), s.user.ID, 0)
+	c.Assert(err, IsNil)
+
+	var ss snapstate.SnapSetup
+	err = ts.Tasks()[0].Get("snap-setup", &ss)
+	c.Assert(err, IsNil)
+
+	// still tracking the closed channel, but refreshed from stable
+	c.Check(ss.Channel, Equals, "closed/candidate")
+	c.Check(ss.SideInfo.Channel, Equals, "closed/stable")
+	c.Check(ss.Revision(), Equals, snap.R(11))
+}
+
+func (s *snapmgrTestSuite) TestUpdateRefreshErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Channel:  "closed/candidate",
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+	})
+
+	for _, channel := range []string{"closed/candidate", "closed/stable"} {
+		s.fakeStore.listRefreshErrs = map[string]error{
+			channel: fmt.Errorf("cannot refresh from %s", channel),
+		}
+		_, err := snapstate.Update(s.state, "some-snap", "", snap.R(0), s.user.ID, 0)
+		c.Check(err, ErrorMatches, "cannot refresh from "+channel)
+	}
+	s.fakeStore.listRefreshErrs = nil
+}
+
+func (s *snapmgrTestSuite) TestInstallClosedChannelFallsBackToLessRisky(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	ts, err := snapstate.Install(s.state, "some-snap", "closed/candidate", snap.R(0), 0, 0)
+	c.Assert(err, IsNil)
+
+	var ss snapstate.SnapSetup
+	err = ts.Tasks()[0].Get("snap-setup", &ss)
+	c.Assert(err, IsNil)
+
+	c.Check(ss.Channel, Equals, "closed/candidate")
+	c.Check(ss.SideInfo.Channel, Equals, "closed/stable")
+}
+
+func (s *snapmgrTestSuite) TestInstallUpdateInvalidChannel(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := snapstate.Install(s.state, "some-snap", "a/b/c/d", snap.R(0), 0, 0)
+	c.Check(err, ErrorMatches, `invalid channel name "a/b/c/d": too many components`)
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+	})
+
+	_, err = snapstate.Update(s.state, "some-snap", "latest/risky", snap.R(0), s.user.ID, 0)
+	c.Check(err, ErrorMatches, `invalid channel name "latest/risky": invalid risk "risky"`)
+}
+
+func (s *snapmgrTestSuite) TestSwitchTasks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Channel:  "edge",
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+	})
+
+	ts, err := snapstate.Switch(s.state, "some-snap", "beta")
+	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 1)
+	t := ts.Tasks()[0]
+	c.Check(t.Kind(), Equals, "switch-snap-channel")
+	c.Check(t.Summary(), Equals, `Switch snap "some-snap" to channel "beta"`)
+
+	var ss snapstate.SnapSetup
+	c.Assert(t.Get("snap-setup", &ss), IsNil)
+	c.Check(ss.Name(), Equals, "some-snap")
+	c.Check(ss.Channel, Equals, "beta")
+	c.Check(ss.Revision(), Equals, snap.R(7))
+}
+
+func (s *snapmgrTestSuite) TestSwitchErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := snapstate.Switch(s.state, "some-snap", "beta")
+	c.Check(err, ErrorMatches, `cannot find snap "some-snap"`)
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+	})
+
+	_, err = snapstate.Switch(s.state, "some-snap", "")
+	c.Check(err, ErrorMatches, `cannot switch snap "some-snap" to an empty channel`)
+
+	_, err = snapstate.Switch(s.state, "some-snap", "beta//")
+	c.Check(err, ErrorMatches, `invalid channel name "beta//": .*`)
+
+	ts, err := snapstate.Switch(s.state, "some-snap", "beta")
+	c.Assert(err, IsNil)
+	chg := s.state.NewChange("switch-snap", "switch snap")
+	chg.AddAll(ts)
+
+	_, err = snapstate.Switch(s.state, "some-snap", "edge")
+	c.Check(err, ErrorMatches, `snap "some-snap" has changes in progress`)
+}
+
+func (s *snapmgrTestSuite) TestSwitchRunThroughAndUndo(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Channel:  "edge",
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+	})
+
+	chg := s.state.NewChange("switch-snap", "switch snap")
+	ts, err := snapstate.Switch(s.state, "some-snap", "beta")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus)
+
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Channel, Equals, "beta")
+	c.Check(snapst.Current, Equals, snap.R(7))
+	// no refresh happened
+	c.Check(s.fakeBackend.ops, HasLen, 0)
+
+	chg = s.state.NewChange("switch-snap", "switch snap")
+	ts, err = snapstate.Switch(s.state, "some-snap", "stable")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	terr := s.state.NewTask("fake-install-snap-error", "fail")
+	terr.WaitAll(ts)
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Channel, Equals, "beta")
+}
+
 func (s *snapmgrTestSuite) TestUpdatePassDevMode(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -669,82 +863,679 @@ func (s *snapmgrTestSuite) TestUpdatePassDevMode(c *C) {
 	})
 }
 
-func (s *snapmgrTestSuite) TestUpdateConflict(c *C) {
+func (s *snapmgrTestSuite) TestUpdateKeepsClassic(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+		Flags:    snapstate.SnapStateFlags(snapstate.Classic),
+	})
+
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, 0)
+	c.Assert(err, IsNil)
+
+	var ss snapstate.SnapSetup
+	err = ts.Tasks()[0].Get("snap-setup", &ss)
+	c.Assert(err, IsNil)
+	c.Check(ss.Classic(), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestUpdateRefusesIncompatibleEpoch(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+		Channel:  "channel-for-epoch-2",
+	})
+
+	_, err := snapstate.Update(s.state, "some-snap", "channel-for-epoch-2", snap.R(0), s.user.ID, 0)
+	c.Assert(err, ErrorMatches, `cannot refresh snap "some-snap" to revision 11: its epoch "2" cannot read data of epoch ""`)
+
+	// refreshing everything skips the snap
+	updates, tts, err := snapstate.UpdateMany(s.state, nil, 0)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 0)
+	c.Check(tts, HasLen, 0)
+
+	// but asking for it explicitly reports the problem
+	_, _, err = snapstate.UpdateMany(s.state, []string{"some-snap"}, 0)
+	c.Assert(err, ErrorMatches, `cannot refresh snap "some-snap" to revision 11: its epoch "2" cannot read data of epoch ""`)
+}
+
+func (s *snapmgrTestSuite) TestUpdateConflict(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+	})
+
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, 0)
+	c.Assert(err, IsNil)
+	// need a change to make the tasks visible
+	s.state.NewChange("refresh", "...").AddAll(ts)
+
+	_, err = snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, 0)
+	c.Assert(err, ErrorMatches, `snap "some-snap" has changes in progress`)
+}
+
+// mockSnapHooks makes the snap hooks of the install, refresh and
+// remove task chains be run by tasks of the given kind.
+func mockSnapHooks(kind string) {
+	mock := func(hookName string) func(st *state.State, snapName string) *state.Task {
+		return func(st *state.State, snapName string) *state.Task {
+			return st.NewTask(kind, fmt.Sprintf("%s hook of %q", hookName, snapName))
+		}
+	}
+	snapstate.SetupInstallHook = mock("install")
+	snapstate.SetupPreRefreshHook = mock("pre-refresh")
+	snapstate.SetupPostRefreshHook = mock("post-refresh")
+	snapstate.SetupRemoveHook = mock("remove")
+}
+
+func taskSummaries(ts *state.TaskSet) []string {
+	summaries := make([]string, len(ts.Tasks()))
+	for i, t := range ts.Tasks() {
+		summaries[i] = t.Summary()
+	}
+	return summaries
+}
+
+func (s *snapmgrTestSuite) TestInstallTasksWithHooks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	mockSnapHooks("fake-install-snap")
+
+	ts, err := snapstate.Install(s.state, "some-snap", "some-channel", snap.R(0), 0, 0)
+	c.Assert(err, IsNil)
+
+	summaries := taskSummaries(ts)
+	c.Assert(summaries, HasLen, 9)
+	c.Check(summaries[7], Equals, `install hook of "some-snap"`)
+	c.Check(ts.Tasks()[7].WaitTasks(), DeepEquals, []*state.Task{ts.Tasks()[6]})
+	c.Check(ts.Tasks()[6].Kind(), Equals, "link-snap")
+	c.Check(ts.Tasks()[8].Kind(), Equals, "start-snap-services")
+}
+
+func (s *snapmgrTestSuite) TestUpdateTasksWithHooks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+		Current:  snap.R(7),
+	})
+	mockSnapHooks("fake-install-snap")
+
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, 0)
+	c.Assert(err, IsNil)
+
+	summaries := taskSummaries(ts)
+	c.Assert(summaries, HasLen, 13)
+	c.Check(summaries[4], Equals, `pre-refresh hook of "some-snap"`)
+	c.Check(ts.Tasks()[5].Kind(), Equals, "stop-snap-services")
+	c.Check(ts.Tasks()[9].Kind(), Equals, "link-snap")
+	c.Check(summaries[10], Equals, `post-refresh hook of "some-snap"`)
+	c.Check(ts.Tasks()[11].Kind(), Equals, "start-snap-services")
+}
+
+func (s *snapmgrTestSuite) TestUpdatePreRefreshHookFailureUndoes(c *C) {
+	si := snap.SideInfo{
+		RealName: "some-snap",
+		Revision: snap.R(7),
+		SnapID:   "some-snap-id",
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si},
+		Current:  si.Revision,
+	})
+	snapstate.SetupPreRefreshHook = func(st *state.State, snapName string) *state.Task {
+		return st.NewTask("fake-install-snap-error", "pre-refresh hook")
+	}
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*fake-install-snap-error errored.*`)
+
+	ops := s.fakeBackend.ops.Ops()
+	c.Check(ops, Not(testutil.Contains), "stop-snap-services")
+	c.Check(ops, Not(testutil.Contains), "link-snap")
+	c.Check(ops, testutil.Contains, "undo-setup-snap")
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Active, Equals, true)
+	c.Check(snapst.Current, Equals, snap.R(7))
+}
+
+func (s *snapmgrTestSuite) TestRemoveTasksWithHooks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(11)},
+		},
+		Current: snap.R(11),
+	})
+	mockSnapHooks("fake-install-snap")
+
+	ts, err := snapstate.Remove(s.state, "foo", snap.R(0), 0)
+	c.Assert(err, IsNil)
+
+	c.Assert(ts.Tasks(), HasLen, 7)
+	c.Check(ts.Tasks()[0].Summary(), Equals, `remove hook of "foo"`)
+	c.Check(ts.Tasks()[1].Kind(), Equals, "stop-snap-services")
+	c.Check(ts.Tasks()[1].WaitTasks(), DeepEquals, []*state.Task{ts.Tasks()[0]})
+}
+
+func (s *snapmgrTestSuite) TestRemoveTasks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(11)},
+		},
+		Current: snap.R(11),
+	})
+
+	ts, err := snapstate.Remove(s.state, "foo", snap.R(0), 0)
+	c.Assert(err, IsNil)
+
+	i := 0
+	c.Assert(ts.Tasks(), HasLen, 6)
+	// all tasks are accounted
+	c.Assert(s.state.NumTask(), Equals, 6)
+	c.Assert(ts.Tasks()[i].Kind(), Equals, "stop-snap-services")
+	i++
+	c.Assert(ts.Tasks()[i].Kind(), Equals, "unlink-snap")
+	i++
+	c.Assert(ts.Tasks()[i].Kind(), Equals, "remove-profiles")
+	i++
+	c.Assert(ts.Tasks()[i].Kind(), Equals, "clear-snap")
+	i++
+	c.Assert(ts.Tasks()[i].Kind(), Equals, "discard-snap")
+	i++
+	c.Assert(ts.Tasks()[i].Kind(), Equals, "discard-conns")
+}
+
+func (s *snapmgrTestSuite) TestRemoveTasksAutomaticSnapshot(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	var snapshotted []string
+	snapstate.AutomaticSnapshot = func(st *state.State, snapName string) (*state.TaskSet, error) {
+		snapshotted = append(snapshotted, snapName)
+		return state.NewTaskSet(st.NewTask("save-snapshot", "...")), nil
+	}
+
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(11)},
+		},
+		Current: snap.R(11),
+	})
+
+	ts, err := snapstate.Remove(s.state, "foo", snap.R(0), 0)
+	c.Assert(err, IsNil)
+	c.Check(snapshotted, DeepEquals, []string{"foo"})
+
+	kinds := make([]string, len(ts.Tasks()))
+	for i, t := range ts.Tasks() {
+		kinds[i] = t.Kind()
+	}
+	c.Check(kinds, DeepEquals, []string{"stop-snap-services", "unlink-snap", "remove-profiles", "save-snapshot", "clear-snap", "discard-snap", "discard-conns"})
+	// the snapshot is taken once the snap is unlinked, and before its data is cleared
+	c.Check(ts.Tasks()[3].WaitTasks(), DeepEquals, []*state.Task{ts.Tasks()[0], ts.Tasks()[1], ts.Tasks()[2]})
+	c.Check(ts.Tasks()[4].WaitTasks(), DeepEquals, []*state.Task{ts.Tasks()[3]})
+
+	// no snapshot when purging
+	snapshotted = nil
+	s.state.NewChange("remove", "...").AddAll(ts)
+	snapstate.Set(s.state, "bar", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "bar", Revision: snap.R(1)},
+		},
+		Current: snap.R(1),
+	})
+	ts, err = snapstate.Remove(s.state, "bar", snap.R(0), snapstate.Purge)
+	c.Assert(err, IsNil)
+	c.Check(snapshotted, HasLen, 0)
+	c.Check(ts.Tasks(), HasLen, 6)
+}
+
+func (s *snapmgrTestSuite) TestRemoveConflict(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap", Revision: snap.R(11)}},
+		Current:  snap.R(11),
+	})
+
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0), 0)
+	c.Assert(err, IsNil)
+	// need a change to make the tasks visible
+	s.state.NewChange("remove", "...").AddAll(ts)
+
+	_, err = snapstate.Remove(s.state, "some-snap", snap.R(0), 0)
+	c.Assert(err, ErrorMatches, `snap "some-snap" has changes in progress`)
+}
+
+func (s *snapmgrTestSuite) TestInstallRunThrough(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(s.state, "some-snap", "some-channel", snap.R(42), s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	// ensure all our tasks ran
+	c.Assert(chg.Err(), IsNil)
+	c.Check(s.fakeStore.downloads, DeepEquals, []fakeDownload{{
+		macaroon: s.user.Macaroon,
+		name:     "some-snap",
+		target:   filepath.Join(dirs.SnapBlobDir, "some-snap_42.snap"),
+	}})
+	c.Assert(s.fakeBackend.ops, DeepEquals, fakeOps{
+		{
+			op:    "storesvc-snap",
+			name:  "some-snap",
+			revno: snap.R(42),
+		},
+		{
+			op:   "storesvc-download",
+			name: "some-snap",
+		},
+		{
+			op:    "validate-snap:Doing",
+			name:  "some-snap",
+			revno: snap.R(42),
+		},
+		{
+			op:  "current",
+			old: "<no-current>",
+		},
+		{
+			op:   "open-snap-file",
+			name: filepath.Join(dirs.SnapBlobDir, "some-snap_42.snap"),
+			sinfo: snap.SideInfo{
+				RealName: "some-snap",
+				SnapID:   "snapIDsnapidsnapidsnapidsnapidsn",
+				Revision: snap.R(42),
+				Channel:  "some-channel",
+			},
+		},
+		{
+			op:    "setup-snap",
+			name:  filepath.Join(dirs.SnapBlobDir, "some-snap_42.snap"),
+			revno: snap.R(42),
+		},
+		{
+			op:   "copy-data",
+			name: "/snap/some-snap/42",
+			old:  "<no-old>",
+		},
+		{
+			op:    "setup-profiles:Doing",
+			name:  "some-snap",
+			revno: snap.R(42),
+		},
+		{
+			op: "candidate",
+			sinfo: snap.SideInfo{
+				RealName: "some-snap",
+				Channel:  "some-channel",
+				SnapID:   "snapIDsnapidsnapidsnapidsnapidsn",
+				Revision: snap.R(42),
+			},
+		},
+		{
+			op:   "link-snap",
+			name: "/snap/some-snap/42",
+		},
+		{
+			op:   "start-snap-services",
+			name: "/snap/some-snap/42",
+		},
+	})
+
+	// check progress
+	ta := ts.Tasks()
+	task := ta[0]
+	_, cur, total := task.Progress()
+	c.Assert(cur, Equals, s.fakeStore.fakeCurrentProgress)
+	c.Assert(total, Equals, s.fakeStore.fakeTotalProgress)
+	c.Check(task.Summary(), Equals, `Download snap "some-snap" (42) from channel "some-channel"`)
+
+	// check link/start snap summary
+	linkTask := ta[len(ta)-2]
+	c.Check(linkTask.Summary(), Equals, `Make snap "some-snap" (42) available to the system`)
+	startTask := ta[len(ta)-1]
+	c.Check(startTask.Summary(), Equals, `Start snap "some-snap" (42) services`)
+
+	// verify snap-setup in the task state
+	var ss snapstate.SnapSetup
+	err = task.Get("snap-setup", &ss)
+	c.Assert(err, IsNil)
+	c.Assert(ss, DeepEquals, snapstate.SnapSetup{
+		Channel:  "some-channel",
+		UserID:   s.user.ID,
+		SnapPath: filepath.Join(dirs.SnapBlobDir, "some-snap_42.snap"),
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "https://some-server.com/some/path.snap",
+		},
+		SideInfo: ss.SideInfo,
+	})
+	c.Assert(ss.SideInfo, DeepEquals, &snap.SideInfo{
+		RealName: "some-snap",
+		Revision: snap.R(42),
+		Channel:  "some-channel",
+		SnapID:   "snapIDsnapidsnapidsnapidsnapidsn",
+	})
+
+	// verify snaps in the system state
+	var snaps map[string]*snapstate.SnapState
+	err = s.state.Get("snaps", &snaps)
+	c.Assert(err, IsNil)
+
+	snapst := snaps["some-snap"]
+	c.Assert(snapst.Active, Equals, true)
+	c.Assert(snapst.Channel, Equals, "some-channel")
+	c.Assert(snapst.Sequence[0], DeepEquals, &snap.SideInfo{
+		RealName: "some-snap",
+		Channel:  "some-channel",
+		SnapID:   "snapIDsnapidsnapidsnapidsnapidsn",
+		Revision: snap.R(42),
+	})
+}
+
+func (s *snapmgrTestSuite) TestInstallInstanceRunThrough(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(s.state, "some-snap_instance", "some-channel", snap.R(42), s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(s.fakeStore.downloads, DeepEquals, []fakeDownload{{
+		macaroon: s.user.Macaroon,
+		name:     "some-snap_instance",
+		target:   filepath.Join(dirs.SnapBlobDir, "some-snap_instance_42.snap"),
+	}})
+	// the store is asked about the snap itself
+	c.Check(s.fakeBackend.ops[0], DeepEquals, fakeOp{
+		op:    "storesvc-snap",
+		name:  "some-snap",
+		revno: snap.R(42),
+	})
+	c.Check(s.fakeBackend.ops.Ops(), DeepEquals, []string{
+		"storesvc-snap",
+		"storesvc-download",
+		"validate-snap:Doing",
+		"current",
+		"open-snap-file",
+		"setup-snap",
+		"copy-data",
+		"setup-profiles:Doing",
+		"candidate",
+		"link-snap",
+		"start-snap-services",
+	})
+	c.Check(s.fakeBackend.ops.First("link-snap").name, Equals, "/snap/some-snap_instance/42")
+
+	var ss snapstate.SnapSetup
+	err = ts.Tasks()[0].Get("snap-setup", &ss)
+	c.Assert(err, IsNil)
+	c.Check(ss.InstanceKey, Equals, "instance")
+	c.Check(ss.Name(), Equals, "some-snap_instance")
+	c.Check(ss.SnapName(), Equals, "some-snap")
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap_instance", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Active, Equals, true)
+	c.Check(snapst.InstanceKey, Equals, "instance")
+	c.Check(snapst.Sequence[0].RealName, Equals, "some-snap")
+
+	info, err := snapst.CurrentInfo()
+	c.Assert(err, IsNil)
+	c.Check(info.Name(), Equals, "some-snap_instance")
+	c.Check(info.SnapName(), Equals, "some-snap")
+
+	// the main instance is not installed
+	err = snapstate.Get(s.state, "some-snap", &snapst)
+	c.Check(err, Equals, state.ErrNoState)
+}
+
+func (s *snapmgrTestSuite) TestInstallInstanceErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := snapstate.Install(s.state, "some-snap_Bad", "", snap.R(0), 0, 0)
+	c.Check(err, ErrorMatches, `invalid instance key: "Bad"`)
+
+	_, err = snapstate.Install(s.state, "some-gadget_instance", "", snap.R(0), 0, 0)
+	c.Check(err, ErrorMatches, `cannot install snap "some-gadget" as instance "some-gadget_instance", only application snaps can have instances`)
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyInstances(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	for _, instanceKey := range []string{"", "instance"} {
+		snapstate.Set(s.state, snap.InstanceName("some-snap", instanceKey), &snapstate.SnapState{
+			Active:      true,
+			Sequence:    []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
+			Current:     snap.R(7),
+			InstanceKey: instanceKey,
+		})
+	}
+
+	updates, tts, err := snapstate.UpdateMany(s.state, nil, 0)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap", "some-snap_instance"})
+	c.Assert(tts, HasLen, 2)
+
+	// the store was asked about each instance separately
+	c.Check(s.fakeBackend.ops.Ops(), DeepEquals, []string{"storesvc-list-refresh", "storesvc-list-refresh"})
+
+	var ss snapstate.SnapSetup
+	err = tts[1].Tasks()[0].Get("snap-setup", &ss)
+	c.Assert(err, IsNil)
+	c.Check(ss.Name(), Equals, "some-snap_instance")
+	c.Check(ss.Revision(), Equals, snap.R(11))
+}
+
+func (s *snapmgrTestSuite) TestInstallWithBaseRunThrough(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(s.state, "some-snap-with-base", "some-channel", snap.R(0), s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(chg.IsReady(), Equals, true)
+
+	// the base got installed into the same change, before the snap
+	// using it was made available
+	var linked []string
+	for _, op := range s.fakeBackend.ops {
+		if op.op == "link-snap" {
+			linked = append(linked, op.name)
+		}
+	}
+	c.Check(linked, DeepEquals, []string{"/snap/some-base/11", "/snap/some-snap-with-base/11"})
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-base", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Active, Equals, true)
+	err = snapstate.Get(s.state, "some-snap-with-base", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Active, Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestInstallWithBaseAlreadyInstalled(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-base", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-base", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+	})
+
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(s.state, "some-snap-with-base", "some-channel", snap.R(0), s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	n := len(chg.Tasks())
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Tasks(), HasLen, n)
+}
+
+func (s *snapmgrTestSuite) TestInstallWithBaseNotOfTypeBase(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
-		Active:   true,
-		Sequence: []*snap.SideInfo{{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)}},
-		Current:  snap.R(7),
-	})
-
-	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, 0)
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(s.state, "some-snap-with-app-base", "some-channel", snap.R(0), s.user.ID, 0)
 	c.Assert(err, IsNil)
-	// need a change to make the tasks visible
-	s.state.NewChange("refresh", "...").AddAll(ts)
+	chg.AddAll(ts)
 
-	_, err = snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, 0)
-	c.Assert(err, ErrorMatches, `snap "some-snap" has changes in progress`)
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Err(), ErrorMatches, `(?s).*cannot use snap "some-other-snap" as the base of snap "some-snap-with-app-base": it is of type "app", not "base".*`)
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-other-snap", &snapst)
+	c.Check(err, Equals, state.ErrNoState)
 }
 
-func (s *snapmgrTestSuite) TestRemoveTasks(c *C) {
+func (s *snapmgrTestSuite) TestInstallWithBaseAlreadyInstalledNotOfTypeBase(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	snapstate.Set(s.state, "foo", &snapstate.SnapState{
-		Active: true,
-		Sequence: []*snap.SideInfo{
-			{RealName: "foo", Revision: snap.R(11)},
-		},
-		Current: snap.R(11),
+	snapstate.Set(s.state, "some-other-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-other-snap", Revision: snap.R(1)}},
+		Current:  snap.R(1),
 	})
 
-	ts, err := snapstate.Remove(s.state, "foo", snap.R(0))
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(s.state, "some-snap-with-app-base", "some-channel", snap.R(0), s.user.ID, 0)
 	c.Assert(err, IsNil)
+	chg.AddAll(ts)
 
-	i := 0
-	c.Assert(ts.Tasks(), HasLen, 6)
-	// all tasks are accounted
-	c.Assert(s.state.NumTask(), Equals, 6)
-	c.Assert(ts.Tasks()[i].Kind(), Equals, "stop-snap-services")
-	i++
-	c.Assert(ts.Tasks()[i].Kind(), Equals, "unlink-snap")
-	i++
-	c.Assert(ts.Tasks()[i].Kind(), Equals, "remove-profiles")
-	i++
-	c.Assert(ts.Tasks()[i].Kind(), Equals, "clear-snap")
-	i++
-	c.Assert(ts.Tasks()[i].Kind(), Equals, "discard-snap")
-	i++
-	c.Assert(ts.Tasks()[i].Kind(), Equals, "discard-conns")
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Err(), ErrorMatches, `(?s).*cannot use snap "some-other-snap" as the base of snap "some-snap-with-app-base": it is of type "app", not "base".*`)
 }
 
-func (s *snapmgrTestSuite) TestRemoveConflict(c *C) {
+func (s *snapmgrTestSuite) TestRemoveBaseInUse(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
-	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+	snapstate.Set(s.state, "some-base", &snapstate.SnapState{
 		Active:   true,
-		Sequence: []*snap.SideInfo{{RealName: "some-snap", Revision: snap.R(11)}},
-		Current:  snap.R(11),
+		Sequence: []*snap.SideInfo{{RealName: "some-base", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+	})
+	snapstate.Set(s.state, "some-snap-with-base", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "some-snap-with-base", Revision: snap.R(2)}},
+		Current:  snap.R(2),
 	})
 
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0))
-	c.Assert(err, IsNil)
-	// need a change to make the tasks visible
-	s.state.NewChange("remove", "...").AddAll(ts)
+	_, err := snapstate.Remove(s.state, "some-base", snap.R(0), 0)
+	c.Check(err, ErrorMatches, `snap "some-base" is not removable, it is the base of: some-snap-with-base`)
 
-	_, err = snapstate.Remove(s.state, "some-snap", snap.R(0))
-	c.Assert(err, ErrorMatches, `snap "some-snap" has changes in progress`)
+	// once nothing uses it anymore the base can go
+	snapstate.Set(s.state, "some-snap-with-base", nil)
+	_, err = snapstate.Remove(s.state, "some-base", snap.R(0), 0)
+	c.Check(err, IsNil)
 }
 
-func (s *snapmgrTestSuite) TestInstallRunThrough(c *C) {
+func (s *snapmgrTestSuite) startedServices(c *C) [][]string {
+	var disabled [][]string
+	for _, op := range s.fakeBackend.ops {
+		if op.op == "start-snap-services" {
+			disabled = append(disabled, op.disabledServices)
+		}
+	}
+	return disabled
+}
+
+func (s *snapmgrTestSuite) TestInstallAppliesServicesInstallMode(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
 	chg := s.state.NewChange("install", "install a snap")
-	ts, err := snapstate.Install(s.state, "some-snap", "some-channel", snap.R(42), s.user.ID, 0)
+	ts, err := snapstate.Install(s.state, "services-snap", "some-channel", snap.R(0), s.user.ID, 0)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -753,117 +1544,74 @@ func (s *snapmgrTestSuite) TestInstallRunThrough(c *C) {
 	s.settle()
 	s.state.Lock()
 
-	// ensure all our tasks ran
 	c.Assert(chg.Err(), IsNil)
-	c.Check(s.fakeStore.downloads, DeepEquals, []fakeDownload{{
-		macaroon: s.user.Macaroon,
-		name:     "some-snap",
-	}})
-	c.Assert(s.fakeBackend.ops, DeepEquals, fakeOps{
-		{
-			op:    "storesvc-snap",
-			name:  "some-snap",
-			revno: snap.R(42),
-		},
-		{
-			op:   "storesvc-download",
-			name: "some-snap",
-		},
-		{
-			op:    "validate-snap:Doing",
-			name:  "some-snap",
-			revno: snap.R(42),
-		},
-		{
-			op:  "current",
-			old: "<no-current>",
-		},
-		{
-			op:   "open-snap-file",
-			name: "downloaded-snap-path",
-		},
-		{
-			op:    "setup-snap",
-			name:  "downloaded-snap-path",
-			revno: snap.R(42),
-		},
-		{
-			op:   "copy-data",
-			name: "/snap/some-snap/42",
-			old:  "<no-old>",
-		},
-		{
-			op:    "setup-profiles:Doing",
-			name:  "some-snap",
-			revno: snap.R(42),
-		},
-		{
-			op: "candidate",
-			sinfo: snap.SideInfo{
-				RealName: "some-snap",
-				Channel:  "some-channel",
-				SnapID:   "snapIDsnapidsnapidsnapidsnapidsn",
-				Revision: snap.R(42),
-			},
-		},
-		{
-			op:   "link-snap",
-			name: "/snap/some-snap/42",
-		},
-		{
-			op:   "start-snap-services",
-			name: "/snap/some-snap/42",
-		},
+	c.Check(s.startedServices(c), DeepEquals, [][]string{{"manual"}})
+}
+
+func (s *snapmgrTestSuite) TestUpdateKeepsServicesEnabledState(c *C) {
+	si := snap.SideInfo{
+		RealName: "services-snap",
+		Revision: snap.R(7),
+		SnapID:   "services-snap-id",
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "services-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si},
+		Current:  si.Revision,
 	})
+	// "manual" got enabled by hand, "svc" disabled
+	s.fakeBackend.disabledServices = []string{"svc"}
 
-	// check progress
-	ta := ts.Tasks()
-	task := ta[0]
-	_, cur, total := task.Progress()
-	c.Assert(cur, Equals, s.fakeStore.fakeCurrentProgress)
-	c.Assert(total, Equals, s.fakeStore.fakeTotalProgress)
-	c.Check(task.Summary(), Equals, `Download snap "some-snap" (42) from channel "some-channel"`)
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "services-snap", "some-channel", snap.R(0), s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
 
-	// check link/start snap summary
-	linkTask := ta[len(ta)-2]
-	c.Check(linkTask.Summary(), Equals, `Make snap "some-snap" (42) available to the system`)
-	startTask := ta[len(ta)-1]
-	c.Check(startTask.Summary(), Equals, `Start snap "some-snap" (42) services`)
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
 
-	// verify snap-setup in the task state
-	var ss snapstate.SnapSetup
-	err = task.Get("snap-setup", &ss)
+	c.Assert(chg.Err(), IsNil)
+	c.Check(s.startedServices(c), DeepEquals, [][]string{{"svc"}})
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "services-snap", &snapst)
 	c.Assert(err, IsNil)
-	c.Assert(ss, DeepEquals, snapstate.SnapSetup{
-		Channel:  "some-channel",
-		UserID:   s.user.ID,
-		SnapPath: "downloaded-snap-path",
-		DownloadInfo: &snap.DownloadInfo{
-			DownloadURL: "https://some-server.com/some/path.snap",
-		},
-		SideInfo: ss.SideInfo,
-	})
-	c.Assert(ss.SideInfo, DeepEquals, &snap.SideInfo{
-		RealName: "some-snap",
-		Revision: snap.R(42),
-		Channel:  "some-channel",
-		SnapID:   "snapIDsnapidsnapidsnapidsnapidsn",
+	c.Check(snapst.DisabledServices, DeepEquals, []string{"svc"})
+}
+
+func (s *snapmgrTestSuite) TestEnableKeepsServicesEnabledState(c *C) {
+	si := snap.SideInfo{
+		RealName: "services-snap",
+		Revision: snap.R(7),
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "services-snap", &snapstate.SnapState{
+		Sequence:         []*snap.SideInfo{&si},
+		Current:          si.Revision,
+		DisabledServices: []string{"svc"},
 	})
 
-	// verify snaps in the system state
-	var snaps map[string]*snapstate.SnapState
-	err = s.state.Get("snaps", &snaps)
+	chg := s.state.NewChange("enable", "enable a snap")
+	ts, err := snapstate.Enable(s.state, "services-snap")
 	c.Assert(err, IsNil)
+	chg.AddAll(ts)
 
-	snapst := snaps["some-snap"]
-	c.Assert(snapst.Active, Equals, true)
-	c.Assert(snapst.Channel, Equals, "some-channel")
-	c.Assert(snapst.Sequence[0], DeepEquals, &snap.SideInfo{
-		RealName: "some-snap",
-		Channel:  "some-channel",
-		SnapID:   "snapIDsnapidsnapidsnapidsnapidsn",
-		Revision: snap.R(42),
-	})
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(s.startedServices(c), DeepEquals, [][]string{{"svc"}})
 }
 
 func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
@@ -919,16 +1667,23 @@ func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
 		},
 		{
 			op:   "open-snap-file",
-			name: "downloaded-snap-path",
+			name: filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
+			sinfo: snap.SideInfo{
+				RealName: "some-snap",
+				SnapID:   "some-snap-id",
+				Revision: snap.R(11),
+				Channel:  "some-channel",
+			},
 		},
 		{
 			op:    "setup-snap",
-			name:  "downloaded-snap-path",
+			name:  filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
 			revno: snap.R(11),
 		},
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/7",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/7",
+			stopReason: snap.StopReasonRefresh,
 		},
 		{
 			op:   "unlink-snap",
@@ -972,6 +1727,7 @@ func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
 	c.Check(s.fakeStore.downloads, DeepEquals, []fakeDownload{{
 		macaroon: s.user.Macaroon,
 		name:     "some-snap",
+		target:   filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
 	}})
 	c.Assert(s.fakeBackend.ops, DeepEquals, expected)
 
@@ -989,7 +1745,7 @@ func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
 		Channel: "some-channel",
 		UserID:  s.user.ID,
 
-		SnapPath: "downloaded-snap-path",
+		SnapPath: filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
 		DownloadInfo: &snap.DownloadInfo{
 			DownloadURL: "https://some-server.com/some/path.snap",
 		},
@@ -1079,16 +1835,23 @@ func (s *snapmgrTestSuite) TestUpdateUndoRunThrough(c *C) {
 		},
 		{
 			op:   "open-snap-file",
-			name: "downloaded-snap-path",
+			name: filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
+			sinfo: snap.SideInfo{
+				RealName: "some-snap",
+				SnapID:   "some-snap-id",
+				Revision: snap.R(11),
+				Channel:  "some-channel",
+			},
 		},
 		{
 			op:    "setup-snap",
-			name:  "downloaded-snap-path",
+			name:  filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
 			revno: snap.R(11),
 		},
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/7",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/7",
+			stopReason: snap.StopReasonRefresh,
 		},
 		{
 			op:   "unlink-snap",
@@ -1150,6 +1913,7 @@ func (s *snapmgrTestSuite) TestUpdateUndoRunThrough(c *C) {
 	c.Check(s.fakeStore.downloads, DeepEquals, []fakeDownload{{
 		macaroon: s.user.Macaroon,
 		name:     "some-snap",
+		target:   filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
 	}})
 	c.Assert(s.fakeBackend.ops, DeepEquals, expected)
 
@@ -1229,16 +1993,23 @@ func (s *snapmgrTestSuite) TestUpdateTotalUndoRunThrough(c *C) {
 		},
 		{
 			op:   "open-snap-file",
-			name: "downloaded-snap-path",
+			name: filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
+			sinfo: snap.SideInfo{
+				RealName: "some-snap",
+				SnapID:   "some-snap-id",
+				Revision: snap.R(11),
+				Channel:  "some-channel",
+			},
 		},
 		{
 			op:    "setup-snap",
-			name:  "downloaded-snap-path",
+			name:  filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
 			revno: snap.R(11),
 		},
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/7",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/7",
+			stopReason: snap.StopReasonRefresh,
 		},
 		{
 			op:   "unlink-snap",
@@ -1315,6 +2086,7 @@ func (s *snapmgrTestSuite) TestUpdateTotalUndoRunThrough(c *C) {
 	c.Check(s.fakeStore.downloads, DeepEquals, []fakeDownload{{
 		macaroon: s.user.Macaroon,
 		name:     "some-snap",
+		target:   filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
 	}})
 	// friendlier failure first
 	c.Assert(s.fakeBackend.ops.Ops(), DeepEquals, expected.Ops())
@@ -1767,7 +2539,7 @@ func (s *snapmgrTestSuite) TestRemoveRunThrough(c *C) {
 	})
 
 	chg := s.state.NewChange("remove", "remove a snap")
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0))
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0), 0)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -1779,8 +2551,9 @@ func (s *snapmgrTestSuite) TestRemoveRunThrough(c *C) {
 	c.Check(len(s.fakeBackend.ops), Equals, 8)
 	expected := fakeOps{
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/7",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/7",
+			stopReason: snap.StopReasonRemove,
 		},
 		{
 			op:   "unlink-snap",
@@ -1872,7 +2645,7 @@ func (s *snapmgrTestSuite) TestRemoveWithManyRevisionsRunThrough(c *C) {
 	})
 
 	chg := s.state.NewChange("remove", "remove a snap")
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0))
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0), 0)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -1883,8 +2656,9 @@ func (s *snapmgrTestSuite) TestRemoveWithManyRevisionsRunThrough(c *C) {
 
 	expected := fakeOps{
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/7",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/7",
+			stopReason: snap.StopReasonRemove,
 		},
 		{
 			op:   "unlink-snap",
@@ -2001,7 +2775,7 @@ func (s *snapmgrTestSuite) TestRemoveOneRevisionRunThrough(c *C) {
 	})
 
 	chg := s.state.NewChange("remove", "remove a snap")
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(3))
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(3), 0)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -2064,7 +2838,7 @@ func (s *snapmgrTestSuite) TestRemoveLastRevisionRunThrough(c *C) {
 	})
 
 	chg := s.state.NewChange("remove", "remove a snap")
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(2))
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(2), 0)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -2139,7 +2913,7 @@ func (s *snapmgrTestSuite) TestRemoveCurrentActiveRevisionRefused(c *C) {
 		SnapType: "app",
 	})
 
-	_, err := snapstate.Remove(s.state, "some-snap", snap.R(2))
+	_, err := snapstate.Remove(s.state, "some-snap", snap.R(2), 0)
 
 	c.Check(err, ErrorMatches, `cannot remove active revision 2 of snap "some-snap"`)
 }
@@ -2160,7 +2934,7 @@ func (s *snapmgrTestSuite) TestRemoveCurrentRevisionOfSeveralRefused(c *C) {
 		SnapType: "app",
 	})
 
-	_, err := snapstate.Remove(s.state, "some-snap", snap.R(2))
+	_, err := snapstate.Remove(s.state, "some-snap", snap.R(2), 0)
 	c.Assert(err, NotNil)
 	c.Check(err.Error(), Equals, `cannot remove active revision 2 of snap "some-snap" (revert first?)`)
 }
@@ -2181,7 +2955,7 @@ func (s *snapmgrTestSuite) TestRemoveMissingRevisionRefused(c *C) {
 		SnapType: "app",
 	})
 
-	_, err := snapstate.Remove(s.state, "some-snap", snap.R(1))
+	_, err := snapstate.Remove(s.state, "some-snap", snap.R(1), 0)
 
 	c.Check(err, ErrorMatches, `revision 1 of snap "some-snap" is not installed`)
 }
@@ -2202,7 +2976,7 @@ func (s *snapmgrTestSuite) TestRemoveRefused(c *C) {
 		SnapType: "app",
 	})
 
-	_, err := snapstate.Remove(s.state, "gadget", snap.R(0))
+	_, err := snapstate.Remove(s.state, "gadget", snap.R(0), 0)
 
 	c.Check(err, ErrorMatches, `snap "gadget" is not removable`)
 }
@@ -2392,8 +3166,9 @@ func (s *snapmgrTestSuite) TestRevertRunThrough(c *C) {
 
 	expected := fakeOps{
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/7",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/7",
+			stopReason: snap.StopReasonRefresh,
 		},
 		{
 			op:   "unlink-snap",
@@ -2514,8 +3289,9 @@ func (s *snapmgrTestSuite) TestRevertToRevisionNewVersion(c *C) {
 
 	expected := fakeOps{
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/2",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/2",
+			stopReason: snap.StopReasonRefresh,
 		},
 		{
 			op:   "unlink-snap",
@@ -2594,8 +3370,9 @@ func (s *snapmgrTestSuite) TestRevertTotalUndoRunThrough(c *C) {
 
 	expected := fakeOps{
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/2",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/2",
+			stopReason: snap.StopReasonRefresh,
 		},
 		{
 			op:   "unlink-snap",
@@ -2689,8 +3466,9 @@ func (s *snapmgrTestSuite) TestRevertUndoRunThrough(c *C) {
 
 	expected := fakeOps{
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/2",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/2",
+			stopReason: snap.StopReasonRefresh,
 		},
 		{
 			op:   "unlink-snap",
@@ -2843,8 +3621,9 @@ func (s *snapmgrTestSuite) TestDisableRunThrough(c *C) {
 
 	expected := fakeOps{
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/7",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/7",
+			stopReason: snap.StopReasonDisable,
 		},
 		{
 			op:   "unlink-snap",
@@ -2917,11 +3696,17 @@ func (s *snapmgrTestSuite) TestUndoMountSnapFailsInCopyData(c *C) {
 		},
 		{
 			op:   "open-snap-file",
-			name: "downloaded-snap-path",
+			name: filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
+			sinfo: snap.SideInfo{
+				RealName: "some-snap",
+				SnapID:   "snapIDsnapidsnapidsnapidsnapidsn",
+				Revision: snap.R(11),
+				Channel:  "some-channel",
+			},
 		},
 		{
 			op:    "setup-snap",
-			name:  "downloaded-snap-path",
+			name:  filepath.Join(dirs.SnapBlobDir, "some-snap_11.snap"),
 			revno: snap.R(11),
 		},
 		{
@@ -3763,8 +4548,9 @@ func (s *snapmgrTestSuite) TestUpdateCanDoBackwards(c *C) {
 	s.state.Lock()
 	expected := fakeOps{
 		{
-			op:   "stop-snap-services",
-			name: "/snap/some-snap/11",
+			op:         "stop-snap-services",
+			name:       "/snap/some-snap/11",
+			stopReason: snap.StopReasonRefresh,
 		},
 		{
 			op:   "unlink-snap",
@@ -23,8 +23,8 @@ package snapstate
 import (
 	"encoding/json"
 	"fmt"
-	"reflect"
 	"sort"
+	"strings"
 
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
@@ -46,6 +46,14 @@ const (
 	// JailMode is set when the user has requested confinement
 	// always be enforcing, even if the snap requests otherwise.
 	JailMode
+
+	// Purge is set when removing a snap to discard its data without
+	// taking an automatic snapshot of it first.
+	Purge
+
+	// Classic is set when the user has agreed to install a snap that
+	// asks for classic confinement, running unconfined on the host.
+	Classic
 )
 
 func (f Flags) DevModeAllowed() bool {
@@ -60,11 +68,25 @@ func (f Flags) JailMode() bool {
 	return f&JailMode != 0
 }
 
+func (f Flags) Purge() bool {
+	return f&Purge != 0
+}
+
+func (f Flags) Classic() bool {
+	return f&Classic != 0
+}
+
 func doInstall(s *state.State, snapst *SnapState, ss *SnapSetup) (*state.TaskSet, error) {
-	if err := checkChangeConflict(s, ss.Name(), snapst); err != nil {
+	if err := CheckChangeConflict(s, ss.Name(), snapst); err != nil {
 		return nil, err
 	}
 
+	if !snapst.HasCurrent() {
+		if err := checkNamespaceConflict(s, ss.Name()); err != nil {
+			return nil, err
+		}
+	}
+
 	if ss.SnapPath == "" && ss.Channel == "" {
 		ss.Channel = "stable"
 	}
@@ -110,9 +132,23 @@ func doInstall(s *state.State, snapst *SnapState, ss *SnapSetup) (*state.TaskSet
 		prev = mount
 	}
 
+	// make sure the base the snap runs on is installed
+	prereq := s.NewTask("prerequisites", fmt.Sprintf(i18n.G("Ensure prerequisites for %q are available"), ss.Name()))
+	addTask(prereq)
+	prev = prereq
+
 	if snapst.Active {
+		// let the current revision prepare for the refresh, a
+		// failing pre-refresh hook aborts it
+		if SetupPreRefreshHook != nil {
+			preRefreshHook := SetupPreRefreshHook(s, ss.Name())
+			addTask(preRefreshHook)
+			prev = preRefreshHook
+		}
+
 		// unlink-current-snap (will stop services for copy-data)
 		stop := s.NewTask("stop-snap-services", fmt.Sprintf(i18n.G("Stop snap %q services"), ss.Name()))
+		stop.Set("stop-reason", snap.StopReasonRefresh)
 		addTask(stop)
 		prev = stop
 
@@ -138,8 +174,25 @@ func doInstall(s *state.State, snapst *SnapState, ss *SnapSetup) (*state.TaskSet
 	addTask(linkSnap)
 	prev = linkSnap
 
+	// let the new revision migrate its data
+	var setupHook func(st *state.State, snapName string) *state.Task
+	if snapst.HasCurrent() {
+		setupHook = SetupPostRefreshHook
+	} else {
+		setupHook = SetupInstallHook
+	}
+	if setupHook != nil {
+		hook := setupHook(s, ss.Name())
+		addTask(hook)
+		prev = hook
+	}
+
 	// run new serices
 	startSnapServices := s.NewTask("start-snap-services", fmt.Sprintf(i18n.G("Start snap %q%s services"), ss.Name(), revisionStr))
+	if !snapst.HasCurrent() {
+		// apply the install-mode of the services
+		startSnapServices.Set("first-install", true)
+	}
 	addTask(startSnapServices)
 	prev = startSnapServices
 
@@ -186,42 +239,6 @@ func doInstall(s *state.State, snapst *SnapState, ss *SnapSetup) (*state.TaskSet
 	return state.NewTaskSet(tasks...), nil
 }
 
-func checkChangeConflict(s *state.State, snapName string, snapst *SnapState) error {
-	for _, task := range s.Tasks() {
-		k := task.Kind()
-		chg := task.Change()
-		if (k == "link-snap" || k == "unlink-snap") && (chg == nil || !chg.Status().Ready()) {
-			ss, err := TaskSnapSetup(task)
-			if err != nil {
-				return fmt.Errorf("internal error: cannot obtain snap setup from task: %s", task.Summary())
-			}
-			if ss.Name() == snapName {
-				return fmt.Errorf("snap %q has changes in progress", snapName)
-			}
-		}
-	}
-
-	if snapst != nil {
-		// caller wants us to also make sure the SnapState in state
-		// matches the one they provided. Necessary because we need to
-		// unlock while talking to the store, during which a change can
-		// sneak in (if it's before the taskset is created) (e.g. for
-		// install, while getting the snap info; for refresh, when
-		// getting what needs refreshing).
-		var cursnapst SnapState
-		if err := Get(s, snapName, &cursnapst); err != nil && err != state.ErrNoState {
-			return err
-		}
-
-		// TODO: implement the rather-boring-but-more-performant SnapState.Equals
-		if !reflect.DeepEqual(snapst, &cursnapst) {
-			return fmt.Errorf("snap %q state changed during install preparations", snapName)
-		}
-	}
-
-	return nil
-}
-
 // InstallPath returns a set of tasks for installing snap from a file path.
 // Note that the state must be locked by the caller.
 // The provided SideInfo can contain just a name which results in a
@@ -264,6 +281,8 @@ func TryPath(s *state.State, name, path string, flags Flags) (*state.TaskSet, er
 }
 
 // Install returns a set of tasks for installing snap.
+// The name can be an instance name of the form <snap>_<key>, to install
+// the snap side by side with other instances of it.
 // Note that the state must be locked by the caller.
 func Install(s *state.State, name, channel string, revision snap.Revision, userID int, flags Flags) (*state.TaskSet, error) {
 	var snapst SnapState
@@ -275,17 +294,30 @@ func Install(s *state.State, name, channel string, revision snap.Revision, userI
 		return nil, fmt.Errorf("snap %q already installed", name)
 	}
 
-	snapInfo, err := snapInfo(s, name, channel, revision, userID, flags)
+	if err := snap.ValidateInstanceName(name); err != nil {
+		return nil, err
+	}
+	if err := validateChannel(channel); err != nil {
+		return nil, err
+	}
+
+	snapName, instanceKey := snap.SplitInstanceName(name)
+	snapInfo, err := snapInfo(s, snapName, channel, revision, userID, flags)
 	if err != nil {
 		return nil, err
 	}
 
+	if instanceKey != "" && snapInfo.Type != snap.TypeApp {
+		return nil, fmt.Errorf("cannot install snap %q as instance %q, only application snaps can have instances", snapName, name)
+	}
+
 	ss := &SnapSetup{
 		Channel:      channel,
 		UserID:       userID,
 		Flags:        SnapSetupFlags(flags),
 		DownloadInfo: &snapInfo.DownloadInfo,
 		SideInfo:     &snapInfo.SideInfo,
+		InstanceKey:  instanceKey,
 	}
 
 	return doInstall(s, &snapst, ss)
@@ -317,9 +349,19 @@ func refreshCandidates(st *state.State, names []string, user *auth.UserState) ([
 
 	sort.Strings(names)
 
-	stateByID := make(map[string]*SnapState, len(snapStates))
-	candidatesInfo := make([]*store.RefreshCandidate, 0, len(snapStates))
-	for _, snapst := range snapStates {
+	instanceNames := make([]string, 0, len(snapStates))
+	for instanceName := range snapStates {
+		instanceNames = append(instanceNames, instanceName)
+	}
+	sort.Strings(instanceNames)
+
+	// the store identifies refresh candidates by their snap id, so
+	// instances of the same snap are asked about in separate batches
+	batches := [][]*store.RefreshCandidate{make([]*store.RefreshCandidate, 0, len(snapStates))}
+	instanceByID := []map[string]string{make(map[string]string)}
+	stateByInstance := make(map[string]*SnapState, len(snapStates))
+	for _, instanceName := range instanceNames {
+		snapst := snapStates[instanceName]
 		if snapst.TryMode() || snapst.DevMode() {
 			// no multi-refresh for trymode nor devmode
 			continue
@@ -346,10 +388,28 @@ func refreshCandidates(st *state.State, names []string, user *auth.UserState) ([
 			continue
 		}
 
-		stateByID[snapInfo.SnapID] = snapst
+		if snapst.Held() {
+			// refreshes are held back by the user
+			if len(names) > 0 {
+				logger.Noticef("not refreshing held snap %q", snapInfo.Name())
+			}
+			continue
+		}
+
+		stateByInstance[instanceName] = snapst
+
+		i := 0
+		for i < len(batches) && instanceByID[i][snapInfo.SnapID] != "" {
+			i++
+		}
+		if i == len(batches) {
+			batches = append(batches, nil)
+			instanceByID = append(instanceByID, make(map[string]string))
+		}
+		instanceByID[i][snapInfo.SnapID] = instanceName
 
 		// get confinement preference from the snapstate
-		candidatesInfo = append(candidatesInfo, &store.RefreshCandidate{
+		batches[i] = append(batches[i], &store.RefreshCandidate{
 			// the desired channel (not info.Channel!)
 			Channel: snapst.Channel,
 			DevMode: snapst.DevModeAllowed(),
@@ -363,14 +423,22 @@ func refreshCandidates(st *state.State, names []string, user *auth.UserState) ([
 
 	theStore := Store(st)
 
+	var updates []*snap.Info
 	st.Unlock()
-	updates, err := theStore.ListRefresh(candidatesInfo, user)
-	st.Lock()
-	if err != nil {
-		return nil, nil, err
+	for i, candidatesInfo := range batches {
+		batchUpdates, err := theStore.ListRefresh(candidatesInfo, user)
+		if err != nil {
+			st.Lock()
+			return nil, nil, err
+		}
+		for _, update := range batchUpdates {
+			_, update.InstanceKey = snap.SplitInstanceName(instanceByID[i][update.SnapID])
+			updates = append(updates, update)
+		}
 	}
+	st.Lock()
 
-	return updates, stateByID, nil
+	return updates, stateByInstance, nil
 }
 
 // ValidateRefreshes allows to hook validation into the handling of refresh candidates.
@@ -385,7 +453,7 @@ func UpdateMany(st *state.State, names []string, userID int) ([]string, []*state
 		return nil, nil, err
 	}
 
-	updates, stateByID, err := refreshCandidates(st, names, user)
+	updates, stateByInstance, err := refreshCandidates(st, names, user)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -405,11 +473,19 @@ func UpdateMany(st *state.State, names []string, userID int) ([]string, []*state
 	updated := make([]string, 0, len(updates))
 	tasksets := make([]*state.TaskSet, 0, len(updates))
 	for _, update := range updates {
-		snapst := stateByID[update.SnapID]
+		snapst := stateByInstance[update.Name()]
 		// XXX: this check goes away when update-to-local is done
 		if err := checkRevisionIsNew(update.Name(), snapst, update.Revision); err != nil {
 			continue
 		}
+		if err := checkEpochCanRead(update, snapst); err != nil {
+			if len(names) == 0 {
+				// doing "refresh all", just skip this snap
+				logger.Noticef("%v", err)
+				continue
+			}
+			return nil, nil, err
+		}
 
 		ss := &SnapSetup{
 			Channel:      snapst.Channel,
@@ -417,6 +493,7 @@ func UpdateMany(st *state.State, names []string, userID int) ([]string, []*state
 			Flags:        SnapSetupFlags(snapst.Flags),
 			DownloadInfo: &update.DownloadInfo,
 			SideInfo:     &update.SideInfo,
+			InstanceKey:  snapst.InstanceKey,
 		}
 
 		ts, err := doInstall(st, snapst, ss)
@@ -453,14 +530,29 @@ func Update(s *state.State, name, channel string, revision snap.Revision, userID
 		return nil, fmt.Errorf("refreshing disabled snap %q not supported", name)
 	}
 
+	if snapst.Held() {
+		return nil, fmt.Errorf("cannot refresh snap %q, refreshes are held %s", name, holdDescription(snapst.Hold))
+	}
+
+	if err := validateChannel(channel); err != nil {
+		return nil, err
+	}
 	if channel == "" {
 		channel = snapst.Channel
 	}
 
+	// the agreement to classic confinement carries over refreshes
+	if snapst.Classic() {
+		flags |= Classic
+	}
+
 	info, err := infoForUpdate(s, &snapst, name, channel, revision, userID, flags)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkEpochCanRead(info, &snapst); err != nil {
+		return nil, err
+	}
 
 	ss := &SnapSetup{
 		Channel:      channel,
@@ -468,11 +560,53 @@ func Update(s *state.State, name, channel string, revision snap.Revision, userID
 		Flags:        SnapSetupFlags(flags),
 		DownloadInfo: &info.DownloadInfo,
 		SideInfo:     &info.SideInfo,
+		InstanceKey:  snapst.InstanceKey,
 	}
 
 	return doInstall(s, &snapst, ss)
 }
 
+// validateChannel checks that a non-empty channel name is well formed.
+func validateChannel(channel string) error {
+	if channel == "" {
+		return nil
+	}
+	_, err := snap.ParseChannel(channel)
+	return err
+}
+
+// Switch switches the channel tracked by the snap without refreshing it.
+// Note that the state must be locked by the caller.
+func Switch(s *state.State, name, channel string) (*state.TaskSet, error) {
+	var snapst SnapState
+	err := Get(s, name, &snapst)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if !snapst.HasCurrent() {
+		return nil, fmt.Errorf("cannot find snap %q", name)
+	}
+
+	if channel == "" {
+		return nil, fmt.Errorf("cannot switch snap %q to an empty channel", name)
+	}
+	if err := validateChannel(channel); err != nil {
+		return nil, err
+	}
+
+	if err := CheckChangeConflict(s, name, nil); err != nil {
+		return nil, err
+	}
+
+	ss := minimalSnapSetup(name, snapst.Current)
+	ss.Channel = channel
+
+	t := s.NewTask("switch-snap-channel", fmt.Sprintf(i18n.G("Switch snap %q to channel %q"), name, channel))
+	t.Set("snap-setup", ss)
+
+	return state.NewTaskSet(t), nil
+}
+
 func infoForUpdate(s *state.State, snapst *SnapState, name, channel string, revision snap.Revision, userID int, flags Flags) (*snap.Info, error) {
 	if revision.Unset() {
 		// good ol' refresh
@@ -497,7 +631,8 @@ func infoForUpdate(s *state.State, snapst *SnapState, name, channel string, revi
 	}
 	if sideInfo == nil {
 		// refresh from given revision from store
-		return snapInfo(s, name, channel, revision, userID, flags)
+		snapName, _ := snap.SplitInstanceName(name)
+		return snapInfo(s, snapName, channel, revision, userID, flags)
 	}
 
 	// refresh-to-local
@@ -519,26 +654,21 @@ func Enable(s *state.State, name string) (*state.TaskSet, error) {
 		return nil, fmt.Errorf("snap %q already enabled", name)
 	}
 
-	if err := checkChangeConflict(s, name, nil); err != nil {
+	if err := CheckChangeConflict(s, name, nil); err != nil {
 		return nil, err
 	}
 
-	ss := &SnapSetup{
-		SideInfo: &snap.SideInfo{
-			RealName: name,
-			Revision: snapst.Current,
-		},
-	}
+	ss := minimalSnapSetup(name, snapst.Current)
 
 	prepareSnap := s.NewTask("prepare-snap", fmt.Sprintf(i18n.G("Prepare snap %q (%s)"), ss.Name(), snapst.Current))
-	prepareSnap.Set("snap-setup", &ss)
+	prepareSnap.Set("snap-setup", ss)
 
 	linkSnap := s.NewTask("link-snap", fmt.Sprintf(i18n.G("Make snap %q (%s) available to the system%s"), ss.Name(), snapst.Current))
-	linkSnap.Set("snap-setup", &ss)
+	linkSnap.Set("snap-setup", ss)
 	linkSnap.WaitFor(prepareSnap)
 
 	startSnapServices := s.NewTask("start-snap-services", fmt.Sprintf(i18n.G("Start snap %q (%s) services"), ss.Name(), snapst.Current))
-	startSnapServices.Set("snap-setup", &ss)
+	startSnapServices.Set("snap-setup", ss)
 	startSnapServices.WaitFor(linkSnap)
 
 	return state.NewTaskSet(prepareSnap, linkSnap, startSnapServices), nil
@@ -558,19 +688,15 @@ func Disable(s *state.State, name string) (*state.TaskSet, error) {
 		return nil, fmt.Errorf("snap %q already disabled", name)
 	}
 
-	if err := checkChangeConflict(s, name, nil); err != nil {
+	if err := CheckChangeConflict(s, name, nil); err != nil {
 		return nil, err
 	}
 
-	ss := &SnapSetup{
-		SideInfo: &snap.SideInfo{
-			RealName: name,
-			Revision: snapst.Current,
-		},
-	}
+	ss := minimalSnapSetup(name, snapst.Current)
 
 	stopSnapServices := s.NewTask("stop-snap-services", fmt.Sprintf(i18n.G("Stop snap %q (%s) services"), ss.Name(), snapst.Current))
-	stopSnapServices.Set("snap-setup", &ss)
+	stopSnapServices.Set("snap-setup", ss)
+	stopSnapServices.Set("stop-reason", snap.StopReasonDisable)
 	unlinkSnap := s.NewTask("unlink-snap", fmt.Sprintf(i18n.G("Make snap %q (%s) unavailable to the system"), ss.Name(), snapst.Current))
 	unlinkSnap.Set("snap-setup-task", stopSnapServices.ID())
 	unlinkSnap.WaitFor(stopSnapServices)
@@ -579,12 +705,7 @@ func Disable(s *state.State, name string) (*state.TaskSet, error) {
 }
 
 func removeInactiveRevision(s *state.State, name string, revision snap.Revision) *state.TaskSet {
-	ss := SnapSetup{
-		SideInfo: &snap.SideInfo{
-			RealName: name,
-			Revision: revision,
-		},
-	}
+	ss := minimalSnapSetup(name, revision)
 
 	clearData := s.NewTask("clear-snap", fmt.Sprintf(i18n.G("Remove data for snap %q (%s)"), name, revision))
 	clearData.Set("snap-setup", ss)
@@ -614,9 +735,48 @@ func canRemove(s *snap.Info, active bool) bool {
 	return true
 }
 
+// checkBaseNotInUse verifies that no installed snap uses the given
+// snap as its base.
+func checkBaseNotInUse(s *state.State, name string) error {
+	snapStates, err := All(s)
+	if err != nil {
+		return err
+	}
+	users := make([]string, 0, len(snapStates))
+	for instanceName, snapst := range snapStates {
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			continue
+		}
+		if info.Base == name {
+			users = append(users, instanceName)
+		}
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	sort.Strings(users)
+	return fmt.Errorf("snap %q is not removable, it is the base of: %s", name, strings.Join(users, ", "))
+}
+
+// SetupInstallHook, SetupPreRefreshHook, SetupPostRefreshHook and
+// SetupRemoveHook allow to hook running the snap hooks of the same name
+// into the install, refresh and remove of snaps.
+var (
+	SetupInstallHook     func(st *state.State, snapName string) *state.Task
+	SetupPreRefreshHook  func(st *state.State, snapName string) *state.Task
+	SetupPostRefreshHook func(st *state.State, snapName string) *state.Task
+	SetupRemoveHook      func(st *state.State, snapName string) *state.Task
+)
+
+// AutomaticSnapshot allows to hook taking a snapshot of the data of a snap before it is removed.
+var AutomaticSnapshot func(s *state.State, snapName string) (*state.TaskSet, error)
+
 // Remove returns a set of tasks for removing snap.
+// Unless flags has Purge set, a snapshot of the snap's data is taken
+// before removing it entirely.
 // Note that the state must be locked by the caller.
-func Remove(s *state.State, name string, revision snap.Revision) (*state.TaskSet, error) {
+func Remove(s *state.State, name string, revision snap.Revision, flags Flags) (*state.TaskSet, error) {
 	var snapst SnapState
 	err := Get(s, name, &snapst)
 	if err != nil && err != state.ErrNoState {
@@ -627,7 +787,7 @@ func Remove(s *state.State, name string, revision snap.Revision) (*state.TaskSet
 		return nil, fmt.Errorf("cannot find snap %q", name)
 	}
 
-	if err := checkChangeConflict(s, name, nil); err != nil {
+	if err := CheckChangeConflict(s, name, nil); err != nil {
 		return nil, err
 	}
 
@@ -665,14 +825,15 @@ func Remove(s *state.State, name string, revision snap.Revision) (*state.TaskSet
 		return nil, fmt.Errorf("snap %q is not removable", name)
 	}
 
-	// main/current SnapSetup
-	ss := SnapSetup{
-		SideInfo: &snap.SideInfo{
-			RealName: name,
-			Revision: revision,
-		},
+	if removeAll || len(snapst.Sequence) == 1 {
+		if err := checkBaseNotInUse(s, name); err != nil {
+			return nil, err
+		}
 	}
 
+	// main/current SnapSetup
+	ss := minimalSnapSetup(name, revision)
+
 	// trigger remove
 
 	full := state.NewTaskSet()
@@ -687,8 +848,17 @@ func Remove(s *state.State, name string, revision snap.Revision) (*state.TaskSet
 	}
 
 	if active { // unlink
+		var removeHook *state.Task
+		if (removeAll || len(snapst.Sequence) == 1) && SetupRemoveHook != nil {
+			removeHook = SetupRemoveHook(s, name)
+		}
+
 		stopSnapServices := s.NewTask("stop-snap-services", fmt.Sprintf(i18n.G("Stop snap %q services"), name))
 		stopSnapServices.Set("snap-setup", ss)
+		stopSnapServices.Set("stop-reason", snap.StopReasonRemove)
+		if removeHook != nil {
+			stopSnapServices.WaitFor(removeHook)
+		}
 
 		unlink := s.NewTask("unlink-snap", fmt.Sprintf(i18n.G("Make snap %q unavailable to the system"), name))
 		unlink.Set("snap-setup-task", stopSnapServices.ID())
@@ -698,10 +868,22 @@ func Remove(s *state.State, name string, revision snap.Revision) (*state.TaskSet
 		removeSecurity.WaitFor(unlink)
 		removeSecurity.Set("snap-setup-task", stopSnapServices.ID())
 
-		addNext(state.NewTaskSet(stopSnapServices, unlink, removeSecurity))
+		ts := state.NewTaskSet(stopSnapServices, unlink, removeSecurity)
+		if removeHook != nil {
+			ts = state.NewTaskSet(removeHook, stopSnapServices, unlink, removeSecurity)
+		}
+		addNext(ts)
 	}
 
 	if removeAll || len(snapst.Sequence) == 1 {
+		if !flags.Purge() && AutomaticSnapshot != nil {
+			ts, err := AutomaticSnapshot(s, name)
+			if err != nil {
+				return nil, err
+			}
+			addNext(ts)
+		}
+
 		seq := snapst.Sequence
 		for i := len(seq) - 1; i >= 0; i-- {
 			si := seq[i]
@@ -709,11 +891,7 @@ func Remove(s *state.State, name string, revision snap.Revision) (*state.TaskSet
 		}
 
 		discardConns := s.NewTask("discard-conns", fmt.Sprintf(i18n.G("Discard interface connections for snap %q (%s)"), name, revision))
-		discardConns.Set("snap-setup", &SnapSetup{
-			SideInfo: &snap.SideInfo{
-				RealName: name,
-			},
-		})
+		discardConns.Set("snap-setup", minimalSnapSetup(name, snap.Revision{}))
 		addNext(state.NewTaskSet(discardConns))
 
 	} else {
@@ -754,13 +932,20 @@ func RevertToRevision(s *state.State, name string, rev snap.Revision, flags Flag
 	if !snapst.Active {
 		return nil, fmt.Errorf("cannot revert inactive snaps")
 	}
+	if snapst.Held() && !snapst.Hold.Revision.Unset() && snapst.Hold.Revision != rev {
+		return nil, fmt.Errorf("cannot revert snap %q, it is pinned to revision %s", name, snapst.Hold.Revision)
+	}
 	i := snapst.LastIndex(rev)
 	if i < 0 {
 		return nil, fmt.Errorf("cannot find revision %s for snap %q", rev, name)
 	}
+	if snapst.Classic() {
+		flags |= Classic
+	}
 	ss := &SnapSetup{
-		SideInfo: snapst.Sequence[i],
-		Flags:    SnapSetupFlags(flags) | SnapSetupFlagRevert,
+		SideInfo:    snapst.Sequence[i],
+		Flags:       SnapSetupFlags(flags) | SnapSetupFlagRevert,
+		InstanceKey: snapst.InstanceKey,
 	}
 	return doInstall(s, &snapst, ss)
 }
@@ -848,6 +1033,12 @@ func Set(s *state.State, name string, snapst *SnapState) {
 	if snapst == nil || (len(snapst.Sequence) == 0) {
 		delete(snaps, name)
 	} else {
+		if snapst.Hold != nil && !snapst.Held() {
+			// forget about expired holds
+			pruned := *snapst
+			pruned.Hold = nil
+			snapst = &pruned
+		}
 		data, err := json.Marshal(snapst)
 		if err != nil {
 			panic("internal error: cannot marshal snap state: " + err.Error())
@@ -925,7 +1116,7 @@ func RemoveMany(st *state.State, names []string) ([]string, []*state.TaskSet, er
 	removed := make([]string, len(names))
 	tasksets := make([]*state.TaskSet, 0, len(names))
 	for i, name := range names {
-		ts, err := Remove(st, name, snap.R(0))
+		ts, err := Remove(st, name, snap.R(0), 0)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -291,10 +291,28 @@ func (c *Change) Status() Status {
 // SetStatus sets the change status, overriding the default behavior (see Status method).
 func (c *Change) SetStatus(s Status) {
 	c.state.writing()
+	var old Status
+	if len(c.state.observers) > 0 {
+		old = c.Status()
+	}
 	c.status = s
 	if s.Ready() {
 		c.markReady()
 	}
+	c.notifyStatusChanged(old)
+}
+
+// notifyStatusChanged tells the state observers about the change going
+// from the old status to its current one, if they differ.
+func (c *Change) notifyStatusChanged(old Status) {
+	if len(c.state.observers) == 0 {
+		return
+	}
+	if new := c.Status(); new != old {
+		for _, o := range c.state.observers {
+			o.ChangeStatusChanged(c, old, new)
+		}
+	}
 }
 
 func (c *Change) markReady() {
@@ -20,7 +20,10 @@
 package state
 
 import (
+	"os"
 	"time"
+
+	"github.com/snapcore/snapd/osutil"
 )
 
 // MockCheckpointRetryDelay changes unlockCheckpointRetryInterval and unlockCheckpointRetryMaxTime.
@@ -44,3 +47,22 @@ func MockTaskTimes(t *Task, spawnTime, readyTime time.Time) {
 	t.spawnTime = spawnTime
 	t.readyTime = readyTime
 }
+
+// MockJournal changes the journal compaction thresholds and how the base
+// state file and the journal are written, for fault injection.
+func MockJournal(maxRecords int, minSize int64, atomicWrite func(string, []byte, os.FileMode, osutil.AtomicWriteFlags) error, write func(*os.File, []byte) (int, error)) (restore func()) {
+	oldMaxRecords := journalMaxRecords
+	oldMinSize := journalMinSize
+	oldAtomicWrite := atomicWriteFile
+	oldWrite := journalWrite
+	journalMaxRecords = maxRecords
+	journalMinSize = minSize
+	atomicWriteFile = atomicWrite
+	journalWrite = write
+	return func() {
+		journalMaxRecords = oldMaxRecords
+		journalMinSize = oldMinSize
+		atomicWriteFile = oldAtomicWrite
+		journalWrite = oldWrite
+	}
+}
@@ -0,0 +1,388 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// The journal is compacted into the base state file once it holds
+// journalMaxRecords records, or once it is larger than both
+// journalMinSize and the base state file.
+var (
+	journalMaxRecords       = 1000
+	journalMinSize    int64 = 64 * 1024
+)
+
+var (
+	atomicWriteFile = osutil.AtomicWriteFile
+	journalWrite    = (*os.File).Write
+)
+
+// splitKeys are the fields of the serialized state whose entries (data
+// keys, changes and tasks) are journaled one by one.
+var splitKeys = map[string]bool{"data": true, "changes": true, "tasks": true}
+
+// generationKey is the field of the base state file holding its
+// generation, bumped on every compaction. Journal records carry the
+// generation of the base state file they apply to, so that records
+// already compacted into the base state file are skipped if a crash
+// left them behind.
+const generationKey = "journal-generation"
+
+// journalRecord holds the entries set and deleted by one checkpoint.
+type journalRecord struct {
+	Gen uint64                     `json:"gen,omitempty"`
+	Set map[string]json.RawMessage `json:"set,omitempty"`
+	Del []string                   `json:"del,omitempty"`
+}
+
+// A Journal persists the state as a base state file plus a journal of the
+// entries (data keys, changes, tasks, ...) modified since, appended as one
+// record per checkpoint and compacted back into the base file every so
+// often. This spares rewriting and syncing the whole state file on every
+// checkpoint.
+//
+// A Backend checkpoints through the Journal, and as a JournalingBackend
+// lets ReadState recover the state from the base file and the journal
+// after a restart or a crash.
+type Journal struct {
+	path        string
+	journalPath string
+
+	f *os.File
+	// entries are the entries of the last checkpointed state
+	entries map[string]json.RawMessage
+	// generation is the generation of the base state file
+	generation uint64
+	records    int
+	size       int64
+	baseSize   int64
+}
+
+// NewJournal returns a Journal keeping the base state at path and its
+// journal at journalPath.
+func NewJournal(path, journalPath string) *Journal {
+	return &Journal{
+		path:        path,
+		journalPath: journalPath,
+	}
+}
+
+func splitEntries(data []byte) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	entries := make(map[string]json.RawMessage, len(fields))
+	for k, v := range fields {
+		if !splitKeys[k] {
+			entries[k] = v
+			continue
+		}
+		var sub map[string]json.RawMessage
+		if err := json.Unmarshal(v, &sub); err != nil {
+			return nil, err
+		}
+		for subk, subv := range sub {
+			entries[k+"/"+subk] = subv
+		}
+	}
+	return entries, nil
+}
+
+// takeGeneration removes the generation from the entries of a base state
+// file, returning it.
+func takeGeneration(entries map[string]json.RawMessage) (uint64, error) {
+	raw, ok := entries[generationKey]
+	if !ok {
+		return 0, nil
+	}
+	delete(entries, generationKey)
+	var gen uint64
+	if err := json.Unmarshal(raw, &gen); err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", generationKey, err)
+	}
+	return gen, nil
+}
+
+// withGeneration returns data with the given generation recorded in it.
+func withGeneration(data []byte, gen uint64) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields[generationKey] = json.RawMessage(strconv.FormatUint(gen, 10))
+	return json.Marshal(fields)
+}
+
+func joinEntries(entries map[string]json.RawMessage) ([]byte, error) {
+	fields := make(map[string]interface{}, len(splitKeys))
+	for k := range splitKeys {
+		fields[k] = make(map[string]json.RawMessage)
+	}
+	for k, v := range entries {
+		if i := strings.IndexByte(k, '/'); i >= 0 && splitKeys[k[:i]] {
+			fields[k[:i]].(map[string]json.RawMessage)[k[i+1:]] = v
+		} else {
+			fields[k] = v
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// Recover returns the state resulting from applying the journal to the
+// given base state. A record torn by a crash while it was appended is
+// dropped, and records of an earlier generation of the base state are
+// skipped.
+func (j *Journal) Recover(base []byte) ([]byte, error) {
+	entries, gen, err := splitBase(base)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(j.journalPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the state journal: %v", err)
+	}
+
+	records, size, err := replayJournal(f, entries, gen)
+	if err == nil {
+		err = resetJournal(f, size)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("cannot recover the state journal: %v", err)
+	}
+
+	j.f = f
+	j.entries = entries
+	j.generation = gen
+	j.records = records
+	j.size = size
+	j.baseSize = int64(len(base))
+
+	if records == 0 {
+		return base, nil
+	}
+	return joinEntries(entries)
+}
+
+// ReplayJournal returns the state resulting from applying the journal
+// read from r to the given base state, without touching the journal
+// itself. A torn or invalid record ends the replay, and records of an
+// earlier generation are skipped, as in Recover.
+func ReplayJournal(base []byte, r io.Reader) ([]byte, error) {
+	entries, gen, err := splitBase(base)
+	if err != nil {
+		return nil, err
+	}
+	records, _, err := replayJournal(r, entries, gen)
+	if err != nil {
+		return nil, fmt.Errorf("cannot replay the state journal: %v", err)
+	}
+	if records == 0 {
+		return base, nil
+	}
+	return joinEntries(entries)
+}
+
+// splitBase returns the entries of the given base state and its
+// generation.
+func splitBase(base []byte) (map[string]json.RawMessage, uint64, error) {
+	entries, err := splitEntries(base)
+	if err == nil {
+		var gen uint64
+		gen, err = takeGeneration(entries)
+		if err == nil {
+			return entries, gen, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("cannot read the state file: %v", err)
+}
+
+// replayJournal applies the records of generation gen read from r to
+// entries, returning how many records were applied and the size the
+// records read take in the journal.
+func replayJournal(r io.Reader, entries map[string]json.RawMessage, gen uint64) (records int, size int64, err error) {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				logger.Noticef("dropping torn record at the end of the state journal")
+			}
+			return records, size, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		var rec journalRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			logger.Noticef("dropping the state journal from its invalid record %d on: %v", n, err)
+			return records, size, nil
+		}
+		size += int64(len(line))
+		if rec.Gen != gen {
+			// already in the base state file
+			continue
+		}
+		for k, v := range rec.Set {
+			entries[k] = v
+		}
+		for _, k := range rec.Del {
+			delete(entries, k)
+		}
+		records++
+	}
+}
+
+// resetJournal truncates the journal to the given size and syncs it.
+func resetJournal(f *os.File, size int64) error {
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+	if _, err := f.Seek(size, 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// Checkpoint appends to the journal the entries of data that changed
+// since the last checkpoint, compacting the journal into the base state
+// file if it grew enough.
+func (j *Journal) Checkpoint(data []byte) error {
+	entries, err := splitEntries(data)
+	if err != nil {
+		return fmt.Errorf("cannot journal the state: %v", err)
+	}
+
+	if j.entries == nil {
+		// nothing to diff against yet
+		return j.compact(data, entries)
+	}
+
+	rec := journalRecord{Gen: j.generation, Set: make(map[string]json.RawMessage)}
+	for k, v := range entries {
+		if old, ok := j.entries[k]; !ok || !bytes.Equal(old, v) {
+			rec.Set[k] = v
+		}
+	}
+	for k := range j.entries {
+		if _, ok := entries[k]; !ok {
+			rec.Del = append(rec.Del, k)
+		}
+	}
+	if len(rec.Set) == 0 && len(rec.Del) == 0 {
+		return nil
+	}
+	sort.Strings(rec.Del)
+
+	if err := j.append(&rec); err != nil {
+		return err
+	}
+	j.entries = entries
+
+	if j.records >= journalMaxRecords || (j.size > journalMinSize && j.size > j.baseSize) {
+		if err := j.compact(data, entries); err != nil {
+			// the journal still has it all, try again next time
+			logger.Noticef("cannot compact the state journal: %v", err)
+		}
+	}
+	return nil
+}
+
+func (j *Journal) append(rec *journalRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("cannot journal the state: %v", err)
+	}
+	b = append(b, '\n')
+
+	_, err = journalWrite(j.f, b)
+	if err == nil {
+		err = j.f.Sync()
+	}
+	if err != nil {
+		// leave no torn record behind for the next append
+		if rerr := resetJournal(j.f, j.size); rerr != nil {
+			logger.Noticef("cannot drop partial record from the state journal: %v", rerr)
+		}
+		return fmt.Errorf("cannot append to the state journal: %v", err)
+	}
+
+	j.records++
+	j.size += int64(len(b))
+	return nil
+}
+
+// compact writes data as the next generation of the base state file and
+// empties the journal. Should that fail after writing the base state
+// file, the records left in the journal are of the previous generation
+// and are skipped on recovery.
+func (j *Journal) compact(data []byte, entries map[string]json.RawMessage) error {
+	gen := j.generation + 1
+	base, err := withGeneration(data, gen)
+	if err != nil {
+		return fmt.Errorf("cannot journal the state: %v", err)
+	}
+	if err := atomicWriteFile(j.path, base, 0600, 0); err != nil {
+		return err
+	}
+	j.generation = gen
+	j.baseSize = int64(len(base))
+
+	if j.f == nil {
+		f, err := os.OpenFile(j.journalPath, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return fmt.Errorf("cannot open the state journal: %v", err)
+		}
+		j.f = f
+	}
+	if err := resetJournal(j.f, 0); err != nil {
+		return fmt.Errorf("cannot empty the state journal: %v", err)
+	}
+
+	j.entries = entries
+	j.records = 0
+	j.size = 0
+	return nil
+}
+
+// Close closes the journal.
+func (j *Journal) Close() error {
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
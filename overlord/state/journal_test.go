@@ -0,0 +1,286 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package state_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type journalSuite struct {
+	path        string
+	journalPath string
+	restore     func()
+}
+
+var _ = Suite(&journalSuite{})
+
+func (s *journalSuite) SetUpTest(c *C) {
+	dir := c.MkDir()
+	s.path = filepath.Join(dir, "state.json")
+	s.journalPath = filepath.Join(dir, "state.journal")
+	s.restore = state.MockJournal(1000, 64*1024, osutil.AtomicWriteFile, (*os.File).Write)
+}
+
+func (s *journalSuite) TearDownTest(c *C) {
+	s.restore()
+}
+
+func (s *journalSuite) readFile(c *C, path string) string {
+	b, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	return string(b)
+}
+
+// recover recovers the state from disk, as after a restart.
+func (s *journalSuite) recover(c *C) string {
+	j := state.NewJournal(s.path, s.journalPath)
+	defer j.Close()
+	data, err := j.Recover([]byte(s.readFile(c, s.path)))
+	c.Assert(err, IsNil)
+	return string(data)
+}
+
+func (s *journalSuite) TestCheckpointAppendsChangedEntries(c *C) {
+	j := state.NewJournal(s.path, s.journalPath)
+	defer j.Close()
+
+	// the first checkpoint has nothing to diff against
+	c.Assert(j.Checkpoint([]byte(`{"changes":{"1":{"id":"1"}},"data":{"a":1,"b":2},"last-change-id":1,"tasks":{}}`)), IsNil)
+	base := `{"changes":{"1":{"id":"1"}},"data":{"a":1,"b":2},"journal-generation":1,"last-change-id":1,"tasks":{}}`
+	c.Check(s.readFile(c, s.path), Equals, base)
+	c.Check(s.readFile(c, s.journalPath), Equals, "")
+
+	c.Assert(j.Checkpoint([]byte(`{"changes":{"1":{"id":"1"}},"data":{"a":1,"b":3},"last-change-id":1,"tasks":{}}`)), IsNil)
+	c.Assert(j.Checkpoint([]byte(`{"changes":{"2":{"id":"2"}},"data":{"a":1,"b":3},"last-change-id":2,"tasks":{"1":{"id":"1"}}}`)), IsNil)
+	// unchanged
+	c.Assert(j.Checkpoint([]byte(`{"changes":{"2":{"id":"2"}},"data":{"a":1,"b":3},"last-change-id":2,"tasks":{"1":{"id":"1"}}}`)), IsNil)
+
+	c.Check(s.readFile(c, s.path), Equals, base)
+	c.Check(s.readFile(c, s.journalPath), Equals, `{"gen":1,"set":{"data/b":3}}
+{"gen":1,"set":{"changes/2":{"id":"2"},"last-change-id":2,"tasks/1":{"id":"1"}},"del":["changes/1"]}
+`)
+
+	c.Check(s.recover(c), Equals, `{"changes":{"2":{"id":"2"}},"data":{"a":1,"b":3},"last-change-id":2,"tasks":{"1":{"id":"1"}}}`)
+}
+
+func (s *journalSuite) TestCompaction(c *C) {
+	s.restore()
+	s.restore = state.MockJournal(2, 1<<20, osutil.AtomicWriteFile, (*os.File).Write)
+
+	j := state.NewJournal(s.path, s.journalPath)
+	defer j.Close()
+
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":1}}`)), IsNil)
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":2}}`)), IsNil)
+	c.Check(s.readFile(c, s.journalPath), Equals, `{"gen":1,"set":{"data/a":2}}`+"\n")
+
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":3}}`)), IsNil)
+	c.Check(s.readFile(c, s.path), Equals, `{"data":{"a":3},"journal-generation":2}`)
+	c.Check(s.readFile(c, s.journalPath), Equals, "")
+
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":4}}`)), IsNil)
+	c.Check(s.readFile(c, s.journalPath), Equals, `{"gen":2,"set":{"data/a":4}}`+"\n")
+	c.Check(s.recover(c), Equals, `{"changes":{},"data":{"a":4},"tasks":{}}`)
+}
+
+func (s *journalSuite) TestRecoverDropsTornRecord(c *C) {
+	c.Assert(ioutil.WriteFile(s.path, []byte(`{"data":{"a":1}}`), 0600), IsNil)
+	c.Assert(ioutil.WriteFile(s.journalPath, []byte(`{"set":{"data/a":2}}`+"\n"+`{"set":{"data/a"`), 0600), IsNil)
+
+	c.Check(s.recover(c), Equals, `{"changes":{},"data":{"a":2},"tasks":{}}`)
+	// the torn record is gone for good
+	c.Check(s.readFile(c, s.journalPath), Equals, `{"set":{"data/a":2}}`+"\n")
+}
+
+func (s *journalSuite) TestReplayJournal(c *C) {
+	base := `{"data":{"a":1,"b":2}}`
+	journal := `{"set":{"data/a":2}}` + "\n" + `{"set":{"changes/1":{"id":"1"}},"del":["data/b"]}` + "\n" + `{"set":{"data/a"`
+
+	data, err := state.ReplayJournal([]byte(base), bytes.NewBufferString(journal))
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"changes":{"1":{"id":"1"}},"data":{"a":2},"tasks":{}}`)
+
+	// an empty journal leaves the base state as is
+	data, err = state.ReplayJournal([]byte(base), bytes.NewBufferString(""))
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, base)
+
+	// records of an earlier generation are already in the base state
+	data, err = state.ReplayJournal([]byte(`{"data":{"a":3},"journal-generation":2}`), bytes.NewBufferString(`{"gen":1,"set":{"data/a":2}}`+"\n"+`{"gen":2,"set":{"data/b":1}}`+"\n"))
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, `{"changes":{},"data":{"a":3,"b":1},"tasks":{}}`)
+
+	_, err = state.ReplayJournal([]byte("garbage"), bytes.NewBufferString(journal))
+	c.Check(err, ErrorMatches, "cannot read the state file: .*")
+	_, err = state.ReplayJournal([]byte(`{"journal-generation":"x"}`), bytes.NewBufferString(journal))
+	c.Check(err, ErrorMatches, "cannot read the state file: invalid journal-generation: .*")
+}
+
+func (s *journalSuite) TestRecoverNoJournal(c *C) {
+	c.Assert(ioutil.WriteFile(s.path, []byte(`{"data":{"a":1}}`), 0600), IsNil)
+	c.Check(s.recover(c), Equals, `{"data":{"a":1}}`)
+}
+
+func (s *journalSuite) TestCrashBeforeEmptyingJournal(c *C) {
+	s.restore()
+	s.restore = state.MockJournal(2, 1<<20, osutil.AtomicWriteFile, (*os.File).Write)
+
+	j := state.NewJournal(s.path, s.journalPath)
+	defer j.Close()
+
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":1,"b":1}}`)), IsNil)
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":2,"b":1}}`)), IsNil)
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":3,"b":1}}`)), IsNil)
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":3,"b":2}}`)), IsNil)
+	c.Check(s.readFile(c, s.path), Equals, `{"data":{"a":3,"b":1},"journal-generation":2}`)
+
+	// pretend the journal could not be emptied after writing the
+	// new base state file
+	c.Assert(ioutil.WriteFile(s.journalPath, []byte(`{"gen":1,"set":{"data/a":2}}`+"\n"+`{"gen":2,"set":{"data/b":2}}`+"\n"), 0600), IsNil)
+
+	// the compacted record doesn't bring a back to 2
+	c.Check(s.recover(c), Equals, `{"changes":{},"data":{"a":3,"b":2},"tasks":{}}`)
+}
+
+func (s *journalSuite) TestCrashBeforeEmptyingJournalOnFirstCheckpoint(c *C) {
+	// a journal left behind by an earlier run
+	c.Assert(ioutil.WriteFile(s.journalPath, []byte(`{"set":{"data/a":2}}`+"\n"), 0600), IsNil)
+
+	j := state.NewJournal(s.path, s.journalPath)
+	defer j.Close()
+
+	// the first checkpoint writes the base state file and empties the
+	// journal, pretend it crashed in between
+	stale := s.readFile(c, s.journalPath)
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":5}}`)), IsNil)
+	c.Assert(ioutil.WriteFile(s.journalPath, []byte(stale), 0600), IsNil)
+
+	c.Check(s.recover(c), Equals, `{"data":{"a":5},"journal-generation":1}`)
+}
+
+func (s *journalSuite) TestFailedBaseWriteKeepsJournal(c *C) {
+	fail := false
+	atomicWrite := func(path string, data []byte, perm os.FileMode, flags osutil.AtomicWriteFlags) error {
+		if fail {
+			return errors.New("disk on fire")
+		}
+		return osutil.AtomicWriteFile(path, data, perm, flags)
+	}
+	s.restore()
+	s.restore = state.MockJournal(2, 1<<20, atomicWrite, (*os.File).Write)
+
+	j := state.NewJournal(s.path, s.journalPath)
+	defer j.Close()
+
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":1}}`)), IsNil)
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":2}}`)), IsNil)
+	fail = true
+	// compacting fails but the checkpoint made it to the journal
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":3}}`)), IsNil)
+	c.Check(s.readFile(c, s.path), Equals, `{"data":{"a":1},"journal-generation":1}`)
+	c.Check(s.recover(c), Equals, `{"changes":{},"data":{"a":3},"tasks":{}}`)
+
+	// compacting is tried again on the next checkpoint
+	fail = false
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":4}}`)), IsNil)
+	c.Check(s.readFile(c, s.path), Equals, `{"data":{"a":4},"journal-generation":2}`)
+	c.Check(s.readFile(c, s.journalPath), Equals, "")
+}
+
+func (s *journalSuite) TestFailedAppendLeavesNoTornRecord(c *C) {
+	fail := false
+	write := func(f *os.File, b []byte) (int, error) {
+		if fail {
+			n, _ := f.Write(b[:len(b)/2])
+			return n, errors.New("disk on fire")
+		}
+		return f.Write(b)
+	}
+	s.restore()
+	s.restore = state.MockJournal(1000, 64*1024, osutil.AtomicWriteFile, write)
+
+	j := state.NewJournal(s.path, s.journalPath)
+	defer j.Close()
+
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":1}}`)), IsNil)
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":2}}`)), IsNil)
+	fail = true
+	err := j.Checkpoint([]byte(`{"data":{"a":3}}`))
+	c.Assert(err, ErrorMatches, "cannot append to the state journal: disk on fire")
+	c.Check(s.readFile(c, s.journalPath), Equals, `{"gen":1,"set":{"data/a":2}}`+"\n")
+
+	// the checkpoint is retried against what made it to disk
+	fail = false
+	c.Assert(j.Checkpoint([]byte(`{"data":{"a":3}}`)), IsNil)
+	c.Check(s.readFile(c, s.journalPath), Equals, `{"gen":1,"set":{"data/a":2}}`+"\n"+`{"gen":1,"set":{"data/a":3}}`+"\n")
+	c.Check(s.recover(c), Equals, `{"changes":{},"data":{"a":3},"tasks":{}}`)
+}
+
+type journalingBackend struct {
+	*state.Journal
+}
+
+func (b journalingBackend) EnsureBefore(d time.Duration)     {}
+func (b journalingBackend) RequestRestart(state.RestartType) {}
+
+func (s *journalSuite) TestReadStateRecoversJournal(c *C) {
+	b := journalingBackend{state.NewJournal(s.path, s.journalPath)}
+	st := state.New(b)
+	st.Lock()
+	st.Set("a", 1)
+	st.Unlock()
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	chg.AddTask(st.NewTask("download", "..."))
+	st.Set("a", 2)
+	st.Unlock()
+	b.Close()
+
+	c.Check(s.readFile(c, s.journalPath), Not(Equals), "")
+
+	b = journalingBackend{state.NewJournal(s.path, s.journalPath)}
+	defer b.Close()
+	st2, err := state.ReadState(b, bytes.NewBufferString(s.readFile(c, s.path)))
+	c.Assert(err, IsNil)
+
+	st2.Lock()
+	defer st2.Unlock()
+	var a int
+	c.Assert(st2.Get("a", &a), IsNil)
+	c.Check(a, Equals, 2)
+	c.Assert(st2.Changes(), HasLen, 1)
+	c.Check(st2.Changes()[0].Tasks(), HasLen, 1)
+
+	// and checkpoints carry on in the journal
+	st2.Set("a", 3)
+	st2.Unlock()
+	st2.Lock()
+	c.Check(s.readFile(c, s.journalPath), Matches, `(?s).*{"gen":1,"set":{"data/a":3}}\n$`)
+}
@@ -21,10 +21,12 @@
 package state
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -42,6 +44,14 @@ type Backend interface {
 	RequestRestart(t RestartType)
 }
 
+// A JournalingBackend is a Backend checkpointing the state incrementally,
+// see Journal. ReadState has it recover the state from the base state it
+// is given and what was checkpointed since.
+type JournalingBackend interface {
+	Backend
+	Recover(base []byte) ([]byte, error)
+}
+
 type customData map[string]*json.RawMessage
 
 func (data customData) get(key string, value interface{}) error {
@@ -96,6 +106,8 @@ type State struct {
 	modified bool
 
 	cache map[interface{}]interface{}
+
+	observers []Observer
 }
 
 // New returns a new empty state.
@@ -110,6 +122,22 @@ func New(backend Backend) *State {
 	}
 }
 
+// An Observer is told about status transitions of changes and tasks and
+// about progress updates of tasks. Its methods are called with the state
+// lock held so they must not block nor try to acquire it again.
+type Observer interface {
+	ChangeStatusChanged(chg *Change, old, new Status)
+	TaskStatusChanged(t *Task, old, new Status)
+	TaskProgressChanged(t *Task)
+}
+
+// AddObserver registers an observer of changes and tasks. Observers are
+// not persisted.
+func (s *State) AddObserver(o Observer) {
+	s.reading()
+	s.observers = append(s.observers, o)
+}
+
 // Modified returns whether the state was modified since the last checkpoint.
 func (s *State) Modified() bool {
 	return s.modified
@@ -384,8 +412,22 @@ func (s *State) Prune(pruneWait, abortWait time.Duration) {
 	}
 }
 
-// ReadState returns the state deserialized from r.
+// ReadState returns the state deserialized from r. If backend is a
+// JournalingBackend, r is its base state and the state is recovered from
+// it and the journal.
 func ReadState(backend Backend, r io.Reader) (*State, error) {
+	if jb, ok := backend.(JournalingBackend); ok {
+		base, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		data, err := jb.Recover(base)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+
 	s := new(State)
 	s.Lock()
 	defer s.unlock()
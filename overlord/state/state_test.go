@@ -399,6 +399,8 @@ func (ss *stateSuite) TestNewTaskAndCheckpoint(c *C) {
 	t2.WaitFor(t1)
 	schedule := time.Now().Add(time.Hour)
 	t2.At(schedule)
+	deadline := schedule.Add(time.Hour)
+	t2.SetDeadline(deadline)
 
 	// implicit checkpoint
 	st.Unlock()
@@ -453,6 +455,8 @@ func (ss *stateSuite) TestNewTaskAndCheckpoint(c *C) {
 
 	c.Check(task0_1.AtTime().IsZero(), Equals, true)
 	c.Check(task0_2.AtTime().Equal(schedule), Equals, true)
+	c.Check(task0_1.Deadline().IsZero(), Equals, true)
+	c.Check(task0_2.Deadline().Equal(deadline), Equals, true)
 }
 
 func (ss *stateSuite) TestEmptyStateDataAndCheckpointReadAndSet(c *C) {
@@ -22,6 +22,7 @@ package state
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/snapcore/snapd/logger"
 	"time"
@@ -54,7 +55,8 @@ type Task struct {
 	spawnTime time.Time
 	readyTime time.Time
 
-	atTime time.Time
+	atTime   time.Time
+	deadline time.Time
 }
 
 func newTask(state *State, id, kind, summary string) *Task {
@@ -85,7 +87,8 @@ type marshalledTask struct {
 	SpawnTime time.Time  `json:"spawn-time"`
 	ReadyTime *time.Time `json:"ready-time,omitempty"`
 
-	AtTime *time.Time `json:"at-time,omitempty"`
+	AtTime   *time.Time `json:"at-time,omitempty"`
+	Deadline *time.Time `json:"deadline,omitempty"`
 }
 
 // MarshalJSON makes Task a json.Marshaller
@@ -99,6 +102,10 @@ func (t *Task) MarshalJSON() ([]byte, error) {
 	if !t.atTime.IsZero() {
 		atTime = &t.atTime
 	}
+	var deadline *time.Time
+	if !t.deadline.IsZero() {
+		deadline = &t.deadline
+	}
 	return json.Marshal(marshalledTask{
 		ID:        t.id,
 		Kind:      t.kind,
@@ -115,7 +122,8 @@ func (t *Task) MarshalJSON() ([]byte, error) {
 		SpawnTime: t.spawnTime,
 		ReadyTime: readyTime,
 
-		AtTime: atTime,
+		AtTime:   atTime,
+		Deadline: deadline,
 	})
 }
 
@@ -151,6 +159,9 @@ func (t *Task) UnmarshalJSON(data []byte) error {
 	if unmarshalled.AtTime != nil {
 		t.atTime = *unmarshalled.AtTime
 	}
+	if unmarshalled.Deadline != nil {
+		t.deadline = *unmarshalled.Deadline
+	}
 	return nil
 }
 
@@ -182,14 +193,33 @@ func (t *Task) Status() Status {
 func (t *Task) SetStatus(new Status) {
 	t.state.writing()
 	old := t.status
+	chg := t.Change()
+	observers := t.state.observers
+	var oldStatus, oldChgStatus Status
+	if len(observers) > 0 {
+		oldStatus = t.Status()
+		if chg != nil {
+			oldChgStatus = chg.Status()
+		}
+	}
 	t.status = new
 	if !old.Ready() && new.Ready() {
 		t.readyTime = timeNow()
 	}
-	chg := t.Change()
 	if chg != nil {
 		chg.taskStatusChanged(t, old, new)
 	}
+	if len(observers) == 0 {
+		return
+	}
+	if newStatus := t.Status(); newStatus != oldStatus {
+		for _, o := range observers {
+			o.TaskStatusChanged(t, oldStatus, newStatus)
+		}
+	}
+	if chg != nil {
+		chg.notifyStatusChanged(oldChgStatus)
+	}
 }
 
 // IsClean returns whether the task has been cleaned. See SetClean.
@@ -246,12 +276,19 @@ func (t *Task) SetProgress(label string, done, total int) {
 	} else {
 		t.state.reading()
 	}
+	old := t.progress
 	if total <= 0 || done > total {
 		// Doing math wrong is easy. Be conservative.
 		t.progress = nil
 	} else {
 		t.progress = &progress{Label: label, Done: done, Total: total}
 	}
+	if old == nil && t.progress == nil || old != nil && t.progress != nil && *old == *t.progress {
+		return
+	}
+	for _, o := range t.state.observers {
+		o.TaskProgressChanged(t)
+	}
 }
 
 // SpawnTime returns the time when the change was created.
@@ -315,6 +352,38 @@ func (t *Task) Log() []string {
 	return t.log
 }
 
+// LogEntry is a message logged into a task, split into its parts.
+type LogEntry struct {
+	Time    time.Time
+	Kind    string
+	Message string
+}
+
+// LogEntries returns the messages returned by Log split into their
+// time, kind and message. Entries that cannot be split are returned
+// whole as the message.
+func (t *Task) LogEntries() []LogEntry {
+	t.state.reading()
+	if len(t.log) == 0 {
+		return nil
+	}
+	entries := make([]LogEntry, len(t.log))
+	for i, msg := range t.log {
+		entries[i] = parseLogEntry(msg)
+	}
+	return entries
+}
+
+func parseLogEntry(msg string) LogEntry {
+	parts := strings.SplitN(msg, " ", 3)
+	if len(parts) == 3 {
+		if tm, err := time.Parse(time.RFC3339, parts[0]); err == nil {
+			return LogEntry{Time: tm, Kind: parts[1], Message: parts[2]}
+		}
+	}
+	return LogEntry{Message: msg}
+}
+
 // Logf logs information about the progress of the task.
 func (t *Task) Logf(format string, args ...interface{}) {
 	t.state.writing()
@@ -347,6 +416,17 @@ func (t *Task) Clear(key string) {
 	delete(t.data, key)
 }
 
+// Data returns the serialized values associated with the task, by key.
+// The returned map is a copy, but the values should not be written to.
+func (t *Task) Data() map[string]*json.RawMessage {
+	t.state.reading()
+	data := make(map[string]*json.RawMessage, len(t.data))
+	for k, v := range t.data {
+		data[k] = v
+	}
+	return data
+}
+
 func addOnce(set []string, s string) []string {
 	for _, cur := range set {
 		if s == cur {
@@ -400,6 +480,29 @@ func (t *Task) At(when time.Time) {
 	}
 }
 
+// Deadline returns the time by which the task must be done. A zero time means no deadline.
+func (t *Task) Deadline() time.Time {
+	t.state.reading()
+	return t.deadline
+}
+
+// SetDeadline sets the time by which the task, if it's not ready, must be done, otherwise it fails and its change is undone. The zero time removes any deadline.
+func (t *Task) SetDeadline(when time.Time) {
+	t.state.writing()
+	iszero := when.IsZero()
+	if t.Status().Ready() && !iszero {
+		return
+	}
+	t.deadline = when
+	if !iszero {
+		d := when.Sub(timeNow())
+		if d < 0 {
+			d = 0
+		}
+		t.state.EnsureBefore(d)
+	}
+}
+
 // A TaskSet holds a set of tasks.
 type TaskSet struct {
 	tasks []*Task
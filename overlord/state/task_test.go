@@ -101,6 +101,28 @@ func (ts *taskSuite) TestClear(c *C) {
 	c.Check(t.Get("a", &v), Equals, state.ErrNoState)
 }
 
+func (ts *taskSuite) TestData(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	t := st.NewTask("download", "1...")
+	c.Check(t.Data(), HasLen, 0)
+
+	t.Set("a", 1)
+	t.Set("b", map[string]string{"c": "d"})
+
+	data := t.Data()
+	c.Assert(data, HasLen, 2)
+	c.Check(string(*data["a"]), Equals, `1`)
+	c.Check(string(*data["b"]), Equals, `{"c":"d"}`)
+
+	// the map is a copy
+	delete(data, "a")
+	var v int
+	c.Check(t.Get("a", &v), IsNil)
+}
+
 func (ts *taskSuite) TestStatusAndSetStatus(c *C) {
 	st := state.New(nil)
 	st.Lock()
@@ -300,6 +322,45 @@ func (ts *taskSuite) TestAtReadyNop(c *C) {
 	c.Check(b.ensureBefore, Equals, time.Hour)
 }
 
+func (ts *taskSuite) TestSetDeadline(c *C) {
+	b := new(fakeStateBackend)
+	b.ensureBefore = time.Hour
+	st := state.New(b)
+	st.Lock()
+	defer st.Unlock()
+
+	t := st.NewTask("download", "1...")
+	c.Check(t.Deadline().IsZero(), Equals, true)
+
+	now := time.Now()
+	restore := state.MockTime(now)
+	defer restore()
+	when := now.Add(10 * time.Second)
+	t.SetDeadline(when)
+
+	c.Check(t.Deadline().Equal(when), Equals, true)
+	c.Check(b.ensureBefore, Equals, 10*time.Second)
+
+	t.SetDeadline(time.Time{})
+	c.Check(t.Deadline().IsZero(), Equals, true)
+}
+
+func (ts *taskSuite) TestSetDeadlineReadyNop(c *C) {
+	b := new(fakeStateBackend)
+	b.ensureBefore = time.Hour
+	st := state.New(b)
+	st.Lock()
+	defer st.Unlock()
+
+	t := st.NewTask("download", "1...")
+	t.SetStatus(state.DoneStatus)
+
+	t.SetDeadline(time.Now().Add(10 * time.Second))
+
+	c.Check(t.Deadline().IsZero(), Equals, true)
+	c.Check(b.ensureBefore, Equals, time.Hour)
+}
+
 func (cs *taskSuite) TestLogf(c *C) {
 	st := state.New(nil)
 	st.Lock()
@@ -329,6 +390,42 @@ func (cs *taskSuite) TestErrorf(c *C) {
 	c.Assert(t.Log()[0], Matches, "....-..-..T.* ERROR Some error")
 }
 
+func (cs *taskSuite) TestLogEntries(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	t := st.NewTask("download", "1...")
+	c.Check(t.LogEntries(), IsNil)
+
+	now := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+	restore := state.MockTime(now)
+	defer restore()
+
+	t.Logf("Some %s", "info")
+	t.Errorf("Some error: with colons and  spaces")
+
+	c.Check(t.LogEntries(), DeepEquals, []state.LogEntry{
+		{Time: now, Kind: state.LogInfo, Message: "Some info"},
+		{Time: now, Kind: state.LogError, Message: "Some error: with colons and  spaces"},
+	})
+}
+
+func (cs *taskSuite) TestLogEntriesUnparsable(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	t := st.NewTask("download", "1...")
+	err := t.UnmarshalJSON([]byte(`{"id": "1", "kind": "download", "log": ["not a time INFO foo", "short"]}`))
+	c.Assert(err, IsNil)
+
+	c.Check(t.LogEntries(), DeepEquals, []state.LogEntry{
+		{Message: "not a time INFO foo"},
+		{Message: "short"},
+	})
+}
+
 func (ts *taskSuite) TestTaskMarshalsLog(c *C) {
 	st := state.New(nil)
 	st.Lock()
@@ -372,6 +469,8 @@ func (cs *taskSuite) TestMethodEntrance(c *C) {
 		func() { t1.HaltTasks() },
 		func() { t1.Progress() },
 		func() { t1.Log() },
+		func() { t1.LogEntries() },
+		func() { t1.Data() },
 		func() { t1.MarshalJSON() },
 		func() { t1.Progress() },
 		func() { t1.SetProgress("", 0, 1) },
@@ -479,3 +578,56 @@ func (ts *taskSuite) TestTaskSetAddTaskAndAddAll(c *C) {
 
 	c.Check(ts0.Tasks(), DeepEquals, []*state.Task{t1, t2, t3, t4})
 }
+
+type recordingObserver struct {
+	events []string
+}
+
+func (o *recordingObserver) ChangeStatusChanged(chg *state.Change, old, new state.Status) {
+	o.events = append(o.events, fmt.Sprintf("change %s: %s -> %s", chg.ID(), old, new))
+}
+
+func (o *recordingObserver) TaskStatusChanged(t *state.Task, old, new state.Status) {
+	o.events = append(o.events, fmt.Sprintf("task %s: %s -> %s", t.ID(), old, new))
+}
+
+func (o *recordingObserver) TaskProgressChanged(t *state.Task) {
+	label, done, total := t.Progress()
+	o.events = append(o.events, fmt.Sprintf("task %s: %s %d/%d", t.ID(), label, done, total))
+}
+
+func (ts *taskSuite) TestObserver(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	o := &recordingObserver{}
+	st.AddObserver(o)
+
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("download", "1...")
+	t2 := st.NewTask("link", "2...")
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+
+	t1.SetStatus(state.DoingStatus)
+	t1.SetProgress("snap", 1, 10)
+	// unchanged progress is not reported again
+	t1.SetProgress("snap", 1, 10)
+	t1.SetStatus(state.DoneStatus)
+	// unchanged status is not reported again
+	t1.SetStatus(state.DoneStatus)
+	t2.SetStatus(state.DoneStatus)
+	chg.SetStatus(state.ErrorStatus)
+
+	c.Check(o.events, DeepEquals, []string{
+		"task 1: Do -> Doing",
+		"change 1: Do -> Doing",
+		"task 1: snap 1/10",
+		"task 1: Doing -> Done",
+		"change 1: Doing -> Do",
+		"task 2: Do -> Done",
+		"change 1: Do -> Done",
+		"change 1: Done -> Error",
+	})
+}
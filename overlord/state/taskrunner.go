@@ -20,6 +20,7 @@
 package state
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -310,6 +311,30 @@ func (r *TaskRunner) Ensure() {
 			r.tryUndo(t)
 		}
 
+		// fail tasks not done by their deadline and track the earliest
+		// deadline to come
+		if deadline := t.Deadline(); !deadline.IsZero() {
+			switch t.Status() {
+			case DoStatus, DoingStatus:
+				if ensureTime.Before(deadline) {
+					if nextTaskTime.IsZero() || nextTaskTime.After(deadline) {
+						nextTaskTime = deadline
+					}
+					break
+				}
+				err := fmt.Errorf("task not done by its deadline (%s)", deadline.Format(time.RFC3339))
+				if tb != nil {
+					// it fails once its handler returns
+					tb.Kill(err)
+					continue
+				}
+				r.abortChange(t.Change())
+				t.SetStatus(ErrorStatus)
+				t.Errorf("%s", err)
+				continue
+			}
+		}
+
 		if tb != nil {
 			// Already being handled.
 			continue
@@ -356,7 +381,7 @@ func (r *TaskRunner) Ensure() {
 		running = append(running, t)
 	}
 
-	// schedule next Ensure no later than the next task time
+	// schedule next Ensure no later than the next task time or deadline
 	if !nextTaskTime.IsZero() {
 		r.state.EnsureBefore(nextTaskTime.Sub(ensureTime))
 	}
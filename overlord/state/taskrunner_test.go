@@ -491,6 +491,107 @@ func (ts *taskRunnerSuite) TestRetryAfterDuration(c *C) {
 	c.Check(t.AtTime().IsZero(), Equals, true)
 }
 
+func (ts *taskRunnerSuite) TestDeadlineFailsPendingTask(c *C) {
+	sb := &stateBackend{ensureBefore: time.Hour}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	ran := false
+	r.AddHandler("later", func(t *state.Task, _ *tomb.Tomb) error {
+		ran = true
+		return nil
+	}, nil)
+
+	now := time.Now()
+	restore := state.MockTime(now)
+	defer restore()
+
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	t := st.NewTask("later", "...")
+	chg.AddTask(t)
+	t.At(now.Add(time.Hour))
+	t.SetDeadline(now.Add(time.Minute))
+	c.Check(t.Deadline().Equal(now.Add(time.Minute)), Equals, true)
+	sb.ensureBefore = time.Hour
+	st.Unlock()
+
+	r.Ensure() // too soon for either
+	c.Check(sb.ensureBefore, Equals, time.Minute)
+
+	state.MockTime(now.Add(time.Minute))
+	r.Ensure()
+	r.Wait()
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(ran, Equals, false)
+	c.Check(t.Status(), Equals, state.ErrorStatus)
+	c.Check(strings.Join(t.Log(), ""), Matches, `.*task not done by its deadline \(.*\)`)
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+}
+
+func (ts *taskRunnerSuite) TestDeadlineFailsRunningTaskAndUndoes(c *C) {
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	undone := false
+	r.AddHandler("quick", func(t *state.Task, _ *tomb.Tomb) error {
+		return nil
+	}, func(t *state.Task, _ *tomb.Tomb) error {
+		undone = true
+		return nil
+	})
+	started := make(chan bool, 1)
+	r.AddHandler("slow", func(t *state.Task, tb *tomb.Tomb) error {
+		started <- true
+		<-tb.Dying()
+		return &state.Retry{}
+	}, nil)
+
+	now := time.Now()
+	restore := state.MockTime(now)
+	defer restore()
+
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("quick", "...")
+	t2 := st.NewTask("slow", "...")
+	t2.WaitFor(t1)
+	t2.SetDeadline(now.Add(time.Minute))
+	chg.AddAll(state.NewTaskSet(t1, t2))
+	st.Unlock()
+
+	r.Ensure()
+	r.Wait()
+	r.Ensure()
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		c.Fatal("slow task wasn't started")
+	}
+
+	state.MockTime(now.Add(time.Minute))
+	r.Ensure()
+	r.Wait()
+
+	st.Lock()
+	c.Check(t2.Status(), Equals, state.ErrorStatus)
+	c.Check(strings.Join(t2.Log(), ""), Matches, `.*task not done by its deadline \(.*\)`)
+	st.Unlock()
+
+	ensureChange(c, r, sb, chg)
+
+	st.Lock()
+	defer st.Unlock()
+	c.Check(t1.Status(), Equals, state.UndoneStatus)
+	c.Check(undone, Equals, true)
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+}
+
 func (ts *taskRunnerSuite) TestTaskSerialization(c *C) {
 	ensureBeforeTick := make(chan bool, 1)
 	sb := &stateBackend{
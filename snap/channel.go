@@ -0,0 +1,114 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// channelRisks are the risk levels of channels, from the least to the
+// most risky.
+var channelRisks = []string{"stable", "candidate", "beta", "edge"}
+
+// Channel identifies a snap channel, written as
+// [<track>/]<risk>[/<branch>].
+type Channel struct {
+	Track  string
+	Risk   string
+	Branch string
+}
+
+func riskLevel(risk string) int {
+	for i, r := range channelRisks {
+		if r == risk {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseChannel parses a channel name. A name without a risk level, e.g.
+// just a track, refers to its stable risk level.
+func ParseChannel(s string) (Channel, error) {
+	if s == "" {
+		return Channel{}, fmt.Errorf("channel name cannot be empty")
+	}
+
+	var ch Channel
+	parts := strings.Split(s, "/")
+	for _, part := range parts {
+		if part == "" {
+			return Channel{}, fmt.Errorf("invalid channel name %q: empty component", s)
+		}
+	}
+
+	switch len(parts) {
+	case 1:
+		if riskLevel(parts[0]) >= 0 {
+			ch.Risk = parts[0]
+		} else {
+			ch.Track = parts[0]
+			ch.Risk = "stable"
+		}
+	case 2:
+		if riskLevel(parts[0]) >= 0 {
+			ch.Risk, ch.Branch = parts[0], parts[1]
+		} else {
+			ch.Track, ch.Risk = parts[0], parts[1]
+		}
+	case 3:
+		ch.Track, ch.Risk, ch.Branch = parts[0], parts[1], parts[2]
+	default:
+		return Channel{}, fmt.Errorf("invalid channel name %q: too many components", s)
+	}
+
+	if riskLevel(ch.Risk) < 0 {
+		return Channel{}, fmt.Errorf("invalid channel name %q: invalid risk %q", s, ch.Risk)
+	}
+
+	return ch, nil
+}
+
+func (ch Channel) String() string {
+	parts := make([]string, 0, 3)
+	if ch.Track != "" {
+		parts = append(parts, ch.Track)
+	}
+	parts = append(parts, ch.Risk)
+	if ch.Branch != "" {
+		parts = append(parts, ch.Branch)
+	}
+	return strings.Join(parts, "/")
+}
+
+// Fallbacks returns the channels to try, in order, when the channel is
+// closed: the channel without its branch, followed by the less risky
+// channels of the same track.
+func (ch Channel) Fallbacks() []Channel {
+	var fallbacks []Channel
+	if ch.Branch != "" {
+		fallbacks = append(fallbacks, Channel{Track: ch.Track, Risk: ch.Risk})
+	}
+	for i := riskLevel(ch.Risk) - 1; i >= 0; i-- {
+		fallbacks = append(fallbacks, Channel{Track: ch.Track, Risk: channelRisks[i]})
+	}
+	return fallbacks
+}
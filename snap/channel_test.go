@@ -0,0 +1,95 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snap_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/snap"
+)
+
+type channelSuite struct{}
+
+var _ = Suite(&channelSuite{})
+
+func (s channelSuite) TestParseChannel(c *C) {
+	for _, t := range []struct {
+		name     string
+		expected snap.Channel
+	}{
+		{"stable", snap.Channel{Risk: "stable"}},
+		{"edge", snap.Channel{Risk: "edge"}},
+		{"1.0", snap.Channel{Track: "1.0", Risk: "stable"}},
+		{"1.0/beta", snap.Channel{Track: "1.0", Risk: "beta"}},
+		{"beta/fix-123", snap.Channel{Risk: "beta", Branch: "fix-123"}},
+		{"1.0/candidate/fix-123", snap.Channel{Track: "1.0", Risk: "candidate", Branch: "fix-123"}},
+	} {
+		ch, err := snap.ParseChannel(t.name)
+		c.Assert(err, IsNil, Commentf(t.name))
+		c.Check(ch, Equals, t.expected, Commentf(t.name))
+	}
+}
+
+func (s channelSuite) TestParseChannelErrors(c *C) {
+	for _, t := range []struct {
+		name string
+		err  string
+	}{
+		{"", `channel name cannot be empty`},
+		{"1.0/", `invalid channel name "1.0/": empty component`},
+		{"/stable", `invalid channel name "/stable": empty component`},
+		{"1.0/foo", `invalid channel name "1.0/foo": invalid risk "foo"`},
+		{"1.0/foo/bar", `invalid channel name "1.0/foo/bar": invalid risk "foo"`},
+		{"1.0/stable/fix/more", `invalid channel name "1.0/stable/fix/more": too many components`},
+	} {
+		_, err := snap.ParseChannel(t.name)
+		c.Check(err, ErrorMatches, t.err, Commentf(t.name))
+	}
+}
+
+func (s channelSuite) TestString(c *C) {
+	for _, name := range []string{"stable", "1.0/beta", "beta/fix-123", "1.0/candidate/fix-123"} {
+		ch, err := snap.ParseChannel(name)
+		c.Assert(err, IsNil)
+		c.Check(ch.String(), Equals, name)
+	}
+}
+
+func (s channelSuite) TestFallbacks(c *C) {
+	for _, t := range []struct {
+		name      string
+		fallbacks []string
+	}{
+		{"stable", nil},
+		{"candidate", []string{"stable"}},
+		{"edge", []string{"beta", "candidate", "stable"}},
+		{"1.0/beta", []string{"1.0/candidate", "1.0/stable"}},
+		{"candidate/fix-123", []string{"candidate", "stable"}},
+		{"1.0/stable/fix-123", []string{"1.0/stable"}},
+	} {
+		ch, err := snap.ParseChannel(t.name)
+		c.Assert(err, IsNil)
+		var fallbacks []string
+		for _, fallback := range ch.Fallbacks() {
+			fallbacks = append(fallbacks, fallback.String())
+		}
+		c.Check(fallbacks, DeepEquals, t.fallbacks, Commentf(t.name))
+	}
+}
@@ -0,0 +1,55 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snap
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseEpoch splits a valid epoch into its number and whether it is
+// starred; the empty epoch is epoch 0.
+func parseEpoch(epoch string) (n int, star bool, err error) {
+	if epoch == "" {
+		return 0, false, nil
+	}
+	if err := ValidateEpoch(epoch); err != nil {
+		return 0, false, err
+	}
+	star = strings.HasSuffix(epoch, "*")
+	n, err = strconv.Atoi(strings.TrimSuffix(epoch, "*"))
+	return n, star, err
+}
+
+// EpochCanRead returns whether a revision with the given epoch can read
+// the data written by a revision with epoch dataEpoch. A revision with
+// epoch "N" reads data of epoch N only, one with epoch "N*" also reads
+// (and migrates) data of epoch N-1; both write data of epoch N.
+func EpochCanRead(epoch, dataEpoch string) bool {
+	n, star, err := parseEpoch(epoch)
+	if err != nil {
+		return false
+	}
+	m, _, err := parseEpoch(dataEpoch)
+	if err != nil {
+		return false
+	}
+	return n == m || (star && n-1 == m)
+}
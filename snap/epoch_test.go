@@ -0,0 +1,56 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2017 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snap_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/snap"
+)
+
+type epochSuite struct{}
+
+var _ = Suite(&epochSuite{})
+
+func (s epochSuite) TestEpochCanRead(c *C) {
+	for _, t := range []struct {
+		epoch, dataEpoch string
+		canRead          bool
+	}{
+		{"", "", true},
+		{"0", "", true},
+		{"", "0", true},
+		{"0", "0", true},
+		{"1", "1", true},
+		{"1", "0", false},
+		{"0", "1", false},
+		{"1*", "0", true},
+		{"1*", "1", true},
+		{"1*", "1*", true},
+		{"2", "1*", false},
+		{"2*", "1*", true},
+		{"2*", "0", false},
+		{"1", "1*", true},
+		{"x", "0", false},
+		{"0", "x", false},
+	} {
+		c.Check(snap.EpochCanRead(t.epoch, t.dataEpoch), Equals, t.canRead, Commentf("%q reading %q", t.epoch, t.dataEpoch))
+	}
+}
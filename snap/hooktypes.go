@@ -25,6 +25,14 @@ import (
 
 var supportedHooks = []*HookType{
 	newHookType(regexp.MustCompile("^configure$")),
+	newHookType(regexp.MustCompile("^install$")),
+	newHookType(regexp.MustCompile("^remove$")),
+	newHookType(regexp.MustCompile("^pre-refresh$")),
+	newHookType(regexp.MustCompile("^post-refresh$")),
+	newHookType(regexp.MustCompile("^prepare-plug-[-a-z0-9]+$")),
+	newHookType(regexp.MustCompile("^prepare-slot-[-a-z0-9]+$")),
+	newHookType(regexp.MustCompile("^connect-plug-[-a-z0-9]+$")),
+	newHookType(regexp.MustCompile("^connect-slot-[-a-z0-9]+$")),
 }
 
 // HookType represents a pattern of supported hook names.
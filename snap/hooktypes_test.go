@@ -0,0 +1,39 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snap_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/snap"
+)
+
+type hookTypesSuite struct{}
+
+var _ = Suite(&hookTypesSuite{})
+
+func (s *hookTypesSuite) TestIsHookSupported(c *C) {
+	for _, hook := range []string{"configure", "install", "remove", "pre-refresh", "post-refresh", "prepare-plug-foo", "prepare-slot-foo-bar", "connect-plug-foo", "connect-slot-foo2"} {
+		c.Check(snap.IsHookSupported(hook), Equals, true, Commentf(hook))
+	}
+	for _, hook := range []string{"", "foo", "install-foo", "refresh", "pre-refresh-foo", "prepare-plug-", "connect-plug", "connect-foo-bar", "prepare-slot-Foo"} {
+		c.Check(snap.IsHookSupported(hook), Equals, false, Commentf(hook))
+	}
+}
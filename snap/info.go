@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/snapcore/snapd/dirs"
@@ -59,8 +60,31 @@ type PlaceInfo interface {
 }
 
 // MinimalPlaceInfo returns a PlaceInfo with just the location information for a snap of the given name and revision.
+// The name can be an instance name.
 func MinimalPlaceInfo(name string, revision Revision) PlaceInfo {
-	return &Info{SideInfo: SideInfo{RealName: name, Revision: revision}}
+	snapName, instanceKey := SplitInstanceName(name)
+	return &Info{SideInfo: SideInfo{RealName: snapName, Revision: revision}, InstanceKey: instanceKey}
+}
+
+// InstanceName returns the name of the instance of the snap with the
+// given instance key, of the form <snap>_<key>. With an empty
+// instance key it is just the snap name.
+func InstanceName(snapName, instanceKey string) string {
+	if instanceKey == "" {
+		return snapName
+	}
+	return fmt.Sprintf("%s_%s", snapName, instanceKey)
+}
+
+// SplitInstanceName splits an instance name of the form <snap>_<key>
+// into the snap name and the instance key, which is empty if there
+// is none.
+func SplitInstanceName(instanceName string) (snapName, instanceKey string) {
+	l := strings.SplitN(instanceName, "_", 2)
+	if len(l) < 2 {
+		return l[0], ""
+	}
+	return l[0], l[1]
 }
 
 // MountDir returns the base directory where it gets mounted of the snap with the given name and revision.
@@ -121,6 +145,10 @@ type Info struct {
 	Architectures []string
 	Assumes       []string
 
+	// Base is the name of the base snap providing the root filesystem
+	// the snap runs on, empty meaning the OS snap.
+	Base string
+
 	OriginalSummary     string
 	OriginalDescription string
 
@@ -131,13 +159,22 @@ type Info struct {
 	Epoch            string
 	Confinement      ConfinementType
 	Apps             map[string]*AppInfo
+	Aliases          map[string]*AppInfo
 	Hooks            map[string]*HookInfo
 	Plugs            map[string]*PlugInfo
 	Slots            map[string]*SlotInfo
 
+	// Layout maps paths in the snap's mount namespace to what they
+	// are remapped to.
+	Layout map[string]*Layout
+
 	// The information in all the remaining fields is not sourced from the snap blob itself.
 	SideInfo
 
+	// InstanceKey distinguishes instances of the same snap that are
+	// installed side by side, it is empty for the main instance.
+	InstanceKey string
+
 	// Broken marks if set whether the snap is broken and the reason.
 	Broken string
 
@@ -151,8 +188,15 @@ type Info struct {
 	Screenshots []ScreenshotInfo
 }
 
-// Name returns the blessed name for the snap.
+// Name returns the blessed name for the installed snap, that is the
+// instance name if the snap has an instance key.
 func (s *Info) Name() string {
+	return InstanceName(s.SnapName(), s.InstanceKey)
+}
+
+// SnapName returns the blessed name of the snap itself, as known to
+// the store, regardless of the instance key.
+func (s *Info) SnapName() string {
 	if s.RealName != "" {
 		return s.RealName
 	}
@@ -220,11 +264,28 @@ func (s *Info) CommonDataHomeDir() string {
 	return filepath.Join(dirs.SnapDataHomeGlob, s.Name(), "common")
 }
 
+// DesktopPrefix returns the prefix of the names of the desktop files
+// installed for the snap. Instances use a "+" rather than "_" to
+// separate the instance key, as "_" separates the prefix from the name
+// of the desktop file.
+func (s *Info) DesktopPrefix() string {
+	if s.InstanceKey == "" {
+		return s.SnapName()
+	}
+	return fmt.Sprintf("%s+%s", s.SnapName(), s.InstanceKey)
+}
+
 // NeedsDevMode retursn whether the snap needs devmode.
 func (s *Info) NeedsDevMode() bool {
 	return s.Confinement == DevmodeConfinement
 }
 
+// NeedsClassic returns whether the snap needs classic confinement, i.e.
+// running on the host filesystem without a sandbox.
+func (s *Info) NeedsClassic() bool {
+	return s.Confinement == ClassicConfinement
+}
+
 // DownloadInfo contains the information to download a snap.
 // It can be marshalled.
 type DownloadInfo struct {
@@ -285,13 +346,25 @@ type AppInfo struct {
 	Daemon          string
 	StopTimeout     timeout.Timeout
 	StopCommand     string
+	ReloadCommand   string
 	PostStopCommand string
 	RestartCond     systemd.RestartCondition
+	StopMode        StopModeType
+	RefreshMode     string
+	InstallMode     string
+
+	// After and Before name the services of the same snap this
+	// service is ordered after and before
+	After  []string
+	Before []string
 
 	Socket       bool
 	SocketMode   string
 	ListenStream string
 
+	Sockets map[string]*SocketInfo
+	Timer   *TimerInfo
+
 	// TODO: this should go away once we have more plumbing and can change
 	// things vs refactor
 	// https://github.com/snapcore/snapd/pull/794#discussion_r58688496
@@ -303,6 +376,24 @@ type AppInfo struct {
 	Environment map[string]string
 }
 
+// SocketInfo provides information about a named socket activating a
+// service app.
+type SocketInfo struct {
+	App *AppInfo
+
+	Name         string
+	ListenStream string
+	SocketMode   string
+}
+
+// TimerInfo provides information about the timer activating a service
+// app. Timer is a systemd calendar event expression.
+type TimerInfo struct {
+	App *AppInfo
+
+	Timer string
+}
+
 // ScreenshotInfo provides information about a screenshot.
 type ScreenshotInfo struct {
 	URL    string
@@ -318,6 +409,36 @@ type HookInfo struct {
 	Plugs map[string]*PlugInfo
 }
 
+// Layout describes how a single path of the snap's mount namespace is
+// remapped. Exactly one of Bind, BindFile, Symlink or Type is set, the
+// first three refer to paths under $SNAP, $SNAP_DATA or $SNAP_COMMON.
+type Layout struct {
+	Snap *Info
+
+	Path     string
+	Bind     string
+	BindFile string
+	Symlink  string
+	Type     string
+}
+
+// ExpandSnapVariables resolves $SNAP, $SNAP_DATA and $SNAP_COMMON in
+// the given path to the directories of the snap, other variables are
+// left alone.
+func (s *Info) ExpandSnapVariables(path string) string {
+	return os.Expand(path, func(v string) string {
+		switch v {
+		case "SNAP":
+			return s.MountDir()
+		case "SNAP_DATA":
+			return s.DataDir()
+		case "SNAP_COMMON":
+			return s.CommonDataDir()
+		}
+		return "${" + v + "}"
+	})
+}
+
 // SecurityTag returns application-specific security tag.
 //
 // Security tags are used by various security subsystems as "profile names" and
@@ -329,8 +450,8 @@ func (app *AppInfo) SecurityTag() string {
 // WrapperPath returns the path to wrapper invoking the app binary.
 func (app *AppInfo) WrapperPath() string {
 	var binName string
-	if app.Name == app.Snap.Name() {
-		binName = filepath.Base(app.Name)
+	if app.Name == app.Snap.SnapName() {
+		binName = app.Snap.Name()
 	} else {
 		binName = fmt.Sprintf("%s.%s", app.Snap.Name(), filepath.Base(app.Name))
 	}
@@ -342,8 +463,8 @@ func (app *AppInfo) launcherCommand(command string) string {
 	if command != "" {
 		command = " " + command
 	}
-	if app.Name == app.Snap.Name() {
-		return fmt.Sprintf("/usr/bin/snap run%s %s", command, app.Name)
+	if app.Name == app.Snap.SnapName() {
+		return fmt.Sprintf("/usr/bin/snap run%s %s", command, app.Snap.Name())
 	}
 	return fmt.Sprintf("/usr/bin/snap run%s %s.%s", command, app.Snap.Name(), filepath.Base(app.Name))
 }
@@ -358,11 +479,21 @@ func (app *AppInfo) LauncherStopCommand() string {
 	return app.launcherCommand("--command=stop")
 }
 
+// LauncherReloadCommand returns the launcher command line to use when invoking the app reload command binary.
+func (app *AppInfo) LauncherReloadCommand() string {
+	return app.launcherCommand("--command=reload")
+}
+
 // LauncherPostStopCommand returns the launcher command line to use when invoking the app post-stop command binary.
 func (app *AppInfo) LauncherPostStopCommand() string {
 	return app.launcherCommand("--command=post-stop")
 }
 
+// IsService returns whether the app is a service, i.e. a daemon.
+func (app *AppInfo) IsService() bool {
+	return app.Daemon != ""
+}
+
 // ServiceFile returns the systemd service file path for the daemon app.
 func (app *AppInfo) ServiceFile() string {
 	return filepath.Join(dirs.SnapServicesDir, app.SecurityTag()+".service")
@@ -373,6 +504,80 @@ func (app *AppInfo) ServiceSocketFile() string {
 	return filepath.Join(dirs.SnapServicesDir, app.SecurityTag()+".socket")
 }
 
+// SortServices sorts the given services so that each comes after the
+// services it is ordered after and before the ones it is ordered
+// before, otherwise by name. Orderings referring to services that are
+// not given are ignored.
+func SortServices(apps []*AppInfo) ([]*AppInfo, error) {
+	byName := make(map[string]*AppInfo, len(apps))
+	for _, app := range apps {
+		byName[app.Name] = app
+	}
+
+	// the services each service must start after
+	after := make(map[string]map[string]bool, len(apps))
+	for _, app := range apps {
+		after[app.Name] = make(map[string]bool)
+	}
+	for _, app := range apps {
+		for _, other := range app.After {
+			if byName[other] != nil {
+				after[app.Name][other] = true
+			}
+		}
+		for _, other := range app.Before {
+			if byName[other] != nil {
+				after[other][app.Name] = true
+			}
+		}
+	}
+
+	// repeatedly take the services that have nothing left to wait
+	// for, whatever remains is part of a cycle
+	sorted := make([]*AppInfo, 0, len(apps))
+	for len(after) > 0 {
+		var ready []string
+		for name, deps := range after {
+			if len(deps) == 0 {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			cycle := make([]string, 0, len(after))
+			for name := range after {
+				cycle = append(cycle, name)
+			}
+			sort.Strings(cycle)
+			return nil, fmt.Errorf("applications are part of a before/after cycle: %s", strings.Join(cycle, ", "))
+		}
+		sort.Strings(ready)
+		for _, name := range ready {
+			delete(after, name)
+			for _, deps := range after {
+				delete(deps, name)
+			}
+			sorted = append(sorted, byName[name])
+		}
+	}
+	return sorted, nil
+}
+
+// IsActivated returns whether the service app is started on demand by
+// its sockets or timer rather than at boot.
+func (app *AppInfo) IsActivated() bool {
+	return len(app.Sockets) > 0 || app.Timer != nil
+}
+
+// File returns the systemd socket unit file path for the socket.
+func (socket *SocketInfo) File() string {
+	return filepath.Join(dirs.SnapServicesDir, socket.App.SecurityTag()+"."+socket.Name+".socket")
+}
+
+// File returns the systemd timer unit file path for the timer.
+func (timer *TimerInfo) File() string {
+	return filepath.Join(dirs.SnapServicesDir, timer.App.SecurityTag()+".timer")
+}
+
 func copyEnv(in map[string]string) map[string]string {
 	out := make(map[string]string)
 	for k, v := range in {
@@ -436,6 +641,7 @@ func (e NotFoundError) Error() string {
 }
 
 // ReadInfo reads the snap information for the installed snap with the given name and given side-info.
+// The name can be an instance name.
 func ReadInfo(name string, si *SideInfo) (*Info, error) {
 	snapYamlFn := filepath.Join(MountDir(name, si.Revision), "meta", "snap.yaml")
 	meta, err := ioutil.ReadFile(snapYamlFn)
@@ -450,6 +656,7 @@ func ReadInfo(name string, si *SideInfo) (*Info, error) {
 	if err != nil {
 		return nil, err
 	}
+	_, info.InstanceKey = SplitInstanceName(name)
 
 	err = addImplicitHooks(info)
 	if err != nil {
@@ -487,11 +694,12 @@ func ReadInfoFromSnapFile(snapf Container, si *SideInfo) (*Info, error) {
 
 // SplitSnapApp will split a string of the form `snap.app` into
 // the `snap` and the `app` part. It also deals with the special
-// case of snapName == appName.
+// case of snapName == appName, also when snap is an instance name.
 func SplitSnapApp(snapApp string) (snap, app string) {
 	l := strings.SplitN(snapApp, ".", 2)
 	if len(l) < 2 {
-		return l[0], l[0]
+		snapName, _ := SplitInstanceName(l[0])
+		return l[0], snapName
 	}
 	return l[0], l[1]
 }
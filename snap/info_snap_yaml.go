@@ -36,6 +36,7 @@ type snapYaml struct {
 	Type             Type                   `yaml:"type"`
 	Architectures    []string               `yaml:"architectures,omitempty"`
 	Assumes          []string               `yaml:"assumes"`
+	Base             string                 `yaml:"base,omitempty"`
 	Description      string                 `yaml:"description"`
 	Summary          string                 `yaml:"summary"`
 	LicenseAgreement string                 `yaml:"license-agreement,omitempty"`
@@ -47,6 +48,7 @@ type snapYaml struct {
 	Slots            map[string]interface{} `yaml:"slots,omitempty"`
 	Apps             map[string]appYaml     `yaml:"apps,omitempty"`
 	Hooks            map[string]hookYaml    `yaml:"hooks,omitempty"`
+	Layout           map[string]layoutYaml  `yaml:"layout,omitempty"`
 }
 
 type plugYaml struct {
@@ -69,8 +71,15 @@ type appYaml struct {
 	Daemon string `yaml:"daemon"`
 
 	StopCommand     string          `yaml:"stop-command,omitempty"`
+	ReloadCommand   string          `yaml:"reload-command,omitempty"`
 	PostStopCommand string          `yaml:"post-stop-command,omitempty"`
 	StopTimeout     timeout.Timeout `yaml:"stop-timeout,omitempty"`
+	StopMode        StopModeType    `yaml:"stop-mode,omitempty"`
+	RefreshMode     string          `yaml:"refresh-mode,omitempty"`
+	InstallMode     string          `yaml:"install-mode,omitempty"`
+
+	After  []string `yaml:"after,omitempty"`
+	Before []string `yaml:"before,omitempty"`
 
 	RestartCond systemd.RestartCondition `yaml:"restart-condition,omitempty"`
 	SlotNames   []string                 `yaml:"slots,omitempty"`
@@ -83,12 +92,29 @@ type appYaml struct {
 	Socket       bool   `yaml:"socket,omitempty"`
 	ListenStream string `yaml:"listen-stream,omitempty"`
 	SocketMode   string `yaml:"socket-mode,omitempty"`
+
+	Sockets map[string]socketsYaml `yaml:"sockets,omitempty"`
+	Timer   string                 `yaml:"timer,omitempty"`
+
+	Aliases []string `yaml:"aliases,omitempty"`
+}
+
+type socketsYaml struct {
+	ListenStream string `yaml:"listen-stream,omitempty"`
+	SocketMode   string `yaml:"socket-mode,omitempty"`
 }
 
 type hookYaml struct {
 	PlugNames []string `yaml:"plugs,omitempty"`
 }
 
+type layoutYaml struct {
+	Bind     string `yaml:"bind,omitempty"`
+	BindFile string `yaml:"bind-file,omitempty"`
+	Symlink  string `yaml:"symlink,omitempty"`
+	Type     string `yaml:"type,omitempty"`
+}
+
 // InfoFromSnapYaml creates a new info based on the given snap.yaml data
 func InfoFromSnapYaml(yamlData []byte) (*Info, error) {
 	var y snapYaml
@@ -125,6 +151,13 @@ func InfoFromSnapYaml(yamlData []byte) (*Info, error) {
 	setAppsFromSnapYaml(y, snap)
 	setHooksFromSnapYaml(y, snap)
 
+	setLayoutFromSnapYaml(y, snap)
+
+	// Collect the aliases declared by the apps
+	if err := setAliasesFromSnapYaml(y, snap); err != nil {
+		return nil, err
+	}
+
 	// Bind unbound plugs to all apps and hooks
 	bindUnboundPlugs(globalPlugNames, snap)
 
@@ -163,6 +196,7 @@ func infoSkeletonFromSnapYaml(y snapYaml) *Info {
 		Type:                typ,
 		Architectures:       architectures,
 		Assumes:             y.Assumes,
+		Base:                y.Base,
 		OriginalDescription: y.Description,
 		OriginalSummary:     y.Summary,
 		LicenseAgreement:    y.LicenseAgreement,
@@ -170,6 +204,7 @@ func infoSkeletonFromSnapYaml(y snapYaml) *Info {
 		Epoch:               epoch,
 		Confinement:         confinement,
 		Apps:                make(map[string]*AppInfo),
+		Aliases:             make(map[string]*AppInfo),
 		Hooks:               make(map[string]*HookInfo),
 		Plugs:               make(map[string]*PlugInfo),
 		Slots:               make(map[string]*SlotInfo),
@@ -232,6 +267,18 @@ func setSlotsFromSnapYaml(y snapYaml, snap *Info) error {
 	return nil
 }
 
+func setAliasesFromSnapYaml(y snapYaml, snap *Info) error {
+	for appName, yApp := range y.Apps {
+		for _, alias := range yApp.Aliases {
+			if other, ok := snap.Aliases[alias]; ok {
+				return fmt.Errorf("cannot set %q as alias for both %q and %q", alias, other.Name, appName)
+			}
+			snap.Aliases[alias] = snap.Apps[appName]
+		}
+	}
+	return nil
+}
+
 func setAppsFromSnapYaml(y snapYaml, snap *Info) {
 	for appName, yApp := range y.Apps {
 		// Collect all apps
@@ -242,14 +289,37 @@ func setAppsFromSnapYaml(y snapYaml, snap *Info) {
 			Daemon:          yApp.Daemon,
 			StopTimeout:     yApp.StopTimeout,
 			StopCommand:     yApp.StopCommand,
+			ReloadCommand:   yApp.ReloadCommand,
 			PostStopCommand: yApp.PostStopCommand,
 			RestartCond:     yApp.RestartCond,
+			StopMode:        yApp.StopMode,
+			RefreshMode:     yApp.RefreshMode,
+			InstallMode:     yApp.InstallMode,
+			After:           yApp.After,
+			Before:          yApp.Before,
 			Socket:          yApp.Socket,
 			SocketMode:      yApp.SocketMode,
 			ListenStream:    yApp.ListenStream,
 			BusName:         yApp.BusName,
 			Environment:     yApp.Environment,
 		}
+		if len(yApp.Sockets) > 0 {
+			app.Sockets = make(map[string]*SocketInfo, len(yApp.Sockets))
+		}
+		for socketName, ySocket := range yApp.Sockets {
+			app.Sockets[socketName] = &SocketInfo{
+				App:          app,
+				Name:         socketName,
+				ListenStream: ySocket.ListenStream,
+				SocketMode:   ySocket.SocketMode,
+			}
+		}
+		if yApp.Timer != "" {
+			app.Timer = &TimerInfo{
+				App:   app,
+				Timer: yApp.Timer,
+			}
+		}
 		if len(y.Plugs) > 0 || len(yApp.PlugNames) > 0 {
 			app.Plugs = make(map[string]*PlugInfo)
 		}
@@ -420,3 +490,20 @@ func convertToSlotOrPlugData(plugOrSlot, name string, data interface{}) (iface,
 		return "", "", nil, err
 	}
 }
+
+func setLayoutFromSnapYaml(y snapYaml, snap *Info) {
+	if len(y.Layout) == 0 {
+		return
+	}
+	snap.Layout = make(map[string]*Layout, len(y.Layout))
+	for path, l := range y.Layout {
+		snap.Layout[path] = &Layout{
+			Snap:     snap,
+			Path:     path,
+			Bind:     l.Bind,
+			BindFile: l.BindFile,
+			Symlink:  l.Symlink,
+			Type:     l.Type,
+		}
+	}
+}
@@ -1132,6 +1132,65 @@ version: 1.0
 	c.Assert(info.Epoch, Equals, "0")
 }
 
+func (s *YamlSuite) TestSnapYamlBase(c *C) {
+	y := []byte(`name: binary
+version: 1.0
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+	c.Check(info.Base, Equals, "")
+
+	y = []byte(`name: binary
+version: 1.0
+base: xenial
+`)
+	info, err = snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+	c.Check(info.Base, Equals, "xenial")
+}
+
+func (s *YamlSuite) TestSnapYamlSocketsAndTimer(c *C) {
+	y := []byte(`name: wat
+version: 42
+apps:
+ svc:
+   command: svc
+   daemon: simple
+   sockets:
+     sock1:
+       listen-stream: $SNAP_DATA/sock1.socket
+       socket-mode: "0666"
+     sock2:
+       listen-stream: 8080
+   timer: daily
+ app:
+   command: app
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	app := info.Apps["svc"]
+	c.Assert(app.Sockets, HasLen, 2)
+	c.Check(app.Sockets["sock1"], DeepEquals, &snap.SocketInfo{
+		App:          app,
+		Name:         "sock1",
+		ListenStream: "$SNAP_DATA/sock1.socket",
+		SocketMode:   "0666",
+	})
+	c.Check(app.Sockets["sock2"], DeepEquals, &snap.SocketInfo{
+		App:          app,
+		Name:         "sock2",
+		ListenStream: "8080",
+	})
+	c.Check(app.Timer, DeepEquals, &snap.TimerInfo{App: app, Timer: "daily"})
+	c.Check(app.IsActivated(), Equals, true)
+
+	app = info.Apps["app"]
+	c.Check(app.Sockets, IsNil)
+	c.Check(app.Timer, IsNil)
+	c.Check(app.IsActivated(), Equals, false)
+}
+
 func (s *YamlSuite) TestSnapYamlConfinementDefault(c *C) {
 	y := []byte(`name: binary
 version: 1.0
@@ -1293,3 +1352,60 @@ apps:
 		"k2": "v2",
 	})
 }
+
+func (s *YamlSuite) TestSnapYamlAliases(c *C) {
+	y := []byte(`
+name: foo
+version: 1.0
+apps:
+ foo:
+  aliases: [foo]
+ bar:
+  aliases: [bar, bar1]
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+
+	c.Check(info.Aliases, HasLen, 3)
+	c.Check(info.Aliases["foo"], Equals, info.Apps["foo"])
+	c.Check(info.Aliases["bar"], Equals, info.Apps["bar"])
+	c.Check(info.Aliases["bar1"], Equals, info.Apps["bar"])
+}
+
+func (s *YamlSuite) TestSnapYamlAliasesConflict(c *C) {
+	y := []byte(`
+name: foo
+version: 1.0
+apps:
+ foo:
+  aliases: [bar]
+ bar:
+  aliases: [bar]
+`)
+	_, err := snap.InfoFromSnapYaml(y)
+	c.Check(err, ErrorMatches, `cannot set "bar" as alias for both ("foo" and "bar"|"bar" and "foo")`)
+}
+
+func (s *YamlSuite) TestSnapYamlLayout(c *C) {
+	y := []byte(`
+name: foo
+version: 1.0
+layout:
+  /usr/share/foo:
+    bind: $SNAP/usr/share/foo
+  /etc/foo.conf:
+    bind-file: $SNAP_DATA/foo.conf
+  /var/lib/foo:
+    symlink: $SNAP_COMMON/lib
+  /var/cache/foo:
+    type: tmpfs
+`)
+	info, err := snap.InfoFromSnapYaml(y)
+	c.Assert(err, IsNil)
+	c.Check(info.Layout, DeepEquals, map[string]*snap.Layout{
+		"/usr/share/foo": {Snap: info, Path: "/usr/share/foo", Bind: "$SNAP/usr/share/foo"},
+		"/etc/foo.conf":  {Snap: info, Path: "/etc/foo.conf", BindFile: "$SNAP_DATA/foo.conf"},
+		"/var/lib/foo":   {Snap: info, Path: "/var/lib/foo", Symlink: "$SNAP_COMMON/lib"},
+		"/var/cache/foo": {Snap: info, Path: "/var/cache/foo", Type: "tmpfs"},
+	})
+}
@@ -91,6 +91,23 @@ apps:
 
 	c.Check(info.Apps["bar"].WrapperPath(), Equals, filepath.Join(dirs.SnapBinariesDir, "foo.bar"))
 	c.Check(info.Apps["foo"].WrapperPath(), Equals, filepath.Join(dirs.SnapBinariesDir, "foo"))
+
+	info.InstanceKey = "bar"
+	c.Check(info.Apps["bar"].WrapperPath(), Equals, filepath.Join(dirs.SnapBinariesDir, "foo_bar.bar"))
+	c.Check(info.Apps["foo"].WrapperPath(), Equals, filepath.Join(dirs.SnapBinariesDir, "foo_bar"))
+}
+
+func (s *infoSuite) TestAppInfoIsService(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`name: foo
+apps:
+   foo:
+   bar:
+     daemon: simple
+`))
+	c.Assert(err, IsNil)
+
+	c.Check(info.Apps["foo"].IsService(), Equals, false)
+	c.Check(info.Apps["bar"].IsService(), Equals, true)
 }
 
 func (s *infoSuite) TestAppInfoLauncherCommand(c *C) {
@@ -107,6 +124,85 @@ apps:
 	info.Revision = snap.R(42)
 	c.Check(info.Apps["bar"].LauncherCommand(), Equals, "/usr/bin/snap run foo.bar")
 	c.Check(info.Apps["foo"].LauncherCommand(), Equals, "/usr/bin/snap run foo")
+
+	info.InstanceKey = "test"
+	c.Check(info.Apps["bar"].LauncherCommand(), Equals, "/usr/bin/snap run foo_test.bar")
+	c.Check(info.Apps["foo"].LauncherCommand(), Equals, "/usr/bin/snap run foo_test")
+}
+
+func (s *infoSuite) TestAppInfoLauncherReloadCommand(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`name: foo
+apps:
+   foo:
+     command: foo-bin
+     daemon: simple
+     reload-command: foo-bin --reload
+`))
+	c.Assert(err, IsNil)
+	c.Check(info.Apps["foo"].LauncherReloadCommand(), Equals, "/usr/bin/snap run --command=reload foo")
+}
+
+func (s *infoSuite) TestSortServices(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`name: foo
+apps:
+   db:
+     daemon: simple
+   web:
+     daemon: simple
+     after: [db, cache]
+   cache:
+     daemon: simple
+     before: [web]
+     after: [db]
+   app:
+     daemon: simple
+`))
+	c.Assert(err, IsNil)
+
+	apps := make([]*snap.AppInfo, 0, len(info.Apps))
+	for _, app := range info.Apps {
+		apps = append(apps, app)
+	}
+	sorted, err := snap.SortServices(apps)
+	c.Assert(err, IsNil)
+	names := make([]string, len(sorted))
+	for i, app := range sorted {
+		names[i] = app.Name
+	}
+	c.Check(names, DeepEquals, []string{"app", "db", "cache", "web"})
+
+	// orderings referring to services not given are ignored
+	sorted, err = snap.SortServices([]*snap.AppInfo{info.Apps["web"], info.Apps["app"]})
+	c.Assert(err, IsNil)
+	c.Check(sorted, DeepEquals, []*snap.AppInfo{info.Apps["app"], info.Apps["web"]})
+
+	info.Apps["db"].After = []string{"web"}
+	_, err = snap.SortServices(apps)
+	c.Check(err, ErrorMatches, `applications are part of a before/after cycle: cache, db, web`)
+}
+
+func (s *infoSuite) TestInstanceNames(c *C) {
+	c.Check(snap.InstanceName("foo", ""), Equals, "foo")
+	c.Check(snap.InstanceName("foo", "bar"), Equals, "foo_bar")
+
+	for _, t := range []struct {
+		in                    string
+		snapName, instanceKey string
+	}{
+		{"foo", "foo", ""},
+		{"foo_bar", "foo", "bar"},
+		{"foo_", "foo", ""},
+	} {
+		snapName, instanceKey := snap.SplitInstanceName(t.in)
+		c.Check(snapName, Equals, t.snapName)
+		c.Check(instanceKey, Equals, t.instanceKey)
+	}
+
+	info := &snap.Info{SuggestedName: "foo", SideInfo: snap.SideInfo{RealName: "foo"}, InstanceKey: "bar"}
+	c.Check(info.Name(), Equals, "foo_bar")
+	c.Check(info.SnapName(), Equals, "foo")
+	appInfo := &snap.AppInfo{Snap: info, Name: "app"}
+	c.Check(appInfo.SecurityTag(), Equals, "snap.foo_bar.app")
 }
 
 const sampleYaml = `
@@ -316,6 +412,9 @@ func (s *infoSuite) TestSplitSnapApp(c *C) {
 		{"foo.bar.baz", []string{"foo", "bar.baz"}},
 		// special case, snapName == appName
 		{"foo", []string{"foo", "foo"}},
+		// instances
+		{"foo_bar.baz", []string{"foo_bar", "baz"}},
+		{"foo_bar", []string{"foo_bar", "foo"}},
 	} {
 		snap, app := snap.SplitSnapApp(t.in)
 		c.Check([]string{snap, app}, DeepEquals, t.out)
@@ -479,3 +578,26 @@ func (s *infoSuite) TestDirAndFileMethods(c *C) {
 	c.Check(info.DataHomeDir(), Equals, "/home/*/snap/name/1")
 	c.Check(info.CommonDataHomeDir(), Equals, "/home/*/snap/name/common")
 }
+
+func (s *infoSuite) TestDirAndFileMethodsInstance(c *C) {
+	dirs.SetRootDir("")
+	info := &snap.Info{SuggestedName: "name", SideInfo: snap.SideInfo{Revision: snap.R(1)}, InstanceKey: "key"}
+	c.Check(info.MountDir(), Equals, fmt.Sprintf("%s/name_key/1", dirs.SnapMountDir))
+	c.Check(info.MountFile(), Equals, "/var/lib/snapd/snaps/name_key_1.snap")
+	c.Check(info.DataDir(), Equals, "/var/snap/name_key/1")
+	c.Check(info.UserDataDir("/home/bob"), Equals, "/home/bob/snap/name_key/1")
+	c.Check(info.CommonDataDir(), Equals, "/var/snap/name_key/common")
+
+	c.Check(snap.MinimalPlaceInfo("name_key", snap.R(1)).MountDir(), Equals, info.MountDir())
+}
+
+func (s *infoSuite) TestExpandSnapVariables(c *C) {
+	dirs.SetRootDir("")
+	info, err := snap.InfoFromSnapYaml([]byte(`name: foo`))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(42)
+	c.Check(info.ExpandSnapVariables("$SNAP/stuff"), Equals, "/snap/foo/42/stuff")
+	c.Check(info.ExpandSnapVariables("$SNAP_DATA/stuff"), Equals, "/var/snap/foo/42/stuff")
+	c.Check(info.ExpandSnapVariables("$SNAP_COMMON/stuff"), Equals, "/var/snap/foo/common/stuff")
+	c.Check(info.ExpandSnapVariables("$GARBAGE/rocks"), Equals, "${GARBAGE}/rocks")
+}
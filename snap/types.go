@@ -22,9 +22,10 @@ package snap
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
-// Type represents the kind of snap (app, core, gadget, os, kernel)
+// Type represents the kind of snap (app, core, gadget, os, kernel, base)
 type Type string
 
 // The various types of snap parts we support
@@ -33,6 +34,7 @@ const (
 	TypeGadget Type = "gadget"
 	TypeOS     Type = "os"
 	TypeKernel Type = "kernel"
+	TypeBase   Type = "base"
 )
 
 // UnmarshalJSON sets *m to a copy of data.
@@ -65,7 +67,7 @@ func (m *Type) fromString(str string) error {
 		t = TypeApp
 	}
 
-	if t != TypeApp && t != TypeGadget && t != TypeOS && t != TypeKernel {
+	if t != TypeApp && t != TypeGadget && t != TypeOS && t != TypeKernel && t != TypeBase {
 		return fmt.Errorf("invalid snap type: %q", str)
 	}
 
@@ -75,12 +77,13 @@ func (m *Type) fromString(str string) error {
 }
 
 // ConfinementType represents the kind of confinement supported by the snap
-// (devmode only, or strict confinement)
+// (devmode only, classic, or strict confinement)
 type ConfinementType string
 
 // The various confinement types we support
 const (
 	DevmodeConfinement ConfinementType = "devmode"
+	ClassicConfinement ConfinementType = "classic"
 	StrictConfinement  ConfinementType = "strict"
 )
 
@@ -106,7 +109,7 @@ func (confinementType *ConfinementType) UnmarshalYAML(unmarshal func(interface{}
 
 func (confinementType *ConfinementType) fromString(str string) error {
 	c := ConfinementType(str)
-	if c != DevmodeConfinement && c != StrictConfinement {
+	if c != DevmodeConfinement && c != ClassicConfinement && c != StrictConfinement {
 		return fmt.Errorf("invalid confinement type: %q", str)
 	}
 
@@ -114,3 +117,41 @@ func (confinementType *ConfinementType) fromString(str string) error {
 
 	return nil
 }
+
+// StopModeType is the type for the "stop-mode:" of a snap app
+type StopModeType string
+
+// KillAll returns whether the stop mode signals all processes of the
+// service rather than just its main process.
+func (st StopModeType) KillAll() bool {
+	return string(st) == "" || strings.HasSuffix(string(st), "-all")
+}
+
+// KillSignal returns the signal used to stop the service, or the empty
+// string for the systemd default.
+func (st StopModeType) KillSignal() string {
+	if st == "" {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimSuffix(string(st), "-all"))
+}
+
+// Validate checks that the stop mode is one of the supported ones.
+func (st StopModeType) Validate() error {
+	switch st {
+	case "", "sigterm", "sigterm-all", "sighup", "sighup-all", "sigusr1", "sigusr1-all", "sigusr2", "sigusr2-all":
+		// valid
+		return nil
+	}
+	return fmt.Errorf(`"stop-mode" field contains invalid value %q`, st)
+}
+
+// ServiceStopReason is why the services of a snap get stopped.
+type ServiceStopReason string
+
+// The various reasons for stopping services
+const (
+	StopReasonRefresh ServiceStopReason = "refresh"
+	StopReasonRemove  ServiceStopReason = "remove"
+	StopReasonDisable ServiceStopReason = "disable"
+)
@@ -53,6 +53,10 @@ func (s *typeSuite) TestJsonMarshalTypes(c *C) {
 	out, err = json.Marshal(TypeKernel)
 	c.Assert(err, IsNil)
 	c.Check(string(out), Equals, "\"kernel\"")
+
+	out, err = json.Marshal(TypeBase)
+	c.Assert(err, IsNil)
+	c.Check(string(out), Equals, "\"base\"")
 }
 
 func (s *typeSuite) TestJsonUnmarshalTypes(c *C) {
@@ -77,6 +81,10 @@ func (s *typeSuite) TestJsonUnmarshalTypes(c *C) {
 	err = json.Unmarshal([]byte("\"kernel\""), &st)
 	c.Assert(err, IsNil)
 	c.Check(st, Equals, TypeKernel)
+
+	err = json.Unmarshal([]byte("\"base\""), &st)
+	c.Assert(err, IsNil)
+	c.Check(st, Equals, TypeBase)
 }
 
 func (s *typeSuite) TestJsonUnmarshalInvalidTypes(c *C) {
@@ -104,6 +112,10 @@ func (s *typeSuite) TestYamlMarshalTypes(c *C) {
 	out, err = yaml.Marshal(TypeKernel)
 	c.Assert(err, IsNil)
 	c.Check(string(out), Equals, "kernel\n")
+
+	out, err = yaml.Marshal(TypeBase)
+	c.Assert(err, IsNil)
+	c.Check(string(out), Equals, "base\n")
 }
 
 func (s *typeSuite) TestYamlUnmarshalTypes(c *C) {
@@ -128,6 +140,10 @@ func (s *typeSuite) TestYamlUnmarshalTypes(c *C) {
 	err = yaml.Unmarshal([]byte("kernel"), &st)
 	c.Assert(err, IsNil)
 	c.Check(st, Equals, TypeKernel)
+
+	err = yaml.Unmarshal([]byte("base"), &st)
+	c.Assert(err, IsNil)
+	c.Check(st, Equals, TypeBase)
 }
 
 func (s *typeSuite) TestYamlUnmarshalInvalidTypes(c *C) {
@@ -144,6 +160,10 @@ func (s *typeSuite) TestYamlMarshalConfinementTypes(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(string(out), Equals, "devmode\n")
 
+	out, err = yaml.Marshal(ClassicConfinement)
+	c.Assert(err, IsNil)
+	c.Check(string(out), Equals, "classic\n")
+
 	out, err = yaml.Marshal(StrictConfinement)
 	c.Assert(err, IsNil)
 	c.Check(string(out), Equals, "strict\n")
@@ -155,6 +175,10 @@ func (s *typeSuite) TestYamlUnmarshalConfinementTypes(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(confinementType, Equals, DevmodeConfinement)
 
+	err = yaml.Unmarshal([]byte("classic"), &confinementType)
+	c.Assert(err, IsNil)
+	c.Check(confinementType, Equals, ClassicConfinement)
+
 	err = yaml.Unmarshal([]byte("strict"), &confinementType)
 	c.Assert(err, IsNil)
 	c.Check(confinementType, Equals, StrictConfinement)
@@ -176,6 +200,10 @@ func (s *typeSuite) TestJsonMarshalConfinementTypes(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(string(out), Equals, "\"devmode\"")
 
+	out, err = json.Marshal(ClassicConfinement)
+	c.Assert(err, IsNil)
+	c.Check(string(out), Equals, "\"classic\"")
+
 	out, err = json.Marshal(StrictConfinement)
 	c.Assert(err, IsNil)
 	c.Check(string(out), Equals, "\"strict\"")
@@ -187,6 +215,10 @@ func (s *typeSuite) TestJsonUnmarshalConfinementTypes(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(confinementType, Equals, DevmodeConfinement)
 
+	err = json.Unmarshal([]byte("\"classic\""), &confinementType)
+	c.Assert(err, IsNil)
+	c.Check(confinementType, Equals, ClassicConfinement)
+
 	err = json.Unmarshal([]byte("\"strict\""), &confinementType)
 	c.Assert(err, IsNil)
 	c.Check(confinementType, Equals, StrictConfinement)
@@ -202,3 +234,29 @@ func (s *typeSuite) TestJsonUnmarshalInvalidConfinementTypes(c *C) {
 		c.Assert(err, NotNil, Commentf("Expected '%s' to be an invalid confinement type", thisConfinementType))
 	}
 }
+
+func (s *typeSuite) TestStopModeType(c *C) {
+	for _, t := range []struct {
+		stopMode   StopModeType
+		killAll    bool
+		killSignal string
+	}{
+		{"", true, ""},
+		{"sigterm", false, "SIGTERM"},
+		{"sigterm-all", true, "SIGTERM"},
+		{"sighup", false, "SIGHUP"},
+		{"sighup-all", true, "SIGHUP"},
+		{"sigusr1", false, "SIGUSR1"},
+		{"sigusr1-all", true, "SIGUSR1"},
+		{"sigusr2", false, "SIGUSR2"},
+		{"sigusr2-all", true, "SIGUSR2"},
+	} {
+		c.Check(t.stopMode.Validate(), IsNil)
+		c.Check(t.stopMode.KillAll(), Equals, t.killAll, Commentf("%q", t.stopMode))
+		c.Check(t.stopMode.KillSignal(), Equals, t.killSignal, Commentf("%q", t.stopMode))
+	}
+
+	for _, stopMode := range []StopModeType{"sigkill", "sigterm-some", "SIGTERM"} {
+		c.Check(stopMode.Validate(), ErrorMatches, `"stop-mode" field contains invalid value .*`)
+	}
+}
@@ -21,13 +21,19 @@ package snap
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // Regular expression describing correct identifiers.
 var validSnapName = regexp.MustCompile("^(?:[a-z0-9]+-?)*[a-z](?:-?[a-z0-9])*$")
 var validEpoch = regexp.MustCompile("^(?:0|[1-9][0-9]*[*]?)$")
 var validHookName = regexp.MustCompile("^[a-z](?:-?[a-z0-9])*$")
+var validAlias = regexp.MustCompile("^[a-zA-Z0-9][-_.a-zA-Z0-9]*$")
+var validInstanceKey = regexp.MustCompile("^[a-z0-9]{1,10}$")
 
 // ValidateName checks if a string can be used as a snap name.
 func ValidateName(name string) error {
@@ -38,6 +44,22 @@ func ValidateName(name string) error {
 	return nil
 }
 
+// ValidateInstanceName checks if a string can be used as a snap
+// instance name, that is a snap name optionally followed by an
+// underscore and an instance key.
+func ValidateInstanceName(instanceName string) error {
+	snapName, instanceKey := SplitInstanceName(instanceName)
+	if err := ValidateName(snapName); err != nil {
+		return err
+	}
+	if instanceKey != "" || strings.HasSuffix(instanceName, "_") {
+		if !validInstanceKey.MatchString(instanceKey) {
+			return fmt.Errorf("invalid instance key: %q", instanceKey)
+		}
+	}
+	return nil
+}
+
 // ValidateEpoch checks if a string can be used as a snap epoch.
 func ValidateEpoch(epoch string) error {
 	valid := validEpoch.MatchString(epoch)
@@ -56,9 +78,138 @@ func ValidateHook(hook *HookInfo) error {
 	return nil
 }
 
+// ValidateAlias checks if a string can be used as an alias name.
+func ValidateAlias(alias string) error {
+	valid := validAlias.MatchString(alias)
+	if !valid {
+		return fmt.Errorf("invalid alias name: %q", alias)
+	}
+	return nil
+}
+
+// layoutRejectionList holds the paths that cannot be remapped by
+// layouts, nor anything under them, as they are either managed by
+// snapd and snap-confine or are too fundamental to the system.
+var layoutRejectionList = []string{
+	"/boot",
+	"/dev",
+	"/home",
+	"/lib/firmware",
+	"/lib/modules",
+	"/lost+found",
+	"/media",
+	"/proc",
+	"/run",
+	"/snap",
+	"/sys",
+	"/tmp",
+	"/usr/lib/snapd",
+	"/var/lib/snapd",
+	"/var/run",
+	"/var/snap",
+}
+
+// isPathUnder returns whether path is dir or is inside it.
+func isPathUnder(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+func validateLayoutSource(what, source string) error {
+	if filepath.Clean(source) != source {
+		return fmt.Errorf("layout %s %q must be a clean path", what, source)
+	}
+	for _, part := range strings.Split(source, "/") {
+		if part == ".." {
+			return fmt.Errorf("layout %s %q must be a clean path", what, source)
+		}
+	}
+	for _, v := range []string{"$SNAP", "$SNAP_DATA", "$SNAP_COMMON"} {
+		if isPathUnder(source, v) {
+			return nil
+		}
+	}
+	return fmt.Errorf("layout %s %q must start with $SNAP, $SNAP_DATA or $SNAP_COMMON", what, source)
+}
+
+// ValidateLayout checks that the given layout entry can be used.
+func ValidateLayout(layout *Layout) error {
+	path := layout.Path
+	if path == "" {
+		return fmt.Errorf("layout cannot use an empty path")
+	}
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return fmt.Errorf("layout %q must be an absolute and clean path", path)
+	}
+	if path == "/" {
+		return fmt.Errorf("layout %q cannot remap the root directory", path)
+	}
+	for _, reserved := range layoutRejectionList {
+		if isPathUnder(path, reserved) {
+			return fmt.Errorf("layout %q in an off-limits area", path)
+		}
+	}
+
+	var kinds []string
+	if layout.Bind != "" {
+		kinds = append(kinds, "bind")
+	}
+	if layout.BindFile != "" {
+		kinds = append(kinds, "bind-file")
+	}
+	if layout.Symlink != "" {
+		kinds = append(kinds, "symlink")
+	}
+	if layout.Type != "" {
+		kinds = append(kinds, "type")
+	}
+	switch len(kinds) {
+	case 0:
+		return fmt.Errorf("layout %q must define a bind mount, a bind-file mount, a symlink or a filesystem type", path)
+	case 1:
+	default:
+		return fmt.Errorf("layout %q must define exactly one of bind, bind-file, symlink or type, not %s", path, strings.Join(kinds, ", "))
+	}
+
+	switch {
+	case layout.Bind != "":
+		return validateLayoutSource("source", layout.Bind)
+	case layout.BindFile != "":
+		return validateLayoutSource("source", layout.BindFile)
+	case layout.Symlink != "":
+		return validateLayoutSource("symlink target", layout.Symlink)
+	case layout.Type != "tmpfs":
+		return fmt.Errorf("layout %q uses invalid filesystem type %q", path, layout.Type)
+	}
+	return nil
+}
+
+func validateLayoutAll(info *Info) error {
+	paths := make([]string, 0, len(info.Layout))
+	for path, layout := range info.Layout {
+		if layout.Path != path {
+			return fmt.Errorf("internal error: layout %q mismatches its path %q", path, layout.Path)
+		}
+		if err := ValidateLayout(layout); err != nil {
+			return err
+		}
+		paths = append(paths, path)
+	}
+
+	// layouts cannot be nested, sorting makes the error deterministic
+	sort.Strings(paths)
+	for i, path := range paths {
+		for _, parent := range paths[:i] {
+			if isPathUnder(path, parent) {
+				return fmt.Errorf("layout %q cannot be nested under layout %q", path, parent)
+			}
+		}
+	}
+	return nil
+}
+
 // Validate verifies the content in the info.
 func Validate(info *Info) error {
-	name := info.Name()
+	name := info.SnapName()
 	if name == "" {
 		return fmt.Errorf("snap name cannot be empty")
 	}
@@ -66,6 +217,11 @@ func Validate(info *Info) error {
 	if err != nil {
 		return err
 	}
+	if info.InstanceKey != "" {
+		if err := ValidateInstanceName(info.Name()); err != nil {
+			return err
+		}
+	}
 
 	epoch := info.Epoch
 	if epoch == "" {
@@ -76,6 +232,10 @@ func Validate(info *Info) error {
 		return err
 	}
 
+	if err := validateBase(info); err != nil {
+		return err
+	}
+
 	// validate app entries
 	for _, app := range info.Apps {
 		err := ValidateApp(app)
@@ -83,6 +243,17 @@ func Validate(info *Info) error {
 			return err
 		}
 	}
+	if err := validateAppOrderCycles(info.Apps); err != nil {
+		return err
+	}
+
+	// validate alias entries
+	for alias := range info.Aliases {
+		err := ValidateAlias(alias)
+		if err != nil {
+			return err
+		}
+	}
 
 	// validate hook entries
 	for _, hook := range info.Hooks {
@@ -96,6 +267,24 @@ func Validate(info *Info) error {
 	if err := plugsSlotsUniqueNames(info); err != nil {
 		return err
 	}
+
+	return validateLayoutAll(info)
+}
+
+// validateBase checks the base the snap asks to run on, if any.
+func validateBase(info *Info) error {
+	if info.Base == "" {
+		return nil
+	}
+	if info.Type == TypeOS || info.Type == TypeBase || info.Type == TypeKernel {
+		return fmt.Errorf("cannot have a base in a snap of type %q", info.Type)
+	}
+	if info.Base == info.SnapName() {
+		return fmt.Errorf("cannot use snap %q as its own base", info.Base)
+	}
+	if err := ValidateName(info.Base); err != nil {
+		return fmt.Errorf("invalid base: %v", err)
+	}
 	return nil
 }
 
@@ -140,6 +329,7 @@ func ValidateApp(app *AppInfo) error {
 	checks := map[string]string{
 		"command":           app.Command,
 		"stop-command":      app.StopCommand,
+		"reload-command":    app.ReloadCommand,
 		"post-stop-command": app.PostStopCommand,
 		"socket-mode":       app.SocketMode,
 		"listen-stream":     app.ListenStream,
@@ -151,5 +341,163 @@ func ValidateApp(app *AppInfo) error {
 			return err
 		}
 	}
+
+	if err := validateAppLifecycle(app); err != nil {
+		return err
+	}
+
+	for _, socket := range app.Sockets {
+		if err := validateSocket(socket); err != nil {
+			return err
+		}
+	}
+	if app.Timer != nil {
+		if err := validateTimer(app.Timer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAppLifecycle checks the fields controlling how a service is
+// started, stopped and ordered.
+func validateAppLifecycle(app *AppInfo) error {
+	if app.Daemon == "" {
+		for field, set := range map[string]bool{
+			"reload-command": app.ReloadCommand != "",
+			"stop-mode":      app.StopMode != "",
+			"refresh-mode":   app.RefreshMode != "",
+			"install-mode":   app.InstallMode != "",
+			"after":          len(app.After) != 0,
+			"before":         len(app.Before) != 0,
+		} {
+			if set {
+				return fmt.Errorf("%q cannot be used for %q, only for services", field, app.Name)
+			}
+		}
+		return nil
+	}
+
+	if err := app.StopMode.Validate(); err != nil {
+		return err
+	}
+	switch app.RefreshMode {
+	case "", "endure", "restart":
+		// valid
+	default:
+		return fmt.Errorf(`"refresh-mode" field contains invalid value %q`, app.RefreshMode)
+	}
+	switch app.InstallMode {
+	case "", "enable", "disable":
+		// valid
+	default:
+		return fmt.Errorf(`"install-mode" field contains invalid value %q`, app.InstallMode)
+	}
+
+	for field, others := range map[string][]string{"after": app.After, "before": app.Before} {
+		for _, other := range others {
+			if other == app.Name {
+				return fmt.Errorf("service %q cannot be ordered %s itself", app.Name, field)
+			}
+			otherApp, ok := app.Snap.Apps[other]
+			if !ok {
+				return fmt.Errorf("service %q refers to missing application %q in %s", app.Name, other, field)
+			}
+			if otherApp.Daemon == "" {
+				return fmt.Errorf("service %q refers to application %q in %s which is not a service", app.Name, other, field)
+			}
+		}
+	}
+	return nil
+}
+
+// validateAppOrderCycles checks that the after/before ordering of the
+// services of a snap has no cycles.
+func validateAppOrderCycles(apps map[string]*AppInfo) error {
+	services := make([]*AppInfo, 0, len(apps))
+	for _, app := range apps {
+		if app.IsService() {
+			services = append(services, app)
+		}
+	}
+	_, err := SortServices(services)
+	return err
+}
+
+var validSocketName = regexp.MustCompile("^[a-z](?:-?[a-z0-9])*$")
+
+func validateSocket(socket *SocketInfo) error {
+	app := socket.App
+	if app.Daemon == "" {
+		return fmt.Errorf("cannot have socket %q in app %q: only services can be socket activated", socket.Name, app.Name)
+	}
+	if !validSocketName.MatchString(socket.Name) {
+		return fmt.Errorf("invalid socket name %q in app %q", socket.Name, app.Name)
+	}
+	if err := validateListenStream(socket.ListenStream); err != nil {
+		return fmt.Errorf("invalid listen-stream of socket %q in app %q: %v", socket.Name, app.Name, err)
+	}
+	if socket.SocketMode != "" {
+		mode, err := strconv.ParseUint(socket.SocketMode, 8, 32)
+		if err != nil || mode > 0777 {
+			return fmt.Errorf("invalid socket-mode %q of socket %q in app %q", socket.SocketMode, socket.Name, app.Name)
+		}
+	}
+	return nil
+}
+
+// validateListenStream checks that the listen stream of a socket is
+// either a path under $SNAP_DATA or $SNAP_COMMON, an abstract socket
+// or a [host:]port address.
+func validateListenStream(listenStream string) error {
+	if listenStream == "" {
+		return fmt.Errorf("cannot be empty")
+	}
+
+	for _, prefix := range []string{"$SNAP_DATA/", "$SNAP_COMMON/"} {
+		if strings.HasPrefix(listenStream, prefix) {
+			path := listenStream[len(prefix):]
+			if path == "" || filepath.Clean(path) != path || strings.HasPrefix(path, "../") || !appContentWhitelist.MatchString(path) {
+				return fmt.Errorf("invalid path %q", listenStream)
+			}
+			return nil
+		}
+	}
+	if strings.HasPrefix(listenStream, "/") {
+		return fmt.Errorf("path %q must start with $SNAP_DATA or $SNAP_COMMON", listenStream)
+	}
+
+	if strings.HasPrefix(listenStream, "@") {
+		if len(listenStream) == 1 || !appContentWhitelist.MatchString(listenStream[1:]) {
+			return fmt.Errorf("invalid abstract socket %q", listenStream)
+		}
+		return nil
+	}
+
+	port := listenStream
+	if i := strings.LastIndex(listenStream, ":"); i >= 0 {
+		host := listenStream[:i]
+		if host == "" || !appContentWhitelist.MatchString(host) {
+			return fmt.Errorf("invalid address %q", listenStream)
+		}
+		port = listenStream[i+1:]
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port in %q", listenStream)
+	}
+	return nil
+}
+
+var validTimer = regexp.MustCompile(`^[A-Za-z0-9 ,:.*/~+-]+$`)
+
+func validateTimer(timer *TimerInfo) error {
+	app := timer.App
+	if app.Daemon == "" {
+		return fmt.Errorf("cannot have a timer in app %q: only services can be timer activated", app.Name)
+	}
+	if !validTimer.MatchString(timer.Timer) {
+		return fmt.Errorf("invalid timer %q in app %q", timer.Timer, app.Name)
+	}
 	return nil
 }
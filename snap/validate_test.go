@@ -20,6 +20,7 @@
 package snap_test
 
 import (
+	"fmt"
 	"regexp"
 
 	. "gopkg.in/check.v1"
@@ -64,6 +65,18 @@ func (s *ValidateSuite) TestValidateName(c *C) {
 	}
 }
 
+func (s *ValidateSuite) TestValidateInstanceName(c *C) {
+	for _, name := range []string{"a", "a-b", "a_b", "a_0", "a_abcdefghij"} {
+		c.Check(ValidateInstanceName(name), IsNil, Commentf(name))
+	}
+	for _, name := range []string{"", "a-", "_a", "0_a"} {
+		c.Check(ValidateInstanceName(name), ErrorMatches, `invalid snap name: ".*"`, Commentf(name))
+	}
+	for _, name := range []string{"a_", "a_B", "a_b-c", "a_b_c", "a_abcdefghijk"} {
+		c.Check(ValidateInstanceName(name), ErrorMatches, `invalid instance key: ".*"`, Commentf(name))
+	}
+}
+
 func (s *ValidateSuite) TestValidateEpoch(c *C) {
 	validEpochs := []string{
 		"0", "1*", "1", "400*", "1234",
@@ -111,6 +124,35 @@ func (s *ValidateSuite) TestValidateHook(c *C) {
 	}
 }
 
+func (s *ValidateSuite) TestValidateAlias(c *C) {
+	validAliases := []string{
+		"a", "aa", "aaa", "aaaa", "Aa", "aA", "1a", "a1", "1-a", "a-1",
+		"a-a", "a_a", "a.a", "a..a", "a--a", "a_-.b", "0",
+	}
+	for _, alias := range validAliases {
+		c.Check(ValidateAlias(alias), IsNil)
+	}
+	invalidAliases := []string{
+		"", "-", "_", ".", "-a", ".a", "a a", "a/a", "日本語",
+	}
+	for _, alias := range invalidAliases {
+		c.Check(ValidateAlias(alias), ErrorMatches, `invalid alias name: ".*"`)
+	}
+}
+
+func (s *ValidateSuite) TestValidateAliasInInfo(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+apps:
+  foo:
+    aliases: [".foo"]
+`))
+	c.Assert(err, IsNil)
+
+	err = Validate(info)
+	c.Check(err, ErrorMatches, `invalid alias name: ".foo"`)
+}
+
 // ValidateApp
 
 func (s *ValidateSuite) TestValidateAppName(c *C) {
@@ -254,3 +296,238 @@ slots:
 	err = Validate(info)
 	c.Check(err, ErrorMatches, `cannot have plug and slot with the same name: "foo"`)
 }
+
+func (s *ValidateSuite) TestValidateBase(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+base: bar
+`))
+	c.Assert(err, IsNil)
+	c.Check(Validate(info), IsNil)
+
+	info.Base = "foo"
+	c.Check(Validate(info), ErrorMatches, `cannot use snap "foo" as its own base`)
+
+	info.Base = "b@r"
+	c.Check(Validate(info), ErrorMatches, `invalid base: invalid snap name: "b@r"`)
+
+	info.Base = "bar"
+	info.Type = TypeBase
+	c.Check(Validate(info), ErrorMatches, `cannot have a base in a snap of type "base"`)
+}
+
+func (s *ValidateSuite) TestValidateAppSockets(c *C) {
+	app := &AppInfo{Name: "foo", Daemon: "simple"}
+	socket := &SocketInfo{App: app, Name: "sock"}
+	app.Sockets = map[string]*SocketInfo{"sock": socket}
+
+	for _, listenStream := range []string{
+		"$SNAP_DATA/foo.socket",
+		"$SNAP_COMMON/dir/foo.socket",
+		"@snap.foo",
+		"8080",
+		"127.0.0.1:8080",
+		"localhost:65535",
+	} {
+		socket.ListenStream = listenStream
+		c.Check(ValidateApp(app), IsNil, Commentf(listenStream))
+	}
+
+	for _, t := range []struct {
+		listenStream, err string
+	}{
+		{"", `cannot be empty`},
+		{"/run/foo.socket", `path "/run/foo.socket" must start with \$SNAP_DATA or \$SNAP_COMMON`},
+		{"$SNAP_DATA/../foo.socket", `invalid path "\$SNAP_DATA/../foo.socket"`},
+		{"$SNAP_COMMON/", `invalid path "\$SNAP_COMMON/"`},
+		{"@", `invalid abstract socket "@"`},
+		{"0", `invalid port in "0"`},
+		{"65536", `invalid port in "65536"`},
+		{":80", `invalid address ":80"`},
+		{"foo", `invalid port in "foo"`},
+	} {
+		socket.ListenStream = t.listenStream
+		c.Check(ValidateApp(app), ErrorMatches, `invalid listen-stream of socket "sock" in app "foo": `+t.err)
+	}
+
+	socket.ListenStream = "8080"
+	socket.SocketMode = "0600"
+	c.Check(ValidateApp(app), IsNil)
+	socket.SocketMode = "0999"
+	c.Check(ValidateApp(app), ErrorMatches, `invalid socket-mode "0999" of socket "sock" in app "foo"`)
+	socket.SocketMode = "01777"
+	c.Check(ValidateApp(app), ErrorMatches, `invalid socket-mode "01777" of socket "sock" in app "foo"`)
+	socket.SocketMode = ""
+
+	socket.Name = "Sock!"
+	c.Check(ValidateApp(app), ErrorMatches, `invalid socket name "Sock!" in app "foo"`)
+	socket.Name = "sock"
+
+	app.Daemon = ""
+	c.Check(ValidateApp(app), ErrorMatches, `cannot have socket "sock" in app "foo": only services can be socket activated`)
+}
+
+func (s *ValidateSuite) TestValidateAppTimer(c *C) {
+	app := &AppInfo{Name: "foo", Daemon: "oneshot"}
+	app.Timer = &TimerInfo{App: app, Timer: "Mon..Fri *-*-* 9:00,18:00"}
+	c.Check(ValidateApp(app), IsNil)
+
+	app.Timer.Timer = "daily\nExecStart=/bin/sh"
+	c.Check(ValidateApp(app), ErrorMatches, `invalid timer "daily\\nExecStart=/bin/sh" in app "foo"`)
+
+	app.Timer.Timer = ""
+	c.Check(ValidateApp(app), ErrorMatches, `invalid timer "" in app "foo"`)
+
+	app.Timer.Timer = "daily"
+	app.Daemon = ""
+	c.Check(ValidateApp(app), ErrorMatches, `cannot have a timer in app "foo": only services can be timer activated`)
+}
+
+func (s *ValidateSuite) TestValidateAppLifecycle(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+apps:
+  svc:
+    daemon: simple
+    reload-command: bin/reload
+    stop-mode: sighup-all
+    refresh-mode: endure
+    install-mode: disable
+    after: [other]
+  other:
+    daemon: simple
+    refresh-mode: restart
+    install-mode: enable
+  app:
+    command: bin/app
+`))
+	c.Assert(err, IsNil)
+	c.Check(Validate(info), IsNil)
+
+	svc := info.Apps["svc"]
+	svc.StopMode = "sigkill"
+	c.Check(ValidateApp(svc), ErrorMatches, `"stop-mode" field contains invalid value "sigkill"`)
+	svc.StopMode = ""
+	svc.RefreshMode = "never"
+	c.Check(ValidateApp(svc), ErrorMatches, `"refresh-mode" field contains invalid value "never"`)
+	svc.RefreshMode = ""
+	svc.InstallMode = "later"
+	c.Check(ValidateApp(svc), ErrorMatches, `"install-mode" field contains invalid value "later"`)
+	svc.InstallMode = ""
+	svc.ReloadCommand = "bin/reload\n"
+	c.Check(ValidateApp(svc), ErrorMatches, `app description field 'reload-command' contains illegal .*`)
+	svc.ReloadCommand = ""
+
+	svc.After = []string{"svc"}
+	c.Check(ValidateApp(svc), ErrorMatches, `service "svc" cannot be ordered after itself`)
+	svc.After = []string{"missing"}
+	c.Check(ValidateApp(svc), ErrorMatches, `service "svc" refers to missing application "missing" in after`)
+	svc.After = nil
+	svc.Before = []string{"app"}
+	c.Check(ValidateApp(svc), ErrorMatches, `service "svc" refers to application "app" in before which is not a service`)
+	svc.Before = nil
+
+	app := info.Apps["app"]
+	for _, t := range []struct {
+		field string
+		set   func()
+	}{
+		{"reload-command", func() { app.ReloadCommand = "bin/reload" }},
+		{"stop-mode", func() { app.StopMode = "sigterm" }},
+		{"refresh-mode", func() { app.RefreshMode = "endure" }},
+		{"install-mode", func() { app.InstallMode = "disable" }},
+		{"after", func() { app.After = []string{"svc"} }},
+		{"before", func() { app.Before = []string{"svc"} }},
+	} {
+		*app = AppInfo{Snap: info, Name: "app", Command: "bin/app"}
+		t.set()
+		c.Check(ValidateApp(app), ErrorMatches, fmt.Sprintf(`%q cannot be used for "app", only for services`, t.field))
+	}
+}
+
+func (s *ValidateSuite) TestValidateAppOrderCycles(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+apps:
+  one:
+    daemon: simple
+    after: [two]
+  two:
+    daemon: simple
+    after: [three]
+  three:
+    daemon: simple
+    before: [one]
+  four:
+    daemon: simple
+`))
+	c.Assert(err, IsNil)
+	c.Check(Validate(info), IsNil)
+
+	info.Apps["three"].After = []string{"one"}
+	c.Check(Validate(info), ErrorMatches, `applications are part of a before/after cycle: one, three, two`)
+}
+
+func (s *ValidateSuite) TestValidateLayout(c *C) {
+	for _, l := range []*Layout{
+		{Path: "/etc/foo", Bind: "$SNAP_DATA/etc/foo"},
+		{Path: "/usr/share/foo", Bind: "$SNAP/usr/share/foo"},
+		{Path: "/etc/foo.conf", BindFile: "$SNAP_COMMON/foo.conf"},
+		{Path: "/usr/share/foo", Bind: "$SNAP/foo..bar"},
+		{Path: "/usr/share/foo", Bind: "$SNAP/..foo"},
+		{Path: "/var/lib/foo", Symlink: "$SNAP_DATA/lib"},
+		{Path: "/var/cache/foo", Type: "tmpfs"},
+	} {
+		c.Check(ValidateLayout(l), IsNil, Commentf(l.Path))
+	}
+
+	for _, t := range []struct {
+		layout *Layout
+		err    string
+	}{
+		{&Layout{Bind: "$SNAP/foo"}, `layout cannot use an empty path`},
+		{&Layout{Path: "etc/foo", Bind: "$SNAP/foo"}, `layout "etc/foo" must be an absolute and clean path`},
+		{&Layout{Path: "/etc/../foo", Bind: "$SNAP/foo"}, `layout "/etc/../foo" must be an absolute and clean path`},
+		{&Layout{Path: "/", Type: "tmpfs"}, `layout "/" cannot remap the root directory`},
+		{&Layout{Path: "/proc/foo", Type: "tmpfs"}, `layout "/proc/foo" in an off-limits area`},
+		{&Layout{Path: "/var/lib/snapd", Type: "tmpfs"}, `layout "/var/lib/snapd" in an off-limits area`},
+		{&Layout{Path: "/usr/lib/snapd/snap-exec", BindFile: "$SNAP/foo"}, `layout "/usr/lib/snapd/snap-exec" in an off-limits area`},
+		{&Layout{Path: "/etc/foo"}, `layout "/etc/foo" must define a bind mount, a bind-file mount, a symlink or a filesystem type`},
+		{&Layout{Path: "/etc/foo", Bind: "$SNAP/foo", Type: "tmpfs"}, `layout "/etc/foo" must define exactly one of bind, bind-file, symlink or type, not bind, type`},
+		{&Layout{Path: "/etc/foo", Bind: "/etc/bar"}, `layout source "/etc/bar" must start with \$SNAP, \$SNAP_DATA or \$SNAP_COMMON`},
+		{&Layout{Path: "/etc/foo", Bind: "$SNAPPY/foo"}, `layout source "\$SNAPPY/foo" must start with \$SNAP, \$SNAP_DATA or \$SNAP_COMMON`},
+		{&Layout{Path: "/etc/foo", BindFile: "$SNAP/../foo"}, `layout source "\$SNAP/../foo" must be a clean path`},
+		{&Layout{Path: "/etc/foo", Bind: "$SNAP/foo/.."}, `layout source "\$SNAP/foo/.." must be a clean path`},
+		{&Layout{Path: "/etc/foo", Symlink: "/etc/bar"}, `layout symlink target "/etc/bar" must start with \$SNAP, \$SNAP_DATA or \$SNAP_COMMON`},
+		{&Layout{Path: "/etc/foo", Symlink: "$SNAP_DATA/../bar"}, `layout symlink target "\$SNAP_DATA/../bar" must be a clean path`},
+		{&Layout{Path: "/etc/foo", Type: "ext4"}, `layout "/etc/foo" uses invalid filesystem type "ext4"`},
+	} {
+		c.Check(ValidateLayout(t.layout), ErrorMatches, t.err)
+	}
+}
+
+func (s *ValidateSuite) TestValidateLayoutNested(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+layout:
+  /usr/share/foo:
+    bind: $SNAP/usr/share/foo
+  /usr/share/foo-bar:
+    bind: $SNAP/usr/share/foo-bar
+`))
+	c.Assert(err, IsNil)
+	c.Check(Validate(info), IsNil)
+
+	info, err = InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+layout:
+  /usr/share/foo:
+    bind: $SNAP/usr/share/foo
+  /usr/share/foo-bar:
+    bind: $SNAP/usr/share/foo-bar
+  /usr/share/foo/bar:
+    type: tmpfs
+`))
+	c.Assert(err, IsNil)
+	c.Check(Validate(info), ErrorMatches, `layout "/usr/share/foo/bar" cannot be nested under layout "/usr/share/foo"`)
+}
@@ -56,6 +56,7 @@ type snapDetails struct {
 	DeveloperID string `json:"developer_id"`
 	Private     bool   `json:"private"`
 	Confinement string `json:"confinement"`
+	Epoch       string `json:"epoch"`
 }
 
 type snapDeltaDetail struct {
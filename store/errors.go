@@ -67,6 +67,18 @@ func (e *ErrDownload) Error() string {
 	return fmt.Sprintf("received an unexpected http response code (%v) when trying to download %s", e.Code, e.URL)
 }
 
+// HashError is returned when the sha3-384 of a downloaded snap does not
+// match the one advertised by the store.
+type HashError struct {
+	name           string
+	sha3_384       string
+	targetSha3_384 string
+}
+
+func (e HashError) Error() string {
+	return fmt.Sprintf("sha3-384 mismatch for %q: got %s but expected %s", e.name, e.sha3_384, e.targetSha3_384)
+}
+
 // ErrInvalidAuthData signals that the authentication data didn't pass validation.
 type ErrInvalidAuthData map[string][]string
 
@@ -20,3 +20,11 @@
 package store
 
 var GetFlags = (*LoggedTransport).getFlags
+
+func MockMaxDownloadRetries(n int) (restore func()) {
+	old := maxDownloadRetries
+	maxDownloadRetries = n
+	return func() {
+		maxDownloadRetries = old
+	}
+}
@@ -22,6 +22,7 @@ package store
 
 import (
 	"bytes"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,14 +30,19 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"path"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/release"
@@ -80,7 +86,10 @@ func infoFromRemote(d snapDetails) *snap.Info {
 	info.Architectures = d.Architectures
 	info.Type = d.Type
 	info.Version = d.Version
-	info.Epoch = "0"
+	info.Epoch = d.Epoch
+	if info.Epoch == "" {
+		info.Epoch = "0"
+	}
 	info.RealName = d.Name
 	info.SnapID = d.SnapID
 	info.Revision = snap.R(d.Revision)
@@ -1059,7 +1068,7 @@ func (s *Store) ListRefresh(installed []*RefreshCandidate, user *auth.UserState)
 		Data:        jsonData,
 	}
 
-	if os.Getenv("SNAPPY_USE_DELTAS") == "1" {
+	if useDeltas() {
 		reqOptions.ExtraHeaders = map[string]string{
 			"X-Ubuntu-Delta-Formats": strings.Join(s.deltaFormats, ","),
 		}
@@ -1112,39 +1121,218 @@ func findRev(needle snap.Revision, haystack []snap.Revision) bool {
 	return false
 }
 
-// Download downloads the snap addressed by download info and returns its
-// filename.
-// The file is saved in temporary storage, and should be removed
-// after use to prevent the disk from running out of space.
-func (s *Store) Download(name string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState) (path string, err error) {
-	w, err := ioutil.TempFile("", name)
+// maxDownloadRetries is the number of times an interrupted download is
+// resumed before giving up.
+var maxDownloadRetries = 5
+
+// Download downloads the snap addressed by download info into targetPath.
+//
+// The snap is first downloaded into a partial file next to targetPath,
+// which is moved into place only once its sha3-384 was verified against
+// the one in the download info. An interrupted download is resumed
+// from the partial file, both within this call and by later calls for
+// the same targetPath.
+//
+// If the download info carries a single delta in a supported format
+// from the currently installed revision, the delta is downloaded and
+// applied instead, falling back to downloading the full snap if any
+// step of that fails.
+func (s *Store) Download(name string, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState) (err error) {
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return err
+	}
+
+	partialPath := targetPath + ".partial"
+	// prefer resuming an interrupted full download over using deltas
+	if useDeltas() && len(downloadInfo.Deltas) == 1 && !osutil.FileExists(partialPath) {
+		err := s.downloadAndApplyDelta(name, targetPath, downloadInfo, pbar, user)
+		if err == nil {
+			return nil
+		}
+		// revert to a full download on any error
+		logger.Noticef("Cannot download or apply delta for %s: %v", name, err)
+	}
+
+	w, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0600)
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer func() {
 		if cerr := w.Close(); cerr != nil && err == nil {
 			err = cerr
 		}
-		if err != nil {
-			os.Remove(w.Name())
-			path = ""
-		}
 	}()
 
+	resume, err := w.Seek(0, os.SEEK_END)
+	if err != nil {
+		return err
+	}
+
 	url := downloadInfo.AnonDownloadURL
 	if url == "" || user != nil {
 		url = downloadInfo.DownloadURL
 	}
 
-	if err := download(name, url, user, s, w, pbar); err != nil {
-		return "", err
+	for retry := 0; ; retry++ {
+		err = download(name, url, user, s, w, resume, pbar)
+		if err == nil {
+			break
+		}
+		if _, ok := err.(*ErrDownload); ok {
+			// the store refused the download, don't resume
+			// from this partial file later on
+			os.Remove(partialPath)
+			return err
+		}
+		if retry >= maxDownloadRetries {
+			return err
+		}
+		logger.Debugf("Download of %s interrupted, resuming: %v", name, err)
+		if resume, err = w.Seek(0, os.SEEK_END); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Sync(); err != nil {
+		return err
+	}
+
+	err = checkSha3_384(name, partialPath, downloadInfo.Sha3_384)
+	if _, ok := err.(HashError); ok && resume > 0 {
+		// the partial file we resumed from might have been
+		// corrupted, so try again from scratch
+		logger.Debugf("Resumed download of %s is corrupted, downloading again: %v", name, err)
+		if err := w.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := w.Seek(0, os.SEEK_SET); err != nil {
+			return err
+		}
+		if err := download(name, url, user, s, w, 0, pbar); err != nil {
+			return err
+		}
+		if err := w.Sync(); err != nil {
+			return err
+		}
+		err = checkSha3_384(name, partialPath, downloadInfo.Sha3_384)
+	}
+	if err != nil {
+		if _, ok := err.(HashError); ok {
+			os.Remove(partialPath)
+		}
+		return err
+	}
+
+	return os.Rename(partialPath, targetPath)
+}
+
+// checkSha3_384 checks that the file at path has the expected sha3-384
+// hex digest, unless that is empty.
+func checkSha3_384(name, path string, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	dgst, _, err := osutil.FileDigest(path, crypto.SHA3_384)
+	if err != nil {
+		return err
+	}
+	sha3_384 := fmt.Sprintf("%x", dgst)
+	if sha3_384 != expected {
+		return HashError{name: name, sha3_384: sha3_384, targetSha3_384: expected}
+	}
+	return nil
+}
+
+// useDeltas returns whether deltas should be requested from the
+// store and applied on download. They are used whenever xdelta3 is
+// available, unless SNAPPY_USE_DELTAS is set to "0".
+func useDeltas() bool {
+	if os.Getenv("SNAPPY_USE_DELTAS") == "0" {
+		return false
+	}
+	_, err := exec.LookPath("xdelta3")
+	return err == nil
+}
+
+// deltaSourcePath returns the path of the installed snap file the
+// given delta applies to.
+func deltaSourcePath(name string, deltaInfo *snap.DeltaInfo) string {
+	return filepath.Join(dirs.SnapBlobDir, fmt.Sprintf("%s_%d.snap", name, deltaInfo.FromRevision))
+}
+
+// downloadAndApplyDelta downloads the delta offered in downloadInfo
+// and applies it to the installed snap file of the delta source
+// revision, reconstructing the snap into targetPath.
+func (s *Store) downloadAndApplyDelta(name string, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState) error {
+	deltaInfo := &downloadInfo.Deltas[0]
+	supported := false
+	for _, format := range s.deltaFormats {
+		if format == deltaInfo.Format {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("unsupported delta format %q", deltaInfo.Format)
+	}
+
+	// don't spend bandwidth on a delta that cannot be applied
+	sourcePath := deltaSourcePath(name, deltaInfo)
+	if !osutil.FileExists(sourcePath) {
+		return fmt.Errorf("snap %q revision %d not found at %s", name, deltaInfo.FromRevision, sourcePath)
+	}
+
+	w, err := ioutil.TempFile(filepath.Dir(targetPath), name+".delta")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		w.Close()
+		os.Remove(w.Name())
+	}()
+
+	url := deltaInfo.AnonDownloadURL
+	if url == "" || user != nil {
+		url = deltaInfo.DownloadURL
+	}
+	if err := download(name, url, user, s, w, 0, pbar); err != nil {
+		return err
+	}
+	if err := w.Sync(); err != nil {
+		return err
 	}
 
-	return w.Name(), w.Sync()
+	return applyDelta(name, w.Name(), deltaInfo, targetPath, downloadInfo.Sha3_384)
 }
 
-// download writes an http.Request showing a progress.Meter
-var download = func(name, downloadURL string, user *auth.UserState, s *Store, w io.Writer, pbar progress.Meter) error {
+// applyDelta reconstructs the target snap from the given delta and the
+// installed snap file of the delta source revision, and moves it to
+// targetPath after verifying that it matches the expected sha3-384.
+func applyDelta(name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
+	sourcePath := deltaSourcePath(name, deltaInfo)
+	partialPath := targetPath + ".partial"
+	cmd := exec.Command("xdelta3", "-d", "-s", sourcePath, deltaPath, partialPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(partialPath)
+		return fmt.Errorf("cannot apply delta: %v", osutil.OutputErr(output, err))
+	}
+
+	// a delta is of no use without a digest to check the result against
+	if targetSha3_384 == "" {
+		os.Remove(partialPath)
+		return fmt.Errorf("cannot check the result of applying the delta: no sha3-384 available")
+	}
+	if err := checkSha3_384(name, partialPath, targetSha3_384); err != nil {
+		os.Remove(partialPath)
+		return err
+	}
+
+	return os.Rename(partialPath, targetPath)
+}
+
+// download writes an http.Request showing a progress.Meter, resuming
+// at the given offset of w if that is not zero.
+var download = func(name, downloadURL string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
 	client := &http.Client{}
 
 	storeURL, err := url.Parse(downloadURL)
@@ -1156,18 +1344,59 @@ var download = func(name, downloadURL string, user *auth.UserState, s *Store, w
 		Method: "GET",
 		URL:    storeURL,
 	}
+	if resume > 0 {
+		reqOptions.ExtraHeaders = map[string]string{
+			"Range": fmt.Sprintf("bytes=%d-", resume),
+		}
+	}
 	resp, err := s.doRequest(client, reqOptions, user)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
+	switch {
+	case resp.StatusCode == http.StatusOK && resume > 0:
+		// the server ignored the range, start from scratch
+		logger.Debugf("Cannot resume download of %s, server does not support ranges", name)
+		if err := w.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := w.Seek(0, os.SEEK_SET); err != nil {
+			return err
+		}
+		resume = 0
+	case resp.StatusCode == http.StatusPartialContent && resume > 0:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != resume {
+			// don't append the wrong bytes to the partial
+			// file, start from scratch on the next attempt
+			if err := w.Truncate(0); err != nil {
+				return err
+			}
+			if _, err := w.Seek(0, os.SEEK_SET); err != nil {
+				return err
+			}
+			return fmt.Errorf("cannot resume download of %s at %d: unexpected Content-Range %q", name, resume, resp.Header.Get("Content-Range"))
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && resume > 0:
+		// the partial file is already complete (or bogus),
+		// the hash check done by the caller tells which
+		logger.Debugf("Cannot resume download of %s at %d, nothing left to download", name, resume)
+		return nil
+	case resp.StatusCode == http.StatusOK:
+		// all good
+	default:
 		return &ErrDownload{Code: resp.StatusCode, URL: resp.Request.URL}
 	}
 
 	if pbar != nil {
-		pbar.Start(name, float64(resp.ContentLength))
+		// a total of 0 means unknown
+		var total float64
+		if resp.ContentLength >= 0 {
+			total = float64(resume + resp.ContentLength)
+		}
+		pbar.Start(name, total)
+		pbar.Set(float64(resume))
 		mw := io.MultiWriter(w, pbar)
 		_, err = io.Copy(mw, resp.Body)
 		pbar.Finished()
@@ -1178,6 +1407,23 @@ var download = func(name, downloadURL string, user *auth.UserState, s *Store, w
 	return err
 }
 
+// contentRangeStart returns the first byte position of a
+// "bytes first-last/length" Content-Range header value.
+func contentRangeStart(contentRange string) (int64, bool) {
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return 0, false
+	}
+	idx := strings.IndexRune(contentRange, '-')
+	if idx < 0 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(contentRange[len("bytes "):idx], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
+
 type assertionSvcError struct {
 	Status int    `json:"status"`
 	Type   string `json:"type"`
@@ -29,9 +29,11 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"golang.org/x/crypto/sha3"
 	. "gopkg.in/check.v1"
 	"gopkg.in/macaroon.v1"
 
@@ -44,6 +46,7 @@ import (
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
 )
 
 type remoteRepoTestSuite struct {
@@ -52,7 +55,7 @@ type remoteRepoTestSuite struct {
 	user   *auth.UserState
 	device *auth.DeviceState
 
-	origDownloadFunc func(string, string, *auth.UserState, *Store, io.Writer, progress.Meter) error
+	origDownloadFunc func(string, string, *auth.UserState, *Store, *os.File, int64, progress.Meter) error
 }
 
 func TestStore(t *testing.T) { TestingT(t) }
@@ -249,8 +252,9 @@ func (t *remoteRepoTestSuite) expectedAuthorization(c *C, user *auth.UserState)
 
 func (t *remoteRepoTestSuite) TestDownloadOK(c *C) {
 
-	download = func(name, url string, user *auth.UserState, s *Store, w io.Writer, pbar progress.Meter) error {
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
 		c.Check(url, Equals, "anon-url")
+		c.Check(resume, Equals, int64(0))
 		w.Write([]byte("I was downloaded"))
 		return nil
 	}
@@ -259,18 +263,20 @@ func (t *remoteRepoTestSuite) TestDownloadOK(c *C) {
 	snap.RealName = "foo"
 	snap.AnonDownloadURL = "anon-url"
 	snap.DownloadURL = "AUTH-URL"
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384([]byte("I was downloaded")))
 
-	path, err := t.store.Download("foo", &snap.DownloadInfo, nil, nil)
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
 	c.Assert(err, IsNil)
-	defer os.Remove(path)
 
 	content, err := ioutil.ReadFile(path)
 	c.Assert(err, IsNil)
 	c.Assert(string(content), Equals, "I was downloaded")
+	c.Assert(osutil.FileExists(path+".partial"), Equals, false)
 }
 
 func (t *remoteRepoTestSuite) TestAuthenticatedDownloadDoesNotUseAnonURL(c *C) {
-	download = func(name, url string, user *auth.UserState, s *Store, w io.Writer, pbar progress.Meter) error {
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
 		// check user is pass and auth url is used
 		c.Check(user, Equals, t.user)
 		c.Check(url, Equals, "AUTH-URL")
@@ -284,9 +290,9 @@ func (t *remoteRepoTestSuite) TestAuthenticatedDownloadDoesNotUseAnonURL(c *C) {
 	snap.AnonDownloadURL = "anon-url"
 	snap.DownloadURL = "AUTH-URL"
 
-	path, err := t.store.Download("foo", &snap.DownloadInfo, nil, t.user)
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, t.user)
 	c.Assert(err, IsNil)
-	defer os.Remove(path)
 
 	content, err := ioutil.ReadFile(path)
 	c.Assert(err, IsNil)
@@ -294,10 +300,8 @@ func (t *remoteRepoTestSuite) TestAuthenticatedDownloadDoesNotUseAnonURL(c *C) {
 }
 
 func (t *remoteRepoTestSuite) TestDownloadFails(c *C) {
-	var tmpfile *os.File
-	download = func(name, url string, user *auth.UserState, s *Store, w io.Writer, pbar progress.Meter) error {
-		tmpfile = w.(*os.File)
-		return fmt.Errorf("uh, it failed")
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		return &ErrDownload{Code: 404}
 	}
 
 	snap := &snap.Info{}
@@ -305,34 +309,474 @@ func (t *remoteRepoTestSuite) TestDownloadFails(c *C) {
 	snap.AnonDownloadURL = "anon-url"
 	snap.DownloadURL = "AUTH-URL"
 	// simulate a failed download
-	path, err := t.store.Download("foo", &snap.DownloadInfo, nil, nil)
-	c.Assert(err, ErrorMatches, "uh, it failed")
-	c.Assert(path, Equals, "")
-	// ... and ensure that the tempfile is removed
-	c.Assert(osutil.FileExists(tmpfile.Name()), Equals, false)
-}
-
-func (t *remoteRepoTestSuite) TestDownloadSyncFails(c *C) {
-	var tmpfile *os.File
-	download = func(name, url string, user *auth.UserState, s *Store, w io.Writer, pbar progress.Meter) error {
-		tmpfile = w.(*os.File)
-		w.Write([]byte("sync will fail"))
-		err := tmpfile.Close()
-		c.Assert(err, IsNil)
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, ErrorMatches, "received an unexpected http response code \\(404\\).*")
+	// ... and ensure that nothing was put in place
+	c.Assert(osutil.FileExists(path), Equals, false)
+}
+
+func (t *remoteRepoTestSuite) TestDownloadResumesInterrupted(c *C) {
+	n := 0
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		n++
+		switch n {
+		case 1:
+			c.Check(resume, Equals, int64(0))
+			w.Write([]byte("I was "))
+			return fmt.Errorf("connection reset by peer")
+		case 2:
+			c.Check(resume, Equals, int64(len("I was ")))
+			w.Write([]byte("downloaded"))
+			return nil
+		}
+		c.Fatalf("unexpected download call")
 		return nil
 	}
 
 	snap := &snap.Info{}
 	snap.RealName = "foo"
 	snap.AnonDownloadURL = "anon-url"
-	snap.DownloadURL = "AUTH-URL"
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384([]byte("I was downloaded")))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "I was downloaded")
+}
+
+func (t *remoteRepoTestSuite) TestDownloadGivesUpAfterRetries(c *C) {
+	restore := MockMaxDownloadRetries(2)
+	defer restore()
+
+	n := 0
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		n++
+		w.Write([]byte("x"))
+		return fmt.Errorf("connection reset by peer")
+	}
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, ErrorMatches, "connection reset by peer")
+	c.Check(n, Equals, 3)
+	c.Check(osutil.FileExists(path), Equals, false)
+
+	// the partial download is kept around to be resumed later
+	content, err := ioutil.ReadFile(path + ".partial")
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "xxx")
+}
+
+func (t *remoteRepoTestSuite) TestDownloadResumesFromPartialFile(c *C) {
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(path+".partial", []byte("I was "), 0600), IsNil)
+
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		c.Check(resume, Equals, int64(len("I was ")))
+		w.Write([]byte("downloaded"))
+		return nil
+	}
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384([]byte("I was downloaded")))
+
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "I was downloaded")
+}
+
+func (t *remoteRepoTestSuite) TestDownloadRestartsCorruptedResume(c *C) {
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(path+".partial", []byte("I was cor"), 0600), IsNil)
+
+	var resumes []int64
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		resumes = append(resumes, resume)
+		if resume > 0 {
+			w.Write([]byte("rupted"))
+		} else {
+			w.Write([]byte("I was downloaded"))
+		}
+		return nil
+	}
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384([]byte("I was downloaded")))
+
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(resumes, DeepEquals, []int64{9, 0})
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "I was downloaded")
+}
+
+func (t *remoteRepoTestSuite) TestDownloadHashMismatch(c *C) {
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		w.Write([]byte("I was downloaded"))
+		return nil
+	}
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.Sha3_384 = "1234"
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, FitsTypeOf, HashError{})
+	c.Assert(err, ErrorMatches, `sha3-384 mismatch for "foo": got [[:xdigit:]]{96} but expected 1234`)
+	c.Check(osutil.FileExists(path), Equals, false)
+	c.Check(osutil.FileExists(path+".partial"), Equals, false)
+}
+
+func (t *remoteRepoTestSuite) TestActualDownloadResumesWithRange(c *C) {
+	n := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Header.Get("Range"), Equals, "bytes=6-")
+		w.Header().Set("Content-Range", "bytes 6-15/16")
+		w.WriteHeader(http.StatusPartialContent)
+		io.WriteString(w, "downloaded")
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(path+".partial", []byte("I was "), 0600), IsNil)
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384([]byte("I was downloaded")))
+
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 1)
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "I was downloaded")
+}
+
+func (t *remoteRepoTestSuite) TestActualDownloadRestartsOnWrongRange(c *C) {
+	var ranges []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if r.Header.Get("Range") != "" {
+			w.Header().Set("Content-Range", "bytes 0-15/16")
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		io.WriteString(w, "I was downloaded")
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(path+".partial", []byte("I was "), 0600), IsNil)
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384([]byte("I was downloaded")))
+
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(ranges, DeepEquals, []string{"bytes=6-", ""})
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "I was downloaded")
+}
+
+func (t *remoteRepoTestSuite) TestActualDownloadCompletePartial(c *C) {
+	n := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Header.Get("Range"), Equals, "bytes=16-")
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(path+".partial", []byte("I was downloaded"), 0600), IsNil)
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384([]byte("I was downloaded")))
+
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 1)
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "I was downloaded")
+}
+
+func (t *remoteRepoTestSuite) TestActualDownloadCorruptCompletePartial(c *C) {
+	var ranges []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if r.Header.Get("Range") != "" {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		io.WriteString(w, "I was downloaded")
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(path+".partial", []byte("I was corrupted!"), 0600), IsNil)
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384([]byte("I was downloaded")))
+
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(ranges, DeepEquals, []string{"bytes=16-", ""})
 
-	// simulate a failed sync
-	path, err := t.store.Download("foo", &snap.DownloadInfo, nil, nil)
-	c.Assert(err, ErrorMatches, "fsync:.*")
-	c.Assert(path, Equals, "")
-	// ... and ensure that the tempfile is removed
-	c.Assert(osutil.FileExists(tmpfile.Name()), Equals, false)
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "I was downloaded")
+}
+
+func (t *remoteRepoTestSuite) TestActualDownloadErrorRemovesPartial(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(path+".partial", []byte("I was "), 0600), IsNil)
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, FitsTypeOf, &ErrDownload{})
+	c.Check(osutil.FileExists(path), Equals, false)
+	c.Check(osutil.FileExists(path+".partial"), Equals, false)
+}
+
+func (t *remoteRepoTestSuite) TestActualDownloadUnknownLength(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "I was downloaded")
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+
+	pbar := &totalProgress{}
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := t.store.Download("foo", path, &snap.DownloadInfo, pbar, nil)
+	c.Assert(err, IsNil)
+	c.Check(pbar.total, Equals, float64(0))
+}
+
+type totalProgress struct {
+	progress.NullProgress
+	total float64
+}
+
+func (p *totalProgress) Start(label string, total float64) {
+	p.total = total
+}
+
+func (t *remoteRepoTestSuite) TestActualDownloadRestartsWithoutRangeSupport(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Header.Get("Range"), Equals, "bytes=6-")
+		io.WriteString(w, "I was downloaded")
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(path+".partial", []byte("I was "), 0600), IsNil)
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384([]byte("I was downloaded")))
+
+	err := t.store.Download("foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Equals, "I was downloaded")
+}
+
+func (t *remoteRepoTestSuite) mockDeltaDownload(c *C, reconstructed string) (*snap.DownloadInfo, *testutil.MockCmd) {
+	os.Unsetenv("SNAPPY_USE_DELTAS")
+
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	err := ioutil.WriteFile(filepath.Join(dirs.SnapBlobDir, "foo_24.snap"), []byte("old snap"), 0644)
+	c.Assert(err, IsNil)
+
+	// xdelta3 -d -s <source> <delta> <target>
+	xdelta3 := testutil.MockCommand(c, "xdelta3", fmt.Sprintf(`echo -n %q > "$5"`, reconstructed))
+
+	sha3_384 := fmt.Sprintf("%x", sha3.Sum384([]byte("new snap")))
+
+	return &snap.DownloadInfo{
+		AnonDownloadURL: "anon-url",
+		Sha3_384:        sha3_384,
+		Deltas: []snap.DeltaInfo{{
+			FromRevision:    24,
+			ToRevision:      26,
+			Format:          "xdelta",
+			AnonDownloadURL: "delta-anon-url",
+		}},
+	}, xdelta3
+}
+
+func (t *remoteRepoTestSuite) TestDownloadWithDelta(c *C) {
+	defer os.Setenv("SNAPPY_USE_DELTAS", os.Getenv("SNAPPY_USE_DELTAS"))
+	downloadInfo, xdelta3 := t.mockDeltaDownload(c, "new snap")
+	defer xdelta3.Restore()
+
+	var urls []string
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		urls = append(urls, url)
+		w.Write([]byte("delta"))
+		return nil
+	}
+
+	path := filepath.Join(dirs.SnapBlobDir, "foo_26.snap")
+	err := t.store.Download("foo", path, downloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+
+	c.Check(urls, DeepEquals, []string{"delta-anon-url"})
+	calls := xdelta3.Calls()
+	c.Assert(calls, HasLen, 1)
+	c.Check(calls[0][:4], DeepEquals, []string{"xdelta3", "-d", "-s", filepath.Join(dirs.SnapBlobDir, "foo_24.snap")})
+	c.Check(calls[0][5], Equals, path+".partial")
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "new snap")
+}
+
+func (t *remoteRepoTestSuite) TestDownloadWithDeltaFallsBackOnMismatch(c *C) {
+	defer os.Setenv("SNAPPY_USE_DELTAS", os.Getenv("SNAPPY_USE_DELTAS"))
+	downloadInfo, xdelta3 := t.mockDeltaDownload(c, "corrupted snap")
+	defer xdelta3.Restore()
+
+	var urls []string
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		urls = append(urls, url)
+		w.Write([]byte("new snap"))
+		return nil
+	}
+
+	path := filepath.Join(dirs.SnapBlobDir, "foo_26.snap")
+	err := t.store.Download("foo", path, downloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+
+	c.Check(urls, DeepEquals, []string{"delta-anon-url", "anon-url"})
+	c.Check(xdelta3.Calls(), HasLen, 1)
+	c.Check(t.logbuf.String(), Matches, `(?s).*Cannot download or apply delta for foo: sha3-384 mismatch for "foo".*`)
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "new snap")
+}
+
+func (t *remoteRepoTestSuite) TestDownloadWithDeltaFallsBackWithoutSource(c *C) {
+	defer os.Setenv("SNAPPY_USE_DELTAS", os.Getenv("SNAPPY_USE_DELTAS"))
+	downloadInfo, xdelta3 := t.mockDeltaDownload(c, "new snap")
+	defer xdelta3.Restore()
+	downloadInfo.Deltas[0].FromRevision = 23
+
+	var urls []string
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		urls = append(urls, url)
+		w.Write([]byte("new snap"))
+		return nil
+	}
+
+	path := filepath.Join(dirs.SnapBlobDir, "foo_26.snap")
+	err := t.store.Download("foo", path, downloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+
+	// the delta is not fetched at all
+	c.Check(urls, DeepEquals, []string{"anon-url"})
+	c.Check(xdelta3.Calls(), HasLen, 0)
+	c.Check(t.logbuf.String(), Matches, `(?s).*Cannot download or apply delta for foo: snap "foo" revision 23 not found.*`)
+}
+
+func (t *remoteRepoTestSuite) TestDownloadWithDeltaDisabled(c *C) {
+	defer os.Setenv("SNAPPY_USE_DELTAS", os.Getenv("SNAPPY_USE_DELTAS"))
+	downloadInfo, xdelta3 := t.mockDeltaDownload(c, "new snap")
+	defer xdelta3.Restore()
+
+	// deltas can be turned off even with xdelta3 available
+	os.Setenv("SNAPPY_USE_DELTAS", "0")
+
+	var urls []string
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		urls = append(urls, url)
+		w.Write([]byte("new snap"))
+		return nil
+	}
+
+	path := filepath.Join(dirs.SnapBlobDir, "foo_26.snap")
+	err := t.store.Download("foo", path, downloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+
+	c.Check(urls, DeepEquals, []string{"anon-url"})
+	c.Check(xdelta3.Calls(), HasLen, 0)
+}
+
+func (t *remoteRepoTestSuite) TestDownloadWithoutXdelta3(c *C) {
+	defer os.Setenv("SNAPPY_USE_DELTAS", os.Getenv("SNAPPY_USE_DELTAS"))
+	downloadInfo, xdelta3 := t.mockDeltaDownload(c, "new snap")
+	xdelta3.Restore()
+
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", c.MkDir())
+
+	var urls []string
+	download = func(name, url string, user *auth.UserState, s *Store, w *os.File, resume int64, pbar progress.Meter) error {
+		urls = append(urls, url)
+		w.Write([]byte("new snap"))
+		return nil
+	}
+
+	path := filepath.Join(dirs.SnapBlobDir, "foo_26.snap")
+	err := t.store.Download("foo", path, downloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+
+	c.Check(urls, DeepEquals, []string{"anon-url"})
 }
 
 func (t *remoteRepoTestSuite) TestDoRequestSetsAuth(c *C) {
@@ -813,7 +1257,7 @@ func (t *remoteRepoTestSuite) TestUbuntuStoreRepositoryDetails(c *C) {
 	})
 	c.Check(result.MustBuy, Equals, true)
 
-	// Make sure the epoch (currently not sent by the store) defaults to "0"
+	// Make sure the epoch (not sent by the store for this snap) defaults to "0"
 	c.Check(result.Epoch, Equals, "0")
 
 	c.Check(repo.SuggestedCurrency(), Equals, "GBP")
@@ -1443,6 +1887,48 @@ func (t *remoteRepoTestSuite) TestUbuntuStoreRepositoryListRefresh(c *C) {
 	c.Assert(results[0].Deltas, HasLen, 0)
 }
 
+func (t *remoteRepoTestSuite) TestUbuntuStoreRepositoryListRefreshEpoch(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jsonReq, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		var resp struct {
+			Snaps []map[string]interface{} `json:"snaps"`
+		}
+
+		err = json.Unmarshal(jsonReq, &resp)
+		c.Assert(err, IsNil)
+
+		c.Assert(resp.Snaps, HasLen, 1)
+		c.Check(resp.Snaps[0]["epoch"], Equals, "1*")
+
+		io.WriteString(w, strings.Replace(MockUpdatesJSON, `"confinement": "strict",`, `"confinement": "strict", "epoch": "2*",`, 1))
+	}))
+
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	bulkURI, err := url.Parse(mockServer.URL + "/updates/")
+	c.Assert(err, IsNil)
+	cfg := Config{
+		BulkURI: bulkURI,
+	}
+	repo := New(&cfg, nil)
+	c.Assert(repo, NotNil)
+
+	results, err := repo.ListRefresh([]*RefreshCandidate{
+		{
+			SnapID:   helloWorldSnapID,
+			Channel:  "stable",
+			Revision: snap.R(1),
+			Epoch:    "1*",
+		},
+	}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(results, HasLen, 1)
+	c.Check(results[0].Revision, Equals, snap.R(26))
+	c.Check(results[0].Epoch, Equals, "2*")
+}
+
 func (t *remoteRepoTestSuite) TestUbuntuStoreRepositoryListRefreshSkipCurrent(c *C) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		jsonReq, err := ioutil.ReadAll(r.Body)
@@ -1614,7 +2100,9 @@ var MockUpdatesWithDeltasJSON = `
 func (t *remoteRepoTestSuite) TestUbuntuStoreRepositoryListRefreshWithDeltas(c *C) {
 	orig_use_deltas := os.Getenv("SNAPPY_USE_DELTAS")
 	defer os.Setenv("SNAPPY_USE_DELTAS", orig_use_deltas)
-	c.Assert(os.Setenv("SNAPPY_USE_DELTAS", "1"), IsNil)
+	c.Assert(os.Unsetenv("SNAPPY_USE_DELTAS"), IsNil)
+	xdelta3 := testutil.MockCommand(c, "xdelta3", "")
+	defer xdelta3.Restore()
 
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c.Check(r.Header.Get("X-Ubuntu-Delta-Formats"), Equals, `xdelta`)
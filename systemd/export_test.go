@@ -26,6 +26,7 @@ import (
 var (
 	SystemdRun = run // NOTE: plain Run clashes with check.v1
 	Jctl       = jctl
+	JctlReader = jctlReader
 )
 
 func MockStopDelays(checkDelay, notifyDelay time.Duration) func() {
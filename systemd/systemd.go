@@ -82,11 +82,59 @@ func jctl(svcs []string) ([]byte, error) {
 // JournalctlCmd is called from Logs to run journalctl; exported for testing.
 var JournalctlCmd = jctl
 
+// jctlReader calls journalctl to get the JSON logs of the given services,
+// returning a reader of its output; closing the reader stops journalctl.
+// A negative n asks for all the logs.
+func jctlReader(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+	cmd := []string{"journalctl", "-o", "json", "--no-pager"}
+	if n < 0 {
+		cmd = append(cmd, "--no-tail")
+	} else {
+		cmd = append(cmd, "-n", strconv.Itoa(n))
+	}
+	if follow {
+		cmd = append(cmd, "-f")
+	}
+
+	for i := range svcs {
+		cmd = append(cmd, "-u", svcs[i])
+	}
+
+	c := exec.Command(cmd[0], cmd[1:]...)
+	out, err := c.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+
+	return &journalReader{ReadCloser: out, cmd: c}, nil
+}
+
+// JournalctlReaderCmd is called from LogReader to run journalctl; exported for testing.
+var JournalctlReaderCmd = jctlReader
+
+// journalReader reads the output of a running journalctl
+type journalReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+// Close stops journalctl and waits for it to finish.
+func (r *journalReader) Close() error {
+	// journalctl might be following the journal, so it has to be stopped
+	r.cmd.Process.Kill()
+	r.cmd.Wait()
+	return nil
+}
+
 // Systemd exposes a minimal interface to manage systemd via the systemctl command.
 type Systemd interface {
 	DaemonReload() error
 	Enable(service string) error
 	Disable(service string) error
+	IsEnabled(service string) (bool, error)
 	Start(service string) error
 	Stop(service string, timeout time.Duration) error
 	Kill(service, signal string) error
@@ -94,6 +142,7 @@ type Systemd interface {
 	Status(service string) (string, error)
 	ServiceStatus(service string) (*ServiceStatus, error)
 	Logs(services []string) ([]Log, error)
+	LogReader(services []string, n int, follow bool) (io.ReadCloser, error)
 	WriteMountUnitFile(name, what, where, fstype string) (string, error)
 }
 
@@ -154,6 +203,9 @@ const (
 	// the default target for systemd units that we generate
 	SocketsTarget = "sockets.target"
 
+	// the default target for systemd timer units that we generate
+	TimersTarget = "timers.target"
+
 	// the location to put system services
 	snapServicesDir = "/etc/systemd/system"
 )
@@ -190,6 +242,20 @@ func (s *systemd) Disable(serviceName string) error {
 	return err
 }
 
+// IsEnabled checks whether the given service is enabled
+func (s *systemd) IsEnabled(serviceName string) (bool, error) {
+	_, err := SystemctlCmd("--root", s.rootDir, "is-enabled", serviceName)
+	if err == nil {
+		return true, nil
+	}
+	// is-enabled exits with a non-zero status for units that are
+	// not enabled, whether disabled, masked or missing
+	if _, ok := err.(*Error); ok {
+		return false, nil
+	}
+	return false, err
+}
+
 // Start the given service
 func (*systemd) Start(serviceName string) error {
 	_, err := SystemctlCmd("start", serviceName)
@@ -228,6 +294,14 @@ func (*systemd) Logs(serviceNames []string) ([]Log, error) {
 	return logs, nil
 }
 
+// LogReader for the given services, returning at most the last n
+// entries (all of them if n is negative) and, if follow is set, any new
+// ones as they are written. The reader yields the entries as a stream of
+// JSON objects that decode into Logs.
+func (*systemd) LogReader(serviceNames []string, n int, follow bool) (io.ReadCloser, error) {
+	return JournalctlReaderCmd(serviceNames, n, follow)
+}
+
 var statusregex = regexp.MustCompile(`(?m)^(?:(.*?)=(.*))?$`)
 
 func (s *systemd) Status(serviceName string) (string, error) {
@@ -384,6 +458,21 @@ func (l Log) Timestamp() string {
 	return t
 }
 
+// Time of the Log, if it has a valid timestamp.
+func (l Log) Time() (time.Time, error) {
+	sus, ok := l["__REALTIME_TIMESTAMP"].(string)
+	if !ok {
+		return time.Time{}, errors.New("no timestamp")
+	}
+	// according to systemd.journal-fields(7) it's microseconds as a decimal string
+	us, err := strconv.ParseInt(sus, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp not a decimal number: %#v", sus)
+	}
+
+	return time.Unix(us/1000000, 1000*(us%1000000)).UTC(), nil
+}
+
 // Message of the Log, if any; otherwise, "-".
 func (l Log) Message() string {
 	if msg, ok := l["MESSAGE"].(string); ok {
@@ -402,6 +491,18 @@ func (l Log) SID() string {
 	return "-"
 }
 
+// PID is the pid of the process that wrote the Log, if any; otherwise, "-".
+func (l Log) PID() string {
+	if pid, ok := l["_PID"].(string); ok {
+		return pid
+	}
+	if pid, ok := l["SYSLOG_PID"].(string); ok {
+		return pid
+	}
+
+	return "-"
+}
+
 func (l Log) String() string {
 	return fmt.Sprintf("%s %s %s", l.Timestamp(), l.SID(), l.Message())
 }
@@ -20,10 +20,13 @@
 package systemd_test
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -91,6 +94,7 @@ func (s *SystemdTestSuite) SetUpTest(c *C) {
 func (s *SystemdTestSuite) TearDownTest(c *C) {
 	SystemctlCmd = SystemdRun
 	JournalctlCmd = Jctl
+	JournalctlReaderCmd = JctlReader
 }
 
 func (s *SystemdTestSuite) myRun(args ...string) (out []byte, err error) {
@@ -198,6 +202,27 @@ func (s *SystemdTestSuite) TestEnable(c *C) {
 
 }
 
+func (s *SystemdTestSuite) TestIsEnabled(c *C) {
+	s.errors = []error{nil, &Error{}, errors.New("boom")}
+
+	enabled, err := New("xyzzy", s.rep).IsEnabled("foo")
+	c.Assert(err, IsNil)
+	c.Check(enabled, Equals, true)
+
+	enabled, err = New("xyzzy", s.rep).IsEnabled("foo")
+	c.Assert(err, IsNil)
+	c.Check(enabled, Equals, false)
+
+	_, err = New("xyzzy", s.rep).IsEnabled("foo")
+	c.Check(err, ErrorMatches, "boom")
+
+	c.Check(s.argses, DeepEquals, [][]string{
+		{"--root", "xyzzy", "is-enabled", "foo"},
+		{"--root", "xyzzy", "is-enabled", "foo"},
+		{"--root", "xyzzy", "is-enabled", "foo"},
+	})
+}
+
 func (s *SystemdTestSuite) TestRestart(c *C) {
 	restore := MockStopDelays(time.Millisecond, 25*time.Second)
 	defer restore()
@@ -277,6 +302,36 @@ func (s *SystemdTestSuite) TestLogString(c *C) {
 
 }
 
+func (s *SystemdTestSuite) TestLogTimeAndPID(c *C) {
+	_, err := Log{}.Time()
+	c.Check(err, ErrorMatches, "no timestamp")
+	_, err = Log{"__REALTIME_TIMESTAMP": "what"}.Time()
+	c.Check(err, ErrorMatches, `timestamp not a decimal number: "what"`)
+	t, err := Log{"__REALTIME_TIMESTAMP": "42"}.Time()
+	c.Check(err, IsNil)
+	c.Check(t, Equals, time.Unix(0, 42000).UTC())
+
+	c.Check(Log{}.PID(), Equals, "-")
+	c.Check(Log{"SYSLOG_PID": "99"}.PID(), Equals, "99")
+	c.Check(Log{"_PID": "42", "SYSLOG_PID": "99"}.PID(), Equals, "42")
+}
+
+func (s *SystemdTestSuite) TestLogReader(c *C) {
+	var args []interface{}
+	JournalctlReaderCmd = func(svcs []string, n int, follow bool) (io.ReadCloser, error) {
+		args = []interface{}{svcs, n, follow}
+		return ioutil.NopCloser(strings.NewReader(`{"a": 1}`)), nil
+	}
+
+	rc, err := New("", s.rep).LogReader([]string{"foo", "bar"}, 10, true)
+	c.Assert(err, IsNil)
+	defer rc.Close()
+	c.Check(args, DeepEquals, []interface{}{[]string{"foo", "bar"}, 10, true})
+	bs, err := ioutil.ReadAll(rc)
+	c.Assert(err, IsNil)
+	c.Check(string(bs), Equals, `{"a": 1}`)
+}
+
 func (s *SystemdTestSuite) TestMountUnitPath(c *C) {
 	c.Assert(MountUnitPath("/apps/hello/1.1", "mount"), Equals, filepath.Join(dirs.SnapServicesDir, "apps-hello-1.1.mount"))
 }
@@ -0,0 +1,277 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package timeutil
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeNow = time.Now
+
+// MockTimeNow mocks the current time used to compute schedules.
+func MockTimeNow(f func() time.Time) (restore func()) {
+	origTimeNow := timeNow
+	timeNow = f
+	return func() { timeNow = origTimeNow }
+}
+
+// Clock represents a hour:minute time within a day.
+type Clock struct {
+	Hour   int
+	Minute int
+}
+
+func (t Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", t.Hour, t.Minute)
+}
+
+// Sub gets the duration between two clock times.
+func (t Clock) Sub(other Clock) time.Duration {
+	t1 := time.Duration(t.Hour)*time.Hour + time.Duration(t.Minute)*time.Minute
+	t2 := time.Duration(other.Hour)*time.Hour + time.Duration(other.Minute)*time.Minute
+	return t1 - t2
+}
+
+var validClock = regexp.MustCompile(`^([0-9]|0[0-9]|1[0-9]|2[0-4]):([0-5][0-9])$`)
+
+// ParseClock parses a string that contains hour:minute and returns
+// a Clock type or an error.
+func ParseClock(s string) (t Clock, err error) {
+	m := validClock.FindStringSubmatch(s)
+	if len(m) == 0 {
+		return t, fmt.Errorf("cannot parse %q", s)
+	}
+
+	t.Hour, err = strconv.Atoi(m[1])
+	if err != nil {
+		return t, fmt.Errorf("cannot parse %q: %s", m[1], err)
+	}
+	t.Minute, err = strconv.Atoi(m[2])
+	if err != nil {
+		return t, fmt.Errorf("cannot parse %q: %s", m[2], err)
+	}
+	if t.Hour == 24 && t.Minute != 0 {
+		return t, fmt.Errorf("cannot parse %q: time beyond 24:00", s)
+	}
+	return t, nil
+}
+
+// Schedule defines a start and end time and an optional weekday in which
+// events should run.
+type Schedule struct {
+	Start Clock
+	End   Clock
+
+	Weekday string
+}
+
+func (sched *Schedule) String() string {
+	if sched.Weekday == "" {
+		return fmt.Sprintf("%s-%s", sched.Start, sched.End)
+	}
+	return fmt.Sprintf("%s,%s-%s", sched.Weekday, sched.Start, sched.End)
+}
+
+// Next returns the start and end of the next window of this schedule
+// that ends after the given time.
+func (sched *Schedule) Next(last time.Time) (start, end time.Time) {
+	wd := time.Weekday(weekdayMap[sched.Weekday])
+
+	t := last
+	for {
+		a := time.Date(t.Year(), t.Month(), t.Day(), sched.Start.Hour, sched.Start.Minute, 0, 0, time.Local)
+		b := a.Add(sched.End.Sub(sched.Start))
+
+		// not using AddDate() here as this takes away the
+		// hour/minute and would break with DST transitions
+		t = t.Add(24 * time.Hour)
+
+		if sched.Weekday != "" && a.Weekday() != wd {
+			continue
+		}
+		if !b.After(last) {
+			continue
+		}
+
+		return a, b
+	}
+}
+
+func randDur(a, b time.Time) time.Duration {
+	dur := b.Sub(a)
+	if dur > 5*time.Minute {
+		// doing it this way we still spread really small windows about
+		dur -= 5 * time.Minute
+	}
+
+	if dur <= 0 {
+		// avoid panic'ing (even if things are probably messed up)
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(dur)))
+}
+
+func init() {
+	rand.Seed(time.Now().UnixNano())
+}
+
+// Next will return the duration until a random time in the next
+// schedule window that does not contain the last time. A window that
+// was missed entirely results in a zero duration.
+func Next(schedule []*Schedule, last time.Time) time.Duration {
+	now := timeNow()
+	// no need to look further back than a week, all schedules repeat
+	// at least weekly
+	if weekAgo := now.Add(-7 * 24 * time.Hour); last.Before(weekAgo) {
+		last = weekAgo
+	}
+
+	a := last.Add(24 * 7 * time.Hour)
+	b := a.Add(1 * time.Hour)
+	for _, sched := range schedule {
+		start, end := sched.Next(last)
+		if !start.After(last) {
+			// the window contains last, look at the following one
+			start, end = sched.Next(end)
+		}
+		if start.Before(a) {
+			a = start
+			b = end
+		}
+	}
+	if a.Before(now) {
+		a = now
+	}
+	if !b.After(a) {
+		return 0
+	}
+
+	when := a.Sub(now) + randDur(a, b)
+
+	return when
+}
+
+var weekdayMap = map[string]int{
+	"sun": 0,
+	"mon": 1,
+	"tue": 2,
+	"wed": 3,
+	"thu": 4,
+	"fri": 5,
+	"sat": 6,
+}
+
+// parseWeekday gets an input like "mon" or "tue" and returns it
+// unchanged if it is a valid weekday.
+func parseWeekday(s string) (string, error) {
+	s = strings.ToLower(s)
+	if _, ok := weekdayMap[s]; !ok {
+		return "", fmt.Errorf(`cannot parse %q: not a valid day`, s)
+	}
+	return s, nil
+}
+
+// parseTimeInterval gets an input like "9:00-11:00"
+// and extracts the start and end of that schedule string and
+// returns them and any errors.
+func parseTimeInterval(s string) (start, end Clock, err error) {
+	l := strings.SplitN(s, "-", 2)
+	if len(l) != 2 {
+		return start, end, fmt.Errorf("cannot parse %q: not a valid interval", s)
+	}
+
+	start, err = ParseClock(l[0])
+	if err != nil {
+		return start, end, fmt.Errorf("cannot parse %q: not a valid time", l[0])
+	}
+	end, err = ParseClock(l[1])
+	if err != nil {
+		return start, end, fmt.Errorf("cannot parse %q: not a valid time", l[1])
+	}
+	if start.Hour == 24 {
+		return start, end, fmt.Errorf("cannot parse %q: start time beyond 23:59", s)
+	}
+	if end.Sub(start) <= 0 {
+		return start, end, fmt.Errorf("cannot parse %q: time interval must end after it starts", s)
+	}
+
+	return start, end, nil
+}
+
+// parseSingleSchedule parses a schedule string like "mon,10:00-11:00"
+// and returns a Schedule or an error.
+func parseSingleSchedule(s string) (*Schedule, error) {
+	if strings.Count(s, ",") > 1 {
+		return nil, fmt.Errorf("cannot parse %q: too many \",\"", s)
+	}
+
+	var weekday string
+	var err error
+	timeInterval := s
+	if strings.Contains(s, ",") {
+		l := strings.SplitN(s, ",", 2)
+		weekday, err = parseWeekday(l[0])
+		if err != nil {
+			return nil, err
+		}
+		timeInterval = l[1]
+	}
+
+	start, end, err := parseTimeInterval(timeInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schedule{
+		Weekday: weekday,
+		Start:   start,
+		End:     end,
+	}, nil
+}
+
+// ParseSchedule takes a schedule string in the form of:
+//
+// 9:00-15:00 (every day between 9am and 3pm)
+// 9:00-15:00/21:00-22:00 (every day between 9am,5pm and 9pm,10pm)
+// thu,9:00-15:00 (only Thursday between 9am and 3pm)
+// fri,9:00-11:00/mon,13:00-15:00 (only Friday between 9am and 11am
+//
+//	and Monday between 1pm and 3pm)
+//
+// and returns a list of Schedule types or an error
+func ParseSchedule(scheduleSpec string) ([]*Schedule, error) {
+	var schedule []*Schedule
+
+	for _, s := range strings.Split(scheduleSpec, "/") {
+		sched, err := parseSingleSchedule(s)
+		if err != nil {
+			return nil, err
+		}
+		schedule = append(schedule, sched)
+	}
+
+	return schedule, nil
+}
@@ -0,0 +1,226 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package timeutil_test
+
+import (
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/timeutil"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type timeutilSuite struct{}
+
+var _ = Suite(&timeutilSuite{})
+
+func (ts *timeutilSuite) TestClock(c *C) {
+	td := timeutil.Clock{Hour: 23, Minute: 59}
+	c.Check(td.Sub(timeutil.Clock{Hour: 22, Minute: 58}), Equals, time.Hour+time.Minute)
+	c.Check(td.String(), Equals, "23:59")
+}
+
+func (ts *timeutilSuite) TestParseClock(c *C) {
+	for _, t := range []struct {
+		timeStr      string
+		hour, minute int
+		errStr       string
+	}{
+		{"8:59", 8, 59, ""},
+		{"08:59", 8, 59, ""},
+		{"12:00", 12, 0, ""},
+		{"24:00", 24, 0, ""},
+		{"24:01", 0, 0, `cannot parse "24:01": time beyond 24:00`},
+		{"10:60", 0, 0, `cannot parse "10:60"`},
+		{"25:00", 0, 0, `cannot parse "25:00"`},
+		{"foo", 0, 0, `cannot parse "foo"`},
+	} {
+		ti, err := timeutil.ParseClock(t.timeStr)
+		if t.errStr != "" {
+			c.Check(err, ErrorMatches, t.errStr)
+		} else {
+			c.Check(err, IsNil)
+			c.Check(ti.Hour, Equals, t.hour)
+			c.Check(ti.Minute, Equals, t.minute)
+		}
+	}
+}
+
+func (ts *timeutilSuite) TestParseWeekday(c *C) {
+	for _, t := range []struct {
+		in       string
+		expected string
+		errStr   string
+	}{
+		{"mon", "mon", ""},
+		{"SUN", "sun", ""},
+		{"foo", "", `cannot parse "foo": not a valid day`},
+	} {
+		weekday, err := timeutil.ParseWeekday(t.in)
+		if t.errStr != "" {
+			c.Check(err, ErrorMatches, t.errStr)
+		} else {
+			c.Check(err, IsNil)
+			c.Check(weekday, Equals, t.expected)
+		}
+	}
+}
+
+func (ts *timeutilSuite) TestParseTimeInterval(c *C) {
+	for _, t := range []struct {
+		in     string
+		start  timeutil.Clock
+		end    timeutil.Clock
+		errStr string
+	}{
+		{"9:00-11:00", timeutil.Clock{Hour: 9}, timeutil.Clock{Hour: 11}, ""},
+		{"23:59-24:00", timeutil.Clock{Hour: 23, Minute: 59}, timeutil.Clock{Hour: 24}, ""},
+		{"9:00-", timeutil.Clock{}, timeutil.Clock{}, `cannot parse "": not a valid time`},
+		{"-11:00", timeutil.Clock{}, timeutil.Clock{}, `cannot parse "": not a valid time`},
+		{"11:00", timeutil.Clock{}, timeutil.Clock{}, `cannot parse "11:00": not a valid interval`},
+		{"11:00-9:00", timeutil.Clock{}, timeutil.Clock{}, `cannot parse "11:00-9:00": time interval must end after it starts`},
+		{"24:00-24:00", timeutil.Clock{}, timeutil.Clock{}, `cannot parse "24:00-24:00": start time beyond 23:59`},
+	} {
+		start, end, err := timeutil.ParseTimeInterval(t.in)
+		if t.errStr != "" {
+			c.Check(err, ErrorMatches, t.errStr)
+		} else {
+			c.Check(err, IsNil)
+			c.Check(start, Equals, t.start)
+			c.Check(end, Equals, t.end)
+		}
+	}
+}
+
+func (ts *timeutilSuite) TestParseSchedule(c *C) {
+	for _, t := range []struct {
+		in       string
+		expected []*timeutil.Schedule
+		errStr   string
+	}{
+		// invalid
+		{"", nil, `cannot parse "": not a valid interval`},
+		{"mon,tue,9:00-11:00", nil, `cannot parse "mon,tue,9:00-11:00": too many ","`},
+		{"foo,9:00-11:00", nil, `cannot parse "foo": not a valid day`},
+		{"9:00-11:00/", nil, `cannot parse "": not a valid interval`},
+		// valid
+		{"9:00-11:00", []*timeutil.Schedule{{Start: timeutil.Clock{Hour: 9}, End: timeutil.Clock{Hour: 11}}}, ""},
+		{"mon,9:00-11:00", []*timeutil.Schedule{{Weekday: "mon", Start: timeutil.Clock{Hour: 9}, End: timeutil.Clock{Hour: 11}}}, ""},
+		{"fri,9:00-11:00/mon,13:00-15:00", []*timeutil.Schedule{
+			{Weekday: "fri", Start: timeutil.Clock{Hour: 9}, End: timeutil.Clock{Hour: 11}},
+			{Weekday: "mon", Start: timeutil.Clock{Hour: 13}, End: timeutil.Clock{Hour: 15}},
+		}, ""},
+	} {
+		schedule, err := timeutil.ParseSchedule(t.in)
+		if t.errStr != "" {
+			c.Check(err, ErrorMatches, t.errStr, Commentf("%q returned unexpected error: %s", t.in, err))
+		} else {
+			c.Check(err, IsNil, Commentf("%q returned error: %s", t.in, err))
+			c.Check(schedule, DeepEquals, t.expected, Commentf("%q failed", t.in))
+		}
+	}
+}
+
+func (ts *timeutilSuite) TestScheduleString(c *C) {
+	for _, t := range []string{"09:00-11:00", "mon,09:00-11:00", "fri,23:00-24:00"} {
+		schedule, err := timeutil.ParseSchedule(t)
+		c.Assert(err, IsNil)
+		c.Check(schedule[0].String(), Equals, t)
+	}
+}
+
+func (ts *timeutilSuite) TestScheduleNext(c *C) {
+	const shortForm = "2006-01-02 15:04"
+
+	for _, t := range []struct {
+		schedule string
+		last     string
+		now      string
+		next     []string
+	}{
+		{
+			// last inside today's window, next one is tomorrow
+			schedule: "9:00-11:00/21:00-23:00",
+			last:     "2017-02-05 22:00",
+			now:      "2017-02-05 22:01",
+			next:     []string{"2017-02-06 09:00", "2017-02-06 11:00"},
+		},
+		{
+			// later window today
+			schedule: "9:00-11:00/21:00-23:00",
+			last:     "2017-02-05 10:00",
+			now:      "2017-02-05 12:00",
+			next:     []string{"2017-02-05 21:00", "2017-02-05 23:00"},
+		},
+		{
+			// weekday windows
+			schedule: "mon,9:00-11:00",
+			last:     "2017-02-06 10:00",
+			now:      "2017-02-06 10:30",
+			next:     []string{"2017-02-13 09:00", "2017-02-13 11:00"},
+		},
+		{
+			// window in progress, last outside of it
+			schedule: "9:00-11:00",
+			last:     "2017-02-04 10:00",
+			now:      "2017-02-05 10:00",
+			next:     []string{"2017-02-05 10:00", "2017-02-05 11:00"},
+		},
+	} {
+		last, err := time.ParseInLocation(shortForm, t.last, time.Local)
+		c.Assert(err, IsNil)
+		now, err := time.ParseInLocation(shortForm, t.now, time.Local)
+		c.Assert(err, IsNil)
+		restore := timeutil.MockTimeNow(func() time.Time { return now })
+
+		sched, err := timeutil.ParseSchedule(t.schedule)
+		c.Assert(err, IsNil)
+
+		a, err := time.ParseInLocation(shortForm, t.next[0], time.Local)
+		c.Assert(err, IsNil)
+		b, err := time.ParseInLocation(shortForm, t.next[1], time.Local)
+		c.Assert(err, IsNil)
+
+		for i := 0; i < 100; i++ {
+			next := timeutil.Next(sched, last)
+			when := now.Add(next)
+			c.Check(when.Before(a), Equals, false, Commentf("%s: %s before %s", t.schedule, when, a))
+			c.Check(when.Before(b), Equals, true, Commentf("%s: %s not before %s", t.schedule, when, b))
+		}
+		restore()
+	}
+}
+
+func (ts *timeutilSuite) TestScheduleNextMissedWindow(c *C) {
+	now := time.Date(2017, 2, 5, 12, 0, 0, 0, time.Local)
+	restore := timeutil.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	sched, err := timeutil.ParseSchedule("9:00-11:00")
+	c.Assert(err, IsNil)
+
+	// never run before
+	c.Check(timeutil.Next(sched, time.Time{}), Equals, time.Duration(0))
+	// last run two days ago, yesterday's window was missed
+	c.Check(timeutil.Next(sched, now.Add(-48*time.Hour)), Equals, time.Duration(0))
+}
@@ -117,7 +117,12 @@ func rewriteExecLine(s *snap.Info, desktopFile, line string) (string, error) {
 	for _, app := range s.Apps {
 		env := fmt.Sprintf("env BAMF_DESKTOP_FILE_HINT=%s ", desktopFile)
 		wrapper := app.WrapperPath()
-		validCmd := filepath.Base(wrapper)
+		// desktop files refer to the apps by the snap name, also
+		// when installed as an instance
+		validCmd := s.SnapName()
+		if app.Name != s.SnapName() {
+			validCmd = fmt.Sprintf("%s.%s", s.SnapName(), app.Name)
+		}
 		// check the prefix to allow %flag style args
 		// this is ok because desktop files are not run through sh
 		// so we don't have to worry about the arguments too much
@@ -199,7 +204,7 @@ func AddSnapDesktopFiles(s *snap.Info) error {
 			return err
 		}
 
-		installedDesktopFileName := filepath.Join(dirs.SnapDesktopFilesDir, fmt.Sprintf("%s_%s", s.Name(), filepath.Base(df)))
+		installedDesktopFileName := filepath.Join(dirs.SnapDesktopFilesDir, fmt.Sprintf("%s_%s", s.DesktopPrefix(), filepath.Base(df)))
 		content = sanitizeDesktopFile(s, installedDesktopFileName, content)
 		if err := osutil.AtomicWriteFile(installedDesktopFileName, []byte(content), 0755, 0); err != nil {
 			return err
@@ -216,7 +221,7 @@ func AddSnapDesktopFiles(s *snap.Info) error {
 
 // RemoveSnapDesktopFiles removes the added desktop files for the applications in the snap.
 func RemoveSnapDesktopFiles(s *snap.Info) error {
-	glob := filepath.Join(dirs.SnapDesktopFilesDir, s.Name()+"_*.desktop")
+	glob := filepath.Join(dirs.SnapDesktopFilesDir, s.DesktopPrefix()+"_*.desktop")
 	activeDesktopFiles, err := filepath.Glob(glob)
 	if err != nil {
 		return fmt.Errorf("cannot get desktop files for %v: %s", glob, err)
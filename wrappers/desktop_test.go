@@ -104,6 +104,30 @@ func (s *desktopSuite) TestRemovePackageDesktopFiles(c *C) {
 	})
 }
 
+func (s *desktopSuite) TestRemovePackageDesktopFilesKeepsInstances(c *C) {
+	mockDesktopFilePath := filepath.Join(dirs.SnapDesktopFilesDir, "foo_foobar.desktop")
+	mockInstanceDesktopFilePath := filepath.Join(dirs.SnapDesktopFilesDir, "foo+bar_foobar.desktop")
+
+	err := os.MkdirAll(dirs.SnapDesktopFilesDir, 0755)
+	c.Assert(err, IsNil)
+	for _, fn := range []string{mockDesktopFilePath, mockInstanceDesktopFilePath} {
+		err = ioutil.WriteFile(fn, mockDesktopFile, 0644)
+		c.Assert(err, IsNil)
+	}
+	info, err := snap.InfoFromSnapYaml([]byte(desktopAppYaml))
+	c.Assert(err, IsNil)
+
+	err = wrappers.RemoveSnapDesktopFiles(info)
+	c.Assert(err, IsNil)
+	c.Check(osutil.FileExists(mockDesktopFilePath), Equals, false)
+	c.Check(osutil.FileExists(mockInstanceDesktopFilePath), Equals, true)
+
+	info.InstanceKey = "bar"
+	err = wrappers.RemoveSnapDesktopFiles(info)
+	c.Assert(err, IsNil)
+	c.Check(osutil.FileExists(mockInstanceDesktopFilePath), Equals, false)
+}
+
 // sanitize
 
 type sanitizeDesktopFileSuite struct{}
@@ -257,6 +281,22 @@ apps:
 	c.Assert(newl, Equals, fmt.Sprintf("Exec=env BAMF_DESKTOP_FILE_HINT=foo.desktop %s/bin/snap.app", dirs.SnapMountDir))
 }
 
+func (s *sanitizeDesktopFileSuite) TestRewriteExecLineInstance(c *C) {
+	snap, err := snap.InfoFromSnapYaml([]byte(`
+name: snap
+version: 1.0
+apps:
+ app:
+  command: cmd
+`))
+	c.Assert(err, IsNil)
+	snap.InstanceKey = "key"
+
+	newl, err := wrappers.RewriteExecLine(snap, "foo.desktop", "Exec=snap.app %U")
+	c.Assert(err, IsNil)
+	c.Assert(newl, Equals, fmt.Sprintf("Exec=env BAMF_DESKTOP_FILE_HINT=foo.desktop %s/bin/snap_key.app %%U", dirs.SnapMountDir))
+}
+
 func (s *sanitizeDesktopFileSuite) TestTrimLang(c *C) {
 	langs := []struct {
 		in  string
@@ -26,8 +26,10 @@ import (
 // some internal helper exposed for testing
 var (
 	// services
-	GenerateSnapServiceFile = generateSnapServiceFile
-	GenerateSnapSocketFile  = generateSnapSocketFile
+	GenerateSnapServiceFile    = generateSnapServiceFile
+	GenerateSnapSocketFile     = generateSnapSocketFile
+	GenerateSnapSocketUnitFile = generateSnapSocketUnitFile
+	GenerateSnapTimerFile      = generateSnapTimerFile
 
 	// desktop
 	SanitizeDesktopFile = sanitizeDesktopFile
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"text/template"
 	"time"
 
@@ -72,10 +73,72 @@ func generateSnapSocketFile(app *snap.AppInfo) (string, error) {
 	return genSocketFile(app), nil
 }
 
-// StartSnapServices starts service units for the applications from the snap which are services.
-func StartSnapServices(s *snap.Info, inter interacter) error {
+func generateSnapSocketUnitFile(socket *snap.SocketInfo) (string, error) {
+	if err := snap.ValidateApp(socket.App); err != nil {
+		return "", err
+	}
+
+	return genSocketUnitFile(socket), nil
+}
+
+func generateSnapTimerFile(timer *snap.TimerInfo) (string, error) {
+	if err := snap.ValidateApp(timer.App); err != nil {
+		return "", err
+	}
+
+	return genTimerFile(timer), nil
+}
+
+// serviceUnitName returns the name of the service unit of the given
+// app of the snap.
+func serviceUnitName(s *snap.Info, appName string) string {
+	return snap.AppSecurityTag(s.Name(), appName) + ".service"
+}
+
+// sortedServices returns the services of the snap in the order they
+// need to be started in.
+func sortedServices(s *snap.Info) ([]*snap.AppInfo, error) {
+	services := make([]*snap.AppInfo, 0, len(s.Apps))
 	for _, app := range s.Apps {
-		if app.Daemon == "" {
+		if app.IsService() {
+			services = append(services, app)
+		}
+	}
+	return snap.SortServices(services)
+}
+
+// activatorUnits returns the names of the socket and timer units that
+// start the service app on demand.
+func activatorUnits(app *snap.AppInfo) []string {
+	units := make([]string, 0, len(app.Sockets)+1)
+	for _, socket := range app.Sockets {
+		units = append(units, filepath.Base(socket.File()))
+	}
+	sort.Strings(units)
+	if app.Timer != nil {
+		units = append(units, filepath.Base(app.Timer.File()))
+	}
+	return units
+}
+
+func writeUnitFile(path, content string) error {
+	os.MkdirAll(filepath.Dir(path), 0755)
+	return osutil.AtomicWriteFile(path, []byte(content), 0644, 0)
+}
+
+// StartSnapServices starts service units for the applications from the snap which are services.
+// The services named in disabled are left disabled and stopped.
+func StartSnapServices(s *snap.Info, disabled []string, inter interacter) error {
+	services, err := sortedServices(s)
+	if err != nil {
+		return err
+	}
+	skip := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		skip[name] = true
+	}
+	for _, app := range services {
+		if skip[app.Name] {
 			continue
 		}
 		// daemon-reload and enable plus start
@@ -85,6 +148,20 @@ func StartSnapServices(s *snap.Info, inter interacter) error {
 			return err
 		}
 
+		if app.IsActivated() {
+			// the service gets started on demand by its sockets
+			// and timer
+			for _, unit := range activatorUnits(app) {
+				if err := sysd.Enable(unit); err != nil {
+					return err
+				}
+				if err := sysd.Start(unit); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
 		if err := sysd.Enable(serviceName); err != nil {
 			return err
 		}
@@ -109,6 +186,34 @@ func StartSnapServices(s *snap.Info, inter interacter) error {
 	return nil
 }
 
+// DisabledSnapServices returns the sorted names of the applications
+// from the snap which are services whose units are not enabled. For
+// services started on demand the activating units are checked.
+func DisabledSnapServices(s *snap.Info, inter interacter) ([]string, error) {
+	sysd := systemd.New(dirs.GlobalRootDir, inter)
+
+	var disabled []string
+	for _, app := range s.Apps {
+		if !app.IsService() {
+			continue
+		}
+		unit := filepath.Base(app.ServiceFile())
+		if app.IsActivated() {
+			unit = activatorUnits(app)[0]
+		}
+		enabled, err := sysd.IsEnabled(unit)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			disabled = append(disabled, app.Name)
+		}
+	}
+	sort.Strings(disabled)
+
+	return disabled, nil
+}
+
 // AddSnapServices adds service units for the applications from the snap which are services.
 func AddSnapServices(s *snap.Info, inter interacter) error {
 	for _, app := range s.Apps {
@@ -120,9 +225,7 @@ func AddSnapServices(s *snap.Info, inter interacter) error {
 		if err != nil {
 			return err
 		}
-		svcFilePath := app.ServiceFile()
-		os.MkdirAll(filepath.Dir(svcFilePath), 0755)
-		if err := osutil.AtomicWriteFile(svcFilePath, []byte(content), 0644, 0); err != nil {
+		if err := writeUnitFile(app.ServiceFile(), content); err != nil {
 			return err
 		}
 		// Generate systemd socket file if needed
@@ -131,9 +234,26 @@ func AddSnapServices(s *snap.Info, inter interacter) error {
 			if err != nil {
 				return err
 			}
-			svcSocketFilePath := app.ServiceSocketFile()
-			os.MkdirAll(filepath.Dir(svcSocketFilePath), 0755)
-			if err := osutil.AtomicWriteFile(svcSocketFilePath, []byte(content), 0644, 0); err != nil {
+			if err := writeUnitFile(app.ServiceSocketFile(), content); err != nil {
+				return err
+			}
+		}
+		// Generate the units activating the service on demand
+		for _, socket := range app.Sockets {
+			content, err := generateSnapSocketUnitFile(socket)
+			if err != nil {
+				return err
+			}
+			if err := writeUnitFile(socket.File(), content); err != nil {
+				return err
+			}
+		}
+		if app.Timer != nil {
+			content, err := generateSnapTimerFile(app.Timer)
+			if err != nil {
+				return err
+			}
+			if err := writeUnitFile(app.Timer.File(), content); err != nil {
 				return err
 			}
 		}
@@ -143,19 +263,31 @@ func AddSnapServices(s *snap.Info, inter interacter) error {
 }
 
 // StopSnapServices stops service units for the applications from the snap which are services.
-func StopSnapServices(s *snap.Info, inter interacter) error {
+// Services with "refresh-mode: endure" are left running when stopping for a refresh.
+func StopSnapServices(s *snap.Info, reason snap.ServiceStopReason, inter interacter) error {
 	sysd := systemd.New(dirs.GlobalRootDir, inter)
 
-	nservices := 0
+	services, err := sortedServices(s)
+	if err != nil {
+		return err
+	}
 
-	for _, app := range s.Apps {
-		if app.Daemon == "" {
+	// stop in the reverse order of starting
+	for i := len(services) - 1; i >= 0; i-- {
+		app := services[i]
+		if reason == snap.StopReasonRefresh && app.RefreshMode == "endure" {
 			continue
 		}
-		nservices++
 
 		serviceName := filepath.Base(app.ServiceFile())
 		tout := serviceStopTimeout(app)
+		// stop the activators first so the service is not started
+		// again behind our back
+		for _, unit := range activatorUnits(app) {
+			if err := sysd.Stop(unit, tout); err != nil {
+				return err
+			}
+		}
 		if err := sysd.Stop(serviceName, tout); err != nil {
 			if !systemd.IsTimeout(err) {
 				return err
@@ -197,6 +329,26 @@ func RemoveSnapServices(s *snap.Info, inter interacter) error {
 		if err := os.Remove(app.ServiceSocketFile()); err != nil && !os.IsNotExist(err) {
 			logger.Noticef("Failed to remove socket file for %q: %v", serviceName, err)
 		}
+
+		for _, socket := range app.Sockets {
+			socketName := filepath.Base(socket.File())
+			if err := sysd.Disable(socketName); err != nil {
+				return err
+			}
+			if err := os.Remove(socket.File()); err != nil && !os.IsNotExist(err) {
+				logger.Noticef("Failed to remove socket file %q for %q: %v", socketName, serviceName, err)
+			}
+		}
+
+		if app.Timer != nil {
+			timerName := filepath.Base(app.Timer.File())
+			if err := sysd.Disable(timerName); err != nil {
+				return err
+			}
+			if err := os.Remove(app.Timer.File()); err != nil && !os.IsNotExist(err) {
+				logger.Noticef("Failed to remove timer file for %q: %v", serviceName, err)
+			}
+		}
 	}
 
 	// only reload if we actually had services
@@ -213,8 +365,10 @@ func genServiceFile(appInfo *snap.AppInfo) string {
 	serviceTemplate := `[Unit]
 # Auto-generated, DO NO EDIT
 Description=Service for snap application {{.App.Snap.Name}}.{{.App.Name}}
-After=snapd.frameworks.target{{ if .App.Socket }} {{.SocketFileName}}{{end}}
-Requires=snapd.frameworks.target{{ if .App.Socket }} {{.SocketFileName}}{{end}}
+After=snapd.frameworks.target{{range .SocketFileNames}} {{.}}{{end}}{{range .AfterServices}} {{.}}{{end}}
+Requires=snapd.frameworks.target{{range .SocketFileNames}} {{.}}{{end}}
+{{- if .BeforeServices}}
+Before={{range $i, $svc := .BeforeServices}}{{if $i}} {{end}}{{$svc}}{{end}}{{end}}
 X-Snappy=yes
 
 [Service]
@@ -222,14 +376,20 @@ ExecStart={{.App.LauncherCommand}}
 Restart={{.Restart}}
 WorkingDirectory={{.App.Snap.DataDir}}
 {{if .App.StopCommand}}ExecStop={{.App.LauncherStopCommand}}{{end}}
+{{- if .App.ReloadCommand}}
+ExecReload={{.App.LauncherReloadCommand}}{{end}}
 {{if .App.PostStopCommand}}ExecStopPost={{.App.LauncherPostStopCommand}}{{end}}
 {{if .StopTimeout}}TimeoutStopSec={{.StopTimeout.Seconds}}{{end}}
+{{- if not .App.StopMode.KillAll}}
+KillMode=process{{end}}
+{{- if .App.StopMode.KillSignal}}
+KillSignal={{.App.StopMode.KillSignal}}{{end}}
 Type={{.App.Daemon}}
 {{if .App.BusName}}BusName={{.App.BusName}}{{end}}
-
+{{if not .App.IsActivated}}
 [Install]
 WantedBy={{.ServiceTargetUnit}}
-`
+{{end}}`
 	var templateOut bytes.Buffer
 	t := template.Must(template.New("wrapper").Parse(serviceTemplate))
 
@@ -242,15 +402,29 @@ WantedBy={{.ServiceTargetUnit}}
 	if restartCond == "" {
 		restartCond = systemd.RestartOnFailure.String()
 	}
-	socketFileName := ""
+	var socketFileNames []string
 	if appInfo.Socket {
-		socketFileName = filepath.Base(appInfo.ServiceSocketFile())
+		socketFileNames = append(socketFileNames, filepath.Base(appInfo.ServiceSocketFile()))
+	}
+	for _, socket := range appInfo.Sockets {
+		socketFileNames = append(socketFileNames, filepath.Base(socket.File()))
+	}
+	sort.Strings(socketFileNames)
+	afterServices := make([]string, 0, len(appInfo.After))
+	for _, other := range appInfo.After {
+		afterServices = append(afterServices, serviceUnitName(appInfo.Snap, other))
+	}
+	beforeServices := make([]string, 0, len(appInfo.Before))
+	for _, other := range appInfo.Before {
+		beforeServices = append(beforeServices, serviceUnitName(appInfo.Snap, other))
 	}
 
 	wrapperData := struct {
 		App *snap.AppInfo
 
-		SocketFileName    string
+		SocketFileNames   []string
+		AfterServices     []string
+		BeforeServices    []string
 		Restart           string
 		StopTimeout       time.Duration
 		ServiceTargetUnit string
@@ -260,7 +434,9 @@ WantedBy={{.ServiceTargetUnit}}
 	}{
 		App: appInfo,
 
-		SocketFileName:    socketFileName,
+		SocketFileNames:   socketFileNames,
+		AfterServices:     afterServices,
+		BeforeServices:    beforeServices,
 		Restart:           restartCond,
 		StopTimeout:       serviceStopTimeout(appInfo),
 		ServiceTargetUnit: systemd.ServicesTarget,
@@ -311,3 +487,90 @@ WantedBy={{.SocketTargetUnit}}
 
 	return templateOut.String()
 }
+
+func genSocketUnitFile(socket *snap.SocketInfo) string {
+	socketTemplate := `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Socket {{.Socket.Name}} for snap application {{.App.Snap.Name}}.{{.App.Name}}
+PartOf={{.ServiceFileName}}
+X-Snappy=yes
+
+[Socket]
+Service={{.ServiceFileName}}
+FileDescriptorName={{.Socket.Name}}
+ListenStream={{.ListenStream}}
+SocketMode={{.SocketMode}}
+
+[Install]
+WantedBy={{.SocketTargetUnit}}
+`
+	var templateOut bytes.Buffer
+	t := template.Must(template.New("socket").Parse(socketTemplate))
+
+	// lp: #1515709, systemd will default to 0666 if no socket mode
+	// is specified
+	socketMode := socket.SocketMode
+	if socketMode == "" {
+		socketMode = "0660"
+	}
+
+	wrapperData := struct {
+		App              *snap.AppInfo
+		Socket           *snap.SocketInfo
+		ServiceFileName  string
+		ListenStream     string
+		SocketMode       string
+		SocketTargetUnit string
+	}{
+		App:              socket.App,
+		Socket:           socket,
+		ServiceFileName:  filepath.Base(socket.App.ServiceFile()),
+		ListenStream:     socket.App.Snap.ExpandSnapVariables(socket.ListenStream),
+		SocketMode:       socketMode,
+		SocketTargetUnit: systemd.SocketsTarget,
+	}
+
+	if err := t.Execute(&templateOut, wrapperData); err != nil {
+		// this can never happen, except we forget a variable
+		logger.Panicf("Unable to execute template: %v", err)
+	}
+
+	return templateOut.String()
+}
+
+func genTimerFile(timer *snap.TimerInfo) string {
+	timerTemplate := `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Timer for snap application {{.App.Snap.Name}}.{{.App.Name}}
+PartOf={{.ServiceFileName}}
+X-Snappy=yes
+
+[Timer]
+Unit={{.ServiceFileName}}
+OnCalendar={{.Timer.Timer}}
+
+[Install]
+WantedBy={{.TimerTargetUnit}}
+`
+	var templateOut bytes.Buffer
+	t := template.Must(template.New("timer").Parse(timerTemplate))
+
+	wrapperData := struct {
+		App             *snap.AppInfo
+		Timer           *snap.TimerInfo
+		ServiceFileName string
+		TimerTargetUnit string
+	}{
+		App:             timer.App,
+		Timer:           timer,
+		ServiceFileName: filepath.Base(timer.App.ServiceFile()),
+		TimerTargetUnit: systemd.TimersTarget,
+	}
+
+	if err := t.Execute(&templateOut, wrapperData); err != nil {
+		// this can never happen, except we forget a variable
+		logger.Panicf("Unable to execute template: %v", err)
+	}
+
+	return templateOut.String()
+}
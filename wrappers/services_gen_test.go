@@ -293,3 +293,152 @@ func (s *servicesWrapperGenSuite) TestGenerateSnapSocketFileMode(c *C) {
 	c.Assert(content, Matches, "(?ms).*SocketMode=0600")
 
 }
+
+const activatedYaml = `
+name: snap
+version: 1.0
+apps:
+    app:
+        command: bin/start
+        daemon: simple
+        sockets:
+            sock1:
+                listen-stream: $SNAP_DATA/sock1.socket
+                socket-mode: "0666"
+            sock2:
+                listen-stream: 8080
+        timer: "Mon..Fri *-*-* 9:00"
+`
+
+func (s *servicesWrapperGenSuite) TestGenerateSnapServiceFileActivated(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(activatedYaml))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(44)
+	app := info.Apps["app"]
+
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app)
+	c.Assert(err, IsNil)
+	c.Check(generatedWrapper, Matches, "(?ms).*^After=snapd.frameworks.target snap.snap.app.sock1.socket snap.snap.app.sock2.socket$.*")
+	c.Check(generatedWrapper, Matches, "(?ms).*^Requires=snapd.frameworks.target snap.snap.app.sock1.socket snap.snap.app.sock2.socket$.*")
+	// activated services are not started at boot
+	c.Check(generatedWrapper, Not(Matches), "(?ms).*WantedBy=.*")
+	c.Check(generatedWrapper, Not(Matches), "(?ms).*\\[Install\\].*")
+}
+
+func (s *servicesWrapperGenSuite) TestGenerateSnapSocketUnitFile(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(activatedYaml))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(44)
+	app := info.Apps["app"]
+
+	content, err := wrappers.GenerateSnapSocketUnitFile(app.Sockets["sock1"])
+	c.Assert(err, IsNil)
+	c.Check(content, Equals, fmt.Sprintf(`[Unit]
+# Auto-generated, DO NO EDIT
+Description=Socket sock1 for snap application snap.app
+PartOf=snap.snap.app.service
+X-Snappy=yes
+
+[Socket]
+Service=snap.snap.app.service
+FileDescriptorName=sock1
+ListenStream=%s/snap/44/sock1.socket
+SocketMode=0666
+
+[Install]
+WantedBy=sockets.target
+`, dirs.SnapDataDir))
+
+	// no socket mode means 0660
+	content, err = wrappers.GenerateSnapSocketUnitFile(app.Sockets["sock2"])
+	c.Assert(err, IsNil)
+	c.Check(content, Matches, "(?ms).*^ListenStream=8080$.*")
+	c.Check(content, Matches, "(?ms).*^SocketMode=0660$.*")
+}
+
+func (s *servicesWrapperGenSuite) TestGenerateSnapTimerFile(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(activatedYaml))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(44)
+	app := info.Apps["app"]
+
+	content, err := wrappers.GenerateSnapTimerFile(app.Timer)
+	c.Assert(err, IsNil)
+	c.Check(content, Equals, `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Timer for snap application snap.app
+PartOf=snap.snap.app.service
+X-Snappy=yes
+
+[Timer]
+Unit=snap.snap.app.service
+OnCalendar=Mon..Fri *-*-* 9:00
+
+[Install]
+WantedBy=timers.target
+`)
+}
+
+func (s *servicesWrapperGenSuite) TestGenerateSnapServiceFileLifecycle(c *C) {
+	yamlText := `
+name: snap
+version: 1.0
+apps:
+    app:
+        command: bin/start
+        stop-command: bin/stop
+        reload-command: bin/reload
+        post-stop-command: bin/stop --post
+        stop-timeout: 10s
+        stop-mode: sighup
+        daemon: simple
+        after: [db]
+        before: [web, worker]
+    db:
+        command: bin/db
+        daemon: simple
+    web:
+        command: bin/web
+        daemon: simple
+    worker:
+        command: bin/worker
+        daemon: simple
+        stop-mode: sigusr1-all
+`
+	info, err := snap.InfoFromSnapYaml([]byte(yamlText))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(44)
+
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(info.Apps["app"])
+	c.Assert(err, IsNil)
+	c.Check(generatedWrapper, Equals, `[Unit]
+# Auto-generated, DO NO EDIT
+Description=Service for snap application snap.app
+After=snapd.frameworks.target snap.snap.db.service
+Requires=snapd.frameworks.target
+Before=snap.snap.web.service snap.snap.worker.service
+X-Snappy=yes
+
+[Service]
+ExecStart=/usr/bin/snap run snap.app
+Restart=on-failure
+WorkingDirectory=/var/snap/snap/44
+ExecStop=/usr/bin/snap run --command=stop snap.app
+ExecReload=/usr/bin/snap run --command=reload snap.app
+ExecStopPost=/usr/bin/snap run --command=post-stop snap.app
+TimeoutStopSec=10
+KillMode=process
+KillSignal=SIGHUP
+Type=simple
+
+
+[Install]
+WantedBy=multi-user.target
+`)
+
+	// the -all variants signal all the processes of the service
+	generatedWrapper, err = wrappers.GenerateSnapServiceFile(info.Apps["worker"])
+	c.Assert(err, IsNil)
+	c.Check(generatedWrapper, Not(Matches), "(?ms).*KillMode=.*")
+	c.Check(generatedWrapper, Matches, "(?ms).*^KillSignal=SIGUSR1$.*")
+}
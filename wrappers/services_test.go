@@ -83,7 +83,7 @@ func (s *servicesTestSuite) TestAddSnapServicesAndRemove(c *C) {
 	}
 
 	sysdLog = nil
-	err = wrappers.StopSnapServices(info, &progress.NullProgress{})
+	err = wrappers.StopSnapServices(info, "", &progress.NullProgress{})
 	c.Assert(err, IsNil)
 	c.Assert(sysdLog, HasLen, 2)
 	c.Check(sysdLog, DeepEquals, [][]string{
@@ -130,7 +130,7 @@ apps:
 
 	svcFName := "snap.wat.wat.service"
 
-	err = wrappers.StopSnapServices(info, &progress.NullProgress{})
+	err = wrappers.StopSnapServices(info, "", &progress.NullProgress{})
 	c.Assert(err, IsNil)
 
 	c.Check(sysdLog, DeepEquals, [][]string{
@@ -151,7 +151,7 @@ func (s *servicesTestSuite) TestStartSnapServices(c *C) {
 	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
 	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
 
-	err := wrappers.StartSnapServices(info, nil)
+	err := wrappers.StartSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 
 	c.Assert(sysdLog, HasLen, 3)
@@ -159,3 +159,167 @@ func (s *servicesTestSuite) TestStartSnapServices(c *C) {
 	c.Check(sysdLog[1], DeepEquals, []string{"--root", dirs.GlobalRootDir, "enable", filepath.Base(svcFile)})
 	c.Check(sysdLog[2], DeepEquals, []string{"start", filepath.Base(svcFile)})
 }
+
+func (s *servicesTestSuite) TestAddStartStopRemoveActivatedSnapServices(c *C) {
+	var sysdLog [][]string
+	systemd.SystemctlCmd = func(cmd ...string) ([]byte, error) {
+		// filter out the "systemctl show" that
+		// StopSnapServices generates
+		if cmd[0] != "show" {
+			sysdLog = append(sysdLog, cmd)
+		}
+		return []byte("ActiveState=inactive\n"), nil
+	}
+
+	info := snaptest.MockSnap(c, `name: hello-snap
+version: 1.0
+apps:
+ svc1:
+  command: bin/hello
+  daemon: simple
+  sockets:
+   sock1:
+    listen-stream: $SNAP_COMMON/sock1.socket
+  timer: daily
+`, &snap.SideInfo{Revision: snap.R(12)})
+
+	err := wrappers.AddSnapServices(info, nil)
+	c.Assert(err, IsNil)
+
+	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
+	sockFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.sock1.socket")
+	timerFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.timer")
+	for _, f := range []string{svcFile, sockFile, timerFile} {
+		c.Check(osutil.FileExists(f), Equals, true)
+	}
+
+	// only the activators get enabled and started
+	err = wrappers.StartSnapServices(info, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+		{"--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.svc1.sock1.socket"},
+		{"start", "snap.hello-snap.svc1.sock1.socket"},
+		{"--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.svc1.timer"},
+		{"start", "snap.hello-snap.svc1.timer"},
+	})
+
+	sysdLog = nil
+	err = wrappers.StopSnapServices(info, "", &progress.NullProgress{})
+	c.Assert(err, IsNil)
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"stop", "snap.hello-snap.svc1.sock1.socket"},
+		{"stop", "snap.hello-snap.svc1.timer"},
+		{"stop", "snap.hello-snap.svc1.service"},
+	})
+
+	sysdLog = nil
+	err = wrappers.RemoveSnapServices(info, &progress.NullProgress{})
+	c.Assert(err, IsNil)
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.svc1.service"},
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.svc1.sock1.socket"},
+		{"--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.svc1.timer"},
+		{"daemon-reload"},
+	})
+	for _, f := range []string{svcFile, sockFile, timerFile} {
+		c.Check(osutil.FileExists(f), Equals, false)
+	}
+}
+
+func (s *servicesTestSuite) TestStartStopSnapServicesOrderAndModes(c *C) {
+	var sysdLog [][]string
+	systemd.SystemctlCmd = func(cmd ...string) ([]byte, error) {
+		// filter out the "systemctl show" that
+		// StopSnapServices generates
+		if cmd[0] != "show" {
+			sysdLog = append(sysdLog, cmd)
+		}
+		return []byte("ActiveState=inactive\n"), nil
+	}
+
+	info := snaptest.MockSnap(c, `name: hello-snap
+version: 1.0
+apps:
+ web:
+  command: bin/web
+  daemon: simple
+  after: [db]
+ db:
+  command: bin/db
+  daemon: simple
+  refresh-mode: endure
+ manual:
+  command: bin/manual
+  daemon: simple
+  install-mode: disable
+`, &snap.SideInfo{Revision: snap.R(12)})
+
+	err := wrappers.AddSnapServices(info, nil)
+	c.Assert(err, IsNil)
+
+	// services are started in order, disabled ones are left alone
+	err = wrappers.StartSnapServices(info, []string{"manual"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+		{"--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.db.service"},
+		{"start", "snap.hello-snap.db.service"},
+		{"daemon-reload"},
+		{"--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.web.service"},
+		{"start", "snap.hello-snap.web.service"},
+	})
+
+	// services are stopped in reverse order
+	sysdLog = nil
+	err = wrappers.StopSnapServices(info, snap.StopReasonRemove, &progress.NullProgress{})
+	c.Assert(err, IsNil)
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"stop", "snap.hello-snap.web.service"},
+		{"stop", "snap.hello-snap.manual.service"},
+		{"stop", "snap.hello-snap.db.service"},
+	})
+
+	// enduring services are kept running across refreshes
+	sysdLog = nil
+	err = wrappers.StopSnapServices(info, snap.StopReasonRefresh, &progress.NullProgress{})
+	c.Assert(err, IsNil)
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"stop", "snap.hello-snap.web.service"},
+		{"stop", "snap.hello-snap.manual.service"},
+	})
+}
+
+func (s *servicesTestSuite) TestDisabledSnapServices(c *C) {
+	var sysdLog [][]string
+	systemd.SystemctlCmd = func(cmd ...string) ([]byte, error) {
+		sysdLog = append(sysdLog, cmd)
+		switch cmd[len(cmd)-1] {
+		case "snap.hello-snap.db.service", "snap.hello-snap.cron.timer":
+			return nil, &systemd.Error{}
+		}
+		return nil, nil
+	}
+
+	info := snaptest.MockSnap(c, `name: hello-snap
+version: 1.0
+apps:
+ web:
+  command: bin/web
+  daemon: simple
+ db:
+  command: bin/db
+  daemon: simple
+ cron:
+  command: bin/cron
+  daemon: oneshot
+  timer: daily
+ tool:
+  command: bin/tool
+`, &snap.SideInfo{Revision: snap.R(12)})
+
+	disabled, err := wrappers.DisabledSnapServices(info, nil)
+	c.Assert(err, IsNil)
+	c.Check(disabled, DeepEquals, []string{"cron", "db"})
+	c.Check(sysdLog, HasLen, 3)
+}